@@ -657,7 +657,7 @@ func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallM
 		if err != nil {
 			return nil, err
 		}
-		sysCtx = core.NewSysContractCallCtx(block.Header(), sysStateDB, b.blockchain)
+		sysCtx = core.NewSysContractCallCtx(b.config, block.Header(), sysStateDB, b.blockchain)
 	}
 	return core.NewStateTransition(vmEnv, msg, gasPool, vmRunner, sysCtx).TransitionDb()
 }
@@ -908,6 +908,10 @@ func (fb *filterBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event
 	return nullSubscription()
 }
 
+func (fb *filterBackend) SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	return nullSubscription()
+}
+
 func (fb *filterBackend) BloomStatus() (uint64, uint64) { return 4096, 0 }
 
 func (fb *filterBackend) ServiceFilter(ctx context.Context, ms *bloombits.MatcherSession) {
@@ -926,6 +930,18 @@ func (fb *filterBackend) RealGasPriceMinimumForHeader(ctx context.Context, curre
 	return nil, fmt.Errorf("filterBackend does not implement RealGasPriceMinimumForHeader")
 }
 
+func (fb *filterBackend) FeeCurrencyWhitelistForHeader(ctx context.Context, header *types.Header) (common.Address, []common.Address, error) {
+	return common.Address{}, nil, fmt.Errorf("filterBackend does not implement FeeCurrencyWhitelistForHeader")
+}
+
+func (fb *filterBackend) ConversionFunctionsForHeader(ctx context.Context, header *types.Header) (func(*common.Address) *big.Int, types.ToCELOFn, error) {
+	return nil, nil, fmt.Errorf("filterBackend does not implement ConversionFunctionsForHeader")
+}
+
+func (fb *filterBackend) ValidatorsForHeader(ctx context.Context, header *types.Header) ([]common.Address, error) {
+	return nil, fmt.Errorf("filterBackend does not implement ValidatorsForHeader")
+}
+
 func nullSubscription() event.Subscription {
 	return event.NewSubscription(func(quit <-chan struct{}) error {
 		<-quit
@@ -0,0 +1,203 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package kms implements an accounts backend for secp256k1 keys held by a key
+// management service or a hardware security module, which sign digests without
+// ever exposing the private key.
+//
+// The services are reached through providers registered by URL scheme, such as
+// awskms://<key id>, gcpkms://<key version name> or pkcs11://<token>/<label>,
+// each of them built with the client library of its service.
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/accounts"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/event"
+)
+
+var (
+	// ErrUnknownScheme is returned for a key URL without a registered provider.
+	ErrUnknownScheme = errors.New("unknown KMS provider")
+
+	errInvalidSignature = errors.New("signature doesn't match the public key")
+)
+
+// Signer is a key held by a key management service.
+type Signer interface {
+	// PublicKey retrieves the public key, helpers exist to parse the usual
+	// encodings (see ParsePublicKeyInfo).
+	PublicKey(ctx context.Context) (*ecdsa.PublicKey, error)
+
+	// Sign signs a 32 byte digest, returning the r and s values of the ECDSA
+	// signature (see ParseSignature for DER encoded signatures).
+	Sign(ctx context.Context, digest []byte) (r, s *big.Int, err error)
+}
+
+// Opener opens the key at the given path of a provider.
+type Opener func(ctx context.Context, path string) (Signer, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]Opener)
+)
+
+// Register makes the provider of the given URL scheme available to NewBackend.
+// It's meant to be called from the init function of the provider implementation.
+func Register(scheme string, open Opener) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if _, ok := providers[scheme]; ok {
+		panic(fmt.Sprintf("kms: provider %q registered twice", scheme))
+	}
+	providers[scheme] = open
+}
+
+// Backend is an accounts backend of the keys held by key management services,
+// with a wallet for every key.
+type Backend struct {
+	wallets []accounts.Wallet
+}
+
+// NewBackend opens the keys at the given URLs, retrieving their public keys once
+// and for all.
+func NewBackend(ctx context.Context, urls []string) (*Backend, error) {
+	backend := new(Backend)
+	for _, rawurl := range urls {
+		url, err := parseURL(rawurl)
+		if err != nil {
+			return nil, err
+		}
+		providersMu.RLock()
+		open, ok := providers[url.Scheme]
+		providersMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownScheme, url.Scheme)
+		}
+		signer, err := open(ctx, url.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", url, err)
+		}
+		wallet, err := newWallet(ctx, url, signer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", url, err)
+		}
+		backend.wallets = append(backend.wallets, wallet)
+	}
+	return backend, nil
+}
+
+// Wallets implements accounts.Backend, returning a wallet for every key.
+func (b *Backend) Wallets() []accounts.Wallet {
+	return b.wallets
+}
+
+// Subscribe implements accounts.Backend. The keys are fixed when the backend is
+// created, so no event is ever sent.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// parseURL splits a key URL into its provider scheme and the key path.
+func parseURL(rawurl string) (accounts.URL, error) {
+	parts := strings.SplitN(rawurl, "://", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return accounts.URL{}, fmt.Errorf("invalid KMS key URL %q, want <scheme>://<key>", rawurl)
+	}
+	return accounts.URL{Scheme: parts[0], Path: parts[1]}, nil
+}
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidSecp256k1      = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+// ParsePublicKeyInfo parses a DER encoded SubjectPublicKeyInfo of a secp256k1
+// key, the format in which the services return public keys. The x509 package
+// doesn't support the curve.
+func ParsePublicKeyInfo(der []byte) (*ecdsa.PublicKey, error) {
+	var info struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.ObjectIdentifier
+		}
+		PublicKey asn1.BitString
+	}
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	} else if len(rest) > 0 {
+		return nil, errors.New("invalid public key: trailing data")
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) || !info.Algorithm.Parameters.Equal(oidSecp256k1) {
+		return nil, fmt.Errorf("unsupported public key algorithm %v on curve %v", info.Algorithm.Algorithm, info.Algorithm.Parameters)
+	}
+	return crypto.UnmarshalPubkey(info.PublicKey.Bytes)
+}
+
+// ParseSignature parses a DER encoded ECDSA signature.
+func ParseSignature(der []byte) (r, s *big.Int, err error) {
+	var sig struct{ R, S *big.Int }
+	if rest, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, nil, fmt.Errorf("invalid signature: %w", err)
+	} else if len(rest) > 0 {
+		return nil, nil, errors.New("invalid signature: trailing data")
+	}
+	return sig.R, sig.S, nil
+}
+
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// recoverableSignature converts the r and s values of a signature of digest by
+// pub into the [R || S || V] format where V is 0 or 1. The services don't return
+// the recovery id, so both are tried, and s is normalized to the lower half of
+// the curve order as required for transactions (see EIP-2).
+func recoverableSignature(pub *ecdsa.PublicKey, digest []byte, r, s *big.Int) ([]byte, error) {
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(secp256k1N) >= 0 || s.Cmp(secp256k1N) >= 0 {
+		return nil, errInvalidSignature
+	}
+	if s.Cmp(secp256k1HalfN) > 0 {
+		s = new(big.Int).Sub(secp256k1N, s)
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:64])
+
+	want := crypto.FromECDSAPub(pub)
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		if have, err := crypto.Ecrecover(digest, sig); err == nil && bytes.Equal(have, want) {
+			return sig, nil
+		}
+	}
+	return nil, errInvalidSignature
+}
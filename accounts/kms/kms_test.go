@@ -0,0 +1,111 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package kms
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+)
+
+// testSigner is a key signing like the services: DER encoded public key and
+// signatures, without recovery id, and with s in the upper half of the order
+// every other time.
+type testSigner struct {
+	key   *ecdsa.PrivateKey
+	signs int
+}
+
+func (s *testSigner) PublicKey(ctx context.Context) (*ecdsa.PublicKey, error) {
+	var info struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.ObjectIdentifier
+		}
+		PublicKey asn1.BitString
+	}
+	info.Algorithm.Algorithm, info.Algorithm.Parameters = oidPublicKeyECDSA, oidSecp256k1
+	pub := crypto.FromECDSAPub(&s.key.PublicKey)
+	info.PublicKey = asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)}
+	der, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKeyInfo(der)
+}
+
+func (s *testSigner) Sign(ctx context.Context, digest []byte) (*big.Int, *big.Int, error) {
+	sig, err := crypto.Sign(digest, s.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, v := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	if s.signs++; s.signs%2 == 0 {
+		v.Sub(secp256k1N, v)
+	}
+	der, err := asn1.Marshal(struct{ R, S *big.Int }{r, v})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ParseSignature(der)
+}
+
+func TestWalletSigning(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	Register("testkms", func(ctx context.Context, path string) (Signer, error) {
+		if path != "validator" {
+			return nil, errors.New("unknown key")
+		}
+		return &testSigner{key: key}, nil
+	})
+	if _, err := NewBackend(context.Background(), []string{"otherkms://validator"}); !errors.Is(err, ErrUnknownScheme) {
+		t.Fatalf("unknown provider error mismatch: have %v, want %v", err, ErrUnknownScheme)
+	}
+	backend, err := NewBackend(context.Background(), []string{"testkms://validator"})
+	if err != nil {
+		t.Fatalf("failed to open key: %v", err)
+	}
+	wallets := backend.Wallets()
+	if len(wallets) != 1 || len(wallets[0].Accounts()) != 1 {
+		t.Fatalf("wallets mismatch: have %d, want 1 with 1 account", len(wallets))
+	}
+	wallet, account := wallets[0], wallets[0].Accounts()[0]
+	if want := crypto.PubkeyToAddress(key.PublicKey); account.Address != want {
+		t.Fatalf("address mismatch: have %x, want %x", account.Address, want)
+	}
+	// Both the low and high s signatures are turned into recoverable ones
+	for i := 0; i < 2; i++ {
+		tx := types.NewTransaction(uint64(i), common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil)
+		signed, err := wallet.SignTx(account, tx, big.NewInt(1))
+		if err != nil {
+			t.Fatalf("failed to sign transaction %d: %v", i, err)
+		}
+		if from, err := types.Sender(types.LatestSignerForChainID(big.NewInt(1)), signed); err != nil || from != account.Address {
+			t.Errorf("transaction %d sender mismatch: have %x, %v, want %x", i, from, err, account.Address)
+		}
+	}
+	if _, err := wallet.SignData(wallet.Accounts()[0], "", nil); err != nil {
+		t.Errorf("failed to sign data: %v", err)
+	}
+}
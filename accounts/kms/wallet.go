@@ -0,0 +1,210 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package kms
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"time"
+
+	ethereum "github.com/celo-org/celo-blockchain"
+	"github.com/celo-org/celo-blockchain/accounts"
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+// signTimeout is the maximum time a signing request to a service can take.
+const signTimeout = 10 * time.Second
+
+var errNotDerivable = errors.New("KMS keys can't be derived")
+
+// wallet is the wallet of a single key held by a key management service. The
+// public key is retrieved when it's opened, only signing reaches the service.
+type wallet struct {
+	url     accounts.URL
+	signer  Signer
+	pub     *ecdsa.PublicKey
+	account accounts.Account
+
+	signTimer  metrics.Timer
+	errorMeter metrics.Meter
+}
+
+func newWallet(ctx context.Context, url accounts.URL, signer Signer) (*wallet, error) {
+	pub, err := signer.PublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &wallet{
+		url:        url,
+		signer:     signer,
+		pub:        pub,
+		account:    accounts.Account{Address: crypto.PubkeyToAddress(*pub), URL: url},
+		signTimer:  metrics.GetOrRegisterTimer("accounts/kms/"+url.Scheme+"/sign", nil),
+		errorMeter: metrics.GetOrRegisterMeter("accounts/kms/"+url.Scheme+"/errors", nil),
+	}, nil
+}
+
+// URL implements accounts.Wallet, returning the URL of the key.
+func (w *wallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status implements accounts.Wallet. The key is always available once opened.
+func (w *wallet) Status() (string, error) {
+	return "Online", nil
+}
+
+// Open implements accounts.Wallet. There's nothing to unlock, the access to the
+// key is controlled by the service.
+func (w *wallet) Open(passphrase string) error { return nil }
+
+// Close implements accounts.Wallet.
+func (w *wallet) Close() error { return nil }
+
+// Accounts implements accounts.Wallet, returning the account of the key.
+func (w *wallet) Accounts() []accounts.Account {
+	return []accounts.Account{w.account}
+}
+
+// Contains implements accounts.Wallet.
+func (w *wallet) Contains(account accounts.Account) bool {
+	return account.Address == w.account.Address && (account.URL == (accounts.URL{}) || account.URL == w.url)
+}
+
+// Derive implements accounts.Wallet, KMS keys aren't hierarchical.
+func (w *wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, errNotDerivable
+}
+
+// ConfirmAddress implements accounts.Wallet, KMS keys aren't hierarchical.
+func (w *wallet) ConfirmAddress(path accounts.DerivationPath) (common.Address, error) {
+	return common.Address{}, errNotDerivable
+}
+
+// SelfDerive implements accounts.Wallet, KMS keys aren't hierarchical.
+func (w *wallet) SelfDerive(bases []accounts.DerivationPath, chain ethereum.ChainStateReader) {}
+
+// signHash signs a digest with the key, in the [R || S || V] format where V is
+// 0 or 1.
+func (w *wallet) signHash(account accounts.Account, hash []byte) ([]byte, error) {
+	if !w.Contains(account) {
+		log.Debug(accounts.ErrUnknownAccount.Error(), "account", account)
+		return nil, accounts.ErrUnknownAccount
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), signTimeout)
+	defer cancel()
+
+	start := time.Now()
+	r, s, err := w.signer.Sign(ctx, hash)
+	w.signTimer.UpdateSince(start)
+	if err != nil {
+		w.errorMeter.Mark(1)
+		return nil, err
+	}
+	sig, err := recoverableSignature(w.pub, hash, r, s)
+	if err != nil {
+		w.errorMeter.Mark(1)
+		return nil, err
+	}
+	return sig, nil
+}
+
+// SignData implements accounts.Wallet, signing keccak256(data).
+func (w *wallet) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	return w.signHash(account, crypto.Keccak256(data))
+}
+
+// SignHash implements accounts.Wallet.
+//
+// DEPRECATED, use SignData in future releases.
+func (w *wallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return w.signHash(account, hash)
+}
+
+// SignText implements accounts.Wallet, signing the hash of the given data
+// prefixed with the Ethereum message prefix (see accounts.TextHash).
+func (w *wallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	return w.signHash(account, accounts.TextHash(text))
+}
+
+// SignTx implements accounts.Wallet, signing the transaction with EIP-2718 if a
+// chain ID is given, or with homestead otherwise.
+func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	signer := types.LatestSignerForChainID(chainID)
+	sig, err := w.signHash(account, signer.Hash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(signer, sig)
+}
+
+// SignDataWithPassphrase implements accounts.Wallet, the passphrase is ignored.
+func (w *wallet) SignDataWithPassphrase(account accounts.Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	return w.SignData(account, mimeType, data)
+}
+
+// SignTextWithPassphrase implements accounts.Wallet, the passphrase is ignored.
+func (w *wallet) SignTextWithPassphrase(account accounts.Account, passphrase string, text []byte) ([]byte, error) {
+	return w.SignText(account, text)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, the passphrase is ignored.
+func (w *wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.SignTx(account, tx, chainID)
+}
+
+// GetPublicKey implements accounts.Wallet, returning the cached public key.
+func (w *wallet) GetPublicKey(account accounts.Account) (*ecdsa.PublicKey, error) {
+	if !w.Contains(account) {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return w.pub, nil
+}
+
+// Decrypt implements accounts.Wallet. The services only sign with secp256k1 keys.
+func (w *wallet) Decrypt(account accounts.Account, c, s1, s2 []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignBLS implements accounts.Wallet. The BLS keys derive from the private ECDSA
+// key, which the services never expose.
+func (w *wallet) SignBLS(account accounts.Account, msg []byte, extraData []byte, useComposite, cip22 bool) (blscrypto.SerializedSignature, error) {
+	return blscrypto.SerializedSignature{}, accounts.ErrNotSupported
+}
+
+// GenerateProofOfPossession implements accounts.Wallet, signing the address as
+// text to prove the ownership of the key.
+func (w *wallet) GenerateProofOfPossession(account accounts.Account, address common.Address) ([]byte, []byte, error) {
+	signature, err := w.SignText(account, crypto.Keccak256(address.Bytes()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return crypto.FromECDSAPub(w.pub), signature, nil
+}
+
+// GenerateProofOfPossessionBLS implements accounts.Wallet, the BLS keys are not
+// available.
+func (w *wallet) GenerateProofOfPossessionBLS(account accounts.Account, address common.Address) ([]byte, []byte, error) {
+	return nil, nil, accounts.ErrNotSupported
+}
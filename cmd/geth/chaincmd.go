@@ -131,6 +131,47 @@ Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing. If the file ends with .gz, the output will
 be gzipped.`,
+	}
+	exportHistoryCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportHistory),
+		Name:      "export-history",
+		Usage:     "Export the blockchain history into era files",
+		ArgsUsage: "<dir> [<epochFirst> <epochLast>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AlfajoresFlag,
+			utils.BaklavaFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The export-history command writes the blocks of the canonical chain with their receipts
+into era files in the given directory, one an epoch, with the checksums of the files in
+checksums.txt. The headers keep their Istanbul extra, with the aggregated seals, and the
+bodies their randomness and epoch SNARK data. Optional second and third arguments are the
+first and last epoch to export (default: from the genesis to the last ended epoch).`,
+	}
+	importHistoryCommand = cli.Command{
+		Action:    utils.MigrateFlags(importHistory),
+		Name:      "import-history",
+		Usage:     "Import the blockchain history from era files",
+		ArgsUsage: "<dir>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AlfajoresFlag,
+			utils.BaklavaFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+			utils.TxLookupLimitFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The import-history command imports the era files written by export-history in the given
+directory into the ancient store, checking them against checksums.txt if present. Every
+header is verified against the validator set of its epoch, and the bodies and receipts
+against the headers, but the blocks aren't executed: the state has to be synced after the
+history. The node must have no blocks but the ones imported before.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -346,6 +387,62 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+func exportHistory(ctx *cli.Context) error {
+	if ctx.NArg() != 1 && ctx.NArg() != 3 {
+		utils.Fatalf("This command requires one or three arguments.")
+	}
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+	start := time.Now()
+
+	if chain.Config().Istanbul == nil {
+		utils.Fatalf("Export error: history export requires an istanbul chain\n")
+	}
+	epochSize := chain.Config().Istanbul.Epoch
+	first, last := uint64(0), chain.CurrentFastBlock().NumberU64()/epochSize
+	if ctx.NArg() == 3 {
+		var ferr, lerr error
+		first, ferr = strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+		last, lerr = strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+		if ferr != nil || lerr != nil {
+			utils.Fatalf("Export error in parsing parameters: epoch number not an integer\n")
+		}
+		if first > last {
+			utils.Fatalf("Export error: first epoch %d after last epoch %d\n", first, last)
+		}
+	}
+	if err := utils.ExportHistory(chain, ctx.Args().First(), first, last); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
+func importHistory(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+	start := time.Now()
+
+	err := utils.ImportHistory(chain, db, ctx.Args().First())
+	chain.Stop()
+	if err != nil {
+		utils.Fatalf("Import error: %v\n", err)
+	}
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
 // importPreimages imports preimage data from the specified file.
 func importPreimages(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
@@ -18,6 +18,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
@@ -27,12 +28,14 @@ import (
 
 	"github.com/celo-org/celo-blockchain/accounts/external"
 	"github.com/celo-org/celo-blockchain/accounts/keystore"
+	"github.com/celo-org/celo-blockchain/accounts/kms"
 	"github.com/celo-org/celo-blockchain/accounts/usbwallet"
 	"github.com/celo-org/celo-blockchain/cmd/utils"
 	"github.com/celo-org/celo-blockchain/eth/ethconfig"
 	"github.com/celo-org/celo-blockchain/internal/ethapi"
 	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/metrics"
+	"github.com/celo-org/celo-blockchain/metrics/alerts"
 	"github.com/celo-org/celo-blockchain/node"
 	"github.com/celo-org/celo-blockchain/params"
 
@@ -88,6 +91,7 @@ type gethConfig struct {
 	Node     node.Config
 	Ethstats ethstatsConfig
 	Metrics  metrics.Config
+	Alerts   alerts.Config
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -162,6 +166,10 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 		cfg.Eth.OverrideHFork = new(big.Int).SetUint64(ctx.GlobalUint64(utils.OverrideHForkFlag.Name))
 	}
 	backend, _ := utils.RegisterEthService(stack, &cfg.Eth)
+	if backend == nil {
+		// Safe mode, only the intact history of the database is served
+		return stack, nil
+	}
 
 	// Configure GraphQL if requested
 	if ctx.GlobalIsSet(utils.GraphQLEnabledFlag.Name) {
@@ -171,6 +179,10 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 	if cfg.Ethstats.URL != "" {
 		utils.RegisterEthStatsService(stack, backend, cfg.Ethstats.URL)
 	}
+	// Evaluate the alerting rules if any is configured.
+	if len(cfg.Alerts.Rules) > 0 {
+		utils.RegisterAlertsService(stack, backend, cfg.Alerts)
+	}
 	return stack, backend
 }
 
@@ -286,6 +298,14 @@ func setAccountManagerBackends(stack *node.Node) error {
 	// we can have both, but it's very confusing for the user to see the same
 	// accounts in both externally and locally, plus very racey.
 	am.AddBackend(keystore.NewKeyStore(keydir, scryptN, scryptP))
+	if len(conf.KMSKeys) > 0 {
+		log.Info("Using KMS keys", "urls", conf.KMSKeys)
+		kmsbackend, err := kms.NewBackend(context.Background(), conf.KMSKeys)
+		if err != nil {
+			return fmt.Errorf("error opening KMS keys: %v", err)
+		}
+		am.AddBackend(kmsbackend)
+	}
 	if conf.USB {
 		// Start a USB hub for Ledger hardware wallets
 		if ledgerhub, err := usbwallet.NewLedgerHub(); err != nil {
@@ -0,0 +1,599 @@
+// Copyright 2021 The celo Authors
+// This file is part of celo.
+//
+// celo is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// celo is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with celo. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/celo-org/celo-blockchain/cmd/utils"
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/backend"
+	istanbulCore "github.com/celo-org/celo-blockchain/consensus/istanbul/core"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	istanbulCommand = cli.Command{
+		Name:      "istanbul",
+		Usage:     "Istanbul consensus debugging utilities",
+		ArgsUsage: "",
+		Category:  "DATABASE COMMANDS",
+		Subcommands: []cli.Command{
+			dumpSnapshotsCmd,
+			replayConsensusCmd,
+			replayMessagesCmd,
+			exportSignedViewsCmd,
+			importSignedViewsCmd,
+			epochSnapshotCmd,
+		},
+	}
+	dumpSnapshotsCmd = cli.Command{
+		Action:    utils.MigrateFlags(dumpSnapshots),
+		Name:      "dump-snapshots",
+		Usage:     "Decode the persisted validator set snapshots into JSON",
+		ArgsUsage: "[<fromBlock> [<toBlock>]]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.BaklavaFlag,
+			utils.AlfajoresFlag,
+		},
+		Description: `This command prints the Istanbul validator set snapshots, persisted at the
+last block of every epoch, of the canonical chain between the given blocks (default: the
+whole chain) as JSON.`,
+	}
+	replayConsensusCmd = cli.Command{
+		Action:    utils.MigrateFlags(replayConsensus),
+		Name:      "replay-consensus",
+		Usage:     "Reconstruct the consensus rounds of a sequence range from the archived messages",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.BaklavaFlag,
+			utils.AlfajoresFlag,
+			archiveFromFlag,
+			archiveToFlag,
+		},
+		Description: `This command replays the consensus messages and round timeouts archived
+with --istanbul.messagearchive for the sequences between --from and --to (default: the
+last sequence archived), like the replay command, alongside the canonical blocks, and
+prints a JSON timeline of the rounds of every block: who proposed, which validators
+prepared and committed, and why each round ended. The node must be stopped.`,
+	}
+	replayMessagesCmd = cli.Command{
+		Action:    utils.MigrateFlags(replayMessages),
+		Name:      "replay",
+		Usage:     "Feed the archived consensus messages of a sequence range through the Istanbul core again",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.BaklavaFlag,
+			utils.AlfajoresFlag,
+			archiveFromFlag,
+			archiveToFlag,
+		},
+		Description: `This command replays the consensus messages and round timeouts archived
+with --istanbul.messagearchive for the sequences between --from and --to (default: the
+last sequence archived) through an Istanbul core started at the block before --from, in
+the order the node handled them, and prints the state of the core after every event as
+JSON lines. The core starts every sequence with the validator set the node did, never
+signs nor sends a message, and doesn't execute the proposals again. The node must be
+stopped.`,
+	}
+	exportSignedViewsCmd = cli.Command{
+		Action:    utils.MigrateFlags(exportSignedViews),
+		Name:      "export-signed-views",
+		Usage:     "Export the views signed by the validators, protecting them from double signing",
+		ArgsUsage: "<filename>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.BaklavaFlag,
+			utils.AlfajoresFlag,
+		},
+		Description: `This command writes to the given file the highest view every validator of
+the node signed a PREPREPARE, PREPARE and COMMIT message in, with the digest signed, as
+JSON. Importing it with import-signed-views on the machine a validator is migrated to
+keeps it from signing conflicting messages there. The node must be stopped.`,
+	}
+	importSignedViewsCmd = cli.Command{
+		Action:    utils.MigrateFlags(importSignedViews),
+		Name:      "import-signed-views",
+		Usage:     "Import the views signed by validators exported from another machine",
+		ArgsUsage: "<filename>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.BaklavaFlag,
+			utils.AlfajoresFlag,
+		},
+		Description: `This command merges the views signed by validators exported with
+export-signed-views into the ones of the node, keeping the highest view of every validator
+and message. The validators then refuse to sign any message conflicting with the ones
+signed on either machine. The node must be stopped.`,
+	}
+	epochSnapshotCmd = cli.Command{
+		Name:      "snapshot",
+		Usage:     "Export and import the validator set snapshots of the epochs",
+		ArgsUsage: "",
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(exportEpochSnapshots),
+				Name:      "export",
+				Usage:     "Export the validator set snapshots of a range of epochs into a file",
+				ArgsUsage: "<filename>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.SyncModeFlag,
+					utils.MainnetFlag,
+					utils.BaklavaFlag,
+					utils.AlfajoresFlag,
+					epochFromFlag,
+					epochToFlag,
+				},
+				Description: `This command writes to the given file the validator set snapshots of the
+epochs between --from and --to of the canonical chain as JSON, with the last block header
+of every epoch: its validator set diff and the seals of the validators of the previous
+epoch. The snapshot of the epoch before --from is included to verify the first transition.`,
+			},
+			{
+				Action:    utils.MigrateFlags(importEpochSnapshots),
+				Name:      "import",
+				Usage:     "Verify and import the validator set snapshots of epochs exported from another node",
+				ArgsUsage: "<filename>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.SyncModeFlag,
+					utils.MainnetFlag,
+					utils.BaklavaFlag,
+					utils.AlfajoresFlag,
+				},
+				Description: `This command imports the validator set snapshots exported with export,
+verifying that the last block header of every epoch is sealed by the validators of the
+previous epoch and that its validator set diff leads to the next snapshot. The snapshot
+the file starts from must be the genesis one or one known to the node, so the node can
+verify epoch transitions without replaying every header. The node must be stopped.`,
+			},
+		},
+	}
+	epochFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First epoch to export the snapshot of",
+		Value: 1,
+	}
+	epochToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last epoch to export the snapshot of (default: the last ended epoch)",
+	}
+	archiveFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First sequence to replay the archived messages of (default: the last sequence)",
+	}
+	archiveToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last sequence to replay the archived messages of (default: the last sequence archived)",
+	}
+)
+
+// snapshotDump is the human readable form of a persisted Istanbul snapshot.
+type snapshotDump struct {
+	Epoch      uint64          `json:"epoch"`
+	EpochSize  uint64          `json:"epochSize"`
+	Number     uint64          `json:"number"`
+	Hash       common.Hash     `json:"hash"`
+	Validators []validatorDump `json:"validators"`
+}
+
+type validatorDump struct {
+	Address      common.Address `json:"address"`
+	BLSPublicKey hexutil.Bytes  `json:"blsPublicKey"`
+}
+
+func dumpSnapshots(ctx *cli.Context) error {
+	if ctx.NArg() > 2 {
+		return fmt.Errorf("Max 2 arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	epochSize, err := readEpochSize(db)
+	if err != nil {
+		return err
+	}
+
+	from, to := uint64(0), rawdb.ReadHeaderNumber(db, rawdb.ReadHeadHeaderHash(db))
+	if to == nil {
+		return fmt.Errorf("no head header found")
+	}
+	last := *to
+	for i, bound := range []*uint64{&from, &last} {
+		if ctx.NArg() > i {
+			number, err := strconv.ParseUint(ctx.Args().Get(i), 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid block number %q: %v", ctx.Args().Get(i), err)
+			}
+			*bound = number
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(snapshotDumps(db, epochSize, from, last))
+}
+
+// snapshotDumps decodes the snapshots of the canonical chain between the from and
+// last blocks.
+func snapshotDumps(db ethdb.Reader, epochSize, from, last uint64) []*snapshotDump {
+	// Snapshots are stored for the genesis and the last block of every epoch
+	dumps := []*snapshotDump{}
+	for epoch := istanbul.GetEpochNumber(from, epochSize); ; epoch++ {
+		number := istanbul.GetEpochLastBlockNumber(epoch, epochSize)
+		if number < from {
+			continue
+		}
+		if number > last {
+			break
+		}
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			log.Warn("Missing canonical block", "number", number)
+			continue
+		}
+		snap, err := backend.ReadSnapshot(db, hash, number)
+		if err != nil {
+			log.Warn("Missing istanbul snapshot", "number", number, "hash", hash, "err", err)
+			continue
+		}
+		dump := &snapshotDump{
+			Epoch:     epoch,
+			EpochSize: epochSize,
+			Number:    snap.Number,
+			Hash:      snap.Hash,
+		}
+		for _, val := range snap.ValSet.List() {
+			blsKey := val.BLSPublicKey()
+			dump.Validators = append(dump.Validators, validatorDump{
+				Address:      val.Address(),
+				BLSPublicKey: blsKey[:],
+			})
+		}
+		dumps = append(dumps, dump)
+	}
+	return dumps
+}
+
+// consensusSequence is the timeline of the consensus on a block.
+type consensusSequence struct {
+	Number         uint64            `json:"number"`
+	Hash           *common.Hash      `json:"hash"`           // Canonical block, nil if not imported
+	Proposer       *common.Address   `json:"proposer"`       // Proposer of the canonical block
+	CommittedRound *uint64           `json:"committedRound"` // Round the canonical block was committed in
+	Rounds         []*consensusRound `json:"rounds"`         // Rounds replayed from the message archive
+}
+
+// archiveReplay is what the archived consensus messages are replayed with.
+type archiveReplay struct {
+	path        string
+	chainConfig *params.ChainConfig
+	config      istanbul.Config
+	from, to    uint64
+}
+
+// newArchiveReplay reads the istanbul config of db and the sequence range to
+// replay from the flags.
+func newArchiveReplay(ctx *cli.Context, cfg *gethConfig, db ethdb.Reader) (*archiveReplay, error) {
+	genesisHash := rawdb.ReadCanonicalHash(db, 0)
+	chainConfig := rawdb.ReadChainConfig(db, genesisHash)
+	if chainConfig == nil || chainConfig.Istanbul == nil {
+		return nil, fmt.Errorf("no istanbul chain config found for genesis %s", genesisHash.Hex())
+	}
+	replay := &archiveReplay{
+		path:        cfg.Eth.Istanbul.MessageArchiveDBPath,
+		chainConfig: chainConfig,
+		config:      cfg.Eth.Istanbul,
+	}
+	if err := istanbul.ApplyParamsChainConfigToConfig(chainConfig, &replay.config); err != nil {
+		return nil, err
+	}
+
+	replay.to = ctx.Uint64(archiveToFlag.Name)
+	if !ctx.IsSet(archiveToFlag.Name) {
+		last, err := istanbulCore.LastArchivedSequence(replay.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the message archive: %v", err)
+		}
+		if last == 0 {
+			return nil, fmt.Errorf("no consensus messages archived in %s", replay.path)
+		}
+		replay.to = last
+	}
+	replay.from = replay.to
+	if ctx.IsSet(archiveFromFlag.Name) {
+		replay.from = ctx.Uint64(archiveFromFlag.Name)
+	}
+	if replay.from > replay.to {
+		return nil, fmt.Errorf("invalid sequence range: %d > %d", replay.from, replay.to)
+	}
+	return replay, nil
+}
+
+func replayMessages(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	replay, err := newArchiveReplay(ctx, &cfg, db)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	var encErr error
+	err = istanbulCore.ReplayMessageArchive(replay.path, db, replay.chainConfig, &replay.config, replay.from, replay.to, func(step *istanbulCore.ReplayStep) {
+		if encErr == nil {
+			encErr = enc.Encode(step)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return encErr
+}
+
+// consensusRound is a consensus round on a block, as replayed from the message archive.
+type consensusRound struct {
+	Round        uint64         `json:"round"`
+	DesiredRound uint64         `json:"desiredRound"`
+	State        string         `json:"state"`
+	Proposer     common.Address `json:"proposer"`
+
+	ProposalHash       *common.Hash     `json:"proposalHash"`       // Proposal received, nil if none
+	RoundChangeSenders []common.Address `json:"roundChangeSenders"` // Validators whose round change certificate justified the proposal
+	Prepares           []common.Address `json:"prepares"`
+	Commits            []common.Address `json:"commits"`
+	Quorum             int              `json:"quorum"`
+
+	Outcome string `json:"outcome"` // Why the round ended
+}
+
+func replayConsensus(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	replay, err := newArchiveReplay(ctx, &cfg, db)
+	if err != nil {
+		return err
+	}
+	summaries, err := istanbulCore.ReplayRoundStates(replay.path, db, replay.chainConfig, &replay.config, replay.from, replay.to)
+	if err != nil {
+		return err
+	}
+	from, to := replay.from, replay.to
+
+	timeline := make([]*consensusSequence, 0, to-from+1)
+	for number := from; number <= to; number++ {
+		sequence := &consensusSequence{Number: number, Rounds: []*consensusRound{}}
+		if hash := rawdb.ReadCanonicalHash(db, number); hash != (common.Hash{}) {
+			sequence.Hash = &hash
+			if header := rawdb.ReadHeader(db, hash, number); header != nil {
+				proposer := header.Coinbase
+				sequence.Proposer = &proposer
+				if extra, err := header.IstanbulExtra(); err == nil && extra.AggregatedSeal.Round != nil {
+					round := extra.AggregatedSeal.Round.Uint64()
+					sequence.CommittedRound = &round
+				}
+			}
+		}
+		timeline = append(timeline, sequence)
+	}
+	for i, summary := range summaries {
+		sequence := timeline[summary.Sequence.Uint64()-from]
+		last := i == len(summaries)-1 || summaries[i+1].Sequence.Cmp(summary.Sequence) != 0
+		sequence.Rounds = append(sequence.Rounds, newConsensusRound(summary, sequence.CommittedRound, last))
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(timeline)
+}
+
+// newConsensusRound explains a replayed round state. Last tells whether it's the
+// last round replayed for its sequence.
+func newConsensusRound(summary *istanbulCore.RoundStateSummary, committedRound *uint64, last bool) *consensusRound {
+	round := &consensusRound{
+		Round:              summary.Round.Uint64(),
+		DesiredRound:       summary.DesiredRound.Uint64(),
+		State:              summary.State,
+		Proposer:           summary.Proposer,
+		RoundChangeSenders: []common.Address{},
+		Prepares:           summary.Prepares,
+		Commits:            summary.Commits,
+		Quorum:             int(math.Ceil(float64(2*len(summary.ValidatorSet)) / 3)),
+	}
+	if summary.Preprepare != nil {
+		hash := summary.Preprepare.ProposalHash
+		round.ProposalHash = &hash
+		round.RoundChangeSenders = summary.Preprepare.RoundChangeCertificateSenders
+	}
+	switch {
+	case committedRound != nil && *committedRound == round.Round:
+		round.Outcome = "block committed"
+	case last && committedRound == nil:
+		round.Outcome = "in progress at the end of the archive"
+	case round.ProposalHash == nil:
+		round.Outcome = fmt.Sprintf("no proposal received from %s", round.Proposer.Hex())
+	case len(round.Prepares)+len(round.Commits) < round.Quorum:
+		round.Outcome = fmt.Sprintf("proposal not prepared, %d prepares or commits of %d", len(round.Prepares)+len(round.Commits), round.Quorum)
+	case len(round.Commits) < round.Quorum:
+		round.Outcome = fmt.Sprintf("proposal not committed, %d commits of %d", len(round.Commits), round.Quorum)
+	default:
+		round.Outcome = "quorum of commits received, block committed by another round"
+	}
+	if round.DesiredRound > round.Round && (committedRound == nil || *committedRound != round.Round) {
+		round.Outcome += fmt.Sprintf(", moved to round %d", round.DesiredRound)
+	}
+	return round
+}
+
+func exportSignedViews(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	export := istanbulCore.ExportSignedViews(db)
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(ctx.Args().First(), data, 0600); err != nil {
+		return fmt.Errorf("failed to write signed views: %v", err)
+	}
+	log.Info("Exported signed views", "views", len(export.Views), "file", ctx.Args().First())
+	return nil
+}
+
+func importSignedViews(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	data, err := os.ReadFile(ctx.Args().First())
+	if err != nil {
+		return fmt.Errorf("failed to read signed views: %v", err)
+	}
+	var views istanbulCore.SignedViews
+	if err := json.Unmarshal(data, &views); err != nil {
+		return fmt.Errorf("invalid signed views: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	updated, err := istanbulCore.ImportSignedViews(db, &views)
+	if err != nil {
+		return err
+	}
+	log.Info("Imported signed views", "views", len(views.Views), "updated", updated)
+	return nil
+}
+
+func exportEpochSnapshots(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	epochSize, err := readEpochSize(db)
+	if err != nil {
+		return err
+	}
+	head := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadHeaderHash(db))
+	if head == nil {
+		return fmt.Errorf("no head header found")
+	}
+	to := *head / epochSize
+	if ctx.IsSet(epochToFlag.Name) {
+		to = ctx.Uint64(epochToFlag.Name)
+	}
+	export, err := backend.ExportEpochSnapshots(db, epochSize, ctx.Uint64(epochFromFlag.Name), to)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(export)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(ctx.Args().First(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write epoch snapshots: %v", err)
+	}
+	log.Info("Exported epoch snapshots", "epochs", len(export.Epochs), "file", ctx.Args().First())
+	return nil
+}
+
+func importEpochSnapshots(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	data, err := os.ReadFile(ctx.Args().First())
+	if err != nil {
+		return fmt.Errorf("failed to read epoch snapshots: %v", err)
+	}
+	var snapshots backend.EpochSnapshots
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return fmt.Errorf("invalid epoch snapshots: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	epochSize, err := readEpochSize(db)
+	if err != nil {
+		return err
+	}
+	imported, err := backend.ImportEpochSnapshots(db, epochSize, &snapshots)
+	if err != nil {
+		return err
+	}
+	log.Info("Imported epoch snapshots", "epochs", len(snapshots.Epochs), "new", imported)
+	return nil
+}
+
+// readEpochSize returns the epoch size of the istanbul chain config in db.
+func readEpochSize(db ethdb.Database) (uint64, error) {
+	genesisHash := rawdb.ReadCanonicalHash(db, 0)
+	chainConfig := rawdb.ReadChainConfig(db, genesisHash)
+	if chainConfig == nil || chainConfig.Istanbul == nil {
+		return 0, fmt.Errorf("no istanbul chain config found for genesis %s", genesisHash.Hex())
+	}
+	return chainConfig.Istanbul.Epoch, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 The celo Authors
+// This file is part of celo.
+//
+// celo is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// celo is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with celo. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/backend"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/validator"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
+)
+
+func TestSnapshotDumps(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	const epochSize = 10
+
+	// Snapshots of the genesis and of the first two epochs, the third is missing
+	validators := make([]istanbul.ValidatorData, 3)
+	for i := range validators {
+		validators[i] = istanbul.ValidatorData{
+			Address:      common.BytesToAddress([]byte{byte(i + 1)}),
+			BLSPublicKey: blscrypto.SerializedPublicKey{byte(i + 1)},
+		}
+	}
+	for epoch, vals := range [][]istanbul.ValidatorData{validators[:1], validators[:2], validators, nil} {
+		number := istanbul.GetEpochLastBlockNumber(uint64(epoch), epochSize)
+		hash := common.BytesToHash([]byte{0xaa, byte(epoch)})
+		rawdb.WriteCanonicalHash(db, hash, number)
+		if vals == nil {
+			continue
+		}
+		blob, err := json.Marshal(&backend.Snapshot{Epoch: epochSize, Number: number, Hash: hash, ValSet: validator.NewSet(vals)})
+		if err != nil {
+			t.Fatalf("Failed to encode snapshot %d: %v", number, err)
+		}
+		if err := rawdb.WriteIstanbulSnapshot(db, hash, blob); err != nil {
+			t.Fatalf("Failed to write snapshot %d: %v", number, err)
+		}
+	}
+
+	dumps := snapshotDumps(db, epochSize, 0, 35)
+	if len(dumps) != 3 {
+		t.Fatalf("snapshot count mismatch: have %d, want 3", len(dumps))
+	}
+	for i, dump := range dumps {
+		number := istanbul.GetEpochLastBlockNumber(uint64(i), epochSize)
+		if dump.Epoch != uint64(i) || dump.EpochSize != epochSize || dump.Number != number || dump.Hash != common.BytesToHash([]byte{0xaa, byte(i)}) {
+			t.Errorf("snapshot %d mismatch: have epoch %d of size %d at %d (%x)", i, dump.Epoch, dump.EpochSize, dump.Number, dump.Hash)
+		}
+		if len(dump.Validators) != i+1 {
+			t.Fatalf("snapshot %d validators mismatch: have %d, want %d", i, len(dump.Validators), i+1)
+		}
+		for j, val := range dump.Validators {
+			if val.Address != validators[j].Address || !bytes.Equal(val.BLSPublicKey, validators[j].BLSPublicKey[:]) {
+				t.Errorf("snapshot %d validator %d mismatch: have %x %x", i, j, val.Address, val.BLSPublicKey)
+			}
+		}
+	}
+	// The range bounds are inclusive
+	if dumps := snapshotDumps(db, epochSize, 1, 10); len(dumps) != 1 || dumps[0].Number != 10 {
+		t.Errorf("ranged snapshots mismatch: have %d, want the one of block 10", len(dumps))
+	}
+}
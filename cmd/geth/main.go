@@ -68,17 +68,32 @@ var (
 		utils.DataDirFlag,
 		utils.AncientFlag,
 		utils.MinFreeDiskSpaceFlag,
+		utils.DatabaseSlowThresholdFlag,
 		utils.KeyStoreDirFlag,
 		utils.ExternalSignerFlag,
+		utils.KMSKeysFlag,
+		utils.StorageKeyPasswordFlag,
+		utils.StorageKeyFileFlag,
 		utils.NoUSBFlag,
 		utils.USBFlag,
 		// utils.SmartCardDaemonPathFlag,
 		utils.OverrideHForkFlag,
 		utils.L2MigrationBlockFlag,
+		utils.ConfigCheckStrictFlag,
+		utils.ConfigCheckReportFlag,
+		utils.NTPServerFlag,
+		utils.HeadLagTimeFlag,
+		utils.HeadLagBlocksFlag,
+		utils.HeadLagWebhookFlag,
+		utils.HeadLagProxyFailoverFlag,
 		utils.TxPoolLocalsFlag,
 		utils.TxPoolNoLocalsFlag,
+		utils.TxPoolStemRelayFlag,
+		utils.TxPoolStreamTokenFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolRemoteJournalFlag,
+		utils.TxPoolRemoteJournalSizeFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -86,11 +101,19 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolRateStalenessFlag,
 		utils.SyncModeFlag,
+		utils.SyncCheckpointFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.GasUsageIndexFlag,
+		utils.ExchangeRateIndexFlag,
+		utils.AccountTxIndexFlag,
+		utils.InternalTransferIndexFlag,
+		utils.SyntheticFeeLogsFlag,
+		utils.SafeModeFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -128,6 +151,8 @@ var (
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.PeerDiversityMaxFractionFlag,
+		utils.PeerDiversityASNDatabaseFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.DNSDiscoveryFlag,
@@ -137,6 +162,7 @@ var (
 		utils.BaklavaFlag,
 		utils.AlfajoresFlag,
 		utils.VMEnableDebugFlag,
+		utils.VMSystemCallFastPathFlag,
 		utils.NetworkIdFlag,
 		utils.CeloStatsURLFlag,
 		utils.LegacyEthStatsURLFlag,
@@ -151,6 +177,12 @@ var (
 		utils.LegacyIstanbulProposerPolicyFlag,
 		utils.LegacyIstanbulLookbackWindowFlag,
 		utils.IstanbulReplicaFlag,
+		utils.IstanbulRemoteSignerFlag,
+		utils.IstanbulParentSealWaitFlag,
+		utils.IstanbulAdaptiveTimeoutFlag,
+		utils.IstanbulProposerPolicyFlag,
+		utils.IstanbulMessageArchiveFlag,
+		utils.IstanbulMessageArchiveSequencesFlag,
 		utils.AnnounceQueryEnodeGossipPeriodFlag,
 		utils.AnnounceAggressiveQueryEnodeGossipOnEnablementFlag,
 		utils.PingIPFromPacketFlag,
@@ -162,8 +194,25 @@ var (
 		utils.ProxyEnodeURLPairsFlag,
 		utils.LegacyProxyEnodeURLPairsFlag,
 		utils.ProxyAllowPrivateIPFlag,
+		utils.ProxiedKnownCacheFlag,
+		utils.ProxyHealthCheckPeriodFlag,
+		utils.ProxyHealthCheckFailuresFlag,
+		utils.ProxyHealthCheckMaxLagFlag,
 		utils.CeloFeeCurrencyDefault,
 		utils.CeloFeeCurrencyLimits,
+		utils.MinerStopOnOutdatedVersionFlag,
+		utils.MinerRecommitBudgetFlag,
+		utils.MinerParallelLanesFlag,
+		utils.MinerLocalGasQuotientFlag,
+		utils.MinerFeeCurrencyReservedGasFlag,
+		utils.MinerRateStalenessFlag,
+		utils.MinerGasLimitOverrideFlag,
+		utils.MinerDenylistFlag,
+		utils.MinerForcedTxsFlag,
+		utils.MinerBlockRelayFlag,
+		utils.MinerPayloadBuilderFlag,
+		utils.MinerBuilderFlag,
+		utils.MinerFeeRecipientsFlag,
 	}
 
 	rpcFlags = []cli.Flag{
@@ -198,6 +247,8 @@ var (
 		utils.RPCGlobalGasPriceMultiplierFlag,
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCStateRangeRateFlag,
+		utils.RPCReceiptFeeFieldsFlag,
 	}
 
 	metricsFlags = []cli.Flag{
@@ -229,6 +280,8 @@ func init() {
 		initCommand,
 		importCommand,
 		exportCommand,
+		exportHistoryCommand,
+		importHistoryCommand,
 		importPreimagesCommand,
 		exportPreimagesCommand,
 		removedbCommand,
@@ -249,6 +302,12 @@ func init() {
 		dumpConfigCommand,
 		// see dbcmd.go
 		dbCommand,
+		// See istanbulcmd.go
+		istanbulCommand,
+		// See rpcdiffcmd.go
+		testCommand,
+		// See reportcmd.go
+		reportCommand,
 		// See cmd/utils/flags_legacy.go
 		utils.ShowDeprecated,
 		// See snapshot.go
@@ -261,6 +320,7 @@ func init() {
 	app.Flags = append(app.Flags, consoleFlags...)
 	app.Flags = append(app.Flags, debug.Flags...)
 	app.Flags = append(app.Flags, metricsFlags...)
+	app.Flags = append(app.Flags, utils.ChaosFlags...)
 
 	app.Before = func(ctx *cli.Context) error {
 		return debug.Setup(ctx)
@@ -313,6 +373,9 @@ func prepare(ctx *cli.Context) {
 	// Start metrics export if enabled
 	utils.SetupMetrics(ctx)
 
+	// Inject faults if testing the recovery of the node
+	utils.SetupChaos(ctx)
+
 	// Start system runtime metrics collection
 	go metrics.CollectProcessMetrics(3 * time.Second)
 }
@@ -437,6 +500,10 @@ func startNode(ctx *cli.Context, stack *node.Node, backend ethapi.Backend) {
 
 	// Start auxiliary services if enabled
 	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) || ctx.GlobalBool(utils.DeveloperFlag.Name) {
+		if backend == nil {
+			log.Warn("Mining disabled in safe mode")
+			return
+		}
 		if ctx.GlobalBool(utils.ProxyFlag.Name) {
 			utils.Fatalf("Proxies can't mine")
 		}
@@ -0,0 +1,428 @@
+// Copyright 2021 The celo Authors
+// This file is part of celo.
+//
+// celo is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// celo is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with celo. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/celo-org/celo-blockchain/cmd/utils"
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/contracts"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/currency"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	reportCommand = cli.Command{
+		Name:      "report",
+		Usage:     "Accounting reports compiled from the chain data",
+		ArgsUsage: "",
+		Category:  "DATABASE COMMANDS",
+		Subcommands: []cli.Command{
+			validatorIncomeCommand,
+		},
+	}
+	validatorIncomeCommand = cli.Command{
+		Action:    utils.MigrateFlags(validatorIncome),
+		Name:      "validator-income",
+		Usage:     "Compile the income statement of a validator over a range of epochs",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.GCModeFlag,
+			utils.MainnetFlag,
+			utils.BaklavaFlag,
+			utils.AlfajoresFlag,
+			reportValidatorFlag,
+			reportFeeRecipientFlag,
+			reportFromEpochFlag,
+			reportToEpochFlag,
+			reportCurrencyFlag,
+			reportFormatFlag,
+		},
+		Description: `This command compiles, for every epoch between --from-epoch and --to-epoch, the
+tips of the blocks whose fees were credited to the fee recipient of the validator (default:
+the validator), per fee currency, the epoch rewards paid to the validator and the slashing
+penalties it was charged. Every amount is also valued in --currency (default: CELO) at the
+exchange rates of the block it was earned in, which requires the state of those blocks: an
+archive node for past epochs. Amounts are in the base units (wei) of their currency, and
+penalties are negative. The statement is printed as CSV, or as JSON with --format json.`,
+	}
+	reportValidatorFlag = cli.StringFlag{
+		Name:  "validator",
+		Usage: "Account address of the validator",
+	}
+	reportFeeRecipientFlag = cli.StringFlag{
+		Name:  "fee-recipient",
+		Usage: "Address credited the tips of the blocks proposed by the validator (default: the validator)",
+	}
+	reportFromEpochFlag = cli.Uint64Flag{
+		Name:  "from-epoch",
+		Usage: "First epoch of the statement",
+		Value: 1,
+	}
+	reportToEpochFlag = cli.Uint64Flag{
+		Name:  "to-epoch",
+		Usage: "Last epoch of the statement (default: the last complete epoch)",
+	}
+	reportCurrencyFlag = cli.StringFlag{
+		Name:  "currency",
+		Usage: "Address of the currency the amounts are valued in (default: CELO)",
+	}
+	reportFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Format of the statement (csv or json)",
+		Value: "csv",
+	}
+)
+
+// Income kinds of the statement entries.
+const (
+	incomeTips        = "tips"
+	incomeEpochReward = "epochReward"
+	incomeSlashing    = "slashing"
+)
+
+var (
+	// validatorPaymentTopic is the topic of the Validators event logged when the
+	// epoch reward of a validator is paid, in the stable token.
+	validatorPaymentTopic = crypto.Keccak256Hash([]byte("ValidatorEpochPaymentDistributed(address,uint256,address,uint256)"))
+
+	// accountSlashedTopic is the topic of the LockedGold event logged when an
+	// account is slashed, in CELO.
+	accountSlashedTopic = crypto.Keccak256Hash([]byte("AccountSlashed(address,uint256,address,uint256)"))
+)
+
+// incomeEntry is the income of a kind in a currency during an epoch.
+type incomeEntry struct {
+	Epoch    uint64          `json:"epoch"`
+	Kind     string          `json:"kind"`
+	Currency *common.Address `json:"currency"` // nil for CELO
+	Amount   *big.Int        `json:"amount"`   // In the base units of Currency
+	Value    *big.Int        `json:"value"`    // In the base units of the statement currency
+}
+
+// incomeStatement is the income of a validator over a range of epochs.
+type incomeStatement struct {
+	Validator    common.Address  `json:"validator"`
+	FeeRecipient common.Address  `json:"feeRecipient"`
+	Currency     *common.Address `json:"currency"` // Currency the entries are valued in, nil for CELO
+	FromEpoch    uint64          `json:"fromEpoch"`
+	ToEpoch      uint64          `json:"toEpoch"`
+	Entries      []*incomeEntry  `json:"entries"`
+	Total        *big.Int        `json:"total"` // Value of all the entries
+}
+
+func newIncomeStatement(validator, feeRecipient common.Address, valuedIn *common.Address, fromEpoch, toEpoch uint64) *incomeStatement {
+	return &incomeStatement{
+		Validator:    validator,
+		FeeRecipient: feeRecipient,
+		Currency:     valuedIn,
+		FromEpoch:    fromEpoch,
+		ToEpoch:      toEpoch,
+		Total:        new(big.Int),
+	}
+}
+
+// relevant returns whether a block has income for the statement, in which case
+// its exchange rates have to be retrieved.
+func (s *incomeStatement) relevant(block *types.Block, receipts types.Receipts) bool {
+	if block.Coinbase() == s.FeeRecipient && len(block.Transactions()) > 0 {
+		return true
+	}
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if s.validatorLog(log) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validatorLog returns whether a log is an epoch payment or a slashing of the
+// validator, regardless of its emitter.
+func (s *incomeStatement) validatorLog(log *types.Log) bool {
+	if len(log.Topics) < 2 || (log.Topics[0] != validatorPaymentTopic && log.Topics[0] != accountSlashedTopic) {
+		return false
+	}
+	return common.BytesToAddress(log.Topics[1].Bytes()) == s.Validator
+}
+
+// addBlock adds the income of a block of an epoch, valued at the exchange rates
+// read with vmRunner. gasPriceMinimum returns the gas price minimum of the fee
+// currencies in the block.
+func (s *incomeStatement) addBlock(epoch uint64, block *types.Block, receipts types.Receipts, gasPriceMinimum func(*common.Address) *big.Int, vmRunner vm.EVMRunner) error {
+	rates := currency.NewManager(vmRunner)
+	if block.Coinbase() == s.FeeRecipient {
+		txs := block.Transactions()
+		if len(receipts) < len(txs) {
+			return fmt.Errorf("missing receipts of block %d", block.NumberU64())
+		}
+		for i, tx := range txs {
+			tip, err := txTip(tx, receipts[i].GasUsed, gasPriceMinimum, rates)
+			if err != nil {
+				return fmt.Errorf("tip of transaction %s: %w", tx.Hash().Hex(), err)
+			}
+			if err := s.add(epoch, incomeTips, tx.FeeCurrency(), tip, rates); err != nil {
+				return err
+			}
+		}
+	}
+	var validators, lockedGold, stableToken *common.Address
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if !s.validatorLog(log) || len(log.Data) < 32 {
+				continue
+			}
+			amount := new(big.Int).SetBytes(log.Data[:32])
+			switch log.Topics[0] {
+			case validatorPaymentTopic:
+				if validators == nil {
+					address, err := contracts.GetRegisteredAddress(vmRunner, config.ValidatorsRegistryId)
+					if err != nil {
+						return fmt.Errorf("validators contract of block %d: %w", block.NumberU64(), err)
+					}
+					token, err := contracts.GetRegisteredAddress(vmRunner, config.StableTokenRegistryId)
+					if err != nil {
+						return fmt.Errorf("stable token of block %d: %w", block.NumberU64(), err)
+					}
+					validators, stableToken = &address, &token
+				}
+				if log.Address != *validators {
+					continue
+				}
+				if err := s.add(epoch, incomeEpochReward, stableToken, amount, rates); err != nil {
+					return err
+				}
+			case accountSlashedTopic:
+				if lockedGold == nil {
+					address, err := contracts.GetRegisteredAddress(vmRunner, config.LockedGoldRegistryId)
+					if err != nil {
+						return fmt.Errorf("locked gold contract of block %d: %w", block.NumberU64(), err)
+					}
+					lockedGold = &address
+				}
+				if log.Address != *lockedGold {
+					continue
+				}
+				if err := s.add(epoch, incomeSlashing, nil, amount.Neg(amount), rates); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// txTip returns the tip a transaction credited the fee recipient, in its fee
+// currency. The fees of transactions denominated in CELO are converted to their
+// fee currency, like the state transition does.
+func txTip(tx *types.Transaction, gasUsed uint64, gasPriceMinimum func(*common.Address) *big.Int, rates *currency.CurrencyManager) (*big.Int, error) {
+	denominated := tx.MaxFeeInFeeCurrency() != nil
+	gpm := gasPriceMinimum(tx.FeeCurrency())
+	if denominated {
+		gpm = gasPriceMinimum(nil)
+	}
+	tip := tx.EffectiveGasTipValue(gpm)
+	if tip.Sign() <= 0 {
+		return new(big.Int), nil
+	}
+	tip.Mul(tip, new(big.Int).SetUint64(gasUsed))
+	if denominated {
+		feeCurrency, err := rates.GetCurrency(tx.FeeCurrency())
+		if err != nil {
+			return nil, err
+		}
+		tip = feeCurrency.FromCELO(tip)
+	}
+	return tip, nil
+}
+
+// add adds an amount of a kind of income in a currency to the epoch entry.
+func (s *incomeStatement) add(epoch uint64, kind string, in *common.Address, amount *big.Int, rates *currency.CurrencyManager) error {
+	if amount.Sign() == 0 {
+		return nil
+	}
+	from, err := rates.GetCurrency(in)
+	if err != nil {
+		return err
+	}
+	to, err := rates.GetCurrency(s.Currency)
+	if err != nil {
+		return err
+	}
+	value := to.FromCELO(from.ToCELO(amount))
+
+	var entry *incomeEntry
+	for _, e := range s.Entries {
+		if e.Epoch == epoch && e.Kind == kind && sameCurrency(e.Currency, in) {
+			entry = e
+			break
+		}
+	}
+	if entry == nil {
+		entry = &incomeEntry{Epoch: epoch, Kind: kind, Currency: in, Amount: new(big.Int), Value: new(big.Int)}
+		s.Entries = append(s.Entries, entry)
+	}
+	entry.Amount.Add(entry.Amount, amount)
+	entry.Value.Add(entry.Value, value)
+	s.Total.Add(s.Total, value)
+	return nil
+}
+
+func sameCurrency(a, b *common.Address) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// writeCSV writes the entries of the statement as CSV, one per line.
+func (s *incomeStatement) writeCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"epoch", "kind", "currency", "amount", "value"}); err != nil {
+		return err
+	}
+	for _, e := range s.Entries {
+		record := []string{fmt.Sprint(e.Epoch), e.Kind, currencyName(e.Currency), e.Amount.String(), e.Value.String()}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func currencyName(address *common.Address) string {
+	if address == nil {
+		return "CELO"
+	}
+	return address.Hex()
+}
+
+// parseReportAddress parses an address flag, nil if empty or CELO.
+func parseReportAddress(ctx *cli.Context, flag cli.StringFlag) (*common.Address, error) {
+	value := ctx.String(flag.Name)
+	if value == "" || strings.EqualFold(value, "CELO") {
+		return nil, nil
+	}
+	if !common.IsHexAddress(value) {
+		return nil, fmt.Errorf("invalid --%s address %q", flag.Name, value)
+	}
+	address := common.HexToAddress(value)
+	return &address, nil
+}
+
+func validatorIncome(ctx *cli.Context) error {
+	validator, err := parseReportAddress(ctx, reportValidatorFlag)
+	if err != nil {
+		return err
+	}
+	if validator == nil {
+		return errors.New("the validator address is required")
+	}
+	feeRecipient, err := parseReportAddress(ctx, reportFeeRecipientFlag)
+	if err != nil {
+		return err
+	}
+	if feeRecipient == nil {
+		feeRecipient = validator
+	}
+	valuedIn, err := parseReportAddress(ctx, reportCurrencyFlag)
+	if err != nil {
+		return err
+	}
+	format := ctx.String(reportFormatFlag.Name)
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("unknown statement format %q", format)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+	defer chain.Stop()
+
+	if chain.Config().Istanbul == nil {
+		return errors.New("no istanbul chain config found")
+	}
+	epochSize := chain.Config().Istanbul.Epoch
+	head := chain.CurrentBlock().NumberU64()
+	fromEpoch, toEpoch := ctx.Uint64(reportFromEpochFlag.Name), ctx.Uint64(reportToEpochFlag.Name)
+	if !ctx.IsSet(reportToEpochFlag.Name) {
+		if toEpoch = istanbul.GetEpochNumber(head, epochSize); !istanbul.IsLastBlockOfEpoch(head, epochSize) && toEpoch > 0 {
+			toEpoch--
+		}
+	}
+	if fromEpoch == 0 || fromEpoch > toEpoch {
+		return fmt.Errorf("invalid epoch range %d-%d", fromEpoch, toEpoch)
+	}
+	first, _ := istanbul.GetEpochFirstBlockNumber(fromEpoch, epochSize)
+	last := istanbul.GetEpochLastBlockNumber(toEpoch, epochSize)
+	if last > head {
+		return fmt.Errorf("epoch %d not complete, the head block is %d", toEpoch, head)
+	}
+	statement := newIncomeStatement(*validator, *feeRecipient, valuedIn, fromEpoch, toEpoch)
+	for number := first; number <= last; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return fmt.Errorf("block %d not found", number)
+		}
+		receipts := chain.GetReceiptsByHash(block.Hash())
+		if !statement.relevant(block, receipts) {
+			continue
+		}
+		parent := chain.GetHeader(block.ParentHash(), number-1)
+		if parent == nil {
+			return fmt.Errorf("parent of block %d not found", number)
+		}
+		// The block was processed with the rates of the state of its parent
+		state, err := chain.StateAt(parent.Root)
+		if err != nil {
+			return fmt.Errorf("state of block %d not available, an archive node is needed for the exchange rates: %v", number-1, err)
+		}
+		header := block.Header()
+		sysCtx := core.NewSysContractCallCtx(chain.Config(), header, state.Copy(), chain)
+		if err := statement.addBlock(istanbul.GetEpochNumber(number, epochSize), block, receipts, sysCtx.GetGasPriceMinimum, chain.NewEVMRunner(header, state)); err != nil {
+			return fmt.Errorf("income of block %d: %w", number, err)
+		}
+	}
+	if format == "json" {
+		out, err := json.MarshalIndent(statement, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	return statement.writeCSV(os.Stdout)
+}
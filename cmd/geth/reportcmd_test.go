@@ -0,0 +1,127 @@
+// Copyright 2021 The celo Authors
+// This file is part of celo.
+//
+// celo is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// celo is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with celo. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core/types"
+)
+
+func TestValidatorIncomeStatement(t *testing.T) {
+	var (
+		celo       = testutil.NewCeloMock()
+		oracles    = testutil.NewSortedOraclesMock()
+		validator  = common.HexToAddress("0xaa")
+		stable     = common.HexToAddress("0x02")
+		validators = common.HexToAddress("0x10")
+		lockedGold = common.HexToAddress("0x11")
+	)
+	// 1 CELO is worth 2 of the stable token
+	oracles.Rates[stable] = [2]*big.Int{big.NewInt(2), big.NewInt(1)}
+	celo.Registry.AddContract(config.SortedOraclesRegistryId, common.HexToAddress("0x06"))
+	celo.Runner.RegisterContract(common.HexToAddress("0x06"), oracles)
+	celo.Registry.AddContract(config.StableTokenRegistryId, stable)
+	celo.Registry.AddContract(config.ValidatorsRegistryId, validators)
+	celo.Registry.AddContract(config.LockedGoldRegistryId, lockedGold)
+
+	gasPriceMinimum := func(feeCurrency *common.Address) *big.Int {
+		if feeCurrency == nil {
+			return big.NewInt(1)
+		}
+		return big.NewInt(2)
+	}
+	txs := types.Transactions{
+		types.NewTx(&types.LegacyTx{Nonce: 0, GasPrice: big.NewInt(3), Gas: 30000}),
+		types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(10), Gas: 30000, FeeCurrency: &stable}),
+	}
+	word := func(n int64) []byte { return common.LeftPadBytes(big.NewInt(n).Bytes(), 32) }
+	receipts := types.Receipts{
+		{GasUsed: 21000},
+		{GasUsed: 21000},
+		{Logs: []*types.Log{
+			{
+				Address: validators,
+				Topics:  []common.Hash{validatorPaymentTopic, common.BytesToHash(validator.Bytes()), {}},
+				Data:    append(word(1000), word(500)...),
+			},
+			{
+				Address: lockedGold,
+				Topics:  []common.Hash{accountSlashedTopic, common.BytesToHash(validator.Bytes()), {}},
+				Data:    append(word(100), word(10)...),
+			},
+			// Payments of other validators, or logged by other contracts, are left out
+			{
+				Address: validators,
+				Topics:  []common.Hash{validatorPaymentTopic, {1}, {}},
+				Data:    append(word(1000), word(500)...),
+			},
+			{
+				Address: common.HexToAddress("0x12"),
+				Topics:  []common.Hash{validatorPaymentTopic, common.BytesToHash(validator.Bytes()), {}},
+				Data:    append(word(1000), word(500)...),
+			},
+		}},
+	}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10), Coinbase: validator}).WithBody(txs, nil, nil)
+
+	statement := newIncomeStatement(validator, validator, nil, 1, 1)
+	if !statement.relevant(block, receipts) {
+		t.Fatalf("block of the validator not relevant")
+	}
+	if err := statement.addBlock(1, block, receipts, gasPriceMinimum, celo.Runner); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	want := []incomeEntry{
+		{Epoch: 1, Kind: incomeTips, Amount: big.NewInt(42000), Value: big.NewInt(42000)},
+		{Epoch: 1, Kind: incomeTips, Currency: &stable, Amount: big.NewInt(168000), Value: big.NewInt(84000)},
+		{Epoch: 1, Kind: incomeEpochReward, Currency: &stable, Amount: big.NewInt(1000), Value: big.NewInt(500)},
+		{Epoch: 1, Kind: incomeSlashing, Amount: big.NewInt(-100), Value: big.NewInt(-100)},
+	}
+	if len(statement.Entries) != len(want) {
+		t.Fatalf("entries mismatch: have %d, want %d", len(statement.Entries), len(want))
+	}
+	for i, entry := range statement.Entries {
+		if entry.Kind != want[i].Kind || !sameCurrency(entry.Currency, want[i].Currency) || entry.Amount.Cmp(want[i].Amount) != 0 || entry.Value.Cmp(want[i].Value) != 0 {
+			t.Errorf("entry %d mismatch: have %v %s %v %v, want %v %s %v %v", i,
+				entry.Kind, currencyName(entry.Currency), entry.Amount, entry.Value,
+				want[i].Kind, currencyName(want[i].Currency), want[i].Amount, want[i].Value)
+		}
+	}
+	if statement.Total.Cmp(big.NewInt(126400)) != 0 {
+		t.Errorf("total mismatch: have %v, want 126400", statement.Total)
+	}
+	// The tips of a block credited to another recipient aren't income
+	other := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(11), Coinbase: common.HexToAddress("0xbb")}).WithBody(txs, nil, nil)
+	if statement.relevant(other, receipts[:2]) {
+		t.Errorf("block of another recipient relevant")
+	}
+	var out bytes.Buffer
+	if err := statement.writeCSV(&out); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(want)+1 || lines[0] != "epoch,kind,currency,amount,value" || lines[4] != "1,slashing,CELO,-100,-100" {
+		t.Errorf("CSV mismatch: have %q", lines)
+	}
+}
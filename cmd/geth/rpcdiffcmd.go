@@ -0,0 +1,272 @@
+// Copyright 2021 The celo Authors
+// This file is part of celo.
+//
+// celo is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// celo is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with celo. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/cmd/utils"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/node"
+	"github.com/celo-org/celo-blockchain/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	testCommand = cli.Command{
+		Name:      "test",
+		Usage:     "Release validation utilities",
+		ArgsUsage: "",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Subcommands: []cli.Command{
+			rpcDiffCommand,
+		},
+	}
+	rpcDiffCommand = cli.Command{
+		Action:    utils.MigrateFlags(rpcDiff),
+		Name:      "rpc-diff",
+		Usage:     "Compare the RPC responses of the local node with a reference endpoint",
+		ArgsUsage: "[<local endpoint>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.BaklavaFlag,
+			utils.AlfajoresFlag,
+			rpcDiffPeerFlag,
+			rpcDiffCorpusFlag,
+			rpcDiffIgnoreFlag,
+		},
+		Description: `This command replays a corpus of RPC queries against the local node (by default
+the IPC endpoint of the data directory) and the reference endpoint given with --peer, and
+reports the queries whose responses differ. Responses are normalized before comparison: hex
+strings are lowercased and the fields given with --ignore are dropped. Errors are equal if
+they have the same code and message.
+
+The corpus is a JSON array of {"method": ..., "params": [...]} objects. The "$block" parameter
+is replaced by the latest block known to both endpoints. If no corpus is given a built-in one
+covering the common eth_ methods is used.`,
+	}
+	rpcDiffPeerFlag = cli.StringFlag{
+		Name:  "peer",
+		Usage: "URL of the reference RPC endpoint",
+	}
+	rpcDiffCorpusFlag = cli.StringFlag{
+		Name:  "corpus",
+		Usage: "JSON file with the RPC queries to replay",
+	}
+	rpcDiffIgnoreFlag = cli.StringFlag{
+		Name:  "ignore",
+		Usage: "Comma separated response fields to ignore",
+		Value: strings.Join(defaultRPCDiffIgnored, ","),
+	}
+)
+
+// rpcQuery is one entry of the rpc-diff corpus.
+type rpcQuery struct {
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// rpcDiffBlockParam is replaced in the query params by the common head block.
+const rpcDiffBlockParam = "$block"
+
+// defaultRPCDiffIgnored are the block fields celo fills with legacy values that
+// other clients (and the L2 endpoints requests get proxied to) report differently.
+var defaultRPCDiffIgnored = []string{"totalDifficulty", "difficulty", "mixHash", "nonce", "sha3Uncles", "uncles", "size"}
+
+var defaultRPCDiffCorpus = []rpcQuery{
+	{Method: "eth_chainId"},
+	{Method: "net_version"},
+	{Method: "eth_getBlockByNumber", Params: []interface{}{"0x0", false}},
+	{Method: "eth_getBlockByNumber", Params: []interface{}{rpcDiffBlockParam, false}},
+	{Method: "eth_getBlockByNumber", Params: []interface{}{rpcDiffBlockParam, true}},
+	{Method: "eth_getBlockTransactionCountByNumber", Params: []interface{}{rpcDiffBlockParam}},
+	{Method: "eth_getBlockReceipts", Params: []interface{}{rpcDiffBlockParam}},
+	{Method: "eth_getCode", Params: []interface{}{"0x000000000000000000000000000000000000ce10", rpcDiffBlockParam}},
+	{Method: "eth_getBalance", Params: []interface{}{"0x000000000000000000000000000000000000ce10", rpcDiffBlockParam}},
+}
+
+func rpcDiff(ctx *cli.Context) error {
+	if ctx.NArg() > 1 {
+		return fmt.Errorf("Max 1 argument: %v", ctx.Command.ArgsUsage)
+	}
+	peerURL := ctx.String(rpcDiffPeerFlag.Name)
+	if peerURL == "" {
+		return fmt.Errorf("missing reference endpoint, set --%s", rpcDiffPeerFlag.Name)
+	}
+	corpus := defaultRPCDiffCorpus
+	if path := ctx.String(rpcDiffCorpusFlag.Name); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &corpus); err != nil {
+			return fmt.Errorf("invalid corpus %s: %v", path, err)
+		}
+	}
+	ignored := make(map[string]bool)
+	for _, field := range strings.Split(ctx.String(rpcDiffIgnoreFlag.Name), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			ignored[field] = true
+		}
+	}
+
+	endpoint := ctx.Args().First()
+	if endpoint == "" {
+		path := node.DefaultDataDir()
+		if ctx.GlobalIsSet(utils.DataDirFlag.Name) {
+			path = ctx.GlobalString(utils.DataDirFlag.Name)
+		}
+		if ctx.GlobalBool(utils.BaklavaFlag.Name) {
+			path = filepath.Join(path, "baklava")
+		} else if ctx.GlobalBool(utils.AlfajoresFlag.Name) {
+			path = filepath.Join(path, "alfajores")
+		}
+		endpoint = fmt.Sprintf("%s/geth.ipc", path)
+	}
+	local, err := dialRPC(endpoint)
+	if err != nil {
+		return fmt.Errorf("unable to attach to the local node: %v", err)
+	}
+	defer local.Close()
+	peer, err := rpc.Dial(peerURL)
+	if err != nil {
+		return fmt.Errorf("unable to attach to the reference endpoint: %v", err)
+	}
+	defer peer.Close()
+
+	// Query the latest block known to both sides, so that head lag doesn't show as diffs
+	var localHead, peerHead hexutil.Uint64
+	if err := callRPC(local, &localHead, "eth_blockNumber"); err != nil {
+		return fmt.Errorf("failed to get the local head: %v", err)
+	}
+	if err := callRPC(peer, &peerHead, "eth_blockNumber"); err != nil {
+		return fmt.Errorf("failed to get the reference head: %v", err)
+	}
+	block := localHead
+	if peerHead < block {
+		block = peerHead
+	}
+
+	diffs := 0
+	for _, query := range corpus {
+		params := make([]interface{}, len(query.Params))
+		for i, param := range query.Params {
+			if param == rpcDiffBlockParam {
+				param = block.String()
+			}
+			params[i] = param
+		}
+		localRes, localErr := queryRPC(local, query.Method, params)
+		peerRes, peerErr := queryRPC(peer, query.Method, params)
+		if sameRPCResult(localRes, localErr, peerRes, peerErr, ignored) {
+			fmt.Printf("OK    %s %v\n", query.Method, params)
+			continue
+		}
+		diffs++
+		fmt.Printf("DIFF  %s %v\n", query.Method, params)
+		fmt.Printf("  local: %s\n", describeRPCResult(localRes, localErr))
+		fmt.Printf("  peer:  %s\n", describeRPCResult(peerRes, peerErr))
+	}
+	if diffs > 0 {
+		return fmt.Errorf("%d of %d queries differ", diffs, len(corpus))
+	}
+	return nil
+}
+
+func callRPC(client *rpc.Client, result interface{}, method string, params ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return client.CallContext(ctx, result, method, params...)
+}
+
+// queryRPC returns the decoded JSON result of the call.
+func queryRPC(client *rpc.Client, method string, params []interface{}) (interface{}, error) {
+	var raw json.RawMessage
+	if err := callRPC(client, &raw, method, params...); err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// normalizeRPCResponse lowercases the hex strings of a decoded JSON response and
+// drops the ignored object fields, recursively.
+func normalizeRPCResponse(value interface{}, ignored map[string]bool) interface{} {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+			return strings.ToLower(v)
+		}
+		return v
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, elem := range v {
+			normalized[i] = normalizeRPCResponse(elem, ignored)
+		}
+		return normalized
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			if !ignored[key] {
+				normalized[key] = normalizeRPCResponse(elem, ignored)
+			}
+		}
+		return normalized
+	default:
+		return v
+	}
+}
+
+// sameRPCResult reports whether both endpoints answered a query alike: with equal
+// normalized responses, or with errors of the same code and message.
+func sameRPCResult(localRes interface{}, localErr error, peerRes interface{}, peerErr error, ignored map[string]bool) bool {
+	if localErr != nil || peerErr != nil {
+		if localErr == nil || peerErr == nil {
+			return false
+		}
+		var localRPCErr, peerRPCErr rpc.Error
+		localCoded, peerCoded := errors.As(localErr, &localRPCErr), errors.As(peerErr, &peerRPCErr)
+		if localCoded != peerCoded || (localCoded && localRPCErr.ErrorCode() != peerRPCErr.ErrorCode()) {
+			return false
+		}
+		return localErr.Error() == peerErr.Error()
+	}
+	return reflect.DeepEqual(normalizeRPCResponse(localRes, ignored), normalizeRPCResponse(peerRes, ignored))
+}
+
+func describeRPCResult(result interface{}, err error) string {
+	if err != nil {
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) {
+			return fmt.Sprintf("error %d: %v", rpcErr.ErrorCode(), err)
+		}
+		return fmt.Sprintf("error: %v", err)
+	}
+	data, _ := json.Marshal(result)
+	return string(data)
+}
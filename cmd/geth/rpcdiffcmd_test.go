@@ -0,0 +1,78 @@
+// Copyright 2021 The celo Authors
+// This file is part of celo.
+//
+// celo is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// celo is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with celo. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeRPCResponse(t *testing.T) {
+	ignored := map[string]bool{"totalDifficulty": true}
+	var local, peer interface{}
+	if err := json.Unmarshal([]byte(`{"hash":"0xABcd","totalDifficulty":"0x1","transactions":[{"to":"0xAA"}]}`), &local); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"hash":"0xabcd","transactions":[{"to":"0xaa"}]}`), &peer); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(normalizeRPCResponse(local, ignored), normalizeRPCResponse(peer, ignored)) {
+		t.Fatal("normalized responses differ")
+	}
+	if err := json.Unmarshal([]byte(`{"hash":"0xabce","transactions":[{"to":"0xaa"}]}`), &peer); err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(normalizeRPCResponse(local, ignored), normalizeRPCResponse(peer, ignored)) {
+		t.Fatal("different responses normalized equal")
+	}
+}
+
+// rpcDiffError is an error with a JSON-RPC error code, like the ones returned by the client.
+type rpcDiffError struct {
+	code int
+	msg  string
+}
+
+func (e rpcDiffError) Error() string  { return e.msg }
+func (e rpcDiffError) ErrorCode() int { return e.code }
+
+func TestSameRPCResult(t *testing.T) {
+	notFound := rpcDiffError{-32601, "the method eth_foo does not exist/is not available"}
+	tests := []struct {
+		name              string
+		localRes, peerRes interface{}
+		localErr, peerErr error
+		same              bool
+	}{
+		{name: "equal results", localRes: "0xAB", peerRes: "0xab", same: true},
+		{name: "different results", localRes: "0x1", peerRes: "0x2"},
+		{name: "local error only", localErr: notFound, peerRes: "0x1"},
+		{name: "peer error only", localRes: "0x1", peerErr: notFound},
+		{name: "equal errors", localErr: notFound, peerErr: notFound, same: true},
+		{name: "different codes", localErr: notFound, peerErr: rpcDiffError{-32000, notFound.msg}},
+		{name: "different messages", localErr: notFound, peerErr: rpcDiffError{notFound.code, "header not found"}},
+		{name: "coded and transport errors", localErr: notFound, peerErr: errors.New(notFound.msg)},
+		{name: "equal transport errors", localErr: errors.New("context deadline exceeded"), peerErr: errors.New("context deadline exceeded"), same: true},
+	}
+	for _, tt := range tests {
+		if same := sameRPCResult(tt.localRes, tt.localErr, tt.peerRes, tt.peerErr, nil); same != tt.same {
+			t.Errorf("%s: have same %v, want %v", tt.name, same, tt.same)
+		}
+	}
+}
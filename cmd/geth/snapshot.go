@@ -45,6 +45,11 @@ var (
 	emptyCode = crypto.Keccak256(nil)
 )
 
+var pruneDryRunFlag = cli.BoolFlag{
+	Name:  "dry-run",
+	Usage: "Verify the pruning target and report the reclaimable space without pruning",
+}
+
 var (
 	snapshotCommand = cli.Command{
 		Name:        "snapshot",
@@ -65,15 +70,24 @@ var (
 					utils.AlfajoresFlag,
 					utils.CacheTrieJournalFlag,
 					utils.BloomFilterSizeFlag,
+					pruneDryRunFlag,
 				},
 				Description: `
 geth snapshot prune-state <state-root>
 will prune historical state data with the help of the state snapshot.
 All trie nodes and contract codes that do not belong to the specified
 version state will be deleted from the database. After pruning, only
-two version states are available: genesis and the specific one.
+two version states are available: genesis and the specific one, along
+with the pinned ones.
+
+The default pruning target is the HEAD-127 state. Before deleting anything,
+the target is verified to cover the whole state of the registry and of the core
+contracts it registers, whose storage the epoch rewards and elections mutate.
+The blocks after the target, with their epoch blocks, are re-executed when the
+node restarts.
 
-The default pruning target is the HEAD-127 state.
+With --dry-run, the target is verified and the state data the pruning would
+delete is reported, leaving the database untouched.
 
 WARNING: It's necessary to delete the trie clean cache after the pruning.
 If you specify another directory for the trie clean cache via "--cache.trie.journal"
@@ -193,6 +207,23 @@ func pruneState(ctx *cli.Context) error {
 			return err
 		}
 	}
+	if ctx.Bool(pruneDryRunFlag.Name) {
+		report, err := pruner.DryRun(targetRoot)
+		if err != nil {
+			log.Error("Failed to verify the pruning target", "err", err)
+			return err
+		}
+		for _, contract := range report.Contracts {
+			log.Info("Core contract state covered", "name", contract.Name, "address", contract.Address, "implementation", contract.Implementation, "nodes", contract.Nodes)
+		}
+		target := []interface{}{"root", report.Root}
+		if report.Number != nil {
+			target = append(target, "number", *report.Number, "epoch", report.Epoch, "epochblocks", report.EpochBlocks)
+		}
+		log.Info("Pruning target verified", target...)
+		log.Info("Reclaimable state data", "nodes", report.Nodes, "size", report.Size)
+		return nil
+	}
 	if err = pruner.Prune(targetRoot); err != nil {
 		log.Error("Failed to prune state", "err", err)
 		return err
@@ -37,6 +37,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.DataDirFlag,
 			utils.AncientFlag,
 			utils.MinFreeDiskSpaceFlag,
+			utils.DatabaseSlowThresholdFlag,
 			utils.KeyStoreDirFlag,
 			utils.USBFlag,
 			utils.NetworkIdFlag,
@@ -44,14 +45,28 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.BaklavaFlag,
 			utils.AlfajoresFlag,
 			utils.SyncModeFlag,
+			utils.SyncCheckpointFlag,
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
 			utils.TxLookupLimitFlag,
+			utils.GasUsageIndexFlag,
+			utils.ExchangeRateIndexFlag,
+			utils.AccountTxIndexFlag,
+			utils.InternalTransferIndexFlag,
+			utils.SyntheticFeeLogsFlag,
+			utils.SafeModeFlag,
 			utils.CeloStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
 			utils.WhitelistFlag,
 			utils.TxFeeRecipientFlag,
+			utils.ConfigCheckStrictFlag,
+			utils.ConfigCheckReportFlag,
+			utils.NTPServerFlag,
+			utils.HeadLagTimeFlag,
+			utils.HeadLagBlocksFlag,
+			utils.HeadLagWebhookFlag,
+			utils.HeadLagProxyFailoverFlag,
 		},
 	},
 	{
@@ -80,8 +95,12 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 		Flags: []cli.Flag{
 			utils.TxPoolLocalsFlag,
 			utils.TxPoolNoLocalsFlag,
+			utils.TxPoolStemRelayFlag,
+			utils.TxPoolStreamTokenFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolRemoteJournalFlag,
+			utils.TxPoolRemoteJournalSizeFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -89,6 +108,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolRateStalenessFlag,
 		},
 	},
 	{
@@ -111,6 +131,9 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.UnlockedAccountFlag,
 			utils.PasswordFileFlag,
 			utils.ExternalSignerFlag,
+			utils.KMSKeysFlag,
+			utils.StorageKeyPasswordFlag,
+			utils.StorageKeyFileFlag,
 			utils.InsecureUnlockAllowedFlag,
 		},
 	},
@@ -142,6 +165,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.RPCGlobalGasPriceMultiplierFlag,
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalTxFeeCapFlag,
+			utils.RPCStateRangeRateFlag,
+			utils.RPCReceiptFeeFieldsFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -159,6 +184,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
+			utils.PeerDiversityMaxFractionFlag,
+			utils.PeerDiversityASNDatabaseFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
 			utils.PingIPFromPacketFlag,
@@ -173,12 +200,26 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.MinerExtraDataFlag,
 			utils.CeloFeeCurrencyDefault,
 			utils.CeloFeeCurrencyLimits,
+			utils.MinerStopOnOutdatedVersionFlag,
+			utils.MinerRecommitBudgetFlag,
+			utils.MinerParallelLanesFlag,
+			utils.MinerLocalGasQuotientFlag,
+			utils.MinerFeeCurrencyReservedGasFlag,
+			utils.MinerRateStalenessFlag,
+			utils.MinerGasLimitOverrideFlag,
+			utils.MinerDenylistFlag,
+			utils.MinerForcedTxsFlag,
+			utils.MinerBlockRelayFlag,
+			utils.MinerPayloadBuilderFlag,
+			utils.MinerBuilderFlag,
+			utils.MinerFeeRecipientsFlag,
 		},
 	},
 	{
 		Name: "VIRTUAL MACHINE",
 		Flags: []cli.Flag{
 			utils.VMEnableDebugFlag,
+			utils.VMSystemCallFastPathFlag,
 		},
 	},
 	{
@@ -195,6 +236,12 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 		Name: "ISTANBUL",
 		Flags: []cli.Flag{
 			utils.IstanbulReplicaFlag,
+			utils.IstanbulRemoteSignerFlag,
+			utils.IstanbulParentSealWaitFlag,
+			utils.IstanbulAdaptiveTimeoutFlag,
+			utils.IstanbulProposerPolicyFlag,
+			utils.IstanbulMessageArchiveFlag,
+			utils.IstanbulMessageArchiveSequencesFlag,
 		},
 	},
 	{
@@ -213,6 +260,10 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.ProxiedFlag,
 			utils.ProxyEnodeURLPairsFlag,
 			utils.ProxyAllowPrivateIPFlag,
+			utils.ProxiedKnownCacheFlag,
+			utils.ProxyHealthCheckPeriodFlag,
+			utils.ProxyHealthCheckFailuresFlag,
+			utils.ProxyHealthCheckMaxLagFlag,
 		},
 	},
 	{
@@ -266,6 +317,10 @@ func init() {
 			for _, flag := range utils.DeprecatedFlags {
 				deprecated[flag.String()] = struct{}{}
 			}
+			// The chaos flags are hidden like the deprecated ones
+			for _, flag := range utils.ChaosFlags {
+				deprecated[flag.String()] = struct{}{}
+			}
 			// Only add uncategorized flags if they are not deprecated
 			var uncategorized []cli.Flag
 			for _, flag := range data.(*cli.App).Flags {
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/consensustest"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/validator"
+	"github.com/celo-org/celo-blockchain/contracts/election"
+	"github.com/celo-org/celo-blockchain/contracts/epoch_rewards"
+	"github.com/celo-org/celo-blockchain/contracts/gold_token"
+	"github.com/celo-org/celo-blockchain/contracts/validators"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/mycelo/env"
+	"github.com/celo-org/celo-blockchain/mycelo/genesis"
+	"github.com/celo-org/celo-blockchain/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var benchCommand = cli.Command{
+	Name:  "bench",
+	Usage: "Benchmarks protocol operations on synthetic chains",
+	Subcommands: []cli.Command{
+		benchEpochCommand,
+	},
+}
+
+var benchEpochCommand = cli.Command{
+	Name:      "epoch",
+	Usage:     "Measures the processing time of an epoch block for a given validator set",
+	ArgsUsage: "",
+	Action:    benchEpoch,
+	Flags: append([]cli.Flag{
+		buildpathFlag,
+		cli.IntFlag{
+			Name:  "validators.pergroup",
+			Usage: "Number of validators in every group",
+			Value: 5,
+		},
+		cli.StringFlag{
+			Name:  "votes",
+			Usage: "Distribution of the votes across groups (uniform, linear, zipf)",
+			Value: "uniform",
+		},
+		cli.IntFlag{
+			Name:  "runs",
+			Usage: "Number of times the epoch block is processed",
+			Value: 5,
+		},
+	}, templateFlags...),
+	Description: `
+Generates a genesis with the given number of validators, grouped by --validators.pergroup, and
+groups voting with the given distribution, then processes the last block of the first epoch on
+top of it, like validators do, and prints how long every step took on each run as CSV:
+  - scores: the validator score updates from the epoch uptimes (all validators fully up)
+  - validatorRewards: the target epoch rewards and the validator payments
+  - voterRewards: the group rewards distributed to voters
+  - election: the election of the next validator set
+  - valsetDiff: the diff between the current and the next validator set
+Durations are in milliseconds. Use it to find the scaling limits of the epoch block before
+raising the validator set size through governance.
+
+The vote distributions give every group its locked gold times a weight: 1 for all groups
+(uniform), decreasing from the number of groups to 1 (linear), or the number of groups
+divided by the rank of the group (zipf).`,
+}
+
+// benchEngine is the consensus engine of the synthetic chain, it reports the
+// genesis validators and the epoch size to the EVM precompiles.
+type benchEngine struct {
+	*consensustest.MockEngine
+	epochSize  uint64
+	validators []istanbul.Validator
+}
+
+func (e *benchEngine) EpochSize() uint64 { return e.epochSize }
+
+func (e *benchEngine) GetValidators(*big.Int, common.Hash) []istanbul.Validator {
+	return e.validators
+}
+
+// groupVoteWeights returns the weight of the votes of every group for the given
+// distribution.
+func groupVoteWeights(distribution string, groups int) ([]uint64, error) {
+	weights := make([]uint64, groups)
+	for i := range weights {
+		switch distribution {
+		case "uniform":
+			weights[i] = 1
+		case "linear":
+			weights[i] = uint64(groups - i)
+		case "zipf":
+			weights[i] = uint64(groups / (i + 1))
+			if weights[i] == 0 {
+				weights[i] = 1
+			}
+		default:
+			return nil, fmt.Errorf("unknown vote distribution %q", distribution)
+		}
+	}
+	return weights, nil
+}
+
+// configureEpochBench groups the validators and sets the group votes of the
+// synthetic chain, electing all the validators.
+func configureEpochBench(accounts *env.AccountsConfig, genesisConfig *genesis.Config, validatorsPerGroup int, distribution string) error {
+	accounts.ValidatorsPerGroup = validatorsPerGroup
+	if accounts.ValidatorsPerGroup > accounts.NumValidators {
+		accounts.ValidatorsPerGroup = accounts.NumValidators
+	}
+	if genesisConfig.Validators.MaxGroupSize < uint64(accounts.ValidatorsPerGroup) {
+		genesisConfig.Validators.MaxGroupSize = uint64(accounts.ValidatorsPerGroup)
+	}
+	if genesisConfig.Election.MaxElectableValidators < uint64(accounts.NumValidators) {
+		genesisConfig.Election.MaxElectableValidators = uint64(accounts.NumValidators)
+	}
+	weights, err := groupVoteWeights(distribution, len(accounts.ValidatorGroupAccounts()))
+	if err != nil {
+		return err
+	}
+	genesisConfig.Election.GroupVoteWeights = weights
+	return nil
+}
+
+func benchEpoch(ctx *cli.Context) error {
+	workdir, err := os.MkdirTemp("", "mycelo-bench")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workdir)
+
+	env, genesisConfig, err := envFromTemplate(ctx, workdir)
+	if err != nil {
+		return err
+	}
+	accounts := env.Accounts()
+	if err := configureEpochBench(accounts, genesisConfig, ctx.Int("validators.pergroup"), ctx.String("votes")); err != nil {
+		return err
+	}
+
+	buildpath, err := readBuildPath(ctx)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(os.Stdout)
+	if err := runEpochBench(w, accounts, genesisConfig, buildpath, ctx.Int("runs")); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// runEpochBench generates the genesis of the synthetic chain with the contracts
+// of buildpath, and writes the timings of processing its first epoch block the
+// given number of times to w.
+func runEpochBench(w *csv.Writer, accounts *env.AccountsConfig, genesisConfig *genesis.Config, buildpath string, runs int) error {
+	generatedGenesis, err := genesis.GenerateGenesis(accounts, genesisConfig, buildpath)
+	if err != nil {
+		return err
+	}
+
+	// Set up the synthetic chain, on top of which the epoch block is processed
+	db := rawdb.NewMemoryDatabase()
+	genesisBlock := generatedGenesis.MustCommit(db)
+
+	valSet := make([]istanbul.ValidatorData, 0, accounts.NumValidators)
+	for _, account := range accounts.ValidatorAccounts() {
+		blsPublicKey, err := account.BLSPublicKey()
+		if err != nil {
+			return err
+		}
+		valSet = append(valSet, istanbul.ValidatorData{Address: account.Address, BLSPublicKey: blsPublicKey})
+	}
+	engine := &benchEngine{
+		MockEngine: consensustest.NewFaker(),
+		epochSize:  genesisConfig.Istanbul.Epoch,
+		validators: validator.NewSet(valSet).List(),
+	}
+	chain, err := core.NewBlockChain(db, nil, generatedGenesis.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer chain.Stop()
+
+	header := &types.Header{
+		ParentHash: genesisBlock.Hash(),
+		Number:     new(big.Int).SetUint64(genesisConfig.Istanbul.Epoch),
+		GasLimit:   genesisBlock.GasLimit(),
+		Time:       genesisBlock.Time() + genesisConfig.Istanbul.Epoch*genesisConfig.Istanbul.BlockPeriod,
+		Coinbase:   accounts.ValidatorAccounts()[0].Address,
+	}
+
+	if err := w.Write([]string{"run", "validators", "groups", "elected", "scores", "validatorRewards", "voterRewards", "election", "valsetDiff", "total"}); err != nil {
+		return err
+	}
+	groups := len(accounts.ValidatorGroupAccounts())
+	for run := 0; run < runs; run++ {
+		statedb, err := chain.StateAt(genesisBlock.Root())
+		if err != nil {
+			return err
+		}
+		timings, elected, err := processEpochBlock(db, chain, header, statedb, engine.validators, valSet)
+		if err != nil {
+			return err
+		}
+		var total time.Duration
+		row := []string{fmt.Sprint(run), fmt.Sprint(accounts.NumValidators), fmt.Sprint(groups), fmt.Sprint(elected)}
+		for _, timing := range timings {
+			total += timing
+			row = append(row, formatMillis(timing))
+		}
+		if err := w.Write(append(row, formatMillis(total))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processEpochBlock runs the epoch block steps of the istanbul engine on the
+// given state, returning how long each of them took and the size of the newly
+// elected validator set.
+func processEpochBlock(db ethdb.KeyValueReader, chain *core.BlockChain, header *types.Header, statedb *state.StateDB, valSet []istanbul.Validator, valSetData []istanbul.ValidatorData) ([]time.Duration, int, error) {
+	vmRunner := chain.NewEVMRunner(header, statedb)
+	if err := gold_token.SetInitialTotalSupplyIfUnset(db, vmRunner); err != nil {
+		return nil, 0, err
+	}
+	var (
+		timings []time.Duration
+		start   = time.Now()
+		lap     = func() {
+			timings = append(timings, time.Since(start))
+			start = time.Now()
+		}
+	)
+	// Validator scores, every validator is considered up for the whole epoch
+	uptimes := make([]*big.Int, len(valSet))
+	for i, val := range valSet {
+		uptimes[i] = params.Fixidity1
+		if err := validators.UpdateValidatorScore(vmRunner, val.Address(), uptimes[i]); err != nil {
+			return nil, 0, fmt.Errorf("failed to update score of %s: %w", val.Address().Hex(), err)
+		}
+	}
+	lap()
+
+	// Validator rewards
+	if err := epoch_rewards.UpdateTargetVotingYield(vmRunner); err != nil {
+		return nil, 0, err
+	}
+	validatorReward, totalVoterRewards, _, _, err := epoch_rewards.CalculateTargetEpochRewards(vmRunner)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, val := range valSet {
+		if _, err := validators.DistributeEpochReward(vmRunner, val.Address(), validatorReward); err != nil {
+			return nil, 0, fmt.Errorf("failed to distribute reward of %s: %w", val.Address().Hex(), err)
+		}
+	}
+	lap()
+
+	// Voter rewards, for the groups that elected at least one validator
+	var groups []common.Address
+	groupUptimes := make(map[common.Address][]*big.Int)
+	for i, val := range valSet {
+		group, err := validators.GetMembershipInLastEpoch(vmRunner, val.Address())
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, ok := groupUptimes[group]; !ok {
+			groups = append(groups, group)
+		}
+		groupUptimes[group] = append(groupUptimes[group], uptimes[i])
+	}
+	if _, err := election.DistributeEpochRewards(vmRunner, groups, totalVoterRewards, groupUptimes); err != nil {
+		return nil, 0, err
+	}
+	lap()
+
+	// Election of the next validator set
+	electedAddresses, err := election.GetElectedValidators(vmRunner)
+	if err != nil {
+		return nil, 0, err
+	}
+	elected, err := validators.GetValidatorData(vmRunner, electedAddresses)
+	if err != nil {
+		return nil, 0, err
+	}
+	lap()
+
+	// Validator set diff written in the epoch block header
+	istanbul.ValidatorSetDiff(valSetData, elected)
+	lap()
+
+	return timings, len(elected), nil
+}
+
+func formatMillis(d time.Duration) string {
+	return fmt.Sprintf("%.3f", float64(d)/float64(time.Millisecond))
+}
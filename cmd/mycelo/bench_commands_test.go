@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestGroupVoteWeights(t *testing.T) {
+	tests := []struct {
+		distribution string
+		want         []uint64
+	}{
+		{"uniform", []uint64{1, 1, 1, 1, 1}},
+		{"linear", []uint64{5, 4, 3, 2, 1}},
+		{"zipf", []uint64{5, 2, 1, 1, 1}},
+	}
+	for _, tt := range tests {
+		weights, err := groupVoteWeights(tt.distribution, 5)
+		if err != nil {
+			t.Fatalf("%s: failed to compute weights: %v", tt.distribution, err)
+		}
+		if !reflect.DeepEqual(weights, tt.want) {
+			t.Errorf("%s: weights mismatch: have %v, want %v", tt.distribution, weights, tt.want)
+		}
+	}
+	if _, err := groupVoteWeights("pareto", 5); err == nil {
+		t.Error("unknown distribution accepted")
+	}
+}
+
+func TestRunEpochBench(t *testing.T) {
+	const buildpath = "../../compiled-system-contracts"
+	if _, err := os.Stat(buildpath); err != nil {
+		t.Skipf("no compiled system contracts in %s, run 'make prepare-system-contracts': %v", buildpath, err)
+	}
+	template := templateFromString("local")
+	env, err := template.createEnv(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create env: %v", err)
+	}
+	accounts := env.Accounts()
+	accounts.NumValidators = 6
+	genesisConfig, err := template.createGenesisConfig(env, nil)
+	if err != nil {
+		t.Fatalf("failed to create genesis config: %v", err)
+	}
+	if err := configureEpochBench(accounts, genesisConfig, 2, "linear"); err != nil {
+		t.Fatalf("failed to configure the bench: %v", err)
+	}
+
+	var out bytes.Buffer
+	w := csv.NewWriter(&out)
+	if err := runEpochBench(w, accounts, genesisConfig, buildpath, 2); err != nil {
+		t.Fatalf("failed to run the bench: %v", err)
+	}
+	w.Flush()
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("rows mismatch: have %d, want the header and 2 runs", len(rows))
+	}
+	for i, row := range rows[1:] {
+		want := []string{fmt.Sprint(i), "6", "3", "6"}
+		if len(row) != len(rows[0]) || !reflect.DeepEqual(row[:4], want) {
+			t.Errorf("run %d mismatch: have %v, want it to start with %v", i, row, want)
+		}
+	}
+}
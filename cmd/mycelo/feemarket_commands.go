@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var feeMarketSimCommand = cli.Command{
+	Name:      "feemarket-sim",
+	Usage:     "Simulates the per fee currency base fee multipliers of the experimental multi fee market",
+	ArgsUsage: "[scenario.json]",
+	Action:    feeMarketSim,
+	Description: `
+Reads a scenario with the multi fee market configuration, the block gas limit and the gas used
+by every fee currency on each block, and prints the base fee multipliers (1 = gas price minimum)
+of every currency after each block as CSV. CELO is keyed by the zero address.
+
+Example scenario:
+{
+  "config": {"targetShareBps": 2500, "changeDenominator": 8},
+  "gasLimit": 35000000,
+  "blocks": [{"0x0000000000000000000000000000000000000000": 20000000, "0x765DE816845861e75A25fCA122bb6898B8B1282a": 12000000}]
+}`,
+}
+
+// feeMarketScenario is the input of feemarket-sim.
+type feeMarketScenario struct {
+	Config   params.MultiFeeMarketConfig `json:"config"`
+	GasLimit uint64                      `json:"gasLimit"`
+	Blocks   []map[common.Address]uint64 `json:"blocks"`
+}
+
+func feeMarketSim(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("expected a scenario file")
+	}
+	data, err := os.ReadFile(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	var scenario feeMarketScenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return err
+	}
+
+	currencySet := make(map[common.Address]struct{})
+	for _, block := range scenario.Blocks {
+		for currency := range block {
+			currencySet[currency] = struct{}{}
+		}
+	}
+	currencies := make([]common.Address, 0, len(currencySet))
+	for currency := range currencySet {
+		currencies = append(currencies, currency)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Hex() < currencies[j].Hex() })
+
+	multipliers := make(map[common.Address]*big.Int, len(currencies))
+	header := []string{"block"}
+	for _, currency := range currencies {
+		multipliers[currency] = core.MultiFeeMarketUnit
+		header = append(header, currency.Hex())
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	unit := new(big.Float).SetInt(core.MultiFeeMarketUnit)
+	for i, block := range scenario.Blocks {
+		row := []string{fmt.Sprint(i)}
+		for _, currency := range currencies {
+			multipliers[currency] = core.NextFeeCurrencyMultiplier(&scenario.Config, multipliers[currency], block[currency], scenario.GasLimit)
+			multiplier := new(big.Float).Quo(new(big.Float).SetInt(multipliers[currency]), unit)
+			row = append(row, multiplier.Text('f', 6))
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
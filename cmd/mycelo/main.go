@@ -61,6 +61,8 @@ func init() {
 		// runNodesCommand,
 		loadBotCommand,
 		envCommand,
+		feeMarketSimCommand,
+		benchCommand,
 	}
 	cli.CommandHelpTemplate = flags.OriginCommandHelpTemplate
 }
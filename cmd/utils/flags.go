@@ -18,6 +18,7 @@
 package utils
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"fmt"
 	"io"
@@ -38,10 +39,12 @@ import (
 	"github.com/celo-org/celo-blockchain/common/fdlimit"
 	mockEngine "github.com/celo-org/celo-blockchain/consensus/consensustest"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/validator"
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
 	"github.com/celo-org/celo-blockchain/core/vm"
 	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/crypto/atrest"
 	"github.com/celo-org/celo-blockchain/eth"
 	"github.com/celo-org/celo-blockchain/eth/downloader"
 	"github.com/celo-org/celo-blockchain/eth/ethconfig"
@@ -54,6 +57,7 @@ import (
 	"github.com/celo-org/celo-blockchain/les"
 	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/metrics"
+	"github.com/celo-org/celo-blockchain/metrics/alerts"
 	"github.com/celo-org/celo-blockchain/metrics/exp"
 	"github.com/celo-org/celo-blockchain/metrics/influxdb"
 	"github.com/celo-org/celo-blockchain/miner"
@@ -119,6 +123,10 @@ var (
 		Name:  "datadir.minfreedisk",
 		Usage: "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
 	}
+	DatabaseSlowThresholdFlag = cli.DurationFlag{
+		Name:  "datadir.slowthreshold",
+		Usage: "Duration above which database operations are logged as slow (0 = disabled)",
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -199,6 +207,10 @@ var (
 		Usage: `Blockchain sync mode ("fast", "full", "light", or "lightest")`,
 		Value: &defaultSyncMode,
 	}
+	SyncCheckpointFlag = cli.StringFlag{
+		Name:  "syncmode.checkpoint",
+		Usage: "Epoch snapshots file exported with 'geth istanbul snapshot export', from the genesis or snapshots imported before, whose last epoch block a new node fast syncs from instead of the genesis",
+	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
@@ -213,6 +225,30 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: ethconfig.Defaults.TxLookupLimit,
 	}
+	GasUsageIndexFlag = cli.BoolFlag{
+		Name:  "gasusageindex",
+		Usage: "Index the gas used per contract in every epoch, served by celo_gasUsageByContract",
+	}
+	ExchangeRateIndexFlag = cli.BoolFlag{
+		Name:  "exchangerateindex",
+		Usage: "Index the exchange rates of the whitelisted currencies at every block, served by celo_exchangeRateAt",
+	}
+	AccountTxIndexFlag = cli.BoolFlag{
+		Name:  "accounttxindex",
+		Usage: "Index the transactions sent and received by every account, served by eth_getTransactionsByAddress",
+	}
+	InternalTransferIndexFlag = cli.BoolFlag{
+		Name:  "internaltransferindex",
+		Usage: "Index the CELO transfers made by the blocks imported from now on, served by celo_getInternalTransfers",
+	}
+	SyntheticFeeLogsFlag = cli.BoolFlag{
+		Name:  "syntheticfeelogs",
+		Usage: "Add logs of the gas fee debits and credits from 0x0000000000000000000000000000000000000fee to the receipts of the blocks processed from now on (counted in the log indexes)",
+	}
+	SafeModeFlag = cli.BoolFlag{
+		Name:  "safemode",
+		Usage: "Start read-only, without syncing, accepting transactions or validating, if the chain database is corrupted",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -248,6 +284,39 @@ var (
 		Usage: "Block number at which to halt the network for Celo L2 migration. This is the first block of Celo as an L2, and one after the last block of Celo as an L1. If unset or set to 0, no halt will occur.",
 	}
 
+	// Startup chain config consistency checks
+	ConfigCheckStrictFlag = cli.BoolFlag{
+		Name:  "configcheck.strict",
+		Usage: "Refuse to start if the chain config, genesis and on-chain minimum client version are inconsistent (default = warn only)",
+	}
+	ConfigCheckReportFlag = cli.StringFlag{
+		Name:  "configcheck.report",
+		Usage: "File to write the startup chain config consistency report to, as JSON",
+	}
+	NTPServerFlag = cli.StringFlag{
+		Name:  "ntp.server",
+		Usage: "NTP server to check the clock skew against (empty = only against the block timestamps)",
+		Value: ethconfig.Defaults.NTPServer,
+	}
+	HeadLagTimeFlag = cli.DurationFlag{
+		Name:  "headlag.time",
+		Usage: "Lag of the chain head behind the local clock raising the head lag alarm of elected validators",
+		Value: ethconfig.Defaults.HeadLagTimeThreshold,
+	}
+	HeadLagBlocksFlag = cli.Uint64Flag{
+		Name:  "headlag.blocks",
+		Usage: "Number of blocks behind the best peer head raising the head lag alarm of elected validators",
+		Value: ethconfig.Defaults.HeadLagBlockThreshold,
+	}
+	HeadLagWebhookFlag = cli.StringFlag{
+		Name:  "headlag.webhook",
+		Usage: "URL the head lag alarms are posted to as JSON",
+	}
+	HeadLagProxyFailoverFlag = cli.BoolFlag{
+		Name:  "headlag.proxyfailover",
+		Usage: "Removes the proxies lagging behind the others when the head lag alarm lasts",
+	}
+
 	BloomFilterSizeFlag = cli.Uint64Flag{
 		Name:  "bloomfilter.size",
 		Usage: "Megabytes of memory allocated to bloom-filter for pruning",
@@ -314,6 +383,14 @@ var (
 		Name:  "txpool.nolocals",
 		Usage: "Disables price exemptions for locally submitted transactions",
 	}
+	TxPoolStemRelayFlag = cli.BoolFlag{
+		Name:  "txpool.stemrelay",
+		Usage: "Relays locally submitted transactions through a single random peer before broadcasting them, hiding their origin",
+	}
+	TxPoolStreamTokenFlag = cli.StringFlag{
+		Name:  "txpool.streamtoken",
+		Usage: "Secret enabling the txpool_subscribe pending transactions stream for the subscribers presenting it",
+	}
 	TxPoolJournalFlag = cli.StringFlag{
 		Name:  "txpool.journal",
 		Usage: "Disk journal for local transaction to survive node restarts",
@@ -324,6 +401,16 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolRemoteJournalFlag = cli.StringFlag{
+		Name:  "txpool.remotejournal",
+		Usage: "Disk journal for remote transactions paying fees in whitelisted currencies to survive node restarts (disabled if empty)",
+		Value: core.DefaultTxPoolConfig.RemoteJournal,
+	}
+	TxPoolRemoteJournalSizeFlag = cli.Uint64Flag{
+		Name:  "txpool.remotejournalsize",
+		Usage: "Maximum number of transactions in the remote transaction journal",
+		Value: core.DefaultTxPoolConfig.RemoteJournalSize,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -359,6 +446,11 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: ethconfig.Defaults.TxPool.Lifetime,
 	}
+	TxPoolRateStalenessFlag = cli.DurationFlag{
+		Name:  "txpool.ratestaleness",
+		Usage: "Maximum age of the exchange rate of a currency for its transactions to be pending, or to replace ones paid in another currency (0 = no bound)",
+		Value: ethconfig.Defaults.TxPool.RateStaleness,
+	}
 
 	// Performance tuning settings
 
@@ -430,6 +522,58 @@ var (
 		Name:  "celo.feecurrency.limits",
 		Usage: "Comma separated currency address-to-block percentage mappings (<address>=<fraction>)",
 	}
+	MinerRecommitBudgetFlag = cli.DurationFlag{
+		Name:  "miner.recommitbudget",
+		Usage: "Maximum time spent applying transactions to a block before sealing it partially filled (0 = unbounded)",
+	}
+	MinerParallelLanesFlag = cli.BoolFlag{
+		Name:  "miner.parallellanes",
+		Usage: "Apply the transactions of each fee currency in parallel, falling back to serial execution on conflicts",
+	}
+	MinerLocalGasQuotientFlag = cli.Float64Flag{
+		Name:  "miner.localgasquotient",
+		Usage: "Fraction of the block gas limit reserved for local transactions, which bundles can't use (0-1)",
+	}
+	MinerFeeCurrencyReservedGasFlag = cli.BoolFlag{
+		Name:  "miner.feecurrency.reservedgas",
+		Usage: "Charge the fee currency gas limits the gas limit of the transactions instead of the gas they used, fitting fewer transactions that overestimate their gas",
+	}
+	MinerRateStalenessFlag = cli.DurationFlag{
+		Name:  "miner.ratestaleness",
+		Usage: "Maximum age of the exchange rate of a currency for its transactions to be included in the blocks built (0 = no bound)",
+	}
+	MinerGasLimitOverrideFlag = cli.Uint64Flag{
+		Name:  "miner.gaslimit-override",
+		Usage: "Gas limit cap of the blocks built while not validating, such as the pending block, skipping the lookup of the on chain gas limit (0 = no cap)",
+	}
+	MinerDenylistFlag = cli.StringFlag{
+		Name:  "miner.denylist",
+		Usage: "JSON file of the addresses and contract code hashes left out of the blocks built ({\"addresses\": [...], \"codeHashes\": [...]})",
+	}
+	MinerForcedTxsFlag = cli.StringFlag{
+		Name:  "miner.forcedtxs",
+		Usage: "JSON file of the transaction templates signed by local accounts and placed at the top of every block built",
+	}
+	MinerBlockRelayFlag = cli.BoolFlag{
+		Name:  "miner.blockrelay",
+		Usage: "Accept block candidates from trusted external builders through the private miner API, re-executing their transactions in the blocks proposed",
+	}
+	MinerPayloadBuilderFlag = cli.BoolFlag{
+		Name:  "miner.payloadbuilder",
+		Usage: "Build the blocks of external validators through the engine API (engine_forkchoiceUpdated, engine_getPayload)",
+	}
+	MinerBuilderFlag = cli.StringFlag{
+		Name:  "miner.builder",
+		Usage: "Engine API endpoint of an external builder supplying the blocks proposed, executed before being sealed (falls back on local building)",
+	}
+	MinerFeeRecipientsFlag = cli.StringFlag{
+		Name:  "miner.feerecipients",
+		Usage: "Comma separated weighted tx fee recipients taking turns as the coinbase of the blocks built (<address>:<weight>)",
+	}
+	MinerStopOnOutdatedVersionFlag = cli.BoolFlag{
+		Name:  "miner.stoponoutdatedversion",
+		Usage: "Stop proposing blocks while the client version is below the minimum client version set on chain",
+	}
 
 	// Account settings
 
@@ -448,10 +592,26 @@ var (
 		Usage: "External signer (url or path to ipc file)",
 		Value: "",
 	}
+	KMSKeysFlag = cli.StringFlag{
+		Name:  "signer.kms",
+		Usage: "Comma separated URLs of the keys held by a KMS or HSM to sign transactions with (<provider>://<key>)",
+	}
+	StorageKeyPasswordFlag = cli.StringFlag{
+		Name:  "storage.password",
+		Usage: "Password file to derive the key encrypting the round states and randomness commitments from",
+	}
+	StorageKeyFileFlag = cli.StringFlag{
+		Name:  "storage.keyfile",
+		Usage: "File of the hex key encrypting the round states and randomness commitments, such as one unwrapped by a KMS",
+	}
 	VMEnableDebugFlag = cli.BoolFlag{
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	VMSystemCallFastPathFlag = cli.BoolFlag{
+		Name:  "vm.syscallfastpath",
+		Usage: "Serve the read only system contract calls, like the block gas limit, gas price minimums and fee currency whitelist, from their last outputs while the storage they read is unchanged (experimental)",
+	}
 	InsecureUnlockAllowedFlag = cli.BoolFlag{
 		Name:  "allow-insecure-unlock",
 		Usage: "Allow insecure account unlocking when account-related RPCs are exposed by http",
@@ -476,6 +636,15 @@ var (
 		Usage: "Sets a cap on transaction fee (in celo) that can be sent via the RPC APIs (0 = no cap)",
 		Value: ethconfig.Defaults.RPCTxFeeCap,
 	}
+	RPCStateRangeRateFlag = cli.Uint64Flag{
+		Name:  "rpc.staterangerate",
+		Usage: "Sets a cap on the accounts and storage slots served per second by the state range debug APIs (0 = no cap)",
+		Value: ethconfig.Defaults.RPCStateRangeRate,
+	}
+	RPCReceiptFeeFieldsFlag = cli.BoolFlag{
+		Name:  "rpc.receiptfeefields",
+		Usage: "Adds the fee currency, the base fee in the fee currency and the fee converted to CELO to the transaction receipts returned by the RPC APIs",
+	}
 	// Logging and debug settings
 
 	CeloStatsURLFlag = cli.StringFlag{
@@ -647,6 +816,14 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	PeerDiversityMaxFractionFlag = cli.Float64Flag{
+		Name:  "peerdiversity.maxfraction",
+		Usage: "Maximum fraction of the dialed peers in a single /16 subnet, autonomous system or country (0 = no limit)",
+	}
+	PeerDiversityASNDatabaseFlag = cli.StringFlag{
+		Name:  "peerdiversity.asndb",
+		Usage: "IP to ASN database (iptoasn.com TSV format) used to bucket peers by autonomous system and country",
+	}
 	PingIPFromPacketFlag = cli.BoolFlag{
 		Name:  "ping-ip-from-packet",
 		Usage: "Has the discovery protocol use the IP address given by a ping packet",
@@ -784,6 +961,32 @@ var (
 		Name:  "istanbul.replica",
 		Usage: "Run this node as a validator replica. Must be paired with --mine. Use the RPCs to enable participation in consensus.",
 	}
+	IstanbulRemoteSignerFlag = cli.StringFlag{
+		Name:  "istanbul.remotesigner",
+		Usage: "Endpoint of the service signing with the validator and BLS keys, such as an HSM, instead of the keystore",
+	}
+	IstanbulParentSealWaitFlag = cli.Uint64Flag{
+		Name:  "istanbul.parentsealwait",
+		Usage: "Maximum extra time (in milliseconds) to wait for more parent block signatures when proposing, improving the uptime scores of slow validators (0 = disabled)",
+		Value: ethconfig.Defaults.Istanbul.ParentSealExtraWait,
+	}
+	IstanbulAdaptiveTimeoutFlag = cli.BoolFlag{
+		Name:  "istanbul.adaptivetimeout",
+		Usage: "Adapt the base round timeout to the observed consensus latency, between half and twice the configured request timeout",
+	}
+	IstanbulProposerPolicyFlag = cli.StringFlag{
+		Name:  "istanbul.proposer",
+		Usage: "Proposer selection policy overriding the genesis config on private networks (" + strings.Join(validator.ProposerPolicyNames(), ", ") + ")",
+	}
+	IstanbulMessageArchiveFlag = cli.BoolFlag{
+		Name:  "istanbul.messagearchive",
+		Usage: "Archive the consensus messages received, to replay them with geth istanbul replay",
+	}
+	IstanbulMessageArchiveSequencesFlag = cli.Uint64Flag{
+		Name:  "istanbul.messagearchive.sequences",
+		Usage: "Number of last sequences kept in the consensus message archive (0 = all)",
+		Value: ethconfig.Defaults.Istanbul.MessageArchiveSequences,
+	}
 
 	// Announce settings
 
@@ -823,10 +1026,30 @@ var (
 		Name:  "proxy.proxyenodeurlpairs",
 		Usage: "Each enode URL in a pair is separated by a semicolon. Enode URL pairs are separated by a space. The format should be \"<proxy 0 internal facing enode URL>;<proxy 0 external facing enode URL>,<proxy 1 internal facing enode URL>;<proxy 1 external facing enode URL>,...\"",
 	}
+	ProxiedKnownCacheFlag = cli.Uint64Flag{
+		Name:  "proxy.knowncache",
+		Usage: "Megabytes of memory allocated to deduplicating the transactions and blocks received through several proxies or peers (0 = disabled)",
+		Value: ethconfig.Defaults.ProxiedKnownCache,
+	}
 	ProxyAllowPrivateIPFlag = cli.BoolFlag{
 		Name:  "proxy.allowprivateip",
 		Usage: "Specifies whether private IP is allowed for external facing proxy enodeURL",
 	}
+	ProxyHealthCheckPeriodFlag = cli.Uint64Flag{
+		Name:  "proxy.healthcheckperiod",
+		Usage: "Time duration (in seconds) between health checks of the proxies (0 = disabled)",
+		Value: ethconfig.Defaults.Istanbul.ProxyHealthCheckPeriod,
+	}
+	ProxyHealthCheckFailuresFlag = cli.Uint64Flag{
+		Name:  "proxy.healthcheckfailures",
+		Usage: "Number of consecutive failed health checks after which the validators assigned to a proxy fail over to the other proxies",
+		Value: ethconfig.Defaults.Istanbul.ProxyHealthCheckFailures,
+	}
+	ProxyHealthCheckMaxLagFlag = cli.Uint64Flag{
+		Name:  "proxy.healthcheckmaxlag",
+		Usage: "Number of blocks the head of a proxy may lag behind the head of the validator before failing its health checks",
+		Value: ethconfig.Defaults.Istanbul.ProxyHealthCheckMaxLag,
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -1314,6 +1537,12 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	if ctx.GlobalIsSet(PeerDiversityMaxFractionFlag.Name) {
+		cfg.PeerDiversityMaxFraction = ctx.GlobalFloat64(PeerDiversityMaxFractionFlag.Name)
+	}
+	if ctx.GlobalIsSet(PeerDiversityASNDatabaseFlag.Name) {
+		cfg.PeerDiversityASNDatabase = ctx.GlobalString(PeerDiversityASNDatabaseFlag.Name)
+	}
 
 	if ctx.GlobalBool(DeveloperFlag.Name) {
 		// --dev mode can't use p2p networking.
@@ -1335,9 +1564,15 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 
+	if ctx.GlobalIsSet(DatabaseSlowThresholdFlag.Name) {
+		cfg.DatabaseSlowThreshold = ctx.GlobalDuration(DatabaseSlowThresholdFlag.Name)
+	}
 	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
 		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
 	}
+	if ctx.GlobalIsSet(KMSKeysFlag.Name) {
+		cfg.KMSKeys = SplitAndTrim(ctx.GlobalString(KMSKeysFlag.Name))
+	}
 
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
@@ -1412,6 +1647,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.RemoteJournal = ctx.GlobalString(TxPoolRemoteJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalSizeFlag.Name) {
+		cfg.RemoteJournalSize = ctx.GlobalUint64(TxPoolRemoteJournalSizeFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1433,6 +1674,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolRateStalenessFlag.Name) {
+		cfg.RateStaleness = ctx.GlobalDuration(TxPoolRateStalenessFlag.Name)
+	}
 }
 
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
@@ -1474,6 +1718,69 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 			cfg.FeeCurrencyLimits[address] = fraction
 		}
 	}
+
+	if ctx.GlobalIsSet(MinerStopOnOutdatedVersionFlag.Name) {
+		cfg.StopOnOutdatedVersion = ctx.GlobalBool(MinerStopOnOutdatedVersionFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerRecommitBudgetFlag.Name) {
+		cfg.RecommitBudget = ctx.GlobalDuration(MinerRecommitBudgetFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerParallelLanesFlag.Name) {
+		cfg.ParallelLanes = ctx.GlobalBool(MinerParallelLanesFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerLocalGasQuotientFlag.Name) {
+		quotient := ctx.GlobalFloat64(MinerLocalGasQuotientFlag.Name)
+		if quotient < 0 || quotient > 1 {
+			Fatalf("Invalid local gas quotient %v, must be between 0 and 1", quotient)
+		}
+		cfg.LocalGasQuotient = quotient
+	}
+	if ctx.GlobalIsSet(MinerFeeCurrencyReservedGasFlag.Name) {
+		cfg.FeeCurrencyReservedGas = ctx.GlobalBool(MinerFeeCurrencyReservedGasFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerRateStalenessFlag.Name) {
+		cfg.RateStaleness = ctx.GlobalDuration(MinerRateStalenessFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerGasLimitOverrideFlag.Name) {
+		cfg.GasLimitOverride = ctx.GlobalUint64(MinerGasLimitOverrideFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerDenylistFlag.Name) {
+		cfg.DenylistFile = ctx.GlobalString(MinerDenylistFlag.Name)
+		if _, err := miner.LoadDenylist(cfg.DenylistFile); err != nil {
+			Fatalf("Failed to load the miner denylist: %v", err)
+		}
+	}
+	if ctx.GlobalIsSet(MinerFeeRecipientsFlag.Name) {
+		split := &miner.TxFeeRecipientSchedule{Period: 1}
+		for _, entry := range SplitAndTrim(ctx.GlobalString(MinerFeeRecipientsFlag.Name)) {
+			parts := strings.Split(entry, ":")
+			if len(parts) != 2 || !common.IsHexAddress(parts[0]) {
+				Fatalf("Invalid tx fee recipient %q, want <address>:<weight>", entry)
+			}
+			weight, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil || weight == 0 {
+				Fatalf("Invalid weight of tx fee recipient %q", entry)
+			}
+			split.Recipients = append(split.Recipients, common.HexToAddress(parts[0]))
+			split.Weights = append(split.Weights, weight)
+		}
+		cfg.TxFeeRecipientSplit = split
+	}
+	if ctx.GlobalIsSet(MinerBlockRelayFlag.Name) {
+		cfg.BlockRelay = ctx.GlobalBool(MinerBlockRelayFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerPayloadBuilderFlag.Name) {
+		cfg.PayloadBuilder = ctx.GlobalBool(MinerPayloadBuilderFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerBuilderFlag.Name) {
+		cfg.Builder = ctx.GlobalString(MinerBuilderFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerForcedTxsFlag.Name) {
+		cfg.ForcedTxsFile = ctx.GlobalString(MinerForcedTxsFlag.Name)
+		if _, err := miner.LoadForcedTxs(cfg.ForcedTxsFile); err != nil {
+			Fatalf("Failed to load the forced transactions: %v", err)
+		}
+	}
 }
 
 func setWhitelist(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1516,13 +1823,66 @@ func setIstanbul(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	cfg.Istanbul.ValidatorEnodeDBPath = stack.ResolvePath(cfg.Istanbul.ValidatorEnodeDBPath)
 	cfg.Istanbul.VersionCertificateDBPath = stack.ResolvePath(cfg.Istanbul.VersionCertificateDBPath)
 	cfg.Istanbul.RoundStateDBPath = stack.ResolvePath(cfg.Istanbul.RoundStateDBPath)
+	cfg.Istanbul.MessageArchiveDBPath = stack.ResolvePath(cfg.Istanbul.MessageArchiveDBPath)
 	cfg.Istanbul.Validator = ctx.GlobalIsSet(MiningEnabledFlag.Name) || ctx.GlobalIsSet(DeveloperFlag.Name)
 	cfg.Istanbul.Replica = ctx.GlobalIsSet(IstanbulReplicaFlag.Name)
+	if ctx.GlobalIsSet(IstanbulRemoteSignerFlag.Name) {
+		cfg.Istanbul.RemoteSigner = ctx.GlobalString(IstanbulRemoteSignerFlag.Name)
+	}
+	if ctx.GlobalIsSet(IstanbulParentSealWaitFlag.Name) {
+		cfg.Istanbul.ParentSealExtraWait = ctx.GlobalUint64(IstanbulParentSealWaitFlag.Name)
+	}
+	if ctx.GlobalIsSet(IstanbulAdaptiveTimeoutFlag.Name) {
+		cfg.Istanbul.AdaptiveRequestTimeout = ctx.GlobalBool(IstanbulAdaptiveTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(IstanbulProposerPolicyFlag.Name) {
+		policy, err := validator.ParseProposerPolicy(ctx.GlobalString(IstanbulProposerPolicyFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", IstanbulProposerPolicyFlag.Name, err)
+		}
+		cfg.Istanbul.ProposerPolicyOverride = &policy
+	}
+	cfg.Istanbul.MessageArchive = ctx.GlobalBool(IstanbulMessageArchiveFlag.Name)
+	if ctx.GlobalIsSet(IstanbulMessageArchiveSequencesFlag.Name) {
+		cfg.Istanbul.MessageArchiveSequences = ctx.GlobalUint64(IstanbulMessageArchiveSequencesFlag.Name)
+	}
 	if ctx.GlobalIsSet(MetricsLoadTestCSVFlag.Name) {
 		cfg.Istanbul.LoadTestCSVFile = ctx.GlobalString(MetricsLoadTestCSVFlag.Name)
 	}
 }
 
+// setStorageKey loads the key encrypting the round states and the randomness
+// commitments journal, if configured. The keystore isn't concerned, its keys are
+// encrypted with their own passphrases.
+func setStorageKey(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
+	CheckExclusive(ctx, StorageKeyPasswordFlag, StorageKeyFileFlag)
+
+	var (
+		key *atrest.Key
+		err error
+	)
+	switch {
+	case ctx.GlobalIsSet(StorageKeyFileFlag.Name):
+		key, err = atrest.LoadKeyFile(ctx.GlobalString(StorageKeyFileFlag.Name))
+	case ctx.GlobalIsSet(StorageKeyPasswordFlag.Name):
+		saltFile := stack.ResolvePath("storagekey.salt")
+		if saltFile == "" {
+			Fatalf("Storage encryption requires a data directory")
+		}
+		var text []byte
+		if text, err = ioutil.ReadFile(ctx.GlobalString(StorageKeyPasswordFlag.Name)); err == nil {
+			key, err = atrest.DeriveKey(strings.TrimRight(string(text), "\r\n"), saltFile)
+		}
+	default:
+		return
+	}
+	if err != nil {
+		Fatalf("Failed to load the storage key: %v", err)
+	}
+	cfg.Istanbul.RoundStateDBKey = key
+	cfg.Miner.RandomnessKey = key
+}
+
 func setProxyP2PConfig(ctx *cli.Context, proxyCfg *p2p.Config) {
 	setNodeKey(ctx, proxyCfg)
 	setNAT(ctx, proxyCfg)
@@ -1570,6 +1930,18 @@ func SetProxyConfig(ctx *cli.Context, nodeCfg *node.Config, ethCfg *eth.Config)
 
 	if ctx.GlobalIsSet(ProxiedFlag.Name) {
 		ethCfg.Istanbul.Proxied = ctx.GlobalBool(ProxiedFlag.Name)
+		if ctx.GlobalIsSet(ProxiedKnownCacheFlag.Name) {
+			ethCfg.ProxiedKnownCache = ctx.GlobalUint64(ProxiedKnownCacheFlag.Name)
+		}
+		if ctx.GlobalIsSet(ProxyHealthCheckPeriodFlag.Name) {
+			ethCfg.Istanbul.ProxyHealthCheckPeriod = ctx.GlobalUint64(ProxyHealthCheckPeriodFlag.Name)
+		}
+		if ctx.GlobalIsSet(ProxyHealthCheckFailuresFlag.Name) {
+			ethCfg.Istanbul.ProxyHealthCheckFailures = ctx.GlobalUint64(ProxyHealthCheckFailuresFlag.Name)
+		}
+		if ctx.GlobalIsSet(ProxyHealthCheckMaxLagFlag.Name) {
+			ethCfg.Istanbul.ProxyHealthCheckMaxLag = ctx.GlobalUint64(ProxyHealthCheckMaxLagFlag.Name)
+		}
 
 		// Mining must be set for proxied nodes
 		if !ctx.GlobalIsSet(MiningEnabledFlag.Name) {
@@ -1705,6 +2077,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setMiner(ctx, &cfg.Miner)
 	setWhitelist(ctx, cfg)
 	setIstanbul(ctx, stack, cfg)
+	setStorageKey(ctx, stack, cfg)
 	setLes(ctx, cfg)
 	cfg.NetworkId = params.MainnetNetworkId
 
@@ -1731,9 +2104,57 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(L2MigrationBlockFlag.Name) {
 		cfg.L2MigrationBlock = new(big.Int).SetUint64(ctx.GlobalUint64(L2MigrationBlockFlag.Name))
 	}
+	if ctx.GlobalIsSet(ConfigCheckStrictFlag.Name) {
+		cfg.StrictConfigCheck = ctx.GlobalBool(ConfigCheckStrictFlag.Name)
+	}
+	if ctx.GlobalIsSet(ConfigCheckReportFlag.Name) {
+		cfg.ConfigCheckReport = ctx.GlobalString(ConfigCheckReportFlag.Name)
+	}
+	if ctx.GlobalIsSet(NTPServerFlag.Name) {
+		cfg.NTPServer = ctx.GlobalString(NTPServerFlag.Name)
+	}
+	if ctx.GlobalIsSet(GasUsageIndexFlag.Name) {
+		cfg.GasUsageIndex = ctx.GlobalBool(GasUsageIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(ExchangeRateIndexFlag.Name) {
+		cfg.ExchangeRateIndex = ctx.GlobalBool(ExchangeRateIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(AccountTxIndexFlag.Name) {
+		cfg.AccountTxIndex = ctx.GlobalBool(AccountTxIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(InternalTransferIndexFlag.Name) {
+		cfg.InternalTransferIndex = ctx.GlobalBool(InternalTransferIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(SyntheticFeeLogsFlag.Name) {
+		cfg.SyntheticFeeLogs = ctx.GlobalBool(SyntheticFeeLogsFlag.Name)
+	}
+	if ctx.GlobalIsSet(SafeModeFlag.Name) {
+		cfg.SafeMode = ctx.GlobalBool(SafeModeFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolStemRelayFlag.Name) {
+		cfg.TxStemRelay = ctx.GlobalBool(TxPoolStemRelayFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolStreamTokenFlag.Name) {
+		cfg.TxPoolStreamToken = ctx.GlobalString(TxPoolStreamTokenFlag.Name)
+	}
+	if ctx.GlobalIsSet(HeadLagTimeFlag.Name) {
+		cfg.HeadLagTimeThreshold = ctx.GlobalDuration(HeadLagTimeFlag.Name)
+	}
+	if ctx.GlobalIsSet(HeadLagBlocksFlag.Name) {
+		cfg.HeadLagBlockThreshold = ctx.GlobalUint64(HeadLagBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(HeadLagWebhookFlag.Name) {
+		cfg.HeadLagWebhook = ctx.GlobalString(HeadLagWebhookFlag.Name)
+	}
+	if ctx.GlobalIsSet(HeadLagProxyFailoverFlag.Name) {
+		cfg.HeadLagProxyFailover = ctx.GlobalBool(HeadLagProxyFailoverFlag.Name)
+	}
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
 	}
+	if ctx.GlobalIsSet(SyncCheckpointFlag.Name) {
+		cfg.SyncCheckpoint = ctx.GlobalString(SyncCheckpointFlag.Name)
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -1797,6 +2218,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(VMSystemCallFastPathFlag.Name) {
+		cfg.SystemCallFastPath = ctx.GlobalBool(VMSystemCallFastPathFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(RPCGlobalGasInflationRateFlag.Name) {
 		cfg.RPCGasInflationRate = ctx.GlobalFloat64(RPCGlobalGasInflationRateFlag.Name)
@@ -1826,11 +2250,17 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.GlobalFloat64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCStateRangeRateFlag.Name) {
+		cfg.RPCStateRangeRate = ctx.GlobalUint64(RPCStateRangeRateFlag.Name)
+	}
 
 	cfg.RPCEthCompatibility = true
 	if ctx.GlobalIsSet(DisableRPCETHCompatibility.Name) {
 		cfg.RPCEthCompatibility = false
 	}
+	if ctx.GlobalIsSet(RPCReceiptFeeFieldsFlag.Name) {
+		cfg.RPCReceiptFeeFields = ctx.GlobalBool(RPCReceiptFeeFieldsFlag.Name)
+	}
 
 	// Disable DNS discovery by default (by using the flag's value even if it hasn't been set and so
 	// has the default value ""), since we don't have DNS discovery set up for Celo.
@@ -1944,9 +2374,14 @@ func SetDNSDiscoveryDefaults(cfg *ethconfig.Config, genesis common.Hash) {
 	}
 }
 
+// safeModeCheckDepth is the number of head blocks of the chain database checked
+// for corruption before starting in safe mode.
+const safeModeCheckDepth = 128
+
 // RegisterEthService adds an Ethereum client to the stack.
 // The second return value is the full node instance, which may be nil if the
-// node is running as a light client.
+// node is running as a light client. Both are nil if the node is running in
+// safe mode, serving the history of a corrupted chain database read-only.
 func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend, *eth.Ethereum) {
 	if !cfg.SyncMode.SyncFullBlockChain() {
 		backend, err := les.New(stack, cfg)
@@ -1956,6 +2391,9 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 		stack.RegisterAPIs(tracers.APIs(backend.ApiBackend))
 		return backend.ApiBackend, nil
 	}
+	if cfg.SafeMode && startSafeMode(stack, cfg) {
+		return nil, nil
+	}
 	backend, err := eth.New(stack, cfg)
 	if err != nil {
 		Fatalf("Failed to register the Ethereum service: %v", err)
@@ -1970,6 +2408,33 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 	return backend.APIBackend, backend
 }
 
+// startSafeMode checks the integrity of the chain database, and registers the
+// safe mode APIs instead of the Ethereum service if it's corrupted.
+func startSafeMode(stack *node.Node, cfg *ethconfig.Config) bool {
+	path := stack.ResolvePath("chaindata")
+	if path == "" || !common.FileExist(path) {
+		return false
+	}
+	var corruption error
+	db, err := stack.OpenDatabaseWithFreezer("chaindata", cfg.DatabaseCache, cfg.DatabaseHandles, cfg.DatabaseFreezer, "eth/db/chaindata/", true)
+	if err == nil {
+		if corruption = rawdb.CheckIntegrity(db, safeModeCheckDepth); corruption == nil {
+			db.Close()
+			return false
+		}
+	} else {
+		// The freezer may be the corrupted part, serve the recent blocks only
+		corruption = fmt.Errorf("%w: %v", rawdb.ErrCorrupted, err)
+		if db, err = stack.OpenDatabase("chaindata", cfg.DatabaseCache, cfg.DatabaseHandles, "eth/db/chaindata/", true); err != nil {
+			log.Error("Failed to open the chain database", "err", err)
+			db = nil
+		}
+	}
+	log.Error("Chain database corrupted, starting in read-only safe mode", "err", corruption)
+	stack.RegisterAPIs(ethapi.SafeModeAPIs(db, corruption))
+	return true
+}
+
 // RegisterEthStatsService configures the Ethereum Stats daemon and adds it to
 // the given node.
 func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url string) {
@@ -1978,6 +2443,22 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 	}
 }
 
+// RegisterAlertsService configures the alerting rules engine and adds it to the given node.
+func RegisterAlertsService(stack *node.Node, backend ethapi.Backend, cfg alerts.Config) {
+	balance := func(ctx context.Context, account common.Address) (*big.Int, error) {
+		state, _, err := backend.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+		if state == nil || err != nil {
+			return nil, err
+		}
+		return state.GetBalance(account), nil
+	}
+	engine, err := alerts.New(cfg, metrics.DefaultRegistry, balance)
+	if err != nil {
+		Fatalf("Failed to configure the alerting rules: %v", err)
+	}
+	stack.RegisterLifecycle(engine)
+}
+
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
 func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, cfg node.Config) {
 	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
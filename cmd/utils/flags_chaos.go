@@ -0,0 +1,67 @@
+// Copyright 2021 The celo Authors
+// This file is part of celo.
+//
+// celo is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// celo is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with celo. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"github.com/celo-org/celo-blockchain/internal/chaos"
+	"github.com/celo-org/celo-blockchain/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// ChaosFlags inject faults for soak tests, they're hidden from the help.
+var ChaosFlags = []cli.Flag{
+	ChaosDBLatencyFlag,
+	ChaosDropConsensusFlag,
+	ChaosGCPauseFlag,
+}
+
+var (
+	ChaosDBLatencyFlag = cli.DurationFlag{
+		Name:   "chaos.db-latency",
+		Usage:  "Delay every chain database read and write by this duration (testing only)",
+		Hidden: true,
+	}
+	ChaosDropConsensusFlag = cli.UintFlag{
+		Name:   "chaos.drop-consensus-pct",
+		Usage:  "Percentage of the received consensus messages to drop (testing only)",
+		Hidden: true,
+	}
+	ChaosGCPauseFlag = cli.DurationFlag{
+		Name:   "chaos.gc-pause",
+		Usage:  "Force a full garbage collection at this interval (testing only)",
+		Hidden: true,
+	}
+)
+
+// SetupChaos enables the fault injection if any of the chaos flags is set. It
+// has to run before the node is created.
+func SetupChaos(ctx *cli.Context) {
+	var set bool
+	for _, flag := range ChaosFlags {
+		set = set || ctx.GlobalIsSet(flag.GetName())
+	}
+	if !set {
+		return
+	}
+	log.Warn("Injecting faults, this node must not be used in production")
+	chaos.Enabled = true
+	chaos.SetDBLatency(ctx.GlobalDuration(ChaosDBLatencyFlag.Name))
+	chaos.SetDropConsensusPercent(uint32(ctx.GlobalUint(ChaosDropConsensusFlag.Name)))
+	if interval := ctx.GlobalDuration(ChaosGCPauseFlag.Name); interval > 0 {
+		chaos.StartGCPauses(interval)
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/internal/era"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/trie"
+)
+
+// historyChecksumsFile is the file listing the sha256 checksums of the era files
+// exported into a directory.
+const historyChecksumsFile = "checksums.txt"
+
+// ExportHistory exports the blocks of the epochs from first to last of the
+// canonical chain, with their receipts, into era files in dir, one an epoch.
+// The checksums of the files are listed in the checksums file of dir.
+func ExportHistory(chain *core.BlockChain, dir string, first, last uint64) error {
+	config := chain.Config()
+	if config.Istanbul == nil {
+		return errors.New("history export requires an istanbul chain")
+	}
+	epochSize := config.Istanbul.Epoch
+	if head := chain.CurrentFastBlock().NumberU64(); istanbul.GetEpochLastBlockNumber(last, epochSize) > head {
+		return fmt.Errorf("epoch %d ends after the head block %d", last, head)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	log.Info("Exporting history", "dir", dir, "first", first, "last", last)
+
+	var (
+		network   = historyNetworkName(config)
+		checksums []string
+	)
+	for epoch := first; epoch <= last; epoch++ {
+		from, to := uint64(0), istanbul.GetEpochLastBlockNumber(epoch, epochSize)
+		if epoch != 0 {
+			from, _ = istanbul.GetEpochFirstBlockNumber(epoch, epochSize)
+		}
+		filename, checksum, err := exportEra(chain, dir, network, epoch, from, to)
+		if err != nil {
+			return fmt.Errorf("epoch %d: %v", epoch, err)
+		}
+		checksums = append(checksums, fmt.Sprintf("%x  %s", checksum, filename))
+		log.Info("Exported epoch history", "epoch", epoch, "file", filename)
+	}
+	return os.WriteFile(filepath.Join(dir, historyChecksumsFile), []byte(strings.Join(checksums, "\n")+"\n"), 0644)
+}
+
+// exportEra writes the blocks from and to into the era file of an epoch,
+// returning its name and checksum.
+func exportEra(chain *core.BlockChain, dir, network string, epoch, from, to uint64) (string, []byte, error) {
+	// The file is named after its accumulator, known once written
+	tmp := filepath.Join(dir, fmt.Sprintf(".%s-%05d.era1.tmp", network, epoch))
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	var (
+		checksum = sha256.New()
+		builder  = era.NewBuilder(io.MultiWriter(f, checksum))
+	)
+	for number := from; number <= to; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return "", nil, fmt.Errorf("missing block %d", number)
+		}
+		receipts := chain.GetReceiptsByHash(block.Hash())
+		if receipts == nil && block.ReceiptHash() != types.EmptyRootHash {
+			return "", nil, fmt.Errorf("missing receipts of block %d", number)
+		}
+		td := chain.GetTd(block.Hash(), number)
+		if td == nil {
+			return "", nil, fmt.Errorf("missing total difficulty of block %d", number)
+		}
+		if err := builder.Add(block, receipts, td); err != nil {
+			return "", nil, err
+		}
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := f.Close(); err != nil {
+		return "", nil, err
+	}
+	filename := era.Filename(network, epoch, root)
+	if err := os.Rename(tmp, filepath.Join(dir, filename)); err != nil {
+		return "", nil, err
+	}
+	return filename, checksum.Sum(nil), nil
+}
+
+// ImportHistory imports the blocks and receipts of the era files in dir into
+// the ancient store of the chain, in the order of their epochs. The headers are
+// inserted into the header chain first, which verifies their seals against the
+// validator sets of their epochs, and the bodies and receipts are checked against
+// the headers. The chain must have no blocks but the ones of its ancient store,
+// the files continuing from them.
+func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.era1"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no era files found in %s", dir)
+	}
+	checksums, err := readHistoryChecksums(dir)
+	if err != nil {
+		return err
+	}
+	head := chain.CurrentFastBlock().NumberU64()
+	if frozen, _ := db.Ancients(); head != 0 && head+1 != frozen {
+		return fmt.Errorf("history import requires the chain to be in the ancient store, head block %d, ancient blocks %d", head, frozen)
+	}
+	log.Info("Importing history", "dir", dir, "files", len(files))
+
+	for _, file := range files {
+		if checksums != nil {
+			if err := verifyHistoryChecksum(file, checksums[filepath.Base(file)]); err != nil {
+				return err
+			}
+		}
+		e, err := era.Open(file)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		err = importEra(chain, e)
+		e.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		log.Info("Imported epoch history", "file", filepath.Base(file), "blocks", e.Count(), "head", chain.CurrentFastBlock().NumberU64())
+	}
+	return nil
+}
+
+// importEra imports the blocks of an era missing from the chain, after checking
+// its accumulator.
+func importEra(chain *core.BlockChain, e *era.Era) error {
+	var (
+		start, end = e.Start(), e.Start() + e.Count()
+		hashes     = make([]common.Hash, 0, e.Count())
+		tds        = make([]*big.Int, 0, e.Count())
+	)
+	for number := start; number < end; number++ {
+		header, err := e.GetHeaderByNumber(number)
+		if err != nil {
+			return err
+		}
+		td, err := e.GetTotalDifficultyByNumber(number)
+		if err != nil {
+			return err
+		}
+		hashes, tds = append(hashes, header.Hash()), append(tds, td)
+	}
+	want, err := e.Accumulator()
+	if err != nil {
+		return err
+	}
+	if root, err := era.ComputeAccumulator(hashes, tds); err != nil || root != want {
+		return fmt.Errorf("accumulator mismatch: have %x, want %x (%v)", root, want, err)
+	}
+	head := chain.CurrentFastBlock().NumberU64()
+	for number := start; number < end; {
+		var (
+			blocks   types.Blocks
+			receipts []types.Receipts
+		)
+		for ; number < end && len(blocks) < importBatchSize; number++ {
+			block, err := e.GetBlockByNumber(number)
+			if err != nil {
+				return err
+			}
+			// The known blocks have to be the ones of the chain
+			if number <= head {
+				if hash := chain.GetCanonicalHash(number); hash != block.Hash() {
+					return fmt.Errorf("block %d mismatch: have %x, want %x", number, block.Hash(), hash)
+				}
+				continue
+			}
+			rs, err := e.GetReceiptsByNumber(number)
+			if err != nil {
+				return err
+			}
+			if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != block.TxHash() {
+				return fmt.Errorf("transactions of block %d mismatch: have root %x, want %x", number, hash, block.TxHash())
+			}
+			if hash := types.DeriveSha(rs, trie.NewStackTrie(nil)); hash != block.ReceiptHash() {
+				return fmt.Errorf("receipts of block %d mismatch: have root %x, want %x", number, hash, block.ReceiptHash())
+			}
+			blocks, receipts = append(blocks, block), append(receipts, rs)
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		headers := make([]*types.Header, len(blocks))
+		for i, block := range blocks {
+			headers[i] = block.Header()
+		}
+		if n, err := chain.InsertHeaderChain(headers, 1, true); err != nil {
+			return fmt.Errorf("invalid header of block %d: %v", headers[n].Number, err)
+		}
+		if _, err := chain.InsertReceiptChain(blocks, receipts, math.MaxUint64); err != nil {
+			return fmt.Errorf("failed to insert blocks %d-%d: %v", blocks[0].NumberU64(), blocks[len(blocks)-1].NumberU64(), err)
+		}
+	}
+	return nil
+}
+
+// readHistoryChecksums reads the checksums of the era files of dir by name, nil
+// if dir has no checksums file.
+func readHistoryChecksums(dir string) (map[string]string, error) {
+	f, err := os.Open(filepath.Join(dir, historyChecksumsFile))
+	if os.IsNotExist(err) {
+		log.Warn("No checksums of the era files found", "dir", dir)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid checksum line %q", scanner.Text())
+		}
+		checksums[fields[1]] = fields[0]
+	}
+	return checksums, scanner.Err()
+}
+
+// verifyHistoryChecksum checks the sha256 checksum of an era file.
+func verifyHistoryChecksum(file string, want string) error {
+	if want == "" {
+		return fmt.Errorf("%s: no checksum listed", file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return err
+	}
+	if have := fmt.Sprintf("%x", hasher.Sum(nil)); have != want {
+		return fmt.Errorf("%s: checksum mismatch: have %s, want %s", file, have, want)
+	}
+	return nil
+}
+
+// historyNetworkName returns the name of the network the era files of a chain
+// are named after.
+func historyNetworkName(config *params.ChainConfig) string {
+	switch config.ChainID.Uint64() {
+	case params.MainnetNetworkId:
+		return "mainnet"
+	case params.BaklavaNetworkId:
+		return "baklava"
+	case params.AlfajoresNetworkId:
+		return "alfajores"
+	default:
+		return config.ChainID.String()
+	}
+}
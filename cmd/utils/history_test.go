@@ -0,0 +1,129 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	mockEngine "github.com/celo-org/celo-blockchain/consensus/consensustest"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+func TestHistoryExportImport(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{
+			Config: params.IstanbulTestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000)}},
+		}
+		db      = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(db)
+		signer  = types.LatestSigner(gspec.Config)
+		epoch   = gspec.Config.Istanbul.Epoch
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, mockEngine.NewFaker(), db, int(2*epoch), func(i int, block *core.BlockGen) {
+		if i%7 == 0 {
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, block.MinimumGasPrice(nil), nil), signer, key)
+			if err != nil {
+				panic(err)
+			}
+			block.AddTx(tx)
+		}
+	})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, mockEngine.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+
+	dir, err := os.MkdirTemp("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ExportHistory(chain, dir, 0, 3); err == nil {
+		t.Fatalf("epoch after the head exported")
+	}
+	if err := ExportHistory(chain, dir, 0, 2); err != nil {
+		t.Fatalf("failed to export history: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.era1"))
+	if len(files) != 3 {
+		t.Fatalf("era files mismatch: have %d, want 3", len(files))
+	}
+
+	// Import into a fresh node, its ancient store
+	frdir, err := os.MkdirTemp("", "history-freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(frdir)
+	importDb, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), frdir, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer importDb.Close()
+	gspec.MustCommit(importDb)
+	imported, _ := core.NewBlockChain(importDb, nil, gspec.Config, mockEngine.NewFaker(), vm.Config{}, nil, nil)
+	defer imported.Stop()
+
+	if err := ImportHistory(imported, importDb, dir); err != nil {
+		t.Fatalf("failed to import history: %v", err)
+	}
+	if head := imported.CurrentFastBlock(); head.Hash() != chain.CurrentBlock().Hash() {
+		t.Fatalf("head mismatch: have %d, want %d", head.NumberU64(), chain.CurrentBlock().NumberU64())
+	}
+	if frozen, _ := importDb.Ancients(); frozen != 2*epoch+1 {
+		t.Errorf("ancient blocks mismatch: have %d, want %d", frozen, 2*epoch+1)
+	}
+	for _, block := range blocks {
+		have := imported.GetBlockByNumber(block.NumberU64())
+		if have == nil || have.Hash() != block.Hash() || len(have.Transactions()) != len(block.Transactions()) {
+			t.Fatalf("block %d mismatch", block.NumberU64())
+		}
+		if have, want := imported.GetReceiptsByHash(block.Hash()), chain.GetReceiptsByHash(block.Hash()); len(have) != len(want) {
+			t.Fatalf("receipts of block %d mismatch: have %d, want %d", block.NumberU64(), len(have), len(want))
+		}
+	}
+	// Importing again skips the known blocks
+	if err := ImportHistory(imported, importDb, dir); err != nil {
+		t.Fatalf("failed to import history again: %v", err)
+	}
+
+	// Tampered files are rejected
+	data, err := os.ReadFile(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)/2] ^= 0xff
+	if err := os.WriteFile(files[1], data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportHistory(imported, importDb, dir); err == nil {
+		t.Errorf("tampered era file imported")
+	}
+}
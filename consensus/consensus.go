@@ -18,6 +18,7 @@
 package consensus
 
 import (
+	"context"
 	"math/big"
 
 	"github.com/celo-org/celo-blockchain/common"
@@ -156,6 +157,13 @@ type Handler interface {
 	Handshake(peer Peer) (bool, error)
 }
 
+// ContextPreparer should be implemented if the consensus Prepare waits, e.g. for the
+// block time, so that the miner can abort the preparation of a stale block.
+type ContextPreparer interface {
+	// PrepareContext is like Engine.Prepare, but returns ctx.Err() once ctx is done.
+	PrepareContext(ctx context.Context, chain ChainHeaderReader, header *types.Header) error
+}
+
 // PoW is a consensus engine based on proof-of-work.
 type PoW interface {
 	Engine
@@ -17,9 +17,11 @@
 package backend
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/consensus"
@@ -28,10 +30,12 @@ import (
 	"github.com/celo-org/celo-blockchain/consensus/istanbul/backend/internal/replica"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul/core"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul/proxy"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/uptime"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul/validator"
 	"github.com/celo-org/celo-blockchain/core/types"
 	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
 	"github.com/celo-org/celo-blockchain/p2p/enode"
+	"github.com/celo-org/celo-blockchain/params"
 	"github.com/celo-org/celo-blockchain/rpc"
 )
 
@@ -140,10 +144,20 @@ func (api *API) GetProposer(sequence *rpc.BlockNumber, round *uint64) (common.Ad
 	if round == nil {
 		round = new(uint64)
 	}
-	proposer := validator.GetProposerSelector(api.istanbul.config.ProposerPolicy)(valSet, previousProposer, *round)
+	proposer := validator.NewProposerSelector(api.istanbul.config)(valSet, previousProposer, *round)
 	return proposer.Address(), nil
 }
 
+// GetBlockSigners retrieves the proposer of a given block and the validators whose
+// seals were aggregated in it.
+func (api *API) GetBlockSigners(number *rpc.BlockNumber) (*BlockSigners, error) {
+	header, err := api.getHeaderByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	return api.istanbul.blockSigners(header)
+}
+
 // AddProxy peers with a remote node that acts as a proxy, even if slots are full
 func (api *API) AddProxy(url, externalUrl string) (bool, error) {
 	if !api.istanbul.config.Proxied {
@@ -210,6 +224,29 @@ func (api *API) GetCurrentRoundChangeSet() (*core.RoundChangeSetSummary, error)
 	return api.istanbul.core.CurrentRoundChangeSet(), nil
 }
 
+// GetCurrentRoundTimeouts retrieves the round timeouts in effect
+func (api *API) GetCurrentRoundTimeouts() (*core.RoundTimeoutsSummary, error) {
+	api.istanbul.coreMu.RLock()
+	defer api.istanbul.coreMu.RUnlock()
+
+	if !api.istanbul.isCoreStarted() {
+		return nil, istanbul.ErrStoppedEngine
+	}
+	return api.istanbul.core.CurrentRoundTimeouts(), nil
+}
+
+// ConsensusStatus retrieves the current view and the health of the consensus:
+// missed proposals, latest round changes, prepared certificate and commit.
+func (api *API) ConsensusStatus() (*core.ConsensusStatus, error) {
+	api.istanbul.coreMu.RLock()
+	defer api.istanbul.coreMu.RUnlock()
+
+	if !api.istanbul.isCoreStarted() {
+		return nil, istanbul.ErrStoppedEngine
+	}
+	return api.istanbul.core.ConsensusStatus(), nil
+}
+
 func (api *API) ForceRoundChange() (bool, error) {
 	api.istanbul.coreMu.RLock()
 	defer api.istanbul.coreMu.RUnlock()
@@ -242,6 +279,19 @@ func (api *API) GetProxiesInfo() ([]*proxy.ProxyInfo, error) {
 	}
 }
 
+// Proxies retrieves the proxies of the proxied validator with their health and
+// the remote validators assigned to them, sorted by internal enode ID.
+func (api *API) Proxies() ([]*proxy.ProxyInfo, error) {
+	proxies, err := api.GetProxiesInfo()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(proxies, func(i, j int) bool {
+		return bytes.Compare(proxies[i].InternalNode.ID().Bytes(), proxies[j].InternalNode.ID().Bytes()) < 0
+	})
+	return proxies, nil
+}
+
 // ProxiedValidators retrieves all of the proxies connected proxied validators.
 // Note that we plan to support validators per proxy in the future, so this function
 // is plural and returns an array of proxied validators.  This is to prevent
@@ -306,6 +356,42 @@ func (api *API) GetLookbackWindow(number *rpc.BlockNumber) (uint64, error) {
 	return api.istanbul.LookbackWindow(header, state), nil
 }
 
+// MyUptime computes the uptime score of the validator in the epoch (default the current one) from
+// the parent seals, the same way the epoch rewards distribution does. The score of an epoch that
+// didn't end yet is the one accrued up to the head.
+func (api *API) MyUptime(epoch *uint64) (*UptimeReport, error) {
+	head := api.chain.CurrentHeader()
+	if head == nil {
+		return nil, errUnknownBlock
+	}
+	number := istanbul.GetEpochNumber(head.Number.Uint64(), api.istanbul.EpochSize())
+	if epoch != nil {
+		number = *epoch
+	}
+	var builder uptime.Builder
+	return api.istanbul.uptimeReport(number, head, &builder)
+}
+
+// ExportEpochSnapshots returns the validator set snapshots of the epochs from and to (default
+// from) of the canonical chain, with the last block headers of the epochs carrying the validator
+// set diffs and the seals, at most maxExportedEpochs of them.
+func (api *API) ExportEpochSnapshots(from uint64, to *uint64) (*EpochSnapshots, error) {
+	last := from
+	if to != nil {
+		last = *to
+	}
+	if last >= from && last-from >= maxExportedEpochs {
+		return nil, fmt.Errorf("too many epochs, at most %d may be exported", maxExportedEpochs)
+	}
+	return ExportEpochSnapshots(api.istanbul.db, api.istanbul.EpochSize(), from, last)
+}
+
+// ImportEpochSnapshots verifies and stores epoch snapshots exported by another node, returning
+// the number of snapshots the node didn't have.
+func (api *API) ImportEpochSnapshots(snapshots *EpochSnapshots) (int, error) {
+	return ImportEpochSnapshots(api.istanbul.db, api.istanbul.EpochSize(), snapshots)
+}
+
 // ResendPreprepare sends again the preprepare message
 func (api *API) ResendPreprepare() error {
 	return api.istanbul.core.ResendPreprepare()
@@ -320,3 +406,65 @@ func (api *API) GossipPrepares() error {
 func (api *API) GossipCommits() error {
 	return api.istanbul.core.GossipCommits()
 }
+
+// CeloAPI is a user facing RPC API exposing Celo proof-of-stake information
+// derived by the Istanbul engine.
+type CeloAPI struct {
+	chain    consensus.ChainHeaderReader
+	istanbul *Backend
+}
+
+// EstimateEpochRewards simulates the epoch rewards distribution on top of the current head,
+// projecting the per validator and per group rewards accrued so far in the in-progress epoch.
+func (api *CeloAPI) EstimateEpochRewards() (*EpochRewardsEstimate, error) {
+	header := api.chain.CurrentHeader()
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	state, err := api.istanbul.stateAt(header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return api.istanbul.estimateEpochRewards(header, state)
+}
+
+// errForkOnPublicNetwork is returned when trying to force a fork on a public network.
+var errForkOnPublicNetwork = errors.New("forcing forks is only available on test networks")
+
+// DebugAPI is an RPC API to exercise Istanbul consensus edge cases on test networks.
+type DebugAPI struct {
+	istanbul *Backend
+}
+
+// ForceFork moves the local validator the given number of rounds (default 1) past its current
+// desired round, abandoning the proposal of the current round. Once a quorum of validators has
+// been forced, the proposer of the new round re-proposes the prepared block if there is one, or
+// a competing block of the same height otherwise, letting downstream applications observe round
+// changes and transient equal-height blocks on demand. It returns the view being moved to.
+func (api *DebugAPI) ForceFork(rounds *uint64) (*istanbul.View, error) {
+	switch api.istanbul.ChainConfig().ChainID.Uint64() {
+	case params.MainnetNetworkId, params.BaklavaNetworkId, params.AlfajoresNetworkId:
+		return nil, errForkOnPublicNetwork
+	}
+	skip := uint64(1)
+	if rounds != nil && *rounds > 0 {
+		skip = *rounds
+	}
+
+	api.istanbul.coreMu.RLock()
+	defer api.istanbul.coreMu.RUnlock()
+
+	if !api.istanbul.isCoreStarted() {
+		return nil, istanbul.ErrStoppedEngine
+	}
+	roundState := api.istanbul.core.CurrentRoundState()
+	if roundState == nil {
+		return nil, istanbul.ErrStoppedEngine
+	}
+	view := &istanbul.View{
+		Sequence: new(big.Int).Set(roundState.Sequence()),
+		Round:    new(big.Int).Add(roundState.DesiredRound(), new(big.Int).SetUint64(skip)),
+	}
+	api.istanbul.core.ForceRoundChanges(skip)
+	return view, nil
+}
@@ -26,6 +26,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/celo-org/celo-blockchain/accounts"
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/consensus"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
@@ -46,6 +47,7 @@ import (
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/state"
 	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
 	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
 	"github.com/celo-org/celo-blockchain/ethdb"
 	"github.com/celo-org/celo-blockchain/event"
@@ -64,6 +66,10 @@ func New(config *istanbul.Config, db ethdb.Database) consensus.Istanbul {
 	if err != nil {
 		logger.Crit("Failed to create recent snapshots cache", "err", err)
 	}
+	recentBlockSigners, err := lru.NewARC(inmemoryBlockSigners)
+	if err != nil {
+		logger.Crit("Failed to create recent block signers cache", "err", err)
+	}
 
 	coreStarted := atomic.Value{}
 	coreStarted.Store(false)
@@ -73,6 +79,7 @@ func New(config *istanbul.Config, db ethdb.Database) consensus.Istanbul {
 		logger:                             logger,
 		db:                                 db,
 		recentSnapshots:                    recentSnapshots,
+		recentBlockSigners:                 recentBlockSigners,
 		coreStarted:                        coreStarted,
 		gossipCache:                        istanbul.NewLRUGossipCache(inmemoryPeers, inmemoryMessages),
 		updatingCachedValidatorConnSetCond: sync.NewCond(&sync.Mutex{}),
@@ -91,6 +98,13 @@ func New(config *istanbul.Config, db ethdb.Database) consensus.Istanbul {
 		blocksFinalizedTransactionsGauge:   metrics.NewRegisteredGauge("consensus/istanbul/blocks/transactions", nil),
 		blocksFinalizedGasUsedGauge:        metrics.NewRegisteredGauge("consensus/istanbul/blocks/gasused", nil),
 		sleepGauge:                         metrics.NewRegisteredGauge("consensus/istanbul/backend/sleep", nil),
+		parentSealIncludedGauge:            metrics.NewRegisteredGauge("consensus/istanbul/backend/parentseal/included", nil),
+		parentSealAvailableGauge:           metrics.NewRegisteredGauge("consensus/istanbul/backend/parentseal/available", nil),
+		parentSealWaitTimer:                metrics.NewRegisteredTimer("consensus/istanbul/backend/parentseal/wait", nil),
+		uptimeEpochGauge:                   metrics.NewRegisteredGauge("consensus/istanbul/uptime/epoch", nil),
+		uptimeElectedGauge:                 metrics.NewRegisteredGauge("consensus/istanbul/uptime/elected", nil),
+		uptimeMonitoredGauge:               metrics.NewRegisteredGauge("consensus/istanbul/uptime/monitored", nil),
+		uptimeScoreGauge:                   metrics.NewRegisteredGauge("consensus/istanbul/uptime/score", nil),
 	}
 	backend.aWallets.Store(&istanbul.Wallets{})
 	if config.LoadTestCSVFile != "" {
@@ -259,6 +273,9 @@ type Backend struct {
 	// Snapshots for recent blocks to speed up reorgs
 	recentSnapshots *lru.ARCCache
 
+	// Signers of recent blocks, see blockSigners
+	recentBlockSigners *lru.ARCCache
+
 	// event subscription for ChainHeadEvent event
 	broadcaster consensus.Broadcaster
 
@@ -274,6 +291,12 @@ type Backend struct {
 	delegateSignFeed  event.Feed
 	delegateSignScope event.SubscriptionScope
 
+	// Latest verified gas price minimum announcement, relayed to the light clients
+	gasPriceMinimums      *istanbul.GasPriceMinimumAnnouncement
+	gasPriceMinimumsMu    sync.RWMutex
+	gasPriceMinimumsFeed  event.Feed
+	gasPriceMinimumsScope event.SubscriptionScope
+
 	// Metric timer used to record block finalization times.
 	finalizationTimer metrics.Timer
 	// Metric timer used to record epoch reward distribution times.
@@ -310,6 +333,18 @@ type Backend struct {
 
 	// Gauge reporting how many nanoseconds were spent sleeping
 	sleepGauge metrics.Gauge
+	// Gauges for the signatures included in the parent seal of the last proposed block, and the
+	// number of validators that could have signed it.
+	parentSealIncludedGauge  metrics.Gauge
+	parentSealAvailableGauge metrics.Gauge
+	// Timer for the extra time spent waiting for parent commits.
+	parentSealWaitTimer metrics.Timer
+	// Gauges for the uptime score of the validator in the current epoch: the epoch, whether it
+	// is elected, the blocks monitored so far and the score in parts per million.
+	uptimeEpochGauge     metrics.Gauge
+	uptimeElectedGauge   metrics.Gauge
+	uptimeMonitoredGauge metrics.Gauge
+	uptimeScoreGauge     metrics.Gauge
 	// Start of the previous block cycle.
 	cycleStart time.Time
 
@@ -344,6 +379,8 @@ type Backend struct {
 	randomSeedMu sync.Mutex
 
 	uptimeMonitor uptime.Builder
+	// Uptime builder of the validator's own score, owned by the chain head loop
+	selfUptimeBuilder uptime.Builder
 
 	// Test hooks
 	abortCommitHook func(result *istanbulCore.StateProcessResult) bool // Method to call upon committing a proposal
@@ -423,6 +460,18 @@ func (sb *Backend) Authorize(ecdsaAddress, blsAddress common.Address, publicKey
 	sb.core.SetAddress(w.Ecdsa.Address)
 }
 
+// AuthorizeSigner authorizes the validator to sign with the ECDSA key of
+// ecdsaAddress held by ecdsaSigner and the BLS key of blsAddress held by
+// blsSigner, such as the wallets of the keystore or a remote signing service.
+func (sb *Backend) AuthorizeSigner(ecdsaAddress, blsAddress common.Address, ecdsaSigner, blsSigner istanbul.Signer) error {
+	publicKey, err := ecdsaSigner.GetPublicKey(accounts.Account{Address: ecdsaAddress})
+	if err != nil {
+		return fmt.Errorf("ECDSA public key missing: %v", err)
+	}
+	sb.Authorize(ecdsaAddress, blsAddress, publicKey, ecdsaSigner.Decrypt, ecdsaSigner.SignData, blsSigner.SignBLS, ecdsaSigner.SignHash)
+	return nil
+}
+
 func (sb *Backend) wallets() *istanbul.Wallets {
 	return sb.aWallets.Load().(*istanbul.Wallets)
 }
@@ -440,6 +489,7 @@ func (sb *Backend) SelfNode() *enode.Node {
 // Close the backend
 func (sb *Backend) Close() error {
 	sb.delegateSignScope.Close()
+	sb.gasPriceMinimumsScope.Close()
 	var errs []error
 	if err := sb.valEnodeTable.Close(); err != nil {
 		errs = append(errs, err)
@@ -782,7 +832,10 @@ func (sb *Backend) getOrderedValidators(number uint64, hash common.Hash) istanbu
 		return valSet
 	}
 
-	if sb.config.ProposerPolicy == istanbul.ShuffledRoundRobin {
+	switch sb.config.ProposerPolicy {
+	case istanbul.RoundRobin, istanbul.Sticky:
+		// The order of the validators doesn't depend on randomness
+	default:
 		seed, err := sb.validatorRandomnessAtBlockNumber(number, hash)
 		if err != nil {
 			if err == contracts.ErrRegistryContractNotDeployed {
@@ -791,6 +844,10 @@ func (sb *Backend) getOrderedValidators(number uint64, hash common.Hash) istanbu
 				sb.logger.Warn("Failed to set randomness for proposer selection", "block_number", number, "hash", hash, "error", err)
 			}
 		}
+		// The weighted random draws are independent for every block
+		if sb.config.ProposerPolicy == istanbul.WeightedRandom {
+			seed = crypto.Keccak256Hash(seed[:], hash[:])
+		}
 		valSet.SetRandomness(seed)
 	}
 
@@ -1080,6 +1137,12 @@ func (sb *Backend) IsPrimary() bool {
 	return false
 }
 
+// SigningProtectionDB returns the database persisting the views the validator
+// signed messages in, the chain database.
+func (sb *Backend) SigningProtectionDB() ethdb.KeyValueStore {
+	return sb.db
+}
+
 // UpdateReplicaState updates the replica state with the latest seq.
 func (sb *Backend) UpdateReplicaState(seq *big.Int) {
 	if sb.replicaState != nil {
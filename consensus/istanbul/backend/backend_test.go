@@ -24,9 +24,13 @@ import (
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/contracts/abis"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/params"
 )
 
 func TestSign(t *testing.T) {
@@ -172,3 +176,160 @@ func TestGetProposer(t *testing.T) {
 	}
 
 }
+
+func TestBlockSigners(t *testing.T) {
+	genesisCfg, nodeKeys := getGenesisAndKeys(1, true)
+	chain, engine, _ := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer chain.Stop()
+	block, err := makeBlock(nodeKeys, chain, engine, chain.Genesis())
+	if err != nil {
+		t.Fatalf("Failed to make a block: %v", err)
+	}
+	if _, err := engine.blockSigners(chain.Genesis().Header()); err != errGenesisNotSigned {
+		t.Errorf("genesis error mismatch: have %v, want %v", err, errGenesisNotSigned)
+	}
+	signers, err := engine.blockSigners(block.Header())
+	if err != nil {
+		t.Fatalf("Failed to retrieve block signers: %v", err)
+	}
+	if signers.Proposer != engine.Address() || signers.Hash != block.Hash() {
+		t.Errorf("proposer mismatch: have %v, want %v", signers.Proposer.Hex(), engine.Address().Hex())
+	}
+	if len(signers.Signers) != 1 || signers.Signers[0] != engine.Address() {
+		t.Errorf("signers mismatch: have %v, want [%v]", signers.Signers, engine.Address().Hex())
+	}
+	if cached, _ := engine.blockSigners(block.Header()); cached != signers {
+		t.Errorf("block signers not served from the cache")
+	}
+}
+
+func TestUptimeReport(t *testing.T) {
+	genesisCfg, nodeKeys := getGenesisAndKeys(1, true)
+	chain, engine, _ := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer chain.Stop()
+	api := &API{chain: chain, istanbul: engine}
+	engine.config.BlockPeriod = 1
+
+	block := chain.Genesis()
+	for i := 0; i < 12; i++ {
+		var err error
+		if block, err = makeBlock(nodeKeys, chain, engine, block); err != nil {
+			t.Fatalf("Failed to make block %d: %v", i+1, err)
+		}
+	}
+	report, err := api.MyUptime(nil)
+	if err != nil {
+		t.Fatalf("Failed to compute the uptime of the current epoch: %v", err)
+	}
+	if report.Epoch != 2 || !report.Elected || report.Final || report.Uptime != nil {
+		t.Errorf("current epoch report mismatch: have %+v, want an unprepared score of epoch 2", report)
+	}
+	epoch := uint64(1)
+	if report, err = api.MyUptime(&epoch); err != nil {
+		t.Fatalf("Failed to compute the uptime of the first epoch: %v", err)
+	}
+	if report.Validator != engine.ValidatorAddress() || !report.Final || report.BlockNumber != 10 || report.Uptime == nil {
+		t.Fatalf("first epoch report mismatch: have %+v", report)
+	}
+	if report.LookbackWindow != 3 || report.MonitoredBlocks != 6 || report.Uptime.ToInt().Cmp(params.Fixidity1) != 0 {
+		t.Errorf("first epoch score mismatch: have %v over %d blocks, want %v over 6", report.Uptime, report.MonitoredBlocks, params.Fixidity1)
+	}
+	epoch = 3
+	if _, err := api.MyUptime(&epoch); err == nil {
+		t.Errorf("uptime of an epoch not started computed")
+	}
+}
+
+// epochRewardsMock serves the core contract calls of the epoch rewards distribution,
+// paying every validator half of the maximum payment and every group a quarter of the
+// voter rewards.
+type epochRewardsMock struct {
+	group   common.Address
+	uptimes map[common.Address]*big.Int
+}
+
+func (m *epochRewardsMock) CarbonOffsettingPartner() common.Address { return common.ZeroAddress }
+func (m *epochRewardsMock) UpdateTargetVotingYield()                {}
+func (m *epochRewardsMock) CalculateTargetEpochRewards() (*big.Int, *big.Int, *big.Int, *big.Int) {
+	return big.NewInt(100), big.NewInt(1000), big.NewInt(50), big.NewInt(10)
+}
+func (m *epochRewardsMock) UpdateValidatorScoreFromSigner(validator common.Address, uptime *big.Int) {
+	m.uptimes[validator] = uptime
+}
+func (m *epochRewardsMock) GetMembershipInLastEpochFromSigner(account common.Address) common.Address {
+	return m.group
+}
+func (m *epochRewardsMock) DistributeEpochPaymentsFromSigner(validator common.Address, maxPayment *big.Int) *big.Int {
+	return new(big.Int).Div(maxPayment, big.NewInt(2))
+}
+func (m *epochRewardsMock) GetGroupEpochRewards(group common.Address, maxTotalRewards *big.Int, uptimes []*big.Int) *big.Int {
+	return new(big.Int).Div(maxTotalRewards, big.NewInt(4))
+}
+
+func TestEstimateEpochRewards(t *testing.T) {
+	genesisCfg, nodeKeys := getGenesisAndKeys(1, true)
+	chain, engine, _ := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer chain.Stop()
+	api := &CeloAPI{chain: chain, istanbul: engine}
+	engine.config.BlockPeriod = 1
+
+	block := chain.Genesis()
+	for i := 0; i < 10; i++ {
+		var err error
+		if block, err = makeBlock(nodeKeys, chain, engine, block); err != nil {
+			t.Fatalf("Failed to make block %d: %v", i+1, err)
+		}
+	}
+	if _, err := api.EstimateEpochRewards(); err == nil {
+		t.Errorf("rewards estimated on the last block of an epoch")
+	}
+	// The uptime scores need a lookback window of blocks in the epoch
+	for i := 10; i < 15; i++ {
+		var err error
+		if block, err = makeBlock(nodeKeys, chain, engine, block); err != nil {
+			t.Fatalf("Failed to make block %d: %v", i+1, err)
+		}
+	}
+	celo := testutil.NewCeloMock()
+	contracts := &epochRewardsMock{group: common.HexToAddress("0x9a"), uptimes: make(map[common.Address]*big.Int)}
+	for id, address := range map[common.Hash]common.Address{
+		config.EpochRewardsRegistryId: common.HexToAddress("0x10"),
+		config.ValidatorsRegistryId:   common.HexToAddress("0x11"),
+		config.ElectionRegistryId:     common.HexToAddress("0x12"),
+	} {
+		celo.Registry.AddContract(id, address)
+		mock := testutil.NewContractMock(abis.AbiFor(id), contracts)
+		celo.Runner.RegisterContract(address, &mock)
+	}
+	state, err := chain.StateAt(block.Root())
+	if err != nil {
+		t.Fatalf("Failed to open the head state: %v", err)
+	}
+	estimate, err := engine.estimateEpochRewardsWith(block.Header(), state, celo.Runner)
+	if err != nil {
+		t.Fatalf("Failed to estimate the epoch rewards: %v", err)
+	}
+	if estimate.Epoch != 2 || estimate.BlockNumber != 15 || estimate.EpochLastBlock != 20 || estimate.Frozen {
+		t.Errorf("estimate position mismatch: have epoch %d at %d ending at %d, want epoch 2 at 15 ending at 20", estimate.Epoch, estimate.BlockNumber, estimate.EpochLastBlock)
+	}
+	if estimate.MaxValidatorReward.ToInt().Int64() != 100 || estimate.TotalVoterRewards.ToInt().Int64() != 1000 ||
+		estimate.CommunityReward.ToInt().Int64() != 50 || estimate.CarbonOffsettingPartnerReward.ToInt().Sign() != 0 {
+		t.Errorf("target rewards mismatch: have %+v", estimate)
+	}
+	if len(estimate.Validators) != 1 {
+		t.Fatalf("validator estimates mismatch: have %d, want 1", len(estimate.Validators))
+	}
+	val := estimate.Validators[0]
+	if val.Address != engine.ValidatorAddress() || val.Group != contracts.group || val.Reward.ToInt().Int64() != 50 {
+		t.Errorf("validator estimate mismatch: have %+v", val)
+	}
+	if uptime := contracts.uptimes[val.Address]; uptime == nil || uptime.Cmp(val.Uptime.ToInt()) != 0 {
+		t.Errorf("validator score updated with %v, want the reported uptime %v", uptime, val.Uptime)
+	}
+	if len(estimate.Groups) != 1 {
+		t.Fatalf("group estimates mismatch: have %d, want 1", len(estimate.Groups))
+	}
+	if group := estimate.Groups[0]; group.Group != contracts.group || group.ValidatorRewards.ToInt().Int64() != 50 || group.VoterRewards.ToInt().Int64() != 250 {
+		t.Errorf("group estimate mismatch: have %+v", group)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"errors"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core/types"
+)
+
+// inmemoryBlockSigners is the number of recent block signers to keep in memory.
+const inmemoryBlockSigners = 1024
+
+// errGenesisNotSigned is returned when requesting the signers of the genesis block.
+var errGenesisNotSigned = errors.New("genesis block has no signers")
+
+// AggregatedSeal is the JSON representation of types.IstanbulAggregatedSeal.
+type AggregatedSeal struct {
+	Bitmap    *hexutil.Big  `json:"bitmap"`
+	Signature hexutil.Bytes `json:"signature"`
+	Round     *hexutil.Big  `json:"round"`
+}
+
+// BlockSigners identifies the proposer of a block and the validators whose
+// committed seals were aggregated in it.
+type BlockSigners struct {
+	Number         hexutil.Uint64   `json:"number"`
+	Hash           common.Hash      `json:"hash"`
+	Proposer       common.Address   `json:"proposer"`
+	Signers        []common.Address `json:"signers"` // Validators set in the seal bitmap, in validator set order
+	AggregatedSeal AggregatedSeal   `json:"aggregatedSeal"`
+}
+
+// blockSigners returns the signers of the given block. As a block can't change
+// once inserted, they are cached by block hash to avoid recovering the proposer
+// and walking the validator set on every request.
+func (sb *Backend) blockSigners(header *types.Header) (*BlockSigners, error) {
+	hash := header.Hash()
+	if signers, ok := sb.recentBlockSigners.Get(hash); ok {
+		return signers.(*BlockSigners), nil
+	}
+	if header.Number.Sign() == 0 {
+		return nil, errGenesisNotSigned
+	}
+	proposer, err := sb.Author(header)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := header.IstanbulExtra()
+	if err != nil {
+		return nil, err
+	}
+	// The block is sealed by the validators elected for it, the ones of its parent
+	seal := extra.AggregatedSeal
+	validators := sb.getValidators(header.Number.Uint64()-1, header.ParentHash)
+	signers := &BlockSigners{
+		Number:   hexutil.Uint64(header.Number.Uint64()),
+		Hash:     hash,
+		Proposer: proposer,
+		Signers:  make([]common.Address, 0),
+		AggregatedSeal: AggregatedSeal{
+			Bitmap:    (*hexutil.Big)(seal.Bitmap),
+			Signature: seal.Signature,
+			Round:     (*hexutil.Big)(seal.Round),
+		},
+	}
+	if seal.Bitmap != nil {
+		for i, validator := range validators.List() {
+			if seal.Bitmap.Bit(i) == 1 {
+				signers.Signers = append(signers.Signers, validator.Address())
+			}
+		}
+	}
+	sb.recentBlockSigners.Add(hash, signers)
+	return signers, nil
+}
@@ -18,9 +18,11 @@ package backend
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
+	"math/bits"
 	"time"
 
 	"github.com/celo-org/celo-blockchain/common"
@@ -28,14 +30,15 @@ import (
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
 	istanbulCore "github.com/celo-org/celo-blockchain/consensus/istanbul/core"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul/uptime"
-	"github.com/celo-org/celo-blockchain/consensus/istanbul/validator"
 	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
+	"github.com/celo-org/celo-blockchain/contracts/currency"
 	gpm "github.com/celo-org/celo-blockchain/contracts/gasprice_minimum"
 	"github.com/celo-org/celo-blockchain/contracts/gold_token"
 	"github.com/celo-org/celo-blockchain/core"
 	ethCore "github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/state"
 	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
 	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
 	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/rlp"
@@ -386,7 +389,12 @@ func (sb *Backend) verifyAggregatedSeals(chain consensus.ChainHeaderReader, head
 }
 
 func (sb *Backend) verifyAggregatedSeal(headerHash common.Hash, validators istanbul.ValidatorSet, aggregatedSeal types.IstanbulAggregatedSeal) error {
-	logger := sb.logger.New("func", "Backend.verifyAggregatedSeal()")
+	return verifyAggregatedSeal(sb.logger.New("func", "Backend.verifyAggregatedSeal()"), headerHash, validators, aggregatedSeal)
+}
+
+// verifyAggregatedSeal checks that the aggregated seal of the header hash holds a
+// quorum of signatures of the validators.
+func verifyAggregatedSeal(logger log.Logger, headerHash common.Hash, validators istanbul.ValidatorSet, aggregatedSeal types.IstanbulAggregatedSeal) error {
 	if len(aggregatedSeal.Signature) != types.IstanbulExtraBlsSignature {
 		return errInvalidAggregatedSeal
 	}
@@ -441,6 +449,12 @@ func (sb *Backend) VerifySeal(header *types.Header) error {
 // rules of a particular engine. The changes are executed inline.
 // The parent seal is not included when the node is not validating.
 func (sb *Backend) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	return sb.PrepareContext(context.Background(), chain, header)
+}
+
+// PrepareContext implements consensus.ContextPreparer, it is like Prepare but stops
+// waiting for the block time and the parent commits once ctx is done.
+func (sb *Backend) PrepareContext(ctx context.Context, chain consensus.ChainHeaderReader, header *types.Header) error {
 	// copy the parent extra data as the header extra data
 	number := header.Number.Uint64()
 	parent := chain.GetHeader(header.ParentHash, number-1)
@@ -464,7 +478,13 @@ func (sb *Backend) Prepare(chain consensus.ChainHeaderReader, header *types.Head
 		sb.sleepGauge.Update(0)
 	} else {
 		sb.sleepGauge.Update(delay.Nanoseconds())
-		time.Sleep(delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
 	}
 
 	if err := writeEmptyIstanbulExtra(header); err != nil {
@@ -475,7 +495,7 @@ func (sb *Backend) Prepare(chain consensus.ChainHeaderReader, header *types.Head
 	// Prepare is called from non-validators, so don't bother with the parent seal unless this
 	// block is to be proposed instead of for the local state.
 	if sb.IsValidating() {
-		return sb.addParentSeal(chain, header)
+		return sb.addParentSeal(ctx, chain, header)
 	} else {
 		return nil
 	}
@@ -535,6 +555,12 @@ func (sb *Backend) LookbackWindow(header *types.Header, state *state.StateDB) ui
 // Note: The block header and state database might be updated to reflect any
 // consensus rules that happen at finalization (e.g. block rewards).
 func (sb *Backend) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction) {
+	sb.FinalizeWithEVMRunnerFactory(chain, header, state, txs, sb.chain)
+}
+
+// FinalizeWithEVMRunnerFactory is like Finalize, but makes the system contract calls
+// through the EVMRunners handed out by factory, letting them be traced.
+func (sb *Backend) FinalizeWithEVMRunnerFactory(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, factory vm.EVMRunnerFactory) {
 	start := time.Now()
 	defer sb.finalizationTimer.UpdateSince(start)
 
@@ -549,7 +575,7 @@ func (sb *Backend) Finalize(chain consensus.ChainHeaderReader, header *types.Hea
 	state.Prepare(common.Hash{}, len(txs))
 
 	snapshot := state.Snapshot()
-	vmRunner := sb.chain.NewEVMRunner(header, state)
+	vmRunner := factory.NewEVMRunner(header, state)
 	err := gold_token.SetInitialTotalSupplyIfUnset(sb.db, vmRunner)
 	if err != nil {
 		state.RevertToSnapshot(snapshot)
@@ -564,10 +590,20 @@ func (sb *Backend) Finalize(chain consensus.ChainHeaderReader, header *types.Hea
 		}
 	}
 
+	if sb.ChainConfig().IsMultiFeeMarket(header.Number) {
+		// Move the per currency base fees based on the gas each currency consumed in this block
+		whitelist, err := currency.CurrencyWhitelist(vmRunner)
+		if err != nil {
+			logger.Warn("Failed to get the currency whitelist for the multi fee market", "err", err)
+		}
+		gasLimit := blockchain_parameters.GetBlockGasLimitOrDefault(vmRunner)
+		core.UpdateMultiFeeMarket(sb.ChainConfig().MultiFeeMarket, state, whitelist, gasLimit)
+	}
+
 	lastBlockOfEpoch := istanbul.IsLastBlockOfEpoch(header.Number.Uint64(), sb.config.Epoch)
 	if lastBlockOfEpoch {
 		snapshot = state.Snapshot()
-		err = sb.distributeEpochRewards(header, state)
+		err = sb.distributeEpochRewards(header, state, vmRunner)
 		if err != nil {
 			sb.logger.Error("Failed to distribute epoch rewards", "blockNumber", header.Number, "err", err)
 			state.RevertToSnapshot(snapshot)
@@ -662,6 +698,15 @@ func (sb *Backend) APIs(chain consensus.ChainHeaderReader) []rpc.API {
 		Version:   "1.0",
 		Service:   &API{chain: chain, istanbul: sb},
 		Public:    true,
+	}, {
+		Namespace: "celo",
+		Version:   "1.0",
+		Service:   &CeloAPI{chain: chain, istanbul: sb},
+		Public:    true,
+	}, {
+		Namespace: "debug",
+		Version:   "1.0",
+		Service:   &DebugAPI{istanbul: sb},
 	}}
 }
 
@@ -880,7 +925,7 @@ func (sb *Backend) snapshot(chain consensus.ChainHeaderReader, number uint64, ha
 		}
 
 		if (blockHash != common.Hash{}) {
-			if s, err := loadSnapshot(sb.config.Epoch, sb.db, blockHash); err == nil {
+			if s, err := loadSnapshot(sb.config.Epoch, sb.db, blockHash, numberIter); err == nil {
 				log.Trace("Loaded validator set snapshot from disk", "number", numberIter, "hash", blockHash)
 				snap = s
 				sb.recentSnapshots.Add(numberIter, snap)
@@ -912,25 +957,11 @@ func (sb *Backend) snapshot(chain consensus.ChainHeaderReader, number uint64, ha
 			return nil, errors.New("Cannot load genesis")
 		}
 
-		istanbulExtra, err := genesis.IstanbulExtra()
-		if err != nil {
-			log.Error("Unable to extract istanbul extra", "err", err)
+		var err error
+		if snap, err = genesisSnapshot(sb.config.Epoch, genesis); err != nil {
 			return nil, err
 		}
 
-		// The genesis block should have an empty RemovedValidators set.  If not, throw an error
-		if istanbulExtra.RemovedValidators.BitLen() != 0 {
-			log.Error("Genesis block has a non empty RemovedValidators set")
-			return nil, errInvalidValidatorSetDiff
-		}
-
-		validators, err := istanbul.CombineIstanbulExtraToValidatorData(istanbulExtra.AddedValidators, istanbulExtra.AddedValidatorsPublicKeys)
-		if err != nil {
-			log.Error("Cannot construct validators data from istanbul extra")
-			return nil, errInvalidValidatorSetDiff
-		}
-		snap = newSnapshot(sb.config.Epoch, 0, genesis.Hash(), validator.NewSet(validators))
-
 		if err := snap.store(sb.db); err != nil {
 			log.Error("Unable to store snapshot", "err", err)
 			return nil, err
@@ -988,7 +1019,7 @@ func (sb *Backend) snapshot(chain consensus.ChainHeaderReader, number uint64, ha
 	return returnSnap, nil
 }
 
-func (sb *Backend) addParentSeal(chain consensus.ChainHeaderReader, header *types.Header) error {
+func (sb *Backend) addParentSeal(ctx context.Context, chain consensus.ChainHeaderReader, header *types.Header) error {
 	number := header.Number.Uint64()
 	logger := sb.logger.New("func", "addParentSeal", "number", number)
 
@@ -1004,19 +1035,25 @@ func (sb *Backend) addParentSeal(chain consensus.ChainHeaderReader, header *type
 		return err
 	}
 
+	// need to pass the previous block from the parent to get the parent's validators
+	// (otherwise we'd be getting the validators for the current block)
+	parentValidators := sb.getValidators(parent.Number.Uint64()-1, parent.ParentHash)
+
 	createParentSeal := func() types.IstanbulAggregatedSeal {
 		// In some cases, "addParentSeal" may be called before sb.core has moved to the next sequence,
 		// preventing signature aggregation.
 		// This typically happens in round > 0, since round 0 typically hits the "time.Sleep()"
 		// above.
 		// When this happens, loop until sb.core moves to the next sequence, with a limit of 500ms.
-		seq := waitCoreToReachSequence(sb.core, header.Number)
+		seq := waitCoreToReachSequence(ctx, sb.core, header.Number)
 		if seq == nil {
 			return parentExtra.AggregatedSeal
 		}
 
 		logger = logger.New("parentAggregatedSeal", parentExtra.AggregatedSeal.String(), "cur_seq", seq)
 
+		sb.waitForParentCommits(ctx, seq, parentExtra.AggregatedSeal, parentValidators.Size())
+
 		parentCommits := sb.core.ParentCommits()
 		if parentCommits == nil || parentCommits.Size() == 0 {
 			logger.Debug("No additional seals to combine with ParentAggregatedSeal")
@@ -1025,29 +1062,93 @@ func (sb *Backend) addParentSeal(chain consensus.ChainHeaderReader, header *type
 
 		logger = logger.New("numParentCommits", parentCommits.Size())
 		logger.Trace("Found commit messages from previous sequence to combine with ParentAggregatedSeal")
+		return mergeParentCommits(logger, parent.Hash(), parentValidators, parentExtra.AggregatedSeal, parentCommits)
+	}
 
-		// if we had any seals gossiped to us, proceed to add them to the
-		// already aggregated signature
-		unionAggregatedSeal, err := istanbulCore.UnionOfSeals(parentExtra.AggregatedSeal, parentCommits)
-		if err != nil {
-			logger.Error("Failed to combine commit messages with ParentAggregatedSeal", "err", err)
-			return parentExtra.AggregatedSeal
-		}
+	parentSeal := createParentSeal()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	sb.parentSealIncludedGauge.Update(int64(countSigners(parentSeal.Bitmap)))
+	sb.parentSealAvailableGauge.Update(int64(parentValidators.Size()))
+	return writeAggregatedSeal(header, parentSeal, true)
+}
 
-		// need to pass the previous block from the parent to get the parent's validators
-		// (otherwise we'd be getting the validators for the current block)
-		parentValidators := sb.getValidators(parent.Number.Uint64()-1, parent.ParentHash)
-		// only update to use the union if we indeed provided a valid aggregate signature for this block
-		if err := sb.verifyAggregatedSeal(parent.Hash(), parentValidators, unionAggregatedSeal); err != nil {
-			logger.Error("Failed to verify combined ParentAggregatedSeal", "err", err)
-			return parentExtra.AggregatedSeal
+// mergeParentCommits adds the seals of the parent commits to the parent aggregated seal. The
+// parent seal is returned unchanged if the combined one doesn't verify against the parent hash.
+func mergeParentCommits(logger log.Logger, parentHash common.Hash, parentValidators istanbul.ValidatorSet, parentSeal types.IstanbulAggregatedSeal, parentCommits istanbulCore.MessageSet) types.IstanbulAggregatedSeal {
+	// if we had any seals gossiped to us, proceed to add them to the
+	// already aggregated signature
+	unionAggregatedSeal, err := istanbulCore.UnionOfSeals(parentSeal, parentCommits)
+	if err != nil {
+		logger.Error("Failed to combine commit messages with ParentAggregatedSeal", "err", err)
+		return parentSeal
+	}
+
+	// only update to use the union if we indeed provided a valid aggregate signature for this block
+	if err := verifyAggregatedSeal(logger, parentHash, parentValidators, unionAggregatedSeal); err != nil {
+		logger.Error("Failed to verify combined ParentAggregatedSeal", "err", err)
+		return parentSeal
+	}
+
+	logger.Debug("Succeeded in verifying combined ParentAggregatedSeal", "combinedParentAggregatedSeal", unionAggregatedSeal.String())
+	return unionAggregatedSeal
+}
+
+// waitForParentCommits gives slow validators more time to have their commits for the parent block
+// included in the parent aggregated seal, which counts towards their uptime score.
+// It waits for at most ParentSealExtraWait, and returns early once every parent validator
+// has signed or the core leaves the first round of the given sequence, since then
+// the extra time would be taken from a round that is already late, or ctx is done.
+func (sb *Backend) waitForParentCommits(ctx context.Context, sequence *big.Int, parentSeal types.IstanbulAggregatedSeal, numValidators int) {
+	if sb.config.ParentSealExtraWait == 0 {
+		return
+	}
+	start := time.Now()
+	defer sb.parentSealWaitTimer.UpdateSince(start)
+
+	timeout := time.After(time.Duration(sb.config.ParentSealExtraWait) * time.Millisecond)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if countParentSigners(parentSeal, sb.core.ParentCommits()) >= numValidators {
+			return
+		}
+		select {
+		case <-ticker.C:
+			view := sb.core.CurrentView()
+			if view == nil || view.Sequence == nil || view.Sequence.Cmp(sequence) != 0 || view.Round.Sign() != 0 {
+				return
+			}
+		case <-timeout:
+			return
+		case <-ctx.Done():
+			return
 		}
+	}
+}
 
-		logger.Debug("Succeeded in verifying combined ParentAggregatedSeal", "combinedParentAggregatedSeal", unionAggregatedSeal.String())
-		return unionAggregatedSeal
+// countParentSigners returns how many validators signed the parent block, either in its
+// aggregated seal or through the commits received after it was committed.
+func countParentSigners(parentSeal types.IstanbulAggregatedSeal, parentCommits istanbulCore.MessageSet) int {
+	bitmap := new(big.Int).Set(parentSeal.Bitmap)
+	if parentCommits != nil {
+		for _, v := range parentCommits.Values() {
+			if index, err := parentCommits.GetAddressIndex(v.Address); err == nil {
+				bitmap.SetBit(bitmap, int(index), 1)
+			}
+		}
 	}
+	return countSigners(bitmap)
+}
 
-	return writeAggregatedSeal(header, createParentSeal(), true)
+// countSigners returns the number of bits set in the bitmap of an aggregated seal.
+func countSigners(bitmap *big.Int) int {
+	count := 0
+	for _, word := range bitmap.Bits() {
+		count += bits.OnesCount(uint(word))
+	}
+	return count
 }
 
 // SetStartValidatingBlock sets block that the validator will start validating on (inclusive)
@@ -1223,7 +1324,7 @@ func writeAggregatedSeal(h *types.Header, aggregatedSeal types.IstanbulAggregate
 	return nil
 }
 
-func waitCoreToReachSequence(core istanbulCore.Engine, expectedSequence *big.Int) *big.Int {
+func waitCoreToReachSequence(ctx context.Context, core istanbulCore.Engine, expectedSequence *big.Int) *big.Int {
 	logger := log.New("func", "waitCoreToReachSequence")
 	timeout := time.After(500 * time.Millisecond)
 	ticker := time.NewTicker(10 * time.Millisecond)
@@ -1239,6 +1340,8 @@ func waitCoreToReachSequence(core istanbulCore.Engine, expectedSequence *big.Int
 		case <-timeout:
 			log.Trace("Timed out while waiting for core to sequence change, unable to combine commit messages with ParentAggregatedSeal", "cur_view", core.CurrentView())
 			return nil
+		case <-ctx.Done():
+			return nil
 		}
 	}
 }
@@ -18,18 +18,27 @@ package backend
 
 import (
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
 	"math/big"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/celo-org/celo-blockchain/accounts"
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/common/hexutil"
 	"github.com/celo-org/celo-blockchain/consensus"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul/core"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/validator"
 	bccore "github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
 	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
 	"github.com/celo-org/celo-blockchain/rlp"
 	. "github.com/onsi/gomega"
 )
@@ -54,6 +63,24 @@ func TestPrepare(t *testing.T) {
 	g.Expect(err).To(BeIdenticalTo(consensus.ErrUnknownAncestor))
 }
 
+func TestPrepareContextCancelled(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	chain, engine := newBlockChain(1, true)
+	defer stopEngine(engine)
+	defer chain.Stop()
+	header := makeHeader(chain.Genesis(), engine.config)
+	// Wait for a block time an hour ahead, which the cancelled context interrupts
+	engine.config.BlockPeriod = uint64(time.Now().Unix()) - chain.Genesis().Time() + 3600
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	err := engine.PrepareContext(ctx, chain, header)
+	g.Expect(err).To(BeIdenticalTo(context.Canceled))
+	g.Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+}
+
 func TestMakeBlockWithSignature(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -473,3 +500,179 @@ func TestWriteAggregatedSeal(t *testing.T) {
 	err = writeAggregatedSeal(h, invalidAggregatedSeal, true)
 	g.Expect(err).To(BeIdenticalTo(errInvalidAggregatedSeal))
 }
+
+func TestCountParentSigners(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(countSigners(big.NewInt(0))).To(Equal(0))
+	g.Expect(countSigners(big.NewInt(0b1011))).To(Equal(3))
+	g.Expect(countSigners(new(big.Int).Lsh(big.NewInt(1), 130))).To(Equal(1))
+
+	seal := types.IstanbulAggregatedSeal{Bitmap: big.NewInt(0b101)}
+	g.Expect(countParentSigners(seal, nil)).To(Equal(2))
+}
+
+// parentCommitsCore is a core at a fixed view, with parent commits that can be
+// added while the backend waits for them.
+type parentCommitsCore struct {
+	core.Engine
+	mu      sync.Mutex
+	view    *istanbul.View
+	commits core.MessageSet
+}
+
+func (c *parentCommitsCore) CurrentView() *istanbul.View {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.view
+}
+
+func (c *parentCommitsCore) ParentCommits() core.MessageSet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.commits
+}
+
+func (c *parentCommitsCore) set(view *istanbul.View, commits core.MessageSet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.view, c.commits = view, commits
+}
+
+// parentCommitsSet is a message set of commits for the parent block.
+type parentCommitsSet struct {
+	core.MessageSet
+	valSet istanbul.ValidatorSet
+	msgs   []*istanbul.Message
+}
+
+func (s *parentCommitsSet) Values() []*istanbul.Message { return s.msgs }
+func (s *parentCommitsSet) Size() int                   { return len(s.msgs) }
+func (s *parentCommitsSet) GetAddressIndex(addr common.Address) (uint64, error) {
+	index := s.valSet.GetIndex(addr)
+	if index < 0 {
+		return 0, errors.New("not a validator")
+	}
+	return uint64(index), nil
+}
+
+// newParentSealValidators returns a validator set and the keys of its validators, in the
+// order of the set.
+func newParentSealValidators(n int) (istanbul.ValidatorSet, []*ecdsa.PrivateKey) {
+	keys := make(map[common.Address]*ecdsa.PrivateKey)
+	validators := make([]istanbul.ValidatorData, n)
+	for i := range validators {
+		key, _ := crypto.GenerateKey()
+		blsPrivateKey, _ := blscrypto.ECDSAToBLS(key)
+		blsPublicKey, _ := blscrypto.PrivateToPublic(blsPrivateKey)
+		validators[i] = istanbul.ValidatorData{Address: crypto.PubkeyToAddress(key.PublicKey), BLSPublicKey: blsPublicKey}
+		keys[validators[i].Address] = key
+	}
+	valSet := validator.NewSet(validators)
+	ordered := make([]*ecdsa.PrivateKey, n)
+	for i, val := range valSet.List() {
+		ordered[i] = keys[val.Address()]
+	}
+	return valSet, ordered
+}
+
+// parentSeal returns the committed seal of the hash by the key at the round.
+func parentSeal(t *testing.T, key *ecdsa.PrivateKey, hash common.Hash, round *big.Int) []byte {
+	seal, err := SignBLSFn(key)(accounts.Account{}, core.PrepareCommittedSeal(hash, round), []byte{}, false, false)
+	if err != nil {
+		t.Fatalf("Failed to sign the parent seal: %v", err)
+	}
+	return seal[:]
+}
+
+func TestWaitForParentCommits(t *testing.T) {
+	valSet, keys := newParentSealValidators(2)
+	sequence := big.NewInt(5)
+	seal := types.IstanbulAggregatedSeal{Bitmap: big.NewInt(0b01)}
+	late := &parentCommitsSet{valSet: valSet, msgs: []*istanbul.Message{
+		istanbul.NewCommitMessage(&istanbul.CommittedSubject{}, crypto.PubkeyToAddress(keys[1].PublicKey)),
+	}}
+
+	mock := &parentCommitsCore{view: &istanbul.View{Sequence: sequence, Round: big.NewInt(0)}}
+	sb := &Backend{config: &istanbul.Config{ParentSealExtraWait: 2000}, core: mock, parentSealWaitTimer: metrics.NewTimer()}
+
+	// All the parent validators signed, no need to wait
+	start := time.Now()
+	sb.waitForParentCommits(context.Background(), sequence, types.IstanbulAggregatedSeal{Bitmap: big.NewInt(0b11)}, valSet.Size())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %v with every parent signer in the seal", elapsed)
+	}
+	// The late commit ends the wait
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		mock.set(mock.CurrentView(), late)
+	}()
+	start = time.Now()
+	sb.waitForParentCommits(context.Background(), sequence, seal, valSet.Size())
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("waited %v for a commit received after 50ms", elapsed)
+	}
+	// Leaving the first round of the sequence ends the wait
+	mock.set(mock.CurrentView(), nil)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		mock.set(&istanbul.View{Sequence: sequence, Round: big.NewInt(1)}, nil)
+	}()
+	start = time.Now()
+	sb.waitForParentCommits(context.Background(), sequence, seal, valSet.Size())
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("waited %v after a round change at 50ms", elapsed)
+	}
+	// Without commits, the wait is bounded
+	mock.set(&istanbul.View{Sequence: sequence, Round: big.NewInt(0)}, nil)
+	sb.config.ParentSealExtraWait = 100
+	start = time.Now()
+	sb.waitForParentCommits(context.Background(), sequence, seal, valSet.Size())
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > time.Second {
+		t.Errorf("waited %v for missing commits, want 100ms", elapsed)
+	}
+	// And disabled by default
+	sb.config.ParentSealExtraWait = 0
+	start = time.Now()
+	sb.waitForParentCommits(context.Background(), sequence, seal, valSet.Size())
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("waited %v with the extra wait disabled", elapsed)
+	}
+}
+
+func TestMergeParentCommits(t *testing.T) {
+	g := NewGomegaWithT(t)
+	valSet, keys := newParentSealValidators(4)
+	parentHash := common.HexToHash("0x1234")
+	round := big.NewInt(0)
+	logger := log.New()
+
+	// The parent was committed with a quorum of the first 3 validators
+	seals := make([][]byte, 3)
+	for i := range seals {
+		seals[i] = parentSeal(t, keys[i], parentHash, round)
+	}
+	signature, err := blscrypto.AggregateSignatures(seals)
+	g.Expect(err).ToNot(HaveOccurred())
+	seal := types.IstanbulAggregatedSeal{Bitmap: big.NewInt(0b0111), Signature: signature, Round: round}
+	g.Expect(verifyAggregatedSeal(logger, parentHash, valSet, seal)).To(Succeed())
+
+	commit := func(key *ecdsa.PrivateKey, hash common.Hash) *istanbul.Message {
+		return istanbul.NewCommitMessage(&istanbul.CommittedSubject{CommittedSeal: parentSeal(t, key, hash, round)}, crypto.PubkeyToAddress(key.PublicKey))
+	}
+
+	// The late commit of the last validator is merged, the ones already in the seal are ignored
+	late := &parentCommitsSet{valSet: valSet, msgs: []*istanbul.Message{commit(keys[0], parentHash), commit(keys[3], parentHash)}}
+	merged := mergeParentCommits(logger, parentHash, valSet, seal, late)
+	g.Expect(merged.Bitmap.Int64()).To(Equal(int64(0b1111)))
+	g.Expect(verifyAggregatedSeal(logger, parentHash, valSet, merged)).To(Succeed())
+
+	// A commit for another block invalidates the union, the parent seal is kept
+	invalid := &parentCommitsSet{valSet: valSet, msgs: []*istanbul.Message{commit(keys[3], common.HexToHash("0x5678"))}}
+	g.Expect(mergeParentCommits(logger, parentHash, valSet, seal, invalid)).To(Equal(seal))
+
+	// So are the commits of non validators
+	outsider, _ := crypto.GenerateKey()
+	unknown := &parentCommitsSet{valSet: valSet, msgs: []*istanbul.Message{commit(outsider, parentHash)}}
+	g.Expect(mergeParentCommits(logger, parentHash, valSet, seal, unknown)).To(Equal(seal))
+}
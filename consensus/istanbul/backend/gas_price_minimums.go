@@ -0,0 +1,213 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/contracts"
+	"github.com/celo-org/celo-blockchain/contracts/currency"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	gp "github.com/celo-org/celo-blockchain/eth/gasprice"
+	"github.com/celo-org/celo-blockchain/event"
+	"github.com/celo-org/celo-blockchain/p2p"
+)
+
+var (
+	// errUnknownGasPriceMinimumsBlock is returned when the block of a gas price
+	// minimum announcement isn't known, so that it can't be verified
+	errUnknownGasPriceMinimumsBlock = errors.New("unknown block of the gas price minimums")
+	// errUnauthorizedGasPriceMinimums is returned when a gas price minimum
+	// announcement isn't signed by the proposer of its block
+	errUnauthorizedGasPriceMinimums = errors.New("gas price minimums not signed by the block proposer")
+	// errInvalidGasPriceMinimums is returned when the announced gas price
+	// minimums aren't the ones of the block
+	errInvalidGasPriceMinimums = errors.New("invalid gas price minimums")
+)
+
+// computeGasPriceMinimums returns the whitelisted fee currencies at the block, led by
+// the zero address for CELO, and their gas price minimums, as returned by the
+// CurrentGasPriceMinimum RPC of a full node whose head is the block.
+func (sb *Backend) computeGasPriceMinimums(header *types.Header) ([]common.Address, []*big.Int, error) {
+	state, err := sb.stateAt(header.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+	vmRunner := sb.chain.NewEVMRunner(header, state)
+	whitelist, err := currency.CurrencyWhitelist(vmRunner)
+	if err != nil && err != contracts.ErrSmartContractNotDeployed && err != contracts.ErrRegistryContractNotDeployed {
+		return nil, nil, err
+	}
+	currencies := append([]common.Address{common.ZeroAddress}, whitelist...)
+	gasPriceMinimums := make([]*big.Int, len(currencies))
+	for i := range currencies {
+		var currencyAddress *common.Address
+		if i > 0 {
+			currencyAddress = &currencies[i]
+		}
+		if gasPriceMinimums[i], err = gp.GetBaseFeeForCurrency(vmRunner, currencyAddress, header.BaseFee); err != nil {
+			return nil, nil, err
+		}
+	}
+	return currencies, gasPriceMinimums, nil
+}
+
+// announceGasPriceMinimums signs and gossips the gas price minimums of a block
+// proposed by this validator, for the light clients.
+func (sb *Backend) announceGasPriceMinimums(block *types.Block) {
+	logger := sb.logger.New("func", "announceGasPriceMinimums", "number", block.NumberU64())
+
+	currencies, gasPriceMinimums, err := sb.computeGasPriceMinimums(block.Header())
+	if err != nil {
+		logger.Warn("Error retrieving the gas price minimums", "err", err)
+		return
+	}
+	announcement, err := istanbul.NewGasPriceMinimumAnnouncement(block.NumberU64(), block.Hash(), currencies, gasPriceMinimums, sb.Sign)
+	if err != nil {
+		logger.Warn("Error signing the gas price minimums", "err", err)
+		return
+	}
+	payload, err := istanbul.NewGasPriceMinimumMessage(announcement, announcement.Address()).Payload()
+	if err != nil {
+		logger.Warn("Error encoding the gas price minimums", "err", err)
+		return
+	}
+	if err := sb.gossipGasPriceMinimums(payload); err != nil {
+		logger.Warn("Error gossiping the gas price minimums", "err", err)
+	}
+	sb.setGasPriceMinimums(announcement)
+}
+
+// handleGasPriceMinimumMsg verifies the gas price minimum announcement of a
+// peer against the chain and relays it if it's newer than the previous ones.
+func (sb *Backend) handleGasPriceMinimumMsg(addr common.Address, peer consensus.Peer, payload []byte) error {
+	logger := sb.logger.New("func", "handleGasPriceMinimumMsg")
+
+	// Since this is a gossiped messaged, mark that the peer gossiped it (and presumably processed it) and check to see if this node already processed it
+	sb.gossipCache.MarkMessageProcessedByPeer(addr, payload)
+	if sb.gossipCache.CheckIfMessageProcessedBySelf(payload) {
+		return nil
+	}
+	defer sb.gossipCache.MarkMessageProcessedBySelf(payload)
+
+	// The announcement carries its own signature, the message doesn't
+	var msg istanbul.Message
+	if err := msg.FromPayload(payload, nil); err != nil {
+		logger.Debug("Error in decoding received gas price minimums", "err", err)
+		return err
+	}
+	announcement := msg.GasPriceMinimums()
+	if announcement == nil || announcement.Address() != msg.Address {
+		return errUnauthorizedGasPriceMinimums
+	}
+	logger = logger.New("number", announcement.Number, "from", announcement.Address())
+	if latest := sb.LatestGasPriceMinimums(); latest != nil && latest.Number >= announcement.Number {
+		return nil
+	}
+	if err := sb.verifyGasPriceMinimums(announcement); err != nil {
+		logger.Debug("Ignoring gas price minimums", "err", err)
+		if err == errUnknownGasPriceMinimumsBlock {
+			return nil
+		}
+		return err
+	}
+	if !sb.setGasPriceMinimums(announcement) {
+		return nil
+	}
+	logger.Trace("Relaying gas price minimums")
+	return sb.gossipGasPriceMinimums(payload)
+}
+
+// verifyGasPriceMinimums checks that the announcement is signed by the proposer
+// of a known block, and that its gas price minimums are the ones of the block.
+func (sb *Backend) verifyGasPriceMinimums(announcement *istanbul.GasPriceMinimumAnnouncement) error {
+	header := sb.chain.GetHeader(announcement.Hash, announcement.Number)
+	if header == nil {
+		return errUnknownGasPriceMinimumsBlock
+	}
+	if proposer, err := sb.Author(header); err != nil || proposer != announcement.Address() {
+		return errUnauthorizedGasPriceMinimums
+	}
+	currencies, gasPriceMinimums, err := sb.computeGasPriceMinimums(header)
+	if err != nil {
+		return err
+	}
+	if len(currencies) != len(announcement.Currencies) {
+		return errInvalidGasPriceMinimums
+	}
+	for i := range currencies {
+		if currencies[i] != announcement.Currencies[i] || gasPriceMinimums[i].Cmp(announcement.GasPriceMinimums[i]) != 0 {
+			return errInvalidGasPriceMinimums
+		}
+	}
+	return nil
+}
+
+// setGasPriceMinimums stores the announcement and notifies the subscribers if
+// it's newer than the latest one, returning whether it is.
+func (sb *Backend) setGasPriceMinimums(announcement *istanbul.GasPriceMinimumAnnouncement) bool {
+	sb.gasPriceMinimumsMu.Lock()
+	if sb.gasPriceMinimums != nil && sb.gasPriceMinimums.Number >= announcement.Number {
+		sb.gasPriceMinimumsMu.Unlock()
+		return false
+	}
+	sb.gasPriceMinimums = announcement
+	sb.gasPriceMinimumsMu.Unlock()
+
+	sb.gasPriceMinimumsFeed.Send(istanbul.GasPriceMinimumsEvent{Announcement: announcement})
+	return true
+}
+
+// gossipGasPriceMinimums sends the gas price minimums message to the peers that
+// support it and haven't sent it to us.
+func (sb *Backend) gossipGasPriceMinimums(payload []byte) error {
+	peers := sb.broadcaster.FindPeers(nil, p2p.AnyPurpose)
+	for nodeID, peer := range peers {
+		if peer.Version() < istanbul.Celo68 {
+			delete(peers, nodeID)
+		}
+	}
+	sb.gossipCache.MarkMessageProcessedBySelf(payload)
+	for nodeID, peer := range peers {
+		nodeAddr := crypto.PubkeyToAddress(*peer.Node().Pubkey())
+		if sb.gossipCache.CheckIfMessageProcessedByPeer(nodeAddr, payload) {
+			delete(peers, nodeID)
+			continue
+		}
+		sb.gossipCache.MarkMessageProcessedByPeer(nodeAddr, payload)
+	}
+	return sb.asyncMulticast(peers, payload, istanbul.GasPriceMinimumMsg)
+}
+
+// SubscribeGasPriceMinimums subscribes a channel to the verified gas price
+// minimum announcements, each newer than the previous ones.
+func (sb *Backend) SubscribeGasPriceMinimums(ch chan<- istanbul.GasPriceMinimumsEvent) event.Subscription {
+	return sb.gasPriceMinimumsScope.Track(sb.gasPriceMinimumsFeed.Subscribe(ch))
+}
+
+// LatestGasPriceMinimums returns the latest verified gas price minimum
+// announcement, or nil if there isn't any.
+func (sb *Backend) LatestGasPriceMinimums() *istanbul.GasPriceMinimumAnnouncement {
+	sb.gasPriceMinimumsMu.RLock()
+	defer sb.gasPriceMinimumsMu.RUnlock()
+	return sb.gasPriceMinimums
+}
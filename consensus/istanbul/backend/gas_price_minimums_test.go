@@ -0,0 +1,113 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/crypto"
+)
+
+func TestGasPriceMinimumAnnouncements(t *testing.T) {
+	genesisCfg, nodeKeys := getGenesisAndKeys(1, true)
+	chain, engine, _ := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer chain.Stop()
+	block, err := makeBlock(nodeKeys, chain, engine, chain.Genesis())
+	if err != nil {
+		t.Fatalf("Failed to make a block: %v", err)
+	}
+
+	// The proposer announces the gas price minimums of its block
+	var announcement *istanbul.GasPriceMinimumAnnouncement
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if announcement = engine.LatestGasPriceMinimums(); announcement != nil {
+			break
+		}
+	}
+	if announcement == nil {
+		t.Fatalf("gas price minimums not announced")
+	}
+	if announcement.Number != block.NumberU64() || announcement.Hash != block.Hash() || announcement.Address() != engine.Address() {
+		t.Fatalf("announcement mismatch: have %v, want block %d %x from %x", announcement, block.NumberU64(), block.Hash(), engine.Address())
+	}
+	if len(announcement.Currencies) == 0 || announcement.Currencies[0] != common.ZeroAddress || announcement.GasPriceMinimum(nil) == nil {
+		t.Fatalf("CELO gas price minimum missing: %v", announcement)
+	}
+	if err := engine.verifyGasPriceMinimums(announcement); err != nil {
+		t.Fatalf("announcement verification failed: %v", err)
+	}
+
+	// Announcements of other signers, values or blocks are rejected
+	other, _ := crypto.GenerateKey()
+	inflated := make([]*big.Int, len(announcement.GasPriceMinimums))
+	for i, gasPriceMinimum := range announcement.GasPriceMinimums {
+		inflated[i] = new(big.Int).Add(gasPriceMinimum, common.Big1)
+	}
+	tests := []struct {
+		number    uint64
+		hash      common.Hash
+		gasPrices []*big.Int
+		signingFn func([]byte) ([]byte, error)
+		wantErr   error
+	}{
+		{block.NumberU64(), block.Hash(), announcement.GasPriceMinimums, func(data []byte) ([]byte, error) { return crypto.Sign(crypto.Keccak256(data), other) }, errUnauthorizedGasPriceMinimums},
+		{block.NumberU64(), block.Hash(), inflated, engine.Sign, errInvalidGasPriceMinimums},
+		{block.NumberU64() + 1, common.HexToHash("0x01"), announcement.GasPriceMinimums, engine.Sign, errUnknownGasPriceMinimumsBlock},
+	}
+	for i, tt := range tests {
+		invalid, err := istanbul.NewGasPriceMinimumAnnouncement(tt.number, tt.hash, announcement.Currencies, tt.gasPrices, tt.signingFn)
+		if err != nil {
+			t.Fatalf("test %d: failed to sign the announcement: %v", i, err)
+		}
+		if err := engine.verifyGasPriceMinimums(invalid); err != tt.wantErr {
+			t.Errorf("test %d: verification error mismatch: have %v, want %v", i, err, tt.wantErr)
+		}
+	}
+
+	// A relayed announcement is kept once verified, the older ones are ignored
+	payload, err := istanbul.NewGasPriceMinimumMessage(announcement, announcement.Address()).Payload()
+	if err != nil {
+		t.Fatalf("Failed to encode the announcement: %v", err)
+	}
+	// Forget our own announcement, as if it came from another validator
+	engine.gossipCache = istanbul.NewLRUGossipCache(inmemoryPeers, inmemoryMessages)
+	engine.gasPriceMinimumsMu.Lock()
+	engine.gasPriceMinimums = nil
+	engine.gasPriceMinimumsMu.Unlock()
+
+	events := make(chan istanbul.GasPriceMinimumsEvent, 1)
+	sub := engine.SubscribeGasPriceMinimums(events)
+	defer sub.Unsubscribe()
+	if err := engine.handleGasPriceMinimumMsg(common.HexToAddress("0x02"), &MockPeer{}, payload); err != nil {
+		t.Fatalf("Failed to handle the announcement: %v", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Announcement.Hash != block.Hash() {
+			t.Errorf("announced block mismatch: have %x, want %x", ev.Announcement.Hash, block.Hash())
+		}
+	default:
+		t.Fatalf("relayed announcement not posted")
+	}
+	if engine.setGasPriceMinimums(announcement) {
+		t.Errorf("announcement of the same block kept again")
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/celo-org/celo-blockchain/consensus/istanbul/validator"
 	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/event"
+	"github.com/celo-org/celo-blockchain/internal/chaos"
 	"github.com/celo-org/celo-blockchain/p2p"
 	"github.com/celo-org/celo-blockchain/p2p/enode"
 	"github.com/celo-org/celo-blockchain/rlp"
@@ -83,6 +84,9 @@ func (sb *Backend) HandleMsg(addr common.Address, msg p2p.Msg, peer consensus.Pe
 		case istanbul.VersionCertificatesMsg:
 			go sb.announceManager.HandleVersionCertificatesMsg(addr, peer, data)
 			return true, nil
+		case istanbul.GasPriceMinimumMsg:
+			go sb.handleGasPriceMinimumMsg(addr, peer, data)
+			return true, nil
 		case istanbul.ValidatorHandshakeMsg:
 			logger.Warn("Received unexpected Istanbul validator handshake message")
 			return true, nil
@@ -94,6 +98,9 @@ func (sb *Backend) HandleMsg(addr common.Address, msg p2p.Msg, peer consensus.Pe
 		// Handle messages as primary validator
 		switch msg.Code {
 		case istanbul.ConsensusMsg:
+			if chaos.DropConsensusMsg() {
+				return true, nil
+			}
 			go sb.istanbulEventMux.Post(istanbul.MessageEvent{
 				Payload: data,
 			})
@@ -118,6 +125,9 @@ func (sb *Backend) HandleMsg(addr common.Address, msg p2p.Msg, peer consensus.Pe
 		case istanbul.VersionCertificatesMsg:
 			go sb.announceManager.HandleVersionCertificatesMsg(addr, peer, data)
 			return true, nil
+		case istanbul.GasPriceMinimumMsg:
+			go sb.handleGasPriceMinimumMsg(addr, peer, data)
+			return true, nil
 		case istanbul.ValidatorHandshakeMsg:
 			logger.Warn("Received unexpected Istanbul validator handshake message")
 			return true, nil
@@ -151,6 +161,9 @@ func (sb *Backend) HandleMsg(addr common.Address, msg p2p.Msg, peer consensus.Pe
 		case istanbul.VersionCertificatesMsg:
 			go sb.announceManager.HandleVersionCertificatesMsg(addr, peer, data)
 			return true, nil
+		case istanbul.GasPriceMinimumMsg:
+			go sb.handleGasPriceMinimumMsg(addr, peer, data)
+			return true, nil
 		case istanbul.ValidatorHandshakeMsg:
 			logger.Warn("Received unexpected Istanbul validator handshake message")
 			return true, nil
@@ -279,7 +292,7 @@ func (sb *Backend) UpdateMetricsForParentOfBlock(child *types.Block) {
 		// to re-propose an existing block, thus not placing it's own signature on it.
 		gpAuthor := sb.AuthorForBlock(number - 2)
 		for i := int64(0); i < missedRounds; i++ {
-			proposer := validator.GetProposerSelector(sb.config.ProposerPolicy)(gpValSet, gpAuthor, uint64(i))
+			proposer := validator.NewProposerSelector(sb.config)(gpValSet, gpAuthor, uint64(i))
 			if sb.Address() == proposer.Address() {
 				sb.blocksMissedRoundsAsProposerMeter.Mark(1)
 				break
@@ -330,6 +343,7 @@ func (sb *Backend) newChainHead(newBlock *types.Block) {
 
 	// Update metrics for whether we were elected and signed the parent of this block.
 	sb.UpdateMetricsForParentOfBlock(newBlock)
+	sb.updateUptimeGauges(newBlock.Header())
 
 	// If this is the last block of the epoch:
 	// * Print an easy to find log message giving our address and whether we're elected in next epoch.
@@ -362,6 +376,11 @@ func (sb *Backend) newChainHead(newBlock *types.Block) {
 		}
 	}
 
+	// Announce the gas price minimums of the blocks we proposed to the light clients
+	if sb.IsValidating() && newBlock.Coinbase() == sb.Address() {
+		go sb.announceGasPriceMinimums(newBlock)
+	}
+
 	sb.blocksFinalizedTransactionsGauge.Update(int64(len(newBlock.Transactions())))
 	sb.blocksFinalizedGasUsedGauge.Update(int64(newBlock.GasUsed()))
 	sb.logger.Trace("End newChainHead", "number", newBlock.Number().Uint64())
@@ -22,7 +22,9 @@ import (
 	"time"
 
 	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/uptime"
 	"github.com/celo-org/celo-blockchain/contracts"
 	"github.com/celo-org/celo-blockchain/contracts/config"
 	"github.com/celo-org/celo-blockchain/contracts/currency"
@@ -36,12 +38,11 @@ import (
 	"github.com/celo-org/celo-blockchain/core/vm"
 )
 
-func (sb *Backend) distributeEpochRewards(header *types.Header, state *state.StateDB) error {
+func (sb *Backend) distributeEpochRewards(header *types.Header, state *state.StateDB, vmRunner vm.EVMRunner) error {
 	start := time.Now()
 	defer sb.rewardDistributionTimer.UpdateSince(start)
 	logger := sb.logger.New("func", "Backend.distributeEpochPaymentsAndRewards", "blocknum", header.Number.Uint64())
 
-	vmRunner := sb.chain.NewEVMRunner(header, state)
 	if !sb.ChainConfig().IsGingerbread(header.Number) {
 		// Check if reward distribution has been frozen and return early without error if it is.
 		if frozen, err := freezer.IsFrozen(vmRunner, config.EpochRewardsRegistryId); err != nil {
@@ -93,7 +94,7 @@ func (sb *Backend) distributeEpochRewards(header *types.Header, state *state.Sta
 		return err
 	}
 
-	uptimes, err := sb.updateValidatorScores(header, state, valSet)
+	uptimes, err := sb.updateValidatorScores(header, state, valSet, vmRunner)
 	if err != nil {
 		return err
 	}
@@ -144,7 +145,7 @@ func (sb *Backend) distributeEpochRewards(header *types.Header, state *state.Sta
 	return nil
 }
 
-func (sb *Backend) updateValidatorScores(header *types.Header, state *state.StateDB, valSet []istanbul.Validator) ([]*big.Int, error) {
+func (sb *Backend) updateValidatorScores(header *types.Header, state *state.StateDB, valSet []istanbul.Validator, vmRunner vm.EVMRunner) ([]*big.Int, error) {
 	epoch := istanbul.GetEpochNumber(header.Number.Uint64(), sb.EpochSize())
 	logger := sb.logger.New("func", "Backend.updateValidatorScores", "blocknum", header.Number.Uint64(), "epoch", epoch, "epochsize", sb.EpochSize())
 
@@ -156,7 +157,6 @@ func (sb *Backend) updateValidatorScores(header *types.Header, state *state.Stat
 		return nil, err
 	}
 
-	vmRunner := sb.chain.NewEVMRunner(header, state)
 	for i, val := range valSet {
 		logger.Trace("Updating validator score", "uptime", uptimes[i], "address", val.Address())
 		err := validators.UpdateValidatorScore(vmRunner, val.Address(), uptimes[i])
@@ -249,3 +249,163 @@ func (sb *Backend) distributeVoterRewards(vmRunner vm.EVMRunner, valSet []istanb
 
 	return gold_token.Mint(vmRunner, lockedGoldAddress, electionRewards)
 }
+
+// EpochRewardsEstimate is a projection of the rewards that would be distributed if the
+// in-progress epoch ended on top of the given block.
+type EpochRewardsEstimate struct {
+	Epoch          uint64 `json:"epoch"`
+	BlockNumber    uint64 `json:"blockNumber"`
+	EpochLastBlock uint64 `json:"epochLastBlock"`
+	// Frozen is true if reward distribution is frozen, in which case all rewards are zero.
+	Frozen bool `json:"frozen"`
+
+	MaxValidatorReward            *hexutil.Big `json:"maxValidatorReward"`
+	TotalVoterRewards             *hexutil.Big `json:"totalVoterRewards"`
+	CommunityReward               *hexutil.Big `json:"communityReward"`
+	CarbonOffsettingPartnerReward *hexutil.Big `json:"carbonOffsettingPartnerReward"`
+
+	Validators []*ValidatorRewardEstimate `json:"validators"`
+	Groups     []*GroupRewardEstimate     `json:"groups"`
+}
+
+// ValidatorRewardEstimate is the projected epoch payment of a single elected validator.
+// The reward is denominated in StableToken and includes the group's commission.
+type ValidatorRewardEstimate struct {
+	Address common.Address `json:"address"`
+	Group   common.Address `json:"group"`
+	Uptime  *hexutil.Big   `json:"uptime"`
+	Reward  *hexutil.Big   `json:"reward"`
+}
+
+// GroupRewardEstimate is the projected epoch payment of a validator group. ValidatorRewards
+// is the sum of the payments to its elected members (in StableToken), VoterRewards is the
+// amount of CELO that would be distributed to its voters.
+type GroupRewardEstimate struct {
+	Group            common.Address `json:"group"`
+	ValidatorRewards *hexutil.Big   `json:"validatorRewards"`
+	VoterRewards     *hexutil.Big   `json:"voterRewards"`
+}
+
+// estimateEpochRewards runs the epoch rewards calculation against a copy of the given state, as if
+// the epoch ended right after header, and returns the resulting per validator and per group rewards.
+// The uptime scores are computed from the parent seals seen so far in the epoch, so the estimate
+// converges to the real distribution as the epoch progresses.
+func (sb *Backend) estimateEpochRewards(header *types.Header, state *state.StateDB) (*EpochRewardsEstimate, error) {
+	// Never touch the caller's state, the reward calculation writes to it.
+	state = state.Copy()
+	return sb.estimateEpochRewardsWith(header, state, sb.chain.NewEVMRunner(header, state))
+}
+
+// estimateEpochRewardsWith runs the epoch rewards calculation of estimateEpochRewards through the
+// given runner, which may freely write to the state.
+func (sb *Backend) estimateEpochRewardsWith(header *types.Header, state *state.StateDB, vmRunner vm.EVMRunner) (*EpochRewardsEstimate, error) {
+	number := header.Number.Uint64()
+	if istanbul.IsLastBlockOfEpoch(number, sb.EpochSize()) {
+		return nil, errors.New("epoch just ended, no blocks of the next epoch to estimate rewards from")
+	}
+	epoch := istanbul.GetEpochNumber(number, sb.EpochSize())
+	estimate := &EpochRewardsEstimate{
+		Epoch:                         epoch,
+		BlockNumber:                   number,
+		EpochLastBlock:                istanbul.GetEpochLastBlockNumber(epoch, sb.EpochSize()),
+		MaxValidatorReward:            (*hexutil.Big)(new(big.Int)),
+		TotalVoterRewards:             (*hexutil.Big)(new(big.Int)),
+		CommunityReward:               (*hexutil.Big)(new(big.Int)),
+		CarbonOffsettingPartnerReward: (*hexutil.Big)(new(big.Int)),
+		Validators:                    []*ValidatorRewardEstimate{},
+		Groups:                        []*GroupRewardEstimate{},
+	}
+
+	if !sb.ChainConfig().IsGingerbread(header.Number) {
+		if frozen, err := freezer.IsFrozen(vmRunner, config.EpochRewardsRegistryId); err != nil {
+			return nil, err
+		} else if frozen {
+			estimate.Frozen = true
+			return estimate, nil
+		}
+	}
+
+	valSet := sb.GetValidators(header.Number, header.Hash())
+	if len(valSet) == 0 {
+		return nil, errors.New("unable to fetch validator set to estimate rewards")
+	}
+
+	// Use a dedicated uptime builder, the backend's one is owned by the block processing.
+	monitor := uptime.NewAutoFixBuilder(
+		uptime.NewMonitor(sb.EpochSize(), epoch, sb.LookbackWindow(header, state), len(valSet)),
+		istanbul.NewHeadersProvider(sb.chain),
+	)
+	if err := monitor.ProcessHeader(header); err != nil {
+		return nil, err
+	}
+	uptimes, err := monitor.ComputeUptime(header)
+	if err != nil {
+		return nil, err
+	}
+
+	carbonOffsettingPartnerAddress, err := epoch_rewards.GetCarbonOffsettingPartnerAddress(vmRunner)
+	if err != nil {
+		return nil, err
+	}
+	if err := epoch_rewards.UpdateTargetVotingYield(vmRunner); err != nil {
+		return nil, err
+	}
+	validatorReward, totalVoterRewards, communityReward, carbonOffsettingPartnerReward, err := epoch_rewards.CalculateTargetEpochRewards(vmRunner)
+	if err != nil {
+		return nil, err
+	}
+	if carbonOffsettingPartnerAddress == common.ZeroAddress {
+		carbonOffsettingPartnerReward = big.NewInt(0)
+	}
+	estimate.MaxValidatorReward = (*hexutil.Big)(validatorReward)
+	estimate.TotalVoterRewards = (*hexutil.Big)(totalVoterRewards)
+	estimate.CommunityReward = (*hexutil.Big)(communityReward)
+	estimate.CarbonOffsettingPartnerReward = (*hexutil.Big)(carbonOffsettingPartnerReward)
+
+	// Scores need to be updated before the payments, as the payments depend on them.
+	for i, val := range valSet {
+		if err := validators.UpdateValidatorScore(vmRunner, val.Address(), uptimes[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	groupUptimes := make(map[common.Address][]*big.Int)
+	groupEstimates := make(map[common.Address]*GroupRewardEstimate)
+	for i, val := range valSet {
+		group, err := validators.GetMembershipInLastEpoch(vmRunner, val.Address())
+		if err != nil {
+			return nil, err
+		}
+		reward, err := validators.DistributeEpochReward(vmRunner, val.Address(), validatorReward)
+		if err != nil {
+			// Same as the real distribution, a failing payment does not abort the others.
+			sb.logger.Debug("Error in estimating rewards for validator", "address", val.Address(), "err", err)
+			reward = big.NewInt(0)
+		}
+		estimate.Validators = append(estimate.Validators, &ValidatorRewardEstimate{
+			Address: val.Address(),
+			Group:   group,
+			Uptime:  (*hexutil.Big)(uptimes[i]),
+			Reward:  (*hexutil.Big)(reward),
+		})
+
+		groupEstimate, ok := groupEstimates[group]
+		if !ok {
+			groupEstimate = &GroupRewardEstimate{Group: group, ValidatorRewards: (*hexutil.Big)(new(big.Int))}
+			groupEstimates[group] = groupEstimate
+			estimate.Groups = append(estimate.Groups, groupEstimate)
+		}
+		groupEstimate.ValidatorRewards.ToInt().Add(groupEstimate.ValidatorRewards.ToInt(), reward)
+		groupUptimes[group] = append(groupUptimes[group], uptimes[i])
+	}
+
+	for _, groupEstimate := range estimate.Groups {
+		voterRewards, err := election.GetGroupEpochRewards(vmRunner, groupEstimate.Group, totalVoterRewards, groupUptimes[groupEstimate.Group])
+		if err != nil {
+			return nil, err
+		}
+		groupEstimate.VoterRewards = (*hexutil.Big)(voterRewards)
+	}
+
+	return estimate, nil
+}
@@ -18,18 +18,19 @@ package backend
 
 import (
 	"encoding/json"
+	"errors"
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul/validator"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
 	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/ethdb"
 	"github.com/celo-org/celo-blockchain/log"
 )
 
-const (
-	dbKeySnapshotPrefix = "istanbul-snapshot"
-)
+// errSnapshotNotFound is returned when no snapshot is stored for a block.
+var errSnapshotNotFound = errors.New("snapshot not found")
 
 // Snapshot is the state of the authorization voting at a given point in time.
 type Snapshot struct {
@@ -54,10 +55,10 @@ func newSnapshot(epoch uint64, number uint64, hash common.Hash, valSet istanbul.
 }
 
 // loadSnapshot loads an existing snapshot from the database.
-func loadSnapshot(epoch uint64, db ethdb.Database, hash common.Hash) (*Snapshot, error) {
-	blob, err := db.Get(append([]byte(dbKeySnapshotPrefix), hash[:]...))
-	if err != nil {
-		return nil, err
+func loadSnapshot(epoch uint64, db ethdb.Database, hash common.Hash, number uint64) (*Snapshot, error) {
+	blob := rawdb.ReadIstanbulSnapshot(db, hash, number)
+	if len(blob) == 0 {
+		return nil, errSnapshotNotFound
 	}
 	snap := new(Snapshot)
 	if err := json.Unmarshal(blob, snap); err != nil {
@@ -76,14 +77,28 @@ func loadSnapshot(epoch uint64, db ethdb.Database, hash common.Hash) (*Snapshot,
 	return snap, nil
 }
 
+// ReadSnapshot retrieves the snapshot persisted for the given block. Unlike loadSnapshot
+// it never rewrites outdated snapshots, so it is safe to use on a read only database.
+func ReadSnapshot(db ethdb.Reader, hash common.Hash, number uint64) (*Snapshot, error) {
+	blob := rawdb.ReadIstanbulSnapshot(db, hash, number)
+	if len(blob) == 0 {
+		return nil, errSnapshotNotFound
+	}
+	snap := new(Snapshot)
+	if err := json.Unmarshal(blob, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
 // store inserts the snapshot into the database.
-func (s *Snapshot) store(db ethdb.Database) error {
+func (s *Snapshot) store(db ethdb.KeyValueWriter) error {
 	s.ValSet.CacheUncompressedBLSKey()
 	blob, err := json.Marshal(s)
 	if err != nil {
 		return err
 	}
-	return db.Put(append([]byte(dbKeySnapshotPrefix), s.Hash[:]...), blob)
+	return rawdb.WriteIstanbulSnapshot(db, s.Hash, blob)
 }
 
 // copy creates a deep copy of the snapshot, though not the individual votes.
@@ -120,45 +135,75 @@ func (s *Snapshot) apply(headers []*types.Header, db ethdb.Database) (*Snapshot,
 	snap := s.copy()
 
 	for _, header := range headers {
-		// Resolve the authorization key and check against validators
-		validator, err := ecrecover(header)
-		if err != nil {
+		if err := snap.applyEpochHeader(header); err != nil {
 			return nil, err
 		}
-		if _, v := snap.ValSet.GetByAddress(validator); v == nil {
-			return nil, errUnauthorized
-		}
+		snap.store(db)
+		log.Trace("Stored voting snapshot to disk", "number", snap.Number, "hash", snap.Hash)
+	}
 
-		// Ensure that the extra data format is satisfied
-		istExtra, err := header.IstanbulExtra()
-		if err != nil {
-			log.Error("Unable to extract the istanbul extra field from the header", "header", header)
-			return nil, err
-		}
+	return snap, nil
+}
 
-		validators, err := istanbul.CombineIstanbulExtraToValidatorData(istExtra.AddedValidators, istExtra.AddedValidatorsPublicKeys)
-		if err != nil {
-			log.Error("Error in combining addresses and public keys")
-			return nil, errInvalidValidatorSetDiff
-		}
+// applyEpochHeader moves the snapshot to the next epoch block header, applying
+// its validator set diff. It doesn't check the header is the next epoch block.
+func (s *Snapshot) applyEpochHeader(header *types.Header) error {
+	// Resolve the authorization key and check against validators
+	validator, err := ecrecover(header)
+	if err != nil {
+		return err
+	}
+	if _, v := s.ValSet.GetByAddress(validator); v == nil {
+		return errUnauthorized
+	}
 
-		if !snap.ValSet.RemoveValidators(istExtra.RemovedValidators) {
-			log.Error("Error in removing the header's RemovedValidators")
-			return nil, errInvalidValidatorSetDiff
-		}
-		if !snap.ValSet.AddValidators(validators) {
-			log.Error("Error in adding the header's AddedValidators")
-			return nil, errInvalidValidatorSetDiff
-		}
+	// Ensure that the extra data format is satisfied
+	istExtra, err := header.IstanbulExtra()
+	if err != nil {
+		log.Error("Unable to extract the istanbul extra field from the header", "header", header)
+		return err
+	}
 
-		snap.Epoch = s.Epoch
-		snap.Number += s.Epoch
-		snap.Hash = header.Hash()
-		snap.store(db)
-		log.Trace("Stored voting snapshot to disk", "number", snap.Number, "hash", snap.Hash)
+	validators, err := istanbul.CombineIstanbulExtraToValidatorData(istExtra.AddedValidators, istExtra.AddedValidatorsPublicKeys)
+	if err != nil {
+		log.Error("Error in combining addresses and public keys")
+		return errInvalidValidatorSetDiff
 	}
 
-	return snap, nil
+	if !s.ValSet.RemoveValidators(istExtra.RemovedValidators) {
+		log.Error("Error in removing the header's RemovedValidators")
+		return errInvalidValidatorSetDiff
+	}
+	if !s.ValSet.AddValidators(validators) {
+		log.Error("Error in adding the header's AddedValidators")
+		return errInvalidValidatorSetDiff
+	}
+
+	s.Number += s.Epoch
+	s.Hash = header.Hash()
+	return nil
+}
+
+// genesisSnapshot creates the snapshot of the validators added by the genesis block.
+func genesisSnapshot(epoch uint64, genesis *types.Header) (*Snapshot, error) {
+	istanbulExtra, err := genesis.IstanbulExtra()
+	if err != nil {
+		log.Error("Unable to extract istanbul extra", "err", err)
+		return nil, err
+	}
+
+	// The genesis block should have an empty RemovedValidators set.  If not, throw an error
+	if istanbulExtra.RemovedValidators.BitLen() != 0 {
+		log.Error("Genesis block has a non empty RemovedValidators set")
+		return nil, errInvalidValidatorSetDiff
+	}
+
+	validators, err := istanbul.CombineIstanbulExtraToValidatorData(istanbulExtra.AddedValidators, istanbulExtra.AddedValidatorsPublicKeys)
+	if err != nil {
+		log.Error("Cannot construct validators data from istanbul extra")
+		return nil, errInvalidValidatorSetDiff
+	}
+	return newSnapshot(epoch, 0, genesis.Hash(), validator.NewSet(validators)), nil
 }
 
 func (s *Snapshot) validators() []istanbul.ValidatorData {
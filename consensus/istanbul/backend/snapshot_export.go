@@ -0,0 +1,306 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/log"
+)
+
+const (
+	// EpochSnapshotsVersion is the version of the epoch snapshots interchange format.
+	EpochSnapshotsVersion = 1
+
+	// maxExportedEpochs is the number of epoch snapshots exported at most by RPC.
+	maxExportedEpochs = 1024
+)
+
+// EpochSnapshots are the validator set snapshots of a range of epochs, with the
+// last block headers of the epochs carrying the transitions between them: the
+// validator set diff, and the seals of the validators of the previous epoch.
+type EpochSnapshots struct {
+	Version   uint64           `json:"version"`
+	EpochSize uint64           `json:"epochSize"`
+	Genesis   common.Hash      `json:"genesis"`
+	Base      *Snapshot        `json:"base"` // Snapshot of the epoch before the first one
+	Epochs    []*EpochSnapshot `json:"epochs"`
+}
+
+// EpochSnapshot is the transition to the validator set snapshot of an epoch.
+type EpochSnapshot struct {
+	Epoch    uint64        `json:"epoch"`
+	Header   *types.Header `json:"header"` // Last block of the epoch
+	Snapshot *Snapshot     `json:"snapshot"`
+}
+
+// errEpochSnapshotsMismatch is returned when importing epoch snapshots that
+// don't follow the chain of the node.
+var errEpochSnapshotsMismatch = errors.New("epoch snapshots of another chain")
+
+// ExportEpochSnapshots returns the validator set snapshots of the epochs from
+// and to of the canonical chain in db, with the epoch block headers.
+func ExportEpochSnapshots(db ethdb.Database, epochSize, from, to uint64) (*EpochSnapshots, error) {
+	if from == 0 || from > to {
+		return nil, fmt.Errorf("invalid epoch range %d-%d", from, to)
+	}
+	snap, err := readEpochSnapshot(db, epochSize, from-1)
+	if err != nil {
+		return nil, err
+	}
+	export := &EpochSnapshots{
+		Version:   EpochSnapshotsVersion,
+		EpochSize: epochSize,
+		Genesis:   rawdb.ReadCanonicalHash(db, 0),
+		Base:      snap,
+		Epochs:    make([]*EpochSnapshot, 0, to-from+1),
+	}
+	for epoch := from; epoch <= to; epoch++ {
+		header := readCanonicalHeader(db, istanbul.GetEpochLastBlockNumber(epoch, epochSize))
+		if header == nil {
+			return nil, fmt.Errorf("missing last block of epoch %d", epoch)
+		}
+		next := snap.copy()
+		if err := next.applyEpochHeader(header); err != nil {
+			return nil, fmt.Errorf("invalid last block of epoch %d: %v", epoch, err)
+		}
+		export.Epochs = append(export.Epochs, &EpochSnapshot{Epoch: epoch, Header: header, Snapshot: next})
+		snap = next
+	}
+	return export, nil
+}
+
+// ImportEpochSnapshots verifies the transitions between the epoch snapshots
+// and stores them in db, returning the number of snapshots not stored before.
+// The seal of every epoch block header is checked against the validators of the
+// previous epoch, and its validator set diff applied to them. The base snapshot
+// must be the genesis one or one already stored.
+func ImportEpochSnapshots(db ethdb.Database, epochSize uint64, snapshots *EpochSnapshots) (int, error) {
+	genesis, err := checkEpochSnapshots(db, epochSize, snapshots)
+	if err != nil {
+		return 0, err
+	}
+	snap, err := knownBaseSnapshot(db, epochSize, genesis, snapshots.Base)
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, epoch := range snapshots.Epochs {
+		next, err := verifyEpochTransition(snap, epoch, epochSize)
+		if err != nil {
+			return imported, err
+		}
+		if _, err := ReadSnapshot(db, next.Hash, next.Number); err != nil {
+			imported++
+		}
+		if err := next.store(db); err != nil {
+			return imported, err
+		}
+		snap = next
+	}
+	return imported, nil
+}
+
+// ImportSyncCheckpoint bootstraps the empty chain in db from the last epoch of
+// the snapshots, for the node to fast sync from its last block instead of the
+// genesis. As for ImportEpochSnapshots the base snapshot must be the genesis one
+// or one already stored, the transitions to the last epoch being verified from
+// it: the epochs before can be imported first with ImportEpochSnapshots, without
+// syncing their blocks. The last two epoch block
+// headers are stored as canonical with their snapshots, the validators of both
+// being needed to verify the first block after the checkpoint, and the last one
+// becomes the head header. Importing the checkpoint the chain was bootstrapped
+// from again is a no-op.
+func ImportSyncCheckpoint(db ethdb.Database, epochSize uint64, snapshots *EpochSnapshots) (*types.Header, error) {
+	genesis, err := checkEpochSnapshots(db, epochSize, snapshots)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots.Epochs) < 2 {
+		return nil, errors.New("sync checkpoint without the last two epochs")
+	}
+	snap, err := knownBaseSnapshot(db, epochSize, genesis, snapshots.Base)
+	if err != nil {
+		return nil, err
+	}
+
+	next := make([]*Snapshot, len(snapshots.Epochs))
+	for i, epoch := range snapshots.Epochs {
+		if next[i], err = verifyEpochTransition(snap, epoch, epochSize); err != nil {
+			return nil, err
+		}
+		snap = next[i]
+	}
+	head := snapshots.Epochs[len(snapshots.Epochs)-1].Header
+	if checkpoint := rawdb.ReadSyncCheckpoint(db); checkpoint != nil {
+		if checkpoint.Hash != head.Hash() {
+			return nil, fmt.Errorf("chain already bootstrapped from block %d %s", checkpoint.Number, checkpoint.Hash.Hex())
+		}
+		return head, nil
+	}
+	if rawdb.ReadHeadHeaderHash(db) != genesis.Hash() {
+		return nil, errors.New("chain not empty")
+	}
+	batch := db.NewBatch()
+	for i := len(snapshots.Epochs) - 2; i < len(snapshots.Epochs); i++ {
+		header := snapshots.Epochs[i].Header
+		rawdb.WriteHeader(batch, header)
+		rawdb.WriteTd(batch, header.Hash(), header.Number.Uint64(), new(big.Int).SetUint64(header.Number.Uint64()+1))
+		rawdb.WriteCanonicalHash(batch, header.Hash(), header.Number.Uint64())
+		if err := next[i].store(batch); err != nil {
+			return nil, err
+		}
+	}
+	rawdb.WriteHeadHeaderHash(batch, head.Hash())
+	rawdb.WriteSyncCheckpoint(batch, &rawdb.SyncCheckpoint{Number: head.Number.Uint64(), Hash: head.Hash()})
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	return head, nil
+}
+
+// checkEpochSnapshots checks that the snapshots can be imported in db, and
+// returns the genesis header of db.
+func checkEpochSnapshots(db ethdb.Database, epochSize uint64, snapshots *EpochSnapshots) (*types.Header, error) {
+	if snapshots.Version != EpochSnapshotsVersion {
+		return nil, fmt.Errorf("unsupported epoch snapshots version %d, want %d", snapshots.Version, EpochSnapshotsVersion)
+	}
+	if snapshots.EpochSize != epochSize {
+		return nil, fmt.Errorf("%w: epoch size %d, want %d", errEpochSnapshotsMismatch, snapshots.EpochSize, epochSize)
+	}
+	genesis := readCanonicalHeader(db, 0)
+	if genesis == nil || genesis.Hash() != snapshots.Genesis {
+		return nil, fmt.Errorf("%w: genesis %s", errEpochSnapshotsMismatch, snapshots.Genesis.Hex())
+	}
+	if snapshots.Base == nil || snapshots.Base.ValSet == nil {
+		return nil, errors.New("missing base snapshot")
+	}
+	return genesis, nil
+}
+
+// knownBaseSnapshot returns the snapshot of db the base snapshot of epoch
+// snapshots claims to be: the genesis one or one already stored, with the same
+// validators. The base of imported snapshots is only trusted if it's known.
+func knownBaseSnapshot(db ethdb.Database, epochSize uint64, genesis *types.Header, base *Snapshot) (*Snapshot, error) {
+	var (
+		snap *Snapshot
+		err  error
+	)
+	if base.Hash == genesis.Hash() {
+		snap, err = genesisSnapshot(epochSize, genesis)
+	} else {
+		snap, err = ReadSnapshot(db, base.Hash, base.Number)
+	}
+	if err != nil || !sameValidators(snap.ValSet, base.ValSet) {
+		return nil, fmt.Errorf("unknown base snapshot at block %d, import the previous epochs first", base.Number)
+	}
+	snap.Epoch = epochSize
+	return snap, nil
+}
+
+// verifyEpochTransition checks the seal of the last block header of the epoch
+// against the validators of the snapshot of the previous epoch, and returns the
+// snapshot of the epoch, applying the validator set diff of the header.
+func verifyEpochTransition(snap *Snapshot, epoch *EpochSnapshot, epochSize uint64) (*Snapshot, error) {
+	number := istanbul.GetEpochLastBlockNumber(epoch.Epoch, epochSize)
+	if epoch.Header == nil || epoch.Header.Number.Uint64() != number || number != snap.Number+epochSize {
+		return nil, fmt.Errorf("%w: epoch %d doesn't follow block %d", errInvalidVotingChain, epoch.Epoch, snap.Number)
+	}
+	extra, err := epoch.Header.IstanbulExtra()
+	if err != nil {
+		return nil, fmt.Errorf("invalid last block of epoch %d: %v", epoch.Epoch, err)
+	}
+	if err := verifyAggregatedSeal(log.Root(), epoch.Header.Hash(), snap.ValSet, extra.AggregatedSeal); err != nil {
+		return nil, fmt.Errorf("invalid seal of epoch %d: %v", epoch.Epoch, err)
+	}
+	next := snap.copy()
+	if err := next.applyEpochHeader(epoch.Header); err != nil {
+		return nil, fmt.Errorf("invalid last block of epoch %d: %v", epoch.Epoch, err)
+	}
+	if epoch.Snapshot != nil && !sameValidators(next.ValSet, epoch.Snapshot.ValSet) {
+		return nil, fmt.Errorf("snapshot of epoch %d doesn't match its validator set diff", epoch.Epoch)
+	}
+	return next, nil
+}
+
+// readEpochSnapshot returns the validator set snapshot of the epoch of the
+// canonical chain in db, applying the epoch headers since the last one stored.
+// Unlike Backend.snapshot it never writes to db.
+func readEpochSnapshot(db ethdb.Database, epochSize, epoch uint64) (*Snapshot, error) {
+	var (
+		headers []*types.Header
+		snap    *Snapshot
+	)
+	for e := epoch; ; e-- {
+		header := readCanonicalHeader(db, istanbul.GetEpochLastBlockNumber(e, epochSize))
+		if header == nil {
+			return nil, fmt.Errorf("missing last block of epoch %d", e)
+		}
+		if s, err := ReadSnapshot(db, header.Hash(), header.Number.Uint64()); err == nil {
+			snap = s
+			break
+		}
+		if e == 0 {
+			s, err := genesisSnapshot(epochSize, header)
+			if err != nil {
+				return nil, err
+			}
+			snap = s
+			break
+		}
+		headers = append(headers, header)
+	}
+	snap.Epoch = epochSize
+	for i := len(headers) - 1; i >= 0; i-- {
+		if err := snap.applyEpochHeader(headers[i]); err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
+
+// readCanonicalHeader retrieves the canonical header of the block number from db.
+func readCanonicalHeader(db ethdb.Reader, number uint64) *types.Header {
+	hash := rawdb.ReadCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return rawdb.ReadHeader(db, hash, number)
+}
+
+// sameValidators indicates if the validator sets hold the same validators, with
+// the same BLS keys, in the same order.
+func sameValidators(a, b istanbul.ValidatorSet) bool {
+	if a == nil || b == nil || a.Size() != b.Size() {
+		return false
+	}
+	for i, val := range a.List() {
+		other := b.GetByIndex(uint64(i))
+		if val.Address() != other.Address() || val.BLSPublicKey() != other.BLSPublicKey() {
+			return false
+		}
+	}
+	return true
+}
@@ -19,6 +19,7 @@ package backend
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"encoding/json"
 	"math/big"
 	"reflect"
 	"sort"
@@ -30,6 +31,7 @@ import (
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
 	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
 	"github.com/celo-org/celo-blockchain/crypto"
 	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
 	"github.com/celo-org/celo-blockchain/params"
@@ -370,7 +372,7 @@ func TestSaveAndLoad(t *testing.T) {
 		t.Errorf("store snapshot failed: %v", err)
 	}
 
-	snap1, err := loadSnapshot(snap.Epoch, db, snap.Hash)
+	snap1, err := loadSnapshot(snap.Epoch, db, snap.Hash, snap.Number)
 	if err != nil {
 		t.Errorf("load snapshot failed: %v", err)
 	}
@@ -384,3 +386,188 @@ func TestSaveAndLoad(t *testing.T) {
 		t.Errorf("validator set mismatch: have %v, want %v", snap1.ValSet, snap.ValSet)
 	}
 }
+
+func TestEpochSnapshotsExportImport(t *testing.T) {
+	genesisCfg, nodeKeys := getGenesisAndKeys(1, true)
+	chain, engine, _ := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer chain.Stop()
+	engine.config.BlockPeriod = 1
+
+	block := chain.Genesis()
+	for i := uint64(0); i < 2*engine.EpochSize(); i++ {
+		var err error
+		if block, err = makeBlock(nodeKeys, chain, engine, block); err != nil {
+			t.Fatalf("Failed to make block %d: %v", i+1, err)
+		}
+	}
+	export, err := ExportEpochSnapshots(engine.db, engine.EpochSize(), 1, 2)
+	if err != nil {
+		t.Fatalf("Failed to export epoch snapshots: %v", err)
+	}
+	if len(export.Epochs) != 2 || export.Base.Hash != chain.Genesis().Hash() {
+		t.Fatalf("Export mismatch: have %d epochs from %x, want 2 from the genesis", len(export.Epochs), export.Base.Hash)
+	}
+	blob, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("Failed to encode epoch snapshots: %v", err)
+	}
+
+	// A node with the genesis block only imports them
+	decode := func() *EpochSnapshots {
+		snapshots := new(EpochSnapshots)
+		if err := json.Unmarshal(blob, snapshots); err != nil {
+			t.Fatalf("Failed to decode epoch snapshots: %v", err)
+		}
+		return snapshots
+	}
+	db := rawdb.NewMemoryDatabase()
+	genesisCfg.MustCommit(db)
+	tampered := decode()
+	tampered.Epochs[0].Header.GasUsed++
+	if _, err := ImportEpochSnapshots(db, engine.EpochSize(), tampered); err == nil {
+		t.Fatalf("Epoch block with an invalid seal imported")
+	}
+	imported, err := ImportEpochSnapshots(db, engine.EpochSize(), decode())
+	if err != nil {
+		t.Fatalf("Failed to import epoch snapshots: %v", err)
+	}
+	if imported != 2 {
+		t.Errorf("Imported snapshots mismatch: have %d, want 2", imported)
+	}
+	for _, epoch := range export.Epochs {
+		snap, err := ReadSnapshot(db, epoch.Header.Hash(), epoch.Header.Number.Uint64())
+		if err != nil {
+			t.Fatalf("Missing snapshot of epoch %d: %v", epoch.Epoch, err)
+		}
+		if !sameValidators(snap.ValSet, epoch.Snapshot.ValSet) {
+			t.Errorf("Snapshot of epoch %d mismatch", epoch.Epoch)
+		}
+	}
+	// Later epochs import from the known snapshots
+	later := decode()
+	later.Base, later.Epochs = later.Epochs[0].Snapshot, later.Epochs[1:]
+	if imported, err := ImportEpochSnapshots(db, engine.EpochSize(), later); err != nil || imported != 0 {
+		t.Errorf("Reimport mismatch: have %d imported, err %v, want 0", imported, err)
+	}
+	unknown := decode()
+	unknown.Base.Hash = common.Hash{1}
+	if _, err := ImportEpochSnapshots(db, engine.EpochSize(), unknown); err == nil {
+		t.Errorf("Epoch snapshots of an unknown base imported")
+	}
+}
+
+func TestSyncCheckpointImport(t *testing.T) {
+	genesisCfg, nodeKeys := getGenesisAndKeys(1, true)
+	chain, engine, _ := newBlockChainWithKeys(false, common.Address{}, false, genesisCfg, nodeKeys[0])
+	defer chain.Stop()
+	engine.config.BlockPeriod = 1
+
+	block := chain.Genesis()
+	for i := uint64(0); i < 3*engine.EpochSize()+2; i++ {
+		var err error
+		if block, err = makeBlock(nodeKeys, chain, engine, block); err != nil {
+			t.Fatalf("Failed to make block %d: %v", i+1, err)
+		}
+	}
+	export, err := ExportEpochSnapshots(engine.db, engine.EpochSize(), 2, 3)
+	if err != nil {
+		t.Fatalf("Failed to export epoch snapshots: %v", err)
+	}
+	blob, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("Failed to encode epoch snapshots: %v", err)
+	}
+	decode := func() *EpochSnapshots {
+		snapshots := new(EpochSnapshots)
+		if err := json.Unmarshal(blob, snapshots); err != nil {
+			t.Fatalf("Failed to decode epoch snapshots: %v", err)
+		}
+		return snapshots
+	}
+	db := rawdb.NewMemoryDatabase()
+	genesisCfg.MustCommit(db)
+
+	// The base snapshot has to be known: a forged one would have its validators
+	// sign the epochs after it
+	if _, err := ImportSyncCheckpoint(db, engine.EpochSize(), decode()); err == nil {
+		t.Fatalf("Sync checkpoint of an unknown base imported")
+	}
+	forged, err := ExportEpochSnapshots(engine.db, engine.EpochSize(), 1, 3)
+	if err != nil {
+		t.Fatalf("Failed to export epoch snapshots: %v", err)
+	}
+	forged.Base.ValSet, _ = newTestValidatorSet(1)
+	if _, err := ImportSyncCheckpoint(db, engine.EpochSize(), forged); err == nil {
+		t.Fatalf("Sync checkpoint of a forged genesis base imported")
+	}
+	first, err := ExportEpochSnapshots(engine.db, engine.EpochSize(), 1, 1)
+	if err != nil {
+		t.Fatalf("Failed to export epoch snapshots: %v", err)
+	}
+	if _, err := ImportEpochSnapshots(db, engine.EpochSize(), first); err != nil {
+		t.Fatalf("Failed to import epoch snapshots: %v", err)
+	}
+	unknown := decode()
+	unknown.Base.Hash = common.Hash{1}
+	if _, err := ImportSyncCheckpoint(db, engine.EpochSize(), unknown); err == nil {
+		t.Fatalf("Sync checkpoint of a forged base imported")
+	}
+
+	tampered := decode()
+	tampered.Epochs[1].Header.GasUsed++
+	if _, err := ImportSyncCheckpoint(db, engine.EpochSize(), tampered); err == nil {
+		t.Fatalf("Sync checkpoint with an invalid seal imported")
+	}
+	single := decode()
+	single.Base, single.Epochs = single.Epochs[0].Snapshot, single.Epochs[1:]
+	if _, err := ImportSyncCheckpoint(db, engine.EpochSize(), single); err == nil {
+		t.Fatalf("Sync checkpoint of a single epoch imported")
+	}
+	head, err := ImportSyncCheckpoint(db, engine.EpochSize(), decode())
+	if err != nil {
+		t.Fatalf("Failed to import sync checkpoint: %v", err)
+	}
+	want := chain.GetHeaderByNumber(3 * engine.EpochSize())
+	if head.Hash() != want.Hash() || rawdb.ReadHeadHeaderHash(db) != want.Hash() {
+		t.Fatalf("Checkpoint mismatch: have %x, want %x", head.Hash(), want.Hash())
+	}
+	if checkpoint := rawdb.ReadSyncCheckpoint(db); checkpoint == nil || checkpoint.Hash != want.Hash() {
+		t.Fatalf("Stored sync checkpoint mismatch: have %v", checkpoint)
+	}
+	// Importing it again is a no-op, another one fails
+	if _, err := ImportSyncCheckpoint(db, engine.EpochSize(), decode()); err != nil {
+		t.Errorf("Failed to import the sync checkpoint again: %v", err)
+	}
+	other, err := ExportEpochSnapshots(engine.db, engine.EpochSize(), 1, 2)
+	if err != nil {
+		t.Fatalf("Failed to export epoch snapshots: %v", err)
+	}
+	if _, err := ImportSyncCheckpoint(db, engine.EpochSize(), other); err == nil {
+		t.Errorf("Another sync checkpoint imported")
+	}
+
+	// The headers after the checkpoint are verified from it
+	config := *istanbul.DefaultConfig
+	config.ReplicaStateDBPath = ""
+	config.ValidatorEnodeDBPath = ""
+	config.VersionCertificateDBPath = ""
+	config.RoundStateDBPath = ""
+	istanbul.ApplyParamsChainConfigToConfig(genesisCfg.Config, &config)
+	config.BlockPeriod = engine.config.BlockPeriod
+	verifier, _ := New(&config, db).(*Backend)
+	bootstrapped, err := core.NewBlockChain(db, nil, genesisCfg.Config, verifier, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create bootstrapped chain: %v", err)
+	}
+	defer bootstrapped.Stop()
+	if bootstrapped.CurrentHeader().Hash() != want.Hash() {
+		t.Fatalf("Bootstrapped head header mismatch: have %d, want %d", bootstrapped.CurrentHeader().Number, want.Number)
+	}
+	headers := []*types.Header{chain.GetHeaderByNumber(want.Number.Uint64() + 1), chain.GetHeaderByNumber(want.Number.Uint64() + 2)}
+	if _, err := bootstrapped.InsertHeaderChain(headers, 1, true); err != nil {
+		t.Fatalf("Failed to insert the headers after the checkpoint: %v", err)
+	}
+	if bootstrapped.CurrentHeader().Hash() != chain.CurrentHeader().Hash() {
+		t.Errorf("Head header mismatch: have %d, want %d", bootstrapped.CurrentHeader().Number, chain.CurrentHeader().Number)
+	}
+}
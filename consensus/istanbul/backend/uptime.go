@@ -0,0 +1,137 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/uptime"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+// UptimeReport is the uptime score of a validator in an epoch, computed from the
+// parent seals of the epoch the same way the epoch rewards distribution does.
+type UptimeReport struct {
+	Epoch           uint64         `json:"epoch"`
+	Validator       common.Address `json:"validator"`
+	Elected         bool           `json:"elected"`
+	BlockNumber     uint64         `json:"blockNumber"` // Block the score is computed up to
+	EpochLastBlock  uint64         `json:"epochLastBlock"`
+	Final           bool           `json:"final"` // Whether the epoch ended, the score being the one rewarded
+	LookbackWindow  uint64         `json:"lookbackWindow"`
+	MonitoredBlocks uint64         `json:"monitoredBlocks"`
+	Uptime          *hexutil.Big   `json:"uptime"` // Fixidity fraction, nil until the first lookback window of the epoch ended
+}
+
+// uptimeReport computes the uptime score of the validator in the epoch, up to
+// head if the epoch didn't end yet. The uptime builder of the epoch is reused if
+// builder points to one, and stored otherwise.
+func (sb *Backend) uptimeReport(epoch uint64, head *types.Header, builder *uptime.Builder) (*UptimeReport, error) {
+	if epoch == 0 {
+		return nil, errors.New("no uptime in the genesis epoch")
+	}
+	if current := istanbul.GetEpochNumber(head.Number.Uint64(), sb.EpochSize()); epoch > current {
+		return nil, fmt.Errorf("epoch %d not started, current epoch is %d", epoch, current)
+	}
+	report := &UptimeReport{
+		Epoch:          epoch,
+		Validator:      sb.ValidatorAddress(),
+		EpochLastBlock: istanbul.GetEpochLastBlockNumber(epoch, sb.EpochSize()),
+	}
+	until := head
+	if report.EpochLastBlock <= head.Number.Uint64() {
+		if until = sb.chain.GetHeaderByNumber(report.EpochLastBlock); until == nil {
+			return nil, errUnknownBlock
+		}
+		report.Final = true
+	}
+	report.BlockNumber = until.Number.Uint64()
+
+	// The validators of the epoch are the signers of its blocks
+	valSet := sb.GetValidators(new(big.Int).Sub(until.Number, common.Big1), until.ParentHash)
+	index := -1
+	for i, val := range valSet {
+		if val.Address() == report.Validator {
+			index = i
+			break
+		}
+	}
+	if report.Elected = index >= 0; !report.Elected {
+		return report, nil
+	}
+	state, err := sb.stateAt(until.Hash())
+	if err != nil {
+		return nil, err
+	}
+	report.LookbackWindow = sb.LookbackWindow(until, state)
+
+	if *builder == nil || (*builder).GetEpoch() != epoch {
+		*builder = uptime.NewAutoFixBuilder(
+			uptime.NewMonitor(sb.EpochSize(), epoch, report.LookbackWindow, len(valSet)),
+			istanbul.NewHeadersProvider(sb.chain),
+		)
+	}
+	if err := (*builder).ProcessHeader(until); err != nil {
+		return nil, err
+	}
+	uptimes, err := (*builder).ComputeUptime(until)
+	if errors.Is(err, uptime.ErrUnpreparedCompute) {
+		return report, nil
+	} else if err != nil {
+		return nil, err
+	}
+	window, err := uptime.MonitoringWindowUntil(epoch, sb.EpochSize(), report.LookbackWindow, report.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	report.MonitoredBlocks = window.Size()
+	report.Uptime = (*hexutil.Big)(uptimes[index])
+	return report, nil
+}
+
+// updateUptimeGauges reports the uptime score of the validator in the epoch of
+// the new chain head, so that missed signatures show before the score is set.
+func (sb *Backend) updateUptimeGauges(head *types.Header) {
+	if sb.ValidatorAddress() == (common.Address{}) || head.Number.Sign() == 0 {
+		return
+	}
+	epoch := istanbul.GetEpochNumber(head.Number.Uint64(), sb.EpochSize())
+	report, err := sb.uptimeReport(epoch, head, &sb.selfUptimeBuilder)
+	if err != nil {
+		sb.logger.Debug("Failed to compute the uptime of the validator", "number", head.Number, "err", err)
+		return
+	}
+	if !report.Elected {
+		sb.uptimeElectedGauge.Update(0)
+		return
+	}
+	sb.uptimeElectedGauge.Update(1)
+	if report.Uptime == nil {
+		return
+	}
+	sb.uptimeEpochGauge.Update(int64(report.Epoch))
+	sb.uptimeMonitoredGauge.Update(int64(report.MonitoredBlocks))
+	// Parts per million, the fixidity fraction doesn't fit a gauge
+	ppm := new(big.Int).Mul(report.Uptime.ToInt(), big.NewInt(1000000))
+	sb.uptimeScoreGauge.Update(ppm.Div(ppm, params.Fixidity1).Int64())
+}
@@ -20,6 +20,8 @@ import (
 	"fmt"
 
 	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/crypto/atrest"
+	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/p2p/enode"
 	"github.com/celo-org/celo-blockchain/params"
 )
@@ -36,12 +38,14 @@ const (
 	RoundRobin ProposerPolicy = iota
 	Sticky
 	ShuffledRoundRobin
+	WeightedRandom
 )
 
 // Config represents the istanbul consensus engine
 type Config struct {
 	RequestTimeout              uint64         `toml:",omitempty"` // The timeout for each Istanbul round in milliseconds.
 	TimeoutBackoffFactor        uint64         `toml:",omitempty"` // Timeout at subsequent rounds is: RequestTimeout + 2**round * TimeoutBackoffFactor (in milliseconds)
+	AdaptiveRequestTimeout      bool           `toml:",omitempty"` // Adapts RequestTimeout to the observed round latency, between half and twice its value
 	MinResendRoundChangeTimeout uint64         `toml:",omitempty"` // Minimum interval with which to resend RoundChange messages for same round
 	MaxResendRoundChangeTimeout uint64         `toml:",omitempty"` // Maximum interval with which to resend RoundChange messages for same round
 	BlockPeriod                 uint64         `toml:",omitempty"` // Default minimum difference between two consecutive block's timestamps in second
@@ -52,22 +56,39 @@ type Config struct {
 	ValidatorEnodeDBPath        string         `toml:",omitempty"` // The location for the validator enodes DB
 	VersionCertificateDBPath    string         `toml:",omitempty"` // The location for the signed announce version DB
 	RoundStateDBPath            string         `toml:",omitempty"` // The location for the round states DB
+	RoundStateDBKey             *atrest.Key    `toml:"-"`          // Encrypts the round states DB if set
 	Validator                   bool           `toml:",omitempty"` // Specified if this node is configured to validate  (specifically if --mine command line is set)
 	Replica                     bool           `toml:",omitempty"` // Specified if this node is configured to be a replica
+	ParentSealExtraWait         uint64         `toml:",omitempty"` // Maximum extra time (in milliseconds) the proposer waits to include more signatures in the parent aggregated seal
+
+	// Private network proposer selection Configs
+	ProposerPolicyOverride *ProposerPolicy           `toml:",omitempty"` // Overrides the proposer policy of the genesis config
+	ProposerWeights        map[common.Address]uint64 `toml:",omitempty"` // Weights of the validators for the weighted random policy, 1 if unset
+
+	// Message archive Configs
+	MessageArchive          bool   `toml:",omitempty"` // Archives the consensus messages received, to replay them with geth istanbul replay
+	MessageArchiveDBPath    string `toml:",omitempty"` // The location for the message archive DB
+	MessageArchiveSequences uint64 `toml:",omitempty"` // Number of the last sequences the messages are archived for, all if 0
 
 	// Proxy Configs
 	Proxy                   bool           `toml:",omitempty"` // Specifies if this node is a proxy
 	ProxiedValidatorAddress common.Address `toml:",omitempty"` // The address of the proxied validator
 
 	// Proxied Validator Configs
-	Proxied      bool           `toml:",omitempty"` // Specifies if this node is proxied
-	ProxyConfigs []*ProxyConfig `toml:",omitempty"` // The set of proxy configs for this proxied validator at startup
+	Proxied                  bool           `toml:",omitempty"` // Specifies if this node is proxied
+	ProxyConfigs             []*ProxyConfig `toml:",omitempty"` // The set of proxy configs for this proxied validator at startup
+	ProxyHealthCheckPeriod   uint64         `toml:",omitempty"` // Time duration (in seconds) between health checks of the proxies, disabled if 0
+	ProxyHealthCheckFailures uint64         `toml:",omitempty"` // Consecutive failed health checks after which the validators assigned to a proxy fail over to the others
+	ProxyHealthCheckMaxLag   uint64         `toml:",omitempty"` // Number of blocks the head of a proxy may lag behind the head of the validator
 
 	// Announce Configs
 	AnnounceQueryEnodeGossipPeriod                 uint64 `toml:",omitempty"` // Time duration (in seconds) between gossiped query enode messages
 	AnnounceAggressiveQueryEnodeGossipOnEnablement bool   `toml:",omitempty"` // Specifies if this node should aggressively query enodes on announce enablement
 	AnnounceAdditionalValidatorsToGossip           int64  `toml:",omitempty"` // Specifies the number of additional non-elected validators to gossip an announce
 
+	// Signer Configs
+	RemoteSigner string `toml:",omitempty"` // Endpoint of the service signing with the validator keys, the keystore if empty
+
 	// Load test config
 	LoadTestCSVFile string `toml:",omitempty"` // If non-empty, specifies the file to write out csv metrics about the block production cycle to.
 }
@@ -92,10 +113,15 @@ var DefaultConfig = &Config{
 	ValidatorEnodeDBPath:           "validatorenodes",
 	VersionCertificateDBPath:       "versioncertificates",
 	RoundStateDBPath:               "roundstates",
+	MessageArchiveDBPath:           "messagearchive",
+	MessageArchiveSequences:        1000,
 	Validator:                      false,
 	Replica:                        false,
 	Proxy:                          false,
 	Proxied:                        false,
+	ProxyHealthCheckPeriod:         10,
+	ProxyHealthCheckFailures:       3,
+	ProxyHealthCheckMaxLag:         5,
 	AnnounceQueryEnodeGossipPeriod: 300, // 5 minutes
 	AnnounceAggressiveQueryEnodeGossipOnEnablement: true,
 	AnnounceAdditionalValidatorsToGossip:           10,
@@ -124,6 +150,20 @@ func ApplyParamsChainConfigToConfig(chainConfig *params.ChainConfig, config *Con
 		return fmt.Errorf("istanbul.lookbackwindow must be less than istanbul.epoch-2")
 	}
 	config.ProposerPolicy = ProposerPolicy(chainConfig.Istanbul.ProposerPolicy)
+	config.ProposerWeights = chainConfig.Istanbul.ProposerWeights
+	if config.ProposerPolicyOverride != nil && *config.ProposerPolicyOverride != config.ProposerPolicy {
+		if chainConfig.ChainID != nil && isPublicNetwork(chainConfig.ChainID.Uint64()) {
+			return fmt.Errorf("the proposer policy of network %d can't be overridden", chainConfig.ChainID)
+		}
+		log.Warn("Overriding the proposer policy of the genesis config", "genesis", config.ProposerPolicy, "policy", *config.ProposerPolicyOverride)
+		config.ProposerPolicy = *config.ProposerPolicyOverride
+	}
 
 	return nil
 }
+
+// isPublicNetwork indicates if the network id is the one of a public celo network,
+// whose validators must all follow the proposer policy of the genesis config.
+func isPublicNetwork(networkId uint64) bool {
+	return networkId == params.MainnetNetworkId || networkId == params.BaklavaNetworkId || networkId == params.AlfajoresNetworkId
+}
@@ -94,6 +94,10 @@ func (c *core) generateEpochValidatorSetData(blockNumber uint64, round uint8, bl
 func (c *core) broadcastCommit(sub *istanbul.Subject) {
 	logger := c.newLogger("func", "broadcastCommit")
 
+	if err := c.protectSigning(istanbul.MsgCommit, sub.View, sub.Digest); err != nil {
+		logger.Error("Refusing to commit seal", "err", err)
+		return
+	}
 	committedSeal, err := c.generateCommittedSeal(sub)
 	if err != nil {
 		logger.Error("Failed to commit seal", "err", err)
@@ -238,6 +242,8 @@ func (c *core) handleCheckedCommitForCurrentSequence(msg *istanbul.Message, comm
 			logger.Error("Failed to create and set prepared certificate", "err", err)
 			return err
 		}
+		pc := c.current.PreparedCertificate()
+		c.health.prepared(c.current.View(), pc.Summary())
 		// Process Backlog Messages
 		c.backlog.updateState(c.current.View(), c.current.State())
 
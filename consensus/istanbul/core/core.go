@@ -31,6 +31,7 @@ import (
 	"github.com/celo-org/celo-blockchain/consensus/istanbul/validator"
 	"github.com/celo-org/celo-blockchain/core/types"
 	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
+	"github.com/celo-org/celo-blockchain/ethdb"
 	"github.com/celo-org/celo-blockchain/event"
 	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/metrics"
@@ -100,6 +101,10 @@ type CoreBackend interface {
 	// ParentBlockValidators returns the validator set of the given proposal's parent block
 	ParentBlockValidators(proposal istanbul.Proposal) istanbul.ValidatorSet
 
+	// SigningProtectionDB returns the database persisting the views the validator
+	// signed messages in, protecting it from double signing after a restart
+	SigningProtectionDB() ethdb.KeyValueStore
+
 	IsPrimaryForSeq(seq *big.Int) bool
 	UpdateReplicaState(seq *big.Int)
 }
@@ -126,10 +131,13 @@ type core struct {
 
 	backlog MsgBacklog
 
-	rsdb      RoundStateDB
-	current   RoundState
-	currentMu sync.RWMutex
-	handlerWg *sync.WaitGroup
+	rsdb              RoundStateDB
+	signingProtection *signingProtection
+	archive           *messageArchive   // Archives the consensus events handled if enabled
+	tracer            func(*ReplayStep) // Traces the consensus events handled when replaying them
+	current           RoundState
+	currentMu         sync.RWMutex
+	handlerWg         *sync.WaitGroup
 
 	roundChangeSetV2 *roundChangeSetV2
 
@@ -137,6 +145,13 @@ type core struct {
 	pendingRequestsMu *sync.Mutex
 
 	consensusTimestamp time.Time
+	preparedTimestamp  time.Time
+	sequenceTimestamp  time.Time
+
+	// Latency of the rounds, adapting the base timeout if enabled
+	timeouts *roundTimeouts
+	// Round changes, prepared certificates and commits for the consensus status
+	health *consensusHealth
 
 	// Time from accepting a pre-prepare (after block verifcation) to preparing or committing
 	consensusPrepareTimeGauge metrics.Gauge
@@ -147,6 +162,12 @@ type core struct {
 	handlePrePrepareTimer metrics.Timer
 	handlePrepareTimer    metrics.Timer
 	handleCommitTimer     metrics.Timer
+	// Histogram of the time of each phase of a sequence: from receiving to
+	// verifying the proposal, from verifying to the prepare quorum, and from the
+	// prepare quorum to the commit quorum
+	phaseVerifyTimer  metrics.Timer
+	phasePrepareTimer metrics.Timer
+	phaseCommitTimer  metrics.Timer
 }
 
 // New creates an Istanbul consensus core
@@ -156,18 +177,24 @@ func New(backend CoreBackend, config *istanbul.Config) Engine {
 		config:                    config,
 		address:                   backend.Address(),
 		logger:                    log.New(),
-		selectProposer:            validator.GetProposerSelector(config.ProposerPolicy),
+		selectProposer:            validator.NewProposerSelector(config),
 		handlerWg:                 new(sync.WaitGroup),
 		backend:                   backend,
 		pendingRequests:           prque.New(nil),
 		pendingRequestsMu:         new(sync.Mutex),
 		consensusTimestamp:        time.Time{},
+		timeouts:                  newRoundTimeouts(),
+		health:                    newConsensusHealth(),
+		signingProtection:         newSigningProtection(backend.SigningProtectionDB()),
 		consensusPrepareTimeGauge: metrics.NewRegisteredGauge("consensus/istanbul/core/consensus_prepare", nil),
 		consensusCommitTimeGauge:  metrics.NewRegisteredGauge("consensus/istanbul/core/consensus_commit", nil),
 		verifyGauge:               metrics.NewRegisteredGauge("consensus/istanbul/core/verify", nil),
 		handlePrePrepareTimer:     metrics.NewRegisteredTimer("consensus/istanbul/core/handle_preprepare", nil),
 		handlePrepareTimer:        metrics.NewRegisteredTimer("consensus/istanbul/core/handle_prepare", nil),
 		handleCommitTimer:         metrics.NewRegisteredTimer("consensus/istanbul/core/handle_commit", nil),
+		phaseVerifyTimer:          metrics.NewRegisteredTimer("consensus/istanbul/core/phase/verify", nil),
+		phasePrepareTimer:         metrics.NewRegisteredTimer("consensus/istanbul/core/phase/prepare", nil),
+		phaseCommitTimer:          metrics.NewRegisteredTimer("consensus/istanbul/core/phase/commit", nil),
 	}
 	msgBacklog := newMsgBacklog(
 		func(msg *istanbul.Message) {
@@ -213,12 +240,53 @@ func (c *core) ParentCommits() MessageSet {
 	return c.current.ParentCommits()
 }
 
+// CurrentRoundTimeouts returns the timeouts in effect.
+func (c *core) CurrentRoundTimeouts() *RoundTimeoutsSummary {
+	configured := time.Duration(c.config.RequestTimeout) * time.Millisecond
+	summary := &RoundTimeoutsSummary{
+		Adaptive:                 c.config.AdaptiveRequestTimeout,
+		ConfiguredRequestTimeout: c.config.RequestTimeout,
+		RequestTimeout:           uint64(c.timeouts.baseTimeout(configured, c.config.AdaptiveRequestTimeout).Milliseconds()),
+		AverageLatency:           uint64(c.timeouts.averageLatency().Milliseconds()),
+	}
+	c.currentMu.RLock()
+	defer c.currentMu.RUnlock()
+	if c.current != nil {
+		summary.CurrentTimeout = uint64(c.getRoundChangeTimeout().Milliseconds())
+	}
+	return summary
+}
+
+// ConsensusStatus returns the current view and the health of the consensus.
+func (c *core) ConsensusStatus() *ConsensusStatus {
+	status := new(ConsensusStatus)
+	c.currentMu.RLock()
+	if c.current != nil {
+		summary := c.current.Summary()
+		status.State = summary.State
+		status.Sequence = summary.Sequence
+		status.Round = summary.Round
+		status.DesiredRound = summary.DesiredRound
+		status.Proposer = summary.Proposer
+		status.IsProposer = summary.Proposer == c.address
+	}
+	c.currentMu.RUnlock()
+	c.health.fill(status)
+	return status
+}
+
 func (c *core) ForceRoundChange() {
 	// timeout current DesiredView
 	view := &istanbul.View{Sequence: c.current.Sequence(), Round: c.current.DesiredRound()}
 	c.sendEvent(timeoutAndMoveToNextRoundEvent{view})
 }
 
+func (c *core) ForceRoundChanges(rounds uint64) {
+	// skip the given number of rounds from the current DesiredView
+	view := &istanbul.View{Sequence: c.current.Sequence(), Round: c.current.DesiredRound()}
+	c.sendEvent(forceRoundChangesEvent{view: view, rounds: rounds})
+}
+
 // PrepareCommittedSeal returns a committed seal for the given hash and round number.
 func PrepareCommittedSeal(hash common.Hash, round *big.Int) []byte {
 	var buf bytes.Buffer
@@ -394,6 +462,17 @@ func (c *core) commit() error {
 		c.consensusCommitTimeGauge.Update(time.Since(c.consensusTimestamp).Nanoseconds())
 		c.consensusTimestamp = time.Time{}
 	}
+	if !c.preparedTimestamp.IsZero() {
+		c.phaseCommitTimer.UpdateSince(c.preparedTimestamp)
+		c.preparedTimestamp = time.Time{}
+	}
+	// Only round 0 sequences measure the network, later rounds include timeouts
+	if !c.sequenceTimestamp.IsZero() {
+		if c.current.Round().Sign() == 0 {
+			c.timeouts.observe(time.Since(c.sequenceTimestamp) - time.Duration(c.config.BlockPeriod)*time.Second)
+		}
+		c.sequenceTimestamp = time.Time{}
+	}
 
 	// Process Backlog Messages
 	c.backlog.updateState(c.current.View(), c.current.State())
@@ -423,6 +502,7 @@ func (c *core) commit() error {
 			c.waitForDesiredRound(nextRound)
 			return nil
 		}
+		c.health.committed(c.current.View(), proposal.Hash(), c.current.Commits().Addresses())
 	}
 
 	logger.Info("Committed")
@@ -546,7 +626,10 @@ func (c *core) startNewRound(round *big.Int, propose bool) error {
 	}
 
 	// Update the roundstate db
+	prevRound := c.current.Round()
 	c.current.StartNewRound(round, valSet, nextProposer)
+	c.preparedTimestamp = time.Time{}
+	c.health.changeRound(newView.Sequence, prevRound, round, prevProposer.Address(), prevProposer.Address() == c.address)
 
 	// Process backlog
 	c.processPendingRequests()
@@ -614,6 +697,8 @@ func (c *core) startNewSequence() error {
 	c.processPendingRequests()
 	c.backlog.updateState(c.current.View(), c.current.State())
 
+	c.sequenceTimestamp, c.preparedTimestamp = time.Now(), time.Time{}
+	c.health.startSequence()
 	c.resetRoundChangeTimer()
 
 	// Some round info will have changed.
@@ -711,6 +796,9 @@ func (c *core) resetRoundState(view *istanbul.View, validatorSet istanbul.Valida
 		headBlock := c.backend.GetCurrentHeadBlock()
 		newParentCommits = newMessageSet(c.backend.ParentBlockValidators(headBlock))
 	}
+	if c.archive != nil {
+		c.archive.recordSequence(view.Sequence.Uint64(), c.address, validatorSet)
+	}
 	return c.current.StartNewSequence(view.Sequence, validatorSet, nextProposer, newParentCommits)
 }
 
@@ -776,7 +864,7 @@ func (c *core) getRoundChangeTimeout() time.Duration {
 		9         515	       520
 		10        1027	       1032
 	*/
-	baseTimeout := time.Duration(c.config.RequestTimeout) * time.Millisecond
+	baseTimeout := c.timeouts.baseTimeout(time.Duration(c.config.RequestTimeout)*time.Millisecond, c.config.AdaptiveRequestTimeout)
 	blockTime := time.Duration(c.config.BlockPeriod) * time.Second
 	round := c.current.DesiredRound().Uint64()
 	if round == 0 {
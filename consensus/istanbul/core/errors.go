@@ -84,4 +84,8 @@ var (
 	errInvalidValidatorAddress = errors.New("failed to find an existing validator by address")
 	// Invalid round state
 	errInvalidState = errors.New("invalid round state")
+	// errSignedHigherView is returned when signing a message of a lower view than one the validator signed.
+	errSignedHigherView = errors.New("refusing to sign below the highest view signed")
+	// errSignedOtherDigest is returned when signing a message of a view the validator signed for another digest.
+	errSignedOtherDigest = errors.New("refusing to sign another digest in a view signed")
 )
@@ -30,3 +30,8 @@ type resendRoundChangeEvent struct {
 type timeoutAndMoveToNextRoundEvent struct {
 	view *istanbul.View
 }
+
+type forceRoundChangesEvent struct {
+	view   *istanbul.View
+	rounds uint64
+}
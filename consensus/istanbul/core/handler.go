@@ -26,11 +26,18 @@ import (
 
 // Start implements core.Engine.Start
 func (c *core) Start() error {
-	rsdb, err := newRoundStateDB(c.config.RoundStateDBPath, nil)
+	opts := defaultRoundStateDBOptions
+	opts.key = c.config.RoundStateDBKey
+	rsdb, err := newRoundStateDB(c.config.RoundStateDBPath, &opts)
 	if err != nil {
 		log.Crit("Failed to open RoundStateDB", "err", err)
 	}
 	c.rsdb = rsdb
+	if c.config.MessageArchive {
+		if c.archive, err = newMessageArchive(c.config.MessageArchiveDBPath, c.config.MessageArchiveSequences); err != nil {
+			log.Error("Failed to open the message archive", "err", err)
+		}
+	}
 	roundState, err := c.createRoundState()
 	if err != nil {
 		return err
@@ -39,6 +46,9 @@ func (c *core) Start() error {
 	c.currentMu.Lock()
 	c.current = roundState
 	c.currentMu.Unlock()
+	if c.archive != nil {
+		c.archive.recordSequence(roundState.Sequence().Uint64(), c.address, roundState.ValidatorSet())
+	}
 	c.roundChangeSetV2 = newRoundChangeSetV2(c.current.ValidatorSet())
 
 	// Reset the Round Change timer for the current round to timeout.
@@ -69,6 +79,10 @@ func (c *core) Stop() error {
 	c.handlerWg.Wait()
 
 	err := c.rsdb.Close()
+	if c.archive != nil {
+		c.archive.Close()
+		c.archive = nil
+	}
 	c.currentMu.Lock()
 	defer c.currentMu.Unlock()
 	c.current = nil
@@ -89,6 +103,7 @@ func (c *core) subscribeEvents() {
 	c.timeoutSub = c.backend.EventMux().Subscribe(
 		timeoutAndMoveToNextRoundEvent{},
 		resendRoundChangeEvent{},
+		forceRoundChangesEvent{},
 	)
 	c.finalCommittedSub = c.backend.EventMux().Subscribe(
 		istanbul.FinalCommittedEvent{},
@@ -124,16 +139,23 @@ func (c *core) handleEvents() {
 					c.storeRequestMsg(r)
 				}
 			case istanbul.MessageEvent:
-				if err := c.handleMsg(ev.Payload); err != nil && err != errFutureMessage && err != errOldMessage {
+				if c.archive != nil {
+					c.archive.recordMessage(ev.Payload)
+				}
+				err := c.handleMsg(ev.Payload)
+				if err != nil && err != errFutureMessage && err != errOldMessage {
 					logger.Warn("Error in handling istanbul message", "err", err)
 				}
+				c.traceMessage(ev.Payload, false, err)
 			case backlogEvent:
 				if payload, err := ev.msg.Payload(); err != nil {
 					logger.Error("Error in retrieving payload from istanbul message that was sent from a backlog event", "err", err)
 				} else {
-					if err := c.handleMsg(payload); err != nil && err != errFutureMessage && err != errOldMessage {
+					err := c.handleMsg(payload)
+					if err != nil && err != errFutureMessage && err != errOldMessage {
 						logger.Warn("Error in handling istanbul message that was sent from a backlog event", "err", err)
 					}
+					c.traceMessage(payload, true, err)
 				}
 			}
 		case event, ok := <-c.timeoutSub.Chan():
@@ -142,13 +164,22 @@ func (c *core) handleEvents() {
 			}
 			switch ev := event.Data.(type) {
 			case timeoutAndMoveToNextRoundEvent:
-				if err := c.handleTimeoutAndMoveToNextRound(ev.view); err != nil {
+				if c.archive != nil {
+					c.archive.recordTimeout(ev.view)
+				}
+				err := c.handleTimeoutAndMoveToNextRound(ev.view)
+				if err != nil {
 					logger.Error("Error on handleTimeoutAndMoveToNextRound", "err", err)
 				}
+				c.traceTimeout(ev.view, err)
 			case resendRoundChangeEvent:
 				if err := c.handleResendRoundChangeEvent(ev.view); err != nil {
 					logger.Error("Error on handleResendRoundChangeEvent", "err", err)
 				}
+			case forceRoundChangesEvent:
+				if err := c.handleForceRoundChanges(ev.view, ev.rounds); err != nil {
+					logger.Error("Error on handleForceRoundChanges", "err", err)
+				}
 			}
 		case event, ok := <-c.finalCommittedSub.Chan():
 			if !ok {
@@ -233,6 +264,20 @@ func (c *core) handleTimeoutAndMoveToNextRound(timedOutView *istanbul.View) erro
 	return c.waitForDesiredRound(nextRound)
 }
 
+func (c *core) handleForceRoundChanges(desiredView *istanbul.View, rounds uint64) error {
+	logger := c.newLogger("func", "handleForceRoundChanges", "set_at_seq", desiredView.Sequence, "set_at_desiredRound", desiredView.Round, "rounds", rounds)
+
+	// Avoid races where message is enqueued then a later event advances sequence or desired round.
+	if c.current.Sequence().Cmp(desiredView.Sequence) != 0 || c.current.DesiredRound().Cmp(desiredView.Round) != 0 {
+		logger.Trace("Forced round changes but now on a different view")
+		return nil
+	}
+
+	logger.Info("Forcing round changes")
+	nextRound := new(big.Int).Add(desiredView.Round, new(big.Int).SetUint64(rounds))
+	return c.waitForDesiredRound(nextRound)
+}
+
 func (c *core) handleResendRoundChangeEvent(desiredView *istanbul.View) error {
 	logger := c.newLogger("func", "handleResendRoundChangeEvent", "set_at_seq", desiredView.Sequence, "set_at_desiredRound", desiredView.Round)
 
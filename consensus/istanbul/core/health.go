@@ -0,0 +1,157 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+// maxRoundChangeHistory is the number of latest round changes reported by the
+// consensus status.
+const maxRoundChangeHistory = 32
+
+// ConsensusStatus reports the health of the consensus of the validator.
+type ConsensusStatus struct {
+	State        string         `json:"state"`
+	Sequence     *big.Int       `json:"sequence"`
+	Round        *big.Int       `json:"round"`
+	DesiredRound *big.Int       `json:"desiredRound"`
+	Proposer     common.Address `json:"proposer"`
+	IsProposer   bool           `json:"isProposer"`
+
+	MissedProposals uint64                `json:"missedProposals"` // Rounds of the validator as proposer that changed, since it started
+	RoundChanges    []*RoundChangeSummary `json:"roundChanges"`    // Latest round changes, oldest first
+
+	LastPrepared  *SealSummary `json:"lastPrepared"`  // Latest prepared certificate
+	LastCommitted *SealSummary `json:"lastCommitted"` // Latest proposal committed
+}
+
+// RoundChangeSummary is the move of a sequence from a round to another one.
+type RoundChangeSummary struct {
+	Sequence  *big.Int       `json:"sequence"`
+	FromRound uint64         `json:"fromRound"`
+	ToRound   uint64         `json:"toRound"`
+	Proposer  common.Address `json:"proposer"` // Proposer of the round left
+	Duration  uint64         `json:"duration"` // Milliseconds spent in the round left
+}
+
+// SealSummary is a proposal prepared or committed by a quorum of validators.
+type SealSummary struct {
+	Sequence     *big.Int         `json:"sequence"`
+	Round        *big.Int         `json:"round"`
+	ProposalHash common.Hash      `json:"proposalHash"`
+	Signers      []common.Address `json:"signers"` // Senders of the prepares and commits, or of the commits if committed
+}
+
+// consensusHealth tracks the round changes, prepared certificates and commits of
+// the core for the consensus status.
+type consensusHealth struct {
+	mu              sync.RWMutex
+	roundStart      time.Time // Start of the current round
+	missedProposals uint64
+	roundChanges    []*RoundChangeSummary
+	lastPrepared    *SealSummary
+	lastCommitted   *SealSummary
+
+	missedProposalMeter metrics.Meter
+	roundChangeMeter    metrics.Meter
+	roundChangeTimer    metrics.Timer
+}
+
+func newConsensusHealth() *consensusHealth {
+	return &consensusHealth{
+		missedProposalMeter: metrics.NewRegisteredMeter("consensus/istanbul/core/missed_proposals", nil),
+		roundChangeMeter:    metrics.NewRegisteredMeter("consensus/istanbul/core/round_changes", nil),
+		roundChangeTimer:    metrics.NewRegisteredTimer("consensus/istanbul/core/round_change_duration", nil),
+	}
+}
+
+// startSequence records the start of round 0 of a sequence.
+func (h *consensusHealth) startSequence() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.roundStart = time.Now()
+}
+
+// changeRound records the move of a sequence to a later round. The change is a
+// missed proposal if the validator was the proposer of the round left.
+func (h *consensusHealth) changeRound(sequence, from, to *big.Int, proposer common.Address, missed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	change := &RoundChangeSummary{
+		Sequence:  new(big.Int).Set(sequence),
+		FromRound: from.Uint64(),
+		ToRound:   to.Uint64(),
+		Proposer:  proposer,
+	}
+	if !h.roundStart.IsZero() {
+		elapsed := time.Since(h.roundStart)
+		change.Duration = uint64(elapsed.Milliseconds())
+		h.roundChangeTimer.Update(elapsed)
+	}
+	h.roundStart = time.Now()
+	if len(h.roundChanges) == maxRoundChangeHistory {
+		h.roundChanges = h.roundChanges[1:]
+	}
+	h.roundChanges = append(h.roundChanges, change)
+	h.roundChangeMeter.Mark(1)
+	if missed {
+		h.missedProposals++
+		h.missedProposalMeter.Mark(1)
+	}
+}
+
+// prepared records the prepared certificate of a view.
+func (h *consensusHealth) prepared(view *istanbul.View, certificate *istanbul.PreparedCertificateSummary) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastPrepared = &SealSummary{
+		Sequence:     new(big.Int).Set(view.Sequence),
+		Round:        new(big.Int).Set(view.Round),
+		ProposalHash: certificate.ProposalHash,
+		Signers:      append(certificate.PrepareSenders, certificate.CommitSenders...),
+	}
+}
+
+// committed records the proposal committed in a view.
+func (h *consensusHealth) committed(view *istanbul.View, proposalHash common.Hash, signers []common.Address) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastCommitted = &SealSummary{
+		Sequence:     new(big.Int).Set(view.Sequence),
+		Round:        new(big.Int).Set(view.Round),
+		ProposalHash: proposalHash,
+		Signers:      signers,
+	}
+}
+
+// fill adds the tracked history to a status.
+func (h *consensusHealth) fill(status *ConsensusStatus) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	status.MissedProposals = h.missedProposals
+	status.RoundChanges = append([]*RoundChangeSummary{}, h.roundChanges...)
+	status.LastPrepared = h.lastPrepared
+	status.LastCommitted = h.lastCommitted
+}
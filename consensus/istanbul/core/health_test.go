@@ -0,0 +1,69 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+)
+
+func TestConsensusHealth(t *testing.T) {
+	var (
+		health   = newConsensusHealth()
+		self     = common.HexToAddress("0x01")
+		other    = common.HexToAddress("0x02")
+		sequence = big.NewInt(10)
+	)
+	health.startSequence()
+	health.changeRound(sequence, big.NewInt(0), big.NewInt(1), self, true)
+	health.changeRound(sequence, big.NewInt(1), big.NewInt(3), other, false)
+	health.prepared(&istanbul.View{Sequence: sequence, Round: big.NewInt(3)}, &istanbul.PreparedCertificateSummary{
+		ProposalHash:   common.Hash{1},
+		PrepareSenders: []common.Address{self},
+		CommitSenders:  []common.Address{other},
+	})
+	health.committed(&istanbul.View{Sequence: sequence, Round: big.NewInt(3)}, common.Hash{1}, []common.Address{self, other})
+
+	status := new(ConsensusStatus)
+	health.fill(status)
+	if status.MissedProposals != 1 {
+		t.Errorf("missed proposals mismatch: have %d, want 1", status.MissedProposals)
+	}
+	if len(status.RoundChanges) != 2 {
+		t.Fatalf("round changes mismatch: have %d, want 2", len(status.RoundChanges))
+	}
+	if change := status.RoundChanges[1]; change.FromRound != 1 || change.ToRound != 3 || change.Proposer != other {
+		t.Errorf("round change mismatch: have %d -> %d by %v, want 1 -> 3 by %v", change.FromRound, change.ToRound, change.Proposer, other)
+	}
+	if status.LastPrepared == nil || len(status.LastPrepared.Signers) != 2 || status.LastPrepared.Round.Uint64() != 3 {
+		t.Errorf("last prepared mismatch: have %+v", status.LastPrepared)
+	}
+	if status.LastCommitted == nil || status.LastCommitted.ProposalHash != (common.Hash{1}) || len(status.LastCommitted.Signers) != 2 {
+		t.Errorf("last committed mismatch: have %+v", status.LastCommitted)
+	}
+	// Only the latest round changes are kept
+	for i := 0; i < maxRoundChangeHistory; i++ {
+		health.changeRound(big.NewInt(11), big.NewInt(int64(i)), big.NewInt(int64(i+1)), other, false)
+	}
+	health.fill(status)
+	if len(status.RoundChanges) != maxRoundChangeHistory || status.RoundChanges[0].Sequence.Uint64() != 11 {
+		t.Errorf("round change history mismatch: have %d, first of sequence %v", len(status.RoundChanges), status.RoundChanges[0].Sequence)
+	}
+}
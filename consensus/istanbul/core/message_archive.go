@@ -0,0 +1,279 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/task"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/ethdb/leveldb"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/rlp"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	archiveEventKey    = "event" // Key prefix of the archived events, by sequence and arrival
+	archiveSequenceKey = "seq"   // Key prefix of the sequences the core started, by sequence
+
+	archiveGarbageCollectorPeriod = 2 * time.Minute
+)
+
+// Kinds of archived events
+const (
+	ArchivedMessage uint64 = iota // Consensus message received by the core
+	ArchivedTimeout               // Timeout of the round change timer of a view
+)
+
+var errNoMessageArchive = errors.New("no message archive db path")
+
+// ArchivedEvent is a consensus event handled by the core, as archived.
+type ArchivedEvent struct {
+	ReceivedAt uint64 // Unix time in nanoseconds
+	Kind       uint64
+	Payload    []byte // Payload of the message, or RLP encoded view of the timeout
+}
+
+// archivedSequence is the state the core started a sequence with, which the
+// validator sets can't be derived again from without the state of the chain.
+type archivedSequence struct {
+	Address      common.Address // Address of the validator of the node
+	ValidatorSet []byte         // Serialized validator set, with the proposer randomness
+}
+
+// messageArchive persists the consensus messages received by the core, and the
+// timeouts of its rounds, for the last sequences, so they can be fed through a
+// core again to debug liveness incidents. See ReplayMessageArchive.
+type messageArchive struct {
+	db        *leveldb.Database
+	sequences uint64 // Number of sequences kept, all if zero
+
+	mu           sync.Mutex
+	counter      uint64 // Events archived since the archive was opened, ordering the ones received at once
+	lastSequence uint64 // Last sequence archived
+
+	stopGarbageCollector task.StopFn
+	logger               log.Logger
+}
+
+// newMessageArchive opens the message archive db at path, pruning the events of
+// the sequences older than the last ones if sequences isn't zero.
+func newMessageArchive(path string, sequences uint64) (*messageArchive, error) {
+	if path == "" {
+		return nil, errNoMessageArchive
+	}
+	db, err := newPersistentDB(path, "consensus/istanbul/archive/db/")
+	if err != nil {
+		return nil, err
+	}
+	archive := &messageArchive{
+		db:        db,
+		sequences: sequences,
+		logger:    log.New("type", "messageArchive", "path", path),
+	}
+	if archive.lastSequence, err = archive.lastArchivedSequence(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if sequences > 0 {
+		archive.stopGarbageCollector = task.RunTaskRepeateadly(archive.garbageCollect, task.NewDefaultTicker(archiveGarbageCollectorPeriod))
+	}
+	return archive, nil
+}
+
+// Close closes the archive db.
+func (a *messageArchive) Close() error {
+	if a.stopGarbageCollector != nil {
+		a.stopGarbageCollector()
+	}
+	return a.db.Close()
+}
+
+// recordMessage archives a consensus message received. Non consensus messages
+// and undecodable ones are ignored.
+func (a *messageArchive) recordMessage(payload []byte) {
+	msg := new(istanbul.Message)
+	if err := msg.FromPayload(payload, nil); err != nil {
+		return
+	}
+	switch msg.Code {
+	case istanbul.MsgPreprepareV2, istanbul.MsgPrepare, istanbul.MsgCommit, istanbul.MsgRoundChangeV2:
+	default:
+		return
+	}
+	view := extractMessageView(msg)
+	if view == nil || view.Sequence == nil {
+		return
+	}
+	a.record(view.Sequence.Uint64(), ArchivedMessage, payload)
+}
+
+// recordTimeout archives a timeout of the round change timer of the view.
+func (a *messageArchive) recordTimeout(view *istanbul.View) {
+	payload, err := rlp.EncodeToBytes(view)
+	if err != nil {
+		return
+	}
+	a.record(view.Sequence.Uint64(), ArchivedTimeout, payload)
+}
+
+func (a *messageArchive) record(sequence uint64, kind uint64, payload []byte) {
+	now := time.Now()
+
+	a.mu.Lock()
+	a.counter++
+	key := archiveEventKeyFor(sequence, uint64(now.UnixNano()), a.counter)
+	a.mu.Unlock()
+
+	blob, err := rlp.EncodeToBytes(&ArchivedEvent{ReceivedAt: uint64(now.UnixNano()), Kind: kind, Payload: payload})
+	if err != nil {
+		return
+	}
+	if err := a.db.Put(key, blob); err != nil {
+		a.logger.Warn("Failed to archive consensus event", "sequence", sequence, "err", err)
+	}
+}
+
+// recordSequence archives the validator set the core started the sequence with,
+// unless it did already.
+func (a *messageArchive) recordSequence(sequence uint64, address common.Address, valSet istanbul.ValidatorSet) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if sequence <= a.lastSequence {
+		return
+	}
+	serialized, err := valSet.Serialize()
+	if err != nil {
+		a.logger.Warn("Failed to serialize validator set", "sequence", sequence, "err", err)
+		return
+	}
+	blob, err := rlp.EncodeToBytes(&archivedSequence{Address: address, ValidatorSet: serialized})
+	if err != nil {
+		return
+	}
+	if err := a.db.Put(archiveSequenceKeyFor(sequence), blob); err != nil {
+		a.logger.Warn("Failed to archive consensus sequence", "sequence", sequence, "err", err)
+		return
+	}
+	a.lastSequence = sequence
+}
+
+// events returns the events archived for the sequences in [from, to], in the
+// order the core handled them.
+func (a *messageArchive) events(from, to uint64) ([]*ArchivedEvent, error) {
+	rang := &util.Range{Start: archiveEventKeyFor(from, 0, 0)}
+	if to < math.MaxUint64 {
+		rang.Limit = archiveEventKeyFor(to+1, 0, 0)
+	}
+	iter := a.db.NewRangeIterator(rang)
+	defer iter.Release()
+
+	var events []*ArchivedEvent
+	for iter.Next() {
+		event := new(ArchivedEvent)
+		if err := rlp.DecodeBytes(iter.Value(), event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, iter.Error()
+}
+
+// sequence returns the state archived for the sequence, or for the closest
+// sequence before it if the core didn't start it, and nil if there's none.
+func (a *messageArchive) sequence(sequence uint64) (*archivedSequence, error) {
+	iter := a.db.NewRangeIterator(&util.Range{Start: archiveSequenceKeyFor(0), Limit: archiveSequenceKeyFor(sequence + 1)})
+	defer iter.Release()
+
+	var blob []byte
+	for iter.Next() {
+		blob = common.CopyBytes(iter.Value())
+	}
+	if err := iter.Error(); err != nil || blob == nil {
+		return nil, err
+	}
+	archived := new(archivedSequence)
+	if err := rlp.DecodeBytes(blob, archived); err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
+
+// lastArchivedSequence returns the last sequence the core started, or zero if
+// there's none.
+func (a *messageArchive) lastArchivedSequence() (uint64, error) {
+	iter := a.db.NewRangeIterator(util.BytesPrefix([]byte(archiveSequenceKey)))
+	defer iter.Release()
+
+	var last uint64
+	for iter.Next() {
+		last = binary.BigEndian.Uint64(iter.Key()[len(archiveSequenceKey):])
+	}
+	return last, iter.Error()
+}
+
+// garbageCollect deletes the events and sequences older than the last ones kept.
+func (a *messageArchive) garbageCollect() {
+	a.mu.Lock()
+	last := a.lastSequence
+	a.mu.Unlock()
+	if last < a.sequences {
+		return
+	}
+	oldest := last - a.sequences + 1
+	count := 0
+	for _, rang := range []*util.Range{
+		{Start: archiveEventKeyFor(0, 0, 0), Limit: archiveEventKeyFor(oldest, 0, 0)},
+		{Start: archiveSequenceKeyFor(0), Limit: archiveSequenceKeyFor(oldest)},
+	} {
+		iter := a.db.NewRangeIterator(rang)
+		batch := a.db.NewBatch()
+		for iter.Next() {
+			batch.Delete(common.CopyBytes(iter.Key()))
+			count++
+		}
+		iter.Release()
+		if err := batch.Write(); err != nil {
+			a.logger.Error("Failed to prune the message archive", "err", err)
+			return
+		}
+	}
+	a.logger.Debug("Pruned the message archive", "oldest_sequence", oldest, "removed_entries", count)
+}
+
+func archiveEventKeyFor(sequence, receivedAt, counter uint64) []byte {
+	key := make([]byte, len(archiveEventKey)+24)
+	copy(key, archiveEventKey)
+	binary.BigEndian.PutUint64(key[len(archiveEventKey):], sequence)
+	binary.BigEndian.PutUint64(key[len(archiveEventKey)+8:], receivedAt)
+	binary.BigEndian.PutUint64(key[len(archiveEventKey)+16:], counter)
+	return key
+}
+
+func archiveSequenceKeyFor(sequence uint64) []byte {
+	key := make([]byte, len(archiveSequenceKey)+8)
+	copy(key, archiveSequenceKey)
+	binary.BigEndian.PutUint64(key[len(archiveSequenceKey):], sequence)
+	return key
+}
@@ -0,0 +1,190 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/rlp"
+	"github.com/celo-org/celo-blockchain/trie"
+)
+
+func TestMessageArchive(t *testing.T) {
+	archive, err := newMessageArchive(filepath.Join(t.TempDir(), "archive"), 0)
+	if err != nil {
+		t.Fatalf("failed to open the message archive: %v", err)
+	}
+	defer archive.Close()
+
+	valSet := newTestValidatorSet(4)
+	addr := valSet.GetByIndex(0).Address()
+	for seq := uint64(1); seq <= 3; seq++ {
+		archive.recordSequence(seq, addr, valSet)
+		for _, code := range []uint64{istanbul.MsgPrepare, istanbul.MsgCommit} {
+			payload, _ := mockViewMsg(newView(seq, 0), code, addr).Payload()
+			archive.recordMessage(payload)
+		}
+		archive.recordTimeout(newView(seq, 0))
+	}
+	// Messages of other kinds aren't archived
+	payload, _ := (&istanbul.Message{Code: istanbul.DEPRECATED_MsgRoundChange, Address: addr}).Payload()
+	archive.recordMessage(payload)
+	// Sequences are only archived once
+	archive.recordSequence(2, common.Address{}, valSet)
+
+	events, err := archive.events(2, 2)
+	if err != nil {
+		t.Fatalf("failed to read the archived events: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("archived events mismatch: have %d, want 3", len(events))
+	}
+	for i, kind := range []uint64{ArchivedMessage, ArchivedMessage, ArchivedTimeout} {
+		if events[i].Kind != kind {
+			t.Errorf("event %d kind mismatch: have %d, want %d", i, events[i].Kind, kind)
+		}
+	}
+	view := new(istanbul.View)
+	if err := rlp.DecodeBytes(events[2].Payload, view); err != nil || view.Cmp(newView(2, 0)) != 0 {
+		t.Errorf("archived timeout mismatch: have %v, want %v (%v)", view, newView(2, 0), err)
+	}
+	for seq, want := range map[uint64]bool{0: false, 2: true, 10: true} {
+		started, err := archive.sequence(seq)
+		if err != nil {
+			t.Fatalf("failed to read the archived sequence %d: %v", seq, err)
+		}
+		if (started != nil) != want {
+			t.Fatalf("archived sequence %d mismatch: have %v, want %v", seq, started != nil, want)
+		}
+		if started != nil && started.Address != addr {
+			t.Errorf("archived address of sequence %d mismatch: have %x, want %x", seq, started.Address, addr)
+		}
+	}
+	if last, err := archive.lastArchivedSequence(); err != nil || last != 3 {
+		t.Fatalf("last archived sequence mismatch: have %d, want 3 (%v)", last, err)
+	}
+
+	// Only the events of the last sequences are kept
+	archive.sequences = 2
+	archive.garbageCollect()
+	if events, _ := archive.events(0, 3); len(events) != 6 {
+		t.Errorf("events kept mismatch: have %d, want 6", len(events))
+	}
+	if started, _ := archive.sequence(1); started != nil {
+		t.Errorf("sequence 1 kept")
+	}
+}
+
+// newReplayTestArchive archives a timeout of the first round of the first
+// sequence followed by the round changes of the other validators, and returns
+// the chain db to replay them on.
+func newReplayTestArchive(t *testing.T, sys *testSystem) (string, ethdb.Database) {
+	self := sys.backends[0]
+	path := filepath.Join(t.TempDir(), "archive")
+
+	archive, err := newMessageArchive(path, 0)
+	if err != nil {
+		t.Fatalf("failed to open the message archive: %v", err)
+	}
+	archive.recordSequence(1, self.address, self.peers)
+	archive.recordTimeout(newView(1, 0))
+	emptyCert, emptyProposal := istanbul.EmptyPreparedCertificateV2()
+	for _, b := range sys.backends[1:] {
+		msg, err := b.getRoundChangeV2Message(*newView(1, 1), emptyCert, emptyProposal)
+		if err != nil {
+			t.Fatalf("failed to create round change: %v", err)
+		}
+		payload, _ := msg.Payload()
+		archive.recordMessage(payload)
+	}
+	archive.Close()
+
+	db := rawdb.NewMemoryDatabase()
+	extra, _ := rlp.EncodeToBytes(&types.IstanbulExtra{AggregatedSeal: types.IstanbulAggregatedSeal{Round: big.NewInt(0)}})
+	header := &types.Header{Number: big.NewInt(0), Extra: append(make([]byte, types.IstanbulExtraVanity), extra...)}
+	genesis := types.NewBlock(header, nil, nil, nil, new(trie.Trie))
+	rawdb.WriteBlock(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+	return path, db
+}
+
+func TestReplayMessageArchive(t *testing.T) {
+	sys := NewMutedTestSystemWithBackendV2(4, 1)
+	path, db := newReplayTestArchive(t, sys)
+
+	var steps []*ReplayStep
+	config := *istanbul.DefaultConfig
+	config.ProposerPolicy = istanbul.RoundRobin
+	chainConfig := &params.ChainConfig{DonutBlock: big.NewInt(0)}
+	if err := ReplayMessageArchive(path, db, chainConfig, &config, 1, 1, func(step *ReplayStep) { steps = append(steps, step) }); err != nil {
+		t.Fatalf("failed to replay the message archive: %v", err)
+	}
+	if len(steps) != 4 {
+		t.Fatalf("replayed steps mismatch: have %d, want 4", len(steps))
+	}
+	if steps[0].Event != "timeout" || steps[0].DesiredRound.Uint64() != 1 {
+		t.Errorf("timeout step mismatch: have %s to desired round %d", steps[0].Event, steps[0].DesiredRound)
+	}
+	for i, step := range steps[1:] {
+		if step.Event != "roundchange" || step.From == nil || *step.From != sys.backends[i+1].address {
+			t.Errorf("step %d mismatch: have %s from %v", i+1, step.Event, step.From)
+		}
+		if step.ReceivedAt == nil {
+			t.Errorf("step %d misses its arrival time", i+1)
+		}
+	}
+	if last := steps[3]; last.Sequence.Uint64() != 1 || last.Round.Uint64() != 1 {
+		t.Errorf("replayed view mismatch: have %d/%d, want 1/1", last.Sequence, last.Round)
+	}
+}
+
+func TestReplayRoundStates(t *testing.T) {
+	sys := NewMutedTestSystemWithBackendV2(4, 1)
+	path, db := newReplayTestArchive(t, sys)
+
+	config := *istanbul.DefaultConfig
+	config.ProposerPolicy = istanbul.RoundRobin
+	chainConfig := &params.ChainConfig{DonutBlock: big.NewInt(0)}
+	summaries, err := ReplayRoundStates(path, db, chainConfig, &config, 1, 1)
+	if err != nil {
+		t.Fatalf("failed to replay the round states: %v", err)
+	}
+	// The first round timed out and the round changes moved the core to the next one
+	if len(summaries) != 2 {
+		t.Fatalf("replayed rounds mismatch: have %d, want 2", len(summaries))
+	}
+	for i, summary := range summaries {
+		if summary.Sequence.Uint64() != 1 || summary.Round.Uint64() != uint64(i) || summary.DesiredRound.Uint64() != 1 {
+			t.Errorf("round %d mismatch: have view %d/%d desiring round %d", i, summary.Sequence, summary.Round, summary.DesiredRound)
+		}
+		if len(summary.ValidatorSet) != 4 {
+			t.Fatalf("round %d validators mismatch: have %d, want 4", i, len(summary.ValidatorSet))
+		}
+		// Every round is attributed to its own proposer, not the one of the round it moved to
+		if want := summary.ValidatorSet[i]; summary.Proposer != want {
+			t.Errorf("round %d proposer mismatch: have %x, want %x", i, summary.Proposer, want)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/validator"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/event"
+	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/rlp"
+)
+
+// replayTimeout is the round change timeout of the replaying core, long enough
+// for the rounds to only change on the archived timeouts and round changes.
+const replayTimeout = 24 * time.Hour
+
+var errReplayNoSigning = errors.New("messages can't be signed when replaying")
+
+// archivedMessageNames are the names of the archived messages, by code.
+var archivedMessageNames = map[uint64]string{
+	istanbul.MsgPreprepareV2:  "preprepare",
+	istanbul.MsgPrepare:       "prepare",
+	istanbul.MsgCommit:        "commit",
+	istanbul.MsgRoundChangeV2: "roundchange",
+}
+
+// ReplayStep is the outcome of a consensus event replayed through the core.
+type ReplayStep struct {
+	ReceivedAt *time.Time      `json:"receivedAt,omitempty"` // Nil for the messages handled again from the backlog
+	Event      string          `json:"event"`                // Message name, or timeout
+	From       *common.Address `json:"from,omitempty"`
+	View       *istanbul.View  `json:"view"`
+	Backlog    bool            `json:"backlog,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Committed  *common.Hash    `json:"committed,omitempty"` // Proposal committed handling the event
+
+	// State of the core after the event
+	Sequence     *big.Int `json:"sequence"`
+	Round        *big.Int `json:"round"`
+	DesiredRound *big.Int `json:"desiredRound"`
+	State        string   `json:"state"`
+}
+
+// traceMessage reports the outcome of handling a message when replaying.
+func (c *core) traceMessage(payload []byte, backlog bool, err error) {
+	if c.tracer == nil {
+		return
+	}
+	step := &ReplayStep{Event: "unknown", Backlog: backlog}
+	msg := new(istanbul.Message)
+	if decodeErr := msg.FromPayload(payload, nil); decodeErr == nil {
+		if name, ok := archivedMessageNames[msg.Code]; ok {
+			step.Event = name
+			step.View = extractMessageView(msg)
+		}
+		step.From = &msg.Address
+	}
+	c.trace(step, err)
+}
+
+// traceTimeout reports the outcome of a timeout of the view when replaying.
+func (c *core) traceTimeout(view *istanbul.View, err error) {
+	if c.tracer == nil {
+		return
+	}
+	c.trace(&ReplayStep{Event: "timeout", View: view}, err)
+}
+
+func (c *core) trace(step *ReplayStep, err error) {
+	if err != nil {
+		step.Error = err.Error()
+	}
+	if c.current != nil {
+		step.Sequence = c.current.Sequence()
+		step.Round = c.current.Round()
+		step.DesiredRound = c.current.DesiredRound()
+		step.State = c.current.State().String()
+	}
+	c.tracer(step)
+}
+
+// LastArchivedSequence opens the message archive db at path and returns the
+// last sequence the core started, or zero if there's none.
+func LastArchivedSequence(path string) (uint64, error) {
+	archive, err := newMessageArchive(path, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer archive.Close()
+	return archive.lastArchivedSequence()
+}
+
+// ReplayMessageArchive feeds the consensus events archived for the sequences in
+// [from, to] through a core started at the canonical block of db before from,
+// in the order they were handled, and calls trace with the outcome of each one.
+// The core starts the sequences with the validator sets archived, doesn't sign
+// or send any message, and accepts the proposals without executing them again.
+// The archive db can't be opened while the node using it is running.
+func ReplayMessageArchive(path string, db ethdb.Reader, chainConfig *params.ChainConfig, config *istanbul.Config, from, to uint64, trace func(*ReplayStep)) error {
+	return replayMessageArchive(path, db, chainConfig, config, from, to, func(_ *core, step *ReplayStep) { trace(step) })
+}
+
+// ReplayRoundStates replays the message archive like ReplayMessageArchive and
+// returns the summaries of the round states the core went through for the
+// sequences in [from, to], as they were when the core left them, ordered by view.
+func ReplayRoundStates(path string, db ethdb.Reader, chainConfig *params.ChainConfig, config *istanbul.Config, from, to uint64) ([]*RoundStateSummary, error) {
+	var summaries []*RoundStateSummary
+	err := replayMessageArchive(path, db, chainConfig, config, from, to, func(c *core, step *ReplayStep) {
+		if c.current == nil || c.current.Sequence().Uint64() < from || c.current.Sequence().Uint64() > to {
+			return
+		}
+		// The round state takes the proposer of the next round when it times out
+		summary := c.current.Summary()
+		parent := c.backend.AuthorForBlock(summary.Sequence.Uint64() - 1)
+		summary.Proposer = c.selectProposer(c.current.ValidatorSet(), parent, summary.Round.Uint64()).Address()
+		if n := len(summaries); n > 0 && summaries[n-1].Sequence.Cmp(summary.Sequence) == 0 && summaries[n-1].Round.Cmp(summary.Round) == 0 {
+			summaries[n-1] = summary
+			return
+		}
+		summaries = append(summaries, summary)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+func replayMessageArchive(path string, db ethdb.Reader, chainConfig *params.ChainConfig, config *istanbul.Config, from, to uint64, trace func(*core, *ReplayStep)) error {
+	if from == 0 || from > to {
+		return fmt.Errorf("invalid sequence range %d-%d", from, to)
+	}
+	archive, err := newMessageArchive(path, 0)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	events, err := archive.events(from, to)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("no consensus events archived for sequences %d-%d", from, to)
+	}
+	head := rawdb.ReadBlock(db, rawdb.ReadCanonicalHash(db, from-1), from-1)
+	if head == nil {
+		return fmt.Errorf("missing block %d", from-1)
+	}
+	started, err := archive.sequence(from)
+	if err != nil {
+		return err
+	}
+	if started == nil {
+		return fmt.Errorf("no validator set archived for sequence %d", from)
+	}
+	backend := &replayBackend{
+		db:          db,
+		archive:     archive,
+		chainConfig: chainConfig,
+		address:     started.Address,
+		head:        head,
+		mux:         new(event.TypeMux),
+		valSets:     make(map[uint64]istanbul.ValidatorSet),
+	}
+	defer backend.mux.Stop()
+
+	replayConfig := *config
+	replayConfig.RoundStateDBPath = ""
+	replayConfig.RoundStateDBKey = nil
+	replayConfig.MessageArchive = false
+	replayConfig.AdaptiveRequestTimeout = false
+	replayConfig.RequestTimeout = uint64(replayTimeout.Milliseconds())
+	replayConfig.MinResendRoundChangeTimeout = uint64(replayTimeout.Milliseconds())
+	replayConfig.MaxResendRoundChangeTimeout = uint64(replayTimeout.Milliseconds())
+
+	var (
+		handled = 0
+		done    = make(chan struct{})
+	)
+	c := New(backend, &replayConfig).(*core)
+	c.tracer = func(step *ReplayStep) {
+		step.Committed = backend.takeCommitted()
+		if !step.Backlog && handled < len(events) {
+			receivedAt := time.Unix(0, int64(events[handled].ReceivedAt))
+			step.ReceivedAt = &receivedAt
+			if handled++; handled == len(events) {
+				defer close(done)
+			}
+		}
+		trace(c, step)
+	}
+	if err := c.Start(); err != nil {
+		return err
+	}
+	defer c.Stop()
+
+	for _, archived := range events {
+		switch archived.Kind {
+		case ArchivedMessage:
+			backend.mux.Post(istanbul.MessageEvent{Payload: archived.Payload})
+		case ArchivedTimeout:
+			view := new(istanbul.View)
+			if err := rlp.DecodeBytes(archived.Payload, view); err != nil {
+				return err
+			}
+			backend.mux.Post(timeoutAndMoveToNextRoundEvent{view: view})
+		default:
+			return fmt.Errorf("unknown archived event kind %d", archived.Kind)
+		}
+	}
+	<-done
+	return nil
+}
+
+// replayBackend serves a replaying core the canonical chain of db up to the
+// head, moved by the proposals the core commits, and the validator sets of
+// the message archive.
+type replayBackend struct {
+	db          ethdb.Reader
+	archive     *messageArchive
+	chainConfig *params.ChainConfig
+	address     common.Address
+	mux         *event.TypeMux
+
+	mu        sync.Mutex
+	head      *types.Block
+	committed *common.Hash                     // Proposal committed since the last event
+	valSets   map[uint64]istanbul.ValidatorSet // Archived validator sets by sequence
+}
+
+func (b *replayBackend) Address() common.Address                  { return b.address }
+func (b *replayBackend) ChainConfig() *params.ChainConfig         { return b.chainConfig }
+func (b *replayBackend) EventMux() *event.TypeMux                 { return b.mux }
+func (b *replayBackend) Gossip(payload []byte, code uint64) error { return nil }
+func (b *replayBackend) SigningProtectionDB() ethdb.KeyValueStore { return rawdb.NewMemoryDatabase() }
+func (b *replayBackend) IsPrimaryForSeq(seq *big.Int) bool        { return true }
+func (b *replayBackend) UpdateReplicaState(seq *big.Int)          {}
+
+func (b *replayBackend) Multicast(addresses []common.Address, payload []byte, code uint64, sendToSelf bool) error {
+	return nil
+}
+
+func (b *replayBackend) Sign([]byte) ([]byte, error) { return nil, errReplayNoSigning }
+
+func (b *replayBackend) SignBLS([]byte, []byte, bool, bool) (blscrypto.SerializedSignature, error) {
+	return blscrypto.SerializedSignature{}, errReplayNoSigning
+}
+
+func (b *replayBackend) CheckSignature(data []byte, address common.Address, sig []byte) error {
+	signer, err := istanbul.GetSignatureAddress(data, sig)
+	if err != nil {
+		return err
+	}
+	if signer != address {
+		return istanbul.ErrInvalidSigner
+	}
+	return nil
+}
+
+// validatorSet returns the validator set archived for the sequence.
+func (b *replayBackend) validatorSet(sequence uint64) istanbul.ValidatorSet {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if valSet, ok := b.valSets[sequence]; ok {
+		return valSet.Copy()
+	}
+	valSet := validator.NewSet(nil)
+	if archived, err := b.archive.sequence(sequence); err == nil && archived != nil {
+		if deserialized, err := validator.DeserializeValidatorSet(archived.ValidatorSet); err == nil {
+			valSet = deserialized
+		}
+	}
+	b.valSets[sequence] = valSet
+	return valSet.Copy()
+}
+
+func (b *replayBackend) Validators(proposal istanbul.Proposal) istanbul.ValidatorSet {
+	return b.validatorSet(proposal.Number().Uint64() + 1)
+}
+
+func (b *replayBackend) ParentBlockValidators(proposal istanbul.Proposal) istanbul.ValidatorSet {
+	return b.validatorSet(proposal.Number().Uint64())
+}
+
+func (b *replayBackend) NextBlockValidators(proposal istanbul.Proposal) (istanbul.ValidatorSet, error) {
+	istExtra, err := proposal.Header().IstanbulExtra()
+	if err != nil {
+		return nil, err
+	}
+	valSet := b.ParentBlockValidators(proposal)
+	if len(istExtra.AddedValidators) == 0 && istExtra.RemovedValidators.BitLen() == 0 {
+		return valSet, nil
+	}
+	addedValidators, err := istanbul.CombineIstanbulExtraToValidatorData(istExtra.AddedValidators, istExtra.AddedValidatorsPublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	if !valSet.RemoveValidators(istExtra.RemovedValidators) || !valSet.AddValidators(addedValidators) {
+		return nil, errors.New("could not obtain next block validators")
+	}
+	return valSet, nil
+}
+
+func (b *replayBackend) Commit(proposal istanbul.Proposal, aggregatedSeal types.IstanbulAggregatedSeal, aggregatedEpochValidatorSetSeal types.IstanbulEpochValidatorSetSeal, result *StateProcessResult) error {
+	block, ok := proposal.(*types.Block)
+	if !ok {
+		return errInvalidProposal
+	}
+	b.mu.Lock()
+	b.head = block
+	hash := block.Hash()
+	b.committed = &hash
+	b.mu.Unlock()
+
+	go b.mux.Post(istanbul.FinalCommittedEvent{})
+	return nil
+}
+
+// takeCommitted returns the hash of the proposal committed since it was last
+// called, if any.
+func (b *replayBackend) takeCommitted() *common.Hash {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	committed := b.committed
+	b.committed = nil
+	return committed
+}
+
+func (b *replayBackend) Verify(istanbul.Proposal) (*StateProcessResult, time.Duration, error) {
+	return nil, 0, nil
+}
+
+func (b *replayBackend) GetCurrentHeadBlock() istanbul.Proposal {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.head
+}
+
+func (b *replayBackend) GetCurrentHeadBlockAndAuthor() (istanbul.Proposal, common.Address) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.head.NumberU64() == 0 {
+		return b.head, common.ZeroAddress
+	}
+	return b.head, b.head.Coinbase()
+}
+
+func (b *replayBackend) LastSubject() (istanbul.Subject, error) {
+	lastProposal := b.GetCurrentHeadBlock()
+	istExtra, err := lastProposal.Header().IstanbulExtra()
+	if err != nil {
+		return istanbul.Subject{}, err
+	}
+	lastView := &istanbul.View{Sequence: lastProposal.Number(), Round: istExtra.AggregatedSeal.Round}
+	return istanbul.Subject{View: lastView, Digest: lastProposal.Hash()}, nil
+}
+
+// header returns the header of the block number, the head or a canonical one.
+func (b *replayBackend) header(number uint64) *types.Header {
+	b.mu.Lock()
+	head := b.head
+	b.mu.Unlock()
+	if head.NumberU64() == number {
+		return head.Header()
+	}
+	return rawdb.ReadHeader(b.db, rawdb.ReadCanonicalHash(b.db, number), number)
+}
+
+func (b *replayBackend) HasBlock(hash common.Hash, number *big.Int) bool {
+	if header := b.header(number.Uint64()); header != nil && header.Hash() == hash {
+		return true
+	}
+	return rawdb.HasHeader(b.db, hash, number.Uint64())
+}
+
+func (b *replayBackend) AuthorForBlock(number uint64) common.Address {
+	if header := b.header(number); header != nil {
+		return header.Coinbase
+	}
+	return common.ZeroAddress
+}
+
+func (b *replayBackend) HashForBlock(number uint64) common.Hash {
+	if header := b.header(number); header != nil {
+		return header.Hash()
+	}
+	return common.Hash{}
+}
@@ -27,8 +27,13 @@ import (
 
 func (c *core) sendPrepare() {
 	logger := c.newLogger("func", "sendPrepare")
+	sub := c.current.Subject()
+	if err := c.protectSigning(istanbul.MsgPrepare, sub.View, sub.Digest); err != nil {
+		logger.Error("Refusing to send prepare", "err", err)
+		return
+	}
 	logger.Debug("Sending prepare")
-	c.broadcast(istanbul.NewPrepareMessage(c.current.Subject(), c.address))
+	c.broadcast(istanbul.NewPrepareMessage(sub, c.address))
 }
 
 func (c *core) verifySignedPrepareOrCommitMessage(message istanbul.Message, seen map[common.Address]bool) (*common.Address, error) {
@@ -195,11 +200,15 @@ func (c *core) handlePrepare(msg *istanbul.Message) error {
 			logger.Error("Failed to create and set preprared certificate", "err", err)
 			return err
 		}
+		pc := c.current.PreparedCertificate()
+		c.health.prepared(c.current.View(), pc.Summary())
 		logger.Trace("Got quorum prepares or commits", "tag", "stateTransition")
 		// Update metrics.
 		if !c.consensusTimestamp.IsZero() {
 			c.consensusPrepareTimeGauge.Update(time.Since(c.consensusTimestamp).Nanoseconds())
+			c.phasePrepareTimer.UpdateSince(c.consensusTimestamp)
 		}
+		c.preparedTimestamp = time.Now()
 
 		// Process Backlog Messages
 		c.backlog.updateState(c.current.View(), c.current.State())
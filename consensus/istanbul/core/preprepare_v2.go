@@ -14,8 +14,13 @@ func (c *core) sendPreprepareV2(request *istanbul.Request, roundChangeCertificat
 
 	// If I'm the proposer and I have the same sequence with the proposal
 	if c.current.Sequence().Cmp(request.Proposal.Number()) == 0 && c.isProposer() {
+		view := c.current.View()
+		if err := c.protectSigning(istanbul.MsgPreprepareV2, view, request.Proposal.Hash()); err != nil {
+			logger.Error("Refusing to send preprepareV2", "err", err)
+			return
+		}
 		m := istanbul.NewPreprepareV2Message(&istanbul.PreprepareV2{
-			View:                     c.current.View(),
+			View:                     view,
 			Proposal:                 request.Proposal,
 			RoundChangeCertificateV2: roundChangeCertificateV2,
 		}, c.address)
@@ -34,14 +39,19 @@ func (c *core) ResendPreprepare() error {
 	if st != StatePreprepared && st != StatePrepared && st != StateCommitted {
 		return errors.New("Cant resend preprepare if not in preprepared, prepared, or committed state")
 	}
-	m := istanbul.NewPreprepareV2Message(c.current.PreprepareV2(), c.address)
+	preprepare := c.current.PreprepareV2()
+	if err := c.protectSigning(istanbul.MsgPreprepareV2, preprepare.View, preprepare.Proposal.Hash()); err != nil {
+		return err
+	}
+	m := istanbul.NewPreprepareV2Message(preprepare, c.address)
 	logger.Debug("Re-Sending preprepare v2", "m", m)
 	c.broadcast(m)
 	return nil
 }
 
 func (c *core) handlePreprepareV2(msg *istanbul.Message) error {
-	defer c.handlePrePrepareTimer.UpdateSince(time.Now())
+	received := time.Now()
+	defer c.handlePrePrepareTimer.UpdateSince(received)
 
 	logger := c.newLogger("func", "handlePreprepareV2", "tag", "handleMsg", "from", msg.Address)
 	logger.Trace("Got preprepareV2 message", "m", msg)
@@ -124,6 +134,7 @@ func (c *core) handlePreprepareV2(msg *istanbul.Message) error {
 	if c.current.State() == StateAcceptRequest {
 		logger.Trace("Accepted preprepare v2", "tag", "stateTransition")
 		c.consensusTimestamp = time.Now()
+		c.phaseVerifyTimer.Update(c.consensusTimestamp.Sub(received))
 
 		err := c.current.TransitionToPrepreparedV2(preprepareV2)
 		if err != nil {
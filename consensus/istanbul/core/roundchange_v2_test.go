@@ -732,3 +732,27 @@ loop2:
 	}
 	close(sys.quit)
 }
+
+func TestHandleForceRoundChanges(t *testing.T) {
+	sys := NewTestSystemWithBackend(4, 1)
+	b := sys.backends[0]
+	b.engine.Start()
+	defer b.engine.Stop()
+	c := b.engine.(*core)
+
+	view := &istanbul.View{Sequence: c.current.Sequence(), Round: c.current.DesiredRound()}
+	if err := c.handleForceRoundChanges(view, 3); err != nil {
+		t.Fatalf("handleForceRoundChanges: %v", err)
+	}
+	if c.current.DesiredRound().Cmp(big.NewInt(3)) != 0 {
+		t.Errorf("desired round mismatch: have %v, want 3", c.current.DesiredRound())
+	}
+
+	// A stale view must not move the desired round again
+	if err := c.handleForceRoundChanges(view, 3); err != nil {
+		t.Fatalf("handleForceRoundChanges: %v", err)
+	}
+	if c.current.DesiredRound().Cmp(big.NewInt(3)) != 0 {
+		t.Errorf("desired round mismatch after stale view: have %v, want 3", c.current.DesiredRound())
+	}
+}
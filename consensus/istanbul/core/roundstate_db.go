@@ -19,6 +19,7 @@ package core
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"io"
 	"math/big"
 	"os"
@@ -27,6 +28,7 @@ import (
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/common/task"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/crypto/atrest"
 	"github.com/celo-org/celo-blockchain/ethdb/leveldb"
 	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/metrics"
@@ -43,8 +45,11 @@ const (
 	lastViewKey  = "lastView" // Last View that we know of
 	rsKey        = "rs"       // Database Key Pefix for RoundState
 	rcvdKey      = "rcvd"     // Database Key Prefix for rcvd messages from the RoundState (split saving)
+	keyCheckKey  = "keyCheck" // Value sealed with the encryption key, if the database is encrypted
 )
 
+var errRoundStateDBEncrypted = errors.New("round state db is encrypted, no key given")
+
 type RoundStateDB interface {
 	GetLastView() (*istanbul.View, error)
 	// GetOldestValidView returns the oldest valid view that can be stored on the db
@@ -61,6 +66,7 @@ type RoundStateDBOptions struct {
 	withGarbageCollector   bool
 	garbageCollectorPeriod time.Duration
 	sequencesToSave        uint64
+	key                    *atrest.Key // Encrypts the round states and rcvd messages if set
 }
 
 type roundStateDBImpl struct {
@@ -123,6 +129,11 @@ func newRoundStateDB(path string, opts *RoundStateDBOptions) (RoundStateDB, erro
 		opts:   coerceOptions(opts),
 		logger: logger,
 	}
+	if err := rsdb.checkKey(); err != nil {
+		logger.Error("Failed to open roundstate db", "err", err)
+		db.Close()
+		return nil, err
+	}
 
 	rsdb.rsRLPMeter = metrics.NewRegisteredMeter(namespace+"rs/rlp/encoding/size", nil)
 	rsdb.rsRLPEncTimer = metrics.NewRegisteredTimer(namespace+"rs/rlp/encoding/duration", nil)
@@ -186,6 +197,47 @@ func newPersistentDB(path string, namespace string) (*leveldb.Database, error) {
 	return db, nil
 }
 
+// checkKey verifies that the database is encrypted with the key of the options,
+// if any. The entries of a database that wasn't encrypted are dropped when a key
+// is first given, but the key of an encrypted one can't be dropped.
+func (rsdb *roundStateDBImpl) checkKey() error {
+	key := rsdb.opts.key
+	blob, err := rsdb.db.Get([]byte(keyCheckKey))
+	switch {
+	case err == goleveldb.ErrNotFound && key == nil:
+		return nil
+	case err == goleveldb.ErrNotFound:
+		rsdb.logger.Info("Encrypting roundstate db, dropping the unencrypted entries")
+		if err := rsdb.dropEntries(); err != nil {
+			return err
+		}
+		return rsdb.db.Put([]byte(keyCheckKey), key.Seal([]byte(keyCheckKey), []byte(keyCheckKey)))
+	case err != nil:
+		return err
+	case key == nil:
+		return errRoundStateDBEncrypted
+	}
+	_, err = key.Open(blob, []byte(keyCheckKey))
+	return err
+}
+
+// dropEntries deletes all the entries but the version.
+func (rsdb *roundStateDBImpl) dropEntries() error {
+	iter := rsdb.db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	batch := rsdb.db.NewBatch()
+	for iter.Next() {
+		if string(iter.Key()) != dbVersionKey {
+			batch.Delete(common.CopyBytes(iter.Key()))
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
 type rcvd struct {
 	Prepares      MessageSet
 	Commits       MessageSet
@@ -293,7 +345,7 @@ func (rsdb *roundStateDBImpl) UpdateLastRcvd(rs RoundState) error {
 
 	before = time.Now()
 	batch := rsdb.db.NewBatch()
-	batch.Put(rcvdViewKey, entryBytes)
+	batch.Put(rcvdViewKey, rsdb.opts.key.Seal(entryBytes, rcvdViewKey))
 	err = batch.Write()
 	if err != nil {
 		logger.Error("Failed to save rcvd messages from roundState", "reason", "levelDB write", "err", err, "func")
@@ -325,7 +377,7 @@ func (rsdb *roundStateDBImpl) UpdateLastRoundState(rs RoundState) error {
 	before = time.Now()
 	batch := rsdb.db.NewBatch()
 	batch.Put([]byte(lastViewKey), viewKey)
-	batch.Put(viewKey, entryBytes)
+	batch.Put(viewKey, rsdb.opts.key.Seal(entryBytes, viewKey))
 
 	err = batch.Write()
 	rsdb.rsDbSaveTimer.UpdateSince(before)
@@ -369,6 +421,9 @@ func (rsdb *roundStateDBImpl) GetRoundStateFor(view *istanbul.View) (RoundState,
 	if err != nil {
 		return nil, err
 	}
+	if rawEntry, err = rsdb.opts.key.Open(rawEntry, viewKey); err != nil {
+		return nil, err
+	}
 
 	var entry roundStateImpl
 	if err = rlp.DecodeBytes(rawEntry, &entry); err != nil {
@@ -385,6 +440,9 @@ func (rsdb *roundStateDBImpl) GetRoundStateFor(view *istanbul.View) (RoundState,
 	if err != nil {
 		return nil, err
 	}
+	if rawRcvd, err = rsdb.opts.key.Open(rawRcvd, rcvdViewKey); err != nil {
+		return nil, err
+	}
 	var r *rcvdRLP = &rcvdRLP{}
 	if err = rlp.DecodeBytes(rawRcvd, &r); err != nil {
 		return nil, err
@@ -6,9 +6,11 @@ import (
 	"math/rand"
 	"testing"
 
+	"github.com/celo-org/celo-blockchain/crypto/atrest"
 	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
 	"github.com/celo-org/celo-blockchain/rlp"
 	"github.com/stretchr/testify/assert"
+	goleveldb "github.com/syndtr/goleveldb/leveldb"
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
@@ -294,3 +296,43 @@ func TestRSDBGetOldestValidView(t *testing.T) {
 	runTestCase("When StoredSequence > sequencesToSave", newView(sequencesToSave+1, 90), newView(1, 0))
 	runTestCase("When StoredSequence >> sequencesToSave", newView(sequencesToSave+1000, 90), newView(1000, 0))
 }
+
+func TestRSDBEncryption(t *testing.T) {
+	valSet := validator.NewSet([]istanbul.ValidatorData{
+		{Address: common.BytesToAddress([]byte(string(rune(2)))), BLSPublicKey: blscrypto.SerializedPublicKey{1, 2, 3}},
+	})
+	key, _ := atrest.NewKey(make([]byte, atrest.KeyLength))
+	path := t.TempDir()
+
+	// The unencrypted entries are dropped when a key is first given
+	rsdb, err := newRoundStateDB(path, &RoundStateDBOptions{withGarbageCollector: false})
+	finishOnError(t, err)
+	finishOnError(t, rsdb.UpdateLastRoundState(newRoundState(newView(1, 0), valSet, valSet.GetByIndex(0))))
+	finishOnError(t, rsdb.Close())
+
+	rsdb, err = newRoundStateDB(path, &RoundStateDBOptions{withGarbageCollector: false, key: key})
+	finishOnError(t, err)
+	if _, err := rsdb.GetLastView(); err != goleveldb.ErrNotFound {
+		t.Fatalf("unencrypted entries kept: %v", err)
+	}
+	rs := newRoundState(newView(2, 0), valSet, valSet.GetByIndex(0))
+	finishOnError(t, rsdb.UpdateLastRoundState(rs))
+	finishOnError(t, rsdb.UpdateLastRcvd(rs))
+	finishOnError(t, rsdb.Close())
+
+	// The encrypted db can't be opened without its key
+	if _, err := newRoundStateDB(path, &RoundStateDBOptions{withGarbageCollector: false}); err != errRoundStateDBEncrypted {
+		t.Fatalf("opening without key error mismatch: have %v, want %v", err, errRoundStateDBEncrypted)
+	}
+	other, _ := atrest.NewKey(bytes.Repeat([]byte{1}, atrest.KeyLength))
+	if _, err := newRoundStateDB(path, &RoundStateDBOptions{withGarbageCollector: false, key: other}); err != atrest.ErrDecrypt {
+		t.Fatalf("opening with another key error mismatch: have %v, want %v", err, atrest.ErrDecrypt)
+	}
+	rsdb, err = newRoundStateDB(path, &RoundStateDBOptions{withGarbageCollector: false, key: key})
+	finishOnError(t, err)
+	defer rsdb.Close()
+
+	savedRs, err := rsdb.GetRoundStateFor(rs.View())
+	finishOnError(t, err)
+	assertEqualRoundState(t, savedRs, rs)
+}
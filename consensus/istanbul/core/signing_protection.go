@@ -0,0 +1,171 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+// SignedViewsVersion is the version of the signed views interchange format.
+const SignedViewsVersion = 1
+
+// protectedMessages are the names of the messages protected from double signing
+// in the signed views interchange format, by code.
+var protectedMessages = map[uint64]string{
+	istanbul.MsgPreprepareV2: "preprepare",
+	istanbul.MsgPrepare:      "prepare",
+	istanbul.MsgCommit:       "commit",
+}
+
+// signingProtection refuses to sign a PREPREPARE, PREPARE or COMMIT conflicting
+// with one the validator signed before, even before a restart or on another
+// machine the signed views were imported from: one of a lower view, or one of
+// the same view with another digest.
+type signingProtection struct {
+	db ethdb.KeyValueStore
+	mu sync.Mutex
+
+	refusedMeter metrics.Meter
+}
+
+func newSigningProtection(db ethdb.KeyValueStore) *signingProtection {
+	return &signingProtection{
+		db:           db,
+		refusedMeter: metrics.NewRegisteredMeter("consensus/istanbul/core/signing_protection/refused", nil),
+	}
+}
+
+// allow persists the view and digest of a message the signer is about to sign,
+// or returns why it mustn't be signed. Signing the same message again is allowed.
+func (p *signingProtection) allow(signer common.Address, code uint64, view *istanbul.View, digest common.Hash) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if last := rawdb.ReadSignedView(p.db, signer, code); last != nil {
+		lastView := &istanbul.View{Sequence: last.Sequence, Round: last.Round}
+		switch cmp := view.Cmp(lastView); {
+		case cmp < 0:
+			p.refusedMeter.Mark(1)
+			return fmt.Errorf("%w: %v signed at %v", errSignedHigherView, protectedMessages[code], lastView)
+		case cmp == 0 && digest != last.Digest:
+			p.refusedMeter.Mark(1)
+			return fmt.Errorf("%w: %v signed for %v at %v", errSignedOtherDigest, protectedMessages[code], last.Digest.Hex(), lastView)
+		case cmp == 0:
+			return nil
+		}
+	}
+	rawdb.WriteSignedView(p.db, &rawdb.SignedView{
+		Signer:   signer,
+		Code:     code,
+		Sequence: view.Sequence,
+		Round:    view.Round,
+		Digest:   digest,
+	})
+	return nil
+}
+
+// protectSigning checks with the signing protection that the validator may sign
+// a message of the view and digest.
+func (c *core) protectSigning(code uint64, view *istanbul.View, digest common.Hash) error {
+	return c.signingProtection.allow(c.address, code, view, digest)
+}
+
+// SignedViews is the interchange format of the views signed by validators, to
+// carry their signing protection when migrating them between machines.
+type SignedViews struct {
+	Version uint64        `json:"version"`
+	Views   []*SignedView `json:"signedViews"`
+}
+
+// SignedView is the highest view a validator signed a message of a kind in.
+type SignedView struct {
+	Signer   common.Address `json:"signer"`
+	Message  string         `json:"message"` // One of preprepare, prepare and commit
+	Sequence uint64         `json:"sequence"`
+	Round    uint64         `json:"round"`
+	Digest   common.Hash    `json:"digest"`
+}
+
+// ExportSignedViews returns the views signed by validators persisted in db.
+func ExportSignedViews(db ethdb.Iteratee) *SignedViews {
+	export := &SignedViews{Version: SignedViewsVersion, Views: []*SignedView{}}
+	for _, view := range rawdb.ReadAllSignedViews(db) {
+		message, ok := protectedMessages[view.Code]
+		if !ok {
+			continue
+		}
+		export.Views = append(export.Views, &SignedView{
+			Signer:   view.Signer,
+			Message:  message,
+			Sequence: view.Sequence.Uint64(),
+			Round:    view.Round.Uint64(),
+			Digest:   view.Digest,
+		})
+	}
+	return export
+}
+
+// ImportSignedViews merges views signed by validators into the ones persisted in
+// db, keeping the highest one of every validator and message. Different digests
+// signed in the same view are merged into an empty one, refusing any further
+// signature of the message in the view. It returns the number of views updated.
+func ImportSignedViews(db ethdb.KeyValueStore, views *SignedViews) (int, error) {
+	if views.Version != SignedViewsVersion {
+		return 0, fmt.Errorf("unsupported signed views version %d, want %d", views.Version, SignedViewsVersion)
+	}
+	codes := make(map[string]uint64, len(protectedMessages))
+	for code, message := range protectedMessages {
+		codes[message] = code
+	}
+	for _, view := range views.Views {
+		if _, ok := codes[view.Message]; !ok {
+			return 0, fmt.Errorf("unknown message %q signed by %s", view.Message, view.Signer.Hex())
+		}
+	}
+	updated := 0
+	for _, view := range views.Views {
+		imported := &rawdb.SignedView{
+			Signer:   view.Signer,
+			Code:     codes[view.Message],
+			Sequence: new(big.Int).SetUint64(view.Sequence),
+			Round:    new(big.Int).SetUint64(view.Round),
+			Digest:   view.Digest,
+		}
+		if last := rawdb.ReadSignedView(db, imported.Signer, imported.Code); last != nil {
+			lastView := &istanbul.View{Sequence: last.Sequence, Round: last.Round}
+			switch cmp := (&istanbul.View{Sequence: imported.Sequence, Round: imported.Round}).Cmp(lastView); {
+			case cmp < 0:
+				continue
+			case cmp == 0 && (imported.Digest == last.Digest || last.Digest == common.Hash{}):
+				continue
+			case cmp == 0:
+				imported.Digest = common.Hash{}
+			}
+		}
+		rawdb.WriteSignedView(db, imported)
+		updated++
+	}
+	return updated, nil
+}
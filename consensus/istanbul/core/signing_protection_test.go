@@ -0,0 +1,118 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+)
+
+func testView(sequence, round int64) *istanbul.View {
+	return &istanbul.View{Sequence: big.NewInt(sequence), Round: big.NewInt(round)}
+}
+
+func TestSigningProtection(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		signer = common.HexToAddress("0x01")
+		p      = newSigningProtection(db)
+	)
+	if err := p.allow(signer, istanbul.MsgPrepare, testView(10, 1), common.Hash{1}); err != nil {
+		t.Fatalf("first prepare refused: %v", err)
+	}
+	// Signing the same message again is fine, another digest or a lower view isn't
+	if err := p.allow(signer, istanbul.MsgPrepare, testView(10, 1), common.Hash{1}); err != nil {
+		t.Fatalf("same prepare refused: %v", err)
+	}
+	if err := p.allow(signer, istanbul.MsgPrepare, testView(10, 1), common.Hash{2}); !errors.Is(err, errSignedOtherDigest) {
+		t.Fatalf("error mismatch: have %v, want %v", err, errSignedOtherDigest)
+	}
+	if err := p.allow(signer, istanbul.MsgPrepare, testView(10, 0), common.Hash{1}); !errors.Is(err, errSignedHigherView) {
+		t.Fatalf("error mismatch: have %v, want %v", err, errSignedHigherView)
+	}
+	if err := p.allow(signer, istanbul.MsgPrepare, testView(9, 5), common.Hash{1}); !errors.Is(err, errSignedHigherView) {
+		t.Fatalf("error mismatch: have %v, want %v", err, errSignedHigherView)
+	}
+	// Messages of other kinds and signers are protected separately
+	if err := p.allow(signer, istanbul.MsgCommit, testView(10, 0), common.Hash{2}); err != nil {
+		t.Fatalf("commit refused: %v", err)
+	}
+	if err := p.allow(common.HexToAddress("0x02"), istanbul.MsgPrepare, testView(1, 0), common.Hash{3}); err != nil {
+		t.Fatalf("prepare of another signer refused: %v", err)
+	}
+	// The protection survives a restart
+	p = newSigningProtection(db)
+	if err := p.allow(signer, istanbul.MsgPrepare, testView(10, 1), common.Hash{2}); !errors.Is(err, errSignedOtherDigest) {
+		t.Fatalf("error mismatch after restart: have %v, want %v", err, errSignedOtherDigest)
+	}
+	if err := p.allow(signer, istanbul.MsgPrepare, testView(10, 2), common.Hash{2}); err != nil {
+		t.Fatalf("prepare of a higher round refused: %v", err)
+	}
+}
+
+func TestSignedViewsInterchange(t *testing.T) {
+	var (
+		signer = common.HexToAddress("0x01")
+		oldDB  = rawdb.NewMemoryDatabase()
+		newDB  = rawdb.NewMemoryDatabase()
+		oldP   = newSigningProtection(oldDB)
+		newP   = newSigningProtection(newDB)
+	)
+	oldP.allow(signer, istanbul.MsgPreprepareV2, testView(20, 0), common.Hash{1})
+	oldP.allow(signer, istanbul.MsgPrepare, testView(20, 1), common.Hash{1})
+	oldP.allow(signer, istanbul.MsgCommit, testView(20, 1), common.Hash{1})
+
+	// The new machine signed a higher commit and a conflicting prepare
+	newP.allow(signer, istanbul.MsgPrepare, testView(20, 1), common.Hash{2})
+	newP.allow(signer, istanbul.MsgCommit, testView(21, 0), common.Hash{3})
+
+	export := ExportSignedViews(oldDB)
+	if len(export.Views) != 3 {
+		t.Fatalf("exported views mismatch: have %d, want 3", len(export.Views))
+	}
+	updated, err := ImportSignedViews(newDB, export)
+	if err != nil {
+		t.Fatalf("failed to import signed views: %v", err)
+	}
+	if updated != 2 {
+		t.Fatalf("updated views mismatch: have %d, want 2", updated)
+	}
+	if err := newP.allow(signer, istanbul.MsgPreprepareV2, testView(20, 0), common.Hash{4}); !errors.Is(err, errSignedOtherDigest) {
+		t.Fatalf("error mismatch: have %v, want %v", err, errSignedOtherDigest)
+	}
+	// Neither prepare may be signed again once merged
+	for _, digest := range []common.Hash{{1}, {2}} {
+		if err := newP.allow(signer, istanbul.MsgPrepare, testView(20, 1), digest); !errors.Is(err, errSignedOtherDigest) {
+			t.Fatalf("error mismatch for %x: have %v, want %v", digest, err, errSignedOtherDigest)
+		}
+	}
+	if view := rawdb.ReadSignedView(newDB, signer, istanbul.MsgCommit); view.Sequence.Uint64() != 21 {
+		t.Fatalf("higher commit overwritten: have sequence %d, want 21", view.Sequence)
+	}
+	// Importing again changes nothing
+	if updated, _ := ImportSignedViews(newDB, export); updated != 0 {
+		t.Fatalf("updated views mismatch on reimport: have %d, want 0", updated)
+	}
+	if _, err := ImportSignedViews(newDB, &SignedViews{Version: SignedViewsVersion + 1}); err == nil {
+		t.Fatalf("unsupported version imported")
+	}
+}
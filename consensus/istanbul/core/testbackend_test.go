@@ -264,6 +264,10 @@ func (self *testSystemBackend) ParentBlockValidators(proposal istanbul.Proposal)
 
 func (self *testSystemBackend) UpdateReplicaState(seq *big.Int) { /* pass */ }
 
+func (self *testSystemBackend) SigningProtectionDB() ethdb.KeyValueStore {
+	return self.db
+}
+
 func (self *testSystemBackend) finalizeAndReturnMessage(msg *istanbul.Message) (istanbul.Message, error) {
 	message := new(istanbul.Message)
 	data, err := self.engine.(*core).finalizeMessage(msg)
@@ -0,0 +1,108 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+const (
+	// adaptiveTimeoutMargin is the adapted base timeout as a multiple of the
+	// average round latency, leaving room for slower rounds than usual.
+	adaptiveTimeoutMargin = 3
+
+	// adaptiveTimeoutWeight is the weight of a new latency in the average.
+	adaptiveTimeoutWeight = 0.1
+)
+
+// The adapted base timeout never goes below half or above twice the configured
+// RequestTimeout: a validator whose timeouts diverge too much from the others
+// desynchronizes from their round changes.
+const (
+	adaptiveTimeoutMinDivisor = 2
+	adaptiveTimeoutMaxFactor  = 2
+)
+
+// RoundTimeoutsSummary reports the timeouts in effect, in milliseconds.
+type RoundTimeoutsSummary struct {
+	Adaptive                 bool   `json:"adaptive"`
+	ConfiguredRequestTimeout uint64 `json:"configuredRequestTimeout"`
+	RequestTimeout           uint64 `json:"requestTimeout"` // Base timeout of every round, adapted if enabled
+	AverageLatency           uint64 `json:"averageLatency"` // Average time of round 0 sequences past the block period
+	CurrentTimeout           uint64 `json:"currentTimeout"` // Timeout of the current round
+}
+
+// roundTimeouts tracks the latency of the sequences committed in round 0, from
+// the start of the sequence to the commit quorum less the block period, which is
+// what the base timeout has to cover for the round not to change.
+type roundTimeouts struct {
+	mu      sync.RWMutex
+	latency time.Duration // Average latency, zero until the first sample
+
+	latencyGauge     metrics.Gauge
+	requestTimeGauge metrics.Gauge
+}
+
+func newRoundTimeouts() *roundTimeouts {
+	return &roundTimeouts{
+		latencyGauge:     metrics.NewRegisteredGauge("consensus/istanbul/core/round_latency", nil),
+		requestTimeGauge: metrics.NewRegisteredGauge("consensus/istanbul/core/request_timeout", nil),
+	}
+}
+
+// observe adds the latency of a sequence committed in round 0 to the average.
+func (t *roundTimeouts) observe(latency time.Duration) {
+	if latency < 0 {
+		latency = 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.latency == 0 {
+		t.latency = latency
+	} else {
+		t.latency += time.Duration(adaptiveTimeoutWeight * float64(latency-t.latency))
+	}
+	t.latencyGauge.Update(t.latency.Milliseconds())
+}
+
+// averageLatency returns the average round 0 latency.
+func (t *roundTimeouts) averageLatency() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.latency
+}
+
+// baseTimeout returns the base timeout of the rounds, the configured one unless
+// adaptive, in which case it follows the average latency within bounds of it.
+func (t *roundTimeouts) baseTimeout(configured time.Duration, adaptive bool) time.Duration {
+	timeout := configured
+	if latency := t.averageLatency(); adaptive && latency > 0 {
+		timeout = adaptiveTimeoutMargin * latency
+		if min := configured / adaptiveTimeoutMinDivisor; timeout < min {
+			timeout = min
+		}
+		if max := configured * adaptiveTimeoutMaxFactor; timeout > max {
+			timeout = max
+		}
+	}
+	t.requestTimeGauge.Update(timeout.Milliseconds())
+	return timeout
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveBaseTimeout(t *testing.T) {
+	configured := 3 * time.Second
+	timeouts := newRoundTimeouts()
+
+	// Without samples, or when disabled, the configured timeout is used
+	if timeout := timeouts.baseTimeout(configured, true); timeout != configured {
+		t.Errorf("timeout without samples mismatch: have %v, want %v", timeout, configured)
+	}
+	timeouts.observe(800 * time.Millisecond)
+	if timeout := timeouts.baseTimeout(configured, false); timeout != configured {
+		t.Errorf("timeout when disabled mismatch: have %v, want %v", timeout, configured)
+	}
+	if timeout := timeouts.baseTimeout(configured, true); timeout != 2400*time.Millisecond {
+		t.Errorf("adapted timeout mismatch: have %v, want %v", timeout, 2400*time.Millisecond)
+	}
+	// New samples move the average by a tenth of their difference
+	timeouts.observe(1800 * time.Millisecond)
+	if latency := timeouts.averageLatency(); latency != 900*time.Millisecond {
+		t.Errorf("average latency mismatch: have %v, want %v", latency, 900*time.Millisecond)
+	}
+	// The adapted timeout stays within half and twice the configured one
+	fast, slow := newRoundTimeouts(), newRoundTimeouts()
+	fast.observe(10 * time.Millisecond)
+	slow.observe(10 * time.Second)
+	if timeout := fast.baseTimeout(configured, true); timeout != configured/2 {
+		t.Errorf("fast timeout mismatch: have %v, want %v", timeout, configured/2)
+	}
+	if timeout := slow.baseTimeout(configured, true); timeout != 2*configured {
+		t.Errorf("slow timeout mismatch: have %v, want %v", timeout, 2*configured)
+	}
+}
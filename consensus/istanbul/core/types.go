@@ -33,12 +33,19 @@ type Engine interface {
 	// a collection of the latest round change messages from all other
 	// validators.
 	CurrentRoundChangeSet() *RoundChangeSetSummary
+	// CurrentRoundTimeouts returns the round timeouts in effect.
+	CurrentRoundTimeouts() *RoundTimeoutsSummary
+	// ConsensusStatus returns the current view and the health of the consensus:
+	// the round changes, prepared certificates and commits.
+	ConsensusStatus() *ConsensusStatus
 
 	SetAddress(common.Address)
 	// Validator -> CommittedSeal from Parent Block
 	ParentCommits() MessageSet
 	// ForceRoundChange will force round change to the current desiredRound + 1
 	ForceRoundChange()
+	// ForceRoundChanges will force round change to the current desiredRound + rounds
+	ForceRoundChanges(rounds uint64)
 
 	// ResendPreprepare sends again the preprepare message.
 	ResendPreprepare() error
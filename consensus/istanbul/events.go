@@ -37,3 +37,9 @@ type MessageWithPeerIDEvent struct {
 // FinalCommittedEvent is posted when a proposal is committed
 type FinalCommittedEvent struct {
 }
+
+// GasPriceMinimumsEvent is posted when a gas price minimum announcement newer
+// than the previous ones has been verified
+type GasPriceMinimumsEvent struct {
+	Announcement *GasPriceMinimumAnnouncement
+}
@@ -24,6 +24,7 @@ import (
 const (
 	// Supported versions
 	Celo67 = 67 // incorporates changes from eth/66 (EIP-2481)
+	Celo68 = 68 // adds the gas price minimum announcements
 )
 
 // protocolName is the official short name of the protocol used during capability negotiation.
@@ -31,11 +32,12 @@ const ProtocolName = "istanbul"
 
 // ProtocolVersions are the supported versions of the istanbul protocol (first is primary).
 // (First is primary in the sense that it's the most current one supported)
-var ProtocolVersions = []uint{Celo67}
+var ProtocolVersions = []uint{Celo68, Celo67}
 
 // protocolLengths are the number of implemented message corresponding to different protocol versions.
 // celo/67, uses as the last message the 0x18, so it has 25 messages (including the 0x00)
-var ProtocolLengths = map[uint]uint64{Celo67: 25}
+// celo/68, uses as the last message the 0x19, so it has 26 messages (including the 0x00)
+var ProtocolLengths = map[uint]uint64{Celo67: 25, Celo68: 26}
 
 // Message codes for istanbul related messages
 // If you want to add a code, you need to increment the protocolLengths Array size
@@ -49,8 +51,10 @@ const (
 	VersionCertificatesMsg = 0x16
 	EnodeCertificateMsg    = 0x17
 	ValidatorHandshakeMsg  = 0x18
+	// Introduced in celo/68
+	GasPriceMinimumMsg = 0x19
 )
 
 func IsIstanbulMsg(msg p2p.Msg) bool {
-	return msg.Code >= ConsensusMsg && msg.Code <= ValidatorHandshakeMsg
+	return msg.Code >= ConsensusMsg && msg.Code <= GasPriceMinimumMsg
 }
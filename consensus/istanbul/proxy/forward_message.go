@@ -20,6 +20,7 @@ import (
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/consensus"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/p2p/enode"
 )
 
 func (pv *proxiedValidatorEngine) sendForwardMsg(ps *proxySet, destAddresses []common.Address, ethMsgCode uint64, payload []byte) error {
@@ -27,14 +28,31 @@ func (pv *proxiedValidatorEngine) sendForwardMsg(ps *proxySet, destAddresses []c
 
 	logger.Info("Sending forward msg", "ethMsgCode", ethMsgCode, "destAddresses", common.ConvertToStringSlice(destAddresses))
 
+	// Announce messages to validators are only sent to the proxies they're assigned to
+	var (
+		shards     map[enode.ID][]common.Address
+		unassigned []common.Address
+	)
+	sharded := destAddresses != nil && shardedFwdMsgCodes[ethMsgCode]
+	if sharded {
+		shards, unassigned = ps.shardDestAddresses(destAddresses)
+	}
+
 	// Send the forward messages to the proxies
 	for _, proxy := range ps.proxiesByID {
 		if proxy.IsPeered() {
+			proxyDestAddresses := destAddresses
+			if sharded {
+				proxyDestAddresses = append(append(make([]common.Address, 0, len(shards[proxy.ID()])+len(unassigned)), shards[proxy.ID()]...), unassigned...)
+				if len(proxyDestAddresses) == 0 {
+					continue
+				}
+			}
 
 			// Convert the message to a fwdMessage
 			msg := istanbul.NewForwardMessage(&istanbul.ForwardMessage{
 				Code:          ethMsgCode,
-				DestAddresses: destAddresses,
+				DestAddresses: proxyDestAddresses,
 				Msg:           payload,
 			}, pv.backend.Address())
 
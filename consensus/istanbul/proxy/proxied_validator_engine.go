@@ -113,6 +113,9 @@ type proxiedValidatorEngine struct {
 	sendFwdMsgsCh chan *fwdMsgInfo // Used to send a forward message to all of the proxies
 
 	newBlockchainEpoch chan struct{} // Used to notify to the thread that a new blockchain epoch has started
+
+	healthProbe   HealthProbe // Used to check the health of the peered proxies, if set
+	healthProbeMu sync.RWMutex
 }
 
 // proxiedValThreadOpFunc is a function type to define operations executed with run's local state as parameters.
@@ -372,6 +375,22 @@ func (pv *proxiedValidatorEngine) NewEpoch() error {
 	return nil
 }
 
+// SetHealthProbe will set the probe checking the health of the peered proxies
+// every ProxyHealthCheckPeriod seconds.
+func (pv *proxiedValidatorEngine) SetHealthProbe(probe HealthProbe) {
+	pv.healthProbeMu.Lock()
+	defer pv.healthProbeMu.Unlock()
+
+	pv.healthProbe = probe
+}
+
+func (pv *proxiedValidatorEngine) getHealthProbe() HealthProbe {
+	pv.healthProbeMu.RLock()
+	defer pv.healthProbeMu.RUnlock()
+
+	return pv.healthProbe
+}
+
 // run handles changes to proxies and validator assignments
 func (pv *proxiedValidatorEngine) threadRun() {
 	var (
@@ -393,6 +412,14 @@ func (pv *proxiedValidatorEngine) threadRun() {
 	schedulerTicker := time.NewTicker(schedulerPeriod)
 	defer schedulerTicker.Stop()
 
+	// The proxies are only health checked if enabled
+	var healthCheckCh <-chan time.Time
+	if pv.config.ProxyHealthCheckPeriod > 0 {
+		healthCheckTicker := time.NewTicker(time.Duration(pv.config.ProxyHealthCheckPeriod) * time.Second)
+		defer healthCheckTicker.Stop()
+		healthCheckCh = healthCheckTicker.C
+	}
+
 	pv.updateValidatorAssignments(ps)
 
 loop:
@@ -490,6 +517,17 @@ loop:
 		case fwdMsg := <-pv.sendFwdMsgsCh:
 			pv.sendForwardMsg(ps, fwdMsg.destAddresses, fwdMsg.ethMsgCode, fwdMsg.payload)
 
+		case <-healthCheckCh:
+			probe := pv.getHealthProbe()
+			if probe == nil {
+				continue
+			}
+			if valsReassigned := ps.checkProxiesHealth(probe, pv.config.ProxyHealthCheckFailures); valsReassigned {
+				logger.Info("Remote validator to proxy assignment has changed after health checks.  Sending val enode share messages and updating announce version")
+				pv.backend.UpdateAnnounceVersion()
+				pv.sendValEnodeShareMsgs(ps)
+			}
+
 		case <-schedulerTicker.C:
 			logger.Trace("schedulerTicker ticked")
 
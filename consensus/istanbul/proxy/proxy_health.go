@@ -0,0 +1,105 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package proxy
+
+import (
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/p2p/enode"
+)
+
+// HealthProbe checks the health of a peered proxy, returning why it's unhealthy
+// or nil if it's healthy.
+type HealthProbe func(proxy *Proxy) error
+
+// shardedFwdMsgCodes are the codes of the announce messages forwarded only to the
+// proxies their destination validators are assigned to, since the other proxies
+// aren't connected to them.
+var shardedFwdMsgCodes = map[uint64]bool{
+	istanbul.QueryEnodeMsg:          true,
+	istanbul.VersionCertificatesMsg: true,
+	istanbul.EnodeCertificateMsg:    true,
+}
+
+// checkProxiesHealth probes the peered proxies. The validators assigned to the
+// proxies failing maxFailures consecutive probes fail over to the healthy ones,
+// as long as one is left, and get assigned again to them once a probe passes.
+// Will return true if any of the validators got reassigned to a different proxy.
+func (ps *proxySet) checkProxiesHealth(probe HealthProbe, maxFailures uint64) bool {
+	logger := ps.logger.New("func", "checkProxiesHealth")
+	if maxFailures == 0 {
+		maxFailures = 1
+	}
+
+	now := time.Now()
+	healthy := 0
+	for _, proxy := range ps.proxiesByID {
+		if !proxy.IsPeered() {
+			continue
+		}
+		proxy.healthCheckTS = now
+		if proxy.healthCheckErr = probe(proxy); proxy.healthCheckErr == nil {
+			proxy.failedHealthChecks = 0
+		} else {
+			proxy.failedHealthChecks++
+			logger.Debug("Proxy failed a health check", "proxy", proxy.String(), "failures", proxy.failedHealthChecks, "err", proxy.healthCheckErr)
+		}
+		if proxy.healthCheckErr == nil || (!proxy.unhealthy && proxy.failedHealthChecks < maxFailures) {
+			healthy++
+		}
+	}
+
+	valsReassigned := false
+	for _, proxy := range ps.proxiesByID {
+		if !proxy.IsPeered() {
+			continue
+		}
+		if proxy.unhealthy && proxy.healthCheckErr == nil {
+			logger.Info("Proxy recovered, assigning validators to it", "proxy", proxy.String())
+			proxy.unhealthy = false
+			valsReassigned = ps.valAssigner.assignProxy(proxy, ps.valAssignments) || valsReassigned
+		} else if !proxy.unhealthy && proxy.failedHealthChecks >= maxFailures {
+			if healthy == 0 {
+				logger.Warn("No healthy proxy to fail over to", "proxy", proxy.String(), "err", proxy.healthCheckErr)
+				continue
+			}
+			logger.Warn("Proxy is unhealthy, failing its validators over to the other proxies", "proxy", proxy.String(), "err", proxy.healthCheckErr)
+			proxy.unhealthy = true
+			valsReassigned = ps.valAssigner.removeProxy(proxy, ps.valAssignments) || valsReassigned
+		}
+	}
+
+	return valsReassigned
+}
+
+// shardDestAddresses returns the destination validators assigned to every proxy,
+// and the unassigned ones every proxy should be sent to.
+func (ps *proxySet) shardDestAddresses(destAddresses []common.Address) (map[enode.ID][]common.Address, []common.Address) {
+	assignments := ps.getValidatorAssignments(destAddresses, nil)
+	shards := make(map[enode.ID][]common.Address)
+	unassigned := make([]common.Address, 0)
+	for _, address := range destAddresses {
+		if proxy := assignments[address]; proxy != nil {
+			shards[proxy.ID()] = append(shards[proxy.ID()], address)
+		} else {
+			unassigned = append(unassigned, address)
+		}
+	}
+	return shards, unassigned
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus/consensustest"
+	"github.com/celo-org/celo-blockchain/p2p"
+	"github.com/celo-org/celo-blockchain/p2p/enode"
+)
+
+func TestProxyHealthChecks(t *testing.T) {
+	ps := newProxySet(newConsistentHashingPolicy())
+	proxy0Config, proxy1Config := createProxyConfig(0), createProxyConfig(1)
+	proxy0ID, proxy1ID := proxy0Config.InternalNode.ID(), proxy1Config.InternalNode.ID()
+	ps.addProxy(proxy0Config)
+	ps.addProxy(proxy1Config)
+	ps.setProxyPeer(proxy0ID, consensustest.NewMockPeer(proxy0Config.InternalNode, p2p.ProxyPurpose))
+	ps.setProxyPeer(proxy1ID, consensustest.NewMockPeer(proxy1Config.InternalNode, p2p.ProxyPurpose))
+
+	vals := make([]common.Address, 10)
+	for i := range vals {
+		vals[i] = common.BytesToAddress([]byte(fmt.Sprintf("validator%d", i)))
+	}
+	ps.addRemoteValidators(vals)
+
+	unhealthy := map[enode.ID]bool{}
+	probe := func(proxy *Proxy) error {
+		if unhealthy[proxy.ID()] {
+			return errors.New("unhealthy")
+		}
+		return nil
+	}
+	assigned := func(proxyID enode.ID) int {
+		return len(ps.getValidatorAssignments(nil, []enode.ID{proxyID}))
+	}
+	if assigned(proxy0ID) == 0 || assigned(proxy1ID) == 0 {
+		t.Fatalf("validators not sharded across the proxies: %d and %d", assigned(proxy0ID), assigned(proxy1ID))
+	}
+
+	// The validators only fail over after enough failed checks
+	unhealthy[proxy0ID] = true
+	if ps.checkProxiesHealth(probe, 2) {
+		t.Fatalf("validators reassigned after one failed check")
+	}
+	if !ps.checkProxiesHealth(probe, 2) {
+		t.Fatalf("validators not reassigned after two failed checks")
+	}
+	if proxy := ps.getProxy(proxy0ID); proxy.IsHealthy() || proxy.failedHealthChecks != 2 || proxy.healthCheckErr == nil {
+		t.Fatalf("unhealthy proxy state mismatch: healthy %v, failures %d, err %v", proxy.IsHealthy(), proxy.failedHealthChecks, proxy.healthCheckErr)
+	}
+	if assigned(proxy0ID) != 0 || assigned(proxy1ID) != len(vals) {
+		t.Fatalf("validators not failed over: %d and %d", assigned(proxy0ID), assigned(proxy1ID))
+	}
+
+	// The last healthy proxy keeps its validators
+	unhealthy[proxy1ID] = true
+	ps.checkProxiesHealth(probe, 2)
+	ps.checkProxiesHealth(probe, 2)
+	if !ps.getProxy(proxy1ID).IsHealthy() || assigned(proxy1ID) != len(vals) {
+		t.Fatalf("validators of the last proxy failed over")
+	}
+
+	// Recovered proxies get validators assigned again
+	unhealthy[proxy0ID] = false
+	if !ps.checkProxiesHealth(probe, 2) {
+		t.Fatalf("validators not reassigned after recovery")
+	}
+	if !ps.getProxy(proxy0ID).IsHealthy() || assigned(proxy0ID) == 0 {
+		t.Fatalf("recovered proxy has no validators assigned")
+	}
+}
+
+func TestShardDestAddresses(t *testing.T) {
+	ps := newProxySet(newConsistentHashingPolicy())
+	proxy0Config, proxy1Config := createProxyConfig(0), createProxyConfig(1)
+	ps.addProxy(proxy0Config)
+	ps.addProxy(proxy1Config)
+	ps.setProxyPeer(proxy0Config.InternalNode.ID(), consensustest.NewMockPeer(proxy0Config.InternalNode, p2p.ProxyPurpose))
+	ps.setProxyPeer(proxy1Config.InternalNode.ID(), consensustest.NewMockPeer(proxy1Config.InternalNode, p2p.ProxyPurpose))
+
+	vals := make([]common.Address, 10)
+	for i := range vals {
+		vals[i] = common.BytesToAddress([]byte(fmt.Sprintf("validator%d", i)))
+	}
+	ps.addRemoteValidators(vals)
+	unknown := common.BytesToAddress([]byte("unknown"))
+
+	shards, unassigned := ps.shardDestAddresses(append(vals, unknown))
+	if len(unassigned) != 1 || unassigned[0] != unknown {
+		t.Fatalf("unassigned destinations mismatch: have %v, want %v", unassigned, []common.Address{unknown})
+	}
+	sharded := 0
+	for proxyID, addresses := range shards {
+		for _, address := range addresses {
+			if proxy := ps.getValidatorAssignments([]common.Address{address}, nil)[address]; proxy == nil || proxy.ID() != proxyID {
+				t.Errorf("destination %x sharded to the wrong proxy", address)
+			}
+		}
+		sharded += len(addresses)
+	}
+	if sharded != len(vals) {
+		t.Fatalf("sharded destinations mismatch: have %d, want %d", sharded, len(vals))
+	}
+}
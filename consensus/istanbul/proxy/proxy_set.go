@@ -97,6 +97,10 @@ func (ps *proxySet) setProxyPeer(proxyID enode.ID, peer consensus.Peer) bool {
 	valsReassigned := false
 	if proxy != nil {
 		proxy.peer = peer
+		// A new connection starts healthy
+		proxy.unhealthy = false
+		proxy.failedHealthChecks = 0
+		proxy.healthCheckErr = nil
 		logger.Trace("Assigning validators to proxy", "proxyID", proxyID)
 		valsReassigned = ps.valAssigner.assignProxy(proxy, ps.valAssignments)
 	}
@@ -124,6 +124,9 @@ type ProxiedValidatorEngine interface {
 
 	// NewEpoch will notify the proxied validator's thread that a new epoch started
 	NewEpoch() error
+
+	// SetHealthProbe will set the probe checking the health of the peered proxies
+	SetHealthProbe(probe HealthProbe)
 }
 
 // ==============================================
@@ -135,12 +138,21 @@ type Proxy struct {
 	externalNode *enode.Node    // Enode for the external network interface
 	peer         consensus.Peer // Connected proxy peer.  Is nil if this node is not connected to the proxy
 	disconnectTS time.Time      // Timestamp when this proxy's peer last disconnected. Initially set to the timestamp of when the proxy was added
+
+	unhealthy          bool      // Whether the validators assigned to this proxy failed over to the others
+	failedHealthChecks uint64    // Number of consecutive failed health checks
+	healthCheckTS      time.Time // Timestamp of the last health check, zero if never checked
+	healthCheckErr     error     // Why the last health check failed, nil if it passed
 }
 
 func (p *Proxy) ID() enode.ID {
 	return p.node.ID()
 }
 
+func (p *Proxy) Node() *enode.Node {
+	return p.node
+}
+
 func (p *Proxy) ExternalNode() *enode.Node {
 	return p.externalNode
 }
@@ -149,6 +161,12 @@ func (p *Proxy) IsPeered() bool {
 	return p.peer != nil
 }
 
+// IsHealthy returns whether the validators assigned to this proxy didn't fail
+// over to the others after failed health checks.
+func (p *Proxy) IsHealthy() bool {
+	return !p.unhealthy
+}
+
 func (p *Proxy) String() string {
 	return fmt.Sprintf("{internalNode: %v, externalNode %v, dcTimestamp: %v, ID: %v}", p.node, p.externalNode, p.disconnectTS, p.ID())
 }
@@ -160,16 +178,29 @@ type ProxyInfo struct {
 	IsPeered                 bool             `json:"isPeered"`
 	AssignedRemoteValidators []common.Address `json:"validators"`            // All validator addresses assigned to the proxy
 	DisconnectTS             int64            `json:"disconnectedTimestamp"` // Unix time of the last disconnect of the peer
+	IsHealthy                bool             `json:"isHealthy"`
+	FailedHealthChecks       uint64           `json:"failedHealthChecks"`   // Number of consecutive failed health checks
+	HealthCheckTS            int64            `json:"healthCheckTimestamp"` // Unix time of the last health check, 0 if never checked
+	HealthCheckError         string           `json:"healthCheckError"`     // Why the last health check failed, empty if it passed
 }
 
 func NewProxyInfo(p *Proxy, assignedVals []common.Address) *ProxyInfo {
-	return &ProxyInfo{
+	info := &ProxyInfo{
 		InternalNode:             p.node,
 		ExternalNode:             p.ExternalNode(),
 		IsPeered:                 p.IsPeered(),
 		DisconnectTS:             p.disconnectTS.Unix(),
 		AssignedRemoteValidators: assignedVals,
+		IsHealthy:                p.IsHealthy(),
+		FailedHealthChecks:       p.failedHealthChecks,
+	}
+	if !p.healthCheckTS.IsZero() {
+		info.HealthCheckTS = p.healthCheckTS.Unix()
+	}
+	if p.healthCheckErr != nil {
+		info.HealthCheckError = p.healthCheckErr.Error()
 	}
+	return info
 }
 
 // ==============================================
@@ -0,0 +1,61 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package remotesigner delegates the signatures of an Istanbul validator to a
+remote service holding its ECDSA and BLS keys, such as an HSM.
+
+Existing remote signers, such as clef, don't hold BLS keys, so the service speaks
+the JSON-RPC 2.0 protocol below, over HTTP, WebSocket or IPC. API implements it
+on top of any istanbul.Signer, e.g. to front the keystore of a separate node.
+
+All the methods are in the "signer" namespace. Addresses are hex encoded with
+the 0x prefix, byte strings are 0x prefixed hex (hexutil.Bytes), and errors are
+JSON-RPC errors. A request is expected to be answered within two seconds, since
+signing holds up the consensus rounds.
+
+	signer_publicKey(address) -> bytes
+
+The uncompressed secp256k1 public key of the account, 65 bytes. The client
+checks it is the key of the address.
+
+	signer_signHash(address, hash) -> bytes
+
+The 65 bytes [R || S || V] secp256k1 signature of a 32 bytes hash, V being 0 or
+1, as accounts.Wallet.SignHash. Used for the randomness commitments of the
+proposed blocks.
+
+	signer_signData(address, mimeType, data) -> bytes
+
+The signature of the keccak256 hash of data, as accounts.Wallet.SignData. Used
+for the consensus, announce and proxy messages, the enode certificates and the
+seals of the proposed blocks.
+
+	signer_signBLS(address, message, extraData, useComposite, cip22) -> bytes
+
+The 48 bytes serialized BLS12-377 signature of a message and its extra data with
+the BLS key of the account, as accounts.Wallet.SignBLS. useComposite selects
+the composite hasher and cip22 the CIP-22 hash to curve. Used for the committed
+seals and the epoch validator set seals.
+
+	signer_decrypt(address, ciphertext, s1, s2) -> bytes
+
+The plaintext of an ECIES ciphertext encrypted to the public key of the account,
+s1 and s2 being the shared information of the encryption, empty for the engine.
+The enode URLs of the validators are announced encrypted to the keys of the
+other validators, so a validator needs it to connect to the others.
+*/
+package remotesigner
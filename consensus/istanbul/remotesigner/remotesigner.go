@@ -0,0 +1,175 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotesigner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/accounts"
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/crypto"
+	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+// Namespace is the JSON-RPC namespace of the remote signing protocol.
+const Namespace = "signer"
+
+// requestTimeout bounds the signing requests, which hold up the consensus rounds.
+const requestTimeout = 2 * time.Second
+
+var errInvalidBLSSignature = errors.New("invalid BLS signature length")
+
+// Client signs with the keys held by a remote signing service.
+type Client struct {
+	client *rpc.Client
+}
+
+var _ istanbul.Signer = (*Client)(nil)
+
+// Dial connects to the remote signing service at endpoint, over HTTP, WebSocket
+// or IPC.
+func Dial(endpoint string) (*Client, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(client), nil
+}
+
+// NewClient creates a client of the remote signing service connected to by c.
+func NewClient(c *rpc.Client) *Client {
+	return &Client{client: c}
+}
+
+// Close disconnects from the remote signing service.
+func (c *Client) Close() {
+	c.client.Close()
+}
+
+func (c *Client) call(result interface{}, method string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return c.client.CallContext(ctx, result, Namespace+"_"+method, args...)
+}
+
+// GetPublicKey returns the ECDSA public key of the account.
+func (c *Client) GetPublicKey(account accounts.Account) (*ecdsa.PublicKey, error) {
+	var res hexutil.Bytes
+	if err := c.call(&res, "publicKey", account.Address); err != nil {
+		return nil, err
+	}
+	key, err := crypto.UnmarshalPubkey(res)
+	if err != nil {
+		return nil, err
+	}
+	if crypto.PubkeyToAddress(*key) != account.Address {
+		return nil, fmt.Errorf("public key of %x instead of %x", crypto.PubkeyToAddress(*key), account.Address)
+	}
+	return key, nil
+}
+
+// Decrypt decrypts an ECIES ciphertext with the ECDSA key of the account.
+func (c *Client) Decrypt(account accounts.Account, ct, s1, s2 []byte) ([]byte, error) {
+	var res hexutil.Bytes
+	if err := c.call(&res, "decrypt", account.Address, hexutil.Bytes(ct), hexutil.Bytes(s1), hexutil.Bytes(s2)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SignData signs the keccak256 hash of data with the ECDSA key of the account.
+func (c *Client) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	var res hexutil.Bytes
+	if err := c.call(&res, "signData", account.Address, mimeType, hexutil.Bytes(data)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SignHash signs a hash with the ECDSA key of the account.
+func (c *Client) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	var res hexutil.Bytes
+	if err := c.call(&res, "signHash", account.Address, hexutil.Bytes(hash)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SignBLS signs a message and extra data with the BLS key of the account.
+func (c *Client) SignBLS(account accounts.Account, msg []byte, extraData []byte, useComposite, cip22 bool) (blscrypto.SerializedSignature, error) {
+	var res hexutil.Bytes
+	if err := c.call(&res, "signBLS", account.Address, hexutil.Bytes(msg), hexutil.Bytes(extraData), useComposite, cip22); err != nil {
+		return blscrypto.SerializedSignature{}, err
+	}
+	if len(res) != blscrypto.SIGNATUREBYTES {
+		return blscrypto.SerializedSignature{}, errInvalidBLSSignature
+	}
+	var signature blscrypto.SerializedSignature
+	copy(signature[:], res)
+	return signature, nil
+}
+
+// API serves the remote signing protocol in Namespace, signing with the accounts
+// of a local signer, such as the keystore of a node fronting an HSM.
+type API struct {
+	signer istanbul.Signer
+}
+
+// NewAPI creates the remote signing API of signer.
+func NewAPI(signer istanbul.Signer) *API {
+	return &API{signer: signer}
+}
+
+// PublicKey returns the uncompressed ECDSA public key of an account.
+func (api *API) PublicKey(address common.Address) (hexutil.Bytes, error) {
+	key, err := api.signer.GetPublicKey(accounts.Account{Address: address})
+	if err != nil {
+		return nil, err
+	}
+	return crypto.FromECDSAPub(key), nil
+}
+
+// Decrypt decrypts an ECIES ciphertext with the ECDSA key of an account.
+func (api *API) Decrypt(address common.Address, ct, s1, s2 hexutil.Bytes) (hexutil.Bytes, error) {
+	return api.signer.Decrypt(accounts.Account{Address: address}, ct, s1, s2)
+}
+
+// SignData signs the keccak256 hash of data with the ECDSA key of an account.
+func (api *API) SignData(address common.Address, mimeType string, data hexutil.Bytes) (hexutil.Bytes, error) {
+	return api.signer.SignData(accounts.Account{Address: address}, mimeType, data)
+}
+
+// SignHash signs a hash with the ECDSA key of an account.
+func (api *API) SignHash(address common.Address, hash hexutil.Bytes) (hexutil.Bytes, error) {
+	return api.signer.SignHash(accounts.Account{Address: address}, hash)
+}
+
+// SignBLS signs a message and extra data with the BLS key of an account.
+func (api *API) SignBLS(address common.Address, msg, extraData hexutil.Bytes, useComposite, cip22 bool) (hexutil.Bytes, error) {
+	signature, err := api.signer.SignBLS(accounts.Account{Address: address}, msg, extraData, useComposite, cip22)
+	if err != nil {
+		return nil, err
+	}
+	return signature[:], nil
+}
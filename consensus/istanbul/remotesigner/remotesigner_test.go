@@ -0,0 +1,99 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotesigner
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/accounts"
+	"github.com/celo-org/celo-blockchain/accounts/keystore"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/crypto/ecies"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+func TestRemoteSigner(t *testing.T) {
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	wallet := ks.Wallets()[0]
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName(Namespace, NewAPI(wallet)); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := NewClient(rpc.DialInProc(server))
+	defer client.Close()
+
+	key, err := client.GetPublicKey(account)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	if crypto.PubkeyToAddress(*key) != account.Address {
+		t.Fatalf("public key mismatch: have %x, want %x", crypto.PubkeyToAddress(*key), account.Address)
+	}
+	if _, err := client.GetPublicKey(accounts.Account{}); err == nil {
+		t.Fatalf("unknown account served")
+	}
+	// Signatures match the local ones
+	data := []byte("istanbul message")
+	remote, err := client.SignData(account, accounts.MimetypeIstanbul, data)
+	if err != nil {
+		t.Fatalf("failed to sign data: %v", err)
+	}
+	local, _ := wallet.SignData(account, accounts.MimetypeIstanbul, data)
+	if !bytes.Equal(remote, local) {
+		t.Errorf("data signature mismatch: have %x, want %x", remote, local)
+	}
+	hash := crypto.Keccak256(data)
+	remote, err = client.SignHash(account, hash)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	local, _ = wallet.SignHash(account, hash)
+	if !bytes.Equal(remote, local) {
+		t.Errorf("hash signature mismatch: have %x, want %x", remote, local)
+	}
+	remoteBLS, err := client.SignBLS(account, data, []byte("extra"), true, true)
+	if err != nil {
+		t.Fatalf("failed to sign with BLS: %v", err)
+	}
+	localBLS, _ := wallet.SignBLS(account, data, []byte("extra"), true, true)
+	if remoteBLS != localBLS {
+		t.Errorf("BLS signature mismatch: have %x, want %x", remoteBLS, localBLS)
+	}
+	// Ciphertexts to the validator are decrypted remotely
+	ct, err := ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(key), data, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	plain, err := client.Decrypt(account, ct, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	if !bytes.Equal(plain, data) {
+		t.Errorf("plaintext mismatch: have %q, want %q", plain, data)
+	}
+}
@@ -49,6 +49,19 @@ type BLSSignerFn func(accounts.Account, []byte, []byte, bool, bool) (blscrypto.S
 // backing account.
 type HashSignerFn func(accounts.Account, []byte) ([]byte, error)
 
+// Signer signs with the keys of a validator, held by the in-process keystore or
+// by a remote signing service such as an HSM. The ECDSA key signs the consensus
+// messages, the block seals and the randomness commitments, and decrypts the
+// announce messages. The BLS key signs the committed seals, aggregated into the
+// block and epoch seals.
+type Signer interface {
+	GetPublicKey(account accounts.Account) (*ecdsa.PublicKey, error)
+	Decrypt(account accounts.Account, c, s1, s2 []byte) ([]byte, error)
+	SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error)
+	SignHash(account accounts.Account, hash []byte) ([]byte, error)
+	SignBLS(account accounts.Account, msg []byte, extraData []byte, useComposite, cip22 bool) (blscrypto.SerializedSignature, error)
+}
+
 // Proposal supports retrieving height and serialized block to be used during Istanbul consensus.
 type Proposal interface {
 	// Number retrieves the sequence number of this proposal.
@@ -430,6 +443,7 @@ type Message struct {
 	enodeCertificate    *EnodeCertificate
 	versionCertificates []*VersionCertificate
 	valEnodeShareData   *ValEnodesShareData
+	gasPriceMinimums    *GasPriceMinimumAnnouncement
 }
 
 // setMessageBytes sets the Msg field of msg to the rlp serialised bytes of
@@ -498,6 +512,10 @@ func (m *Message) DecodeMessage() error {
 		var v *ValEnodesShareData
 		err = m.decode(&v)
 		m.valEnodeShareData = v
+	case GasPriceMinimumMsg:
+		var g *GasPriceMinimumAnnouncement
+		err = m.decode(&g)
+		m.gasPriceMinimums = g
 	default:
 		err = fmt.Errorf("unrecognised message code %d", m.Code)
 	}
@@ -608,6 +626,12 @@ func (m *Message) ValEnodesShareData() *ValEnodesShareData {
 	return m.valEnodeShareData
 }
 
+// GasPriceMinimums returns the gas price minimum announcement if this is a gas
+// price minimum message.
+func (m *Message) GasPriceMinimums() *GasPriceMinimumAnnouncement {
+	return m.gasPriceMinimums
+}
+
 func (m *Message) Copy() *Message {
 	return &Message{
 		Code:      m.Code,
@@ -897,6 +921,119 @@ func (vc *VersionCertificate) recoverAddressAndPubKey() error {
 	return nil
 }
 
+// ## GasPriceMinimumAnnouncement ######################################################################
+
+// NewGasPriceMinimumMessage constructs a Message instance with the given sender
+// and gas price minimum announcement. Both the announcement instance and its
+// serialized bytes are part of the returned Message.
+func NewGasPriceMinimumMessage(announcement *GasPriceMinimumAnnouncement, sender common.Address) *Message {
+	message := &Message{
+		Address:          sender,
+		Code:             GasPriceMinimumMsg,
+		gasPriceMinimums: announcement,
+	}
+	setMessageBytes(message, announcement)
+	return message
+}
+
+// GasPriceMinimumAnnouncement is a signed message from the proposer of a block
+// giving the gas price minimums in CELO and in the whitelisted fee currencies
+// at that block, for the light clients that can't compute them.
+type GasPriceMinimumAnnouncement struct {
+	Number           uint64
+	Hash             common.Hash
+	Currencies       []common.Address // The zero address stands for CELO
+	GasPriceMinimums []*big.Int
+	Signature        []byte
+	address          common.Address
+}
+
+// NewGasPriceMinimumAnnouncement constructs a GasPriceMinimumAnnouncement of
+// the block with the given number and hash, signed with the signingFn.
+func NewGasPriceMinimumAnnouncement(number uint64, hash common.Hash, currencies []common.Address, gasPriceMinimums []*big.Int, signingFn func([]byte) ([]byte, error)) (*GasPriceMinimumAnnouncement, error) {
+	if len(currencies) != len(gasPriceMinimums) {
+		return nil, errors.New("mismatched currencies and gas price minimums")
+	}
+	ga := &GasPriceMinimumAnnouncement{
+		Number:           number,
+		Hash:             hash,
+		Currencies:       currencies,
+		GasPriceMinimums: gasPriceMinimums,
+	}
+	payloadToSign, err := ga.signaturePayload()
+	if err != nil {
+		return nil, err
+	}
+	ga.Signature, err = signingFn(payloadToSign)
+	if err != nil {
+		return nil, err
+	}
+	if err := ga.recoverAddress(); err != nil {
+		return nil, err
+	}
+	return ga, nil
+}
+
+// Used as a salt when signing a GasPriceMinimumAnnouncement, see
+// versionCertificateSalt.
+var gasPriceMinimumAnnouncementSalt = []byte("gasPriceMinimumAnnouncement")
+
+func (ga *GasPriceMinimumAnnouncement) signaturePayload() ([]byte, error) {
+	return rlp.EncodeToBytes([]interface{}{gasPriceMinimumAnnouncementSalt, ga.Number, ga.Hash, ga.Currencies, ga.GasPriceMinimums})
+}
+
+// Address returns the address of the signer of the announcement.
+func (ga *GasPriceMinimumAnnouncement) Address() common.Address {
+	return ga.address
+}
+
+// GasPriceMinimum returns the announced gas price minimum of the currency, nil
+// standing for CELO, or nil if the currency isn't part of the announcement.
+func (ga *GasPriceMinimumAnnouncement) GasPriceMinimum(currency *common.Address) *big.Int {
+	var currencyAddress common.Address
+	if currency != nil {
+		currencyAddress = *currency
+	}
+	for i, addr := range ga.Currencies {
+		if addr == currencyAddress {
+			return new(big.Int).Set(ga.GasPriceMinimums[i])
+		}
+	}
+	return nil
+}
+
+func (ga *GasPriceMinimumAnnouncement) String() string {
+	return fmt.Sprintf("{Number: %d, Hash: %s, Address: %s, Currencies: %d}", ga.Number, ga.Hash.String(), ga.address.String(), len(ga.Currencies))
+}
+
+func (ga *GasPriceMinimumAnnouncement) DecodeRLP(s *rlp.Stream) error {
+	// Create separate type to avoid stack overflow when calling Decode
+	type decodable GasPriceMinimumAnnouncement
+	var d decodable
+	if err := s.Decode(&d); err != nil {
+		return err
+	}
+	*ga = GasPriceMinimumAnnouncement(d)
+
+	if len(ga.Currencies) != len(ga.GasPriceMinimums) {
+		return errors.New("mismatched currencies and gas price minimums")
+	}
+	return ga.recoverAddress()
+}
+
+func (ga *GasPriceMinimumAnnouncement) recoverAddress() error {
+	payloadToSign, err := ga.signaturePayload()
+	if err != nil {
+		return err
+	}
+	pubKey, err := crypto.SigToPub(crypto.Keccak256(payloadToSign), ga.Signature)
+	if err != nil {
+		return err
+	}
+	ga.address = crypto.PubkeyToAddress(*pubKey)
+	return nil
+}
+
 // ## SharedValidatorEnode ######################################################################
 
 // NewValEnodesShareMessage constructs a Message instance with the given sender
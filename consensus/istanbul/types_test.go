@@ -24,6 +24,7 @@ import (
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
 	"github.com/celo-org/celo-blockchain/rlp"
 	"golang.org/x/crypto/sha3"
 )
@@ -262,3 +263,43 @@ func TestForwardMessageRLPEncoding(t *testing.T) {
 		t.Fatalf("RLP Encode/Decode mismatch. Got %v, expected %v", result, original)
 	}
 }
+
+func TestGasPriceMinimumAnnouncementRLPEncoding(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signingFn := func(data []byte) ([]byte, error) { return crypto.Sign(crypto.Keccak256(data), key) }
+	cUSD := common.HexToAddress("0x765de816845861e75a25fca122bb6898b8b1282a")
+	original, err := NewGasPriceMinimumAnnouncement(42, common.HexToHash("0x2a"), []common.Address{common.ZeroAddress, cUSD}, []*big.Int{big.NewInt(5), big.NewInt(10)}, signingFn)
+	if err != nil {
+		t.Fatalf("Error %v", err)
+	}
+	if original.Address() != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("Signer mismatch. Got %v, expected %v", original.Address(), crypto.PubkeyToAddress(key.PublicKey))
+	}
+
+	rawVal, err := rlp.EncodeToBytes(original)
+	if err != nil {
+		t.Fatalf("Error %v", err)
+	}
+	var result *GasPriceMinimumAnnouncement
+	if err = rlp.DecodeBytes(rawVal, &result); err != nil {
+		t.Fatalf("Error %v", err)
+	}
+	if !reflect.DeepEqual(original, result) {
+		t.Fatalf("RLP Encode/Decode mismatch. Got %v, expected %v", result, original)
+	}
+	if gpm := result.GasPriceMinimum(&cUSD); gpm == nil || gpm.Int64() != 10 {
+		t.Fatalf("Gas price minimum mismatch. Got %v, expected 10", gpm)
+	}
+	if gpm := result.GasPriceMinimum(&common.Address{1}); gpm != nil {
+		t.Fatalf("Gas price minimum of an unknown currency. Got %v", gpm)
+	}
+
+	// Changing the announced values changes the signer
+	original.GasPriceMinimums[1] = big.NewInt(1)
+	if rawVal, err = rlp.EncodeToBytes(original); err != nil {
+		t.Fatalf("Error %v", err)
+	}
+	if err = rlp.DecodeBytes(rawVal, &result); err == nil && result.Address() == original.Address() {
+		t.Fatalf("Tampered announcement recovered to the signer %v", result.Address())
+	}
+}
@@ -17,11 +17,18 @@
 package validator
 
 import (
+	"encoding/binary"
 	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul/validator/random"
+	"github.com/celo-org/celo-blockchain/crypto"
 )
 
 func proposerIndex(valSet istanbul.ValidatorSet, proposer common.Address) uint64 {
@@ -74,17 +81,134 @@ func StickyProposer(valSet istanbul.ValidatorSet, proposer common.Address, round
 	return valSet.List()[idx%uint64(valSet.Size())]
 }
 
-// GetProposerSelector returns the ProposerSelector for the given Policy
-func GetProposerSelector(pp istanbul.ProposerPolicy) istanbul.ProposerSelector {
-	switch pp {
-	case istanbul.Sticky:
-		return StickyProposer
-	case istanbul.RoundRobin:
-		return RoundRobinProposer
-	case istanbul.ShuffledRoundRobin:
-		return ShuffledRoundRobinProposer
-	default:
+// WeightedRandomProposer returns a selector drawing the proposer at random, with
+// a probability proportional to the weight of the validator, 1 if not in weights.
+// The draw only depends on the randomness of the validator set and the round, so
+// the randomness must change with every block.
+func WeightedRandomProposer(weights map[common.Address]uint64) istanbul.ProposerSelector {
+	return func(valSet istanbul.ValidatorSet, proposer common.Address, round uint64) istanbul.Validator {
+		if valSet.Size() == 0 {
+			return nil
+		}
+		validators := valSet.List()
+		cumulative := make([]*big.Int, len(validators))
+		total := new(big.Int)
+		for i, val := range validators {
+			weight, ok := weights[val.Address()]
+			if !ok {
+				weight = 1
+			}
+			total.Add(total, new(big.Int).SetUint64(weight))
+			cumulative[i] = new(big.Int).Set(total)
+		}
+		if total.Sign() == 0 {
+			return RoundRobinProposer(valSet, proposer, round)
+		}
+		seed := valSet.GetRandomness()
+		var roundBytes [8]byte
+		binary.BigEndian.PutUint64(roundBytes[:], round)
+		draw := new(big.Int).SetBytes(crypto.Keccak256(seed[:], roundBytes[:]))
+		draw.Mod(draw, total)
+		for i, bound := range cumulative {
+			if draw.Cmp(bound) < 0 {
+				return validators[i]
+			}
+		}
+		return validators[len(validators)-1]
+	}
+}
+
+// ProposerSelectorFactory creates the proposer selector of a policy given the
+// config of the engine.
+type ProposerSelectorFactory func(config *istanbul.Config) istanbul.ProposerSelector
+
+type proposerPolicy struct {
+	name    string
+	factory ProposerSelectorFactory
+}
+
+var (
+	proposerPoliciesMu sync.RWMutex
+	proposerPolicies   = map[istanbul.ProposerPolicy]proposerPolicy{
+		istanbul.RoundRobin:         {"roundrobin", staticSelector(RoundRobinProposer)},
+		istanbul.Sticky:             {"sticky", staticSelector(StickyProposer)},
+		istanbul.ShuffledRoundRobin: {"shuffledroundrobin", staticSelector(ShuffledRoundRobinProposer)},
+		istanbul.WeightedRandom: {"weightedrandom", func(config *istanbul.Config) istanbul.ProposerSelector {
+			return WeightedRandomProposer(config.ProposerWeights)
+		}},
+	}
+)
+
+func staticSelector(selector istanbul.ProposerSelector) ProposerSelectorFactory {
+	return func(*istanbul.Config) istanbul.ProposerSelector { return selector }
+}
+
+// RegisterProposerPolicy registers the proposer selector of a custom policy, so
+// that private networks can select it in their genesis config or by name with
+// the node flag. It must be called before the engine is created.
+func RegisterProposerPolicy(policy istanbul.ProposerPolicy, name string, factory ProposerSelectorFactory) error {
+	proposerPoliciesMu.Lock()
+	defer proposerPoliciesMu.Unlock()
+
+	for pp, registered := range proposerPolicies {
+		if pp == policy || registered.name == name {
+			return fmt.Errorf("proposer selection policy %d (%s) already registered", pp, registered.name)
+		}
+	}
+	proposerPolicies[policy] = proposerPolicy{name: name, factory: factory}
+	return nil
+}
+
+// ParseProposerPolicy returns the proposer selection policy registered with the
+// name, or of the number.
+func ParseProposerPolicy(name string) (istanbul.ProposerPolicy, error) {
+	proposerPoliciesMu.RLock()
+	defer proposerPoliciesMu.RUnlock()
+
+	for pp, registered := range proposerPolicies {
+		if registered.name == strings.ToLower(name) {
+			return pp, nil
+		}
+	}
+	if number, err := strconv.ParseUint(name, 10, 64); err == nil {
+		if _, ok := proposerPolicies[istanbul.ProposerPolicy(number)]; ok {
+			return istanbul.ProposerPolicy(number), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown proposer selection policy: %s", name)
+}
+
+// ProposerPolicyNames returns the names of the registered proposer selection
+// policies, in policy order.
+func ProposerPolicyNames() []string {
+	proposerPoliciesMu.RLock()
+	defer proposerPoliciesMu.RUnlock()
+
+	policies := make([]istanbul.ProposerPolicy, 0, len(proposerPolicies))
+	for pp := range proposerPolicies {
+		policies = append(policies, pp)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i] < policies[j] })
+	names := make([]string, len(policies))
+	for i, pp := range policies {
+		names[i] = proposerPolicies[pp].name
+	}
+	return names
+}
+
+// NewProposerSelector returns the ProposerSelector for the policy of the config
+func NewProposerSelector(config *istanbul.Config) istanbul.ProposerSelector {
+	proposerPoliciesMu.RLock()
+	registered, ok := proposerPolicies[config.ProposerPolicy]
+	proposerPoliciesMu.RUnlock()
+	if !ok {
 		// Programming error.
-		panic(fmt.Sprintf("unknown proposer selection policy: %v", pp))
+		panic(fmt.Sprintf("unknown proposer selection policy: %v", config.ProposerPolicy))
 	}
+	return registered.factory(config)
+}
+
+// GetProposerSelector returns the ProposerSelector for the given Policy
+func GetProposerSelector(pp istanbul.ProposerPolicy) istanbul.ProposerSelector {
+	return NewProposerSelector(&istanbul.Config{ProposerPolicy: pp})
 }
@@ -18,6 +18,7 @@ package validator
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
 	"testing"
 
@@ -268,3 +269,95 @@ func TestShuffledRoundRobinProposer(t *testing.T) {
 		}
 	})
 }
+
+func TestWeightedRandomProposer(t *testing.T) {
+	var addrs []common.Address
+	for _, strAddr := range testAddresses {
+		addrs = append(addrs, common.HexToAddress(strAddr))
+	}
+	v, err := istanbul.CombineIstanbulExtraToValidatorData(addrs, make([]blscrypto.SerializedPublicKey, len(addrs)))
+	if err != nil {
+		t.Fatalf("CombineIstanbulExtraToValidatorData(...): %v", err)
+	}
+	valSet := newDefaultSet(v)
+	config := &istanbul.Config{
+		ProposerPolicy:  istanbul.WeightedRandom,
+		ProposerWeights: map[common.Address]uint64{addrs[0]: 6, addrs[1]: 0},
+	}
+	selector := NewProposerSelector(config)
+
+	// Validators propose in proportion to their weights, 1 by default, over
+	// blocks of different randomness.
+	counts := make(map[common.Address]int)
+	for seq := 0; seq < 1000; seq++ {
+		valSet.SetRandomness(common.BigToHash(big.NewInt(int64(seq))))
+		proposer := selector(valSet, addrs[seq%len(addrs)], 0)
+		if again := selector(valSet, addrs[0], 0); again.Address() != proposer.Address() {
+			t.Fatalf("proposer of sequence %d depends on the last proposer: have %v, want %v", seq, again.Address(), proposer.Address())
+		}
+		counts[proposer.Address()]++
+	}
+	if counts[addrs[1]] != 0 {
+		t.Errorf("validator of weight 0 proposed %d blocks", counts[addrs[1]])
+	}
+	if counts[addrs[0]] < 500 || counts[addrs[0]] > 700 {
+		t.Errorf("validator of weight 6 proposed %d blocks, want about 600", counts[addrs[0]])
+	}
+	for _, addr := range addrs[2:] {
+		if counts[addr] < 50 || counts[addr] > 150 {
+			t.Errorf("validator of weight 1 proposed %d blocks, want about 100", counts[addr])
+		}
+	}
+
+	// Round changes draw again
+	valSet.SetRandomness(common.HexToHash("f36aa9716b892ec8"))
+	proposers := make(map[common.Address]bool)
+	for round := uint64(0); round < 100; round++ {
+		proposers[selector(valSet, common.Address{}, round).Address()] = true
+	}
+	if len(proposers) != len(addrs)-1 {
+		t.Errorf("proposers mismatch over round changes: have %d, want %d", len(proposers), len(addrs)-1)
+	}
+}
+
+func TestProposerPolicies(t *testing.T) {
+	for _, name := range ProposerPolicyNames() {
+		policy, err := ParseProposerPolicy(name)
+		if err != nil {
+			t.Fatalf("failed to parse policy %s: %v", name, err)
+		}
+		if have, err := ParseProposerPolicy(fmt.Sprint(uint64(policy))); err != nil || have != policy {
+			t.Errorf("policy %s mismatch by number: have %d, %v, want %d", name, have, err, policy)
+		}
+	}
+	if policy, err := ParseProposerPolicy("WeightedRandom"); err != nil || policy != istanbul.WeightedRandom {
+		t.Errorf("weighted random policy mismatch: have %d, %v", policy, err)
+	}
+	if _, err := ParseProposerPolicy("custom"); err == nil {
+		t.Errorf("unregistered policy parsed")
+	}
+
+	// Custom policies are selected like the built-in ones
+	const custom = istanbul.ProposerPolicy(100)
+	first := func(valSet istanbul.ValidatorSet, proposer common.Address, round uint64) istanbul.Validator {
+		return valSet.GetByIndex(0)
+	}
+	if err := RegisterProposerPolicy(custom, "custom", func(*istanbul.Config) istanbul.ProposerSelector { return first }); err != nil {
+		t.Fatalf("failed to register policy: %v", err)
+	}
+	defer func() {
+		proposerPoliciesMu.Lock()
+		delete(proposerPolicies, custom)
+		proposerPoliciesMu.Unlock()
+	}()
+	if err := RegisterProposerPolicy(istanbul.Sticky, "other", nil); err == nil {
+		t.Errorf("built-in policy registered again")
+	}
+	if policy, err := ParseProposerPolicy("custom"); err != nil || policy != custom {
+		t.Fatalf("custom policy mismatch: have %d, %v, want %d", policy, err, custom)
+	}
+	valSet := newDefaultSet([]istanbul.ValidatorData{{Address: common.HexToAddress(testAddresses[0])}, {Address: common.HexToAddress(testAddresses[1])}})
+	if proposer := GetProposerSelector(custom)(valSet, common.HexToAddress(testAddresses[0]), 1); proposer.Address() != common.HexToAddress(testAddresses[0]) {
+		t.Errorf("custom proposer mismatch: have %v", proposer.Address())
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/common/math"
+	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
 	gpm "github.com/celo-org/celo-blockchain/contracts/gasprice_minimum"
 	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/core/vm"
@@ -80,6 +81,34 @@ func CalcBaseFee(config *params.ChainConfig, parent *types.Header, vmRunnerParen
 	return newBaseFee
 }
 
+// ForecastBaseFees projects the basefees of the n blocks following parent with
+// CalcBaseFee, assuming each of them uses gasUsed gas. vmRunnerParent must run on
+// a copy of the state at the end of parent: it's modified by the gas price minimum
+// update finalizing every block forecast.
+func ForecastBaseFees(config *params.ChainConfig, parent *types.Header, vmRunnerParent vm.EVMRunner, gasUsed uint64, n int) ([]*big.Int, error) {
+	var (
+		baseFees = make([]*big.Int, 0, n)
+		gasLimit = blockchain_parameters.GetBlockGasLimitOrDefault(vmRunnerParent)
+	)
+	for i := 0; i < n; i++ {
+		baseFee := CalcBaseFee(config, parent, vmRunnerParent)
+		baseFees = append(baseFees, baseFee)
+		if i == n-1 {
+			break
+		}
+		if _, err := gpm.UpdateGasPriceMinimum(vmRunnerParent, gasUsed); err != nil {
+			return nil, err
+		}
+		parent = &types.Header{
+			Number:   new(big.Int).Add(parent.Number, common.Big1),
+			GasUsed:  gasUsed,
+			GasLimit: gasLimit,
+			BaseFee:  baseFee,
+		}
+	}
+	return baseFees, nil
+}
+
 // CalcBaseFee calculates the basefee of the header.
 func CalcBaseFeeEthereum(config *params.ChainConfig, parent *types.Header) *big.Int {
 	if config.FakeBaseFee != nil {
@@ -53,6 +53,31 @@ const BlockchainParametersStr = `[
 		"stateMutability": "view",
 		"type": "function"
 	},
+	{
+		"constant": true,
+		"inputs": [],
+		"name": "getMinimumClientVersion",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "major",
+				"type": "uint256"
+			},
+			{
+				"internalType": "uint256",
+				"name": "minor",
+				"type": "uint256"
+			},
+			{
+				"internalType": "uint256",
+				"name": "patch",
+				"type": "uint256"
+			}
+		],
+		"payable": false,
+		"stateMutability": "view",
+		"type": "function"
+	},
 	{
 		"constant": true,
 		"inputs": [],
@@ -94,6 +119,26 @@ const SortedOraclesStr = `[
 		"stateMutability": "view",
 		"type": "function"
 	}
+,
+	{
+		"constant": true,
+		"inputs": [
+			{
+				"name": "token",
+				"type": "address"
+			}
+		],
+		"name": "medianTimestamp",
+		"outputs": [
+			{
+				"name": "",
+				"type": "uint256"
+			}
+		],
+		"payable": false,
+		"stateMutability": "view",
+		"type": "function"
+	}
 ]`
 
 // This is taken from celo-monorepo/packages/protocol/build/<env>/contracts/ERC20.json
@@ -0,0 +1,143 @@
+package contracts
+
+import (
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/log"
+)
+
+// QueryBatch gathers read only method calls to make them together, multicall
+// style. When the runner is a vm.BatchEVMRunner, the addresses of the registered
+// contracts are looked up in a single EVM session and the calls made in another
+// one, instead of an EVM being created for every lookup and call.
+type QueryBatch struct {
+	queries []batchQuery
+}
+
+type batchQuery struct {
+	method *BoundMethod
+	result interface{}
+	args   []interface{}
+}
+
+// NewQueryBatch creates an empty QueryBatch
+func NewQueryBatch() *QueryBatch {
+	return &QueryBatch{}
+}
+
+// Query adds a read only call of the method to the batch, whose return value is
+// unpacked into result when the batch runs. It returns the index of the call in
+// the errors returned by Run.
+func (b *QueryBatch) Query(bm *BoundMethod, result interface{}, args ...interface{}) int {
+	b.queries = append(b.queries, batchQuery{method: bm, result: result, args: args})
+	return len(b.queries) - 1
+}
+
+// Run makes the calls of the batch with the given EVMRunner, and returns their
+// errors by call index. The calls are made as BoundMethod.Query would, a failed
+// call not preventing the next ones.
+func (b *QueryBatch) Run(vmRunner vm.EVMRunner) []error {
+	defer meterExecutionTime("batch")()
+
+	errs := make([]error, len(b.queries))
+	addresses := b.resolveAddresses(vmRunner, errs)
+
+	var (
+		calls   []vm.QueryCall
+		indexes []int
+	)
+	for i, q := range b.queries {
+		if errs[i] != nil {
+			continue
+		}
+		input, err := q.method.encodeCall(q.args...)
+		if err != nil {
+			log.Error("Error invoking evm function: can't encode method arguments", "to", addresses[i], "method", q.method.method, "args", q.args, "err", err)
+			errs[i] = err
+			continue
+		}
+		calls = append(calls, vm.QueryCall{Recipient: addresses[i], Input: input, Gas: q.method.maxGas})
+		indexes = append(indexes, i)
+	}
+	for j, res := range queryAll(vmRunner, calls) {
+		i := indexes[j]
+		q := b.queries[i]
+		logger := log.New("to", addresses[i], "method", q.method.method)
+		errs[i] = q.method.handleOutput(logger, calls[j].Input, res.Ret, res.Err, q.result)
+	}
+	return errs
+}
+
+// resolveAddresses returns the addresses of the contracts of the calls, looking
+// up every registry id once. The lookup failures are set in errs.
+func (b *QueryBatch) resolveAddresses(vmRunner vm.EVMRunner, errs []error) []common.Address {
+	var (
+		addresses = make([]common.Address, len(b.queries))
+		ids       []common.Hash
+		lookups   = make(map[common.Hash]int)
+	)
+	for i, q := range b.queries {
+		if q.method.registryId == nil {
+			addresses[i], errs[i] = q.method.resolveAddress(vmRunner)
+			continue
+		}
+		if _, ok := lookups[*q.method.registryId]; !ok {
+			lookups[*q.method.registryId] = len(ids)
+			ids = append(ids, *q.method.registryId)
+		}
+	}
+	if len(ids) == 0 {
+		return addresses
+	}
+	calls := make([]vm.QueryCall, len(ids))
+	for j, id := range ids {
+		input, err := getAddressMethod.encodeCall(id)
+		if err != nil {
+			// Packing a bytes32 can't fail
+			panic(err)
+		}
+		calls[j] = vm.QueryCall{Recipient: config.RegistrySmartContractAddress, Input: input, Gas: MaxGasForGetAddressFor}
+	}
+	vmRunner.StopGasMetering()
+	results := queryAll(vmRunner, calls)
+	vmRunner.StartGasMetering()
+
+	var (
+		resolved    = make([]common.Address, len(ids))
+		resolveErrs = make([]error, len(ids))
+	)
+	for j, res := range results {
+		var contractAddress common.Address
+		logger := log.New("to", config.RegistrySmartContractAddress, "method", getAddressMethod.method)
+		err := getAddressMethod.handleOutput(logger, calls[j].Input, res.Ret, res.Err, &contractAddress)
+		resolved[j], resolveErrs[j] = registeredAddress(contractAddress, err)
+	}
+	for i, q := range b.queries {
+		if q.method.registryId == nil {
+			continue
+		}
+		j := lookups[*q.method.registryId]
+		if resolveErrs[j] != nil {
+			logResolveError(ids[j], q.method.method, resolveErrs[j])
+		}
+		addresses[i], errs[i] = resolved[j], resolveErrs[j]
+	}
+	return addresses
+}
+
+// queryAll makes the read only calls in a single EVM session if the runner
+// supports it, one by one otherwise.
+func queryAll(vmRunner vm.EVMRunner, calls []vm.QueryCall) []vm.QueryResult {
+	if len(calls) == 0 {
+		return nil
+	}
+	if batchRunner, ok := vmRunner.(vm.BatchEVMRunner); ok {
+		return batchRunner.QueryBatch(calls)
+	}
+	results := make([]vm.QueryResult, len(calls))
+	for i, call := range calls {
+		results[i].Ret, results[i].Err = vmRunner.Query(call.Recipient, call.Input, call.Gas)
+	}
+	return results
+}
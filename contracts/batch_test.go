@@ -0,0 +1,100 @@
+package contracts
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/abis"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	. "github.com/onsi/gomega"
+)
+
+// batchingRunner counts the single calls and the batches of calls it makes.
+type batchingRunner struct {
+	*testutil.MockEVMRunner
+	queries, batches int
+}
+
+func (r *batchingRunner) Query(recipient common.Address, input []byte, gas uint64) ([]byte, error) {
+	r.queries++
+	return r.MockEVMRunner.Query(recipient, input, gas)
+}
+
+func (r *batchingRunner) QueryBatch(calls []vm.QueryCall) []vm.QueryResult {
+	r.batches++
+	results := make([]vm.QueryResult, len(calls))
+	for i, call := range calls {
+		results[i].Ret, results[i].Err = r.MockEVMRunner.Query(call.Recipient, call.Input, call.Gas)
+	}
+	return results
+}
+
+func TestQueryBatch(t *testing.T) {
+	var (
+		cUSD          = common.HexToAddress("0x20")
+		cEUR          = common.HexToAddress("0x21")
+		holder        = common.HexToAddress("0x30")
+		sortedOracles = testutil.NewSortedOraclesMock()
+	)
+	newBatch := func() (*QueryBatch, *[2]*big.Int, *[2]*big.Int, **big.Int, **big.Int, *common.Hash) {
+		var (
+			usdRate, eurRate  [2]*big.Int
+			gasLimit, balance *big.Int
+			commitment        common.Hash
+			batch             = NewQueryBatch()
+		)
+		batch.Query(NewRegisteredContractMethod(config.SortedOraclesRegistryId, abis.SortedOracles, "medianRate", 100000), &usdRate, cUSD)
+		batch.Query(NewRegisteredContractMethod(config.SortedOraclesRegistryId, abis.SortedOracles, "medianRate", 100000), &eurRate, cEUR)
+		batch.Query(NewRegisteredContractMethod(config.BlockchainParametersRegistryId, abis.BlockchainParameters, "blockGasLimit", 100000), &gasLimit)
+		batch.Query(NewRegisteredContractMethod(config.RandomRegistryId, abis.Random, "commitments", 100000), &commitment, holder)
+		batch.Query(NewBoundMethod(common.HexToAddress("0x02"), abis.ERC20, "balanceOf", 100000), &balance, holder)
+		return batch, &usdRate, &eurRate, &gasLimit, &balance, &commitment
+	}
+	newRunner := func() *testutil.MockEVMRunner {
+		celo := testutil.NewCeloMock()
+		celo.Registry.AddContract(config.SortedOraclesRegistryId, common.HexToAddress("0x11"))
+		celo.Runner.RegisterContract(common.HexToAddress("0x11"), sortedOracles)
+		return celo.Runner
+	}
+	sortedOracles.Rates[cUSD] = [2]*big.Int{big.NewInt(3), big.NewInt(2)}
+	sortedOracles.Rates[cEUR] = [2]*big.Int{big.NewInt(5), big.NewInt(4)}
+
+	for _, batching := range []bool{true, false} {
+		g := NewGomegaWithT(t)
+		batch, usdRate, eurRate, gasLimit, balance, commitment := newBatch()
+
+		var errs []error
+		runner := &batchingRunner{MockEVMRunner: newRunner()}
+		if batching {
+			errs = batch.Run(runner)
+			// The registry lookups, then the calls
+			g.Expect(runner.batches).To(Equal(2))
+			g.Expect(runner.queries).To(BeZero())
+		} else {
+			errs = batch.Run(runner.MockEVMRunner)
+		}
+		g.Expect(errs).To(HaveLen(5))
+		g.Expect(errs[0]).ToNot(HaveOccurred())
+		g.Expect(usdRate[0].Int64()).To(Equal(int64(3)))
+		g.Expect(errs[1]).ToNot(HaveOccurred())
+		g.Expect(eurRate[1].Int64()).To(Equal(int64(4)))
+		g.Expect(errs[2]).ToNot(HaveOccurred())
+		g.Expect((*gasLimit).Uint64()).To(Equal(testutil.NewBlockchainParametersMock().BlockGasLimit().Uint64()))
+		g.Expect(errs[3]).To(Equal(ErrSmartContractNotDeployed))
+		g.Expect(*commitment).To(Equal(common.Hash{}))
+		g.Expect(errs[4]).ToNot(HaveOccurred())
+		g.Expect((*balance).Int64()).To(Equal(int64(1_000_000_000_000_000)))
+	}
+
+	t.Run("should fail every registered call without registry", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		batch, _, _, _, _, _ := newBatch()
+		errs := batch.Run(testutil.NewMockEVMRunner())
+		for _, err := range errs[:4] {
+			g.Expect(err).To(Equal(ErrRegistryContractNotDeployed))
+		}
+	})
+}
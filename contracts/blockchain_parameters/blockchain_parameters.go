@@ -37,6 +37,7 @@ var (
 	intrinsicGasForAlternativeFeeCurrencyMethod = contracts.NewRegisteredContractMethod(config.BlockchainParametersRegistryId, abis.BlockchainParameters, "intrinsicGasForAlternativeFeeCurrency", maxGasForReadBlockchainParameter)
 	blockGasLimitMethod                         = contracts.NewRegisteredContractMethod(config.BlockchainParametersRegistryId, abis.BlockchainParameters, "blockGasLimit", maxGasForReadBlockchainParameter)
 	getUptimeLookbackWindowMethod               = contracts.NewRegisteredContractMethod(config.BlockchainParametersRegistryId, abis.BlockchainParameters, "getUptimeLookbackWindow", maxGasForReadBlockchainParameter)
+	getMinimumClientVersionMethod               = contracts.NewRegisteredContractMethod(config.BlockchainParametersRegistryId, abis.BlockchainParameters, "getMinimumClientVersion", maxGasForReadBlockchainParameter)
 )
 
 const DefaultIntrinsicGasForAlternativeFeeCurrency = config.IntrinsicGasForAlternativeFeeCurrency
@@ -101,6 +102,60 @@ func GetLookbackWindow(vmRunner vm.EVMRunner) (uint64, error) {
 	return lookbackWindow.Uint64(), nil
 }
 
+// GetMinimumClientVersion retrieves the minimum client version required by the network
+func GetMinimumClientVersion(vmRunner vm.EVMRunner) (*config.VersionInfo, error) {
+	var major, minor, patch *big.Int
+	err := getMinimumClientVersionMethod.Query(vmRunner, &[]interface{}{&major, &minor, &patch})
+	if err != nil {
+		logError("getMinimumClientVersion", err)
+		return nil, err
+	}
+	return &config.VersionInfo{Major: major.Uint64(), Minor: minor.Uint64(), Patch: patch.Uint64()}, nil
+}
+
+// Parameters are the blockchain parameters read together by GetParametersOrDefault
+type Parameters struct {
+	BlockGasLimit                         uint64
+	IntrinsicGasForAlternativeFeeCurrency uint64
+	MinimumClientVersion                  *config.VersionInfo // nil if it couldn't be read
+}
+
+// GetParametersOrDefault retrieves the block max gas limit, the intrinsic gas for
+// alternative fee currencies and the minimum client version in a single batch of
+// calls. The values that couldn't be read are the defaults, and a nil minimum
+// client version.
+func GetParametersOrDefault(vmRunner vm.EVMRunner) *Parameters {
+	var (
+		gasLimit, intrinsicGas, major, minor, patch *big.Int
+		batch                                       = contracts.NewQueryBatch()
+	)
+	gasLimitCall := batch.Query(blockGasLimitMethod, &gasLimit)
+	intrinsicGasCall := batch.Query(intrinsicGasForAlternativeFeeCurrencyMethod, &intrinsicGas)
+	versionCall := batch.Query(getMinimumClientVersionMethod, &[]interface{}{&major, &minor, &patch})
+	errs := batch.Run(vmRunner)
+
+	parameters := &Parameters{
+		BlockGasLimit:                         params.DefaultGasLimit,
+		IntrinsicGasForAlternativeFeeCurrency: config.IntrinsicGasForAlternativeFeeCurrency,
+	}
+	if err := errs[gasLimitCall]; err != nil {
+		logError("blockGasLimit", err)
+	} else {
+		parameters.BlockGasLimit = gasLimit.Uint64()
+	}
+	if err := errs[intrinsicGasCall]; err != nil {
+		log.Trace("Default gas", "gas", config.IntrinsicGasForAlternativeFeeCurrency, "method", "intrinsicGasForAlternativeFeeCurrency")
+	} else {
+		parameters.IntrinsicGasForAlternativeFeeCurrency = intrinsicGas.Uint64()
+	}
+	if err := errs[versionCall]; err != nil {
+		logError("getMinimumClientVersion", err)
+	} else {
+		parameters.MinimumClientVersion = &config.VersionInfo{Major: major.Uint64(), Minor: minor.Uint64(), Patch: patch.Uint64()}
+	}
+	return parameters
+}
+
 func logError(method string, err error) {
 	if err == contracts.ErrRegistryContractNotDeployed {
 		log.Debug("Error calling "+method, "err", err, "contract", hexutil.Encode(config.BlockchainParametersRegistryId[:]))
@@ -107,3 +107,45 @@ func TestGetLookbackWindow(t *testing.T) {
 		g.Expect(lookbackWindow).To(Equal(uint64(15)))
 	})
 }
+
+func TestGetMinimumClientVersion(t *testing.T) {
+	testutil.TestFailOnFailingRunner(t, GetMinimumClientVersion)
+	testutil.TestFailsWhenContractNotDeployed(t, contracts.ErrSmartContractNotDeployed, GetMinimumClientVersion)
+	t.Run("should return minimum client version", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		runner := testutil.NewSingleMethodRunner(
+			config.BlockchainParametersRegistryId,
+			"getMinimumClientVersion",
+			func() (*big.Int, *big.Int, *big.Int) {
+				return big.NewInt(1), big.NewInt(8), big.NewInt(3)
+			},
+		)
+
+		version, err := GetMinimumClientVersion(runner)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(*version).To(Equal(config.VersionInfo{Major: 1, Minor: 8, Patch: 3}))
+	})
+}
+
+func TestGetParametersOrDefault(t *testing.T) {
+	t.Run("should return defaults on failing runner", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		parameters := GetParametersOrDefault(testutil.FailingVmRunner{})
+		g.Expect(*parameters).To(Equal(Parameters{
+			BlockGasLimit:                         params.DefaultGasLimit,
+			IntrinsicGasForAlternativeFeeCurrency: DefaultIntrinsicGasForAlternativeFeeCurrency,
+		}))
+	})
+	t.Run("should return the parameters", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+
+		celo := testutil.NewCeloMock()
+		parameters := GetParametersOrDefault(celo.Runner)
+		g.Expect(parameters.BlockGasLimit).To(Equal(celo.BlockchainParameters.BlockGasLimit().Uint64()))
+		g.Expect(parameters.IntrinsicGasForAlternativeFeeCurrency).To(Equal(celo.BlockchainParameters.IntrinsicGasForAlternativeFeeCurrency().Uint64()))
+		major, minor, patch := celo.BlockchainParameters.GetMinimumClientVersion()
+		g.Expect(*parameters.MinimumClientVersion).To(Equal(config.VersionInfo{Major: major.Uint64(), Minor: minor.Uint64(), Patch: patch.Uint64()}))
+	})
+}
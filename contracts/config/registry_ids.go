@@ -8,6 +8,10 @@ import (
 var (
 	RegistrySmartContractAddress = common.HexToAddress("0x000000000000000000000000000000000000ce10")
 
+	// ProxyImplementationSlot is the storage slot of the core contract proxies
+	// holding the address of their implementation, as set by EIP-1967.
+	ProxyImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+
 	// Celo registered contract IDs.
 	// The names are taken from celo-monorepo/packages/protocol/lib/registry-utils.ts
 	AttestationsRegistryId         = makeRegistryId("Attestations")
@@ -28,6 +32,30 @@ var (
 	FeeHandlerId                   = makeRegistryId("FeeHandler")
 )
 
+// CoreContracts are the registered contracts the protocol relies on, called by
+// the system calls of every block or mutated by the epoch rewards and elections.
+var CoreContracts = []struct {
+	Name string
+	Id   common.Hash
+}{
+	{"Attestations", AttestationsRegistryId},
+	{"BlockchainParameters", BlockchainParametersRegistryId},
+	{"Election", ElectionRegistryId},
+	{"EpochRewards", EpochRewardsRegistryId},
+	{"FeeCurrencyWhitelist", FeeCurrencyWhitelistRegistryId},
+	{"FeeHandler", FeeHandlerId},
+	{"Freezer", FreezerRegistryId},
+	{"GasPriceMinimum", GasPriceMinimumRegistryId},
+	{"GoldToken", GoldTokenRegistryId},
+	{"Governance", GovernanceRegistryId},
+	{"LockedGold", LockedGoldRegistryId},
+	{"Random", RandomRegistryId},
+	{"Reserve", ReserveRegistryId},
+	{"SortedOracles", SortedOraclesRegistryId},
+	{"StableToken", StableTokenRegistryId},
+	{"Validators", ValidatorsRegistryId},
+}
+
 func makeRegistryId(contractName string) [32]byte {
 	hash := crypto.Keccak256([]byte(contractName))
 	var id [32]byte
@@ -1,6 +1,10 @@
 package config
 
-import "github.com/celo-org/celo-blockchain/params"
+import (
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/params"
+)
 
 type VersionInfo struct {
 	Major uint64
@@ -36,3 +40,7 @@ func (v *VersionInfo) Cmp(version *VersionInfo) int {
 var CurrentVersionInfo = func() *VersionInfo {
 	return &VersionInfo{params.VersionMajor, params.VersionMinor, params.VersionPatch}
 }()
+
+func (v *VersionInfo) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
@@ -55,7 +55,8 @@ func NewBoundMethod(contractAddress common.Address, abi *abi.ABI, methodName str
 
 func NewRegisteredContractMethod(registryId common.Hash, abi *abi.ABI, methodName string, maxGas uint64) *BoundMethod {
 	return &BoundMethod{
-		Method: NewMethod(abi, methodName, maxGas),
+		Method:     NewMethod(abi, methodName, maxGas),
+		registryId: &registryId,
 		resolveAddress: func(vmRunner vm.EVMRunner) (common.Address, error) {
 			return resolveAddressForCall(vmRunner, registryId, methodName)
 		},
@@ -67,6 +68,7 @@ func NewRegisteredContractMethod(registryId common.Hash, abi *abi.ABI, methodNam
 // that addresses need to be obtained from the Registry before making a call
 type BoundMethod struct {
 	Method
+	registryId     *common.Hash // Registry id of the contract, nil if bound to an address
 	resolveAddress func(vm.EVMRunner) (common.Address, error)
 }
 
@@ -104,7 +106,12 @@ func (bm *BoundMethod) run(vmRunner vm.EVMRunner, result interface{}, readOnly b
 	} else {
 		output, err = vmRunner.Execute(contractAddress, input, bm.maxGas, value)
 	}
+	return bm.handleOutput(logger, input, output, err, result)
+}
 
+// handleOutput unpacks the output of a call of the method into result, or logs
+// and returns the error of the call.
+func (bm *BoundMethod) handleOutput(logger log.Logger, input, output []byte, err error, result interface{}) error {
 	if err != nil {
 		message, _ := unpackError(output)
 		logger.Error("Error invoking evm function: EVM call failure", "input", hexutil.Encode(input), "maxgas", bm.maxGas, "err", err, "message", message)
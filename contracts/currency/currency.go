@@ -19,6 +19,8 @@ package currency
 import (
 	"fmt"
 	"math/big"
+	"sync"
+	"time"
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/contracts"
@@ -33,12 +35,14 @@ const (
 	maxGasToReadErc20Balance uint64 = 100 * n.Thousand
 	maxGasForGetWhiteList    uint64 = 200 * n.Thousand
 	maxGasForMedianRate      uint64 = 100 * n.Thousand
+	maxGasForMedianTimestamp uint64 = 100 * n.Thousand
 )
 
 var (
-	medianRateMethod   = contracts.NewRegisteredContractMethod(config.SortedOraclesRegistryId, abis.SortedOracles, "medianRate", maxGasForMedianRate)
-	getWhitelistMethod = contracts.NewRegisteredContractMethod(config.FeeCurrencyWhitelistRegistryId, abis.FeeCurrencyWhitelist, "getWhitelist", maxGasForGetWhiteList)
-	getBalanceMethod   = contracts.NewMethod(abis.ERC20, "balanceOf", maxGasToReadErc20Balance)
+	medianRateMethod      = contracts.NewRegisteredContractMethod(config.SortedOraclesRegistryId, abis.SortedOracles, "medianRate", maxGasForMedianRate)
+	medianTimestampMethod = contracts.NewRegisteredContractMethod(config.SortedOraclesRegistryId, abis.SortedOracles, "medianTimestamp", maxGasForMedianTimestamp)
+	getWhitelistMethod    = contracts.NewRegisteredContractMethod(config.FeeCurrencyWhitelistRegistryId, abis.FeeCurrencyWhitelist, "getWhitelist", maxGasForGetWhiteList)
+	getBalanceMethod      = contracts.NewMethod(abis.ERC20, "balanceOf", maxGasToReadErc20Balance)
 )
 
 // NoopExchangeRate represents an exchange rate of 1 to 1
@@ -75,6 +79,11 @@ func (c *Currency) FromCELO(celoAmount *big.Int) *big.Int {
 	return c.toCELORate.FromBase(celoAmount)
 }
 
+// ToCELORate returns the exchange rate of CELO to the currency
+func (c *Currency) ToCELORate() ExchangeRate {
+	return c.toCELORate
+}
+
 // CmpToCurrency compares a currency amount to an amount in a different currency
 func (c *Currency) CmpToCurrency(currencyAmount *big.Int, sndCurrencyAmount *big.Int, sndCurrency *Currency) int {
 	if c == sndCurrency || c.Address == sndCurrency.Address {
@@ -122,6 +131,16 @@ func NewExchangeRate(numerator *big.Int, denominator *big.Int) (*ExchangeRate, e
 	return &ExchangeRate{numerator, denominator}, nil
 }
 
+// Numerator returns the token amount of the rate
+func (er *ExchangeRate) Numerator() *big.Int {
+	return new(big.Int).Set(er.numerator)
+}
+
+// Denominator returns the base amount of the rate
+func (er *ExchangeRate) Denominator() *big.Int {
+	return new(big.Int).Set(er.denominator)
+}
+
 // ToBase converts from token to base
 func (er *ExchangeRate) ToBase(tokenAmount *big.Int) *big.Int {
 	return new(big.Int).Div(new(big.Int).Mul(tokenAmount, er.denominator), er.numerator)
@@ -139,6 +158,7 @@ func (er *ExchangeRate) FromBase(goldAmount *big.Int) *big.Int {
 type CurrencyManager struct {
 	vmRunner vm.EVMRunner
 
+	cacheMu          sync.Mutex
 	currencyCache    map[common.Address]*Currency                               // map of exchange rates of the form (CELO, token)
 	_getExchangeRate func(vm.EVMRunner, *common.Address) (*ExchangeRate, error) // function to obtain exchange rate from blockchain state
 }
@@ -158,6 +178,18 @@ func NewCacheOnlyManager(currencyCache map[common.Address]*Currency) *CurrencyMa
 	return &CurrencyManager{currencyCache: currencyCache}
 }
 
+// NewManagerFrom creates a new CurrencyManager starting with the exchange rates
+// cached by prev, for a state in which they're known to be the same.
+func NewManagerFrom(vmRunner vm.EVMRunner, prev *CurrencyManager) *CurrencyManager {
+	cc := newManager(prev._getExchangeRate, vmRunner)
+	prev.cacheMu.Lock()
+	defer prev.cacheMu.Unlock()
+	for addr, currency := range prev.currencyCache {
+		cc.currencyCache[addr] = currency
+	}
+	return cc
+}
+
 func newManager(_getExchangeRate func(vm.EVMRunner, *common.Address) (*ExchangeRate, error), vmRunner vm.EVMRunner) *CurrencyManager {
 	return &CurrencyManager{
 		vmRunner:         vmRunner,
@@ -172,6 +204,9 @@ func (cc *CurrencyManager) GetCurrency(currencyAddress *common.Address) (*Curren
 		return &CELOCurrency, nil
 	}
 
+	cc.cacheMu.Lock()
+	defer cc.cacheMu.Unlock()
+
 	val, ok := cc.currencyCache[*currencyAddress]
 	if ok {
 		return val, nil
@@ -237,6 +272,85 @@ func GetExchangeRate(vmRunner vm.EVMRunner, currencyAddress *common.Address) (*E
 	return NewExchangeRate(returnArray[0], returnArray[1])
 }
 
+// GetExchangeRates retrieves the currency-to-CELO exchange rates of the currencies,
+// by currency index, in a single batch of calls.
+func GetExchangeRates(vmRunner vm.EVMRunner, currencies []common.Address) ([]*ExchangeRate, error) {
+	var (
+		returnArrays = make([][2]*big.Int, len(currencies))
+		batch        = contracts.NewQueryBatch()
+	)
+	for i := range currencies {
+		batch.Query(medianRateMethod, &returnArrays[i], currencies[i])
+	}
+	errs := batch.Run(vmRunner)
+
+	rates := make([]*ExchangeRate, len(currencies))
+	for i, err := range errs {
+		if err == contracts.ErrSmartContractNotDeployed {
+			log.Warn("Registry address lookup failed", "err", err)
+			rates[i] = &NoopExchangeRate
+			continue
+		} else if err != nil {
+			log.Error("medianRate invocation error", "feeCurrencyAddress", currencies[i].Hex(), "err", err)
+			rates[i] = &NoopExchangeRate
+			continue
+		}
+		log.Trace("medianRate invocation success", "feeCurrencyAddress", currencies[i], "returnArray", returnArrays[i])
+		rate, err := NewExchangeRate(returnArrays[i][0], returnArrays[i][1])
+		if err != nil {
+			return nil, err
+		}
+		rates[i] = rate
+	}
+	return rates, nil
+}
+
+// GetMedianTimestamp retrieves the time of the last report included in the
+// median exchange rate of a currency, zero if it was never reported.
+func GetMedianTimestamp(vmRunner vm.EVMRunner, currencyAddress common.Address) (uint64, error) {
+	var timestamp *big.Int
+	if err := medianTimestampMethod.Query(vmRunner, &timestamp, currencyAddress); err != nil {
+		return 0, err
+	}
+	if !timestamp.IsUint64() {
+		return 0, fmt.Errorf("invalid median timestamp %v", timestamp)
+	}
+	return timestamp.Uint64(), nil
+}
+
+// GetMedianTimestamps retrieves the times of the last reports included in the
+// median exchange rates of the currencies, by currency index, in a single batch
+// of calls. The time of a currency is zero if its error is set.
+func GetMedianTimestamps(vmRunner vm.EVMRunner, currencies []common.Address) ([]uint64, []error) {
+	var (
+		timestamps = make([]*big.Int, len(currencies))
+		batch      = contracts.NewQueryBatch()
+	)
+	for i := range currencies {
+		batch.Query(medianTimestampMethod, &timestamps[i], currencies[i])
+	}
+	errs := batch.Run(vmRunner)
+
+	times := make([]uint64, len(currencies))
+	for i, err := range errs {
+		if err != nil {
+			continue
+		}
+		if !timestamps[i].IsUint64() {
+			errs[i] = fmt.Errorf("invalid median timestamp %v", timestamps[i])
+			continue
+		}
+		times[i] = timestamps[i].Uint64()
+	}
+	return times, errs
+}
+
+// RateStale returns whether a median exchange rate last reported at the given
+// time, zero if never, is more than maxAge older than now.
+func RateStale(reported, now uint64, maxAge time.Duration) bool {
+	return reported == 0 || reported+uint64(maxAge/time.Second) < now
+}
+
 // GetBalanceOf returns an account's balance on a given ERC20 currency
 func GetBalanceOf(vmRunner vm.EVMRunner, accountOwner common.Address, contractAddress common.Address) (result *big.Int, err error) {
 	log.Trace("GetBalanceOf() Called", "accountOwner", accountOwner.Hex(), "contractAddress", contractAddress)
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
 	"github.com/celo-org/celo-blockchain/core/vm"
 	. "github.com/onsi/gomega"
 )
@@ -248,3 +250,32 @@ func TestCurrency(t *testing.T) {
 		g.Expect(expensiveCurrency.CmpToCurrency(big.NewInt(10), big.NewInt(10), &cheapCurrency)).Should(Equal(1))
 	})
 }
+
+func TestGetExchangeRates(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var (
+		cUSD          = common.HexToAddress("0x20")
+		cEUR          = common.HexToAddress("0x21")
+		celo          = testutil.NewCeloMock()
+		sortedOracles = testutil.NewSortedOraclesMock()
+	)
+	celo.Registry.AddContract(config.SortedOraclesRegistryId, common.HexToAddress("0x11"))
+	celo.Runner.RegisterContract(common.HexToAddress("0x11"), sortedOracles)
+	sortedOracles.Rates[cUSD] = [2]*big.Int{big.NewInt(3), big.NewInt(2)}
+	sortedOracles.Timestamps[cEUR] = 42
+
+	rates, err := GetExchangeRates(celo.Runner, []common.Address{cUSD, cEUR})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(rates).To(HaveLen(2))
+	g.Expect(*rates[0]).To(Equal(*MustNewExchangeRate(big.NewInt(3), big.NewInt(2))))
+	g.Expect(*rates[1]).To(Equal(*MustNewExchangeRate(common.Big1, common.Big1)))
+
+	timestamps, errs := GetMedianTimestamps(celo.Runner, []common.Address{cUSD, cEUR})
+	g.Expect(errs).To(Equal([]error{nil, nil}))
+	g.Expect(timestamps).To(Equal([]uint64{0, 42}))
+
+	// Without oracles the rates are 1:1
+	rates, err = GetExchangeRates(testutil.NewCeloMock().Runner, []common.Address{cUSD})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(rates[0]).To(Equal(&NoopExchangeRate))
+}
@@ -108,6 +108,12 @@ func getGroupEpochRewards(vmRunner vm.EVMRunner, group common.Address, maxReward
 	return groupEpochRewards, nil
 }
 
+// GetGroupEpochRewards returns the voter rewards a group would receive for the epoch given
+// the uptimes of its elected members.
+func GetGroupEpochRewards(vmRunner vm.EVMRunner, group common.Address, maxTotalRewards *big.Int, uptimes []*big.Int) (*big.Int, error) {
+	return getGroupEpochRewards(vmRunner, group, maxTotalRewards, uptimes)
+}
+
 func DistributeEpochRewards(vmRunner vm.EVMRunner, groups []common.Address, maxTotalRewards *big.Int, uptimes map[common.Address][]*big.Int) (*big.Int, error) {
 	totalRewards := big.NewInt(0)
 	voteTotals, err := getTotalVotesForEligibleValidatorGroups(vmRunner)
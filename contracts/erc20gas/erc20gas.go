@@ -54,7 +54,8 @@ func DebitFees(evm *vm.EVM, address common.Address, amount *big.Int, feeCurrency
 	transactionData := common.GetEncodedAbi(debitGasFeesSelector, [][]byte{common.AddressToAbi(address), common.AmountToAbi(amount)})
 
 	// Run only primary evm.Call() with tracer
-	if evm.GetDebug() {
+	debug := evm.GetDebug()
+	if debug {
 		evm.SetDebug(false)
 		defer func() { evm.SetDebug(true) }()
 	}
@@ -63,6 +64,9 @@ func DebitFees(evm *vm.EVM, address common.Address, amount *big.Int, feeCurrency
 	// The caller was already charged for the cost of this operation via IntrinsicGas.
 	ret, leftoverGas, err := evm.Call(rootCaller, *feeCurrency, transactionData, maxGasForDebitGasFeesTransactions, big.NewInt(0))
 	gasUsed := maxGasForDebitGasFeesTransactions - leftoverGas
+	if debug {
+		captureFeeCurrencyCall(evm, *feeCurrency, transactionData, gasUsed, err)
+	}
 	log.Trace("debitGasFees called", "feeCurrency", *feeCurrency, "gasUsed", gasUsed)
 	if err != nil {
 		revertReason, err2 := abi.UnpackRevert(ret)
@@ -87,7 +91,8 @@ func CreditFees(
 	transactionData := common.GetEncodedAbi(creditGasFeesSelector, [][]byte{common.AddressToAbi(from), common.AddressToAbi(feeRecipient), common.AddressToAbi(*gatewayFeeRecipient), common.AddressToAbi(feeHandler), common.AmountToAbi(refund), common.AmountToAbi(tipTxFee), common.AmountToAbi(gatewayFee), common.AmountToAbi(baseTxFee)})
 
 	// Run only primary evm.Call() with tracer
-	if evm.GetDebug() {
+	debug := evm.GetDebug()
+	if debug {
 		evm.SetDebug(false)
 		defer func() { evm.SetDebug(true) }()
 	}
@@ -96,6 +101,9 @@ func CreditFees(
 	// The caller was already charged for the cost of this operation via IntrinsicGas.
 	ret, leftoverGas, err := evm.Call(rootCaller, *feeCurrency, transactionData, maxGasForCreditGasFeesTransactions, big.NewInt(0))
 	gasUsed := maxGasForCreditGasFeesTransactions - leftoverGas
+	if debug {
+		captureFeeCurrencyCall(evm, *feeCurrency, transactionData, gasUsed, err)
+	}
 	log.Trace("creditGas called", "feeCurrency", *feeCurrency, "gasUsed", gasUsed)
 	if err != nil {
 		revertReason, err2 := abi.UnpackRevert(ret)
@@ -105,3 +113,11 @@ func CreditFees(
 	}
 	return err
 }
+
+// captureFeeCurrencyCall hands a fee debit or credit call to the tracer of the
+// EVM, if it captures them.
+func captureFeeCurrencyCall(evm *vm.EVM, feeCurrency common.Address, input []byte, gasUsed uint64, err error) {
+	if logger, ok := evm.Config.Tracer.(vm.FeeCurrencyLogger); ok {
+		logger.CaptureFeeCurrencyCall(feeCurrency, input, gasUsed, err)
+	}
+}
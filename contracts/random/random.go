@@ -56,6 +56,28 @@ func GetLastCommitment(vmRunner vm.EVMRunner, validator common.Address) (common.
 	return lastCommitment, nil
 }
 
+// GetLastCommitmentIfRunning returns whether the random contract is running, as
+// IsRunning does, and if it is the last commitment of the validator, looking up
+// the contract in the registry once for both.
+func GetLastCommitmentIfRunning(vmRunner vm.EVMRunner, validator common.Address) (bool, common.Hash, error) {
+	lastCommitment := common.Hash{}
+	batch := contracts.NewQueryBatch()
+	batch.Query(commitmentsMethod, &lastCommitment, validator)
+	err := batch.Run(vmRunner)[0]
+	if err == contracts.ErrSmartContractNotDeployed || err == contracts.ErrRegistryContractNotDeployed {
+		return false, common.Hash{}, nil
+	} else if err != nil {
+		log.Error("Failed to get last commitment", "err", err)
+		return true, lastCommitment, err
+	}
+
+	if (lastCommitment == common.Hash{}) {
+		log.Debug("Unable to find last randomness commitment in smart contract")
+	}
+
+	return true, lastCommitment, nil
+}
+
 // ComputeCommitment calulcates the commitment for a given randomness.
 func ComputeCommitment(vmRunner vm.EVMRunner, randomness common.Hash) (common.Hash, error) {
 	commitment := common.Hash{}
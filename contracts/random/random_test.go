@@ -138,3 +138,35 @@ func TestBlockRandomness(t *testing.T) {
 		g.Expect(ret).To(Equal(someRandomness))
 	})
 }
+
+func TestGetLastCommitmentIfRunning(t *testing.T) {
+	validatorAddress := common.HexToAddress("0x09")
+	someCommitment := common.HexToHash("0x666")
+
+	t.Run("should not be running if Registry Not deployed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		running, _, err := GetLastCommitmentIfRunning(testutil.NewMockEVMRunner(), validatorAddress)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(running).To(BeFalse())
+	})
+	t.Run("should not be running if Random Not deployed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		vmrunner := testutil.NewMockEVMRunner()
+		vmrunner.RegisterContract(config.RegistrySmartContractAddress, testutil.NewRegistryMock())
+		running, _, err := GetLastCommitmentIfRunning(vmrunner, validatorAddress)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(running).To(BeFalse())
+	})
+	t.Run("should retrieve last commitment", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		vmrunner := testutil.NewSingleMethodRunner(config.RandomRegistryId, "commitments", func(validator common.Address) common.Hash {
+			g.Expect(validator).To(Equal(validatorAddress))
+			return someCommitment
+		})
+
+		running, ret, err := GetLastCommitmentIfRunning(vmrunner, validatorAddress)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(running).To(BeTrue())
+		g.Expect(ret).To(Equal(someCommitment))
+	})
+}
@@ -26,7 +26,12 @@ func GetRegisteredAddress(vmRunner vm.EVMRunner, registryId common.Hash) (common
 
 	var contractAddress common.Address
 	err := getAddressMethod.Query(vmRunner, &contractAddress, registryId)
+	return registeredAddress(contractAddress, err)
+}
 
+// registeredAddress returns the address and the error of a registry lookup from
+// the outcome of its getAddressFor call.
+func registeredAddress(contractAddress common.Address, err error) (common.Address, error) {
 	// TODO (mcortesi) Remove ErrEmptyArguments check after we change Proxy to fail on unset impl
 	// TODO(asa): Why was this change necessary?
 	if err == abi.ErrEmptyArguments || err == vm.ErrExecutionReverted {
@@ -11,6 +11,7 @@ type CeloMock struct {
 	BlockchainParameters *BlockchainParametersMock
 	FeeCurrencyWhitelist *FeeCurrencyWhitelistMock
 	ERC20Token           *ERC20TokenMock
+	GasPriceMinimum      *GasPriceMinimumMock // Only set by NewCeloMockFromFixture
 }
 
 func NewCeloMock() CeloMock {
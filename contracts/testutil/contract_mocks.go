@@ -36,16 +36,23 @@ func (bp *BlockchainParametersMock) BlockGasLimit() *big.Int {
 func (bp *BlockchainParametersMock) GetUptimeLookbackWindow() *big.Int {
 	return bp.LookbackWindow
 }
+func (bp *BlockchainParametersMock) GetMinimumClientVersion() (*big.Int, *big.Int, *big.Int) {
+	return new(big.Int).SetUint64(bp.MinimumVersion.Major), new(big.Int).SetUint64(bp.MinimumVersion.Minor), new(big.Int).SetUint64(bp.MinimumVersion.Patch)
+}
 func (bp *BlockchainParametersMock) IntrinsicGasForAlternativeFeeCurrency() *big.Int {
 	return bp.IntrinsicGasForAlternativeFeeCurrencyValue
 }
 
 type FeeCurrencyWhitelistMock struct {
 	ContractMock
+
+	Whitelist []common.Address
 }
 
 func NewWhitelistMock() *FeeCurrencyWhitelistMock {
-	mock := &FeeCurrencyWhitelistMock{}
+	mock := &FeeCurrencyWhitelistMock{
+		Whitelist: []common.Address{common.HexToAddress("02"), common.HexToAddress("05")},
+	}
 
 	contract := NewContractMock(abis.FeeCurrencyWhitelist, mock)
 	mock.ContractMock = contract
@@ -53,7 +60,7 @@ func NewWhitelistMock() *FeeCurrencyWhitelistMock {
 }
 
 func (bp *FeeCurrencyWhitelistMock) GetWhitelist() []common.Address {
-	return []common.Address{common.HexToAddress("02"), common.HexToAddress("05")}
+	return bp.Whitelist
 }
 
 type ERC20TokenMock struct {
@@ -75,3 +82,61 @@ func (bp *ERC20TokenMock) BalanceOf(addr common.Address) *big.Int {
 func (bp *ERC20TokenMock) DebitGasFees(from common.Address, value *big.Int) {
 	// Does not return anything
 }
+
+type GasPriceMinimumMock struct {
+	ContractMock
+
+	// GasPriceMinimums are keyed by currency address, currencies missing
+	// from it use DefaultGasPriceMinimum
+	GasPriceMinimums       map[common.Address]*big.Int
+	DefaultGasPriceMinimum *big.Int
+}
+
+func NewGasPriceMinimumMock() *GasPriceMinimumMock {
+	mock := &GasPriceMinimumMock{
+		GasPriceMinimums:       make(map[common.Address]*big.Int),
+		DefaultGasPriceMinimum: big.NewInt(100_000_000),
+	}
+
+	contract := NewContractMock(abis.GasPriceMinimum, mock)
+	mock.ContractMock = contract
+	return mock
+}
+
+func (gpm *GasPriceMinimumMock) GetGasPriceMinimum(currency common.Address) *big.Int {
+	if value, ok := gpm.GasPriceMinimums[currency]; ok {
+		return value
+	}
+	return gpm.DefaultGasPriceMinimum
+}
+
+type SortedOraclesMock struct {
+	ContractMock
+
+	// Rates and Timestamps are keyed by currency address, currencies missing
+	// from Rates are 1:1 with CELO and from Timestamps never reported
+	Rates      map[common.Address][2]*big.Int
+	Timestamps map[common.Address]uint64
+}
+
+func NewSortedOraclesMock() *SortedOraclesMock {
+	mock := &SortedOraclesMock{
+		Rates:      make(map[common.Address][2]*big.Int),
+		Timestamps: make(map[common.Address]uint64),
+	}
+
+	contract := NewContractMock(abis.SortedOracles, mock)
+	mock.ContractMock = contract
+	return mock
+}
+
+func (so *SortedOraclesMock) MedianRate(currency common.Address) (*big.Int, *big.Int) {
+	if rate, ok := so.Rates[currency]; ok {
+		return rate[0], rate[1]
+	}
+	return big.NewInt(1), big.NewInt(1)
+}
+
+func (so *SortedOraclesMock) MedianTimestamp(currency common.Address) *big.Int {
+	return new(big.Int).SetUint64(so.Timestamps[currency])
+}
@@ -0,0 +1,19 @@
+package testutil
+
+import (
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+)
+
+// Check we actually implement EVMRunnerFactory
+var _ vm.EVMRunnerFactory = MockEVMRunnerFactory{}
+
+// MockEVMRunnerFactory is an EVMRunnerFactory handing out the same runner for
+// every header and state, to be used in place of the blockchain.
+type MockEVMRunnerFactory struct {
+	Runner vm.EVMRunner
+}
+
+func (f MockEVMRunnerFactory) NewEVMRunner(header *types.Header, state vm.StateDB) vm.EVMRunner {
+	return f.Runner
+}
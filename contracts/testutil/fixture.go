@@ -0,0 +1,88 @@
+package testutil
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/core/vm"
+)
+
+// Addresses of the contracts only registered by NewCeloMockFromFixture
+var (
+	GoldTokenMockAddress       = common.HexToAddress("0x04")
+	GasPriceMinimumMockAddress = common.HexToAddress("0x06")
+)
+
+// SystemContractsFixture describes the system contracts state served by a
+// CeloMock, so that tests can be driven by JSON files rather than a full chain.
+// Unset fields keep the NewCeloMock defaults.
+type SystemContractsFixture struct {
+	BlockGasLimit                         *big.Int            `json:"blockGasLimit,omitempty"`
+	IntrinsicGasForAlternativeFeeCurrency *big.Int            `json:"intrinsicGasForAlternativeFeeCurrency,omitempty"`
+	MinimumClientVersion                  *config.VersionInfo `json:"minimumClientVersion,omitempty"`
+	// FeeCurrencies is the fee currency whitelist
+	FeeCurrencies []common.Address `json:"feeCurrencies,omitempty"`
+	// GasPriceMinimums deploys the GasPriceMinimum contract with these values,
+	// CELO is keyed by the zero address
+	GasPriceMinimums map[common.Address]*big.Int `json:"gasPriceMinimums,omitempty"`
+}
+
+// ParseSystemContractsFixture decodes a JSON fixture.
+func ParseSystemContractsFixture(data []byte) (*SystemContractsFixture, error) {
+	fixture := new(SystemContractsFixture)
+	if err := json.Unmarshal(data, fixture); err != nil {
+		return nil, err
+	}
+	return fixture, nil
+}
+
+// LoadSystemContractsFixture reads a JSON fixture file.
+func LoadSystemContractsFixture(path string) (*SystemContractsFixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSystemContractsFixture(data)
+}
+
+// NewCeloMockFromFixture creates a CeloMock serving the state of the fixture.
+func NewCeloMockFromFixture(fixture *SystemContractsFixture) CeloMock {
+	celo := NewCeloMock()
+
+	if fixture.BlockGasLimit != nil {
+		celo.BlockchainParameters.BlockGasLimitValue = fixture.BlockGasLimit
+	}
+	if fixture.IntrinsicGasForAlternativeFeeCurrency != nil {
+		celo.BlockchainParameters.IntrinsicGasForAlternativeFeeCurrencyValue = fixture.IntrinsicGasForAlternativeFeeCurrency
+	}
+	if fixture.MinimumClientVersion != nil {
+		celo.BlockchainParameters.MinimumVersion = *fixture.MinimumClientVersion
+	}
+	if fixture.FeeCurrencies != nil {
+		celo.FeeCurrencyWhitelist.Whitelist = fixture.FeeCurrencies
+		for _, currency := range fixture.FeeCurrencies {
+			celo.Runner.RegisterContract(currency, celo.ERC20Token)
+		}
+	}
+	if fixture.GasPriceMinimums != nil {
+		celo.GasPriceMinimum = NewGasPriceMinimumMock()
+		for currency, value := range fixture.GasPriceMinimums {
+			if currency == (common.Address{}) {
+				currency = GoldTokenMockAddress
+			}
+			celo.GasPriceMinimum.GasPriceMinimums[currency] = value
+		}
+		celo.Registry.AddContract(config.GoldTokenRegistryId, GoldTokenMockAddress)
+		celo.Registry.AddContract(config.GasPriceMinimumRegistryId, GasPriceMinimumMockAddress)
+		celo.Runner.RegisterContract(GasPriceMinimumMockAddress, celo.GasPriceMinimum)
+	}
+	return celo
+}
+
+// RunnerFactory returns an EVMRunnerFactory handing out the mock runner.
+func (c CeloMock) RunnerFactory() vm.EVMRunnerFactory {
+	return MockEVMRunnerFactory{Runner: c.Runner}
+}
@@ -0,0 +1,34 @@
+package testutil
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/currency"
+	"github.com/celo-org/celo-blockchain/contracts/gasprice_minimum"
+	. "github.com/onsi/gomega"
+)
+
+func TestFixtureWorks(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fixture, err := LoadSystemContractsFixture("testdata/two_currencies.json")
+	g.Expect(err).NotTo(HaveOccurred())
+	celo := NewCeloMockFromFixture(fixture)
+	runner := celo.RunnerFactory().NewEVMRunner(nil, nil)
+
+	g.Expect(blockchain_parameters.GetBlockGasLimit(runner)).To(Equal(uint64(35000000)))
+	g.Expect(blockchain_parameters.GetIntrinsicGasForAlternativeFeeCurrencyOrDefault(runner)).To(Equal(uint64(50000)))
+	g.Expect(blockchain_parameters.GetMinimumClientVersion(runner)).To(Equal(&config.VersionInfo{Major: 1, Minor: 8, Patch: 0}))
+
+	cusd := common.HexToAddress("0x765de816845861e75a25fca122bb6898b8b1282a")
+	ceur := common.HexToAddress("0xd8763cba276a3738e6de85b4b3bf5fded6d6ca73")
+	g.Expect(currency.CurrencyWhitelist(runner)).To(Equal([]common.Address{cusd, ceur}))
+
+	g.Expect(gasprice_minimum.GetGasPriceMinimum(runner, nil)).To(Equal(big.NewInt(5000000000)))
+	g.Expect(gasprice_minimum.GetGasPriceMinimum(runner, &cusd)).To(Equal(big.NewInt(2500000000)))
+	g.Expect(gasprice_minimum.GetGasPriceMinimum(runner, &ceur)).To(Equal(celo.GasPriceMinimum.DefaultGasPriceMinimum))
+}
@@ -41,19 +41,24 @@ func resolveAddressForCall(caller vm.EVMRunner, registryId common.Hash, method s
 	contractAddress, err := GetRegisteredAddress(caller, registryId)
 
 	if err != nil {
-		hexRegistryId := hexutil.Encode(registryId[:])
-		if err == ErrSmartContractNotDeployed {
-			log.Debug("Contract not yet registered", "function", method, "registryId", hexRegistryId)
-		} else if err == ErrRegistryContractNotDeployed {
-			log.Debug("Registry contract not yet deployed", "function", method, "registryId", hexRegistryId)
-		} else {
-			log.Error("Error in getting registered address", "function", method, "registryId", hexRegistryId, "err", err)
-		}
+		logResolveError(registryId, method, err)
 		return common.ZeroAddress, err
 	}
 	return contractAddress, nil
 }
 
+// logResolveError logs the failure of the registry lookup of the contract of a method
+func logResolveError(registryId common.Hash, method string, err error) {
+	hexRegistryId := hexutil.Encode(registryId[:])
+	if err == ErrSmartContractNotDeployed {
+		log.Debug("Contract not yet registered", "function", method, "registryId", hexRegistryId)
+	} else if err == ErrRegistryContractNotDeployed {
+		log.Debug("Registry contract not yet deployed", "function", method, "registryId", hexRegistryId)
+	} else {
+		log.Error("Error in getting registered address", "function", method, "registryId", hexRegistryId, "err", err)
+	}
+}
+
 // noopResolver returns a address resolver function that always resolve to the same address
 func noopResolver(addr common.Address) func(vm.EVMRunner) (common.Address, error) {
 	return func(e vm.EVMRunner) (common.Address, error) { return addr, nil }
@@ -0,0 +1,113 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+const (
+	// AccountTxSectionSize is the number of blocks in an account transaction
+	// index section. Sections are only indexed once complete, this one is small
+	// enough for the index to follow the head closely.
+	AccountTxSectionSize = 32
+
+	// accountTxConfirms is the number of blocks after which a section is indexed.
+	// Istanbul blocks are final, one is enough to be past the head updates.
+	accountTxConfirms = 1
+
+	// accountTxThrottling is the time to wait between processing two consecutive
+	// index sections, to avoid disk overload when indexing a synced chain.
+	accountTxThrottling = 10 * time.Millisecond
+)
+
+// AccountTxIndexer implements a core.ChainIndexer, recording the transactions
+// of the canonical chain under the accounts which sent and received them, the
+// contracts they created being their recipients.
+type AccountTxIndexer struct {
+	db     ethdb.Database
+	config *params.ChainConfig
+	batch  ethdb.Batch
+}
+
+// NewAccountTxIndexer returns a chain indexer recording the transactions of the
+// canonical chain by sender and recipient.
+func NewAccountTxIndexer(db ethdb.Database, config *params.ChainConfig, fullChainDownloaded bool) *ChainIndexer {
+	backend := &AccountTxIndexer{db: db, config: config}
+	table := rawdb.NewTable(db, "account-tx-index-")
+
+	return NewChainIndexer(db, table, backend, AccountTxSectionSize, accountTxConfirms, accountTxThrottling, "accounttx", fullChainDownloaded)
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new section.
+func (a *AccountTxIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	a.batch = a.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, recording the transactions of a
+// block under their sender and recipient.
+func (a *AccountTxIndexer) Process(ctx context.Context, header *types.Header) error {
+	hash, number := header.Hash(), header.Number.Uint64()
+	body := rawdb.ReadBody(a.db, hash, number)
+	if body == nil {
+		return fmt.Errorf("block %d body not found", number)
+	}
+	signer := types.MakeSigner(a.config, header.Number)
+	for i, tx := range body.Transactions {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return fmt.Errorf("block %d transaction %d: %w", number, i, err)
+		}
+		to := tx.To()
+		if to == nil {
+			created := crypto.CreateAddress(from, tx.Nonce())
+			to = &created
+		}
+		entry := &rawdb.AccountTransaction{Number: number, Index: uint64(i), Hash: tx.Hash(), BlockHash: hash}
+		if from == *to {
+			entry.Sender, entry.Recipient = true, true
+			rawdb.WriteAccountTransaction(a.batch, from, entry)
+			continue
+		}
+		sent, received := *entry, *entry
+		sent.Sender, received.Recipient = true, true
+		rawdb.WriteAccountTransaction(a.batch, from, &sent)
+		rawdb.WriteAccountTransaction(a.batch, *to, &received)
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, storing the transactions of the
+// section.
+func (a *AccountTxIndexer) Commit() error {
+	return a.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (a *AccountTxIndexer) Prune(threshold uint64) error {
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+func TestAccountTxIndexer(t *testing.T) {
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		indexer  = &AccountTxIndexer{db: db, config: params.TestChainConfig}
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		receiver = common.Address{2}
+		created  = crypto.CreateAddress(sender, 2)
+		header   = &types.Header{Number: big.NewInt(1)}
+		signer   = types.MakeSigner(params.TestChainConfig, header.Number)
+	)
+	// A transfer, a transfer to self and a contract creation
+	var txs types.Transactions
+	for _, tx := range []*types.Transaction{
+		types.NewTransaction(0, receiver, big.NewInt(1), 21000, big.NewInt(1), nil),
+		types.NewTransaction(1, sender, big.NewInt(1), 21000, big.NewInt(1), nil),
+		types.NewContractCreation(2, big.NewInt(0), 200000, big.NewInt(1), []byte{1}),
+	} {
+		signed, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		txs = append(txs, signed)
+	}
+	rawdb.WriteBody(db, header.Hash(), 1, &types.Body{Transactions: txs, Randomness: &types.EmptyRandomness, EpochSnarkData: &types.EmptyEpochSnarkData})
+
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset: %v", err)
+	}
+	if err := indexer.Process(context.Background(), header); err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if err := indexer.Process(context.Background(), &types.Header{Number: big.NewInt(2)}); err == nil {
+		t.Fatal("processed a block without body")
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	entry := func(index int, sent, received bool) *rawdb.AccountTransaction {
+		return &rawdb.AccountTransaction{Number: 1, Index: uint64(index), Hash: txs[index].Hash(), BlockHash: header.Hash(), Sender: sent, Recipient: received}
+	}
+	for account, want := range map[common.Address][]*rawdb.AccountTransaction{
+		sender:   {entry(0, true, false), entry(1, true, true), entry(2, true, false)},
+		receiver: {entry(0, false, true)},
+		created:  {entry(2, false, true)},
+	} {
+		if have := rawdb.ReadAccountTransactions(db, account, 0, 0, 10, 10); !reflect.DeepEqual(have, want) {
+			t.Errorf("account %x transactions mismatch: have %v, want %v", account, have, want)
+		}
+	}
+}
@@ -138,7 +138,7 @@ func benchInsertChain(b *testing.B, disk bool, gen func(int, *BlockGen)) {
 			b.Fatalf("cannot create temporary directory: %v", err)
 		}
 		defer os.RemoveAll(dir)
-		db, err = rawdb.NewLevelDBDatabase(dir, 128, 128, "", false)
+		db, err = rawdb.NewLevelDBDatabase(dir, 128, 128, "", false, 0)
 		if err != nil {
 			b.Fatalf("cannot create temporary database: %v", err)
 		}
@@ -234,7 +234,7 @@ func benchWriteChain(b *testing.B, full bool, count uint64) {
 		if err != nil {
 			b.Fatalf("cannot create temporary directory: %v", err)
 		}
-		db, err := rawdb.NewLevelDBDatabase(dir, 128, 1024, "", false)
+		db, err := rawdb.NewLevelDBDatabase(dir, 128, 1024, "", false, 0)
 		if err != nil {
 			b.Fatalf("error opening database at %v: %v", dir, err)
 		}
@@ -251,7 +251,7 @@ func benchReadChain(b *testing.B, full bool, count uint64) {
 	}
 	defer os.RemoveAll(dir)
 
-	db, err := rawdb.NewLevelDBDatabase(dir, 128, 1024, "", false)
+	db, err := rawdb.NewLevelDBDatabase(dir, 128, 1024, "", false, 0)
 	if err != nil {
 		b.Fatalf("error opening database at %v: %v", dir, err)
 	}
@@ -262,7 +262,7 @@ func benchReadChain(b *testing.B, full bool, count uint64) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		db, err := rawdb.NewLevelDBDatabase(dir, 128, 1024, "", false)
+		db, err := rawdb.NewLevelDBDatabase(dir, 128, 1024, "", false, 0)
 		if err != nil {
 			b.Fatalf("error opening database at %v: %v", dir, err)
 		}
@@ -24,7 +24,8 @@ import (
 
 // AddBlockReceipt checks whether logs were emitted by the core contract calls made as part
 // of block processing outside of transactions.  If there are any, it creates a receipt for
-// them (the so-called "block receipt") and appends it to receipts
+// them (the so-called "block receipt") and appends it to receipts. The synthetic logs of
+// the receipts are then numbered after all the other logs of the block.
 func AddBlockReceipt(receipts types.Receipts, statedb *state.StateDB, blockHash common.Hash) types.Receipts {
 	blockLogs := statedb.GetLogs(common.Hash{}, blockHash)
 	if len(blockLogs) > 0 {
@@ -38,5 +39,6 @@ func AddBlockReceipt(receipts types.Receipts, statedb *state.StateDB, blockHash
 		}
 		receipts = append(receipts, receipt)
 	}
+	receipts.NumberSyntheticLogs()
 	return receipts
 }
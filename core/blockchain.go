@@ -134,6 +134,7 @@ type CacheConfig struct {
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	InternalTransfers   bool          // Whether to store the CELO transfers made by the blocks processed
 
 	SnapshotWait bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
@@ -195,24 +196,27 @@ type BlockChain struct {
 	currentBlock     atomic.Value // Current head of the block chain
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
-	stateCache    state.Database // State database to reuse between imports (contains state cache)
-	bodyCache     *lru.Cache     // Cache for the most recent block bodies
-	bodyRLPCache  *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
-	receiptsCache *lru.Cache     // Cache for the most recent receipts per block
-	blockCache    *lru.Cache     // Cache for the most recent entire blocks
-	txLookupCache *lru.Cache     // Cache for the most recent transaction lookup data.
-	futureBlocks  *lru.Cache     // future blocks are blocks added for later processing
+	stateCache    state.Database           // State database to reuse between imports (contains state cache)
+	bodyCache     *lru.Cache               // Cache for the most recent block bodies
+	bodyRLPCache  *lru.Cache               // Cache for the most recent block bodies in RLP encoded format
+	receiptsCache *lru.Cache               // Cache for the most recent receipts per block
+	blockCache    *lru.Cache               // Cache for the most recent entire blocks
+	txLookupCache *lru.Cache               // Cache for the most recent transaction lookup data.
+	futureBlocks  *lru.Cache               // future blocks are blocks added for later processing
+	governance    *GovernanceCache         // Governance parameters of the latest blocks
+	sysCtxCache   *SysContractCallCtxCache // System call contexts of the latest blocks
 
 	wg            sync.WaitGroup //
 	quit          chan struct{}  // shutdown signal, closed in Stop.
 	running       int32          // 0 if chain is running, 1 when stopped
 	procInterrupt int32          // interrupt signaler for block processing
 
-	engine     consensus.Engine
-	validator  Validator // Block and state validator interface
-	prefetcher Prefetcher
-	processor  Processor // Block transaction processor interface
-	vmConfig   vm.Config
+	engine        consensus.Engine
+	validator     Validator // Block and state validator interface
+	prefetcher    Prefetcher
+	sysPrefetcher *SysContractPrefetcher // Pre-loads the system contract state of the next block
+	processor     Processor              // Block transaction processor interface
+	vmConfig      vm.Config
 
 	shouldPreserve func(*types.Block) bool // Function used to determine whether should preserve the given block.
 }
@@ -255,7 +259,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	}
 	bc.validator = NewBlockValidator(chainConfig, bc, engine)
 	bc.prefetcher = newStatePrefetcher(chainConfig, bc, engine)
+	bc.sysPrefetcher = newSysContractPrefetcher()
 	bc.processor = NewStateProcessor(chainConfig, bc, engine)
+	bc.governance = NewGovernanceCache(bc)
+	bc.sysCtxCache = NewSysContractCallCtxCache(bc.chainConfig, bc)
 
 	var err error
 	bc.hc, err = NewHeaderChain(db, chainConfig, engine, bc.insertStopped)
@@ -286,6 +293,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	bc.replayRandomnessJournal()
 
 	// Make sure the state associated with the block is available
 	head := bc.CurrentBlock()
@@ -388,6 +396,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	bc.wg.Add(1)
 	go bc.futureBlocksLoop()
 
+	// Start mirroring the governance parameters of the chain head.
+	bc.wg.Add(1)
+	go bc.governanceLoop()
+
 	// Start tx indexer/unindexer.
 	if txLookupLimit != nil {
 		bc.txLookupLimit = *txLookupLimit
@@ -718,6 +730,12 @@ func (bc *BlockChain) Processor() Processor {
 	return bc.processor
 }
 
+// SysContractPrefetcher returns the prefetcher of the system contract state read
+// by every block.
+func (bc *BlockChain) SysContractPrefetcher() *SysContractPrefetcher {
+	return bc.sysPrefetcher
+}
+
 // State returns a new mutable state based on the current HEAD block.
 func (bc *BlockChain) State() (*state.StateDB, error) {
 	return bc.StateAt(bc.CurrentBlock().Root())
@@ -728,6 +746,72 @@ func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return state.New(root, bc.stateCache, bc.snaps)
 }
 
+// PinState keeps the state of the canonical block with the given number from
+// pruning, flushing it to disk if it's only held in memory. States already
+// pruned can't be pinned.
+func (bc *BlockChain) PinState(number uint64) (rawdb.PinnedState, error) {
+	if !bc.chainmu.TryLock() {
+		return rawdb.PinnedState{}, errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	header := bc.GetHeaderByNumber(number)
+	if header == nil {
+		return rawdb.PinnedState{}, fmt.Errorf("block #%d not found", number)
+	}
+	pinned := rawdb.PinnedState{Number: number, Hash: header.Hash(), Root: header.Root}
+	states := rawdb.ReadPinnedStates(bc.db)
+	for _, state := range states {
+		if state == pinned {
+			return pinned, nil
+		}
+	}
+	if !bc.HasState(header.Root) {
+		return rawdb.PinnedState{}, fmt.Errorf("state of block #%d is not available", number)
+	}
+	if !bc.cacheConfig.TrieDirtyDisabled {
+		if err := bc.stateCache.TrieDB().Commit(header.Root, false, nil); err != nil {
+			return rawdb.PinnedState{}, err
+		}
+	}
+	states = append(states, pinned)
+	sort.Slice(states, func(i, j int) bool { return states[i].Number < states[j].Number })
+	rawdb.WritePinnedStates(bc.db, states)
+
+	log.Info("Pinned historical state", "number", number, "hash", pinned.Hash, "root", pinned.Root)
+	return pinned, nil
+}
+
+// UnpinState lets the pinned state of the block with the given number be pruned
+// again, and reports whether it was pinned.
+func (bc *BlockChain) UnpinState(number uint64) (bool, error) {
+	if !bc.chainmu.TryLock() {
+		return false, errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	var (
+		states = rawdb.ReadPinnedStates(bc.db)
+		kept   = states[:0]
+	)
+	for _, state := range states {
+		if state.Number != number {
+			kept = append(kept, state)
+		}
+	}
+	if len(kept) == len(states) {
+		return false, nil
+	}
+	rawdb.WritePinnedStates(bc.db, kept)
+	log.Info("Unpinned historical state", "number", number)
+	return true, nil
+}
+
+// PinnedStates returns the states kept from pruning, sorted by block number.
+func (bc *BlockChain) PinnedStates() []rawdb.PinnedState {
+	return rawdb.ReadPinnedStates(bc.db)
+}
+
 // StateCache returns the caching database underpinning the blockchain instance.
 func (bc *BlockChain) StateCache() state.Database {
 	return bc.stateCache
@@ -1560,9 +1644,12 @@ func (bc *BlockChain) insertPreprocessedBlock(block *types.Block, receipts []*ty
 	rawdb.WriteBlock(blockBatch, block)
 	rawdb.WriteReceipts(blockBatch, block.Hash(), block.NumberU64(), receipts)
 	rawdb.WritePreimages(blockBatch, state.Preimages())
+	if bc.cacheConfig.InternalTransfers {
+		rawdb.WriteInternalTransfers(blockBatch, block.Hash(), block.NumberU64(), state.Transfers())
+	}
 	if (randomCommitment != common.Hash{}) {
-		// Note that the random commitment cache entry is never transferred over to the freezer,
-		// unlike all of the other saved data within this batch write
+		// Note that the random commitment cache entry is moved to the freezer along with the
+		// block, keyed by block number instead of by commitment
 		rawdb.WriteRandomCommitmentCache(blockBatch, randomCommitment, block.ParentHash())
 	}
 	if err := blockBatch.Write(); err != nil {
@@ -1920,6 +2007,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 		}
 		// Enable prefetching to pull in trie node paths while processing transactions
 		statedb.StartPrefetcher("chain")
+		bc.sysPrefetcher.Prefetch(statedb)
 		activeState = statedb
 		// If we have a followup block, run that against the current state to pre-cache
 		// transactions and probabilistically some of the account/storage trie nodes.
@@ -2301,6 +2389,13 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	} else {
 		log.Error("Impossible reorg, please file an issue", "oldnum", oldBlock.Number(), "oldhash", oldBlock.Hash(), "newnum", newBlock.Number(), "newhash", newBlock.Hash())
 	}
+	// The system call contexts made on the dropped blocks won't be asked again
+	dropped := make([]common.Hash, len(oldChain))
+	for i, block := range oldChain {
+		dropped[i] = block.Hash()
+	}
+	bc.sysCtxCache.Invalidate(dropped)
+
 	// Insert the new chain(except the head block(reverse order)),
 	// taking care of the proper incremental order.
 	for i := len(newChain) - 1; i >= 1; i-- {
@@ -2624,11 +2719,33 @@ func (bc *BlockChain) SubscribeBlockProcessingEvent(ch chan<- bool) event.Subscr
 	return bc.scope.Track(bc.blockProcFeed.Subscribe(ch))
 }
 
+// replayRandomnessJournal restores the randomness cache entries of the journaled
+// commitments, which are missing if the node stopped before importing the blocks
+// it built with them.
+func (bc *BlockChain) replayRandomnessJournal() {
+	var restored int
+	for _, entry := range rawdb.ReadAllRandomCommitmentJournal(bc.db) {
+		if (rawdb.ReadRandomCommitmentCache(bc.db, entry.Commitment) == common.Hash{}) {
+			rawdb.WriteRandomCommitmentCache(bc.db, entry.Commitment, entry.ParentHash)
+			restored++
+		}
+	}
+	if restored > 0 {
+		log.Info("Restored randomness cache from the journal", "entries", restored)
+	}
+}
+
 // RecoverRandomnessCache will do a search for the block that was used to generate the given commitment.
-// Specifically, it will find the block that this node authored and that block's parent hash is used to
+// Commitments found in the randomness journal are restored from it directly. Otherwise, it will find
+// the block that this node authored and that block's parent hash is used to
 // created the commitment.  The search is a reverse iteration of the node's local chain starting at
 // the block where it's hash is the given commitmentBlockHash.
 func (bc *BlockChain) RecoverRandomnessCache(commitment common.Hash, commitmentBlockHash common.Hash) error {
+	// The journal knows the commitments made by this node, no need to search for them
+	if entry := rawdb.ReadRandomCommitmentJournal(bc.db, commitment); entry != nil {
+		rawdb.WriteRandomCommitmentCache(bc.db, commitment, entry.ParentHash)
+		return nil
+	}
 	istEngine, isIstanbul := bc.engine.(consensus.Istanbul)
 	if !isIstanbul {
 		return nil
@@ -2641,12 +2758,18 @@ func (bc *BlockChain) RecoverRandomnessCache(commitment common.Hash, commitmentB
 	for {
 		blockHeader := bc.GetHeaderByHash(blockHashIter)
 
-		// We got to the genesis block, so search didn't find the latest
-		// block authored by this validator.
-		if blockHeader.Number.Uint64() == 0 {
+		// We got to the genesis block (or to the start of a pruned chain), so
+		// search didn't find the latest block authored by this validator.
+		if blockHeader == nil || blockHeader.Number.Uint64() == 0 {
 			return errCommitmentNotFound
 		}
 
+		// The frozen blocks authored by this validator have their parent hashes in the freezer
+		if frozen := rawdb.ReadAncientRandomCommitment(bc.db, blockHeader.Number.Uint64()); frozen != (common.Hash{}) && frozen == blockHeader.ParentHash {
+			parentHash = frozen
+			break
+		}
+
 		blockAuthor, err := istEngine.Author(blockHeader)
 		if err != nil {
 			log.Error("Error is retrieving block author", "block number", blockHeader.Number.Uint64(), "block hash", blockHeader.Hash(), "error", err)
@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -1591,6 +1592,57 @@ func TestLargeReorgTrieGC(t *testing.T) {
 	}
 }
 
+// Tests that a pinned historical state is flushed out of the garbage collected
+// memory cache, and remains available once the chain moves past it.
+func TestPinState(t *testing.T) {
+	engine := mockEngine.NewFaker()
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := new(Genesis).MustCommit(db)
+	blocks, _ := GenerateChain(params.IstanbulTestChainConfig, genesis, engine, db, 2*TriesInMemory, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{1}) })
+
+	diskdb := rawdb.NewMemoryDatabase()
+	new(Genesis).MustCommit(diskdb)
+
+	chain, err := NewBlockChain(diskdb, nil, params.IstanbulTestChainConfig, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[:10]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	pinned, err := chain.PinState(5)
+	if err != nil {
+		t.Fatalf("failed to pin state: %v", err)
+	}
+	if pinned.Root != blocks[4].Root() || pinned.Hash != blocks[4].Hash() {
+		t.Fatalf("pinned state mismatch: have %x/%x, want %x/%x", pinned.Hash, pinned.Root, blocks[4].Hash(), blocks[4].Root())
+	}
+	if _, err := chain.InsertChain(blocks[10:]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if !chain.HasState(blocks[4].Root()) {
+		t.Errorf("pinned state was pruned")
+	}
+	if chain.HasState(blocks[5].Root()) {
+		t.Errorf("unpinned state was not pruned")
+	}
+	if _, err := chain.PinState(6); err == nil {
+		t.Errorf("pinned a pruned state")
+	}
+	if states := chain.PinnedStates(); len(states) != 1 || states[0] != pinned {
+		t.Errorf("pinned states mismatch: have %v, want [%v]", states, pinned)
+	}
+	if ok, err := chain.UnpinState(5); !ok || err != nil {
+		t.Errorf("failed to unpin state: %v, %v", ok, err)
+	}
+	if states := chain.PinnedStates(); len(states) != 0 {
+		t.Errorf("pinned states left: %v", states)
+	}
+}
+
 func TestBlockchainRecovery(t *testing.T) {
 	// Configure and generate a sample block chain
 	var (
@@ -3361,3 +3413,272 @@ func TestEIP1559TransitionPreGingerbread(t *testing.T) {
 		t.Fatalf("sender paid fee incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+// Tests that the randomness cache entries of journaled commitments are restored
+// when the chain is opened, and that the journal is used to recover cache misses.
+func TestRandomnessJournalReplay(t *testing.T) {
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		_        = new(Genesis).MustCommit(db)
+		restored = &rawdb.RandomCommitmentJournalEntry{Commitment: common.Hash{1}, ParentHash: common.Hash{2}, Randomness: common.Hash{3}}
+		cached   = &rawdb.RandomCommitmentJournalEntry{Commitment: common.Hash{4}, ParentHash: common.Hash{5}, Randomness: common.Hash{6}}
+	)
+	rawdb.WriteRandomCommitmentJournal(db, restored)
+	rawdb.WriteRandomCommitmentJournal(db, cached)
+	rawdb.WriteRandomCommitmentCache(db, cached.Commitment, cached.ParentHash)
+
+	chain, err := NewBlockChain(db, nil, params.IstanbulTestChainConfig, mockEngine.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	for _, entry := range []*rawdb.RandomCommitmentJournalEntry{restored, cached} {
+		if have := rawdb.ReadRandomCommitmentCache(db, entry.Commitment); have != entry.ParentHash {
+			t.Errorf("commitment %x: cached parent hash mismatch: have %x, want %x", entry.Commitment, have, entry.ParentHash)
+		}
+	}
+	// Entries lost afterwards are recovered from the journal, without searching the chain
+	missed := &rawdb.RandomCommitmentJournalEntry{Commitment: common.Hash{7}, ParentHash: common.Hash{8}, Randomness: common.Hash{9}}
+	rawdb.WriteRandomCommitmentJournal(db, missed)
+	if err := chain.RecoverRandomnessCache(missed.Commitment, chain.CurrentBlock().Hash()); err != nil {
+		t.Fatalf("failed to recover randomness cache: %v", err)
+	}
+	if have := rawdb.ReadRandomCommitmentCache(db, missed.Commitment); have != missed.ParentHash {
+		t.Errorf("recovered parent hash mismatch: have %x, want %x", have, missed.ParentHash)
+	}
+}
+
+func TestInternalTransfers(t *testing.T) {
+	var (
+		engine       = mockEngine.NewFaker()
+		db           = rawdb.NewMemoryDatabase()
+		key, _       = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address      = crypto.PubkeyToAddress(key.PublicKey)
+		recipient    = common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+		destructed   = common.HexToAddress("0x000000000000000000000000000000000000cccc")
+		beneficiary  = common.HexToAddress("0x000000000000000000000000000000000000dddd")
+		destructCode = append(append([]byte{byte(vm.PUSH20)}, beneficiary.Bytes()...), byte(vm.SELFDESTRUCT))
+		gspec        = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				address:    {Balance: big.NewInt(1000000000000000)},
+				destructed: {Code: destructCode, Balance: big.NewInt(5)},
+			},
+		}
+	)
+	genesis := gspec.MustCommit(db)
+	signer := types.LatestSigner(gspec.Config)
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, db, 1, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+		for _, tx := range []*types.Transaction{
+			types.NewTransaction(0, recipient, big.NewInt(1000), params.TxGas, b.MinimumGasPrice(nil), nil),
+			types.NewTransaction(1, destructed, big.NewInt(0), 100000, b.MinimumGasPrice(nil), nil),
+		} {
+			signed, err := types.SignTx(tx, signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign: %v", err)
+			}
+			b.AddTx(signed)
+		}
+	})
+
+	diskdb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(diskdb)
+	cacheConfig := *defaultCacheConfig
+	cacheConfig.InternalTransfers = true
+	chain, err := NewBlockChain(diskdb, &cacheConfig, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+
+	transfers, ok := rawdb.ReadInternalTransfers(diskdb, blocks[0].Hash(), 1)
+	if !ok {
+		t.Fatal("internal transfers not stored")
+	}
+	var calls []*types.InternalTransfer
+	for i, transfer := range transfers {
+		if transfer.Index != uint(i) {
+			t.Errorf("transfer %d index mismatch: have %d", i, transfer.Index)
+		}
+		switch transfer.Kind {
+		case types.TransferFee:
+			if transfer.From != address {
+				t.Errorf("fee transfer %d from %x, want %x", i, transfer.From, address)
+			}
+		default:
+			calls = append(calls, transfer)
+		}
+	}
+	txs := blocks[0].Transactions()
+	want := []*types.InternalTransfer{
+		{Kind: types.TransferCall, From: address, To: recipient, Value: big.NewInt(1000), TxHash: txs[0].Hash(), TxIndex: 0},
+		{Kind: types.TransferSelfDestruct, From: destructed, To: beneficiary, Value: big.NewInt(5), TxHash: txs[1].Hash(), TxIndex: 1},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("transfers mismatch: have %v, want %v", calls, want)
+	}
+	for i := range want {
+		have := *calls[i]
+		have.Index = 0
+		if !reflect.DeepEqual(&have, want[i]) {
+			t.Errorf("transfer %d mismatch: have %+v, want %+v", i, have, want[i])
+		}
+	}
+}
+
+func TestSyntheticFeeLogs(t *testing.T) {
+	var (
+		engine  = mockEngine.NewFaker()
+		db      = rawdb.NewMemoryDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000000000)}},
+		}
+	)
+	genesis := gspec.MustCommit(db)
+	signer := types.LatestSigner(gspec.Config)
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, db, 1, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+		tx, err := types.SignTx(types.NewTransaction(0, common.Address{2}, big.NewInt(1000), 30000, new(big.Int).Add(b.MinimumGasPrice(nil), big.NewInt(params.GWei)), nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		b.AddTx(tx)
+	})
+
+	// The blocks were generated without the synthetic logs, which must not change
+	// their consensus fields
+	diskdb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(diskdb)
+	chain, err := NewBlockChain(diskdb, nil, gspec.Config, engine, vm.Config{SyntheticFeeLogs: true}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+
+	receipts := chain.GetReceiptsByHash(blocks[0].Hash())
+	var debited, credited *big.Int
+	credited = new(big.Int)
+	for _, log := range receipts[0].Logs {
+		if !log.Synthetic || log.Address != types.FeeLogAddress {
+			t.Fatalf("unexpected log %+v", log)
+		}
+		if log.Topics[1] != common.BytesToHash(address.Bytes()) || log.Topics[len(log.Topics)-1] != (common.Hash{}) {
+			t.Errorf("fee log %d sender or currency mismatch: have %v", log.Index, log.Topics)
+		}
+		amount := new(big.Int).SetBytes(log.Data)
+		switch log.Topics[0] {
+		case types.GasFeeDebitedTopic:
+			debited = amount
+		case types.GasFeeCreditedTopic:
+			credited.Add(credited, amount)
+		default:
+			t.Errorf("unexpected fee log topic %x", log.Topics[0])
+		}
+	}
+	if want := new(big.Int).Mul(big.NewInt(30000), blocks[0].Transactions()[0].GasPrice()); debited == nil || debited.Cmp(want) != 0 {
+		t.Fatalf("debited fee mismatch: have %v, want %v", debited, want)
+	}
+	if credited.Cmp(debited) != 0 {
+		t.Errorf("credited fees mismatch: have %v, want %v", credited, debited)
+	}
+}
+
+// Tests that the synthetic fee logs don't change the indices of the contract logs,
+// neither when the blocks are processed nor when their receipts are read back.
+func TestSyntheticFeeLogsIndices(t *testing.T) {
+	var (
+		engine  = mockEngine.NewFaker()
+		db      = rawdb.NewMemoryDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000000000)}},
+		}
+	)
+	genesis := gspec.MustCommit(db)
+	signer := types.LatestSigner(gspec.Config)
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, db, 2, func(i int, b *BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, err := types.SignTx(types.NewContractCreation(b.TxNonce(address), new(big.Int), 100000, new(big.Int).Add(b.MinimumGasPrice(nil), big.NewInt(params.GWei)), logCode), signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign: %v", err)
+			}
+			b.AddTx(tx)
+		}
+	})
+
+	// insert imports the blocks into a new chain, returning the logs it emitted
+	// and the logs of the receipts it stored
+	insert := func(syntheticFeeLogs bool) (emitted, stored []*types.Log) {
+		diskdb := rawdb.NewMemoryDatabase()
+		gspec.MustCommit(diskdb)
+		chain, err := NewBlockChain(diskdb, nil, gspec.Config, engine, vm.Config{SyntheticFeeLogs: syntheticFeeLogs}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create tester chain: %v", err)
+		}
+		defer chain.Stop()
+		logsCh := make(chan []*types.Log, len(blocks))
+		sub := chain.SubscribeLogsEvent(logsCh)
+		defer sub.Unsubscribe()
+		if n, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+		}
+		for range blocks {
+			emitted = append(emitted, <-logsCh...)
+		}
+		for _, block := range blocks {
+			for _, receipt := range chain.GetReceiptsByHash(block.Hash()) {
+				stored = append(stored, receipt.Logs...)
+			}
+		}
+		return emitted, stored
+	}
+	check := func(kind string, without, with []*types.Log) {
+		var contractLogs []*types.Log
+		count := make(map[uint64]uint)
+		for _, log := range with {
+			if !log.Synthetic {
+				contractLogs = append(contractLogs, log)
+				count[log.BlockNumber]++
+			}
+		}
+		if len(contractLogs) != len(without) || len(contractLogs) == len(with) {
+			t.Fatalf("%s logs count mismatch: have %d contract logs out of %d, want %d contract logs and synthetic ones", kind, len(contractLogs), len(with), len(without))
+		}
+		for i, want := range without {
+			if have := contractLogs[i]; have.BlockNumber != want.BlockNumber || have.TxIndex != want.TxIndex || have.Index != want.Index {
+				t.Errorf("%s log %d mismatch: have block %d tx %d index %d, want block %d tx %d index %d", kind, i, have.BlockNumber, have.TxIndex, have.Index, want.BlockNumber, want.TxIndex, want.Index)
+			}
+		}
+		// The synthetic logs are numbered after the contract logs of their block
+		seen := make(map[uint64]map[uint]bool)
+		for _, log := range with {
+			if seen[log.BlockNumber] == nil {
+				seen[log.BlockNumber] = make(map[uint]bool)
+			}
+			if seen[log.BlockNumber][log.Index] {
+				t.Errorf("%s log index %d of block %d used twice", kind, log.Index, log.BlockNumber)
+			}
+			seen[log.BlockNumber][log.Index] = true
+			if log.Synthetic && log.Index < count[log.BlockNumber] {
+				t.Errorf("%s synthetic log index %d of block %d before the contract logs: have %d contract logs", kind, log.Index, log.BlockNumber, count[log.BlockNumber])
+			}
+		}
+	}
+	emitted, stored := insert(false)
+	emittedSynthetic, storedSynthetic := insert(true)
+	check("emitted", emitted, emittedSynthetic)
+	check("stored", stored, storedSynthetic)
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/math"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+// The experimental multidimensional fee market (see params.MultiFeeMarketConfig) keeps, for
+// every fee currency, a base fee multiplier applied on top of the gas price minimum and the
+// gas consumed by the currency in the block being built. Both live in the storage of
+// MultiFeeMarketAddress so that they are part of the state root. CELO is keyed by the zero
+// address, as in GasPriceMinimums.
+
+var (
+	// MultiFeeMarketAddress holds the multidimensional fee market state.
+	MultiFeeMarketAddress = common.HexToAddress("0x000000000000000000000000000000000000fee0")
+
+	// MultiFeeMarketUnit is the fixed point representation of a multiplier of one.
+	MultiFeeMarketUnit = new(big.Int).SetUint64(1e18)
+)
+
+const (
+	multiFeeMarketMultiplierSlot byte = iota
+	multiFeeMarketGasUsedSlot
+)
+
+func multiFeeMarketKey(feeCurrency *common.Address, slot byte) common.Hash {
+	var key common.Address
+	if feeCurrency != nil {
+		key = *feeCurrency
+	}
+	return crypto.Keccak256Hash(key.Bytes(), []byte{slot})
+}
+
+// AddMultiFeeMarketGasUsed accounts the gas used by a transaction paying fees in feeCurrency.
+func AddMultiFeeMarketGasUsed(statedb vm.StateDB, feeCurrency *common.Address, gas uint64) {
+	// Keep the account from being removed as empty
+	if statedb.GetNonce(MultiFeeMarketAddress) == 0 {
+		statedb.SetNonce(MultiFeeMarketAddress, 1)
+	}
+	key := multiFeeMarketKey(feeCurrency, multiFeeMarketGasUsedSlot)
+	used := statedb.GetState(MultiFeeMarketAddress, key).Big()
+	used.Add(used, new(big.Int).SetUint64(gas))
+	statedb.SetState(MultiFeeMarketAddress, key, common.BigToHash(used))
+}
+
+// GetFeeCurrencyMultiplier returns the base fee multiplier of feeCurrency, MultiFeeMarketUnit
+// if it was never set.
+func GetFeeCurrencyMultiplier(statedb vm.StateDB, feeCurrency *common.Address) *big.Int {
+	multiplier := statedb.GetState(MultiFeeMarketAddress, multiFeeMarketKey(feeCurrency, multiFeeMarketMultiplierSlot)).Big()
+	if multiplier.Sign() == 0 {
+		return new(big.Int).Set(MultiFeeMarketUnit)
+	}
+	return multiplier
+}
+
+// UpdateMultiFeeMarket moves the base fee multiplier of CELO and every given currency according
+// to the gas they consumed in the block, and resets the consumed gas for the next block.
+func UpdateMultiFeeMarket(cfg *params.MultiFeeMarketConfig, statedb vm.StateDB, currencies []common.Address, gasLimit uint64) {
+	if statedb.GetNonce(MultiFeeMarketAddress) == 0 {
+		statedb.SetNonce(MultiFeeMarketAddress, 1)
+	}
+	update := func(feeCurrency *common.Address) {
+		usedKey := multiFeeMarketKey(feeCurrency, multiFeeMarketGasUsedSlot)
+		used := statedb.GetState(MultiFeeMarketAddress, usedKey).Big()
+		multiplier := NextFeeCurrencyMultiplier(cfg, GetFeeCurrencyMultiplier(statedb, feeCurrency), used.Uint64(), gasLimit)
+		statedb.SetState(MultiFeeMarketAddress, multiFeeMarketKey(feeCurrency, multiFeeMarketMultiplierSlot), common.BigToHash(multiplier))
+		statedb.SetState(MultiFeeMarketAddress, usedKey, common.Hash{})
+	}
+	update(nil)
+	for i := range currencies {
+		update(&currencies[i])
+	}
+}
+
+// NextFeeCurrencyMultiplier returns the multiplier following one block where the currency used
+// gasUsed out of gasLimit. It follows the EIP-1559 base fee rule with the currency share of the
+// gas limit as target, and never goes below MultiFeeMarketUnit so that the gas price minimum
+// stays the floor of every currency base fee.
+func NextFeeCurrencyMultiplier(cfg *params.MultiFeeMarketConfig, multiplier *big.Int, gasUsed, gasLimit uint64) *big.Int {
+	target := gasLimit * cfg.TargetShareBps / 10000
+	if target == 0 || gasUsed == target {
+		return new(big.Int).Set(multiplier)
+	}
+	denominator := cfg.ChangeDenominator
+	if denominator == 0 {
+		denominator = params.BaseFeeChangeDenominator
+	}
+	var (
+		targetBig      = new(big.Int).SetUint64(target)
+		denominatorBig = new(big.Int).SetUint64(denominator)
+	)
+	if gasUsed > target {
+		delta := new(big.Int).Mul(multiplier, new(big.Int).SetUint64(gasUsed-target))
+		delta.Div(delta, targetBig)
+		delta = math.BigMax(delta.Div(delta, denominatorBig), common.Big1)
+		return delta.Add(multiplier, delta)
+	}
+	delta := new(big.Int).Mul(multiplier, new(big.Int).SetUint64(target-gasUsed))
+	delta.Div(delta, targetBig)
+	delta.Div(delta, denominatorBig)
+	return math.BigMax(delta.Sub(multiplier, delta), MultiFeeMarketUnit)
+}
+
+// ApplyFeeCurrencyMultiplier scales baseFee by the fixed point multiplier.
+func ApplyFeeCurrencyMultiplier(baseFee, multiplier *big.Int) *big.Int {
+	if baseFee == nil {
+		return nil
+	}
+	scaled := new(big.Int).Mul(baseFee, multiplier)
+	return scaled.Div(scaled, MultiFeeMarketUnit)
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+func TestNextFeeCurrencyMultiplier(t *testing.T) {
+	cfg := &params.MultiFeeMarketConfig{TargetShareBps: 2500, ChangeDenominator: 8}
+	unit := MultiFeeMarketUnit
+	double := new(big.Int).Mul(unit, big.NewInt(2))
+
+	testCases := []struct {
+		name       string
+		multiplier *big.Int
+		gasUsed    uint64
+		expected   *big.Int
+	}{
+		{"at target", double, 250, double},
+		// (500 - 250) / 250 / 8 = 1/8 of the multiplier
+		{"above target", double, 500, new(big.Int).Add(double, new(big.Int).Div(double, big.NewInt(8)))},
+		{"below target", double, 0, new(big.Int).Sub(double, new(big.Int).Div(double, big.NewInt(8)))},
+		{"floored at one", unit, 0, unit},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := NextFeeCurrencyMultiplier(cfg, tc.multiplier, tc.gasUsed, 1000)
+			if got.Cmp(tc.expected) != 0 {
+				t.Errorf("multiplier mismatch: have %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestUpdateMultiFeeMarket(t *testing.T) {
+	cfg := &params.MultiFeeMarketConfig{TargetShareBps: 2500, ChangeDenominator: 8}
+	cusd := common.HexToAddress("0x765DE816845861e75A25fCA122bb6898B8B1282a")
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	AddMultiFeeMarketGasUsed(statedb, &cusd, 600)
+	AddMultiFeeMarketGasUsed(statedb, &cusd, 400)
+	UpdateMultiFeeMarket(cfg, statedb, []common.Address{cusd}, 1000)
+	statedb.Finalise(true)
+
+	if !statedb.Exist(MultiFeeMarketAddress) {
+		t.Fatal("multi fee market account should not be removed as empty")
+	}
+	// cUSD used 1000, 3 times its target, while CELO used nothing and stays at the floor
+	expected := new(big.Int).Add(MultiFeeMarketUnit, new(big.Int).Div(new(big.Int).Mul(MultiFeeMarketUnit, big.NewInt(3)), big.NewInt(8)))
+	if got := GetFeeCurrencyMultiplier(statedb, &cusd); got.Cmp(expected) != 0 {
+		t.Errorf("cUSD multiplier mismatch: have %v, want %v", got, expected)
+	}
+	if got := GetFeeCurrencyMultiplier(statedb, nil); got.Cmp(MultiFeeMarketUnit) != 0 {
+		t.Errorf("CELO multiplier mismatch: have %v, want %v", got, MultiFeeMarketUnit)
+	}
+	if used := statedb.GetState(MultiFeeMarketAddress, multiFeeMarketKey(&cusd, multiFeeMarketGasUsedSlot)); used != (common.Hash{}) {
+		t.Errorf("gas used should be reset, have %v", used.Big())
+	}
+}
@@ -56,3 +56,32 @@ func (mgp MultiGasPool) PoolFor(feeCurrency *FeeCurrency) *GasPool {
 
 	return mgp.pools[*feeCurrency]
 }
+
+// Copy returns a deep copy of the pool, so that gas used from the original can be
+// rolled back by restoring the copy.
+func (mgp MultiGasPool) Copy() MultiGasPool {
+	pools := make(map[FeeCurrency]*GasPool, len(mgp.pools))
+	for currency, pool := range mgp.pools {
+		cpy := *pool
+		pools[currency] = &cpy
+	}
+	var defaultPool *GasPool
+	if mgp.defaultPool != nil {
+		cpy := *mgp.defaultPool
+		defaultPool = &cpy
+	}
+	return MultiGasPool{
+		pools:       pools,
+		defaultPool: defaultPool,
+	}
+}
+
+// Remaining returns the gas left in the pool of every configured fee currency,
+// the default pool excluded.
+func (mgp MultiGasPool) Remaining() map[FeeCurrency]uint64 {
+	remaining := make(map[FeeCurrency]uint64, len(mgp.pools))
+	for currency, pool := range mgp.pools {
+		remaining[currency] = pool.Gas()
+	}
+	return remaining
+}
@@ -137,3 +137,22 @@ func TestMultiCurrencyGasPool(t *testing.T) {
 		})
 	}
 }
+
+func TestMultiCurrencyGasPoolCopy(t *testing.T) {
+	cusd_token := common.HexToAddress("0x765DE816845861e75A25fCA122bb6898B8B1282a")
+	mgp := NewMultiGasPool(1_000, []FeeCurrency{cusd_token}, 0.9, FeeCurrencyLimitMapping{cusd_token: 0.5})
+
+	cpy := mgp.Copy()
+	if err := mgp.PoolFor(&cusd_token).SubGas(100); err != nil {
+		t.Fatalf("failed to use gas: %v", err)
+	}
+	if err := mgp.PoolFor(nil).SubGas(100); err != nil {
+		t.Fatalf("failed to use gas: %v", err)
+	}
+	if have := cpy.PoolFor(&cusd_token).Gas(); have != 500 {
+		t.Errorf("copied currency pool changed: have %d, want %d", have, 500)
+	}
+	if have := cpy.PoolFor(nil).Gas(); have != 1_000 {
+		t.Errorf("copied default pool changed: have %d, want %d", have, 1_000)
+	}
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts"
+	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+// Names of the checks run by CheckChainConsistency.
+const (
+	ForkOrderCheck            = "forkOrder"
+	GenesisCheck              = "genesis"
+	MinimumClientVersionCheck = "minimumClientVersion"
+)
+
+// knownChainConfigs maps the genesis hash of the public networks to their chain config.
+var knownChainConfigs = map[common.Hash]*params.ChainConfig{
+	params.MainnetGenesisHash:   params.MainnetChainConfig,
+	params.BaklavaGenesisHash:   params.BaklavaChainConfig,
+	params.AlfajoresGenesisHash: params.AlfajoresChainConfig,
+}
+
+// ConsistencyCheck is the outcome of a single startup consistency check.
+type ConsistencyCheck struct {
+	Name    string `json:"name"`
+	Ok      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// ConsistencyReport is the machine readable outcome of CheckChainConsistency.
+type ConsistencyReport struct {
+	GenesisHash   common.Hash        `json:"genesisHash"`
+	HeadNumber    uint64             `json:"headNumber"`
+	ClientVersion string             `json:"clientVersion"`
+	Checks        []ConsistencyCheck `json:"checks"`
+}
+
+// Failed returns the checks that did not pass.
+func (r *ConsistencyReport) Failed() []ConsistencyCheck {
+	var failed []ConsistencyCheck
+	for _, check := range r.Checks {
+		if !check.Ok {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+func (r *ConsistencyReport) add(name string, err error, okMessage string) {
+	if err != nil {
+		r.Checks = append(r.Checks, ConsistencyCheck{Name: name, Ok: false, Message: err.Error()})
+	} else {
+		r.Checks = append(r.Checks, ConsistencyCheck{Name: name, Ok: true, Message: okMessage})
+	}
+}
+
+// CheckChainConsistency verifies that the local chain config fork ordering, the genesis block
+// and the minimum client version set on chain in BlockchainParameters are consistent with
+// each other and with the running client.
+func CheckChainConsistency(bc *BlockChain) *ConsistencyReport {
+	head := bc.CurrentBlock()
+	chainConfig := bc.Config()
+	report := &ConsistencyReport{
+		GenesisHash:   bc.Genesis().Hash(),
+		HeadNumber:    head.NumberU64(),
+		ClientVersion: config.CurrentVersionInfo.String(),
+	}
+
+	report.add(ForkOrderCheck, chainConfig.CheckConfigForkOrder(), "forks are enabled in order")
+
+	genesisMessage := "custom network"
+	genesisErr := func() error {
+		known, ok := knownChainConfigs[report.GenesisHash]
+		if !ok {
+			return nil
+		}
+		genesisMessage = "matches the network chain config"
+		if chainConfig.ChainID == nil || known.ChainID.Cmp(chainConfig.ChainID) != 0 {
+			return fmt.Errorf("chain id %v does not match chain id %v of the network with genesis %s", chainConfig.ChainID, known.ChainID, report.GenesisHash.Hex())
+		}
+		if compatErr := known.CheckCompatible(chainConfig, head.NumberU64()); compatErr != nil {
+			return fmt.Errorf("chain config does not match the network with genesis %s: %w", report.GenesisHash.Hex(), compatErr)
+		}
+		return nil
+	}()
+	report.add(GenesisCheck, genesisErr, genesisMessage)
+
+	versionMessage := ""
+	versionErr := func() error {
+		vmRunner, err := bc.NewEVMRunnerForCurrentBlock()
+		if err != nil {
+			return err
+		}
+		minVersion, err := blockchain_parameters.GetMinimumClientVersion(vmRunner)
+		if errors.Is(err, contracts.ErrRegistryContractNotDeployed) || errors.Is(err, contracts.ErrSmartContractNotDeployed) {
+			versionMessage = "BlockchainParameters not deployed"
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if config.CurrentVersionInfo.Cmp(minVersion) < 0 {
+			return fmt.Errorf("client version %s is below the minimum version %s required on chain at block %d", config.CurrentVersionInfo, minVersion, head.NumberU64())
+		}
+		versionMessage = fmt.Sprintf("client version %s satisfies the minimum version %s", config.CurrentVersionInfo, minVersion)
+		return nil
+	}()
+	report.add(MinimumClientVersionCheck, versionErr, versionMessage)
+
+	return report
+}
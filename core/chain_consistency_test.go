@@ -0,0 +1,26 @@
+package core
+
+import (
+	"testing"
+
+	mockEngine "github.com/celo-org/celo-blockchain/consensus/consensustest"
+)
+
+func TestCheckChainConsistency(t *testing.T) {
+	_, blockchain, err := newCanonical(mockEngine.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	report := CheckChainConsistency(blockchain)
+	if len(report.Checks) != 3 {
+		t.Fatalf("expected 3 checks, got %d", len(report.Checks))
+	}
+	if failed := report.Failed(); len(failed) != 0 {
+		t.Errorf("expected no failed checks on a custom network without contracts, got %v", failed)
+	}
+	if report.GenesisHash != blockchain.Genesis().Hash() {
+		t.Errorf("report genesis hash mismatch: have %x, want %x", report.GenesisHash, blockchain.Genesis().Hash())
+	}
+}
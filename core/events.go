@@ -24,6 +24,51 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// TxStage is a stage of the lifecycle of a transaction, from its arrival in the
+// pool to its finalization in the chain.
+type TxStage string
+
+const (
+	TxStageQueued    TxStage = "queued"    // In the pool, not executable yet
+	TxStagePending   TxStage = "pending"   // In the pool, executable
+	TxStageProposed  TxStage = "proposed"  // In a block the local miner submitted to the consensus engine
+	TxStageSealed    TxStage = "sealed"    // In a block committed by Istanbul with the local validator
+	TxStageFinalized TxStage = "finalized" // In a block of the canonical chain, final under Istanbul
+)
+
+// TxLifecycleEvent is posted when transactions reach a stage of their lifecycle
+// that isn't notified by NewTxsEvent or ChainEvent: queued in the pool, proposed
+// or sealed in a block.
+type TxLifecycleEvent struct {
+	Stage TxStage
+	Txs   []common.Hash
+	Block *types.Block // Block the transactions are in, nil if queued
+}
+
+// PendingRemoval is the reason a transaction left the pending transactions of
+// the pool.
+type PendingRemoval string
+
+const (
+	PendingIncluded  PendingRemoval = "included"  // Nonce reached by the chain
+	PendingReplaced  PendingRemoval = "replaced"  // Replaced by a transaction bumping its price
+	PendingUnpayable PendingRemoval = "unpayable" // Balance or block gas limit too low
+	PendingDemoted   PendingRemoval = "demoted"   // Moved back to the queue
+	PendingEvicted   PendingRemoval = "evicted"   // Dropped to make room or below the price threshold
+)
+
+// PendingChange is the addition of a transaction to the pending transactions of
+// the pool, or the removal of one.
+type PendingChange struct {
+	Hash   common.Hash
+	Tx     *types.Transaction // Transaction added, nil if removed
+	Reason PendingRemoval     // Reason of the removal, empty if added
+}
+
+// PendingChangesEvent is posted with the changes of the pending transactions of
+// the pool, in the order they were made.
+type PendingChangesEvent struct{ Changes []PendingChange }
+
 // NewMinedBlockEvent is posted when a block has been imported.
 type NewMinedBlockEvent struct{ Block *types.Block }
 
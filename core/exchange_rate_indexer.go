@@ -0,0 +1,122 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/currency"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/log"
+)
+
+const (
+	// ExchangeRateSectionSize is the number of blocks in an exchange rate index
+	// section. Sections are only indexed once complete, this one is small enough
+	// for the states of their blocks to still be in memory.
+	ExchangeRateSectionSize = 32
+
+	// exchangeRateConfirms is the number of blocks after which a section is
+	// indexed. Istanbul blocks are final, one is enough to be past the head updates.
+	exchangeRateConfirms = 1
+
+	// exchangeRateThrottling is the time to wait between processing two
+	// consecutive index sections. There's none, since the states of the blocks
+	// are eventually pruned.
+	exchangeRateThrottling time.Duration = 0
+)
+
+// ExchangeRateIndexer implements a core.ChainIndexer, recording the exchange rate
+// to CELO of every whitelisted currency at every block of the canonical chain,
+// read from the state of the block. The blocks whose state is not available,
+// like the ones before a snap sync, aren't indexed.
+type ExchangeRateIndexer struct {
+	db       ethdb.Database
+	runnerAt func(header *types.Header) (vm.EVMRunner, error) // Runner on the state of a block
+	rates    map[uint64][]*rawdb.CurrencyExchangeRate
+}
+
+// NewExchangeRateIndexer returns a chain indexer recording the exchange rates of
+// the whitelisted currencies at every block of the canonical chain.
+func NewExchangeRateIndexer(db ethdb.Database, chain *BlockChain, fullChainDownloaded bool) *ChainIndexer {
+	backend := &ExchangeRateIndexer{
+		db: db,
+		runnerAt: func(header *types.Header) (vm.EVMRunner, error) {
+			state, err := chain.StateAt(header.Root)
+			if err != nil {
+				return nil, err
+			}
+			return chain.NewEVMRunner(header, state), nil
+		},
+	}
+	table := rawdb.NewTable(db, "exchange-rate-index-")
+
+	return NewChainIndexer(db, table, backend, ExchangeRateSectionSize, exchangeRateConfirms, exchangeRateThrottling, "exchangerate", fullChainDownloaded)
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new section.
+func (e *ExchangeRateIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	e.rates = make(map[uint64][]*rawdb.CurrencyExchangeRate)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, reading the exchange rates of the
+// currencies whitelisted at a block.
+func (e *ExchangeRateIndexer) Process(ctx context.Context, header *types.Header) error {
+	number := header.Number.Uint64()
+	vmRunner, err := e.runnerAt(header)
+	if err != nil {
+		log.Debug("Exchange rates not indexed, missing state", "number", number, "err", err)
+		return nil
+	}
+	// The whitelist fails before the contracts are deployed, with no currencies
+	whitelist, _ := currency.CurrencyWhitelist(vmRunner)
+	exchangeRates, err := currency.GetExchangeRates(vmRunner, whitelist)
+	if err != nil {
+		return err
+	}
+	rates := make([]*rawdb.CurrencyExchangeRate, 0, len(whitelist))
+	for i, rate := range exchangeRates {
+		rates = append(rates, &rawdb.CurrencyExchangeRate{
+			Currency:    whitelist[i],
+			Numerator:   rate.Numerator(),
+			Denominator: rate.Denominator(),
+		})
+	}
+	e.rates[number] = rates
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, storing the exchange rates of the
+// blocks of the section.
+func (e *ExchangeRateIndexer) Commit() error {
+	batch := e.db.NewBatch()
+	for number, rates := range e.rates {
+		rawdb.WriteExchangeRates(batch, number, rates)
+	}
+	return batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (e *ExchangeRateIndexer) Prune(threshold uint64) error {
+	return nil
+}
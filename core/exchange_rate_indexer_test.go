@@ -0,0 +1,84 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+)
+
+func TestExchangeRateIndexer(t *testing.T) {
+	var (
+		db            = rawdb.NewMemoryDatabase()
+		celo          = testutil.NewCeloMock()
+		sortedOracles = testutil.NewSortedOraclesMock()
+		cusd          = common.HexToAddress("0x02")
+		ceur          = common.HexToAddress("0x05")
+	)
+	celo.Registry.AddContract(config.SortedOraclesRegistryId, common.HexToAddress("0x11"))
+	celo.Runner.RegisterContract(common.HexToAddress("0x11"), sortedOracles)
+	sortedOracles.Rates[cusd] = [2]*big.Int{big.NewInt(3), big.NewInt(1)}
+	sortedOracles.Rates[ceur] = [2]*big.Int{big.NewInt(5), big.NewInt(2)}
+
+	// The state of the second block is missing
+	indexer := &ExchangeRateIndexer{
+		db: db,
+		runnerAt: func(header *types.Header) (vm.EVMRunner, error) {
+			if header.Number.Uint64() == 1 {
+				return nil, errors.New("missing trie node")
+			}
+			return celo.Runner, nil
+		},
+	}
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset: %v", err)
+	}
+	for i := uint64(0); i < 3; i++ {
+		if err := indexer.Process(context.Background(), &types.Header{Number: new(big.Int).SetUint64(i)}); err != nil {
+			t.Fatalf("failed to process block %d: %v", i, err)
+		}
+		// The rates change after the first block
+		sortedOracles.Rates[cusd] = [2]*big.Int{big.NewInt(4), big.NewInt(1)}
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	want := []*rawdb.CurrencyExchangeRate{
+		{Currency: cusd, Numerator: big.NewInt(3), Denominator: big.NewInt(1)},
+		{Currency: ceur, Numerator: big.NewInt(5), Denominator: big.NewInt(2)},
+	}
+	if have, ok := rawdb.ReadExchangeRates(db, 0); !ok || !reflect.DeepEqual(have, want) {
+		t.Errorf("block 0 exchange rates mismatch: have %v, want %v", have, want)
+	}
+	if have, ok := rawdb.ReadExchangeRates(db, 1); ok {
+		t.Errorf("block 1 without state indexed: have %v", have)
+	}
+	want[0].Numerator = big.NewInt(4)
+	if have, ok := rawdb.ReadExchangeRates(db, 2); !ok || !reflect.DeepEqual(have, want) {
+		t.Errorf("block 2 exchange rates mismatch: have %v, want %v", have, want)
+	}
+}
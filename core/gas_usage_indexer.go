@@ -0,0 +1,133 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/ethdb"
+)
+
+const (
+	// gasUsageConfirms is the number of blocks after which a section is indexed.
+	// Istanbul blocks are final, one is enough to be past the head updates.
+	gasUsageConfirms = 1
+
+	// gasUsageThrottling is the time to wait between processing two consecutive
+	// index sections, to avoid disk overload when indexing a synced chain.
+	gasUsageThrottling = 100 * time.Millisecond
+
+	// GasUsageSectionContracts is the maximum number of contracts stored for a
+	// section, those above which the most gas was used.
+	GasUsageSectionContracts = 1024
+)
+
+// GasUsageIndexer implements a core.ChainIndexer, aggregating the gas used by
+// the transactions of every section of the chain per destination contract. Plain
+// transfers, without data, aren't counted.
+type GasUsageIndexer struct {
+	db      ethdb.Database
+	section uint64
+	usage   map[common.Address]*rawdb.ContractGasUsage
+}
+
+// NewGasUsageIndexer returns a chain indexer aggregating the gas usage by contract
+// of the canonical chain, in sections of the given size, usually the epoch size.
+func NewGasUsageIndexer(db ethdb.Database, size uint64, fullChainDownloaded bool) *ChainIndexer {
+	backend := &GasUsageIndexer{db: db}
+	table := rawdb.NewTable(db, "gas-usage-index-")
+
+	return NewChainIndexer(db, table, backend, size, gasUsageConfirms, gasUsageThrottling, "gasusage", fullChainDownloaded)
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new section.
+func (g *GasUsageIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	g.section, g.usage = section, make(map[common.Address]*rawdb.ContractGasUsage)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the gas used by the
+// transactions of a block, the difference of their cumulative gas used.
+func (g *GasUsageIndexer) Process(ctx context.Context, header *types.Header) error {
+	hash, number := header.Hash(), header.Number.Uint64()
+	body := rawdb.ReadBody(g.db, hash, number)
+	if body == nil {
+		return fmt.Errorf("block %d body not found", number)
+	}
+	receipts := rawdb.ReadRawReceipts(g.db, hash, number)
+	if len(receipts) < len(body.Transactions) {
+		return fmt.Errorf("block %d receipts not found", number)
+	}
+	var cumulative uint64
+	for i, tx := range body.Transactions {
+		gasUsed := receipts[i].CumulativeGasUsed - cumulative
+		cumulative = receipts[i].CumulativeGasUsed
+
+		var contract common.Address
+		if to := tx.To(); to != nil {
+			if len(tx.Data()) == 0 {
+				continue
+			}
+			contract = *to
+		}
+		usage, ok := g.usage[contract]
+		if !ok {
+			usage = &rawdb.ContractGasUsage{Contract: contract}
+			g.usage[contract] = usage
+		}
+		usage.GasUsed += gasUsed
+		usage.Transactions++
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, storing the contracts of the
+// section by decreasing gas used.
+func (g *GasUsageIndexer) Commit() error {
+	usage := make([]*rawdb.ContractGasUsage, 0, len(g.usage))
+	for _, contract := range g.usage {
+		usage = append(usage, contract)
+	}
+	SortGasUsage(usage)
+	if len(usage) > GasUsageSectionContracts {
+		usage = usage[:GasUsageSectionContracts]
+	}
+	rawdb.WriteGasUsageSection(g.db, g.section, usage)
+	return nil
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (g *GasUsageIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// SortGasUsage sorts contracts by decreasing gas used, then by address.
+func SortGasUsage(usage []*rawdb.ContractGasUsage) {
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].GasUsed != usage[j].GasUsed {
+			return usage[i].GasUsed > usage[j].GasUsed
+		}
+		return bytes.Compare(usage[i].Contract[:], usage[j].Contract[:]) < 0
+	})
+}
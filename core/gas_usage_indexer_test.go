@@ -0,0 +1,76 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+)
+
+func TestGasUsageIndexer(t *testing.T) {
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		indexer  = &GasUsageIndexer{db: db}
+		contract = common.Address{1}
+		receiver = common.Address{2}
+	)
+	// Two blocks with a call, a transfer and a creation each, the gas used by
+	// every transaction being the increase of the cumulative gas used
+	txs := types.Transactions{
+		types.NewTransaction(0, contract, big.NewInt(0), 100000, big.NewInt(1), []byte{1}),
+		types.NewTransaction(1, receiver, big.NewInt(1), 21000, big.NewInt(1), nil),
+		types.NewContractCreation(2, big.NewInt(0), 200000, big.NewInt(1), []byte{1}),
+	}
+	receipts := types.Receipts{
+		{CumulativeGasUsed: 30000, Logs: []*types.Log{}},
+		{CumulativeGasUsed: 51000, Logs: []*types.Log{}},
+		{CumulativeGasUsed: 151000, Logs: []*types.Log{}},
+	}
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset: %v", err)
+	}
+	for i := uint64(0); i < 2; i++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(i)}
+		rawdb.WriteBody(db, header.Hash(), i, &types.Body{Transactions: txs, Randomness: &types.EmptyRandomness, EpochSnarkData: &types.EmptyEpochSnarkData})
+		rawdb.WriteReceipts(db, header.Hash(), i, receipts)
+		if err := indexer.Process(context.Background(), header); err != nil {
+			t.Fatalf("failed to process block %d: %v", i, err)
+		}
+	}
+	if err := indexer.Process(context.Background(), &types.Header{Number: big.NewInt(2)}); err == nil {
+		t.Fatal("processed a block without body")
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	want := []*rawdb.ContractGasUsage{
+		{Contract: common.Address{}, GasUsed: 200000, Transactions: 2},
+		{Contract: contract, GasUsed: 60000, Transactions: 2},
+	}
+	if have := rawdb.ReadGasUsageSection(db, 0); !reflect.DeepEqual(have, want) {
+		t.Errorf("gas usage mismatch: have %v, want %v", have, want)
+	}
+	if have := rawdb.ReadGasUsageSection(db, 1); have != nil {
+		t.Errorf("unindexed section gas usage: have %v, want nil", have)
+	}
+}
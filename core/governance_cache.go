@@ -0,0 +1,272 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts"
+	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/currency"
+	gpm "github.com/celo-org/celo-blockchain/contracts/gasprice_minimum"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// governanceCacheLimit is the number of blocks the governance parameters are
+// kept for.
+const governanceCacheLimit = 16
+
+var (
+	// governanceContracts are the system contracts the governance parameters are
+	// read from, by registry id.
+	governanceContracts = []common.Hash{
+		config.BlockchainParametersRegistryId,
+		config.FeeCurrencyWhitelistRegistryId,
+		config.GasPriceMinimumRegistryId,
+	}
+
+	// registryChanged marks the changes of the registry, among the registry ids
+	// of the system contracts whose parameters may have changed.
+	registryChanged = common.Hash{}
+
+	// gasPriceMinimumUpdatedTopic is the event of the gas price minimum update of
+	// every block, which leaves the parameters of the contract unchanged.
+	gasPriceMinimumUpdatedTopic = crypto.Keccak256Hash([]byte("GasPriceMinimumUpdated(uint256)"))
+
+	governanceHitMeter    = metrics.NewRegisteredMeter("chain/governance/hit", nil)
+	governanceUpdateMeter = metrics.NewRegisteredMeter("chain/governance/update", nil)
+	governanceReloadMeter = metrics.NewRegisteredMeter("chain/governance/reload", nil)
+)
+
+// GovernanceParams are the governance parameters of the system contracts in the
+// state of a block. They're shared by all the readers and must not be modified.
+type GovernanceParams struct {
+	Number uint64
+	Hash   common.Hash
+
+	BlockGasLimit                         uint64
+	IntrinsicGasForAlternativeFeeCurrency uint64
+	MinimumClientVersion                  *config.VersionInfo // Nil if BlockchainParameters isn't deployed
+	Whitelist                             []common.Address
+	GasPriceMinimumFloor                  *big.Int // Fallback gas price minimum if GasPriceMinimum isn't deployed
+
+	addresses map[common.Hash]common.Address // System contracts by registry id, zero if not deployed
+}
+
+// IsWhitelisted indicates if the fee currency is whitelisted, or it's the native
+// token.
+func (p *GovernanceParams) IsWhitelisted(feeCurrency *common.Address) bool {
+	if feeCurrency == nil {
+		return true
+	}
+	for _, whitelisted := range p.Whitelist {
+		if whitelisted == *feeCurrency {
+			return true
+		}
+	}
+	return false
+}
+
+// ContractAddress returns the address of a system contract the parameters are
+// read from, or the zero address if it isn't deployed.
+func (p *GovernanceParams) ContractAddress(registryId common.Hash) common.Address {
+	return p.addresses[registryId]
+}
+
+// governanceChain is the chain the governance cache reads the parameters from.
+type governanceChain interface {
+	StateAt(root common.Hash) (*state.StateDB, error)
+	NewEVMRunner(header *types.Header, state vm.StateDB) vm.EVMRunner
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+}
+
+// GovernanceCache mirrors the governance parameters of the system contracts of
+// the latest blocks. The parameters of a block are derived from the ones of its
+// parent, only the contracts that emitted logs in the block being called again.
+type GovernanceCache struct {
+	chain  governanceChain
+	params *lru.Cache // Governance parameters by block hash
+	mu     sync.Mutex // Serializes the reads of the parameters
+}
+
+// NewGovernanceCache creates an empty governance cache reading from chain.
+func NewGovernanceCache(chain governanceChain) *GovernanceCache {
+	params, _ := lru.New(governanceCacheLimit)
+	return &GovernanceCache{chain: chain, params: params}
+}
+
+// Params returns the governance parameters in the state of the block of header.
+// They're always read at that block: from the cache, updated from the parent
+// block with the logs of the block, or read from the system contracts if the
+// parent isn't cached.
+func (c *GovernanceCache) Params(header *types.Header) (*GovernanceParams, error) {
+	hash := header.Hash()
+	if params, ok := c.params.Get(hash); ok {
+		governanceHitMeter.Mark(1)
+		return params.(*GovernanceParams), nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if params, ok := c.params.Get(hash); ok {
+		governanceHitMeter.Mark(1)
+		return params.(*GovernanceParams), nil
+	}
+	var (
+		parent *GovernanceParams
+		dirty  map[common.Hash]bool
+	)
+	if cached, ok := c.params.Get(header.ParentHash); ok {
+		parent = cached.(*GovernanceParams)
+		dirty = parent.changes(c.chain.GetReceiptsByHash(hash))
+	}
+	var params *GovernanceParams
+	if parent != nil && len(dirty) == 0 {
+		governanceHitMeter.Mark(1)
+		copied := *parent
+		params = &copied
+	} else {
+		state, err := c.chain.StateAt(header.Root)
+		if err != nil {
+			return nil, err
+		}
+		vmRunner := c.chain.NewEVMRunner(header, state)
+		if parent == nil {
+			governanceReloadMeter.Mark(1)
+			params = readGovernanceParams(vmRunner, nil, nil)
+		} else {
+			governanceUpdateMeter.Mark(1)
+			params = readGovernanceParams(vmRunner, parent, dirty)
+		}
+	}
+	params.Number, params.Hash = header.Number.Uint64(), hash
+	c.params.Add(hash, params)
+	return params, nil
+}
+
+// changes returns the registry ids of the system contracts whose parameters may
+// have changed given the logs of the next block, nil if none did. Updates of
+// the registry may change them all.
+func (p *GovernanceParams) changes(receipts types.Receipts) map[common.Hash]bool {
+	var dirty map[common.Hash]bool
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			if l.Address == config.RegistrySmartContractAddress {
+				return map[common.Hash]bool{registryChanged: true}
+			}
+			for id, address := range p.addresses {
+				if address != l.Address || address == (common.Address{}) {
+					continue
+				}
+				if id == config.GasPriceMinimumRegistryId && len(l.Topics) > 0 && l.Topics[0] == gasPriceMinimumUpdatedTopic {
+					continue
+				}
+				if dirty == nil {
+					dirty = make(map[common.Hash]bool)
+				}
+				dirty[id] = true
+			}
+		}
+	}
+	return dirty
+}
+
+// readGovernanceParams reads the governance parameters of the system contracts
+// that changed since the parent parameters, or all of them if the parent is nil
+// or the registry changed.
+func readGovernanceParams(vmRunner vm.EVMRunner, parent *GovernanceParams, dirty map[common.Hash]bool) *GovernanceParams {
+	params := &GovernanceParams{addresses: make(map[common.Hash]common.Address, len(governanceContracts))}
+	if parent == nil || dirty[registryChanged] {
+		dirty = make(map[common.Hash]bool, len(governanceContracts))
+		for _, id := range governanceContracts {
+			dirty[id] = true
+		}
+		for _, id := range governanceContracts {
+			address, err := contracts.GetRegisteredAddress(vmRunner, id)
+			if err != nil && !errors.Is(err, contracts.ErrRegistryContractNotDeployed) && !errors.Is(err, contracts.ErrSmartContractNotDeployed) {
+				log.Debug("Failed to read system contract address", "id", id, "err", err)
+			}
+			params.addresses[id] = address
+		}
+	} else {
+		*params = *parent
+	}
+	if dirty[config.BlockchainParametersRegistryId] {
+		parameters := blockchain_parameters.GetParametersOrDefault(vmRunner)
+		params.BlockGasLimit = parameters.BlockGasLimit
+		params.IntrinsicGasForAlternativeFeeCurrency = parameters.IntrinsicGasForAlternativeFeeCurrency
+		params.MinimumClientVersion = parameters.MinimumClientVersion
+	}
+	if dirty[config.FeeCurrencyWhitelistRegistryId] {
+		whitelist, err := currency.CurrencyWhitelist(vmRunner)
+		if err != nil {
+			whitelist = []common.Address{}
+		}
+		params.Whitelist = whitelist
+	}
+	if dirty[config.GasPriceMinimumRegistryId] {
+		params.GasPriceMinimumFloor, _ = gpm.GetGasPriceMinimumFloor(vmRunner)
+	}
+	return params
+}
+
+// governanceProvider is implemented by the chains mirroring the governance
+// parameters of their latest blocks.
+type governanceProvider interface {
+	Governance() *GovernanceCache
+}
+
+// Governance returns the governance parameters cache of the chain.
+func (bc *BlockChain) Governance() *GovernanceCache {
+	return bc.governance
+}
+
+// governanceLoop keeps the governance parameters of the chain head in the cache,
+// so that its readers are served without calling the system contracts.
+func (bc *BlockChain) governanceLoop() {
+	defer bc.wg.Done()
+
+	heads := make(chan ChainHeadEvent, chainHeadChanSize)
+	sub := bc.chainHeadFeed.Subscribe(heads)
+	defer sub.Unsubscribe()
+
+	if _, err := bc.governance.Params(bc.CurrentHeader()); err != nil {
+		log.Debug("Failed to read governance parameters", "err", err)
+	}
+	for {
+		select {
+		case ev := <-heads:
+			if _, err := bc.governance.Params(ev.Block.Header()); err != nil {
+				log.Debug("Failed to read governance parameters", "number", ev.Block.Number(), "err", err)
+			}
+		case <-sub.Err():
+			return
+		case <-bc.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+)
+
+// governanceTestChain serves the system contracts of a celo mock, whatever the
+// block, and the receipts set by the test.
+type governanceTestChain struct {
+	celo     testutil.CeloMock
+	receipts map[common.Hash]types.Receipts
+}
+
+func (c *governanceTestChain) StateAt(root common.Hash) (*state.StateDB, error) { return nil, nil }
+
+func (c *governanceTestChain) NewEVMRunner(header *types.Header, state vm.StateDB) vm.EVMRunner {
+	return c.celo.Runner
+}
+
+func (c *governanceTestChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
+	return c.receipts[hash]
+}
+
+func TestGovernanceCache(t *testing.T) {
+	chain := &governanceTestChain{celo: testutil.NewCeloMock(), receipts: make(map[common.Hash]types.Receipts)}
+	cache := NewGovernanceCache(chain)
+
+	var (
+		parent = &types.Header{Number: big.NewInt(1)}
+		next   = func(logs ...*types.Log) *types.Header {
+			header := &types.Header{Number: new(big.Int).Add(parent.Number, common.Big1), ParentHash: parent.Hash()}
+			chain.receipts[header.Hash()] = types.Receipts{{Logs: logs}}
+			parent = header
+			return header
+		}
+		params = func(header *types.Header) *GovernanceParams {
+			params, err := cache.Params(header)
+			if err != nil {
+				t.Fatalf("failed to read governance parameters of block %d: %v", header.Number, err)
+			}
+			return params
+		}
+	)
+	if have := params(parent).BlockGasLimit; have != 20000000 {
+		t.Fatalf("block gas limit mismatch: have %d, want 20000000", have)
+	}
+	// Parameters are only read again after logs of their contract
+	chain.celo.BlockchainParameters.BlockGasLimitValue = big.NewInt(30000000)
+	if have := params(next()).BlockGasLimit; have != 20000000 {
+		t.Fatalf("block gas limit read without logs: have %d, want 20000000", have)
+	}
+	if have := params(next(&types.Log{Address: common.HexToAddress("0x03")})).BlockGasLimit; have != 20000000 {
+		t.Fatalf("block gas limit read after whitelist logs: have %d, want 20000000", have)
+	}
+	header := next(&types.Log{Address: common.HexToAddress("0x01")})
+	if have := params(header); have.BlockGasLimit != 30000000 || have.Number != header.Number.Uint64() || have.Hash != header.Hash() {
+		t.Fatalf("parameters mismatch after logs: have gas limit %d at %d, want 30000000 at %d", have.BlockGasLimit, have.Number, header.Number)
+	}
+	// Updates of the registry move the contracts
+	chain.celo.Registry.AddContract(config.BlockchainParametersRegistryId, common.HexToAddress("0x04"))
+	chain.celo.Runner.RegisterContract(common.HexToAddress("0x04"), chain.celo.BlockchainParameters)
+	header = next(&types.Log{Address: config.RegistrySmartContractAddress})
+	if have := params(header).ContractAddress(config.BlockchainParametersRegistryId); have != common.HexToAddress("0x04") {
+		t.Fatalf("contract address mismatch after registry logs: have %x, want 0x04", have)
+	}
+	// Blocks whose parent isn't cached are read in full
+	chain.celo.BlockchainParameters.BlockGasLimitValue = big.NewInt(40000000)
+	if have := params(&types.Header{Number: big.NewInt(100)}).BlockGasLimit; have != 40000000 {
+		t.Fatalf("block gas limit mismatch without parent: have %d, want 40000000", have)
+	}
+	if have := params(header).BlockGasLimit; have != 30000000 {
+		t.Fatalf("cached block gas limit mismatch: have %d, want 30000000", have)
+	}
+	if !params(header).IsWhitelisted(nil) {
+		t.Fatalf("native token not whitelisted")
+	}
+}
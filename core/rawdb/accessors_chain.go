@@ -817,6 +817,13 @@ func writeAncientBlock(op ethdb.AncientWriteOp, block *types.Block, header *type
 	if err := op.Append(freezerDifficultyTable, num, td); err != nil {
 		return fmt.Errorf("can't append block %d total difficulty: %v", num, err)
 	}
+	// Blocks synced into the freezer have no celo data stored by this node
+	if err := op.AppendRaw(freezerRandomnessTable, num, nil); err != nil {
+		return fmt.Errorf("can't append block %d randomness: %v", num, err)
+	}
+	if err := op.AppendRaw(freezerIstanbulSnapshotTable, num, nil); err != nil {
+		return fmt.Errorf("can't append block %d istanbul snapshot: %v", num, err)
+	}
 	return nil
 }
 
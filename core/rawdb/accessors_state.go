@@ -20,6 +20,7 @@ import (
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/ethdb"
 	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/rlp"
 )
 
 // ReadPreimage retrieves a single preimage of the provided hash.
@@ -94,3 +95,41 @@ func DeleteTrieNode(db ethdb.KeyValueWriter, hash common.Hash) {
 		log.Crit("Failed to delete trie node", "err", err)
 	}
 }
+
+// PinnedState is a historical state kept from pruning.
+type PinnedState struct {
+	Number uint64
+	Hash   common.Hash // Hash of the block
+	Root   common.Hash // Root of its state
+}
+
+// ReadPinnedStates retrieves the states kept from pruning, sorted by number.
+func ReadPinnedStates(db ethdb.KeyValueReader) []PinnedState {
+	blob, err := db.Get(pinnedStatesKey)
+	if err != nil || len(blob) == 0 {
+		return nil
+	}
+	var states []PinnedState
+	if err := rlp.DecodeBytes(blob, &states); err != nil {
+		log.Error("Invalid pinned states", "err", err)
+		return nil
+	}
+	return states
+}
+
+// WritePinnedStates stores the states kept from pruning.
+func WritePinnedStates(db ethdb.KeyValueWriter, states []PinnedState) {
+	if len(states) == 0 {
+		if err := db.Delete(pinnedStatesKey); err != nil {
+			log.Crit("Failed to delete pinned states", "err", err)
+		}
+		return
+	}
+	blob, err := rlp.EncodeToBytes(states)
+	if err != nil {
+		log.Crit("Failed to encode pinned states", "err", err)
+	}
+	if err := db.Put(pinnedStatesKey, blob); err != nil {
+		log.Crit("Failed to store pinned states", "err", err)
+	}
+}
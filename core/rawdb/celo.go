@@ -2,6 +2,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/celo-org/celo-blockchain/common"
@@ -11,7 +12,39 @@ import (
 	"github.com/celo-org/celo-blockchain/rlp"
 )
 
-var genesisSupplyKey = []byte("genesis-supply-genesis")
+var (
+	genesisSupplyKey = []byte("genesis-supply-genesis")
+
+	// randomnessCommitmentPrefix + commitment -> parent hash of the block committing it
+	randomnessCommitmentPrefix = []byte("db-randomness-prefix")
+
+	// istanbulSnapshotPrefix + hash -> JSON(istanbul validator set snapshot)
+	istanbulSnapshotPrefix = []byte("istanbul-snapshot")
+
+	// randomnessJournalPrefix + commitment -> RLP(RandomCommitmentJournalEntry)
+	randomnessJournalPrefix = []byte("db-randomness-journal-")
+
+	// gasUsagePrefix + section (uint64 big endian) -> RLP([]ContractGasUsage)
+	gasUsagePrefix = []byte("gas-usage-")
+
+	// exchangeRatesPrefix + num (uint64 big endian) -> RLP([]CurrencyExchangeRate)
+	exchangeRatesPrefix = []byte("exchange-rates-")
+
+	// accountTxPrefix + address + num (uint64 big endian) + index (uint32 big endian) -> RLP(accountTxEntry)
+	accountTxPrefix = []byte("account-tx-")
+
+	// internalTransfersPrefix + num (uint64 big endian) + hash -> RLP([]InternalTransfer)
+	internalTransfersPrefix = []byte("internal-transfers-")
+
+	// accountTransferPrefix + address + num (uint64 big endian) + hash + index (uint32 big endian) -> RLP(InternalTransfer)
+	accountTransferPrefix = []byte("account-transfer-")
+
+	// signedViewPrefix + signer + message code (uint64 big endian) -> RLP(SignedView)
+	signedViewPrefix = []byte("istanbul-signed-view-")
+
+	// syncCheckpointKey -> RLP(SyncCheckpoint)
+	syncCheckpointKey = []byte("SyncCheckpoint")
+)
 
 // ReadGenesisCeloSupply retrieves a CELO token supply at genesis
 func ReadGenesisCeloSupply(db ethdb.KeyValueReader) *big.Int {
@@ -52,8 +85,479 @@ func ReadRandomCommitmentCache(db ethdb.Reader, commitment common.Hash) common.H
 // randomnessCommitmentKey will return the key for where the
 // given commitment's cached key-value entry
 func randomnessCommitmentKey(commitment common.Hash) []byte {
-	dbRandomnessPrefix := []byte("db-randomness-prefix")
-	return append(dbRandomnessPrefix, commitment.Bytes()...)
+	return append(append([]byte{}, randomnessCommitmentPrefix...), commitment.Bytes()...)
+}
+
+// DeleteRandomCommitmentCache removes the cache entry of a random beacon commitment.
+func DeleteRandomCommitmentCache(db ethdb.KeyValueWriter, commitment common.Hash) {
+	if err := db.Delete(randomnessCommitmentKey(commitment)); err != nil {
+		log.Crit("Failed to delete randomness commitment cache entry", "err", err)
+	}
+}
+
+// ReadAncientRandomCommitment retrieves the parent hash of a frozen block whose
+// randomness commitment was made by this node, the zero hash if the commitment
+// wasn't cached when the block was frozen.
+func ReadAncientRandomCommitment(db ethdb.AncientReader, number uint64) common.Hash {
+	data, _ := db.Ancient(freezerRandomnessTable, number)
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// ReadIstanbulSnapshot retrieves the encoded istanbul validator set snapshot of
+// a block, looking it up in the ancient store if the block was frozen.
+func ReadIstanbulSnapshot(db ethdb.Reader, hash common.Hash, number uint64) []byte {
+	if data, _ := db.Get(istanbulSnapshotKey(hash)); len(data) > 0 {
+		return data
+	}
+	// The canonical hash has to be checked, the ancient store having only the
+	// snapshots of the canonical blocks.
+	if h, _ := db.Ancient(freezerHashTable, number); common.BytesToHash(h) == hash {
+		if data, _ := db.Ancient(freezerIstanbulSnapshotTable, number); len(data) > 0 {
+			return data
+		}
+	}
+	return nil
+}
+
+// WriteIstanbulSnapshot stores the encoded istanbul validator set snapshot of a block.
+func WriteIstanbulSnapshot(db ethdb.KeyValueWriter, hash common.Hash, data []byte) error {
+	return db.Put(istanbulSnapshotKey(hash), data)
+}
+
+// DeleteIstanbulSnapshot removes the istanbul validator set snapshot of a block.
+func DeleteIstanbulSnapshot(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Delete(istanbulSnapshotKey(hash)); err != nil {
+		log.Crit("Failed to delete istanbul snapshot", "err", err)
+	}
+}
+
+// istanbulSnapshotKey returns the key of the istanbul validator set snapshot of a block.
+func istanbulSnapshotKey(hash common.Hash) []byte {
+	return append(append([]byte{}, istanbulSnapshotPrefix...), hash.Bytes()...)
+}
+
+// RandomCommitmentJournalEntry is a randomness commitment made by this node in a
+// block it built, together with the randomness to reveal in its next block.
+type RandomCommitmentJournalEntry struct {
+	Commitment common.Hash
+	ParentHash common.Hash // Parent hash of the block the commitment was made in
+	Randomness common.Hash // Randomness the commitment was computed from, zero if sealed
+
+	SealedRandomness []byte `rlp:"optional"` // Randomness encrypted with the storage key, if any
+}
+
+// WriteRandomCommitmentJournal journals a randomness commitment before it is used
+// in a block, so that the randomness can be revealed even if the node stops before
+// the block is imported. Entries are keyed by commitment, writing the same one twice
+// is a no-op.
+func WriteRandomCommitmentJournal(db ethdb.KeyValueWriter, entry *RandomCommitmentJournalEntry) {
+	data, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		log.Crit("Failed to encode randomness commitment journal entry", "err", err)
+	}
+	if err := db.Put(randomnessJournalKey(entry.Commitment), data); err != nil {
+		log.Crit("Failed to store randomness commitment journal entry", "err", err)
+	}
+}
+
+// ReadRandomCommitmentJournal retrieves the journal entry of a randomness commitment,
+// or nil if the commitment wasn't journaled.
+func ReadRandomCommitmentJournal(db ethdb.KeyValueReader, commitment common.Hash) *RandomCommitmentJournalEntry {
+	data, _ := db.Get(randomnessJournalKey(commitment))
+	if len(data) == 0 {
+		return nil
+	}
+	entry := new(RandomCommitmentJournalEntry)
+	if err := rlp.DecodeBytes(data, entry); err != nil {
+		log.Error("Invalid randomness commitment journal entry", "commitment", commitment, "err", err)
+		return nil
+	}
+	return entry
+}
+
+// ReadAllRandomCommitmentJournal retrieves all the entries of the randomness
+// commitment journal, skipping the corrupted ones.
+func ReadAllRandomCommitmentJournal(db ethdb.Iteratee) []*RandomCommitmentJournalEntry {
+	it := db.NewIterator(randomnessJournalPrefix, nil)
+	defer it.Release()
+
+	var entries []*RandomCommitmentJournalEntry
+	for it.Next() {
+		if len(it.Key()) != len(randomnessJournalPrefix)+common.HashLength {
+			continue
+		}
+		entry := new(RandomCommitmentJournalEntry)
+		if err := rlp.DecodeBytes(it.Value(), entry); err != nil {
+			log.Error("Invalid randomness commitment journal entry", "key", it.Key(), "err", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// randomnessJournalKey returns the key of the given commitment's journal entry.
+func randomnessJournalKey(commitment common.Hash) []byte {
+	return append(append([]byte{}, randomnessJournalPrefix...), commitment.Bytes()...)
+}
+
+// ContractGasUsage is the gas used by the transactions sent to a contract in a
+// section of the chain.
+type ContractGasUsage struct {
+	Contract     common.Address // Zero for contract creations
+	GasUsed      uint64
+	Transactions uint64
+}
+
+// WriteGasUsageSection stores the gas usage per contract of a section of the
+// chain indexed by the gas usage indexer.
+func WriteGasUsageSection(db ethdb.KeyValueWriter, section uint64, usage []*ContractGasUsage) {
+	data, err := rlp.EncodeToBytes(usage)
+	if err != nil {
+		log.Crit("Failed to encode gas usage section", "err", err)
+	}
+	if err := db.Put(gasUsageKey(section), data); err != nil {
+		log.Crit("Failed to store gas usage section", "err", err)
+	}
+}
+
+// ReadGasUsageSection retrieves the gas usage per contract of a section of the
+// chain, or nil if it wasn't indexed.
+func ReadGasUsageSection(db ethdb.KeyValueReader, section uint64) []*ContractGasUsage {
+	data, _ := db.Get(gasUsageKey(section))
+	if len(data) == 0 {
+		return nil
+	}
+	var usage []*ContractGasUsage
+	if err := rlp.DecodeBytes(data, &usage); err != nil {
+		log.Error("Invalid gas usage section", "section", section, "err", err)
+		return nil
+	}
+	return usage
+}
+
+// gasUsageKey returns the key of the gas usage of a section.
+func gasUsageKey(section uint64) []byte {
+	return append(append([]byte{}, gasUsagePrefix...), encodeBlockNumber(section)...)
+}
+
+// CurrencyExchangeRate is the exchange rate of a currency to CELO, as the
+// numerator and denominator of the median rate of the oracles.
+type CurrencyExchangeRate struct {
+	Currency    common.Address
+	Numerator   *big.Int
+	Denominator *big.Int
+}
+
+// WriteExchangeRates stores the exchange rates of the whitelisted currencies at
+// a block indexed by the exchange rate indexer.
+func WriteExchangeRates(db ethdb.KeyValueWriter, number uint64, rates []*CurrencyExchangeRate) {
+	data, err := rlp.EncodeToBytes(rates)
+	if err != nil {
+		log.Crit("Failed to encode exchange rates", "err", err)
+	}
+	if err := db.Put(exchangeRatesKey(number), data); err != nil {
+		log.Crit("Failed to store exchange rates", "err", err)
+	}
+}
+
+// ReadExchangeRates retrieves the exchange rates of the whitelisted currencies
+// at a block, and whether they were indexed.
+func ReadExchangeRates(db ethdb.KeyValueReader, number uint64) ([]*CurrencyExchangeRate, bool) {
+	data, _ := db.Get(exchangeRatesKey(number))
+	if len(data) == 0 {
+		return nil, false
+	}
+	var rates []*CurrencyExchangeRate
+	if err := rlp.DecodeBytes(data, &rates); err != nil {
+		log.Error("Invalid exchange rates", "number", number, "err", err)
+		return nil, false
+	}
+	return rates, true
+}
+
+// exchangeRatesKey returns the key of the exchange rates at a block.
+func exchangeRatesKey(number uint64) []byte {
+	return append(append([]byte{}, exchangeRatesPrefix...), encodeBlockNumber(number)...)
+}
+
+// AccountTransaction is a transaction an account sent or received, the latter
+// including the creation of a contract.
+type AccountTransaction struct {
+	Number    uint64
+	Index     uint64
+	Hash      common.Hash
+	BlockHash common.Hash
+	Sender    bool
+	Recipient bool
+}
+
+// accountTxEntry is the stored part of an AccountTransaction, the position of
+// the transaction being in the key.
+type accountTxEntry struct {
+	Hash      common.Hash
+	BlockHash common.Hash
+	Sender    bool
+	Recipient bool
+}
+
+// WriteAccountTransaction stores a transaction of an account indexed by the
+// account transaction indexer.
+func WriteAccountTransaction(db ethdb.KeyValueWriter, address common.Address, tx *AccountTransaction) {
+	data, err := rlp.EncodeToBytes(&accountTxEntry{Hash: tx.Hash, BlockHash: tx.BlockHash, Sender: tx.Sender, Recipient: tx.Recipient})
+	if err != nil {
+		log.Crit("Failed to encode account transaction", "err", err)
+	}
+	if err := db.Put(accountTxKey(address, tx.Number, tx.Index), data); err != nil {
+		log.Crit("Failed to store account transaction", "err", err)
+	}
+}
+
+// ReadAccountTransactions retrieves the first limit transactions of an account,
+// in chain order, starting at the given position of the from block and ending
+// with the to block.
+func ReadAccountTransactions(db ethdb.Iteratee, address common.Address, from, index, to uint64, limit int) []*AccountTransaction {
+	prefix := append(append([]byte{}, accountTxPrefix...), address.Bytes()...)
+	it := db.NewIterator(prefix, accountTxKey(address, from, index)[len(prefix):])
+	defer it.Release()
+
+	var txs []*AccountTransaction
+	for len(txs) < limit && it.Next() {
+		key := it.Key()[len(prefix):]
+		if len(key) != 12 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[:8])
+		if number > to {
+			break
+		}
+		entry := new(accountTxEntry)
+		if err := rlp.DecodeBytes(it.Value(), entry); err != nil {
+			log.Error("Invalid account transaction", "address", address, "number", number, "err", err)
+			continue
+		}
+		txs = append(txs, &AccountTransaction{
+			Number:    number,
+			Index:     uint64(binary.BigEndian.Uint32(key[8:])),
+			Hash:      entry.Hash,
+			BlockHash: entry.BlockHash,
+			Sender:    entry.Sender,
+			Recipient: entry.Recipient,
+		})
+	}
+	return txs
+}
+
+// accountTxKey returns the key of the transaction of an account at a position.
+func accountTxKey(address common.Address, number, index uint64) []byte {
+	key := append(append([]byte{}, accountTxPrefix...), address.Bytes()...)
+	key = append(key, encodeBlockNumber(number)...)
+	enc := make([]byte, 4)
+	binary.BigEndian.PutUint32(enc, uint32(index))
+	return append(key, enc...)
+}
+
+// WriteInternalTransfers stores the CELO transfers made by a block, indexing them
+// by the accounts they moved CELO from and to as well.
+func WriteInternalTransfers(db ethdb.KeyValueWriter, hash common.Hash, number uint64, transfers []*types.InternalTransfer) {
+	data, err := rlp.EncodeToBytes(transfers)
+	if err != nil {
+		log.Crit("Failed to encode internal transfers", "err", err)
+	}
+	if err := db.Put(internalTransfersKey(number, hash), data); err != nil {
+		log.Crit("Failed to store internal transfers", "err", err)
+	}
+	for i, transfer := range transfers {
+		data, err := rlp.EncodeToBytes(transfer)
+		if err != nil {
+			log.Crit("Failed to encode internal transfer", "err", err)
+		}
+		for _, address := range []common.Address{transfer.From, transfer.To} {
+			// Mints are not indexed by the zero address they come from
+			if transfer.Kind == types.TransferMint && address == transfer.From {
+				continue
+			}
+			if err := db.Put(accountTransferKey(address, number, hash, uint64(i)), data); err != nil {
+				log.Crit("Failed to store account transfer", "err", err)
+			}
+		}
+	}
+}
+
+// ReadInternalTransfers retrieves the CELO transfers made by a block, and whether
+// they were indexed.
+func ReadInternalTransfers(db ethdb.KeyValueReader, hash common.Hash, number uint64) ([]*types.InternalTransfer, bool) {
+	data, _ := db.Get(internalTransfersKey(number, hash))
+	if len(data) == 0 {
+		return nil, false
+	}
+	var transfers []*types.InternalTransfer
+	if err := rlp.DecodeBytes(data, &transfers); err != nil {
+		log.Error("Invalid internal transfers", "hash", hash, "number", number, "err", err)
+		return nil, false
+	}
+	for i, transfer := range transfers {
+		transfer.Index = uint(i)
+	}
+	return transfers, true
+}
+
+// AccountTransfer is a CELO transfer an account made or received in a block.
+type AccountTransfer struct {
+	Number    uint64
+	BlockHash common.Hash
+	Transfer  *types.InternalTransfer
+}
+
+// ReadAccountTransfers retrieves the first limit CELO transfers of an account made
+// from the from block to the to block, in chain order. The transfers of all the
+// blocks at a height are returned, canonical or not.
+func ReadAccountTransfers(db ethdb.Iteratee, address common.Address, from, to uint64, limit int) []*AccountTransfer {
+	prefix := append(append([]byte{}, accountTransferPrefix...), address.Bytes()...)
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var transfers []*AccountTransfer
+	for len(transfers) < limit && it.Next() {
+		key := it.Key()[len(prefix):]
+		if len(key) != 8+common.HashLength+4 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[:8])
+		if number > to {
+			break
+		}
+		transfer := new(types.InternalTransfer)
+		if err := rlp.DecodeBytes(it.Value(), transfer); err != nil {
+			log.Error("Invalid account transfer", "address", address, "number", number, "err", err)
+			continue
+		}
+		transfer.Index = uint(binary.BigEndian.Uint32(key[8+common.HashLength:]))
+		transfers = append(transfers, &AccountTransfer{
+			Number:    number,
+			BlockHash: common.BytesToHash(key[8 : 8+common.HashLength]),
+			Transfer:  transfer,
+		})
+	}
+	return transfers
+}
+
+// internalTransfersKey returns the key of the transfers made by a block.
+func internalTransfersKey(number uint64, hash common.Hash) []byte {
+	key := append(append([]byte{}, internalTransfersPrefix...), encodeBlockNumber(number)...)
+	return append(key, hash.Bytes()...)
+}
+
+// accountTransferKey returns the key of the transfer of an account at a position
+// of a block.
+func accountTransferKey(address common.Address, number uint64, hash common.Hash, index uint64) []byte {
+	key := append(append([]byte{}, accountTransferPrefix...), address.Bytes()...)
+	key = append(key, encodeBlockNumber(number)...)
+	key = append(key, hash.Bytes()...)
+	enc := make([]byte, 4)
+	binary.BigEndian.PutUint32(enc, uint32(index))
+	return append(key, enc...)
+}
+
+// SignedView is the highest view an Istanbul validator signed a consensus message
+// of a kind in, with the digest signed, protecting it from double signing.
+type SignedView struct {
+	Signer   common.Address
+	Code     uint64 // Istanbul message code
+	Sequence *big.Int
+	Round    *big.Int
+	Digest   common.Hash
+}
+
+// WriteSignedView stores the highest view a validator signed a message of a kind in,
+// replacing the previous one.
+func WriteSignedView(db ethdb.KeyValueWriter, view *SignedView) {
+	data, err := rlp.EncodeToBytes(view)
+	if err != nil {
+		log.Crit("Failed to encode signed view", "err", err)
+	}
+	if err := db.Put(signedViewKey(view.Signer, view.Code), data); err != nil {
+		log.Crit("Failed to store signed view", "err", err)
+	}
+}
+
+// ReadSignedView retrieves the highest view a validator signed a message of a kind
+// in, or nil if it never did.
+func ReadSignedView(db ethdb.KeyValueReader, signer common.Address, code uint64) *SignedView {
+	data, _ := db.Get(signedViewKey(signer, code))
+	if len(data) == 0 {
+		return nil
+	}
+	view := new(SignedView)
+	if err := rlp.DecodeBytes(data, view); err != nil {
+		log.Error("Invalid signed view", "signer", signer, "code", code, "err", err)
+		return nil
+	}
+	return view
+}
+
+// ReadAllSignedViews retrieves the highest views signed by all the validators,
+// skipping the corrupted ones.
+func ReadAllSignedViews(db ethdb.Iteratee) []*SignedView {
+	it := db.NewIterator(signedViewPrefix, nil)
+	defer it.Release()
+
+	var views []*SignedView
+	for it.Next() {
+		if len(it.Key()) != len(signedViewPrefix)+common.AddressLength+8 {
+			continue
+		}
+		view := new(SignedView)
+		if err := rlp.DecodeBytes(it.Value(), view); err != nil {
+			log.Error("Invalid signed view", "key", it.Key(), "err", err)
+			continue
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// signedViewKey returns the key of the highest view a validator signed a message
+// of a kind in.
+func signedViewKey(signer common.Address, code uint64) []byte {
+	key := append(append([]byte{}, signedViewPrefix...), signer.Bytes()...)
+	return append(key, encodeBlockNumber(code)...)
+}
+
+// SyncCheckpoint is the last block of an epoch a node was bootstrapped from
+// instead of the genesis, none of the blocks before it being stored.
+type SyncCheckpoint struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// WriteSyncCheckpoint stores the epoch block the chain was bootstrapped from.
+func WriteSyncCheckpoint(db ethdb.KeyValueWriter, checkpoint *SyncCheckpoint) {
+	data, err := rlp.EncodeToBytes(checkpoint)
+	if err != nil {
+		log.Crit("Failed to encode sync checkpoint", "err", err)
+	}
+	if err := db.Put(syncCheckpointKey, data); err != nil {
+		log.Crit("Failed to store sync checkpoint", "err", err)
+	}
+}
+
+// ReadSyncCheckpoint retrieves the epoch block the chain was bootstrapped from,
+// or nil if it was synced from the genesis.
+func ReadSyncCheckpoint(db ethdb.KeyValueReader) *SyncCheckpoint {
+	data, _ := db.Get(syncCheckpointKey)
+	if len(data) == 0 {
+		return nil
+	}
+	checkpoint := new(SyncCheckpoint)
+	if err := rlp.DecodeBytes(data, checkpoint); err != nil {
+		log.Error("Invalid sync checkpoint", "err", err)
+		return nil
+	}
+	return checkpoint
 }
 
 // Extra hash comparison is necessary since ancient database only maintains
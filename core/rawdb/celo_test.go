@@ -2,7 +2,11 @@ package rawdb
 
 import (
 	"math/big"
+	"reflect"
 	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
 )
 
 // Tests Genesis CELO supply storage and retrieval operations.
@@ -25,3 +29,163 @@ func TestGenesisCeloSupply(t *testing.T) {
 		t.Fatalf("Retrieved CELO token supply mismatch: have %v, want %v", supply, initialSupply)
 	}
 }
+
+// Tests randomness commitment journal storage and retrieval operations.
+func TestRandomCommitmentJournal(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if entry := ReadRandomCommitmentJournal(db, common.Hash{1}); entry != nil {
+		t.Fatalf("Non existent journal entry returned: %v", entry)
+	}
+	entries := []*RandomCommitmentJournalEntry{
+		{Commitment: common.Hash{1}, ParentHash: common.Hash{2}, Randomness: common.Hash{3}},
+		{Commitment: common.Hash{4}, ParentHash: common.Hash{5}, Randomness: common.Hash{6}},
+		{Commitment: common.Hash{9}, ParentHash: common.Hash{10}, SealedRandomness: []byte{11}},
+	}
+	for _, entry := range entries {
+		WriteRandomCommitmentJournal(db, entry)
+	}
+	// The commitment cache lives next to the journal, it must not be mistaken for an entry
+	WriteRandomCommitmentCache(db, common.Hash{7}, common.Hash{8})
+
+	for _, want := range entries {
+		if have := ReadRandomCommitmentJournal(db, want.Commitment); have == nil || !reflect.DeepEqual(have, want) {
+			t.Fatalf("Retrieved journal entry mismatch: have %v, want %v", have, want)
+		}
+	}
+	if have := ReadAllRandomCommitmentJournal(db); !reflect.DeepEqual(have, entries) {
+		t.Fatalf("Journal mismatch: have %v, want %v", have, entries)
+	}
+}
+
+// Tests exchange rates storage and retrieval operations.
+func TestExchangeRates(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if rates, ok := ReadExchangeRates(db, 1); ok {
+		t.Fatalf("Non existent exchange rates returned: %v", rates)
+	}
+	rates := []*CurrencyExchangeRate{
+		{Currency: common.Address{1}, Numerator: big.NewInt(2), Denominator: big.NewInt(3)},
+		{Currency: common.Address{4}, Numerator: big.NewInt(5), Denominator: big.NewInt(6)},
+	}
+	WriteExchangeRates(db, 1, rates)
+	// A block without any whitelisted currency was still indexed
+	WriteExchangeRates(db, 2, nil)
+
+	if have, ok := ReadExchangeRates(db, 1); !ok || !reflect.DeepEqual(have, rates) {
+		t.Fatalf("Retrieved exchange rates mismatch: have %v, want %v", have, rates)
+	}
+	if have, ok := ReadExchangeRates(db, 2); !ok || len(have) != 0 {
+		t.Fatalf("Retrieved exchange rates mismatch: have %v, want none", have)
+	}
+}
+
+// Tests account transactions storage and retrieval operations.
+func TestAccountTransactions(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	account, other := common.Address{1}, common.Address{2}
+	txs := []*AccountTransaction{
+		{Number: 1, Index: 0, Hash: common.Hash{1}, BlockHash: common.Hash{11}, Sender: true},
+		{Number: 1, Index: 3, Hash: common.Hash{2}, BlockHash: common.Hash{11}, Recipient: true},
+		{Number: 4, Index: 1, Hash: common.Hash{3}, BlockHash: common.Hash{14}, Sender: true, Recipient: true},
+		{Number: 300, Index: 0, Hash: common.Hash{4}, BlockHash: common.Hash{15}, Sender: true},
+	}
+	for _, tx := range txs {
+		WriteAccountTransaction(db, account, tx)
+	}
+	WriteAccountTransaction(db, other, &AccountTransaction{Number: 2, Hash: common.Hash{5}, Sender: true})
+
+	if have := ReadAccountTransactions(db, account, 0, 0, 1000, 10); !reflect.DeepEqual(have, txs) {
+		t.Fatalf("Retrieved account transactions mismatch: have %v, want %v", have, txs)
+	}
+	// Starting from a position, up to a block
+	if have := ReadAccountTransactions(db, account, 1, 1, 299, 10); !reflect.DeepEqual(have, txs[1:3]) {
+		t.Fatalf("Retrieved account transactions mismatch: have %v, want %v", have, txs[1:3])
+	}
+	if have := ReadAccountTransactions(db, account, 0, 0, 1000, 2); !reflect.DeepEqual(have, txs[:2]) {
+		t.Fatalf("Retrieved account transactions mismatch: have %v, want %v", have, txs[:2])
+	}
+	if have := ReadAccountTransactions(db, common.Address{3}, 0, 0, 1000, 10); len(have) != 0 {
+		t.Fatalf("Retrieved transactions of an inactive account: %v", have)
+	}
+}
+
+func TestInternalTransfers(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	account, other := common.Address{1}, common.Address{2}
+	transfers := []*types.InternalTransfer{
+		{Kind: types.TransferMint, To: account, Value: big.NewInt(1), TxIndex: 0, Index: 0},
+		{Kind: types.TransferCall, From: account, To: other, Value: big.NewInt(2), TxHash: common.Hash{3}, TxIndex: 0, Index: 1},
+		{Kind: types.TransferFee, From: other, To: other, Value: big.NewInt(3), TxHash: common.Hash{4}, TxIndex: 1, Index: 2},
+	}
+	if _, ok := ReadInternalTransfers(db, common.Hash{11}, 1); ok {
+		t.Fatalf("Non existent internal transfers returned")
+	}
+	WriteInternalTransfers(db, common.Hash{11}, 1, transfers)
+	WriteInternalTransfers(db, common.Hash{12}, 2, nil)
+	WriteInternalTransfers(db, common.Hash{13}, 3, transfers[1:2])
+
+	if have, ok := ReadInternalTransfers(db, common.Hash{11}, 1); !ok || !reflect.DeepEqual(have, transfers) {
+		t.Fatalf("Retrieved internal transfers mismatch: have %v, want %v", have, transfers)
+	}
+	if have, ok := ReadInternalTransfers(db, common.Hash{12}, 2); !ok || len(have) != 0 {
+		t.Fatalf("Retrieved internal transfers of a block without any: %v, %v", have, ok)
+	}
+	moved := *transfers[1]
+	moved.Index = 0
+	want := []*AccountTransfer{
+		{Number: 1, BlockHash: common.Hash{11}, Transfer: transfers[0]},
+		{Number: 1, BlockHash: common.Hash{11}, Transfer: transfers[1]},
+		{Number: 3, BlockHash: common.Hash{13}, Transfer: &moved},
+	}
+	if have := ReadAccountTransfers(db, account, 0, 10, 10); !reflect.DeepEqual(have, want) {
+		t.Fatalf("Retrieved account transfers mismatch: have %v, want %v", have, want)
+	}
+	if have := ReadAccountTransfers(db, account, 2, 10, 10); !reflect.DeepEqual(have, want[2:]) {
+		t.Fatalf("Retrieved account transfers mismatch: have %v, want %v", have, want[2:])
+	}
+	if have := ReadAccountTransfers(db, account, 0, 10, 1); !reflect.DeepEqual(have, want[:1]) {
+		t.Fatalf("Retrieved account transfers mismatch: have %v, want %v", have, want[:1])
+	}
+	// A transfer to self is indexed once, and mints aren't indexed by the zero address
+	if have := ReadAccountTransfers(db, other, 1, 1, 10); len(have) != 2 {
+		t.Fatalf("Retrieved account transfers mismatch: have %v, want 2", have)
+	}
+	if have := ReadAccountTransfers(db, common.Address{}, 0, 10, 10); len(have) != 0 {
+		t.Fatalf("Retrieved transfers of the zero address: %v", have)
+	}
+}
+
+// Tests signed view storage and retrieval operations.
+func TestSignedViews(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if view := ReadSignedView(db, common.Address{1}, 1); view != nil {
+		t.Fatalf("Non existent signed view returned: %v", view)
+	}
+	views := []*SignedView{
+		{Signer: common.Address{1}, Code: 1, Sequence: big.NewInt(10), Round: big.NewInt(0), Digest: common.Hash{2}},
+		{Signer: common.Address{1}, Code: 2, Sequence: big.NewInt(10), Round: big.NewInt(1), Digest: common.Hash{3}},
+		{Signer: common.Address{4}, Code: 1, Sequence: big.NewInt(11), Round: big.NewInt(2), Digest: common.Hash{5}},
+	}
+	for _, view := range views {
+		WriteSignedView(db, view)
+	}
+	for _, want := range views {
+		if have := ReadSignedView(db, want.Signer, want.Code); have == nil || !reflect.DeepEqual(have, want) {
+			t.Fatalf("Retrieved signed view mismatch: have %v, want %v", have, want)
+		}
+	}
+	if have := ReadAllSignedViews(db); !reflect.DeepEqual(have, views) {
+		t.Fatalf("Signed views mismatch: have %v, want %v", have, views)
+	}
+	// Writing a view again replaces the previous one
+	update := &SignedView{Signer: common.Address{1}, Code: 1, Sequence: big.NewInt(12), Round: big.NewInt(0), Digest: common.Hash{6}}
+	WriteSignedView(db, update)
+	if have := ReadSignedView(db, update.Signer, update.Code); !reflect.DeepEqual(have, update) {
+		t.Fatalf("Updated signed view mismatch: have %v, want %v", have, update)
+	}
+}
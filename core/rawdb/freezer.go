@@ -27,10 +27,12 @@ import (
 	"time"
 
 	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/ethdb"
 	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/metrics"
 	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/rlp"
 	"github.com/prometheus/tsdb/fileutil"
 )
 
@@ -299,16 +301,23 @@ func (f *freezer) Sync() error {
 	return nil
 }
 
-// repair truncates all data tables to the same length.
+// repair truncates all data tables to the same length. The celo tables shorter
+// than the others are left to the migration filling them.
 func (f *freezer) repair() error {
 	min := uint64(math.MaxUint64)
-	for _, table := range f.tables {
+	for name, table := range f.tables {
+		if freezerCeloTables[name] {
+			continue
+		}
 		items := atomic.LoadUint64(&table.items)
 		if min > items {
 			min = items
 		}
 	}
-	for _, table := range f.tables {
+	for name, table := range f.tables {
+		if freezerCeloTables[name] && atomic.LoadUint64(&table.items) < min {
+			continue
+		}
 		if err := table.truncate(min); err != nil {
 			return err
 		}
@@ -383,6 +392,13 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 			backoff = true
 			continue
 		}
+		// The blocks of a chain bootstrapped from a checkpoint can't be frozen, the
+		// ones before the checkpoint being missing.
+		if checkpoint := ReadSyncCheckpoint(nfdb); checkpoint != nil && f.frozen <= checkpoint.Number {
+			log.Debug("Blocks before the sync checkpoint unavailable", "checkpoint", checkpoint.Number, "frozen", f.frozen)
+			backoff = true
+			continue
+		}
 
 		// Seems we have data ready to be frozen, process in usable batches
 		var (
@@ -393,7 +409,7 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 		if limit-first > freezerBatchLimit {
 			limit = first + freezerBatchLimit
 		}
-		ancients, err := f.freezeRange(nfdb, first, limit)
+		ancients, commitments, err := f.freezeRange(nfdb, first, limit)
 		if err != nil {
 			log.Error("Error in block freeze operation", "err", err)
 			backoff = true
@@ -412,8 +428,12 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 			if first+uint64(i) != 0 {
 				DeleteBlockWithoutNumber(batch, ancients[i], first+uint64(i))
 				DeleteCanonicalHash(batch, first+uint64(i))
+				DeleteIstanbulSnapshot(batch, ancients[i])
 			}
 		}
+		for _, commitment := range commitments {
+			DeleteRandomCommitmentCache(batch, commitment)
+		}
 		if err := batch.Write(); err != nil {
 			log.Crit("Failed to delete frozen canonical blocks", "err", err)
 		}
@@ -428,6 +448,7 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 				for _, hash := range dangling {
 					log.Trace("Deleting side chain", "number", number, "hash", hash)
 					DeleteBlock(batch, hash, number)
+					DeleteIstanbulSnapshot(batch, hash)
 				}
 			}
 		}
@@ -461,6 +482,7 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 					// Delete all block data associated with the child
 					log.Debug("Deleting dangling block", "number", tip, "hash", children[i], "parent", child.ParentHash)
 					DeleteBlock(batch, children[i], tip)
+					DeleteIstanbulSnapshot(batch, children[i])
 				}
 				dangling = children
 				tip++
@@ -486,7 +508,9 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 	}
 }
 
-func (f *freezer) freezeRange(nfdb *nofreezedb, number, limit uint64) (hashes []common.Hash, err error) {
+// freezeRange moves the canonical blocks from number to limit into the freezer,
+// returning their hashes along with the cached randomness commitments frozen.
+func (f *freezer) freezeRange(nfdb *nofreezedb, number, limit uint64) (hashes []common.Hash, commitments []common.Hash, err error) {
 	hashes = make([]common.Hash, 0, limit-number)
 
 	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
@@ -512,6 +536,18 @@ func (f *freezer) freezeRange(nfdb *nofreezedb, number, limit uint64) (hashes []
 			if len(td) == 0 {
 				return fmt.Errorf("total difficulty missing, can't freeze block %d", number)
 			}
+			// The celo data of the block, empty if the node has none for it
+			var decoded types.Body
+			if err := rlp.DecodeBytes(body, &decoded); err != nil {
+				return fmt.Errorf("invalid block body, can't freeze block %d: %v", number, err)
+			}
+			var randomness []byte
+			if decoded.Randomness != nil && decoded.Randomness.Committed != (common.Hash{}) {
+				if randomness, _ = nfdb.Get(randomnessCommitmentKey(decoded.Randomness.Committed)); len(randomness) > 0 {
+					commitments = append(commitments, decoded.Randomness.Committed)
+				}
+			}
+			snapshot, _ := nfdb.Get(istanbulSnapshotKey(hash))
 
 			// Write to the batch.
 			if err := op.AppendRaw(freezerHashTable, number, hash[:]); err != nil {
@@ -529,11 +565,125 @@ func (f *freezer) freezeRange(nfdb *nofreezedb, number, limit uint64) (hashes []
 			if err := op.AppendRaw(freezerDifficultyTable, number, td); err != nil {
 				return fmt.Errorf("can't write td to freezer: %v", err)
 			}
+			if err := op.AppendRaw(freezerRandomnessTable, number, randomness); err != nil {
+				return fmt.Errorf("can't write randomness to freezer: %v", err)
+			}
+			if err := op.AppendRaw(freezerIstanbulSnapshotTable, number, snapshot); err != nil {
+				return fmt.Errorf("can't write istanbul snapshot to freezer: %v", err)
+			}
 
 			hashes = append(hashes, hash)
 		}
 		return nil
 	})
 
-	return hashes, err
+	return hashes, commitments, err
+}
+
+// migrateCeloTables fills the celo tables shorter than the other tables, like
+// the ones created in the freezer of a node upgraded to freeze them, with the
+// data of the frozen blocks left in the key-value store. The migrated entries
+// are then deleted from the key-value store, but for the genesis block's.
+func (f *freezer) migrateCeloTables(db ethdb.KeyValueStore) error {
+	var (
+		frozen = atomic.LoadUint64(&f.frozen)
+		start  = frozen
+	)
+	for name := range freezerCeloTables {
+		if table, ok := f.tables[name]; ok {
+			if items := atomic.LoadUint64(&table.items); items < start {
+				start = items
+			}
+		}
+	}
+	if start == frozen {
+		return nil
+	}
+	log.Info("Migrating celo data into the ancient store", "from", start, "frozen", frozen)
+
+	// The few blocks with celo data are indexed by number to fill the tables
+	var (
+		nfdb        = &nofreezedb{KeyValueStore: db}
+		randomness  = make(map[uint64][]byte)
+		snapshots   = make(map[uint64][]byte)
+		commitments []common.Hash
+		hashes      []common.Hash
+	)
+	it := db.NewIterator(randomnessCommitmentPrefix, nil)
+	for it.Next() {
+		if len(it.Key()) != len(randomnessCommitmentPrefix)+common.HashLength {
+			continue
+		}
+		// The commitment was made for a child of the parent, the frozen one
+		// committing it is the canonical block with the commitment in its body.
+		commitment, parent := common.BytesToHash(it.Key()[len(randomnessCommitmentPrefix):]), common.BytesToHash(it.Value())
+		number := ReadHeaderNumber(nfdb, parent)
+		if number == nil || *number+1 < start || *number+1 >= frozen {
+			continue
+		}
+		var body types.Body
+		if data, _ := f.Ancient(freezerBodiesTable, *number+1); len(data) == 0 || rlp.DecodeBytes(data, &body) != nil {
+			continue
+		}
+		if body.Randomness != nil && body.Randomness.Committed == commitment {
+			randomness[*number+1] = common.CopyBytes(it.Value())
+			commitments = append(commitments, commitment)
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+	it = db.NewIterator(istanbulSnapshotPrefix, nil)
+	for it.Next() {
+		if len(it.Key()) != len(istanbulSnapshotPrefix)+common.HashLength {
+			continue
+		}
+		hash := common.BytesToHash(it.Key()[len(istanbulSnapshotPrefix):])
+		number := ReadHeaderNumber(nfdb, hash)
+		if number == nil || *number < start || *number >= frozen {
+			continue
+		}
+		if h, _ := f.Ancient(freezerHashTable, *number); common.BytesToHash(h) == hash {
+			snapshots[*number] = common.CopyBytes(it.Value())
+			if *number != 0 {
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+	// Fill the tables, then delete the entries migrated
+	for name, items := range map[string]map[uint64][]byte{freezerRandomnessTable: randomness, freezerIstanbulSnapshotTable: snapshots} {
+		table, ok := f.tables[name]
+		if !ok {
+			continue
+		}
+		batch := table.newBatch()
+		for number := atomic.LoadUint64(&table.items); number < frozen; number++ {
+			if err := batch.AppendRaw(number, items[number]); err != nil {
+				return fmt.Errorf("can't migrate %s to freezer: %v", name, err)
+			}
+		}
+		if err := batch.commit(); err != nil {
+			return err
+		}
+		if err := table.Sync(); err != nil {
+			return err
+		}
+	}
+	batch := db.NewBatch()
+	for _, commitment := range commitments {
+		DeleteRandomCommitmentCache(batch, commitment)
+	}
+	for _, hash := range hashes {
+		DeleteIstanbulSnapshot(batch, hash)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Migrated celo data into the ancient store", "commitments", len(randomness), "snapshots", len(snapshots))
+	return nil
 }
@@ -24,10 +24,14 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/ethdb/memorydb"
 	"github.com/celo-org/celo-blockchain/rlp"
 	"github.com/stretchr/testify/require"
 )
@@ -299,3 +303,85 @@ func checkAncientCount(t *testing.T, f *freezer, kind string, n uint64) {
 		t.Errorf("Ancient(%q, %d) returned unexpected error %q", kind, index, err)
 	}
 }
+
+func TestFreezerCeloTables(t *testing.T) {
+	t.Parallel()
+
+	// Create a chain committing randomness, the first commitment by this node
+	var (
+		kv     = memorydb.New()
+		blocks []*types.Block
+		parent common.Hash
+	)
+	for i := 0; i < 4; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent}
+		randomness := &types.Randomness{Committed: common.BytesToHash([]byte{byte(i)})}
+		block := types.NewBlockWithHeader(header).WithBody(nil, randomness, nil)
+		WriteBlock(kv, block)
+		WriteCanonicalHash(kv, block.Hash(), block.NumberU64())
+		WriteTd(kv, block.Hash(), block.NumberU64(), big.NewInt(int64(i+1)))
+		WriteReceipts(kv, block.Hash(), block.NumberU64(), nil)
+		blocks, parent = append(blocks, block), block.Hash()
+	}
+	WriteHeadHeaderHash(kv, parent)
+	WriteRandomCommitmentCache(kv, blocks[1].Randomness().Committed, blocks[0].Hash())
+	WriteIstanbulSnapshot(kv, blocks[0].Hash(), []byte("genesis"))
+	WriteIstanbulSnapshot(kv, blocks[2].Hash(), []byte("epoch"))
+
+	f, dir := newFreezerForTesting(t, FreezerNoSnappy)
+	defer os.RemoveAll(dir)
+	hashes, commitments, err := f.freezeRange(&nofreezedb{KeyValueStore: kv}, 0, 3)
+	if err != nil {
+		t.Fatal("freezeRange failed:", err)
+	}
+	if len(hashes) != 4 || len(commitments) != 1 || commitments[0] != blocks[1].Randomness().Committed {
+		t.Fatalf("frozen data mismatch: have %d hashes, commitments %v", len(hashes), commitments)
+	}
+	check := func(db ethdb.Reader) {
+		t.Helper()
+		for i, want := range []common.Hash{{}, blocks[0].Hash(), {}, {}} {
+			if have := ReadAncientRandomCommitment(db, uint64(i)); have != want {
+				t.Errorf("frozen randomness of block %d mismatch: have %x, want %x", i, have, want)
+			}
+		}
+		if have := ReadIstanbulSnapshot(db, blocks[2].Hash(), 2); string(have) != "epoch" {
+			t.Errorf("frozen snapshot mismatch: have %q", have)
+		}
+		if have := ReadIstanbulSnapshot(db, blocks[2].Hash(), 1); have != nil {
+			t.Errorf("snapshot found at another block: %q", have)
+		}
+		if have := ReadIstanbulSnapshot(db, blocks[1].Hash(), 1); have != nil {
+			t.Errorf("missing snapshot found: %q", have)
+		}
+	}
+	check(&freezerdb{KeyValueStore: memorydb.New(), AncientStore: f})
+	f.Close()
+
+	// Drop the celo tables, as in a freezer created before them, and migrate
+	for name := range freezerCeloTables {
+		files, _ := filepath.Glob(filepath.Join(dir, name+".*"))
+		for _, file := range files {
+			if err := os.Remove(file); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	db, err := NewDatabaseWithFreezer(kv, dir, "", false)
+	if err != nil {
+		t.Fatal("can't open freezer:", err)
+	}
+	defer db.Close()
+	if frozen, _ := db.Ancients(); frozen != 4 {
+		t.Fatalf("frozen blocks mismatch after migration: have %d, want 4", frozen)
+	}
+	check(db)
+	if has, _ := kv.Has(randomnessCommitmentKey(blocks[1].Randomness().Committed)); has {
+		t.Errorf("migrated randomness commitment left in the key-value store")
+	}
+	if has, _ := kv.Has(istanbulSnapshotKey(blocks[2].Hash())); has {
+		t.Errorf("migrated snapshot left in the key-value store")
+	}
+	if has, _ := kv.Has(istanbulSnapshotKey(blocks[0].Hash())); !has {
+		t.Errorf("genesis snapshot deleted from the key-value store")
+	}
+}
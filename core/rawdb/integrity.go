@@ -0,0 +1,76 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/ethdb"
+)
+
+// ErrCorrupted is returned, wrapped, by CheckIntegrity when the chain data is
+// inconsistent or can't be decoded.
+var ErrCorrupted = errors.New("chain database corrupted")
+
+// CheckIntegrity verifies the genesis block and the last depth canonical blocks
+// up to the head block: their canonical hashes, headers and bodies have to be
+// present, decodable and linked, and their receipts present if they have any
+// transactions. The state isn't checked, the blockchain rewinds to a block with
+// a state at startup. An empty database is consistent.
+func CheckIntegrity(db ethdb.Reader, depth uint64) error {
+	genesis := ReadCanonicalHash(db, 0)
+	if genesis == (common.Hash{}) {
+		return nil
+	}
+	if ReadHeader(db, genesis, 0) == nil {
+		return fmt.Errorf("%w: genesis header %x missing", ErrCorrupted, genesis)
+	}
+	head := ReadHeadBlockHash(db)
+	if head == (common.Hash{}) {
+		return fmt.Errorf("%w: head block hash missing", ErrCorrupted)
+	}
+	number := ReadHeaderNumber(db, head)
+	if number == nil {
+		return fmt.Errorf("%w: number of head block %x missing", ErrCorrupted, head)
+	}
+	for n, hash := *number, head; depth > 0; n, depth = n-1, depth-1 {
+		if canonical := ReadCanonicalHash(db, n); canonical != hash {
+			return fmt.Errorf("%w: canonical hash of block %d mismatch: have %x, want %x", ErrCorrupted, n, canonical, hash)
+		}
+		header := ReadHeader(db, hash, n)
+		if header == nil {
+			return fmt.Errorf("%w: header of block %d [%x] missing", ErrCorrupted, n, hash)
+		}
+		if header.Hash() != hash {
+			return fmt.Errorf("%w: header of block %d hash mismatch: have %x, want %x", ErrCorrupted, n, header.Hash(), hash)
+		}
+		body := ReadBody(db, hash, n)
+		if body == nil {
+			return fmt.Errorf("%w: body of block %d [%x] missing", ErrCorrupted, n, hash)
+		}
+		if len(body.Transactions) > 0 && len(ReadReceiptsRLP(db, hash, n)) == 0 {
+			return fmt.Errorf("%w: receipts of block %d [%x] missing", ErrCorrupted, n, hash)
+		}
+		if n == 0 {
+			break
+		}
+		hash = header.ParentHash
+	}
+	return nil
+}
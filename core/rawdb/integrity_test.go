@@ -0,0 +1,65 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/core/types"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	db := NewMemoryDatabase()
+	if err := CheckIntegrity(db, 128); err != nil {
+		t.Fatalf("empty database corrupted: %v", err)
+	}
+	var blocks []*types.Block
+	parent := (*types.Block)(nil)
+	for i := int64(0); i < 5; i++ {
+		header := &types.Header{Number: big.NewInt(i)}
+		if parent != nil {
+			header.ParentHash = parent.Hash()
+		}
+		block := types.NewBlockWithHeader(header).WithBody(nil, &types.Randomness{}, &types.EpochSnarkData{})
+		WriteBlock(db, block)
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		blocks, parent = append(blocks, block), block
+	}
+	WriteHeadBlockHash(db, parent.Hash())
+
+	// Checks deeper than the chain stop at the genesis block
+	if err := CheckIntegrity(db, 128); err != nil {
+		t.Fatalf("chain corrupted: %v", err)
+	}
+	// Missing bodies below the checked depth are left out
+	DeleteBody(db, blocks[1].Hash(), 1)
+	if err := CheckIntegrity(db, 2); err != nil {
+		t.Fatalf("chain corrupted below the checked depth: %v", err)
+	}
+	if err := CheckIntegrity(db, 128); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("missing body not detected: %v", err)
+	}
+	WriteBody(db, blocks[1].Hash(), 1, blocks[1].Body())
+
+	// Canonical hashes have to link the headers
+	WriteCanonicalHash(db, blocks[0].Hash(), 2)
+	if err := CheckIntegrity(db, 128); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("mismatching canonical hash not detected: %v", err)
+	}
+}
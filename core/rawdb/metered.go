@@ -0,0 +1,295 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+// Tables the operations of a metered database are attributed to.
+const (
+	tableHeaders    = "headers"
+	tableBodies     = "bodies"
+	tableReceipts   = "receipts"
+	tableTxLookup   = "txlookup"
+	tableBloomBits  = "bloombits"
+	tableTrie       = "trie"
+	tableCode       = "code"
+	tableSnapshot   = "snapshot"
+	tablePreimages  = "preimages"
+	tableRandomness = "randomness"
+	tableMetadata   = "metadata"
+	tableOther      = "other"
+)
+
+var meteredTables = []string{
+	tableHeaders, tableBodies, tableReceipts, tableTxLookup, tableBloomBits, tableTrie, tableCode,
+	tableSnapshot, tablePreimages, tableRandomness, tableMetadata, tableOther,
+}
+
+// keyTable returns the table a database key belongs to, using the same layout
+// as InspectDatabase.
+func keyTable(key []byte) string {
+	switch {
+	case bytes.HasPrefix(key, headerPrefix) && (len(key) == len(headerPrefix)+8+common.HashLength ||
+		bytes.HasSuffix(key, headerTDSuffix) || bytes.HasSuffix(key, headerHashSuffix)):
+		return tableHeaders
+	case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == len(headerNumberPrefix)+common.HashLength:
+		return tableHeaders
+	case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == len(blockBodyPrefix)+8+common.HashLength:
+		return tableBodies
+	case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == len(blockReceiptsPrefix)+8+common.HashLength:
+		return tableReceipts
+	case len(key) == common.HashLength:
+		return tableTrie
+	case bytes.HasPrefix(key, CodePrefix) && len(key) == len(CodePrefix)+common.HashLength:
+		return tableCode
+	case bytes.HasPrefix(key, txLookupPrefix) && len(key) == len(txLookupPrefix)+common.HashLength:
+		return tableTxLookup
+	case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == len(SnapshotAccountPrefix)+common.HashLength,
+		bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == len(SnapshotStoragePrefix)+2*common.HashLength:
+		return tableSnapshot
+	case bytes.HasPrefix(key, preimagePrefix):
+		return tablePreimages
+	case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == len(bloomBitsPrefix)+10+common.HashLength,
+		bytes.HasPrefix(key, BloomBitsIndexPrefix):
+		return tableBloomBits
+	case bytes.HasPrefix(key, []byte("db-randomness-")):
+		return tableRandomness
+	case bytes.HasPrefix(key, configPrefix), bytes.Equal(key, genesisSupplyKey):
+		return tableMetadata
+	}
+	for _, meta := range [][]byte{
+		databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, lastPivotKey,
+		fastTrieProgressKey, snapshotDisabledKey, snapshotRootKey, snapshotJournalKey,
+		snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
+		uncleanShutdownKey, badBlockKey, pinnedStatesKey,
+	} {
+		if bytes.Equal(key, meta) {
+			return tableMetadata
+		}
+	}
+	return tableOther
+}
+
+// prefixTable returns the table the keys with the given iteration prefix belong
+// to, or tableOther if they span several tables.
+func prefixTable(prefix []byte) string {
+	switch {
+	case len(prefix) == 0:
+		return tableOther
+	case bytes.HasPrefix(prefix, []byte("cht")), bytes.HasPrefix(prefix, []byte("blt")):
+		return tableOther // Light client tries, sharing the single byte prefixes below
+	case bytes.HasPrefix(prefix, headerPrefix), bytes.HasPrefix(prefix, headerNumberPrefix):
+		return tableHeaders
+	case bytes.HasPrefix(prefix, blockBodyPrefix):
+		return tableBodies
+	case bytes.HasPrefix(prefix, blockReceiptsPrefix):
+		return tableReceipts
+	case bytes.HasPrefix(prefix, txLookupPrefix):
+		return tableTxLookup
+	case bytes.HasPrefix(prefix, CodePrefix):
+		return tableCode
+	case bytes.HasPrefix(prefix, SnapshotAccountPrefix), bytes.HasPrefix(prefix, SnapshotStoragePrefix):
+		return tableSnapshot
+	case bytes.HasPrefix(prefix, bloomBitsPrefix):
+		return tableBloomBits
+	}
+	return keyTable(prefix)
+}
+
+// tableMeters are the metrics of the operations on a single table.
+type tableMeters struct {
+	get, has, put, delete, iterate metrics.Timer
+	batch                          metrics.Meter // Bytes written through batches
+}
+
+// meteredStore wraps a key-value store, timing every operation by table and
+// logging the ones slower than a threshold, so that the latency of block
+// processing and consensus can be attributed to the database.
+type meteredStore struct {
+	ethdb.KeyValueStore
+
+	tables     map[string]*tableMeters
+	batchTimer metrics.Timer
+	slow       time.Duration
+}
+
+// NewMeteredKeyValueStore wraps db, registering its metrics under namespace.
+// Operations taking longer than slow are logged, 0 disables the log. The store
+// is returned unwrapped if metrics are disabled and there's no slow threshold.
+func NewMeteredKeyValueStore(db ethdb.KeyValueStore, namespace string, slow time.Duration) ethdb.KeyValueStore {
+	if !metrics.Enabled && slow == 0 {
+		return db
+	}
+	store := &meteredStore{
+		KeyValueStore: db,
+		tables:        make(map[string]*tableMeters, len(meteredTables)),
+		batchTimer:    metrics.GetOrRegisterTimer(namespace+"batch/write", nil),
+		slow:          slow,
+	}
+	for _, table := range meteredTables {
+		prefix := namespace + "table/" + table + "/"
+		store.tables[table] = &tableMeters{
+			get:     metrics.GetOrRegisterTimer(prefix+"get", nil),
+			has:     metrics.GetOrRegisterTimer(prefix+"has", nil),
+			put:     metrics.GetOrRegisterTimer(prefix+"put", nil),
+			delete:  metrics.GetOrRegisterTimer(prefix+"delete", nil),
+			iterate: metrics.GetOrRegisterTimer(prefix+"iterate", nil),
+			batch:   metrics.GetOrRegisterMeter(prefix+"batch", nil),
+		}
+	}
+	return store
+}
+
+// done records an operation on key that started at start.
+func (db *meteredStore) done(op string, timer metrics.Timer, table string, key []byte, start time.Time) {
+	elapsed := time.Since(start)
+	timer.Update(elapsed)
+	if db.slow > 0 && elapsed >= db.slow {
+		log.Warn("Slow database operation", "op", op, "table", table, "key", hexutil.Bytes(key), "elapsed", common.PrettyDuration(elapsed))
+	}
+}
+
+// Has retrieves if a key is present in the key-value data store.
+func (db *meteredStore) Has(key []byte) (bool, error) {
+	table := keyTable(key)
+	defer db.done("has", db.tables[table].has, table, key, time.Now())
+	return db.KeyValueStore.Has(key)
+}
+
+// Get retrieves the given key if it's present in the key-value data store.
+func (db *meteredStore) Get(key []byte) ([]byte, error) {
+	table := keyTable(key)
+	defer db.done("get", db.tables[table].get, table, key, time.Now())
+	return db.KeyValueStore.Get(key)
+}
+
+// Put inserts the given value into the key-value data store.
+func (db *meteredStore) Put(key []byte, value []byte) error {
+	table := keyTable(key)
+	defer db.done("put", db.tables[table].put, table, key, time.Now())
+	return db.KeyValueStore.Put(key, value)
+}
+
+// Delete removes the key from the key-value data store.
+func (db *meteredStore) Delete(key []byte) error {
+	table := keyTable(key)
+	defer db.done("delete", db.tables[table].delete, table, key, time.Now())
+	return db.KeyValueStore.Delete(key)
+}
+
+// NewIterator creates a binary-alphabetical iterator over a subset of database
+// content with a particular key prefix, starting at a particular initial key.
+// The iteration is attributed to the table of the prefix, and timed from its
+// creation to its release.
+func (db *meteredStore) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	table := prefixTable(prefix)
+	return &meteredIterator{
+		Iterator: db.KeyValueStore.NewIterator(prefix, start),
+		db:       db,
+		table:    table,
+		prefix:   prefix,
+		start:    time.Now(),
+	}
+}
+
+// NewBatch creates a write-only key-value store that buffers changes to its host
+// database until a final write is called.
+func (db *meteredStore) NewBatch() ethdb.Batch {
+	return &meteredBatch{Batch: db.KeyValueStore.NewBatch(), db: db, sizes: make(map[string]int)}
+}
+
+// meteredIterator times the iteration of a metered store. Steps slower than the
+// threshold are logged, not entire iterations, which legitimately take long.
+type meteredIterator struct {
+	ethdb.Iterator
+	db     *meteredStore
+	table  string
+	prefix []byte
+	start  time.Time
+}
+
+// Next moves the iterator to the next key/value pair.
+func (it *meteredIterator) Next() bool {
+	start := time.Now()
+	next := it.Iterator.Next()
+	if elapsed := time.Since(start); it.db.slow > 0 && elapsed >= it.db.slow {
+		log.Warn("Slow database operation", "op", "iterate", "table", it.table, "prefix", hexutil.Bytes(it.prefix), "elapsed", common.PrettyDuration(elapsed))
+	}
+	return next
+}
+
+// Release releases associated resources, recording the iteration time.
+func (it *meteredIterator) Release() {
+	it.Iterator.Release()
+	it.db.tables[it.table].iterate.UpdateSince(it.start)
+}
+
+// meteredBatch accounts the data written by a batch to the tables of its keys.
+type meteredBatch struct {
+	ethdb.Batch
+	db    *meteredStore
+	sizes map[string]int
+}
+
+// Put inserts the given value into the batch for later committing.
+func (b *meteredBatch) Put(key, value []byte) error {
+	b.sizes[keyTable(key)] += len(key) + len(value)
+	return b.Batch.Put(key, value)
+}
+
+// Delete inserts the a key removal into the batch for later committing.
+func (b *meteredBatch) Delete(key []byte) error {
+	b.sizes[keyTable(key)] += len(key)
+	return b.Batch.Delete(key)
+}
+
+// Write flushes any accumulated data to disk.
+func (b *meteredBatch) Write() error {
+	start := time.Now()
+	err := b.Batch.Write()
+	elapsed := time.Since(start)
+
+	b.db.batchTimer.Update(elapsed)
+	for table, size := range b.sizes {
+		b.db.tables[table].batch.Mark(int64(size))
+	}
+	if b.db.slow > 0 && elapsed >= b.db.slow {
+		ctx := []interface{}{"op", "batch", "size", common.StorageSize(b.ValueSize()), "elapsed", common.PrettyDuration(elapsed)}
+		for _, table := range meteredTables {
+			if size, ok := b.sizes[table]; ok {
+				ctx = append(ctx, table, common.StorageSize(size))
+			}
+		}
+		log.Warn("Slow database operation", ctx...)
+	}
+	return err
+}
+
+// Reset resets the batch for reuse.
+func (b *meteredBatch) Reset() {
+	b.Batch.Reset()
+	b.sizes = make(map[string]int)
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/ethdb/memorydb"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+// Tests that database keys are attributed to the table of their schema.
+func TestKeyTable(t *testing.T) {
+	hash := common.Hash{'h'}
+	tests := []struct {
+		key   []byte
+		table string
+	}{
+		{headerKey(1, hash), tableHeaders},
+		{headerTDKey(1, hash), tableHeaders},
+		{headerHashKey(1), tableHeaders},
+		{headerNumberKey(hash), tableHeaders},
+		{blockBodyKey(1, hash), tableBodies},
+		{blockReceiptsKey(1, hash), tableReceipts},
+		{txLookupKey(hash), tableTxLookup},
+		{hash.Bytes(), tableTrie},
+		{codeKey(hash), tableCode},
+		{accountSnapshotKey(hash), tableSnapshot},
+		{storageSnapshotKey(hash, hash), tableSnapshot},
+		{preimageKey(hash), tablePreimages},
+		{bloomBitsKey(1, 1, hash), tableBloomBits},
+		{randomnessCommitmentKey(hash), tableRandomness},
+		{randomnessJournalKey(hash), tableRandomness},
+		{genesisSupplyKey, tableMetadata},
+		{headBlockKey, tableMetadata},
+		{[]byte("unknown"), tableOther},
+	}
+	for _, test := range tests {
+		if table := keyTable(test.key); table != test.table {
+			t.Errorf("key %x: table mismatch: have %s, want %s", test.key, table, test.table)
+		}
+	}
+	if table := prefixTable(headerKeyPrefix(1)); table != tableHeaders {
+		t.Errorf("header prefix: table mismatch: have %s, want %s", table, tableHeaders)
+	}
+	if table := prefixTable([]byte("cht-")); table != tableOther {
+		t.Errorf("cht prefix: table mismatch: have %s, want %s", table, tableOther)
+	}
+}
+
+// Tests that a metered store passes the operations through and accounts them
+// to the tables of their keys.
+func TestMeteredStore(t *testing.T) {
+	defer func(enabled bool) { metrics.Enabled = enabled }(metrics.Enabled)
+	metrics.Enabled = true
+
+	var (
+		db   = NewMeteredKeyValueStore(memorydb.New(), "test/metered/", time.Hour)
+		hash = common.Hash{1}
+	)
+	if err := db.Put(headerKey(1, hash), []byte{1}); err != nil {
+		t.Fatalf("failed to put header: %v", err)
+	}
+	if value, err := db.Get(headerKey(1, hash)); err != nil || !bytes.Equal(value, []byte{1}) {
+		t.Fatalf("header mismatch: have %x, %v, want 01", value, err)
+	}
+	batch := db.NewBatch()
+	if err := batch.Put(blockReceiptsKey(1, hash), []byte{2, 3}); err != nil {
+		t.Fatalf("failed to batch receipts: %v", err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	it := db.NewIterator(blockReceiptsPrefix, nil)
+	if !it.Next() || !bytes.Equal(it.Value(), []byte{2, 3}) {
+		t.Fatalf("receipts not iterated")
+	}
+	it.Release()
+
+	if count := metrics.GetOrRegisterTimer("test/metered/table/headers/put", nil).Count(); count != 1 {
+		t.Errorf("header puts mismatch: have %d, want 1", count)
+	}
+	if count := metrics.GetOrRegisterTimer("test/metered/table/headers/get", nil).Count(); count != 1 {
+		t.Errorf("header gets mismatch: have %d, want 1", count)
+	}
+	if size := metrics.GetOrRegisterMeter("test/metered/table/receipts/batch", nil).Count(); size != int64(len(blockReceiptsKey(1, hash))+2) {
+		t.Errorf("receipts batch size mismatch: have %d, want %d", size, len(blockReceiptsKey(1, hash))+2)
+	}
+	if count := metrics.GetOrRegisterTimer("test/metered/table/receipts/iterate", nil).Count(); count != 1 {
+		t.Errorf("receipts iterations mismatch: have %d, want 1", count)
+	}
+	if count := metrics.GetOrRegisterTimer("test/metered/batch/write", nil).Count(); count != 1 {
+		t.Errorf("batch writes mismatch: have %d, want 1", count)
+	}
+}
@@ -72,6 +72,9 @@ var (
 	// badBlockKey tracks the list of bad blocks seen by local
 	badBlockKey = []byte("InvalidBlock")
 
+	// pinnedStatesKey tracks the historical states kept from pruning
+	pinnedStatesKey = []byte("PinnedStates")
+
 	// uncleanShutdownKey tracks the list of local crashes
 	uncleanShutdownKey = []byte("unclean-shutdown") // config prefix for the db
 
@@ -115,6 +118,14 @@ const (
 
 	// freezerDifficultyTable indicates the name of the freezer total difficulty table.
 	freezerDifficultyTable = "diffs"
+
+	// freezerRandomnessTable indicates the name of the freezer table of the parent
+	// hashes of the blocks whose randomness commitments were made by this node.
+	freezerRandomnessTable = "randomness"
+
+	// freezerIstanbulSnapshotTable indicates the name of the freezer table of the
+	// istanbul validator set snapshots, stored for the last blocks of the epochs.
+	freezerIstanbulSnapshotTable = "istanbul-snapshots"
 )
 
 // FreezerNoSnappy configures whether compression is disabled for the ancient-tables.
@@ -125,6 +136,18 @@ var FreezerNoSnappy = map[string]bool{
 	freezerBodiesTable:     false,
 	freezerReceiptTable:    false,
 	freezerDifficultyTable: true,
+
+	freezerRandomnessTable:       true,
+	freezerIstanbulSnapshotTable: false,
+}
+
+// freezerCeloTables are the ancient-tables of the celo data of the blocks. Most
+// blocks have none, and their items are empty. A freezer created before these
+// tables has them filled by the migration on startup, instead of truncating the
+// other tables to their length.
+var freezerCeloTables = map[string]bool{
+	freezerRandomnessTable:       true,
+	freezerIstanbulSnapshotTable: true,
 }
 
 // LegacyTxLookupEntry is the legacy TxLookupEntry definition with some unnecessary
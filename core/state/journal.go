@@ -124,6 +124,9 @@ type (
 	addLogChange struct {
 		txhash common.Hash
 	}
+	addTransferChange struct {
+		txhash common.Hash
+	}
 	addPreimageChange struct {
 		hash common.Hash
 	}
@@ -235,6 +238,20 @@ func (ch addLogChange) dirtied() *common.Address {
 	return nil
 }
 
+func (ch addTransferChange) revert(s *StateDB) {
+	transfers := s.transfers[ch.txhash]
+	if len(transfers) == 1 {
+		delete(s.transfers, ch.txhash)
+	} else {
+		s.transfers[ch.txhash] = transfers[:len(transfers)-1]
+	}
+	s.transferSize--
+}
+
+func (ch addTransferChange) dirtied() *common.Address {
+	return nil
+}
+
 func (ch addPreimageChange) revert(s *StateDB) {
 	delete(s.preimages, ch.hash)
 }
@@ -0,0 +1,65 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"github.com/celo-org/celo-blockchain/common"
+)
+
+// LoadedStorage returns the accounts loaded by the state along with the storage
+// slots read in each of them.
+func (s *StateDB) LoadedStorage() map[common.Address][]common.Hash {
+	loaded := make(map[common.Address][]common.Hash, len(s.stateObjects))
+	for addr, obj := range s.stateObjects {
+		slots := make([]common.Hash, 0, len(obj.originStorage))
+		for key := range obj.originStorage {
+			slots = append(slots, key)
+		}
+		loaded[addr] = slots
+	}
+	return loaded
+}
+
+// PrefetchStorage schedules the accounts and storage slots into the trie
+// prefetcher, which loads their trie nodes concurrently, a storage trie per
+// account, before they are read or updated. It's a noop unless the prefetcher is
+// started.
+func (s *StateDB) PrefetchStorage(storage map[common.Address][]common.Hash) {
+	if s.prefetcher == nil {
+		return
+	}
+	addrs := make([][]byte, 0, len(storage))
+	for addr := range storage {
+		addrs = append(addrs, common.CopyBytes(addr[:]))
+	}
+	s.prefetcher.prefetch(s.originalRoot, addrs)
+
+	for addr, slots := range storage {
+		if len(slots) == 0 {
+			continue
+		}
+		obj := s.getStateObject(addr)
+		if obj == nil || obj.data.Root == emptyRoot {
+			continue
+		}
+		keys := make([][]byte, 0, len(slots))
+		for _, slot := range slots {
+			keys = append(keys, common.CopyBytes(slot[:]))
+		}
+		s.prefetcher.prefetch(obj.data.Root, keys)
+	}
+}
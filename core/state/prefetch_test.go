@@ -0,0 +1,59 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+)
+
+func TestPrefetchLoadedStorage(t *testing.T) {
+	var (
+		filled = filledStateDB()
+		addr   = common.HexToAddress("0xaffeaffeaffeaffeaffeaffeaffeaffeaffeaffe")
+		skey   = common.HexToHash("aaa")
+	)
+	root, err := filled.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, _ := New(root, filled.db, nil)
+	reader.GetState(addr, skey)
+	loaded := reader.LoadedStorage()
+	if len(loaded) != 1 || len(loaded[addr]) != 1 || loaded[addr][0] != skey {
+		t.Fatalf("loaded storage mismatch: have %v", loaded)
+	}
+
+	// Nothing is scheduled without a running prefetcher
+	state, _ := New(root, filled.db, nil)
+	state.PrefetchStorage(loaded)
+
+	state.prefetcher = newTriePrefetcher(state.db, root, "")
+	defer state.StopPrefetcher()
+	state.PrefetchStorage(loaded)
+	storageRoot := state.getStateObject(addr).data.Root
+	if len(state.prefetcher.fetchers) != 2 || state.prefetcher.fetchers[root] == nil || state.prefetcher.fetchers[storageRoot] == nil {
+		t.Fatalf("prefetched tries mismatch: have %d", len(state.prefetcher.fetchers))
+	}
+	if trie := state.prefetcher.trie(storageRoot); trie == nil || trie.Hash() != storageRoot {
+		t.Errorf("storage trie not prefetched")
+	}
+	if value := state.GetState(addr, skey); value != common.HexToHash("bbb") {
+		t.Errorf("prefetched slot mismatch: have %x", value)
+	}
+}
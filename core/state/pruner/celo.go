@@ -0,0 +1,284 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus"
+	mockEngine "github.com/celo-org/celo-blockchain/consensus/consensustest"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/contracts"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/core/vm/vmcontext"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/rlp"
+	"github.com/celo-org/celo-blockchain/trie"
+)
+
+// maxTargetSearch bounds the canonical blocks searched back from the head for
+// the block of the pruning target.
+const maxTargetSearch = 1024
+
+// CoreContract is a core contract whose state the pruning target covers.
+type CoreContract struct {
+	Name           string
+	Address        common.Address // Proxy holding the storage
+	Implementation common.Address // Contract the proxy delegates to, if set
+	Nodes          int            // Trie nodes of the storage
+}
+
+// PruneReport describes the pruning target, and for a dry run, the state data
+// the pruning would delete.
+type PruneReport struct {
+	Root   common.Hash
+	Number *uint64 // Block of the target, nil if not found in the recent canonical chain
+	Epoch  uint64
+
+	// EpochBlocks are the last blocks of the epochs between the target and the
+	// head, re-executed with their epoch rewards and elections once the node
+	// rewinds to the target.
+	EpochBlocks []uint64
+
+	Contracts []CoreContract
+	Nodes     int // Stale trie nodes and codes
+	Size      common.StorageSize
+}
+
+func (r *PruneReport) log() {
+	ctx := []interface{}{"root", r.Root}
+	if r.Number != nil {
+		ctx = append(ctx, "number", *r.Number, "epoch", r.Epoch, "epochblocks", len(r.EpochBlocks))
+	}
+	log.Info("Verified the core contracts state of the pruning target", append(ctx, "contracts", len(r.Contracts))...)
+}
+
+// DryRun reports the state data the pruning to the given root would delete,
+// without deleting anything, after verifying the target as Prune does. The
+// target is selected the same way. An interrupted pruning has to be resumed
+// first, part of its state being deleted already.
+func (p *Pruner) DryRun(root common.Hash) (*PruneReport, error) {
+	_, stateBloomRoot, err := findBloomFilter(p.datadir)
+	if err != nil {
+		return nil, err
+	}
+	if stateBloomRoot != (common.Hash{}) {
+		return nil, fmt.Errorf("interrupted pruning to %x has to be resumed", stateBloomRoot)
+	}
+	root, layers, err := p.selectTarget(root)
+	if err != nil {
+		return nil, err
+	}
+	report, err := p.verifyCeloState(root)
+	if err != nil {
+		return nil, err
+	}
+	pinned := pinnedRoots(p.db)
+	if err := p.markActiveState(root, pinned); err != nil {
+		return nil, err
+	}
+	middleRoots := middleStateRoots(layers, root, pinned)
+
+	iter := p.db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		stale, err := isStale(iter.Key(), p.stateBloom, middleRoots)
+		if err != nil {
+			return nil, err
+		}
+		if stale {
+			report.Nodes++
+			report.Size += common.StorageSize(len(iter.Key()) + len(iter.Value()))
+		}
+	}
+	return report, iter.Error()
+}
+
+// verifyCeloState checks that the database holds the whole state of the registry
+// and of the core contracts in the target state, their accounts, codes and
+// storage tries, as the trie nodes missing there can't be regenerated once the
+// other states are pruned. The contracts are resolved through the registry of
+// the target state.
+func (p *Pruner) verifyCeloState(root common.Hash) (*PruneReport, error) {
+	chainConfig := rawdb.ReadChainConfig(p.db, rawdb.ReadCanonicalHash(p.db, 0))
+	if chainConfig == nil {
+		return nil, errors.New("missing chain config")
+	}
+	report := &PruneReport{Root: root}
+
+	// The system calls are made in the context of the target block, or of the
+	// head if it's older than searched.
+	header := p.targetHeader(root)
+	if header != nil && chainConfig.Istanbul != nil {
+		var (
+			number    = header.Number.Uint64()
+			epochSize = chainConfig.Istanbul.Epoch
+		)
+		report.Number, report.Epoch = &number, istanbul.GetEpochNumber(number, epochSize)
+		for n := number + 1; n <= p.headHeader.Number.Uint64(); n++ {
+			if istanbul.IsLastBlockOfEpoch(n, epochSize) {
+				report.EpochBlocks = append(report.EpochBlocks, n)
+			}
+		}
+	} else {
+		log.Warn("Pruning target not found in the recent canonical chain", "root", root)
+		header = p.headHeader
+	}
+	accTrie, err := trie.NewSecure(root, trie.NewDatabase(p.db))
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := state.New(root, state.NewDatabase(p.db), nil)
+	if err != nil {
+		return nil, err
+	}
+	// The registry is verified before being called to resolve the others
+	registry, err := verifyCoreContract(p.db, accTrie, statedb, "Registry", config.RegistrySmartContractAddress)
+	if err != nil {
+		return nil, err
+	}
+	report.Contracts = append(report.Contracts, registry)
+
+	vmRunner := vmcontext.NewEVMRunner(&headerChain{db: p.db, config: chainConfig, engine: mockEngine.NewFaker()}, header, statedb)
+	for _, contract := range config.CoreContracts {
+		address, err := contracts.GetRegisteredAddress(vmRunner, contract.Id)
+		if err == contracts.ErrSmartContractNotDeployed || err == contracts.ErrRegistryContractNotDeployed {
+			log.Debug("Core contract not registered", "name", contract.Name)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %v", contract.Name, err)
+		}
+		verified, err := verifyCoreContract(p.db, accTrie, statedb, contract.Name, address)
+		if err != nil {
+			return nil, err
+		}
+		report.Contracts = append(report.Contracts, verified)
+	}
+	if err := statedb.Error(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// targetHeader returns the canonical header of the block with the given state
+// root, searching back from the head.
+func (p *Pruner) targetHeader(root common.Hash) *types.Header {
+	header := p.headHeader
+	for i := 0; i < maxTargetSearch && header != nil; i++ {
+		if header.Root == root {
+			return header
+		}
+		if header.Number.Uint64() == 0 {
+			break
+		}
+		header = rawdb.ReadHeader(p.db, header.ParentHash, header.Number.Uint64()-1)
+	}
+	return nil
+}
+
+// verifyCoreContract checks that the database holds the state of a core contract
+// proxy and the code of its implementation.
+func verifyCoreContract(db ethdb.Database, accTrie *trie.SecureTrie, statedb *state.StateDB, name string, address common.Address) (CoreContract, error) {
+	contract := CoreContract{Name: name, Address: address}
+	nodes, err := verifyContractState(db, accTrie, address)
+	if err != nil {
+		return contract, fmt.Errorf("%s state not covered: %v", name, err)
+	}
+	contract.Nodes = nodes
+	if impl := statedb.GetState(address, config.ProxyImplementationSlot); impl != (common.Hash{}) {
+		contract.Implementation = common.BytesToAddress(impl.Bytes())
+		if _, err := verifyContractState(db, accTrie, contract.Implementation); err != nil {
+			return contract, fmt.Errorf("%s implementation not covered: %v", name, err)
+		}
+	}
+	return contract, nil
+}
+
+// verifyContractState checks that the account of the contract, its code and its
+// whole storage trie are in the database, and returns the trie nodes of the
+// storage.
+func verifyContractState(db ethdb.Database, accTrie *trie.SecureTrie, address common.Address) (int, error) {
+	blob, err := accTrie.TryGet(address.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	if len(blob) == 0 {
+		return 0, fmt.Errorf("missing account %x", address)
+	}
+	var acc types.StateAccount
+	if err := rlp.DecodeBytes(blob, &acc); err != nil {
+		return 0, err
+	}
+	if bytes.Equal(acc.CodeHash, emptyCode) {
+		return 0, fmt.Errorf("no code at %x", address)
+	}
+	if len(rawdb.ReadCode(db, common.BytesToHash(acc.CodeHash))) == 0 {
+		return 0, fmt.Errorf("missing code %x", acc.CodeHash)
+	}
+	if acc.Root == emptyRoot {
+		return 0, nil
+	}
+	storageTrie, err := trie.NewSecure(acc.Root, trie.NewDatabase(db))
+	if err != nil {
+		return 0, err
+	}
+	var (
+		nodes int
+		iter  = storageTrie.NodeIterator(nil)
+	)
+	for iter.Next(true) {
+		if iter.Hash() != (common.Hash{}) {
+			nodes++
+		}
+	}
+	return nodes, iter.Error()
+}
+
+// headerChain is the chain context of the system calls resolving the core
+// contracts, backed by the headers of the database.
+type headerChain struct {
+	db     ethdb.Database
+	config *params.ChainConfig
+	engine consensus.Engine
+}
+
+func (c *headerChain) Engine() consensus.Engine { return c.engine }
+
+func (c *headerChain) Config() *params.ChainConfig { return c.config }
+
+func (c *headerChain) GetVMConfig() *vm.Config { return &vm.Config{} }
+
+func (c *headerChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return rawdb.ReadHeader(c.db, hash, number)
+}
+
+func (c *headerChain) GetHeaderByNumber(number uint64) *types.Header {
+	hash := rawdb.ReadCanonicalHash(c.db, number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return rawdb.ReadHeader(c.db, hash, number)
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	mockEngine "github.com/celo-org/celo-blockchain/consensus/consensustest"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+// countStateEntries returns the trie nodes and codes of the database.
+func countStateEntries(db ethdb.Database) int {
+	var count int
+	iter := db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		if isCode, _ := rawdb.IsCodeKey(iter.Key()); isCode || len(iter.Key()) == common.HashLength {
+			count++
+		}
+	}
+	return count
+}
+
+func TestPruneCeloState(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		impl     = common.HexToAddress("0x1001")
+		contract = common.HexToAddress("0x1002")
+		registry = core.DefaultGenesisBlock().Alloc[config.RegistrySmartContractAddress]
+	)
+	// The registry implementation returns the contract for every id
+	registry.Storage = map[common.Hash]common.Hash{config.ProxyImplementationSlot: common.BytesToHash(impl.Bytes())}
+	implCode := append(append([]byte{byte(vm.PUSH20)}, contract.Bytes()...), byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN))
+
+	chainConfig := *params.IstanbulTestChainConfig
+	istanbulConfig := *chainConfig.Istanbul
+	istanbulConfig.Epoch = 10
+	chainConfig.Istanbul = &istanbulConfig
+	gspec := &core.Genesis{
+		Config: &chainConfig,
+		Alloc: core.GenesisAlloc{
+			address:                             {Balance: big.NewInt(1000000000000000)},
+			config.RegistrySmartContractAddress: registry,
+			impl:                                {Code: implCode, Balance: new(big.Int)},
+			contract:                            {Code: []byte{byte(vm.STOP)}, Balance: new(big.Int), Storage: map[common.Hash]common.Hash{{0x01}: {0x01}}},
+		},
+	}
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		gendb   = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	gspec.MustCommit(db)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, mockEngine.NewFaker(), gendb, 140, func(i int, block *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, block.MinimumGasPrice(nil), nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	cacheConfig := &core.CacheConfig{TrieCleanLimit: 256, TrieDirtyDisabled: true, SnapshotLimit: 256, SnapshotWait: true}
+	chain, err := core.NewBlockChain(db, cacheConfig, gspec.Config, mockEngine.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	chain.Stop()
+
+	datadir, err := os.MkdirTemp("", "pruner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+	pruner, err := NewPruner(db, datadir, filepath.Join(datadir, "triecache"), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := countStateEntries(db)
+	report, err := pruner.DryRun(common.Hash{})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if report.Number == nil || *report.Number != 13 || report.Root != blocks[12].Root() || report.Epoch != 2 {
+		t.Errorf("target mismatch: have %x, number %v and epoch %d, want %x, 13 and 2", report.Root, report.Number, report.Epoch, blocks[12].Root())
+	}
+	if len(report.EpochBlocks) != 13 || report.EpochBlocks[0] != 20 || report.EpochBlocks[12] != 140 {
+		t.Errorf("epoch blocks mismatch: have %v", report.EpochBlocks)
+	}
+	if len(report.Contracts) != len(config.CoreContracts)+1 {
+		t.Fatalf("core contracts mismatch: have %d, want %d", len(report.Contracts), len(config.CoreContracts)+1)
+	}
+	if have := report.Contracts[0]; have.Name != "Registry" || have.Implementation != impl || have.Nodes == 0 {
+		t.Errorf("registry mismatch: have %+v", have)
+	}
+	if have := report.Contracts[1]; have.Address != contract || have.Nodes != 1 {
+		t.Errorf("core contract mismatch: have %+v", have)
+	}
+	if report.Nodes == 0 || report.Size == 0 {
+		t.Errorf("no reclaimable state data reported")
+	}
+	if have := countStateEntries(db); have != before {
+		t.Fatalf("dry run changed the state entries: have %d, want %d", have, before)
+	}
+
+	// A target missing the code of an implementation is refused
+	codeHash := crypto.Keccak256Hash(implCode)
+	rawdb.DeleteCode(db, codeHash)
+	if _, err := pruner.DryRun(common.Hash{}); err == nil || !strings.Contains(err.Error(), "Registry implementation") {
+		t.Errorf("missing implementation code not detected: %v", err)
+	}
+	if err := pruner.Prune(common.Hash{}); err == nil {
+		t.Errorf("pruned without the implementation code")
+	}
+	rawdb.WriteCode(db, codeHash, implCode)
+
+	if err := pruner.Prune(common.Hash{}); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	if have := countStateEntries(db); have != before-report.Nodes {
+		t.Errorf("pruned state entries mismatch: have %d left, want %d", have, before-report.Nodes)
+	}
+	if blob := rawdb.ReadTrieNode(db, report.Root); len(blob) == 0 {
+		t.Errorf("target state pruned")
+	}
+}
@@ -135,21 +135,11 @@ func prune(snaptree *snapshot.Tree, root common.Hash, maindb ethdb.Database, sta
 		// - trie node
 		// - legacy contract code
 		// - new-scheme contract code
-		isCode, codeKey := rawdb.IsCodeKey(key)
-		if len(key) == common.HashLength || isCode {
-			checkKey := key
-			if isCode {
-				checkKey = codeKey
-			}
-			if _, exist := middleStateRoots[common.BytesToHash(checkKey)]; exist {
-				log.Debug("Forcibly delete the middle state roots", "hash", common.BytesToHash(checkKey))
-			} else {
-				if ok, err := stateBloom.Contain(checkKey); err != nil {
-					return err
-				} else if ok {
-					continue
-				}
-			}
+		stale, err := isStale(key, stateBloom, middleStateRoots)
+		if err != nil {
+			return err
+		}
+		if stale {
 			count += 1
 			size += common.StorageSize(len(key) + len(iter.Value()))
 			batch.Delete(key)
@@ -243,6 +233,49 @@ func (p *Pruner) Prune(root common.Hash) error {
 	if stateBloomRoot != (common.Hash{}) {
 		return RecoverPruning(p.datadir, p.db, p.trieCachePath)
 	}
+	root, layers, err := p.selectTarget(root)
+	if err != nil {
+		return err
+	}
+	// The core contracts of Celo have to be kept whole, refuse to prune if
+	// the target doesn't cover their state.
+	report, err := p.verifyCeloState(root)
+	if err != nil {
+		return err
+	}
+	report.log()
+
+	// Before start the pruning, delete the clean trie cache first.
+	// It's necessary otherwise in the next restart we will hit the
+	// deleted state root in the "clean cache" so that the incomplete
+	// state is picked for usage.
+	deleteCleanTrieCache(p.trieCachePath)
+
+	// All the state roots of the middle layer should be forcibly pruned,
+	// otherwise the dangling state will be left. Pinned ones are kept.
+	var (
+		pinned      = pinnedRoots(p.db)
+		middleRoots = middleStateRoots(layers, root, pinned)
+	)
+	// Mark the target, genesis and pinned states in the bloom filter
+	start := time.Now()
+	if err := p.markActiveState(root, pinned); err != nil {
+		return err
+	}
+	filterName := bloomFilterName(p.datadir, root)
+
+	log.Info("Writing state bloom to disk", "name", filterName)
+	if err := p.stateBloom.Commit(filterName, filterName+stateBloomFileTempSuffix); err != nil {
+		return err
+	}
+	log.Info("State bloom filter committed", "name", filterName)
+	return prune(p.snaptree, root, p.db, p.stateBloom, filterName, middleRoots, start)
+}
+
+// selectTarget resolves the state root to prune to, the bottom-most snapshot
+// layer with its state available if none is specified, and returns it with the
+// snapshot layers above it.
+func (p *Pruner) selectTarget(root common.Hash) (common.Hash, []snapshot.Snapshot, error) {
 	// If the target state root is not specified, use the HEAD-127 as the
 	// target. The reason for picking it is:
 	// - in most of the normal cases, the related state is available
@@ -257,7 +290,7 @@ func (p *Pruner) Prune(root common.Hash) error {
 			// Reject if the accumulated diff layers are less than 128. It
 			// means in most of normal cases, there is no associated state
 			// with bottom-most diff layer.
-			return fmt.Errorf("snapshot not old enough yet: need %d more blocks", 128-len(layers))
+			return common.Hash{}, nil, fmt.Errorf("snapshot not old enough yet: need %d more blocks", 128-len(layers))
 		}
 		// Use the bottom-most diff layer as the target
 		root = layers[len(layers)-1].Root()
@@ -288,9 +321,9 @@ func (p *Pruner) Prune(root common.Hash) error {
 		}
 		if !found {
 			if len(layers) > 0 {
-				return errors.New("no snapshot paired state")
+				return common.Hash{}, nil, errors.New("no snapshot paired state")
 			}
-			return fmt.Errorf("associated state[%x] is not present", root)
+			return common.Hash{}, nil, fmt.Errorf("associated state[%x] is not present", root)
 		}
 	} else {
 		if len(layers) > 0 {
@@ -299,24 +332,14 @@ func (p *Pruner) Prune(root common.Hash) error {
 			log.Info("Selecting user-specified state as the pruning target", "root", root)
 		}
 	}
-	// Before start the pruning, delete the clean trie cache first.
-	// It's necessary otherwise in the next restart we will hit the
-	// deleted state root in the "clean cache" so that the incomplete
-	// state is picked for usage.
-	deleteCleanTrieCache(p.trieCachePath)
+	return root, layers, nil
+}
 
-	// All the state roots of the middle layer should be forcibly pruned,
-	// otherwise the dangling state will be left.
-	middleRoots := make(map[common.Hash]struct{})
-	for _, layer := range layers {
-		if layer.Root() == root {
-			break
-		}
-		middleRoots[layer.Root()] = struct{}{}
-	}
+// markActiveState puts all the state entries of the target state, the genesis
+// and the pinned states into the bloom filter.
+func (p *Pruner) markActiveState(root common.Hash, pinned map[common.Hash]struct{}) error {
 	// Traverse the target state, re-construct the whole state trie and
 	// commit to the given bloom filter.
-	start := time.Now()
 	if err := snapshot.GenerateTrie(p.snaptree, root, p.db, p.stateBloom); err != nil {
 		return err
 	}
@@ -325,14 +348,53 @@ func (p *Pruner) Prune(root common.Hash) error {
 	if err := extractGenesis(p.db, p.stateBloom); err != nil {
 		return err
 	}
-	filterName := bloomFilterName(p.datadir, root)
+	// Traverse the pinned historical states, put all their entries
+	// into the bloom filter too.
+	for pinnedRoot := range pinned {
+		log.Info("Keeping pinned state", "root", pinnedRoot)
+		if err := extractTrie(p.db, pinnedRoot, p.stateBloom); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	log.Info("Writing state bloom to disk", "name", filterName)
-	if err := p.stateBloom.Commit(filterName, filterName+stateBloomFileTempSuffix); err != nil {
-		return err
+// middleStateRoots returns the roots of the snapshot layers above the target,
+// apart from the pinned ones.
+func middleStateRoots(layers []snapshot.Snapshot, root common.Hash, pinned map[common.Hash]struct{}) map[common.Hash]struct{} {
+	roots := make(map[common.Hash]struct{})
+	for _, layer := range layers {
+		if layer.Root() == root {
+			break
+		}
+		if _, ok := pinned[layer.Root()]; !ok {
+			roots[layer.Root()] = struct{}{}
+		}
 	}
-	log.Info("State bloom filter committed", "name", filterName)
-	return prune(p.snaptree, root, p.db, p.stateBloom, filterName, middleRoots, start)
+	return roots
+}
+
+// isStale reports whether the database entry is state data not belonging to
+// the active states, the trie nodes and codes marked in the bloom filter, or is
+// the root of a middle layer.
+func isStale(key []byte, stateBloom *stateBloom, middleStateRoots map[common.Hash]struct{}) (bool, error) {
+	isCode, codeKey := rawdb.IsCodeKey(key)
+	if len(key) != common.HashLength && !isCode {
+		return false, nil
+	}
+	checkKey := key
+	if isCode {
+		checkKey = codeKey
+	}
+	if _, exist := middleStateRoots[common.BytesToHash(checkKey)]; exist {
+		log.Debug("Forcibly delete the middle state roots", "hash", common.BytesToHash(checkKey))
+		return true, nil
+	}
+	ok, err := stateBloom.Contain(checkKey)
+	if err != nil {
+		return false, err
+	}
+	return !ok, nil
 }
 
 // RecoverPruning will resume the pruning procedure during the system restart.
@@ -383,6 +445,7 @@ func RecoverPruning(datadir string, db ethdb.Database, trieCachePath string) err
 	var (
 		found       bool
 		layers      = snaptree.Snapshots(headBlock.Root(), 128, true)
+		pinned      = pinnedRoots(db)
 		middleRoots = make(map[common.Hash]struct{})
 	)
 	for _, layer := range layers {
@@ -390,7 +453,9 @@ func RecoverPruning(datadir string, db ethdb.Database, trieCachePath string) err
 			found = true
 			break
 		}
-		middleRoots[layer.Root()] = struct{}{}
+		if _, ok := pinned[layer.Root()]; !ok {
+			middleRoots[layer.Root()] = struct{}{}
+		}
 	}
 	if !found {
 		log.Error("Pruning target state is not existent")
@@ -410,7 +475,22 @@ func extractGenesis(db ethdb.Database, stateBloom *stateBloom) error {
 	if genesis == nil {
 		return errors.New("missing genesis block")
 	}
-	t, err := trie.NewSecure(genesis.Root(), trie.NewDatabase(db))
+	return extractTrie(db, genesis.Root(), stateBloom)
+}
+
+// pinnedRoots returns the roots of the historical states kept from pruning.
+func pinnedRoots(db ethdb.Database) map[common.Hash]struct{} {
+	roots := make(map[common.Hash]struct{})
+	for _, state := range rawdb.ReadPinnedStates(db) {
+		roots[state.Root] = struct{}{}
+	}
+	return roots
+}
+
+// extractTrie loads the state with the given root and commits all the state
+// entries into the given bloomfilter.
+func extractTrie(db ethdb.Database, root common.Hash, stateBloom *stateBloom) error {
+	t, err := trie.NewSecure(root, trie.NewDatabase(db))
 	if err != nil {
 		return err
 	}
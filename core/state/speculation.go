@@ -0,0 +1,74 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"github.com/celo-org/celo-blockchain/common"
+)
+
+// TrackAccessedAccounts makes the state record every account read or written
+// from now on, including the ones that don't exist.
+func (s *StateDB) TrackAccessedAccounts() {
+	s.accessedAccounts = make(map[common.Address]struct{})
+	s.modifiedAccounts = make(map[common.Address]struct{})
+}
+
+// AccessedAccounts returns the accounts accessed since TrackAccessedAccounts was
+// called, nil if the state isn't tracking them.
+func (s *StateDB) AccessedAccounts() map[common.Address]struct{} {
+	return s.accessedAccounts
+}
+
+// ModifiedAccounts returns the accounts modified by the transactions finalised
+// since TrackAccessedAccounts was called. The changes made to the state before,
+// e.g. by the transactions of the block it was copied from, are left out.
+func (s *StateDB) ModifiedAccounts() map[common.Address]struct{} {
+	modified := make(map[common.Address]struct{}, len(s.modifiedAccounts))
+	for addr := range s.modifiedAccounts {
+		modified[addr] = struct{}{}
+	}
+	return modified
+}
+
+// MergeAccounts replaces the given accounts with their finalised version in src,
+// a state copied from s. The caller must ensure that the transactions applied to
+// src touched nothing else modified in s since, or the result is inconsistent.
+// Logs are not merged.
+func (s *StateDB) MergeAccounts(src *StateDB, addrs map[common.Address]struct{}) {
+	for addr := range addrs {
+		obj := src.stateObjects[addr]
+		if obj == nil {
+			continue
+		}
+		s.stateObjects[addr] = obj.deepCopy(s)
+		s.stateObjectsPending[addr] = struct{}{}
+		s.stateObjectsDirty[addr] = struct{}{}
+
+		if s.snap != nil {
+			if _, destructed := src.snapDestructs[obj.addrHash]; destructed {
+				s.snapDestructs[obj.addrHash] = struct{}{}
+				delete(s.snapAccounts, obj.addrHash)
+				delete(s.snapStorage, obj.addrHash)
+			}
+		}
+	}
+	for hash, preimage := range src.preimages {
+		if _, ok := s.preimages[hash]; !ok {
+			s.preimages[hash] = preimage
+		}
+	}
+}
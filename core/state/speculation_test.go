@@ -0,0 +1,55 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+)
+
+// Tests that the modified accounts of a tracking copy are the ones changed after
+// the copy, not the ones the original state changed before.
+func TestModifiedAccountsSinceTracking(t *testing.T) {
+	var (
+		earlier = common.HexToAddress("0x01")
+		read    = common.HexToAddress("0x02")
+		written = common.HexToAddress("0x03")
+	)
+	s, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	s.SetBalance(earlier, big.NewInt(1))
+	s.Finalise(true)
+
+	cpy := s.Copy()
+	cpy.TrackAccessedAccounts()
+	cpy.GetBalance(earlier)
+	cpy.GetBalance(read)
+	cpy.SetBalance(written, big.NewInt(2))
+	cpy.Finalise(true)
+
+	accessed, modified := cpy.AccessedAccounts(), cpy.ModifiedAccounts()
+	for _, addr := range []common.Address{earlier, read, written} {
+		if _, ok := accessed[addr]; !ok {
+			t.Errorf("account %x not accessed", addr)
+		}
+	}
+	if _, ok := modified[written]; !ok || len(modified) != 1 {
+		t.Errorf("modified accounts mismatch: have %v, want [%x]", modified, written)
+	}
+}
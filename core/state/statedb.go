@@ -78,6 +78,8 @@ type StateDB struct {
 	stateObjects        map[common.Address]*stateObject
 	stateObjectsPending map[common.Address]struct{} // State objects finalized but not yet written to the trie
 	stateObjectsDirty   map[common.Address]struct{} // State objects modified in the current execution
+	accessedAccounts    map[common.Address]struct{} // Accounts accessed since tracking started, nil if not tracking
+	modifiedAccounts    map[common.Address]struct{} // Accounts finalised since tracking started, nil if not tracking
 
 	// DB error.
 	// State objects are used by the consensus core and VM which are
@@ -94,6 +96,9 @@ type StateDB struct {
 	logs    map[common.Hash][]*types.Log
 	logSize uint
 
+	transfers    map[common.Hash][]*types.InternalTransfer
+	transferSize uint
+
 	preimages map[common.Hash][]byte
 
 	// Per-transaction access list
@@ -139,6 +144,7 @@ func New(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error)
 		stateObjectsPending: make(map[common.Address]struct{}),
 		stateObjectsDirty:   make(map[common.Address]struct{}),
 		logs:                make(map[common.Hash][]*types.Log),
+		transfers:           make(map[common.Hash][]*types.InternalTransfer),
 		preimages:           make(map[common.Hash][]byte),
 		journal:             newJournal(),
 		accessList:          newAccessList(),
@@ -213,6 +219,36 @@ func (s *StateDB) Logs() []*types.Log {
 	return logs
 }
 
+// AddTransfer records a move of CELO made by the current transaction. Transfers
+// of no value aren't recorded.
+func (s *StateDB) AddTransfer(transfer *types.InternalTransfer) {
+	if transfer.Value.Sign() == 0 {
+		return
+	}
+	s.journal.append(addTransferChange{txhash: s.thash})
+
+	transfer.TxHash = s.thash
+	transfer.TxIndex = uint(s.txIndex)
+	transfer.Index = s.transferSize
+	s.transfers[s.thash] = append(s.transfers[s.thash], transfer)
+	s.transferSize++
+}
+
+// GetTransfers returns the transfers recorded for a transaction.
+func (s *StateDB) GetTransfers(hash common.Hash) []*types.InternalTransfer {
+	return s.transfers[hash]
+}
+
+// Transfers returns all the transfers recorded, in the order they were made.
+func (s *StateDB) Transfers() []*types.InternalTransfer {
+	transfers := make([]*types.InternalTransfer, 0, s.transferSize)
+	for _, t := range s.transfers {
+		transfers = append(transfers, t...)
+	}
+	sort.Slice(transfers, func(i, j int) bool { return transfers[i].Index < transfers[j].Index })
+	return transfers
+}
+
 // AddPreimage records a SHA3 preimage seen by the VM.
 func (s *StateDB) AddPreimage(hash common.Hash, preimage []byte) {
 	if _, ok := s.preimages[hash]; !ok {
@@ -296,6 +332,16 @@ func (s *StateDB) GetCodeSize(addr common.Address) int {
 	return 0
 }
 
+// GetStorageRoot returns the storage root of an account as of the last commit
+// or intermediate root, the pending changes to its storage aren't reflected.
+func (s *StateDB) GetStorageRoot(addr common.Address) common.Hash {
+	stateObject := s.getStateObject(addr)
+	if stateObject == nil {
+		return common.Hash{}
+	}
+	return stateObject.data.Root
+}
+
 func (s *StateDB) GetCodeHash(addr common.Address) common.Hash {
 	stateObject := s.getStateObject(addr)
 	if stateObject == nil {
@@ -501,6 +547,9 @@ func (s *StateDB) getStateObject(addr common.Address) *stateObject {
 // flag set. This is needed by the state journal to revert to the correct s-
 // destructed object instead of wiping all knowledge about the state object.
 func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
+	if s.accessedAccounts != nil {
+		s.accessedAccounts[addr] = struct{}{}
+	}
 	// Prefer live objects if any is available
 	if obj := s.stateObjects[addr]; obj != nil {
 		return obj
@@ -655,6 +704,8 @@ func (s *StateDB) Copy() *StateDB {
 		refund:              s.refund,
 		logs:                make(map[common.Hash][]*types.Log, len(s.logs)),
 		logSize:             s.logSize,
+		transfers:           make(map[common.Hash][]*types.InternalTransfer, len(s.transfers)),
+		transferSize:        s.transferSize,
 		preimages:           make(map[common.Hash][]byte, len(s.preimages)),
 		journal:             newJournal(),
 		hasher:              crypto.NewKeccakState(),
@@ -698,6 +749,14 @@ func (s *StateDB) Copy() *StateDB {
 		}
 		state.logs[hash] = cpy
 	}
+	for hash, transfers := range s.transfers {
+		cpy := make([]*types.InternalTransfer, len(transfers))
+		for i, t := range transfers {
+			cpy[i] = new(types.InternalTransfer)
+			*cpy[i] = *t
+		}
+		state.transfers[hash] = cpy
+	}
 	for hash, preimage := range s.preimages {
 		state.preimages[hash] = preimage
 	}
@@ -772,6 +831,22 @@ func (s *StateDB) GetRefund() uint64 {
 	return s.refund
 }
 
+// DirtyAccounts returns the accounts modified since the last Finalise, along with
+// the storage slots modified in each of them.
+func (s *StateDB) DirtyAccounts() map[common.Address][]common.Hash {
+	dirties := make(map[common.Address][]common.Hash, len(s.journal.dirties))
+	for addr := range s.journal.dirties {
+		var slots []common.Hash
+		if obj, exist := s.stateObjects[addr]; exist {
+			for key := range obj.dirtyStorage {
+				slots = append(slots, key)
+			}
+		}
+		dirties[addr] = slots
+	}
+	return dirties
+}
+
 // Finalise finalises the state by removing the s destructed objects and clears
 // the journal as well as the refunds. Finalise, however, will not push any updates
 // into the tries just yet. Only IntermediateRoot or Commit will do that.
@@ -805,6 +880,9 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 		}
 		s.stateObjectsPending[addr] = struct{}{}
 		s.stateObjectsDirty[addr] = struct{}{}
+		if s.modifiedAccounts != nil {
+			s.modifiedAccounts[addr] = struct{}{}
+		}
 
 		// At this point, also ship the address off to the precacher. The precacher
 		// will start loading tries, and when the change is eventually committed,
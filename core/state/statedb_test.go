@@ -319,6 +319,13 @@ func newTestAction(addr common.Address, r *rand.Rand) testAction {
 			},
 			args: make([]int64, 1),
 		},
+		{
+			name: "AddTransfer",
+			fn: func(a testAction, s *StateDB) {
+				s.AddTransfer(&types.InternalTransfer{Kind: types.TransferCall, To: addr, Value: big.NewInt(a.args[0])})
+			},
+			args: make([]int64, 1),
+		},
 		{
 			name: "AddPreimage",
 			fn: func(a testAction, s *StateDB) {
@@ -467,6 +474,9 @@ func (test *snapshotTest) checkEqual(state, checkstate *StateDB) error {
 		return fmt.Errorf("got GetLogs(common.Hash{}) == %v, want GetLogs(common.Hash{}) == %v",
 			state.GetLogs(common.Hash{}, common.Hash{}), checkstate.GetLogs(common.Hash{}, common.Hash{}))
 	}
+	if !reflect.DeepEqual(state.Transfers(), checkstate.Transfers()) {
+		return fmt.Errorf("got Transfers() == %v, want Transfers() == %v", state.Transfers(), checkstate.Transfers())
+	}
 	return nil
 }
 
@@ -488,6 +498,26 @@ func TestTouchDelete(t *testing.T) {
 	}
 }
 
+func TestDirtyAccounts(t *testing.T) {
+	s := newStateTest()
+	var (
+		funded = common.HexToAddress("0x01")
+		stored = common.HexToAddress("0x02")
+		slot   = common.HexToHash("0x03")
+	)
+	s.state.AddBalance(funded, big.NewInt(1))
+	s.state.SetState(stored, slot, common.HexToHash("0x04"))
+
+	dirties := s.state.DirtyAccounts()
+	if len(dirties) != 2 || len(dirties[funded]) != 0 || len(dirties[stored]) != 1 || dirties[stored][0] != slot {
+		t.Fatalf("dirty accounts mismatch: have %v", dirties)
+	}
+	s.state.Finalise(false)
+	if dirties := s.state.DirtyAccounts(); len(dirties) != 0 {
+		t.Fatalf("dirty accounts after finalise: have %v", dirties)
+	}
+}
+
 // TestCopyOfCopy tests that modified objects are carried over to the copy, and the copy of the copy.
 // See https://github.com/ethereum/go-ethereum/pull/15225#issuecomment-380191512
 func TestCopyOfCopy(t *testing.T) {
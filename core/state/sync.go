@@ -28,6 +28,17 @@ import (
 
 // NewStateSync create a new state trie download scheduler.
 func NewStateSync(root common.Hash, database ethdb.KeyValueReader, bloom *trie.SyncBloom, onLeaf func(paths [][]byte, leaf []byte) error) *trie.Sync {
+	syncer := trie.NewSync(emptyRoot, database, nil, bloom)
+	AddStateSubTrie(syncer, root, nil, onLeaf)
+	return syncer
+}
+
+// AddStateSubTrie schedules the retrieval of the state trie node with the given
+// hash and hex path, and of the nodes below it missing from the database. The
+// path of a storage trie node starts with the hex path of its account. As for
+// the root in NewStateSync, the storage tries and codes of the accounts found
+// below an account trie node are retrieved as well.
+func AddStateSubTrie(syncer *trie.Sync, hash common.Hash, path []byte, onLeaf func(paths [][]byte, leaf []byte) error) {
 	// Register the storage slot callback if the external callback is specified.
 	var onSlot func(paths [][]byte, hexpath []byte, leaf []byte, parent common.Hash) error
 	if onLeaf != nil {
@@ -35,9 +46,12 @@ func NewStateSync(root common.Hash, database ethdb.KeyValueReader, bloom *trie.S
 			return onLeaf(paths, leaf)
 		}
 	}
+	if len(path) >= 2*common.HashLength {
+		syncer.AddSubTrie(hash, path, common.Hash{}, onSlot)
+		return
+	}
 	// Register the account callback to connect the state trie and the storage
 	// trie belongs to the contract.
-	var syncer *trie.Sync
 	onAccount := func(paths [][]byte, hexpath []byte, leaf []byte, parent common.Hash) error {
 		if onLeaf != nil {
 			if err := onLeaf(paths, leaf); err != nil {
@@ -52,6 +66,5 @@ func NewStateSync(root common.Hash, database ethdb.KeyValueReader, bloom *trie.S
 		syncer.AddCodeEntry(common.BytesToHash(obj.CodeHash), hexpath, parent)
 		return nil
 	}
-	syncer = trie.NewSync(root, database, onAccount, bloom)
-	return syncer
+	syncer.AddSubTrie(hash, path, common.Hash{}, onAccount)
 }
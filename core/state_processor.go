@@ -93,7 +93,8 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		sysCtx  *SysContractCallCtx
 	)
 	if p.config.IsEspresso(blockNumber) {
-		sysCtx = NewSysContractCallCtx(header, statedb, p.bc)
+		sysCtx = NewSysContractCallCtx(p.config, header, statedb, p.bc)
+		p.bc.sysPrefetcher.Record(sysCtx)
 		if p.config.FakeBaseFee != nil {
 			sysCtx = MockSysContractCallCtx(p.bc.Config().FakeBaseFee)
 		}
@@ -163,6 +164,9 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, gp *GasPool
 		root = statedb.IntermediateRoot(config.IsEIP158(blockNumber)).Bytes()
 	}
 	*usedGas += result.UsedGas
+	if config.IsMultiFeeMarket(blockNumber) {
+		AddMultiFeeMarketGasUsed(statedb, msg.FeeCurrency(), result.UsedGas)
+	}
 
 	// Create a new receipt for the transaction, storing the intermediate root and gas used
 	// by the tx.
@@ -180,8 +184,19 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, gp *GasPool
 		receipt.ContractAddress = crypto.CreateAddress(evm.TxContext.Origin, tx.Nonce())
 	}
 
-	// Set the receipt logs and create the bloom filter.
+	// Set the receipt logs, followed by the synthetic fee logs, and create the bloom filter.
 	receipt.Logs = statedb.GetLogs(tx.Hash(), blockHash)
+	if len(result.FeeLogs) > 0 {
+		logs := make([]*types.Log, 0, len(receipt.Logs)+len(result.FeeLogs))
+		logs = append(logs, receipt.Logs...)
+		for _, log := range result.FeeLogs {
+			log.TxHash = tx.Hash()
+			log.TxIndex = uint(statedb.TxIndex())
+			log.BlockHash = blockHash
+			logs = append(logs, log)
+		}
+		receipt.Logs = logs
+	}
 	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
 	receipt.BlockHash = blockHash
 	receipt.BlockNumber = blockNumber
@@ -208,7 +223,7 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, txFeeRecipien
 	return applyTransaction(msg, config, gp, statedb, header.Number, header.Hash(), tx, usedGas, vmenv, vmRunner, sysCtx)
 }
 
-func ApplyBlockRandomnessTx(block *types.Block, vmRunner *vm.EVMRunner, statedb *state.StateDB, bc *BlockChain) error {
+func ApplyBlockRandomnessTx(block *types.Block, vmRunner *vm.EVMRunner, statedb *state.StateDB, bc ChainContext) error {
 	if !random.IsRunning(*vmRunner) {
 		return nil
 	}
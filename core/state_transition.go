@@ -75,6 +75,7 @@ type StateTransition struct {
 	gasPriceMinimum *big.Int
 	sysCtx          *SysContractCallCtx
 	erc20FeeDebited *big.Int
+	feeLogs         []*types.Log
 }
 
 // Message represents a message sent to a contract.
@@ -118,9 +119,10 @@ func CheckEthCompatibility(msg Message) error {
 // ExecutionResult includes all output after executing given evm
 // message no matter the execution itself is successful or not.
 type ExecutionResult struct {
-	UsedGas    uint64 // Total used gas but include the refunded gas
-	Err        error  // Any error encountered during the execution(listed in core/vm/errors.go)
-	ReturnData []byte // Returned data from evm(function result or data supplied with revert opcode)
+	UsedGas    uint64       // Total used gas but include the refunded gas
+	Err        error        // Any error encountered during the execution(listed in core/vm/errors.go)
+	ReturnData []byte       // Returned data from evm(function result or data supplied with revert opcode)
+	FeeLogs    []*types.Log // Synthetic gas fee logs, kept out of the state's logs so they don't shift the indices of the others
 }
 
 // Unwrap returns the internal evm error which allows us for further
@@ -376,6 +378,7 @@ func (st *StateTransition) debitFee(from common.Address, feeCurrency *common.Add
 	// native currency
 	if feeCurrency == nil {
 		st.state.SubBalance(from, effectiveFee)
+		st.addFeeLog(types.GasFeeDebitedTopic, effectiveFee, from, common.ZeroAddress)
 		return nil
 	} else {
 		if st.msg.MaxFeeInFeeCurrency() != nil {
@@ -383,7 +386,11 @@ func (st *StateTransition) debitFee(from common.Address, feeCurrency *common.Add
 		} else {
 			st.erc20FeeDebited = effectiveFee
 		}
-		return erc20gas.DebitFees(st.evm, from, st.erc20FeeDebited, feeCurrency)
+		if err := erc20gas.DebitFees(st.evm, from, st.erc20FeeDebited, feeCurrency); err != nil {
+			return err
+		}
+		st.addFeeLog(types.GasFeeDebitedTopic, st.erc20FeeDebited, from, *feeCurrency)
+		return nil
 	}
 }
 
@@ -562,6 +569,7 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 		UsedGas:    st.gasUsed(),
 		Err:        vmerr,
 		ReturnData: ret,
+		FeeLogs:    st.feeLogs,
 	}, nil
 }
 
@@ -637,11 +645,14 @@ func (st *StateTransition) creditTxFees(denominatedCurrencyRate *currency.Exchan
 	if feeCurrency == nil {
 		if gatewayFeeRecipient != &common.ZeroAddress {
 			st.state.AddBalance(*gatewayFeeRecipient, st.msg.GatewayFee())
+			st.state.AddTransfer(&types.InternalTransfer{Kind: types.TransferFee, From: from, To: *gatewayFeeRecipient, Value: new(big.Int).Set(st.msg.GatewayFee())})
 		}
 		if feeHandlerAddress != common.ZeroAddress {
 			st.state.AddBalance(feeHandlerAddress, baseTxFee)
+			st.state.AddTransfer(&types.InternalTransfer{Kind: types.TransferFee, From: from, To: feeHandlerAddress, Value: new(big.Int).Set(baseTxFee)})
 		}
 		st.state.AddBalance(st.evm.Context.Coinbase, tipTxFee)
+		st.state.AddTransfer(&types.InternalTransfer{Kind: types.TransferFee, From: from, To: st.evm.Context.Coinbase, Value: new(big.Int).Set(tipTxFee)})
 		st.state.AddBalance(from, refund)
 	} else {
 		if err = erc20gas.CreditFees(st.evm, from, st.evm.Context.Coinbase, gatewayFeeRecipient, feeHandlerAddress, refund, tipTxFee, st.msg.GatewayFee(), baseTxFee, feeCurrency); err != nil {
@@ -650,9 +661,42 @@ func (st *StateTransition) creditTxFees(denominatedCurrencyRate *currency.Exchan
 		}
 
 	}
+	currencyAddress := common.ZeroAddress
+	if feeCurrency != nil {
+		currencyAddress = *feeCurrency
+	}
+	if st.msg.GatewayFeeRecipient() != nil {
+		st.addFeeLog(types.GatewayFeePaidTopic, st.msg.GatewayFee(), from, *gatewayFeeRecipient, currencyAddress)
+	}
+	if feeHandlerAddress != common.ZeroAddress {
+		st.addFeeLog(types.GasFeeCreditedTopic, baseTxFee, from, feeHandlerAddress, currencyAddress)
+	}
+	st.addFeeLog(types.GasFeeCreditedTopic, tipTxFee, from, st.evm.Context.Coinbase, currencyAddress)
+	st.addFeeLog(types.GasFeeCreditedTopic, refund, from, from, currencyAddress)
 	return nil
 }
 
+// addFeeLog adds a synthetic gas fee log of the given event to the result of the
+// transaction if they are enabled, with the addresses as the indexed arguments.
+// The fees of no value aren't logged.
+func (st *StateTransition) addFeeLog(event common.Hash, amount *big.Int, addresses ...common.Address) {
+	if !st.evm.Config.SyntheticFeeLogs || amount == nil || amount.Sign() == 0 {
+		return
+	}
+	topics := make([]common.Hash, 0, len(addresses)+1)
+	topics = append(topics, event)
+	for _, address := range addresses {
+		topics = append(topics, common.BytesToHash(address.Bytes()))
+	}
+	st.feeLogs = append(st.feeLogs, &types.Log{
+		Address:     types.FeeLogAddress,
+		Topics:      topics,
+		Data:        common.BigToHash(amount).Bytes(),
+		BlockNumber: st.evm.Context.BlockNumber.Uint64(),
+		Synthetic:   true,
+	})
+}
+
 // refundGas adds unused gas back the state transition and gas pool.
 func (st *StateTransition) refundGas(refundQuotient uint64) {
 	// Apply refund counter, capped to a refund quotient
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/celo-org/celo-blockchain/common"
@@ -11,6 +12,7 @@ import (
 	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/core/vm"
 	gp "github.com/celo-org/celo-blockchain/eth/gasprice"
+	"github.com/celo-org/celo-blockchain/params"
 )
 
 // SysContractCallCtx acts as a cache holding information obtained through
@@ -23,12 +25,8 @@ type SysContractCallCtx struct {
 	// gasPriceMinimums stores values for whitelisted currencies keyed by their contract address
 	// Note that native token(CELO) is keyed by common.ZeroAddress
 	gasPriceMinimums GasPriceMinimums
-}
-
-// runnerFactory exists to allow multiple different implementations to be
-// used as input to NewSysContractCallCtx.
-type runnerFactory interface {
-	NewEVMRunner(*types.Header, vm.StateDB) vm.EVMRunner
+	// state is the copy of the state the system calls were made on
+	state *state.StateDB
 }
 
 // NewSysContractCallCtx returns a SysContractCallCtx filled with data obtained
@@ -41,11 +39,13 @@ type runnerFactory interface {
 // the state (by adding to the access list) as such the provided state is
 // copied to ensure that the state provided by the caller is not modified by
 // this operation.
-func NewSysContractCallCtx(header *types.Header, state *state.StateDB, factory runnerFactory) (sc *SysContractCallCtx) {
-	vmRunner := factory.NewEVMRunner(header, state.Copy())
+func NewSysContractCallCtx(config *params.ChainConfig, header *types.Header, state *state.StateDB, factory vm.EVMRunnerFactory) (sc *SysContractCallCtx) {
+	sysState := state.Copy()
+	vmRunner := factory.NewEVMRunner(header, sysState)
 	sc = &SysContractCallCtx{
 		whitelistedCurrencies: make(map[common.Address]struct{}),
 		gasPriceMinimums:      make(map[common.Address]*big.Int),
+		state:                 sysState,
 	}
 	// intrinsic gas
 	sc.nonCeloCurrencyIntrinsicGas = blockchain_parameters.GetIntrinsicGasForAlternativeFeeCurrencyOrDefault(vmRunner)
@@ -65,9 +65,26 @@ func NewSysContractCallCtx(header *types.Header, state *state.StateDB, factory r
 		gasPriceMinimum, _ := gp.GetBaseFeeForCurrency(vmRunner, &feeCurrency, header.BaseFee)
 		sc.gasPriceMinimums[feeCurrency] = gasPriceMinimum
 	}
+	// Per currency base fees of the multidimensional fee market
+	if config.IsMultiFeeMarket(header.Number) {
+		for feeCurrency, gasPriceMinimum := range sc.gasPriceMinimums {
+			feeCurrency := feeCurrency
+			multiplier := GetFeeCurrencyMultiplier(state, &feeCurrency)
+			sc.gasPriceMinimums[feeCurrency] = ApplyFeeCurrencyMultiplier(gasPriceMinimum, multiplier)
+		}
+	}
 	return sc
 }
 
+// StorageReads returns the accounts and storage slots read by the system calls,
+// nil for a mocked context.
+func (sc *SysContractCallCtx) StorageReads() map[common.Address][]common.Hash {
+	if sc.state == nil {
+		return nil
+	}
+	return sc.state.LoadedStorage()
+}
+
 // GetIntrinsicGasForAlternativeFeeCurrency retrieves intrinsic gas for non-native fee currencies.
 func (sc *SysContractCallCtx) GetIntrinsicGasForAlternativeFeeCurrency() uint64 {
 	return sc.nonCeloCurrencyIntrinsicGas
@@ -132,3 +149,23 @@ func (gpm GasPriceMinimums) GetGasPriceMinimum(feeCurrency *common.Address) *big
 
 	return gpm.valOrDefault(key)
 }
+
+// CreateConversionFunctions creates a function to convert any currency to Celo and a function to get the gas price minimum for that currency.
+// Both functions internally cache their results.
+func CreateConversionFunctions(sysCtx *SysContractCallCtx, factory vm.EVMRunnerFactory, header *types.Header, state *state.StateDB) (func(feeCurrency *common.Address) *big.Int, types.ToCELOFn) {
+	vmRunner := factory.NewEVMRunner(header, state)
+	currencyManager := currency.NewManager(vmRunner)
+
+	baseFeeFn := func(feeCurrency *common.Address) *big.Int {
+		return sysCtx.GetGasPriceMinimum(feeCurrency)
+	}
+	toCeloFn := func(amount *big.Int, feeCurrency *common.Address) (*big.Int, error) {
+		curr, err := currencyManager.GetCurrency(feeCurrency)
+		if err != nil {
+			return nil, fmt.Errorf("toCeloFn: %w", err)
+		}
+		return curr.ToCELO(amount), nil
+	}
+
+	return baseFeeFn, toCeloFn
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/metrics"
+	"github.com/celo-org/celo-blockchain/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// sysCtxCacheLimit is the number of system call contexts kept, a few per head
+// for the base fees of the head and of the block built on top of it.
+const sysCtxCacheLimit = 8
+
+var (
+	sysCtxHitMeter        = metrics.NewRegisteredMeter("chain/sysctx/hit", nil)
+	sysCtxMissMeter       = metrics.NewRegisteredMeter("chain/sysctx/miss", nil)
+	sysCtxInvalidateMeter = metrics.NewRegisteredMeter("chain/sysctx/invalidate", nil)
+)
+
+// sysCtxKey identifies a system call context by the block whose state the calls
+// are made on and the base fee the gas price minimums are derived from.
+type sysCtxKey struct {
+	block   common.Hash
+	baseFee string
+}
+
+func newSysCtxKey(block common.Hash, baseFee *big.Int) sysCtxKey {
+	key := sysCtxKey{block: block}
+	if baseFee != nil {
+		key.baseFee = baseFee.String()
+	}
+	return key
+}
+
+// SysContractCallCtxCache keeps the SysContractCallCtx of the latest blocks, so
+// that the whitelist and gas price minimum reads run once per head for the miner,
+// recommitting blocks on the same parent, and the transaction pool. The contexts
+// are shared by all the readers and must not be modified.
+type SysContractCallCtxCache struct {
+	config  *params.ChainConfig
+	factory vm.EVMRunnerFactory
+	ctxs    *lru.Cache // Contexts by sysCtxKey
+	mu      sync.Mutex // Serializes the system calls
+}
+
+// NewSysContractCallCtxCache creates an empty cache making the system calls with
+// the runners of factory.
+func NewSysContractCallCtxCache(config *params.ChainConfig, factory vm.EVMRunnerFactory) *SysContractCallCtxCache {
+	ctxs, _ := lru.New(sysCtxCacheLimit)
+	return &SysContractCallCtxCache{config: config, factory: factory, ctxs: ctxs}
+}
+
+// Get returns the context of the system calls made on the state of the block
+// with the given hash, which has to be unmodified, for the block of header: the
+// block itself or the one built on top of it.
+func (c *SysContractCallCtxCache) Get(block common.Hash, header *types.Header, state *state.StateDB) *SysContractCallCtx {
+	key := newSysCtxKey(block, header.BaseFee)
+	if sysCtx, ok := c.ctxs.Get(key); ok {
+		sysCtxHitMeter.Mark(1)
+		return sysCtx.(*SysContractCallCtx)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if sysCtx, ok := c.ctxs.Get(key); ok {
+		sysCtxHitMeter.Mark(1)
+		return sysCtx.(*SysContractCallCtx)
+	}
+	sysCtxMissMeter.Mark(1)
+	sysCtx := NewSysContractCallCtx(c.config, header, state, c.factory)
+	c.ctxs.Add(key, sysCtx)
+	return sysCtx
+}
+
+// Invalidate drops the contexts made on the states of the given blocks, the ones
+// reorged out of the chain.
+func (c *SysContractCallCtxCache) Invalidate(blocks []common.Hash) {
+	dropped := make(map[common.Hash]struct{}, len(blocks))
+	for _, hash := range blocks {
+		dropped[hash] = struct{}{}
+	}
+	for _, key := range c.ctxs.Keys() {
+		if _, ok := dropped[key.(sysCtxKey).block]; ok {
+			c.ctxs.Remove(key)
+			sysCtxInvalidateMeter.Mark(1)
+		}
+	}
+}
+
+// sysCtxCacheProvider is implemented by the chains caching the system call
+// contexts of their latest blocks.
+type sysCtxCacheProvider interface {
+	SysContractCallCtxCache() *SysContractCallCtxCache
+}
+
+// SysContractCallCtxCache returns the system call contexts cache of the chain.
+func (bc *BlockChain) SysContractCallCtxCache() *SysContractCallCtxCache {
+	return bc.sysCtxCache
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+func TestSysContractCallCtxCache(t *testing.T) {
+	var (
+		celo       = testutil.NewCeloMock()
+		cache      = NewSysContractCallCtxCache(params.IstanbulTestChainConfig, testutil.MockEVMRunnerFactory{Runner: celo.Runner})
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		head       = &types.Header{Number: big.NewInt(1), BaseFee: big.NewInt(10)}
+		next       = &types.Header{Number: big.NewInt(2), ParentHash: head.Hash(), BaseFee: big.NewInt(20)}
+		whitelist  = func(sysCtx *SysContractCallCtx) int { return len(sysCtx.GetWhitelistedCurrencies()) }
+	)
+	headCtx := cache.Get(head.Hash(), head, statedb)
+	if whitelist(headCtx) != 2 || headCtx.GetGasPriceMinimum(nil).Int64() != 10 {
+		t.Fatalf("context mismatch: have %d whitelisted and base fee %v", whitelist(headCtx), headCtx.GetGasPriceMinimum(nil))
+	}
+	// The system calls aren't made again for the same block and base fee
+	celo.FeeCurrencyWhitelist.Whitelist = celo.FeeCurrencyWhitelist.Whitelist[:1]
+	nextCtx := cache.Get(head.Hash(), next, statedb)
+	if nextCtx == headCtx || whitelist(nextCtx) != 1 || nextCtx.GetGasPriceMinimum(nil).Int64() != 20 {
+		t.Fatalf("context of the next block mismatch: have %d whitelisted and base fee %v", whitelist(nextCtx), nextCtx.GetGasPriceMinimum(nil))
+	}
+	recommit := &types.Header{Number: big.NewInt(2), ParentHash: head.Hash(), BaseFee: big.NewInt(20), Time: 1}
+	if have := cache.Get(head.Hash(), recommit, statedb); have != nextCtx {
+		t.Errorf("context of the recommitted block not cached")
+	}
+	if have := cache.Get(head.Hash(), head, statedb); have != headCtx {
+		t.Errorf("context of the head not cached")
+	}
+	// Reorged blocks are made again
+	cache.Invalidate([]common.Hash{{0x01}})
+	if have := cache.Get(head.Hash(), head, statedb); have != headCtx {
+		t.Errorf("context of another block invalidated")
+	}
+	cache.Invalidate([]common.Hash{head.Hash()})
+	if have := cache.Get(head.Hash(), head, statedb); have == headCtx || whitelist(have) != 1 {
+		t.Errorf("context of the reorged block not invalidated")
+	}
+}
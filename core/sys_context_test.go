@@ -0,0 +1,63 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+func TestSysContractCallCtxFromFixture(t *testing.T) {
+	cusd := common.HexToAddress("0x765de816845861e75a25fca122bb6898b8b1282a")
+	fixture, err := testutil.ParseSystemContractsFixture([]byte(`{
+		"intrinsicGasForAlternativeFeeCurrency": 50000,
+		"feeCurrencies": ["0x765de816845861e75a25fca122bb6898b8b1282a"],
+		"gasPriceMinimums": {
+			"0x0000000000000000000000000000000000000000": 5000000000,
+			"0x765de816845861e75a25fca122bb6898b8b1282a": 2500000000
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("failed to parse fixture: %v", err)
+	}
+	celo := testutil.NewCeloMockFromFixture(fixture)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	sc := NewSysContractCallCtx(params.IstanbulTestChainConfig, &types.Header{Number: big.NewInt(1)}, statedb, celo.RunnerFactory())
+
+	if have := sc.GetIntrinsicGasForAlternativeFeeCurrency(); have != 50000 {
+		t.Errorf("intrinsic gas mismatch: have %d, want %d", have, 50000)
+	}
+	if !sc.IsWhitelisted(&cusd) || len(sc.GetWhitelistedCurrencies()) != 1 {
+		t.Errorf("whitelist mismatch: have %v, want %v", sc.GetWhitelistedCurrencies(), []common.Address{cusd})
+	}
+	if have := sc.GetGasPriceMinimum(nil); have.Cmp(big.NewInt(5000000000)) != 0 {
+		t.Errorf("CELO gas price minimum mismatch: have %v, want %v", have, 5000000000)
+	}
+	if have := sc.GetGasPriceMinimum(&cusd); have.Cmp(big.NewInt(2500000000)) != 0 {
+		t.Errorf("cUSD gas price minimum mismatch: have %v, want %v", have, 2500000000)
+	}
+}
+
+func TestSysContractCallCtxMultiFeeMarket(t *testing.T) {
+	celo := testutil.NewCeloMockFromFixture(&testutil.SystemContractsFixture{
+		GasPriceMinimums: map[common.Address]*big.Int{{}: big.NewInt(5000000000)},
+	})
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	// A doubled CELO base fee in the fee market state, which exists before the fork
+	statedb.SetState(MultiFeeMarketAddress, multiFeeMarketKey(nil, multiFeeMarketMultiplierSlot), common.BigToHash(new(big.Int).Mul(MultiFeeMarketUnit, big.NewInt(2))))
+
+	config := *params.IstanbulTestChainConfig
+	config.MultiFeeMarket = &params.MultiFeeMarketConfig{Block: big.NewInt(2)}
+	for number, want := range map[int64]int64{1: 5000000000, 2: 10000000000} {
+		sc := NewSysContractCallCtx(&config, &types.Header{Number: big.NewInt(number)}, statedb, celo.RunnerFactory())
+		if have := sc.GetGasPriceMinimum(nil); have.Cmp(big.NewInt(want)) != 0 {
+			t.Errorf("gas price minimum of block %d mismatch: have %v, want %v", number, have, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/state"
+)
+
+// SysContractPrefetcher pre-loads the trie nodes of the system contract state for
+// the next block. Every block reads the same storage of the Registry, the
+// GasPriceMinimum, the FeeCurrencyWhitelist and the SortedOracles, among others,
+// when building its SysContractCallCtx. The accounts and slots read for the last
+// block are scheduled into the trie prefetcher of the state of the next one,
+// which loads them concurrently, a storage trie per contract.
+type SysContractPrefetcher struct {
+	lock    sync.RWMutex
+	storage map[common.Address][]common.Hash // Accounts and slots read by the last system calls
+}
+
+// newSysContractPrefetcher creates a prefetcher with nothing to prefetch until
+// the system calls of a block are recorded.
+func newSysContractPrefetcher() *SysContractPrefetcher {
+	return new(SysContractPrefetcher)
+}
+
+// Record remembers the accounts and storage slots read by the system calls of a
+// block, to prefetch for the next one.
+func (p *SysContractPrefetcher) Record(sysCtx *SysContractCallCtx) {
+	if sysCtx == nil {
+		return
+	}
+	storage := sysCtx.StorageReads()
+	if len(storage) == 0 {
+		return
+	}
+	p.lock.Lock()
+	p.storage = storage
+	p.lock.Unlock()
+}
+
+// Prefetch schedules the accounts and storage slots read by the last recorded
+// system calls into the trie prefetcher of the state, which has to be started.
+func (p *SysContractPrefetcher) Prefetch(statedb *state.StateDB) {
+	p.lock.RLock()
+	storage := p.storage
+	p.lock.RUnlock()
+
+	if storage != nil {
+		statedb.PrefetchStorage(storage)
+	}
+}
@@ -31,6 +31,10 @@ import (
 // into the journal, but no such file is currently open.
 var errNoActiveJournal = errors.New("no active journal")
 
+// errJournalFull is returned if a transaction is attempted to be inserted into
+// a bounded journal which already holds as many as it can.
+var errJournalFull = errors.New("journal full")
+
 // devNull is a WriteCloser that just discards anything written into it. Its
 // goal is to allow the transaction journal to write into a fake journal when
 // loading transactions on startup without printing warnings due to no file
@@ -45,12 +49,26 @@ func (*devNull) Close() error                      { return nil }
 type txJournal struct {
 	path   string         // Filesystem path to store the transactions at
 	writer io.WriteCloser // Output stream to write new transactions into
+	kind   string         // Kind of the journaled transactions, for the logs
+	limit  int            // Maximum number of transactions journaled, 0 for no limit
+	count  int            // Number of transactions journaled since the last rotation
 }
 
 // newTxJournal creates a new transaction journal to
 func newTxJournal(path string) *txJournal {
 	return &txJournal{
 		path: path,
+		kind: "local",
+	}
+}
+
+// newRemoteTxJournal creates a new journal of remote transactions, holding up
+// to limit of them.
+func newRemoteTxJournal(path string, limit int) *txJournal {
+	return &txJournal{
+		path:  path,
+		kind:  "remote",
+		limit: limit,
 	}
 }
 
@@ -111,7 +129,7 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 			batch = batch[:0]
 		}
 	}
-	log.Info("Loaded local transaction journal", "transactions", total, "dropped", dropped)
+	log.Info("Loaded "+journal.kind+" transaction journal", "transactions", total, "dropped", dropped)
 
 	return failure
 }
@@ -121,9 +139,13 @@ func (journal *txJournal) insert(tx *types.Transaction) error {
 	if journal.writer == nil {
 		return errNoActiveJournal
 	}
+	if journal.limit > 0 && journal.count >= journal.limit {
+		return errJournalFull
+	}
 	if err := rlp.Encode(journal.writer, tx); err != nil {
 		return err
 	}
+	journal.count++
 	return nil
 }
 
@@ -144,6 +166,9 @@ func (journal *txJournal) rotate(all map[common.Address]types.Transactions) erro
 	}
 	journaled := 0
 	for _, txs := range all {
+		if journal.limit > 0 && journaled+len(txs) > journal.limit {
+			txs = txs[:journal.limit-journaled]
+		}
 		for _, tx := range txs {
 			if err = rlp.Encode(replacement, tx); err != nil {
 				replacement.Close()
@@ -162,8 +187,8 @@ func (journal *txJournal) rotate(all map[common.Address]types.Transactions) erro
 	if err != nil {
 		return err
 	}
-	journal.writer = sink
-	log.Info("Regenerated local transaction journal", "transactions", journaled, "accounts", len(all))
+	journal.writer, journal.count = sink, journaled
+	log.Info("Regenerated "+journal.kind+" transaction journal", "transactions", journaled, "accounts", len(all))
 
 	return nil
 }
@@ -333,6 +333,12 @@ func (l *txList) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Tran
 			newGasTipCap = tx.GasTipCap()
 		} else {
 			txCtx := l.ctx.Load().(txPoolContext)
+			// Values in different currencies are only comparable with recent rates
+			if txCtx.staleRate(old.DenominatedFeeCurrency()) || txCtx.staleRate(tx.DenominatedFeeCurrency()) {
+				log.Debug("Refused replacement with stale exchange rate", "hash", tx.Hash(), "currency", tx.DenominatedFeeCurrency(), "old", old.DenominatedFeeCurrency())
+				staleRateReplaceMeter.Mark(1)
+				return false, nil
+			}
 			// Convert old values into tx fee currency
 			var err error
 			if oldGasFeeCap, err = toCELO(old.GasFeeCap(), old.DenominatedFeeCurrency(), &txCtx); err != nil {
@@ -550,15 +556,46 @@ func (l *txList) Remove(tx *types.Transaction) (bool, types.Transactions) {
 	return true, nil
 }
 
+// Postpone removes the transactions paying fees in a currency whose exchange rate
+// is stale, and all those above the lowest of them, returning them to be queued
+// until the rate is reported again.
+func (l *txList) Postpone() types.Transactions {
+	txCtx := l.ctx.Load().(txPoolContext)
+	if len(txCtx.staleRates) == 0 {
+		return nil
+	}
+	lowest := uint64(math.MaxUint64)
+	for _, tx := range l.txs.items {
+		if nonce := tx.Nonce(); nonce < lowest && txCtx.staleRate(tx.FeeCurrency()) {
+			lowest = nonce
+		}
+	}
+	if lowest == math.MaxUint64 {
+		return nil
+	}
+	return l.txs.Filter(func(tx *types.Transaction) bool { return tx.Nonce() >= lowest })
+}
+
 // Ready retrieves a sequentially increasing list of transactions starting at the
 // provided nonce that is ready for processing. The returned transactions will be
-// removed from the list.
+// removed from the list. Those from the first one paying fees in a currency with
+// a stale exchange rate onwards are kept.
 //
 // Note, all transactions with nonces lower than start will also be returned to
 // prevent getting into and invalid state. This is not something that should ever
 // happen but better to be self correcting than failing!
 func (l *txList) Ready(start uint64) types.Transactions {
-	return l.txs.Ready(start)
+	ready := l.txs.Ready(start)
+	txCtx := l.ctx.Load().(txPoolContext)
+	for i, tx := range ready {
+		if txCtx.staleRate(tx.FeeCurrency()) {
+			for _, held := range ready[i:] {
+				l.txs.Put(held)
+			}
+			return ready[:i]
+		}
+	}
+	return ready
 }
 
 // Len returns the length of the transaction list.
@@ -657,9 +694,10 @@ type txPricedList struct {
 	maxStales int64 // Maximum amount of stale price points allowed before a forced re-heap
 
 	ctx              *atomic.Value
-	all              *txLookup              // Pointer to the map of all transactions
-	urgent, floating multiCurrencyPriceHeap // Heaps of prices of all the stored **remote** transactions
-	reheapMu         sync.Mutex             // Mutex asserts that only one routine is reheaping the list
+	all              *txLookup    // Pointer to the map of all transactions
+	subPools         *txSubPools  // Per currency heaps of prices of all the stored **remote** transactions
+	urgent, floating subPoolHeaps // Urgent and floating heaps of all the sub-pools
+	reheapMu         sync.Mutex   // Mutex asserts that only one routine is reheaping the list
 }
 
 const (
@@ -671,12 +709,14 @@ const (
 // newTxPricedList creates a new price-sorted transaction heap.
 func newTxPricedList(all *txLookup, ctx *atomic.Value, maxStales int64) *txPricedList {
 	txCtx := ctx.Load().(txPoolContext)
+	subPools := newTxSubPools(txCtx.CmpValues, txCtx.SysContractCallCtx.GetCurrentGasPriceMinimumMap())
 	return &txPricedList{
 		ctx:       ctx,
 		all:       all,
 		maxStales: maxStales,
-		urgent:    newMultiCurrencyPriceHeap(txCtx.CmpValues, txCtx.SysContractCallCtx.GetCurrentGasPriceMinimumMap()),
-		floating:  newMultiCurrencyPriceHeap(txCtx.CmpValues, txCtx.SysContractCallCtx.GetCurrentGasPriceMinimumMap()),
+		subPools:  subPools,
+		urgent:    subPools.urgent(),
+		floating:  subPools.floating(),
 	}
 }
 
@@ -713,14 +753,14 @@ func (l *txPricedList) Removed(count int) {
 func (l *txPricedList) Underpriced(tx *types.Transaction) bool {
 	// Note: with two queues, being underpriced is defined as being worse than the worst item
 	// in all non-empty queues if there is any. If both queues are empty then nothing is underpriced.
-	urgentUnderpriced := l.underpricedForMulti(&l.urgent, tx)
-	floatingUnderpriced := l.underpricedForMulti(&l.floating, tx)
+	urgentUnderpriced := l.underpricedForMulti(l.urgent, tx)
+	floatingUnderpriced := l.underpricedForMulti(l.floating, tx)
 	return (urgentUnderpriced || l.urgent.Len() == 0) &&
 		(floatingUnderpriced || l.floating.Len() == 0) &&
 		(l.urgent.Len() != 0 || l.floating.Len() != 0)
 }
 
-func (l *txPricedList) underpricedForMulti(h *multiCurrencyPriceHeap, tx *types.Transaction) bool {
+func (l *txPricedList) underpricedForMulti(h subPoolHeaps, tx *types.Transaction) bool {
 	// wellpriced returns if, after pruning, tx is above minimum price
 	// compared to the top of the heap (minimum priced tx in heap)
 	wellpriced := func(heap *priceHeap) bool {
@@ -728,12 +768,9 @@ func (l *txPricedList) underpricedForMulti(h *multiCurrencyPriceHeap, tx *types.
 		return heap.Len() > 0 && !h.IsCheaper(tx, heap.list[0])
 	}
 
-	// If tx is wellpriced for at least one heap, then it is not underpriced
-	if wellpriced(h.nativeCurrencyHeap) {
-		return false
-	}
-	for _, sh := range h.currencyHeaps {
-		if wellpriced(sh) {
+	// If tx is wellpriced for at least one sub-pool, then it is not underpriced
+	for _, sp := range h.pools.pools {
+		if wellpriced(h.heap(sp)) {
 			return false
 		}
 	}
@@ -804,30 +841,32 @@ func (l *txPricedList) Reheap() {
 	defer l.reheapMu.Unlock()
 	start := time.Now()
 	atomic.StoreInt64(&l.stales, 0)
-	l.urgent.Clear()
+	l.subPools.Clear()
 	l.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
 		l.urgent.Add(tx)
 		return true
 	}, false, true) // Only iterate remotes
 	l.urgent.Init()
 
-	// balance out the two heaps by moving the worse half of transactions into the
-	// floating heap
+	// balance out the two heaps of every sub-pool by moving the worse half of its
+	// transactions into its floating heap. This stays within a currency, so a reheap
+	// doesn't compare currencies nor needs their exchange rates.
 	// Note: Discard would also do this before the first eviction but Reheap can do
 	// is more efficiently. Also, Underpriced would work suboptimally the first time
 	// if the floating queue was empty.
-	floatingCount := l.urgent.Len() * floatingRatio / (urgentRatio + floatingRatio)
-	l.floating.Clear()
-	for i := 0; i < floatingCount; i++ {
-		l.floating.Add(l.urgent.Pop())
+	for _, sp := range l.subPools.pools {
+		floatingCount := sp.urgent.Len() * floatingRatio / (urgentRatio + floatingRatio)
+		for i := 0; i < floatingCount; i++ {
+			sp.floating.list = append(sp.floating.list, heap.Pop(sp.urgent).(*types.Transaction))
+		}
+		heap.Init(sp.floating)
 	}
-	l.floating.Init()
 	reheapTimer.Update(time.Since(start))
 }
 
 // SetBaseFee updates the base fee and triggers a re-heap. Note that Removed is not
 // necessary to call right before SetBaseFee when processing a new block.
 func (l *txPricedList) SetBaseFee(txCtx *txPoolContext) {
-	l.urgent.UpdateFeesAndCurrencies(txCtx.CmpValues, txCtx.SysContractCallCtx.GetCurrentGasPriceMinimumMap())
+	l.subPools.UpdateFeesAndCurrencies(txCtx.CmpValues, txCtx.SysContractCallCtx.GetCurrentGasPriceMinimumMap())
 	l.Reheap()
 }
@@ -0,0 +1,104 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"sync"
+)
+
+// pendingQueueLimit is the maximum number of PendingChangesEvent queued for a
+// subscriber. A subscriber falling further behind is dropped.
+const pendingQueueLimit = 1024
+
+// ErrPendingChangesOverflow is returned on the subscription of a subscriber of
+// the pending changes dropped for not keeping up with them. Its view of the
+// pending transactions is incomplete, it has to subscribe again.
+var ErrPendingChangesOverflow = errors.New("pending changes subscriber fell behind")
+
+// pendingQueue is the ordered queue of the PendingChangesEvent of a subscriber.
+// Events are queued with the pool lock held, in the order the changes were made,
+// and delivered by the subscription, so a slow subscriber never blocks the pool.
+type pendingQueue struct {
+	mu       sync.Mutex
+	events   []PendingChangesEvent
+	wake     chan struct{} // Signals events were queued
+	overflow chan struct{} // Closed when the subscriber is dropped
+}
+
+func newPendingQueue() *pendingQueue {
+	return &pendingQueue{
+		wake:     make(chan struct{}, 1),
+		overflow: make(chan struct{}),
+	}
+}
+
+// push queues an event, or drops the subscriber if its queue is full. It returns
+// false if the subscriber was dropped.
+func (q *pendingQueue) push(ev PendingChangesEvent) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.events) >= pendingQueueLimit {
+		q.events = nil
+		close(q.overflow)
+		return false
+	}
+	q.events = append(q.events, ev)
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// pop returns the next event queued, if any.
+func (q *pendingQueue) pop() (PendingChangesEvent, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.events) == 0 {
+		return PendingChangesEvent{}, false
+	}
+	ev := q.events[0]
+	q.events[0] = PendingChangesEvent{}
+	q.events = q.events[1:]
+	return ev, true
+}
+
+// deliver sends the queued events to ch in order, until the subscription ends
+// or the subscriber is dropped.
+func (q *pendingQueue) deliver(ch chan<- PendingChangesEvent, quit <-chan struct{}) error {
+	for {
+		for ev, ok := q.pop(); ok; ev, ok = q.pop() {
+			select {
+			case ch <- ev:
+			case <-q.overflow:
+				return ErrPendingChangesOverflow
+			case <-quit:
+				return nil
+			}
+		}
+		select {
+		case <-q.wake:
+		case <-q.overflow:
+			return ErrPendingChangesOverflow
+		case <-quit:
+			return nil
+		}
+	}
+}
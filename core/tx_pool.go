@@ -29,7 +29,9 @@ import (
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/common/prque"
 	"github.com/celo-org/celo-blockchain/consensus"
+	"github.com/celo-org/celo-blockchain/contracts"
 	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
+	"github.com/celo-org/celo-blockchain/contracts/config"
 	"github.com/celo-org/celo-blockchain/contracts/currency"
 	"github.com/celo-org/celo-blockchain/contracts/erc20gas"
 	gpm "github.com/celo-org/celo-blockchain/contracts/gasprice_minimum"
@@ -121,6 +123,15 @@ var (
 	// throttleTxMeter counts how many transactions are rejected due to too-many-changes between
 	// txpool reorgs.
 	throttleTxMeter = metrics.NewRegisteredMeter("txpool/throttle", nil)
+	// ratesReusedMeter counts the reorgs which kept the exchange rates of the previous
+	// head, the oracles being unchanged.
+	ratesReusedMeter = metrics.NewRegisteredMeter("txpool/rates/reused", nil)
+	// staleRateReplaceMeter counts the replacements by transactions in another
+	// currency refused because the exchange rate of either was stale.
+	staleRateReplaceMeter = metrics.NewRegisteredMeter("txpool/replace/stalerate", nil)
+	// staleRateDemoteMeter counts the pending transactions demoted to the queue
+	// because the exchange rate of their fee currency was stale.
+	staleRateDemoteMeter = metrics.NewRegisteredMeter("txpool/stalerate/demoted", nil)
 	// reorgDurationTimer measures how long time a txpool reorg takes.
 	reorgDurationTimer = metrics.NewRegisteredTimer("txpool/reorgtime", nil)
 	// dropBetweenReorgHistogram counts how many drops we experience between two reorg runs. It is expected
@@ -187,6 +198,9 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	RemoteJournal     string // Journal of remote transactions paying fees in whitelisted currencies to survive node restarts, disabled if empty
+	RemoteJournalSize uint64 // Maximum number of transactions in the remote journal
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -196,6 +210,8 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	RateStaleness time.Duration // Maximum age of the exchange rate of a currency for its transactions to be pending, or to replace ones in another currency, 0 for no bound
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -204,6 +220,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	RemoteJournalSize: 1024,
+
 	PriceLimit: 0,
 	PriceBump:  10,
 
@@ -213,6 +231,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	RateStaleness: 10 * time.Minute,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -223,6 +243,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.RemoteJournal != "" && conf.RemoteJournalSize < 1 {
+		log.Warn("Sanitizing invalid txpool remote journal size", "provided", conf.RemoteJournalSize, "updated", DefaultTxPoolConfig.RemoteJournalSize)
+		conf.RemoteJournalSize = DefaultTxPoolConfig.RemoteJournalSize
+	}
 	if conf.PriceBump < 1 {
 		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
 		conf.PriceBump = DefaultTxPoolConfig.PriceBump
@@ -254,6 +278,17 @@ type txPoolContext struct {
 	*SysContractCallCtx
 	*currency.CurrencyManager
 	celoGasPriceMinimumFloor *big.Int
+	staleRates               map[common.Address]struct{} // Currencies whose exchange rate is too old to compare them to others
+}
+
+// staleRate returns whether the exchange rate of a currency is too old to compare
+// its values to the ones of other currencies. The rate of CELO never is.
+func (ctx *txPoolContext) staleRate(feeCurrency *common.Address) bool {
+	if feeCurrency == nil {
+		return false
+	}
+	_, stale := ctx.staleRates[*feeCurrency]
+	return stale
 }
 
 // TxPool contains all currently known transactions. Transactions
@@ -269,10 +304,14 @@ type TxPool struct {
 	chain       blockChain
 	gasPrice    *big.Int
 	txFeed      event.Feed
+	stageFeed   event.Feed
 	scope       event.SubscriptionScope
 	signer      types.Signer
 	mu          sync.RWMutex
 
+	pendingSubs   map[*pendingQueue]struct{} // Queues of the subscribers of the pending changes
+	pendingSubsMu sync.Mutex                 // Protects pendingSubs, taken with mu held to queue events
+
 	homestead     bool // Fork indicator for the homestead fork
 	istanbul      bool // Fork indicator whether we are in the istanbul stage.
 	donut         bool // Fork indicator for the Donut fork.
@@ -281,21 +320,29 @@ type TxPool struct {
 	gingerbreadP2 bool // Fork indicator for the Gingerbread P2 fork.
 	hfork         bool // Fork indicator for the HFork.
 
-	currentState    *state.StateDB // Current state in the blockchain head
-	currentVMRunner vm.EVMRunner   // Current EVMRunner
-	pendingNonces   *txNoncer      // Pending state tracking virtual nonces
-	currentMaxGas   uint64         // Current gas limit for transaction caps
-	currentCtx      atomic.Value   // Current block context (holds a txPoolContext)
+	currentState    *state.StateDB            // Current state in the blockchain head
+	currentVMRunner vm.EVMRunner              // Current EVMRunner
+	pendingNonces   *txNoncer                 // Pending state tracking virtual nonces
+	currentMaxGas   uint64                    // Current gas limit for transaction caps
+	currentCtx      atomic.Value              // Current block context (holds a txPoolContext)
+	oracles         common.Address            // SortedOracles the exchange rates of the current context are from
+	oraclesRoot     common.Hash               // Storage root of the oracles the exchange rates are from
+	rateTimes       map[common.Address]uint64 // Median timestamps of the exchange rates, cleared when the oracles change
 
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
 
+	remoteJournal *txJournal // Journal of remote fee currency transactions to back up to disk
+
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
 	beats   map[common.Address]time.Time // Last heartbeat from each known account
 	all     *txLookup                    // All transactions to allow lookups
 	priced  *txPricedList                // All transactions sorted by price. One heap per fee currency.
 
+	queuedTxs      []common.Hash   // Transactions queued since the last lifecycle event
+	pendingChanges []PendingChange // Changes of the pending transactions since the last event
+
 	chainHeadCh     chan ChainHeadEvent
 	chainHeadSub    event.Subscription
 	reqResetCh      chan *txpoolResetRequest
@@ -329,6 +376,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		queue:           make(map[common.Address]*txList),
 		beats:           make(map[common.Address]time.Time),
 		all:             newTxLookup(),
+		pendingSubs:     make(map[*pendingQueue]struct{}),
 		chainHeadCh:     make(chan ChainHeadEvent, chainHeadChanSize),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
@@ -365,6 +413,17 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If journaling the remote fee currency transactions, load them as remotes
+	if config.RemoteJournal != "" {
+		pool.remoteJournal = newRemoteTxJournal(config.RemoteJournal, int(pool.config.RemoteJournalSize))
+
+		if err := pool.remoteJournal.load(pool.AddRemotesSync); err != nil {
+			log.Warn("Failed to load remote transaction journal", "err", err)
+		}
+		if err := pool.remoteJournal.rotate(pool.feeCurrencyRemotes()); err != nil {
+			log.Warn("Failed to rotate remote transaction journal", "err", err)
+		}
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -449,6 +508,13 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.remoteJournal != nil {
+				pool.mu.Lock()
+				if err := pool.remoteJournal.rotate(pool.feeCurrencyRemotes()); err != nil {
+					log.Warn("Failed to rotate remote tx journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
 		}
 	}
 }
@@ -465,6 +531,9 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.remoteJournal != nil {
+		pool.remoteJournal.close()
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -474,6 +543,83 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- NewTxsEvent) event.Subscripti
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeTxLifecycleEvent registers a subscription of the TxLifecycleEvent of
+// the transactions entering the queue and starts sending event to the given channel.
+func (pool *TxPool) SubscribeTxLifecycleEvent(ch chan<- TxLifecycleEvent) event.Subscription {
+	return pool.scope.Track(pool.stageFeed.Subscribe(ch))
+}
+
+// SubscribePendingChanges returns the pending transactions of the pool, grouped
+// by account and sorted by nonce, and registers a subscription of the
+// PendingChangesEvent of the later changes, starting sending events to the given
+// channel. Events taken before the snapshot might be delivered too: replaying
+// them in order on the snapshot converges to the same pending transactions.
+func (pool *TxPool) SubscribePendingChanges(ch chan<- PendingChangesEvent) (map[common.Address]types.Transactions, event.Subscription) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pending := make(map[common.Address]types.Transactions, len(pool.pending))
+	for addr, list := range pool.pending {
+		pending[addr] = list.Flatten()
+	}
+	queue := newPendingQueue()
+	pool.pendingSubsMu.Lock()
+	pool.pendingSubs[queue] = struct{}{}
+	pool.pendingSubsMu.Unlock()
+
+	sub := event.NewSubscription(func(quit <-chan struct{}) error {
+		defer func() {
+			pool.pendingSubsMu.Lock()
+			delete(pool.pendingSubs, queue)
+			pool.pendingSubsMu.Unlock()
+		}()
+		return queue.deliver(ch, quit)
+	})
+	return pending, pool.scope.Track(sub)
+}
+
+// pendingAdded records the addition of a transaction to the pending ones.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) pendingAdded(tx *types.Transaction) {
+	pool.pendingChanges = append(pool.pendingChanges, PendingChange{Hash: tx.Hash(), Tx: tx})
+}
+
+// pendingRemoved records the removal of transactions from the pending ones.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) pendingRemoved(txs types.Transactions, reason PendingRemoval) {
+	for _, tx := range txs {
+		pool.pendingChanges = append(pool.pendingChanges, PendingChange{Hash: tx.Hash(), Reason: reason})
+	}
+}
+
+// unlockAndPost releases the pool lock and posts the lifecycle event of the
+// transactions queued and the changes of the pending ones since the last events.
+// The changes are queued for every subscriber before releasing the lock, keeping
+// them in order, and subscribers falling too far behind are dropped.
+func (pool *TxPool) unlockAndPost() {
+	queued, changes := pool.queuedTxs, pool.pendingChanges
+	pool.queuedTxs, pool.pendingChanges = nil, nil
+
+	if len(changes) > 0 {
+		ev := PendingChangesEvent{Changes: changes}
+		pool.pendingSubsMu.Lock()
+		for queue := range pool.pendingSubs {
+			if !queue.push(ev) {
+				delete(pool.pendingSubs, queue)
+				log.Warn("Dropped slow pending changes subscriber", "limit", pendingQueueLimit)
+			}
+		}
+		pool.pendingSubsMu.Unlock()
+	}
+	pool.mu.Unlock()
+
+	if len(queued) > 0 {
+		pool.stageFeed.Send(TxLifecycleEvent{Stage: TxStageQueued, Txs: queued})
+	}
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -645,6 +791,27 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// feeCurrencyRemotes retrieves the transactions of the remote accounts paying
+// fees in other currencies than CELO, grouped by origin account and sorted by
+// nonce. The returned transaction set is a copy and can be freely modified by
+// calling code.
+func (pool *TxPool) feeCurrencyRemotes() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for _, lists := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		for addr, list := range lists {
+			if pool.locals.contains(addr) {
+				continue
+			}
+			for _, tx := range list.Flatten() {
+				if tx.FeeCurrency() != nil {
+					txs[addr] = append(txs[addr], tx)
+				}
+			}
+		}
+	}
+	return txs
+}
+
 func (pool *TxPool) ctx() *txPoolContext {
 	ctx := pool.currentCtx.Load().(txPoolContext)
 	return &ctx
@@ -845,7 +1012,9 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.pendingRemoved(types.Transactions{old}, PendingReplaced)
 		}
+		pool.pendingAdded(tx)
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
 		pool.journalTx(from, tx)
@@ -917,14 +1086,24 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 	if _, exist := pool.beats[from]; !exist {
 		pool.beats[from] = time.Now()
 	}
+	pool.queuedTxs = append(pool.queuedTxs, hash)
 	return old != nil, nil
 }
 
 // journalTx adds the specified transaction to the local disk journal if it is
-// deemed to have been sent from a local account.
+// deemed to have been sent from a local account, or to the remote one if it pays
+// fees in another currency than CELO.
 func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
+	if !pool.locals.contains(from) {
+		if pool.remoteJournal != nil && tx.FeeCurrency() != nil {
+			if err := pool.remoteJournal.insert(tx); err != nil && err != errJournalFull {
+				log.Warn("Failed to journal remote transaction", "err", err)
+			}
+		}
+		return
+	}
 	// Only journal if it's enabled and the transaction is local
-	if pool.journal == nil || !pool.locals.contains(from) {
+	if pool.journal == nil {
 		return
 	}
 	if err := pool.journal.insert(tx); err != nil {
@@ -956,10 +1135,12 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.pendingRemoved(types.Transactions{old}, PendingReplaced)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
 	}
+	pool.pendingAdded(tx)
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.pendingNonces.set(addr, tx.Nonce()+1)
 
@@ -1046,7 +1227,7 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 	// Process all the new transaction and merge any errors into the original slice
 	pool.mu.Lock()
 	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
-	pool.mu.Unlock()
+	pool.unlockAndPost()
 
 	var nilSlot = 0
 	for _, err := range newErrs {
@@ -1139,6 +1320,9 @@ func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
 			if pending.Empty() {
 				delete(pool.pending, addr)
 			}
+			pool.pendingRemoved(types.Transactions{tx}, PendingEvicted)
+			pool.pendingRemoved(invalids, PendingDemoted)
+
 			// Postpone any invalidated transactions
 			for _, tx := range invalids {
 				// Internal shuffle shouldn't touch the lookup set.
@@ -1296,6 +1480,14 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 		promoteAddrs = make([]common.Address, 0, len(pool.queue))
 		for addr := range pool.queue {
 			promoteAddrs = append(promoteAddrs, addr)
+
+			// Queued transactions following the pending ones, such as those postponed
+			// while the exchange rate of their currency was stale, are ready too
+			if list := pool.pending[addr]; list != nil {
+				if next := list.LastElement().Nonce() + 1; next > pool.pendingNonces.get(addr) {
+					pool.pendingNonces.set(addr, next)
+				}
+			}
 		}
 	}
 	// Check for pending transactions for every account that sent new ones
@@ -1324,7 +1516,7 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	}
 	dropBetweenReorgHistogram.Update(int64(pool.changesSinceReorg))
 	pool.changesSinceReorg = 0 // Reset change counter
-	pool.mu.Unlock()
+	pool.unlockAndPost()
 
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
@@ -1422,13 +1614,21 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.pendingNonces = newTxNoncer(statedb)
 
 	pool.currentVMRunner = pool.chain.NewEVMRunner(newHead, statedb)
-	pool.currentMaxGas = blockchain_parameters.GetBlockGasLimitOrDefault(pool.currentVMRunner)
-	gasPriceMinimumFloor, _ := gpm.GetGasPriceMinimumFloor(pool.currentVMRunner)
+	var gasPriceMinimumFloor *big.Int
+	if governance, err := pool.governanceParams(newHead); err == nil {
+		pool.currentMaxGas, gasPriceMinimumFloor = governance.BlockGasLimit, governance.GasPriceMinimumFloor
+	} else {
+		pool.currentMaxGas = blockchain_parameters.GetBlockGasLimitOrDefault(pool.currentVMRunner)
+		gasPriceMinimumFloor, _ = gpm.GetGasPriceMinimumFloor(pool.currentVMRunner)
+	}
 	// atomic store of the new txPoolContext
+	sysCtx := pool.sysContractCallCtx(newHead, statedb)
+	currencyManager := pool.currencyManager(statedb) // Resets the rate timestamps if the oracles changed
 	newCtx := txPoolContext{
-		NewSysContractCallCtx(newHead, statedb, pool.chain),
-		currency.NewManager(pool.currentVMRunner),
+		sysCtx,
+		currencyManager,
 		gasPriceMinimumFloor,
+		pool.staleRates(newHead, sysCtx),
 	}
 	pool.currentCtx.Store(newCtx)
 
@@ -1447,6 +1647,85 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.hfork = pool.chainconfig.IsHFork(next)
 }
 
+// governanceParams returns the governance parameters of the head mirrored by the
+// chain, if it does.
+func (pool *TxPool) governanceParams(head *types.Header) (*GovernanceParams, error) {
+	provider, ok := pool.chain.(governanceProvider)
+	if !ok || provider.Governance() == nil {
+		return nil, errors.New("governance parameters not mirrored")
+	}
+	return provider.Governance().Params(head)
+}
+
+// sysContractCallCtx returns the system call context of the head state, shared
+// with the miner if the chain caches them.
+func (pool *TxPool) sysContractCallCtx(head *types.Header, statedb *state.StateDB) *SysContractCallCtx {
+	provider, ok := pool.chain.(sysCtxCacheProvider)
+	if !ok || provider.SysContractCallCtxCache() == nil {
+		return NewSysContractCallCtx(pool.chainconfig, head, statedb, pool.chain)
+	}
+	return provider.SysContractCallCtxCache().Get(head.Hash(), head, statedb)
+}
+
+// currencyManager returns the currency manager of the new head state. It starts
+// with the exchange rates of the previous head if the oracles didn't change, so
+// that they're only queried again after reports.
+func (pool *TxPool) currencyManager(statedb *state.StateDB) *currency.CurrencyManager {
+	oracles, err := contracts.GetRegisteredAddress(pool.currentVMRunner, config.SortedOraclesRegistryId)
+	if err != nil {
+		pool.oracles, pool.oraclesRoot, pool.rateTimes = common.Address{}, common.Hash{}, nil
+		return currency.NewManager(pool.currentVMRunner)
+	}
+	root := statedb.GetStorageRoot(oracles)
+	prev, _ := pool.currentCtx.Load().(txPoolContext)
+	unchanged := prev.CurrencyManager != nil && oracles == pool.oracles && root == pool.oraclesRoot
+	pool.oracles, pool.oraclesRoot = oracles, root
+	if unchanged {
+		ratesReusedMeter.Mark(1)
+		return currency.NewManagerFrom(pool.currentVMRunner, prev.CurrencyManager)
+	}
+	pool.rateTimes = nil
+	return currency.NewManager(pool.currentVMRunner)
+}
+
+// staleRates returns the whitelisted currencies whose median exchange rate was
+// last reported more than RateStaleness before the new head, or never. Without
+// oracles the rates are 1:1 and no bound applies.
+func (pool *TxPool) staleRates(head *types.Header, sysCtx *SysContractCallCtx) map[common.Address]struct{} {
+	if pool.config.RateStaleness <= 0 || pool.oracles == (common.Address{}) {
+		return nil
+	}
+	if pool.rateTimes == nil {
+		pool.rateTimes = make(map[common.Address]uint64)
+	}
+	whitelist := sysCtx.GetWhitelistedCurrencies()
+	var missing []common.Address
+	for _, feeCurrency := range whitelist {
+		if _, ok := pool.rateTimes[feeCurrency]; !ok {
+			missing = append(missing, feeCurrency)
+		}
+	}
+	if len(missing) > 0 {
+		timestamps, errs := currency.GetMedianTimestamps(pool.currentVMRunner, missing)
+		for i, feeCurrency := range missing {
+			if errs[i] != nil {
+				log.Debug("Failed to get exchange rate timestamp", "currency", feeCurrency, "err", errs[i])
+			}
+			pool.rateTimes[feeCurrency] = timestamps[i]
+		}
+	}
+	stale := make(map[common.Address]struct{})
+	for _, feeCurrency := range whitelist {
+		if currency.RateStale(pool.rateTimes[feeCurrency], head.Time, pool.config.RateStaleness) {
+			stale[feeCurrency] = struct{}{}
+		}
+	}
+	if len(stale) > 0 {
+		log.Debug("Stale exchange rates in the transaction pool", "number", head.Number, "currencies", len(stale))
+	}
+	return stale
+}
+
 // promoteExecutables moves transactions that have become processable from the
 // future queue to the set of pending transactions. During this process, all
 // invalidated transactions (low nonce, low balance) are deleted.
@@ -1585,6 +1864,7 @@ func (pool *TxPool) truncatePending() {
 						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 					}
 					pool.priced.Removed(len(caps))
+					pool.pendingRemoved(caps, PendingEvicted)
 					pendingGauge.Dec(int64(len(caps)))
 					if pool.locals.contains(offenders[i]) {
 						localGauge.Dec(int64(len(caps)))
@@ -1612,6 +1892,7 @@ func (pool *TxPool) truncatePending() {
 					log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 				}
 				pool.priced.Removed(len(caps))
+				pool.pendingRemoved(caps, PendingEvicted)
 				pendingGauge.Dec(int64(len(caps)))
 				if pool.locals.contains(addr) {
 					localGauge.Dec(int64(len(caps)))
@@ -1687,6 +1968,8 @@ func (pool *TxPool) demoteUnexecutables() {
 			pool.all.Remove(hash)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
+		pool.pendingRemoved(olds, PendingIncluded)
+
 		// Get balances in each currency
 		balances := make(map[common.Address]*big.Int)
 		allCurrencies := list.FeeCurrencies()
@@ -1702,6 +1985,8 @@ func (pool *TxPool) demoteUnexecutables() {
 			pool.all.Remove(hash)
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
+		pool.pendingRemoved(drops, PendingUnpayable)
+		pool.pendingRemoved(invalids, PendingDemoted)
 
 		for _, tx := range invalids {
 			hash := tx.Hash()
@@ -1710,9 +1995,21 @@ func (pool *TxPool) demoteUnexecutables() {
 			// Internal shuffle shouldn't touch the lookup set.
 			pool.enqueueTx(hash, tx, false, false)
 		}
-		pendingGauge.Dec(int64(len(olds) + len(drops) + len(invalids)))
+		// Postpone the transactions paying in currencies with a stale exchange rate,
+		// which might not pay the fees they appear to
+		postponed := list.Postpone()
+		for _, tx := range postponed {
+			hash := tx.Hash()
+			log.Trace("Demoting pending transaction with stale exchange rate", "hash", hash, "currency", tx.FeeCurrency())
+
+			// Internal shuffle shouldn't touch the lookup set.
+			pool.enqueueTx(hash, tx, false, false)
+		}
+		staleRateDemoteMeter.Mark(int64(len(postponed)))
+		pool.pendingRemoved(postponed, PendingDemoted)
+		pendingGauge.Dec(int64(len(olds) + len(drops) + len(invalids) + len(postponed)))
 		if pool.locals.contains(addr) {
-			localGauge.Dec(int64(len(olds) + len(drops) + len(invalids)))
+			localGauge.Dec(int64(len(olds) + len(drops) + len(invalids) + len(postponed)))
 		}
 		// If there's a gap in front, alert (should never happen) and postpone all transactions
 		if list.Len() > 0 && list.txs.Get(nonce) == nil {
@@ -1724,6 +2021,7 @@ func (pool *TxPool) demoteUnexecutables() {
 				// Internal shuffle shouldn't touch the lookup set.
 				pool.enqueueTx(hash, tx, false, false)
 			}
+			pool.pendingRemoved(gapped, PendingDemoted)
 			pendingGauge.Dec(int64(len(gapped)))
 			// This might happen in a reorg, so log it to the metering
 			blockReorgInvalidatedTx.Mark(int64(len(gapped)))
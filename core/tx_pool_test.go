@@ -30,6 +30,7 @@ import (
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/consensus"
 	mockEngine "github.com/celo-org/celo-blockchain/consensus/consensustest"
+	"github.com/celo-org/celo-blockchain/contracts/config"
 	"github.com/celo-org/celo-blockchain/contracts/currency"
 	"github.com/celo-org/celo-blockchain/contracts/testutil"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
@@ -69,6 +70,7 @@ type testBlockChain struct {
 	statedb       *state.StateDB
 	chainHeadFeed *event.Feed
 	celoMock      testutil.CeloMock
+	headTime      uint64
 }
 
 func newTestBlockchain() *testBlockChain {
@@ -83,7 +85,7 @@ func newTestBlockchain() *testBlockChain {
 }
 
 func (bc *testBlockChain) CurrentBlock() *types.Block {
-	return types.NewBlock(&types.Header{}, nil, nil, nil, trie.NewStackTrie(nil))
+	return types.NewBlock(&types.Header{Time: bc.headTime}, nil, nil, nil, trie.NewStackTrie(nil))
 }
 
 func (bc *testBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
@@ -2131,6 +2133,7 @@ func TestDualHeapEviction(t *testing.T) {
 		},
 		&currency.CurrencyManager{},
 		common.Big0,
+		nil,
 	}
 	for baseFee = 0; baseFee <= 1000; baseFee += 100 {
 		txCtx.SysContractCallCtx.gasPriceMinimums[common.ZeroAddress] = big.NewInt(int64(baseFee))
@@ -2621,6 +2624,129 @@ func TestTransactionReplacementCeloDynamicFeeDifferentCurrencies(t *testing.T) {
 	}
 }
 
+// Tests that a transaction is only replaced by one paid in another currency while
+// the exchange rate between them is recent.
+func TestTransactionReplacementStaleExchangeRate(t *testing.T) {
+	t.Parallel()
+
+	var (
+		blockchain = newTestBlockchain()
+		oracles    = common.HexToAddress("0x06")
+		mock       = testutil.NewSortedOraclesMock()
+		reported   = uint64(1000)
+	)
+	// 1 CELO is worth 2 of the default fee currency
+	mock.Rates[defaultFeeCurrency] = [2]*big.Int{big.NewInt(2), big.NewInt(1)}
+	mock.Timestamps[defaultFeeCurrency] = reported
+	blockchain.celoMock.Registry.AddContract(config.SortedOraclesRegistryId, oracles)
+	blockchain.celoMock.Runner.RegisterContract(oracles, mock)
+	blockchain.headTime = reported + 60
+
+	pool := NewTxPool(testTxPoolConfig, eip1559Config, blockchain)
+	defer pool.Stop()
+	<-pool.initDoneCh
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	if err := pool.addRemoteSync(celoDynamicFeeTxV2(0, 100000, big.NewInt(100), big.NewInt(60), key, defaultFeeCurrency)); err != nil {
+		t.Fatalf("failed to add original transaction: %v", err)
+	}
+	// The replacement has to pay the price bump over the converted fee cap and tip
+	if err := pool.addRemoteSync(dynamicFeeTx(0, 100000, big.NewInt(54), big.NewInt(33), key)); err != ErrReplaceUnderpriced {
+		t.Fatalf("converted underpriced replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if err := pool.addRemoteSync(dynamicFeeTx(0, 100000, big.NewInt(55), big.NewInt(33), key)); err != nil {
+		t.Fatalf("failed to replace with a transaction in CELO: %v", err)
+	}
+	// Without reports for too long, only replacements in the same currency are accepted
+	blockchain.headTime = reported + uint64(testTxPoolConfig.RateStaleness/time.Second) + 1
+	<-pool.requestReset(nil, nil)
+	if err := pool.addRemoteSync(celoDynamicFeeTxV2(0, 100000, big.NewInt(200), big.NewInt(120), key, defaultFeeCurrency)); err != ErrReplaceUnderpriced {
+		t.Fatalf("stale rate replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if err := pool.addRemoteSync(dynamicFeeTx(0, 100000, big.NewInt(61), big.NewInt(37), key)); err != nil {
+		t.Fatalf("failed to replace in the same currency with a stale rate: %v", err)
+	}
+	// A new report changes the storage of the oracles, and the rate is recent again
+	mock.Timestamps[defaultFeeCurrency] = blockchain.headTime
+	blockchain.statedb.SetState(oracles, common.Hash{1}, common.Hash{1})
+	blockchain.statedb.IntermediateRoot(false)
+	<-pool.requestReset(nil, nil)
+	if err := pool.addRemoteSync(celoDynamicFeeTxV2(0, 100000, big.NewInt(200), big.NewInt(120), key, defaultFeeCurrency)); err != nil {
+		t.Fatalf("failed to replace with a transaction in another currency after a report: %v", err)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the pending transactions paying in a currency whose exchange rate
+// is stale are demoted along with their successors, and promoted back once the
+// rate is reported again.
+func TestTransactionDemotionStaleExchangeRate(t *testing.T) {
+	t.Parallel()
+
+	var (
+		blockchain = newTestBlockchain()
+		oracles    = common.HexToAddress("0x06")
+		mock       = testutil.NewSortedOraclesMock()
+		reported   = uint64(1000)
+	)
+	mock.Rates[defaultFeeCurrency] = [2]*big.Int{big.NewInt(2), big.NewInt(1)}
+	mock.Timestamps[defaultFeeCurrency] = reported
+	blockchain.celoMock.Registry.AddContract(config.SortedOraclesRegistryId, oracles)
+	blockchain.celoMock.Runner.RegisterContract(oracles, mock)
+	blockchain.headTime = reported + 60
+
+	pool := NewTxPool(testTxPoolConfig, eip1559Config, blockchain)
+	defer pool.Stop()
+	<-pool.initDoneCh
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	txs := []*types.Transaction{
+		dynamicFeeTx(0, 100000, big.NewInt(50), big.NewInt(30), key),
+		celoDynamicFeeTxV2(1, 100000, big.NewInt(100), big.NewInt(60), key, defaultFeeCurrency),
+		dynamicFeeTx(2, 100000, big.NewInt(50), big.NewInt(30), key),
+	}
+	for i, tx := range txs {
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 0 {
+		t.Fatalf("pool size mismatch: have %d pending, %d queued, want 3, 0", pending, queued)
+	}
+	// Without reports for too long, the transactions from the one in the fee
+	// currency onwards are queued
+	blockchain.headTime = reported + uint64(testTxPoolConfig.RateStaleness/time.Second) + 1
+	<-pool.requestReset(nil, nil)
+	if pending, queued := pool.Stats(); pending != 1 || queued != 2 {
+		t.Fatalf("stale rate pool size mismatch: have %d pending, %d queued, want 1, 2", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// They stay queued on the following resets while the rate is stale
+	<-pool.requestReset(nil, nil)
+	if pending, queued := pool.Stats(); pending != 1 || queued != 2 {
+		t.Fatalf("repeated stale rate pool size mismatch: have %d pending, %d queued, want 1, 2", pending, queued)
+	}
+	// A new report changes the storage of the oracles, and the rate is recent again
+	mock.Timestamps[defaultFeeCurrency] = blockchain.headTime
+	blockchain.statedb.SetState(oracles, common.Hash{1}, common.Hash{1})
+	blockchain.statedb.IntermediateRoot(false)
+	<-pool.requestReset(nil, nil)
+	if pending, queued := pool.Stats(); pending != 3 || queued != 0 {
+		t.Fatalf("reported rate pool size mismatch: have %d pending, %d queued, want 3, 0", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that local transactions are journaled to disk, but remote transactions
 // get discarded between restarts.
 func TestTransactionJournaling(t *testing.T)         { testTransactionJournaling(t, false) }
@@ -2737,6 +2863,70 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that the remote transactions paying fees in other currencies than CELO
+// are journaled to disk, up to the journal size, if enabled.
+func TestTransactionRemoteJournaling(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary file for the journal
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary journal: %v", err)
+	}
+	journal := file.Name()
+	defer os.Remove(journal)
+
+	// Clean up the temporary file, we only need the path for now
+	file.Close()
+	os.Remove(journal)
+
+	blockchain := newTestBlockchain()
+
+	config := testTxPoolConfig
+	config.NoLocals = true
+	config.RemoteJournal = journal
+	config.RemoteJournalSize = 2
+
+	pool := NewTxPool(config, eip1559Config, blockchain)
+
+	celo, _ := crypto.GenerateKey()
+	stable, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(celo.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(stable.PublicKey), big.NewInt(1000000000))
+
+	if err := pool.addRemoteSync(dynamicFeeTx(0, 100000, big.NewInt(50), big.NewInt(30), celo)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		if err := pool.addRemoteSync(celoDynamicFeeTxV2(nonce, 100000, big.NewInt(100), big.NewInt(60), stable, defaultFeeCurrency)); err != nil {
+			t.Fatalf("failed to add remote fee currency transaction %d: %v", nonce, err)
+		}
+	}
+	if pending, _ := pool.Stats(); pending != 4 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 4)
+	}
+	// Only the fee currency transactions within the journal size survive a restart
+	pool.Stop()
+	oldstatedb := blockchain.statedb
+	blockchain = newTestBlockchain()
+	blockchain.statedb = oldstatedb
+
+	pool = NewTxPool(config, eip1559Config, blockchain)
+	defer pool.Stop()
+
+	pending, queued := pool.Stats()
+	if pending != 2 || queued != 0 {
+		t.Fatalf("restored transactions mismatched: have %d pending, %d queued, want 2, 0", pending, queued)
+	}
+	restored, _ := pool.Pending(false)
+	if txs := restored[crypto.PubkeyToAddress(stable.PublicKey)]; len(txs) != 2 || txs[0].Nonce() != 0 || txs[1].Nonce() != 1 {
+		t.Fatalf("restored fee currency transactions mismatched: have %v", txs)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -2810,6 +3000,51 @@ func TestTransactionSlotCount(t *testing.T) {
 	}
 }
 
+// Tests that the exchange rates are only queried again when the state of the
+// oracles changes.
+func TestTransactionPoolExchangeRatesReuse(t *testing.T) {
+	t.Parallel()
+
+	var (
+		blockchain = newTestBlockchain()
+		oracles    = common.HexToAddress("0x06")
+		cusd       = common.HexToAddress("0x02")
+		queries    int
+	)
+	medianRate := testutil.NewSingleMethodContract(config.SortedOraclesRegistryId, "medianRate",
+		func(currency common.Address) (*big.Int, *big.Int) {
+			queries++
+			return big.NewInt(2), big.NewInt(1)
+		},
+	)
+	blockchain.celoMock.Registry.AddContract(config.SortedOraclesRegistryId, oracles)
+	blockchain.celoMock.Runner.RegisterContract(oracles, medianRate)
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+	<-pool.initDoneCh
+
+	rate := func() {
+		if _, err := pool.ctx().GetCurrency(&cusd); err != nil {
+			t.Fatalf("failed to get currency: %v", err)
+		}
+	}
+	rate()
+	<-pool.requestReset(nil, nil)
+	rate()
+	if queries != 1 {
+		t.Fatalf("exchange rate queries mismatch with unchanged oracles: have %d, want 1", queries)
+	}
+	// A report changes the storage of the oracles
+	blockchain.statedb.SetState(oracles, common.Hash{1}, common.Hash{1})
+	blockchain.statedb.IntermediateRoot(false)
+	<-pool.requestReset(nil, nil)
+	rate()
+	if queries != 2 {
+		t.Fatalf("exchange rate queries mismatch with changed oracles: have %d, want 2", queries)
+	}
+}
+
 // Benchmarks the speed of validating the contents of the pending queue of the
 // transaction pool.
 func BenchmarkPendingDemotion100(b *testing.B)   { benchmarkPendingDemotion(b, 100) }
@@ -2929,3 +3164,171 @@ func BenchmarkInsertRemoteWithAllLocals(b *testing.B) {
 		pool.Stop()
 	}
 }
+
+// Tests that the transactions entering the queue, new or demoted, are posted as
+// lifecycle events.
+func TestTransactionQueuedLifecycleEvent(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	events := make(chan TxLifecycleEvent, 8)
+	sub := pool.SubscribeTxLifecycleEvent(events)
+	defer sub.Unsubscribe()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+	future := transaction(1, 100000, key)
+	if err := pool.addRemoteSync(future); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Stage != TxStageQueued || len(ev.Txs) != 1 || ev.Txs[0] != future.Hash() || ev.Block != nil {
+			t.Errorf("event mismatch: have %s %x, want %s [%x]", ev.Stage, ev.Txs, TxStageQueued, future.Hash())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("queued event not posted")
+	}
+	// Promoted transactions leave the queue, they are notified by NewTxsEvent
+	if err := pool.addRemoteSync(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	for {
+		select {
+		case ev := <-events:
+			for _, hash := range ev.Txs {
+				if hash == future.Hash() {
+					t.Errorf("promoted transaction queued again")
+				}
+			}
+		case <-time.After(100 * time.Millisecond):
+			return
+		}
+	}
+}
+
+func TestTransactionPendingChanges(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+	if err := pool.addRemoteSync(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	events := make(chan PendingChangesEvent, 8)
+	snapshot, sub := pool.SubscribePendingChanges(events)
+	defer sub.Unsubscribe()
+
+	mirror := make(map[common.Hash]bool)
+	for _, txs := range snapshot {
+		for _, tx := range txs {
+			mirror[tx.Hash()] = true
+		}
+	}
+	if len(mirror) != 1 {
+		t.Fatalf("snapshot mismatch: have %d transactions, want 1", len(mirror))
+	}
+	// Add a pending transaction and replace it
+	old := pricedTransaction(1, 100000, big.NewInt(1), key)
+	if err := pool.addRemoteSync(old); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	replacement := pricedTransaction(1, 100000, big.NewInt(2), key)
+	if err := pool.addRemoteSync(replacement); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	var replaced bool
+	for len(mirror) != 2 || !mirror[replacement.Hash()] {
+		select {
+		case ev := <-events:
+			for _, change := range ev.Changes {
+				if change.Tx != nil {
+					mirror[change.Hash] = true
+					continue
+				}
+				if change.Hash == old.Hash() && change.Reason == PendingReplaced {
+					replaced = true
+				}
+				delete(mirror, change.Hash)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("mirror mismatch: have %d transactions, want 2", len(mirror))
+		}
+	}
+	if !replaced {
+		t.Errorf("replacement not notified")
+	}
+	if mirror[old.Hash()] {
+		t.Errorf("replaced transaction still mirrored")
+	}
+}
+
+// Tests that a subscriber of the pending changes not receiving them neither blocks
+// the pool nor the other subscribers, and is dropped once too far behind.
+func TestTransactionPendingChangesSlowSubscriber(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	slow := make(chan PendingChangesEvent)
+	_, slowSub := pool.SubscribePendingChanges(slow)
+	defer slowSub.Unsubscribe()
+
+	events := make(chan PendingChangesEvent)
+	_, sub := pool.SubscribePendingChanges(events)
+	defer sub.Unsubscribe()
+
+	count := pendingQueueLimit + 2
+	received := make(chan []common.Hash)
+	go func() {
+		var hashes []common.Hash
+		for len(hashes) < count {
+			ev := <-events
+			for _, change := range ev.Changes {
+				hashes = append(hashes, change.Hash)
+			}
+		}
+		received <- hashes
+	}()
+	posted := make(chan struct{})
+	go func() {
+		for i := 0; i < count; i++ {
+			pool.mu.Lock()
+			pool.pendingRemoved(types.Transactions{transaction(uint64(i), 100000, key)}, PendingEvicted)
+			pool.unlockAndPost()
+		}
+		close(posted)
+	}()
+	select {
+	case <-posted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pool blocked by a slow subscriber")
+	}
+	select {
+	case hashes := <-received:
+		for i, hash := range hashes {
+			if want := transaction(uint64(i), 100000, key).Hash(); hash != want {
+				t.Fatalf("change %d: hash mismatch: have %x, want %x", i, hash, want)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("changes not received by the other subscriber")
+	}
+	select {
+	case err := <-slowSub.Err():
+		if err != ErrPendingChangesOverflow {
+			t.Fatalf("slow subscriber error mismatch: have %v, want %v", err, ErrPendingChangesOverflow)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow subscriber not dropped")
+	}
+	pool.pendingSubsMu.Lock()
+	defer pool.pendingSubsMu.Unlock()
+	if len(pool.pendingSubs) != 1 {
+		t.Errorf("subscribers mismatch: have %d, want 1", len(pool.pendingSubs))
+	}
+}
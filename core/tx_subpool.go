@@ -0,0 +1,207 @@
+package core
+
+import (
+	"container/heap"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
+)
+
+type CurrencyCmpFn func(*big.Int, *common.Address, *big.Int, *common.Address) int
+
+func (cc CurrencyCmpFn) GasTipCapCmp(tx, other *types.Transaction) int {
+	return cc(tx.GasTipCap(), tx.FeeCurrency(), other.GasTipCap(), other.FeeCurrency())
+}
+
+// EffectiveGasTipCmp returns the same comparison result as Transaction.EffectiveGasTipCmp
+// but taking into account the exchange rate comparison of the CurrencyCmpFn.
+// Each baseFee is expressed in each tx's currency.
+func (cc CurrencyCmpFn) EffectiveGasTipCmp(tx, other *types.Transaction, baseFeeA, baseFeeB *big.Int) int {
+	return cc(tx.EffectiveGasTipValue(baseFeeA), tx.FeeCurrency(), other.EffectiveGasTipValue(baseFeeB), other.FeeCurrency())
+}
+
+func (cc CurrencyCmpFn) GasFeeCapCmp(a, b *types.Transaction) int {
+	return cc(a.GasFeeCap(), a.FeeCurrency(), b.GasFeeCap(), b.FeeCurrency())
+}
+
+// Cmp returns the same comparison as the priceHeap comparison but taking into account
+// the exchange rate comparison of the CurrencyCmpFn.
+// Each baseFee is expressed in each tx's currency.
+func (cc CurrencyCmpFn) Cmp(a, b *types.Transaction, baseFeeA, baseFeeB *big.Int) int {
+	if baseFeeA != nil && baseFeeB != nil {
+		// Compare effective tips if baseFee is specified
+		if c := cc.EffectiveGasTipCmp(a, b, baseFeeA, baseFeeB); c != 0 {
+			return c
+		}
+	}
+	// Compare fee caps if baseFee is not specified or effective tips are equal
+	if c := cc.GasFeeCapCmp(a, b); c != 0 {
+		return c
+	}
+	// Compare tips if effective tips and fee caps are equal
+	return cc.GasTipCapCmp(a, b)
+}
+
+// txSubPool is the priced part of the pool for the remote transactions paying
+// their fees in one currency. Its heaps are sorted in that currency, against its
+// gas price minimum, so keeping them sorted never needs an exchange rate.
+type txSubPool struct {
+	urgent   *priceHeap // Sorted by effective tip over the gas price minimum
+	floating *priceHeap // Sorted by fee cap
+}
+
+// txSubPools are the priced sub-pools of the fee currencies, CELO's being under
+// the zero address. Exchange rates are only needed to compare the cheapest
+// transactions of different sub-pools, when the pool is full. The currencyCmp of
+// the pool context fetches them the first time they are needed for a head, so a
+// reorg, which re-sorts every sub-pool on its own, doesn't fetch any.
+type txSubPools struct {
+	currencyCmp CurrencyCmpFn
+	gpm         GasPriceMinimums // sub-pools should always be re-sorted after gas price minimums (baseFees) are changed
+	pools       map[common.Address]*txSubPool
+}
+
+func newTxSubPools(currencyCmp CurrencyCmpFn, gpm GasPriceMinimums) *txSubPools {
+	return &txSubPools{
+		currencyCmp: currencyCmp,
+		gpm:         gpm,
+		pools: map[common.Address]*txSubPool{
+			// Not initializing the CELO basefee since it gets updated as soon
+			// as the node starts, and tx pool tests (upstream) assume baseFee == nil
+			common.ZeroAddress: {urgent: &priceHeap{}, floating: &priceHeap{}},
+		},
+	}
+}
+
+// subPoolFor returns the sub-pool of the currency paying the fees of tx, and
+// creates it if it doesn't exist.
+func (s *txSubPools) subPoolFor(tx *types.Transaction) *txSubPool {
+	fc := tx.FeeCurrency()
+	var key common.Address
+	if fc != nil && tx.Type() != types.CeloDenominatedTxType {
+		key = *fc
+	}
+	sp, ok := s.pools[key]
+	if !ok {
+		sp = &txSubPool{
+			urgent:   &priceHeap{baseFee: s.gpm.GetGasPriceMinimum(&key)},
+			floating: &priceHeap{},
+		}
+		s.pools[key] = sp
+	}
+	return sp
+}
+
+// UpdateFeesAndCurrencies sets the comparison function and gas price minimums of
+// a new head. The urgent heaps have to be re-sorted afterwards.
+func (s *txSubPools) UpdateFeesAndCurrencies(currencyCmp CurrencyCmpFn, gpm GasPriceMinimums) {
+	s.currencyCmp = currencyCmp
+	s.gpm = gpm
+	for key, sp := range s.pools {
+		if key == common.ZeroAddress {
+			sp.urgent.baseFee = gpm.GetNativeGPM()
+		} else {
+			key := key
+			sp.urgent.baseFee = gpm.GetGasPriceMinimum(&key)
+		}
+	}
+}
+
+// Clear empties every sub-pool.
+func (s *txSubPools) Clear() {
+	for _, sp := range s.pools {
+		sp.urgent.list = nil
+		sp.floating.list = nil
+	}
+}
+
+// urgent returns the urgent heaps of the sub-pools.
+func (s *txSubPools) urgent() subPoolHeaps {
+	return subPoolHeaps{pools: s}
+}
+
+// floating returns the floating heaps of the sub-pools.
+func (s *txSubPools) floating() subPoolHeaps {
+	return subPoolHeaps{pools: s, floating: true}
+}
+
+// subPoolHeaps is one of the heaps, urgent or floating, of all the sub-pools,
+// seen as the heap of all their transactions.
+type subPoolHeaps struct {
+	pools    *txSubPools
+	floating bool
+}
+
+func (h subPoolHeaps) heap(sp *txSubPool) *priceHeap {
+	if h.floating {
+		return sp.floating
+	}
+	return sp.urgent
+}
+
+// heapFor returns the heap of the sub-pool of tx.
+func (h subPoolHeaps) heapFor(tx *types.Transaction) *priceHeap {
+	return h.heap(h.pools.subPoolFor(tx))
+}
+
+// Add to the heap. Must call Init afterwards to retain the heap invariants.
+func (h subPoolHeaps) Add(tx *types.Transaction) {
+	ph := h.heapFor(tx)
+	ph.list = append(ph.list, tx)
+}
+
+// Push to the heap, maintains heap invariants.
+func (h subPoolHeaps) Push(tx *types.Transaction) {
+	heap.Push(h.heapFor(tx), tx)
+}
+
+// IsCheaper returs true iff tx1 effective gas price <= tx2's
+func (h subPoolHeaps) IsCheaper(tx1, tx2 *types.Transaction) bool {
+	baseFee1 := h.heapFor(tx1).baseFee
+	baseFee2 := h.heapFor(tx2).baseFee
+	return h.pools.currencyCmp.Cmp(tx1, tx2, baseFee1, baseFee2) <= 0
+}
+
+// cheapestTx returns the cheapest of the sub-pools' cheapest transactions.
+func (h subPoolHeaps) cheapestTx() *types.Transaction {
+	var cheapestTx *types.Transaction
+	for _, sp := range h.pools.pools {
+		ph := h.heap(sp)
+		if len(ph.list) == 0 {
+			continue
+		}
+		if tx := ph.list[0]; cheapestTx == nil || h.IsCheaper(tx, cheapestTx) {
+			cheapestTx = tx
+		}
+	}
+	return cheapestTx
+}
+
+func (h subPoolHeaps) Pop() *types.Transaction {
+	cheapestTx := h.cheapestTx()
+	if cheapestTx == nil {
+		return nil
+	}
+	return heap.Pop(h.heapFor(cheapestTx)).(*types.Transaction)
+}
+
+func (h subPoolHeaps) Len() int {
+	r := 0
+	for _, sp := range h.pools.pools {
+		r += len(h.heap(sp).list)
+	}
+	return r
+}
+
+func (h subPoolHeaps) Init() {
+	for _, sp := range h.pools.pools {
+		heap.Init(h.heap(sp))
+	}
+}
+
+func (h subPoolHeaps) Clear() {
+	for _, sp := range h.pools.pools {
+		h.heap(sp).list = nil
+	}
+}
@@ -28,7 +28,7 @@ func txC(price int, currency *common.Address) *types.Transaction {
 }
 
 func TestNilPushes(t *testing.T) {
-	m := newMultiCurrencyPriceHeap(nil, nil)
+	m := newTxSubPools(nil, nil).urgent()
 	m.Push(tx(100))
 	m.Push(tx(50))
 	m.Push(tx(200))
@@ -44,7 +44,7 @@ func TestCurrencyPushes(t *testing.T) {
 	gpm := map[common.Address]*big.Int{
 		*c: big.NewInt(1000),
 	}
-	m := newMultiCurrencyPriceHeap(nil, gpm)
+	m := newTxSubPools(nil, gpm).urgent()
 	m.Push(txC(100, c))
 	m.Push(txC(50, c))
 	m.Push(txC(200, c))
@@ -56,7 +56,7 @@ func TestCurrencyPushes(t *testing.T) {
 }
 
 func TestNilAdds(t *testing.T) {
-	m := newMultiCurrencyPriceHeap(nil, nil)
+	m := newTxSubPools(nil, nil).urgent()
 	m.Add(tx(100))
 	m.Add(tx(250))
 	m.Add(tx(50))
@@ -79,7 +79,7 @@ func TestCurrencyAdds(t *testing.T) {
 	gpm := map[common.Address]*big.Int{
 		*c: big.NewInt(1000),
 	}
-	m := newMultiCurrencyPriceHeap(nil, gpm)
+	m := newTxSubPools(nil, gpm).urgent()
 	m.Add(txC(100, c))
 	m.Add(txC(250, c))
 	m.Add(txC(50, c))
@@ -125,8 +125,9 @@ func TestMultiPushPop(t *testing.T) {
 		}
 		return val1 - val2
 	}
-	m := newMultiCurrencyPriceHeap(cmp, gpm)
-	m.UpdateFeesAndCurrencies(cmp, gpm)
+	pools := newTxSubPools(cmp, gpm)
+	pools.UpdateFeesAndCurrencies(cmp, gpm)
+	m := pools.urgent()
 	m.Push(txC(100, c1)) // 100 * 10 - 10 * 10 = 900 (subtracting basefee x currencyValue)
 	m.Push(txC(250, c1)) // 2500 - 10 * 10 = 2400
 	m.Push(txC(50, c1))  // 500 - 100 = 400
@@ -208,8 +209,9 @@ func TestMultiAddInit(t *testing.T) {
 		}
 		return val1 - val2
 	}
-	m := newMultiCurrencyPriceHeap(cmp, gpm)
-	m.UpdateFeesAndCurrencies(cmp, gpm)
+	pools := newTxSubPools(cmp, gpm)
+	pools.UpdateFeesAndCurrencies(cmp, gpm)
+	m := pools.urgent()
 	m.Add(txC(100, c1)) // 100 * 10 - 10 * 10 = 900 (subtracting basefee x currencyValue)
 	m.Add(txC(250, c1)) // 2500 - 10 * 10 = 2400
 	m.Add(txC(50, c1))  // 500 - 100 = 400
@@ -321,7 +323,7 @@ func TestClear(t *testing.T) {
 	gpm := map[common.Address]*big.Int{
 		*c: big.NewInt(1000),
 	}
-	m := newMultiCurrencyPriceHeap(nil, gpm)
+	m := newTxSubPools(nil, gpm).urgent()
 	m.Push(txC(100, c))
 	m.Push(txC(250, c))
 	m.Push(txC(50, c))
@@ -401,6 +403,7 @@ func TestMulticurrencyUnderpriced(t *testing.T) {
 		},
 		currencyManager,
 		nil,
+		nil,
 	}
 	ctxVal := atomic.Value{}
 	ctxVal.Store(txPoolCtx)
@@ -599,3 +602,67 @@ func TestMulticurrencyUnderpriced(t *testing.T) {
 		})
 	}
 }
+
+// TestSubPoolsReheap tests that a reheap balances every sub-pool on its own,
+// without comparing currencies, and that the sub-pools are only compared when
+// transactions get discarded.
+func TestSubPoolsReheap(t *testing.T) {
+	c1 := curr(1)
+	c2 := curr(2)
+	cmps := 0
+	var cmp CurrencyCmpFn = func(p1 *big.Int, cc1 *common.Address, p2 *big.Int, cc2 *common.Address) int {
+		if !common.AreEqualAddresses(cc1, cc2) {
+			cmps++
+		}
+		// currency1 = x10, currency2 = x100, currency nil = x1
+		val1, val2 := p1.Int64(), p2.Int64()
+		if common.AreEqualAddresses(cc1, c1) {
+			val1 *= 10
+		}
+		if common.AreEqualAddresses(cc2, c1) {
+			val2 *= 10
+		}
+		if common.AreEqualAddresses(cc1, c2) {
+			val1 *= 100
+		}
+		if common.AreEqualAddresses(cc2, c2) {
+			val2 *= 100
+		}
+		return int(val1 - val2)
+	}
+	txPoolCtx := txPoolContext{&SysContractCallCtx{}, currency.NewCacheOnlyManager(nil), nil, nil}
+	ctxVal := atomic.Value{}
+	ctxVal.Store(txPoolCtx)
+
+	all := newTxLookup()
+	for _, price := range []int{100, 250, 50, 200, 75} {
+		all.Add(txC(price, c1), false)      // 1000, 2500, 500, 2000, 750
+		all.Add(txC(price/10+1, c2), false) // 1100, 2600, 600, 2100, 800
+		all.Add(tx(price*11), false)        // 1100, 2750, 550, 2200, 825
+	}
+	pricedList := newTxPricedList(all, &ctxVal, 1024)
+	pricedList.subPools.currencyCmp = cmp
+	pricedList.Reheap()
+
+	assert.Equal(t, 0, cmps, "currencies compared during a reheap")
+	assert.Len(t, pricedList.subPools.pools, 3)
+	for key, sp := range pricedList.subPools.pools {
+		assert.Equal(t, 4, sp.urgent.Len(), "urgent heap of %v", key)
+		assert.Equal(t, 1, sp.floating.Len(), "floating heap of %v", key)
+	}
+	// The floating heaps hold the cheapest transaction of every currency
+	assert.Equal(t, big.NewInt(50), pricedList.subPools.pools[*c1].floating.list[0].GasPrice())
+	assert.Equal(t, big.NewInt(6), pricedList.subPools.pools[*c2].floating.list[0].GasPrice())
+	assert.Equal(t, big.NewInt(550), pricedList.subPools.pools[common.ZeroAddress].floating.list[0].GasPrice())
+
+	// Discarding compares the cheapest transactions of the sub-pools
+	drop, ok := pricedList.Discard(2, false)
+	assert.True(t, ok)
+	assert.NotZero(t, cmps, "currencies not compared while discarding")
+	if assert.Len(t, drop, 2) {
+		assert.Equal(t, c1, drop[0].FeeCurrency())
+		assert.Equal(t, big.NewInt(50), drop[0].GasPrice())
+		assert.Nil(t, drop[1].FeeCurrency())
+		assert.Equal(t, big.NewInt(550), drop[1].GasPrice())
+	}
+}
@@ -100,12 +100,16 @@ func (b *Bloom) UnmarshalText(input []byte) error {
 	return hexutil.UnmarshalFixedText("Bloom", input, b[:])
 }
 
-// CreateBloom creates a bloom filter out of the give Receipts (+Logs)
+// CreateBloom creates a bloom filter out of the give Receipts (+Logs), leaving
+// out the synthetic logs
 func CreateBloom(receipts Receipts) Bloom {
 	buf := make([]byte, 6)
 	var bin Bloom
 	for _, receipt := range receipts {
 		for _, log := range receipt.Logs {
+			if log.Synthetic {
+				continue
+			}
 			bin.add(log.Address.Bytes(), buf)
 			for _, b := range log.Topics {
 				bin.add(b[:], buf)
@@ -115,11 +119,15 @@ func CreateBloom(receipts Receipts) Bloom {
 	return bin
 }
 
-// LogsBloom returns the bloom bytes for the given logs
+// LogsBloom returns the bloom bytes for the given logs, leaving out the synthetic
+// ones
 func LogsBloom(logs []*Log) []byte {
 	buf := make([]byte, 6)
 	var bin Bloom
 	for _, log := range logs {
+		if log.Synthetic {
+			continue
+		}
 		bin.add(log.Address.Bytes(), buf)
 		for _, b := range log.Topics {
 			bin.add(b[:], buf)
@@ -0,0 +1,48 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/crypto"
+)
+
+// FeeLogAddress is the address the synthetic gas fee logs are emitted from. No
+// contract lives there.
+var FeeLogAddress = common.HexToAddress("0x0000000000000000000000000000000000000fee")
+
+// The topics of the synthetic gas fee logs, the hashes of their event signatures.
+// The fee currency is the zero address for the fees paid in CELO, and the amount,
+// in the fee currency, is the data of the log.
+var (
+	// GasFeeDebitedTopic is the topic of
+	// GasFeeDebited(address indexed from, address indexed feeCurrency, uint256 amount),
+	// the fee debited from the sender of a transaction before its execution.
+	GasFeeDebitedTopic = crypto.Keccak256Hash([]byte("GasFeeDebited(address,address,uint256)"))
+
+	// GasFeeCreditedTopic is the topic of
+	// GasFeeCredited(address indexed from, address indexed recipient, address indexed feeCurrency, uint256 amount),
+	// a part of the fee of a transaction credited after its execution: the tip to
+	// the block's fee recipient, the base fee to the fee handler, or the refund
+	// to the sender.
+	GasFeeCreditedTopic = crypto.Keccak256Hash([]byte("GasFeeCredited(address,address,address,uint256)"))
+
+	// GatewayFeePaidTopic is the topic of
+	// GatewayFeePaid(address indexed from, address indexed recipient, address indexed feeCurrency, uint256 amount),
+	// the gateway fee of a transaction credited to its recipient.
+	GatewayFeePaidTopic = crypto.Keccak256Hash([]byte("GatewayFeePaid(address,address,address,uint256)"))
+)
@@ -24,6 +24,7 @@ func (l Log) MarshalJSON() ([]byte, error) {
 		BlockHash   common.Hash    `json:"blockHash"`
 		Index       hexutil.Uint   `json:"logIndex"`
 		Removed     bool           `json:"removed"`
+		Synthetic   bool           `json:"synthetic,omitempty"`
 	}
 	var enc Log
 	enc.Address = l.Address
@@ -35,6 +36,7 @@ func (l Log) MarshalJSON() ([]byte, error) {
 	enc.BlockHash = l.BlockHash
 	enc.Index = hexutil.Uint(l.Index)
 	enc.Removed = l.Removed
+	enc.Synthetic = l.Synthetic
 	return json.Marshal(&enc)
 }
 
@@ -50,6 +52,7 @@ func (l *Log) UnmarshalJSON(input []byte) error {
 		BlockHash   *common.Hash    `json:"blockHash"`
 		Index       *hexutil.Uint   `json:"logIndex"`
 		Removed     *bool           `json:"removed"`
+		Synthetic   *bool           `json:"synthetic,omitempty"`
 	}
 	var dec Log
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -86,5 +89,8 @@ func (l *Log) UnmarshalJSON(input []byte) error {
 	if dec.Removed != nil {
 		l.Removed = *dec.Removed
 	}
+	if dec.Synthetic != nil {
+		l.Synthetic = *dec.Synthetic
+	}
 	return nil
 }
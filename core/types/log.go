@@ -53,6 +53,11 @@ type Log struct {
 	// The Removed field is true if this log was reverted due to a chain reorganisation.
 	// You must pay attention to this field if you receive logs through a filter query.
 	Removed bool `json:"removed"`
+
+	// The Synthetic field is true if this log was made up by the node rather than
+	// emitted by a contract, like the gas fee logs. Synthetic logs are neither part
+	// of the consensus encoding of the receipts nor of the blooms.
+	Synthetic bool `json:"synthetic,omitempty"`
 }
 
 type logMarshaling struct {
@@ -69,7 +74,12 @@ type rlpLog struct {
 }
 
 // rlpStorageLog is the storage encoding of a log.
-type rlpStorageLog rlpLog
+type rlpStorageLog struct {
+	Address   common.Address
+	Topics    []common.Hash
+	Data      []byte
+	Synthetic bool `rlp:"optional"`
+}
 
 // legacyRlpStorageLog is the previous storage encoding of a log including some redundant fields.
 type legacyRlpStorageLog struct {
@@ -105,9 +115,10 @@ type LogForStorage Log
 // EncodeRLP implements rlp.Encoder.
 func (l *LogForStorage) EncodeRLP(w io.Writer) error {
 	return rlp.Encode(w, rlpStorageLog{
-		Address: l.Address,
-		Topics:  l.Topics,
-		Data:    l.Data,
+		Address:   l.Address,
+		Topics:    l.Topics,
+		Data:      l.Data,
+		Synthetic: l.Synthetic,
 	})
 }
 
@@ -123,9 +134,10 @@ func (l *LogForStorage) DecodeRLP(s *rlp.Stream) error {
 	err = rlp.DecodeBytes(blob, &dec)
 	if err == nil {
 		*l = LogForStorage{
-			Address: dec.Address,
-			Topics:  dec.Topics,
-			Data:    dec.Data,
+			Address:   dec.Address,
+			Topics:    dec.Topics,
+			Data:      dec.Data,
+			Synthetic: dec.Synthetic,
 		}
 	} else {
 		// Try to decode log with previous definition.
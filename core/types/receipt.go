@@ -137,7 +137,7 @@ func NewReceipt(root []byte, failed bool, cumulativeGasUsed uint64) *Receipt {
 // EncodeRLP implements rlp.Encoder, and flattens the consensus fields of a receipt
 // into an RLP stream. If no post state is present, byzantium fork is assumed.
 func (r *Receipt) EncodeRLP(w io.Writer) error {
-	data := &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs}
+	data := &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.consensusLogs()}
 	if r.Type == LegacyTxType {
 		return rlp.Encode(w, data)
 	}
@@ -218,6 +218,24 @@ func (r *Receipt) statusEncoding() []byte {
 	return r.PostState
 }
 
+// consensusLogs returns the logs of the receipt which are part of its consensus
+// encoding, leaving out the synthetic ones.
+func (r *Receipt) consensusLogs() []*Log {
+	for i, log := range r.Logs {
+		if !log.Synthetic {
+			continue
+		}
+		logs := append(make([]*Log, 0, len(r.Logs)-1), r.Logs[:i]...)
+		for _, log := range r.Logs[i+1:] {
+			if !log.Synthetic {
+				logs = append(logs, log)
+			}
+		}
+		return logs
+	}
+	return r.Logs
+}
+
 // Size returns the approximate memory used by all internal contents. It is used
 // to approximate and limit the memory consumption of various caches.
 func (r *Receipt) Size() common.StorageSize {
@@ -336,7 +354,7 @@ func (rs Receipts) Len() int { return len(rs) }
 // EncodeIndex encodes the i'th receipt to w.
 func (rs Receipts) EncodeIndex(i int, w *bytes.Buffer) {
 	r := rs[i]
-	data := &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs}
+	data := &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.consensusLogs()}
 	switch r.Type {
 	case LegacyTxType:
 		rlp.Encode(w, data)
@@ -404,8 +422,10 @@ func (r Receipts) DeriveFields(config *params.ChainConfig, hash common.Hash, num
 			r[i].Logs[j].BlockHash = hash
 			r[i].Logs[j].TxHash = r[i].TxHash
 			r[i].Logs[j].TxIndex = uint(i)
-			r[i].Logs[j].Index = logIndex
-			logIndex++
+			if !r[i].Logs[j].Synthetic {
+				r[i].Logs[j].Index = logIndex
+				logIndex++
+			}
 		}
 	}
 
@@ -421,10 +441,33 @@ func (r Receipts) DeriveFields(config *params.ChainConfig, hash common.Hash, num
 			logIndex++
 		}
 	}
+	r.NumberSyntheticLogs()
 
 	return nil
 }
 
+// NumberSyntheticLogs sets the indices of the synthetic logs of the receipts,
+// in order, after those of all the other logs of the block, so the other logs
+// have the same indices whether the synthetic ones are enabled or not.
+func (r Receipts) NumberSyntheticLogs() {
+	logIndex := uint(0)
+	for _, receipt := range r {
+		for _, log := range receipt.Logs {
+			if !log.Synthetic {
+				logIndex++
+			}
+		}
+	}
+	for _, receipt := range r {
+		for _, log := range receipt.Logs {
+			if log.Synthetic {
+				log.Index = logIndex
+				logIndex++
+			}
+		}
+	}
+}
+
 func isBlockReceipt(receipt *Receipt) bool {
 	return len(receipt.Logs) > 0 && receipt.Logs[0].TxHash == receipt.Logs[0].BlockHash
 }
@@ -273,6 +273,39 @@ func TestTypedReceiptEncodingDecoding(t *testing.T) {
 	}
 }
 
+func TestSyntheticLogsEncoding(t *testing.T) {
+	contractLog := &Log{Address: common.BytesToAddress([]byte{0x11}), Topics: []common.Hash{common.HexToHash("dead")}, Data: []byte{0x01}}
+	feeLog := &Log{Address: FeeLogAddress, Topics: []common.Hash{GasFeeDebitedTopic}, Data: []byte{0x02}, Synthetic: true}
+
+	receipt := &Receipt{Status: ReceiptStatusSuccessful, CumulativeGasUsed: 1, Logs: []*Log{feeLog, contractLog, feeLog}}
+	consensus := &Receipt{Status: ReceiptStatusSuccessful, CumulativeGasUsed: 1, Logs: []*Log{contractLog}}
+	if CreateBloom(Receipts{receipt}) != CreateBloom(Receipts{consensus}) {
+		t.Fatal("synthetic logs in the bloom")
+	}
+	receipt.Bloom, consensus.Bloom = CreateBloom(Receipts{receipt}), CreateBloom(Receipts{consensus})
+
+	have, _ := rlp.EncodeToBytes(receipt)
+	want, _ := rlp.EncodeToBytes(consensus)
+	if !bytes.Equal(have, want) {
+		t.Fatalf("consensus encoding mismatch: have %x, want %x", have, want)
+	}
+	if DeriveSha(Receipts{receipt}, newHasher()) != DeriveSha(Receipts{consensus}, newHasher()) {
+		t.Fatal("synthetic logs in the receipt root")
+	}
+	// The synthetic logs are kept in the storage encoding
+	enc, err := rlp.EncodeToBytes((*ReceiptForStorage)(receipt))
+	if err != nil {
+		t.Fatalf("failed to encode receipt for storage: %v", err)
+	}
+	var stored ReceiptForStorage
+	if err := rlp.DecodeBytes(enc, &stored); err != nil {
+		t.Fatalf("failed to decode stored receipt: %v", err)
+	}
+	if len(stored.Logs) != 3 || !stored.Logs[0].Synthetic || stored.Logs[1].Synthetic || !reflect.DeepEqual(stored.Logs[1], contractLog) {
+		t.Fatalf("stored logs mismatch: have %v", stored.Logs)
+	}
+}
+
 func clearComputedFieldsOnReceipts(t *testing.T, receipts Receipts) {
 	t.Helper()
 
@@ -568,7 +568,7 @@ func (s TxByNonce) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 // TxWithMinerFee wraps a transaction with its gas price or effective miner gasTipCap
 type TxWithMinerFee struct {
 	tx       *Transaction
-	minerFee *big.Int // in CELO
+	minerFee *big.Int // in CELO, in its fee currency within TransactionsByPriceAndNonce
 }
 type ToCELOFn func(amount *big.Int, feeCurrency *common.Address) (*big.Int, error)
 
@@ -619,15 +619,28 @@ func (s *TxByPriceAndTime) Pop() interface{} {
 	return x
 }
 
+// txCurrencyHeads are the heads of the accounts whose next transaction pays its
+// fees in a currency, sorted by their miner fee in that currency. Only the best
+// head is converted to CELO, to be compared with the other currencies.
+type txCurrencyHeads struct {
+	feeCurrency *common.Address
+	heads       TxByPriceAndTime // Miner fees in feeCurrency
+	best        *big.Int         // Miner fee in CELO of heads[0]
+}
+
 // TransactionsByPriceAndNonce represents a set of transactions that can return
 // transactions in a profit-maximizing sorted order, while supporting removing
 // entire batches of transactions for non-executable accounts.
+//
+// The heads of the accounts are merged from a heap per fee currency, so only
+// the best head of every currency needs to be converted to CELO.
 type TransactionsByPriceAndNonce struct {
-	txs       map[common.Address]Transactions            // Per account nonce-sorted list of transactions
-	heads     TxByPriceAndTime                           // Next transaction for each unique account (price heap)
-	signer    Signer                                     // Signer for the set of transactions
-	baseFeeFn func(feeCurrency *common.Address) *big.Int // Function to get the basefee for the specified feecurrency.
-	toCELO    ToCELOFn                                   // Current exchange rate to CELO
+	txs        map[common.Address]Transactions            // Per account nonce-sorted list of transactions
+	currencies map[common.Address]*txCurrencyHeads        // Next transaction for each unique account, per fee currency (CELO under the zero address)
+	best       *txCurrencyHeads                           // Currency of the next transaction by price, nil if none
+	signer     Signer                                     // Signer for the set of transactions
+	baseFeeFn  func(feeCurrency *common.Address) *big.Int // Function to get the basefee for the specified feecurrency.
+	toCELO     ToCELOFn                                   // Current exchange rate to CELO
 }
 
 // NewTransactionsByPriceAndNonce creates a transaction set that can retrieve
@@ -637,57 +650,142 @@ type TransactionsByPriceAndNonce struct {
 // if after providing it to the constructor.
 // Note: txCmpFunc should handle the basefee
 func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transactions, baseFeeFn func(feeCurrency *common.Address) *big.Int, toCELO ToCELOFn) *TransactionsByPriceAndNonce {
-	// Initialize a price and received time based heap with the head transactions
-	heads := make(TxByPriceAndTime, 0, len(txs))
+	t := &TransactionsByPriceAndNonce{
+		txs:        txs,
+		currencies: make(map[common.Address]*txCurrencyHeads),
+		signer:     signer,
+		baseFeeFn:  baseFeeFn,
+		toCELO:     toCELO,
+	}
+	// Initialize a price and received time based heap per currency with the head transactions
 	for from, accTxs := range txs {
 		acc, _ := Sender(signer, accTxs[0])
-		wrapped, err := NewTxWithMinerFee(accTxs[0], baseFeeFn, toCELO)
+		wrapped, err := t.wrap(accTxs[0])
 		// Remove transaction if sender doesn't match from, or if wrapping fails.
 		if acc != from || err != nil {
 			delete(txs, from)
 			continue
 		}
-		heads = append(heads, wrapped)
+		c := t.currencyHeads(accTxs[0].FeeCurrency())
+		c.heads = append(c.heads, wrapped)
 		txs[from] = accTxs[1:]
 	}
-	heap.Init(&heads)
+	for _, c := range t.currencies {
+		heap.Init(&c.heads)
+		t.convert(c)
+	}
+	t.selectBest()
+	return t
+}
+
+// wrap wraps a transaction with its effective miner fee in its fee currency.
+func (t *TransactionsByPriceAndNonce) wrap(tx *Transaction) (*TxWithMinerFee, error) {
+	minerFee, err := tx.EffectiveGasTip(t.baseFeeFn(tx.FeeCurrency()))
+	if err != nil {
+		return nil, err
+	}
+	return &TxWithMinerFee{tx: tx, minerFee: minerFee}, nil
+}
 
-	// Assemble and return the transaction set
-	return &TransactionsByPriceAndNonce{
-		txs:       txs,
-		heads:     heads,
-		signer:    signer,
-		baseFeeFn: baseFeeFn,
-		toCELO:    toCELO,
+// currencyHeads returns the heads of the given fee currency, creating them if needed.
+func (t *TransactionsByPriceAndNonce) currencyHeads(feeCurrency *common.Address) *txCurrencyHeads {
+	var key common.Address
+	if feeCurrency != nil {
+		key = *feeCurrency
+	}
+	c, ok := t.currencies[key]
+	if !ok {
+		c = &txCurrencyHeads{feeCurrency: feeCurrency}
+		t.currencies[key] = c
+	}
+	return c
+}
+
+// convert converts the miner fee of the best head of a currency to CELO. If the
+// conversion fails, the transactions of all the accounts of the currency heads
+// are dropped.
+func (t *TransactionsByPriceAndNonce) convert(c *txCurrencyHeads) {
+	c.best = nil
+	if len(c.heads) == 0 {
+		return
+	}
+	best, err := t.toCELO(c.heads[0].minerFee, c.feeCurrency)
+	if err != nil {
+		log.Error("TransactionsByPriceAndNonce: Could not convert fees for currency", "currency", c.feeCurrency, "tx", c.heads[0].tx, "err", err)
+		for _, head := range c.heads {
+			acc, _ := Sender(t.signer, head.tx)
+			delete(t.txs, acc)
+		}
+		c.heads = nil
+		return
+	}
+	c.best = best
+}
+
+// selectBest selects the currency with the best head, comparing them as
+// TxByPriceAndTime does.
+func (t *TransactionsByPriceAndNonce) selectBest() {
+	t.best = nil
+	for _, c := range t.currencies {
+		if c.best == nil {
+			continue
+		}
+		if t.best == nil {
+			t.best = c
+			continue
+		}
+		cmp := c.best.Cmp(t.best.best)
+		if cmp > 0 || (cmp == 0 && c.heads[0].tx.time.Before(t.best.heads[0].tx.time)) {
+			t.best = c
+		}
 	}
 }
 
 // Peek returns the next transaction by price.
 func (t *TransactionsByPriceAndNonce) Peek() *Transaction {
-	if len(t.heads) == 0 {
+	if t.best == nil {
 		return nil
 	}
-	return t.heads[0].tx
+	return t.best.heads[0].tx
 }
 
 // Shift replaces the current best head with the next one from the same account.
 func (t *TransactionsByPriceAndNonce) Shift() {
-	acc, _ := Sender(t.signer, t.heads[0].tx)
+	acc, _ := Sender(t.signer, t.best.heads[0].tx)
 	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
-		if wrapped, err := NewTxWithMinerFee(txs[0], t.baseFeeFn, t.toCELO); err == nil {
-			t.heads[0], t.txs[acc] = wrapped, txs[1:]
-			heap.Fix(&t.heads, 0)
+		if wrapped, err := t.wrap(txs[0]); err == nil {
+			t.txs[acc] = txs[1:]
+			c := t.currencyHeads(txs[0].FeeCurrency())
+			if c == t.best {
+				c.heads[0] = wrapped
+				heap.Fix(&c.heads, 0)
+				t.convert(c)
+			} else {
+				t.popBest()
+				heap.Push(&c.heads, wrapped)
+				if c.heads[0] == wrapped {
+					t.convert(c)
+				}
+			}
+			t.selectBest()
 			return
 		}
 	}
-	heap.Pop(&t.heads)
+	t.Pop()
 }
 
 // Pop removes the best transaction, *not* replacing it with the next one from
 // the same account. This should be used when a transaction cannot be executed
 // and hence all subsequent ones should be discarded from the same account.
 func (t *TransactionsByPriceAndNonce) Pop() {
-	heap.Pop(&t.heads)
+	t.popBest()
+	t.selectBest()
+}
+
+// popBest removes the best head from its currency heads.
+func (t *TransactionsByPriceAndNonce) popBest() {
+	heap.Pop(&t.best.heads)
+	t.convert(t.best)
 }
 
 // Message is a fully derived transaction and implements core.Message
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -554,6 +555,96 @@ func TestTransactionTimeSort(t *testing.T) {
 	}
 }
 
+// Tests that transactions paying their fees in different currencies are merged by
+// their miner fee in CELO, that only the best head of every currency is converted
+// to CELO, and that the transactions of a currency failing to convert are dropped.
+func TestTransactionCurrencySort(t *testing.T) {
+	currA, currB, currC := common.HexToAddress("0xa"), common.HexToAddress("0xb"), common.HexToAddress("0xc")
+	rates := map[common.Address]int64{currA: 2, currB: 3}
+	conversions := 0
+	toCELO := func(amount *big.Int, feeCurrency *common.Address) (*big.Int, error) {
+		conversions++
+		if feeCurrency == nil {
+			return amount, nil
+		}
+		rate, ok := rates[*feeCurrency]
+		if !ok {
+			return nil, errors.New("no exchange rate")
+		}
+		return new(big.Int).Mul(amount, big.NewInt(rate)), nil
+	}
+	signer := HomesteadSigner{}
+	groups := map[common.Address]Transactions{}
+	addTxs := func(feeCurrencies ...*common.Address) common.Address {
+		key, _ := crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		for nonce, feeCurrency := range feeCurrencies {
+			tx, _ := SignTx(NewTx(&LegacyTx{
+				Nonce:       uint64(nonce),
+				To:          &common.Address{},
+				Gas:         100,
+				GasPrice:    big.NewInt(int64(1 + rand.Intn(50))),
+				FeeCurrency: feeCurrency,
+			}), signer, key)
+			groups[addr] = append(groups[addr], tx)
+		}
+		return addr
+	}
+	for i := 0; i < 10; i++ {
+		addTxs(nil, nil, nil)
+		addTxs(&currA, &currA, &currA)
+		addTxs(&currB, &currB, &currB)
+	}
+	addTxs(&currC, &currC)
+	switcher := addTxs(&currA, &currC)
+	expected := 10*3*3 + 1
+
+	baseFeeFn := func(*common.Address) *big.Int { return nil }
+	txset := NewTransactionsByPriceAndNonce(signer, groups, baseFeeFn, toCELO)
+	if conversions != 4 {
+		t.Errorf("conversions before any transaction: have %d, want %d", conversions, 4)
+	}
+	celoFee := func(tx *Transaction) *big.Int {
+		fee, _ := toCELO(tx.GasPrice(), tx.FeeCurrency())
+		return fee
+	}
+	txs := Transactions{}
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		txs = append(txs, tx)
+		txset.Shift()
+	}
+	if len(txs) != expected {
+		t.Fatalf("expected %d transactions, found %d", expected, len(txs))
+	}
+	// Every transaction becomes the best head of its currency once, the one of the
+	// switcher in currC fails to convert again
+	if want := 4 + expected - 3 + 1; conversions != want {
+		t.Errorf("conversions: have %d, want %d", conversions, want)
+	}
+	senders := make(map[common.Address]int)
+	for i, txi := range txs {
+		fromi, _ := Sender(signer, txi)
+		if txi.FeeCurrency() != nil && *txi.FeeCurrency() == currC {
+			t.Fatalf("tx #%d: unconvertible currency included", i)
+		}
+		if int(txi.Nonce()) != senders[fromi] {
+			t.Errorf("invalid nonce ordering: tx #%d (A=%x N=%v), want N=%v", i, fromi[:4], txi.Nonce(), senders[fromi])
+		}
+		senders[fromi]++
+		// If the next tx has different from account, the price must be lower than the current one
+		if i+1 < len(txs) {
+			next := txs[i+1]
+			fromNext, _ := Sender(signer, next)
+			if fromi != fromNext && celoFee(txi).Cmp(celoFee(next)) < 0 {
+				t.Errorf("invalid gasprice ordering: tx #%d (A=%x P=%v) < tx #%d (A=%x P=%v)", i, fromi[:4], celoFee(txi), i+1, fromNext[:4], celoFee(next))
+			}
+		}
+	}
+	if senders[switcher] != 1 {
+		t.Errorf("switcher transactions: have %d, want 1", senders[switcher])
+	}
+}
+
 // TestTransactionCoding tests serializing/de-serializing to/from rlp and JSON.
 func TestTransactionCoding(t *testing.T) {
 	key, err := crypto.GenerateKey()
@@ -0,0 +1,72 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+)
+
+// TransferKind is the way CELO was moved by an internal transfer.
+type TransferKind uint8
+
+const (
+	// TransferCall is value sent by a call or a contract creation, or through the
+	// transfer precompile of the CELO token.
+	TransferCall TransferKind = iota
+	// TransferMint is CELO minted through the transfer precompile, as the epoch
+	// rewards are.
+	TransferMint
+	// TransferSelfDestruct is the balance of a self destructed contract sent to
+	// its beneficiary.
+	TransferSelfDestruct
+	// TransferFee is a part of the gas fee of a transaction paid in CELO credited
+	// to its recipient: the gateway fee, the base fee or the tip.
+	TransferFee
+)
+
+// String implements fmt.Stringer.
+func (k TransferKind) String() string {
+	switch k {
+	case TransferCall:
+		return "call"
+	case TransferMint:
+		return "mint"
+	case TransferSelfDestruct:
+		return "selfdestruct"
+	case TransferFee:
+		return "fee"
+	default:
+		return "unknown"
+	}
+}
+
+// InternalTransfer is a move of CELO between two accounts made during the
+// processing of a block, the values of the transactions included. Those made by
+// the system calls of the block are filed under the zero transaction hash.
+type InternalTransfer struct {
+	Kind  TransferKind
+	From  common.Address // Zero for mints
+	To    common.Address
+	Value *big.Int
+
+	// Derived fields. These fields are filled in by the state.
+	TxHash  common.Hash
+	TxIndex uint
+	Index   uint `rlp:"-"` // Index of the transfer in the block
+}
@@ -99,6 +99,7 @@ func (c *transfer) Run(input []byte, ctx *celoPrecompileContext) ([]byte, error)
 	if from == common.ZeroAddress {
 		// Mint case: Create cGLD out of thin air
 		ctx.evm.StateDB.AddBalance(to, value)
+		ctx.evm.StateDB.AddTransfer(&types.InternalTransfer{Kind: types.TransferMint, To: to, Value: value})
 	} else {
 		// Fail if we're trying to transfer more than the available balance
 		if !ctx.CanTransfer(ctx.evm.StateDB, from, value) {
@@ -71,6 +71,7 @@ type StateDB interface {
 	Snapshot() int
 
 	AddLog(*types.Log)
+	AddTransfer(*types.InternalTransfer)
 	AddPreimage(common.Hash, []byte)
 
 	ForEachStorage(common.Address, func(common.Hash, common.Hash) bool) error
@@ -119,3 +120,34 @@ type EVMRunner interface {
 	// Deprecated. DO NOT USE
 	StartGasMetering()
 }
+
+// QueryCall is a read only call of a batch, as made by EVMRunner.Query.
+type QueryCall struct {
+	Recipient common.Address
+	Input     []byte
+	Gas       uint64
+}
+
+// QueryResult is the outcome of a QueryCall.
+type QueryResult struct {
+	Ret []byte
+	Err error
+}
+
+// BatchEVMRunner is an EVMRunner able to make several read only calls in a
+// single EVM session, instead of creating an EVM for every call.
+type BatchEVMRunner interface {
+	EVMRunner
+
+	// QueryBatch performs the read only calls in order over the runner's state,
+	// and returns their outcomes by call index. The failure of a call doesn't
+	// prevent the next ones.
+	QueryBatch(calls []QueryCall) []QueryResult
+}
+
+// EVMRunnerFactory creates the EVMRunner used for system contract calls on top of
+// the given header and state. It is implemented by the blockchain, and by mocks in
+// tests that should not need one.
+type EVMRunnerFactory interface {
+	NewEVMRunner(header *types.Header, state StateDB) EVMRunner
+}
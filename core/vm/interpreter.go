@@ -38,7 +38,11 @@ type Config struct {
 	ExtraEips []int // Additional EIPS that are to be enabled
 
 	// Celo
-	SkipDebitCredit bool
+	SkipDebitCredit  bool
+	SyntheticFeeLogs bool // Enables the synthetic logs of the gas fee debits and credits
+	// SystemCallFastPath serves the read only system contract calls from the
+	// outputs of their last executions while the storage they read is unchanged
+	SystemCallFastPath bool
 }
 
 // ScopeContext contains the things that are per-call, such as stack and memory,
@@ -112,6 +112,13 @@ type EVMLogger interface {
 	CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error)
 }
 
+// FeeCurrencyLogger is implemented by the EVMLoggers also capturing the calls
+// debiting and crediting the gas fees of transactions paid in fee currencies.
+// These calls are made on the EVM of the transaction but are not traced by it.
+type FeeCurrencyLogger interface {
+	CaptureFeeCurrencyCall(feeCurrency common.Address, input []byte, gasUsed uint64, err error)
+}
+
 // StructLogger is an EVM state logger and implements EVMLogger.
 //
 // StructLogger can capture state based on the given Log configuration and also keeps
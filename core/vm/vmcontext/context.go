@@ -130,6 +130,7 @@ func CanTransfer(db vm.StateDB, addr common.Address, amount *big.Int) bool {
 func Transfer(db vm.StateDB, sender, recipient common.Address, amount *big.Int) {
 	db.SubBalance(sender, amount)
 	db.AddBalance(recipient, amount)
+	db.AddTransfer(&types.InternalTransfer{Kind: types.TransferCall, From: sender, To: recipient, Value: new(big.Int).Set(amount)})
 }
 
 // VerifySealFn returns a function which returns true when the given header has a verifiable seal.
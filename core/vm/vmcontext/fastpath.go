@@ -0,0 +1,260 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package vmcontext
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/metrics"
+	"github.com/celo-org/celo-blockchain/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// queryMemoLimit is the number of distinct queries whose last outputs are kept.
+const queryMemoLimit = 1024
+
+var (
+	fastPathHitMeter   = metrics.NewRegisteredMeter("vm/fastpath/hit", nil)
+	fastPathMissMeter  = metrics.NewRegisteredMeter("vm/fastpath/miss", nil)
+	fastPathSkipMeter  = metrics.NewRegisteredMeter("vm/fastpath/skip", nil)
+	queryMemo, _       = lru.New(queryMemoLimit)
+	queryColdSloadCost = params.ColdSloadCostEIP2929 - params.WarmStorageReadCostEIP2929
+	queryColdCallCost  = params.ColdAccountAccessCostEIP2929 - params.WarmStorageReadCostEIP2929
+)
+
+// queryKey identifies a query. Its output only depends on the key, the code run
+// and the storage read, as long as the code doesn't read the environment.
+type queryKey struct {
+	recipient common.Address
+	input     string
+	gas       uint64
+	metered   bool
+	rules     params.Rules
+}
+
+// queryEntry is the output of the last execution of a query, along with what it
+// depends on and the changes the execution made to the state.
+type queryEntry struct {
+	output []byte
+	codes  map[common.Address]common.Hash                 // Code hashes of the accounts run or inspected
+	slots  map[common.Address]map[common.Hash]common.Hash // Values of the storage slots read
+	warm   map[common.Address]struct{}                    // Accounts added to the access list
+}
+
+// valid returns whether executing the query again on the state would run the
+// same code on the same storage, returning the same output.
+func (e *queryEntry) valid(state vm.StateDB) bool {
+	for addr, hash := range e.codes {
+		if state.GetCodeHash(addr) != hash {
+			return false
+		}
+	}
+	for addr, slots := range e.slots {
+		for key, value := range slots {
+			if state.GetState(addr, key) != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// replay makes the changes executing the query would make to the state: the
+// touch of the recipient and the access list additions.
+func (e *queryEntry) replay(state vm.StateDB, recipient common.Address) {
+	state.AddBalance(recipient, common.Big0)
+	for addr := range e.warm {
+		state.AddAddressToAccessList(addr)
+	}
+	for addr, slots := range e.slots {
+		for key := range slots {
+			state.AddSlotToAccessList(addr, key)
+		}
+	}
+}
+
+// querySession makes the queries of an evmRunner with the fast path enabled,
+// serving them from the memo when their entries are valid, and executing them on
+// an EVM recording their new entries otherwise.
+type querySession struct {
+	runner   *evmRunner
+	evm      *vm.EVM
+	recorder *queryRecorder
+}
+
+func newQuerySession(runner *evmRunner) *querySession {
+	return &querySession{runner: runner}
+}
+
+func (s *querySession) query(recipient common.Address, input []byte, gas uint64) ([]byte, error) {
+	key := queryKey{recipient: recipient, input: string(input), gas: gas, metered: !s.runner.dontMeterGas, rules: s.runner.rules}
+	if cached, ok := queryMemo.Get(key); ok {
+		if entry := cached.(*queryEntry); entry.valid(s.runner.state) {
+			fastPathHitMeter.Mark(1)
+			entry.replay(s.runner.state, recipient)
+			return common.CopyBytes(entry.output), nil
+		}
+	}
+	fastPathMissMeter.Mark(1)
+
+	if s.evm == nil {
+		s.recorder = &queryRecorder{state: s.runner.state, precompiles: make(map[common.Address]struct{})}
+		for _, addr := range vm.ActivePrecompiles(s.runner.rules) {
+			s.recorder.precompiles[addr] = struct{}{}
+		}
+		s.evm = s.runner.newEVM(VMAddress, s.recorder)
+	}
+	if s.runner.dontMeterGas {
+		s.evm.StopGasMetering()
+	} else {
+		s.evm.StartGasMetering()
+	}
+	s.recorder.reset()
+	ret, leftOverGas, err := s.evm.StaticCall(vm.AccountRef(s.evm.Origin), recipient, input, gas)
+	if err == nil && s.recorder.memoizable(key.metered, gas, gas-leftOverGas) {
+		s.recorder.entry.output = common.CopyBytes(ret)
+		queryMemo.Add(key, s.recorder.entry)
+	} else {
+		fastPathSkipMeter.Mark(1)
+		queryMemo.Remove(key)
+	}
+	return ret, err
+}
+
+// queryRecorder is an EVMLogger recording the entry of a query: the code it runs
+// and the storage it reads. Queries reading the environment or their gas left,
+// calling accounts without code or precompiles, or whose calls fail aren't
+// memoizable. The gas left depends on the accesses being warm or cold, so it can
+// only be read to be passed on to a call, as proxies do.
+type queryRecorder struct {
+	state       vm.StateDB
+	precompiles map[common.Address]struct{}
+
+	entry    *queryEntry
+	depth    int  // Deepest call depth of the query
+	gasRead  bool // Whether the last operation read the gas left
+	volatile bool
+}
+
+func (r *queryRecorder) reset() {
+	r.entry = &queryEntry{
+		codes: make(map[common.Address]common.Hash),
+		slots: make(map[common.Address]map[common.Hash]common.Hash),
+		warm:  make(map[common.Address]struct{}),
+	}
+	r.depth, r.gasRead, r.volatile = 0, false, false
+}
+
+// memoizable returns whether the recorded query can be served from its entry,
+// given the gas it used. A metered query must also succeed had all of its
+// accesses been cold, with the gas kept by its callers at each depth to spare.
+func (r *queryRecorder) memoizable(metered bool, gas, gasUsed uint64) bool {
+	if r.volatile {
+		return false
+	}
+	if !metered {
+		return true
+	}
+	var slots uint64
+	for _, s := range r.entry.slots {
+		slots += uint64(len(s))
+	}
+	worst := gasUsed + slots*queryColdSloadCost + uint64(len(r.entry.warm))*queryColdCallCost
+	return worst+uint64(r.depth)*(gas/64) <= gas
+}
+
+func (r *queryRecorder) account(addr common.Address) {
+	r.entry.warm[addr] = struct{}{}
+	r.entry.codes[addr] = r.state.GetCodeHash(addr)
+}
+
+// CaptureState implements the EVMLogger interface to record the code run, the
+// storage slots read and the accounts accessed.
+func (r *queryRecorder) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if r.volatile {
+		return
+	}
+	if depth > r.depth {
+		r.depth = depth
+	}
+	if r.gasRead {
+		// The gas left on the stack is only forwarded to a call
+		if op != vm.CALL && op != vm.CALLCODE && op != vm.DELEGATECALL && op != vm.STATICCALL {
+			r.volatile = true
+			return
+		}
+		r.gasRead = false
+	}
+	contract := scope.Contract
+	codeAddr := contract.Address()
+	if contract.CodeAddr != nil {
+		codeAddr = *contract.CodeAddr
+	}
+	r.entry.codes[codeAddr] = contract.CodeHash
+
+	stack := scope.Stack
+	switch op {
+	case vm.SLOAD:
+		addr, key := contract.Address(), common.Hash(stack.Back(0).Bytes32())
+		if r.entry.slots[addr] == nil {
+			r.entry.slots[addr] = make(map[common.Hash]common.Hash)
+		}
+		r.entry.slots[addr][key] = r.state.GetState(addr, key)
+	case vm.EXTCODESIZE, vm.EXTCODEHASH, vm.EXTCODECOPY:
+		r.account(common.Address(stack.Back(0).Bytes20()))
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		r.account(common.Address(stack.Back(1).Bytes20()))
+	case vm.GAS:
+		r.gasRead = true
+	case vm.BALANCE, vm.SELFBALANCE, vm.COINBASE, vm.TIMESTAMP, vm.NUMBER, vm.GASLIMIT,
+		vm.CHAINID, vm.BASEFEE, vm.BLOCKHASH, vm.GASPRICE, vm.SSTORE, vm.CREATE, vm.CREATE2,
+		vm.SELFDESTRUCT, vm.LOG0, vm.LOG1, vm.LOG2, vm.LOG3, vm.LOG4:
+		r.volatile = true
+	}
+}
+
+// CaptureEnter implements the EVMLogger interface to reject the calls of
+// precompiles, which can read the environment, and of accounts without code.
+func (r *queryRecorder) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if _, ok := r.precompiles[to]; ok || r.state.GetCodeSize(to) == 0 {
+		r.volatile = true
+	}
+}
+
+// CaptureExit implements the EVMLogger interface to reject the failed calls,
+// whose access list additions are reverted.
+func (r *queryRecorder) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if err != nil {
+		r.volatile = true
+	}
+}
+
+// CaptureFault implements the EVMLogger interface.
+func (r *queryRecorder) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	r.volatile = true
+}
+
+// CaptureStart implements the EVMLogger interface.
+func (r *queryRecorder) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+
+// CaptureEnd implements the EVMLogger interface.
+func (r *queryRecorder) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) {
+}
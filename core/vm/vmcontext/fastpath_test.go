@@ -0,0 +1,164 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package vmcontext
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus"
+	mockEngine "github.com/celo-org/celo-blockchain/consensus/consensustest"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+// testChain is the evmRunnerContext of a chain without headers.
+type testChain struct {
+	vmConfig vm.Config
+}
+
+func (c *testChain) Engine() consensus.Engine                    { return mockEngine.NewFaker() }
+func (c *testChain) GetHeader(common.Hash, uint64) *types.Header { return nil }
+func (c *testChain) GetHeaderByNumber(uint64) *types.Header      { return nil }
+func (c *testChain) Config() *params.ChainConfig                 { return params.TestChainConfig }
+func (c *testChain) GetVMConfig() *vm.Config                     { return &c.vmConfig }
+
+var (
+	// valueCode returns the storage slot 0
+	valueCode = []byte{0x60, 0x00, 0x54, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+	// doubleCode returns twice the storage slot 0
+	doubleCode = []byte{0x60, 0x00, 0x54, 0x80, 0x01, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+	// proxyCode delegates the calls to the address of the storage slot 1
+	proxyCode = []byte{
+		0x36, 0x60, 0x00, 0x60, 0x00, 0x37, // CALLDATACOPY(0, 0, CALLDATASIZE)
+		0x60, 0x00, 0x60, 0x00, 0x36, 0x60, 0x00, 0x60, 0x01, 0x54, 0x5a, 0xf4, // DELEGATECALL(GAS, SLOAD(1), 0, CALLDATASIZE, 0, 0)
+		0x3d, 0x60, 0x00, 0x60, 0x00, 0x3e, // RETURNDATACOPY(0, 0, RETURNDATASIZE)
+		0x3d, 0x60, 0x00, 0xf3, // RETURN(0, RETURNDATASIZE)
+	}
+	// numberCode returns the block number
+	numberCode = []byte{0x43, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+	// gasCode returns the gas left
+	gasCode = []byte{0x5a, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+)
+
+func TestSystemCallFastPath(t *testing.T) {
+	var (
+		proxy  = common.HexToAddress("0xfa01")
+		impl   = common.HexToAddress("0xfa02")
+		double = common.HexToAddress("0xfa03")
+		number = common.HexToAddress("0xfa04")
+		gas    = common.HexToAddress("0xfa06")
+		gasFwd = common.HexToAddress("0xfa07")
+		header = &types.Header{Number: big.NewInt(1), GasLimit: 20000000}
+		evm    = &testChain{}
+		fast   = &testChain{vmConfig: vm.Config{SystemCallFastPath: true}}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(proxy, proxyCode)
+	statedb.SetCode(impl, valueCode)
+	statedb.SetCode(double, doubleCode)
+	statedb.SetCode(number, numberCode)
+	statedb.SetCode(gas, gasCode)
+	statedb.SetCode(gasFwd, proxyCode)
+	statedb.SetState(gasFwd, common.HexToHash("0x01"), common.BytesToHash(gas.Bytes()))
+	statedb.SetState(proxy, common.HexToHash("0x01"), common.BytesToHash(impl.Bytes()))
+	statedb.SetState(proxy, common.Hash{}, common.HexToHash("0x2a"))
+	statedb.Finalise(true)
+
+	// query makes the same query with the EVM and the fast path on copies of the
+	// state, checking that they have the same outcome and access lists.
+	query := func(header *types.Header, recipient common.Address, gas uint64) ([]byte, bool) {
+		t.Helper()
+		evmState, fastState := statedb.Copy(), statedb.Copy()
+		want, wantErr := NewEVMRunner(evm, header, evmState).Query(recipient, nil, gas)
+		have, haveErr := NewEVMRunner(fast, header, fastState).Query(recipient, nil, gas)
+		if !bytes.Equal(have, want) || (haveErr == nil) != (wantErr == nil) {
+			t.Fatalf("query of %x mismatch: have %x, %v, want %x, %v", recipient, have, haveErr, want, wantErr)
+		}
+		for _, addr := range []common.Address{proxy, impl, double} {
+			for _, slot := range []common.Hash{{}, common.HexToHash("0x01")} {
+				haveAddr, haveSlot := fastState.SlotInAccessList(addr, slot)
+				wantAddr, wantSlot := evmState.SlotInAccessList(addr, slot)
+				if haveAddr != wantAddr || haveSlot != wantSlot {
+					t.Fatalf("access list mismatch for %x %x: have %v %v, want %v %v", addr, slot, haveAddr, haveSlot, wantAddr, wantSlot)
+				}
+			}
+		}
+		key := queryKey{recipient: recipient, input: "", gas: gas, metered: true, rules: NewEVMRunner(fast, header, fastState).(*evmRunner).rules}
+		cached, ok := queryMemo.Get(key)
+		return have, ok && cached.(*queryEntry).valid(statedb)
+	}
+
+	if ret, memoized := query(header, proxy, 100000); new(big.Int).SetBytes(ret).Int64() != 42 || !memoized {
+		t.Fatalf("proxied query mismatch: have %x, memoized %v", ret, memoized)
+	}
+	// Served from the memo
+	if ret, memoized := query(header, proxy, 100000); new(big.Int).SetBytes(ret).Int64() != 42 || !memoized {
+		t.Fatalf("memoized query mismatch: have %x, memoized %v", ret, memoized)
+	}
+	// A changed value, implementation and implementation code are all read again
+	statedb.SetState(proxy, common.Hash{}, common.HexToHash("0x2b"))
+	if ret, _ := query(header, proxy, 100000); new(big.Int).SetBytes(ret).Int64() != 43 {
+		t.Fatalf("changed value mismatch: have %x", ret)
+	}
+	statedb.SetState(proxy, common.HexToHash("0x01"), common.BytesToHash(double.Bytes()))
+	if ret, _ := query(header, proxy, 100000); new(big.Int).SetBytes(ret).Int64() != 86 {
+		t.Fatalf("changed implementation mismatch: have %x", ret)
+	}
+	statedb.SetCode(double, valueCode)
+	if ret, _ := query(header, proxy, 100000); new(big.Int).SetBytes(ret).Int64() != 43 {
+		t.Fatalf("changed code mismatch: have %x", ret)
+	}
+	// Running out of gas when the accesses are cold isn't memoized
+	if _, memoized := query(header, proxy, 5000); memoized {
+		t.Fatalf("query memoized without enough gas for cold accesses")
+	}
+	// Reading the environment isn't memoized
+	for i := int64(1); i <= 2; i++ {
+		if ret, memoized := query(&types.Header{Number: big.NewInt(i), GasLimit: 20000000}, number, 100000); new(big.Int).SetBytes(ret).Int64() != i || memoized {
+			t.Fatalf("block number query mismatch: have %x, memoized %v", ret, memoized)
+		}
+	}
+	// Neither is calling an account without code
+	if ret, memoized := query(header, common.HexToAddress("0xfa05"), 100000); len(ret) != 0 || memoized {
+		t.Fatalf("empty account query mismatch: have %x, memoized %v", ret, memoized)
+	}
+	// Nor reading the gas left, which depends on the accesses being warm, other
+	// than to forward it to a call
+	for i := 0; i < 2; i++ {
+		for _, recipient := range []common.Address{gas, gasFwd} {
+			if ret, memoized := query(header, recipient, 100000); len(ret) == 0 || memoized {
+				t.Fatalf("gas query of %x mismatch: have %x, memoized %v", recipient, ret, memoized)
+			}
+		}
+	}
+
+	// Batched queries are served from the memo as well
+	fastState := statedb.Copy()
+	results := NewEVMRunner(fast, header, fastState).(vm.BatchEVMRunner).QueryBatch([]vm.QueryCall{
+		{Recipient: proxy, Gas: 100000},
+		{Recipient: number, Gas: 100000},
+	})
+	if new(big.Int).SetBytes(results[0].Ret).Int64() != 43 || new(big.Int).SetBytes(results[1].Ret).Int64() != 1 {
+		t.Fatalf("batched queries mismatch: have %x, %x", results[0].Ret, results[1].Ret)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/params"
 )
 
 // VMAddress is the address the VM uses to make internal calls to contracts
@@ -19,18 +20,24 @@ type evmRunnerContext interface {
 	GetVMConfig() *vm.Config
 }
 
+// Check we actually implement BatchEVMRunner
+var _ vm.BatchEVMRunner = &evmRunner{}
+
 type evmRunner struct {
-	newEVM func(from common.Address) *vm.EVM
+	// newEVM creates an EVM for calls from the sender, traced by the tracer if not nil
+	newEVM func(from common.Address, tracer vm.EVMLogger) *vm.EVM
 	state  vm.StateDB
 
 	dontMeterGas bool
+	fastPath     bool         // Whether the queries are served from the storage they last read
+	rules        params.Rules // Fork rules of the header for the fast path, without chain id
 }
 
 func NewEVMRunner(chain evmRunnerContext, header *types.Header, state vm.StateDB) vm.EVMRunner {
-
-	return &evmRunner{
+	vmConfig := chain.GetVMConfig()
+	ev := &evmRunner{
 		state: state,
-		newEVM: func(from common.Address) *vm.EVM {
+		newEVM: func(from common.Address, tracer vm.EVMLogger) *vm.EVM {
 			// The EVM Context requires a msg, but the actual field values don't really matter for this case.
 			// Putting in zero values for gas price and tx fee recipient
 			blockContext := NewBlockContext(header, chain, nil)
@@ -38,13 +45,24 @@ func NewEVMRunner(chain evmRunnerContext, header *types.Header, state vm.StateDB
 				Origin:   from,
 				GasPrice: common.Big0,
 			}
-			return vm.NewEVM(blockContext, txContext, state, chain.Config(), *chain.GetVMConfig())
+			config := *vmConfig
+			if tracer != nil {
+				config.Debug, config.Tracer = true, tracer
+			}
+			return vm.NewEVM(blockContext, txContext, state, chain.Config(), config)
 		},
+		// Traced calls are never served from the fast path
+		fastPath: vmConfig.SystemCallFastPath && !vmConfig.Debug,
+	}
+	if ev.fastPath {
+		ev.rules = chain.Config().Rules(header.Number)
+		ev.rules.ChainID = nil
 	}
+	return ev
 }
 
 func (ev *evmRunner) Execute(recipient common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, err error) {
-	evm := ev.newEVM(VMAddress)
+	evm := ev.newEVM(VMAddress, nil)
 	if ev.dontMeterGas {
 		evm.StopGasMetering()
 	}
@@ -53,7 +71,7 @@ func (ev *evmRunner) Execute(recipient common.Address, input []byte, gas uint64,
 }
 
 func (ev *evmRunner) ExecuteFrom(sender, recipient common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, err error) {
-	evm := ev.newEVM(sender)
+	evm := ev.newEVM(sender, nil)
 	if ev.dontMeterGas {
 		evm.StopGasMetering()
 	}
@@ -62,7 +80,7 @@ func (ev *evmRunner) ExecuteFrom(sender, recipient common.Address, input []byte,
 }
 
 func (ev *evmRunner) ExecuteAndDiscardChanges(recipient common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, err error) {
-	evm := ev.newEVM(VMAddress)
+	evm := ev.newEVM(VMAddress, nil)
 	var snapshot = evm.StateDB.Snapshot()
 	if ev.dontMeterGas {
 		evm.StopGasMetering()
@@ -73,7 +91,10 @@ func (ev *evmRunner) ExecuteAndDiscardChanges(recipient common.Address, input []
 }
 
 func (ev *evmRunner) Query(recipient common.Address, input []byte, gas uint64) (ret []byte, err error) {
-	evm := ev.newEVM(VMAddress)
+	if ev.fastPath {
+		return newQuerySession(ev).query(recipient, input, gas)
+	}
+	evm := ev.newEVM(VMAddress, nil)
 	if ev.dontMeterGas {
 		evm.StopGasMetering()
 	}
@@ -81,6 +102,30 @@ func (ev *evmRunner) Query(recipient common.Address, input []byte, gas uint64) (
 	return ret, err
 }
 
+// QueryBatch implements BatchEVMRunner.QueryBatch, making all the calls with the
+// same EVM.
+func (ev *evmRunner) QueryBatch(calls []vm.QueryCall) []vm.QueryResult {
+	results := make([]vm.QueryResult, len(calls))
+	if len(calls) == 0 {
+		return results
+	}
+	if ev.fastPath {
+		session := newQuerySession(ev)
+		for i, call := range calls {
+			results[i].Ret, results[i].Err = session.query(call.Recipient, call.Input, call.Gas)
+		}
+		return results
+	}
+	evm := ev.newEVM(VMAddress, nil)
+	if ev.dontMeterGas {
+		evm.StopGasMetering()
+	}
+	for i, call := range calls {
+		results[i].Ret, _, results[i].Err = evm.StaticCall(vm.AccountRef(evm.Origin), call.Recipient, call.Input, call.Gas)
+	}
+	return results
+}
+
 func (ev *evmRunner) StopGasMetering() {
 	ev.dontMeterGas = true
 }
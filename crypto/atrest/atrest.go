@@ -0,0 +1,146 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package atrest encrypts the secrets a node keeps in its auxiliary stores, such
+// as the randomness it committed to or the consensus messages it sent, so that
+// they can't be read from a copy of the data directory.
+//
+// The values are sealed with AES-256-GCM, authenticated together with the key
+// they're stored under so that they can't be swapped. The encryption key is
+// either derived from a passphrase or read from a file, where a key unwrapped by
+// a key management service can be placed at startup.
+package atrest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// KeyLength is the length of the encryption keys.
+	KeyLength = 32
+
+	saltLength = 32
+)
+
+// The scrypt parameters of the keys derived from passphrases, the same as the
+// standard ones of the keystore.
+var (
+	scryptN = 1 << 18
+	scryptP = 1
+	scryptR = 8
+)
+
+// ErrDecrypt is returned for a value sealed with another key, or altered.
+var ErrDecrypt = errors.New("could not decrypt value with the storage key")
+
+// Key is the encryption key of the stores. A nil key leaves the values as is.
+type Key struct {
+	aead cipher.AEAD
+}
+
+// NewKey creates a key from KeyLength bytes of secret.
+func NewKey(secret []byte) (*Key, error) {
+	if len(secret) != KeyLength {
+		return nil, fmt.Errorf("invalid storage key length %d, want %d", len(secret), KeyLength)
+	}
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{aead: aead}, nil
+}
+
+// LoadKeyFile reads a hex encoded key from a file.
+func LoadKeyFile(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := hex.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage key file %s: %w", path, err)
+	}
+	return NewKey(secret)
+}
+
+// DeriveKey derives a key from a passphrase with scrypt. The salt is kept in
+// saltFile, which is created with a random one if it doesn't exist: deleting it
+// makes every value sealed with the key unreadable.
+func DeriveKey(passphrase string, saltFile string) (*Key, error) {
+	salt, err := os.ReadFile(saltFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		salt = make([]byte, saltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(saltFile, salt, 0600); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case len(salt) != saltLength:
+		return nil, fmt.Errorf("invalid storage key salt %s", saltFile)
+	}
+	secret, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, KeyLength)
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(secret)
+}
+
+// Seal encrypts a value stored under the given key of a store, returning the
+// nonce followed by the ciphertext. It returns the value as is if k is nil.
+func (k *Key) Seal(value, storeKey []byte) []byte {
+	if k == nil {
+		return value
+	}
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(value)+k.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("atrest: failed to generate nonce: %v", err))
+	}
+	return k.aead.Seal(nonce, nonce, value, storeKey)
+}
+
+// Open decrypts a value sealed under the given key of a store. It returns the
+// value as is if k is nil.
+func (k *Key) Open(sealed, storeKey []byte) ([]byte, error) {
+	if k == nil {
+		return sealed, nil
+	}
+	if len(sealed) < k.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:k.aead.NonceSize()], sealed[k.aead.NonceSize():]
+	value, err := k.aead.Open(nil, nonce, ciphertext, storeKey)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return value, nil
+}
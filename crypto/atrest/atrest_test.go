@@ -0,0 +1,84 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package atrest
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	scryptN = 1 << 10
+	defer func() { scryptN = 1 << 18 }()
+
+	saltFile := filepath.Join(t.TempDir(), "salt")
+	key, err := DeriveKey("passphrase", saltFile)
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	value, storeKey := []byte("randomness"), []byte("commitment")
+	sealed := key.Seal(value, storeKey)
+	if bytes.Contains(sealed, value) {
+		t.Fatalf("sealed value contains the plaintext")
+	}
+	if opened, err := key.Open(sealed, storeKey); err != nil || !bytes.Equal(opened, value) {
+		t.Fatalf("opened value mismatch: have %q, %v, want %q", opened, err, value)
+	}
+	// Values can't be moved to another store key
+	if _, err := key.Open(sealed, []byte("other")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("moved value error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	// The same passphrase derives the same key from the saved salt
+	again, err := DeriveKey("passphrase", saltFile)
+	if err != nil {
+		t.Fatalf("failed to derive key again: %v", err)
+	}
+	if opened, err := again.Open(sealed, storeKey); err != nil || !bytes.Equal(opened, value) {
+		t.Errorf("rederived key opened value mismatch: have %q, %v, want %q", opened, err, value)
+	}
+	wrong, err := DeriveKey("wrong", saltFile)
+	if err != nil {
+		t.Fatalf("failed to derive wrong key: %v", err)
+	}
+	if _, err := wrong.Open(sealed, storeKey); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("wrong key error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	// A nil key leaves the values as is
+	var none *Key
+	if sealed := none.Seal(value, storeKey); !bytes.Equal(sealed, value) {
+		t.Errorf("nil key sealed value mismatch: have %q, want %q", sealed, value)
+	}
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte("0001020304050607080910111213141516171819202122232425262728293031\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeyFile(path); err != nil {
+		t.Fatalf("failed to load key: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("00010203"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeyFile(path); err == nil {
+		t.Fatalf("short key loaded")
+	}
+}
@@ -0,0 +1,67 @@
+package e2e_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/internal/chaos"
+	"github.com/celo-org/celo-blockchain/test"
+	"github.com/stretchr/testify/require"
+)
+
+// This test soaks a network in injected faults, slow databases, lost consensus
+// messages and garbage collection pauses, and checks that it keeps producing
+// blocks and processing transactions, before and after the faults stop.
+func TestChaosRecovery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("soak test")
+	}
+	// The faults have to be enabled before the nodes are created
+	chaos.Enabled = true
+	chaos.SetDBLatency(time.Millisecond)
+	chaos.SetDropConsensusPercent(20)
+	stopGCPauses := chaos.StartGCPauses(200 * time.Millisecond)
+	stopFaults := func() {
+		chaos.SetDBLatency(0)
+		chaos.SetDropConsensusPercent(0)
+		if stopGCPauses != nil {
+			stopGCPauses()
+			stopGCPauses = nil
+		}
+	}
+	defer func() {
+		stopFaults()
+		chaos.Enabled = false
+	}()
+
+	ac := test.AccountConfig(3, 2)
+	gingerbreadBlock := common.Big0
+	gc, ec, err := test.BuildConfig(ac, gingerbreadBlock, nil)
+	require.NoError(t, err)
+	network, shutdown, err := test.NewNetwork(ac, gc, ec)
+	require.NoError(t, err)
+	defer shutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*2)
+	defer cancel()
+
+	accounts := test.Accounts(ac.DeveloperAccounts(), gc.ChainConfig())
+
+	// The network is slowed down by the faults, but makes progress
+	tx, err := accounts[0].SendCelo(ctx, accounts[1].Address, 1, network[0])
+	require.NoError(t, err)
+	require.NoError(t, network.AwaitTransactions(ctx, tx))
+	head, err := network[0].WsClient.BlockNumber(ctx)
+	require.NoError(t, err)
+	require.NoError(t, network.AwaitBlock(ctx, head+5))
+
+	// And recovers once they stop
+	stopFaults()
+	tx, err = accounts[0].SendCelo(ctx, accounts[1].Address, 1, network[0])
+	require.NoError(t, err)
+	require.NoError(t, network.AwaitTransactions(ctx, tx))
+	head, err = network[0].WsClient.BlockNumber(ctx)
+	require.NoError(t, err)
+	require.NoError(t, network.AwaitBlock(ctx, head+5))
+}
@@ -0,0 +1,147 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+// maxAccountTransactions is the most transactions returned in a page, and the
+// default page size.
+const maxAccountTransactions = 1000
+
+var (
+	errAccountTxIndexDisabled = errors.New("account transaction index disabled, see --accounttxindex")
+	errInvalidCursor          = errors.New("invalid cursor")
+)
+
+// AccountTransaction is a transaction an account sent or received.
+type AccountTransaction struct {
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	BlockHash        common.Hash    `json:"blockHash"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	Hash             common.Hash    `json:"hash"`
+	Sender           bool           `json:"sender"`
+	Recipient        bool           `json:"recipient"` // Including the contract creations
+}
+
+// AccountTxPagination selects a page of the transactions of an account.
+type AccountTxPagination struct {
+	Limit  *hexutil.Uint64 `json:"limit"`  // At most maxAccountTransactions, the default
+	Cursor hexutil.Bytes   `json:"cursor"` // The one returned with the previous page
+}
+
+// AccountTransactions is a page of the transactions of an account in a range of
+// blocks, in chain order.
+type AccountTransactions struct {
+	FromBlock    hexutil.Uint64        `json:"fromBlock"`
+	ToBlock      hexutil.Uint64        `json:"toBlock"`
+	Transactions []*AccountTransaction `json:"transactions"`
+	Cursor       hexutil.Bytes         `json:"cursor"` // Of the next page, null for the last one
+}
+
+// PublicAccountTxAPI serves the account transaction index.
+type PublicAccountTxAPI struct {
+	eth *Ethereum
+}
+
+// NewPublicAccountTxAPI creates a new account transaction index API.
+func NewPublicAccountTxAPI(eth *Ethereum) *PublicAccountTxAPI {
+	return &PublicAccountTxAPI{eth: eth}
+}
+
+// GetTransactionsByAddress returns the transactions an account sent or received
+// in [fromBlock, toBlock], the latter cut to the indexed blocks. The results are
+// paginated, the cursor of a page fetching the next one of the same range.
+func (api *PublicAccountTxAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, pagination *AccountTxPagination) (*AccountTransactions, error) {
+	indexer := api.eth.accountTxIndexer
+	if indexer == nil {
+		return nil, errAccountTxIndexDisabled
+	}
+	var (
+		head           = api.eth.blockchain.CurrentHeader().Number.Uint64()
+		from           = resolveGasUsageBlock(fromBlock, head)
+		to             = resolveGasUsageBlock(toBlock, head)
+		sections, _, _ = indexer.Sections()
+	)
+	if sections == 0 || from >= sections*core.AccountTxSectionSize || from > to {
+		return nil, fmt.Errorf("blocks %d to %d not indexed yet", from, to)
+	}
+	if last := sections*core.AccountTxSectionSize - 1; to > last {
+		to = last
+	}
+	return accountTransactions(api.eth.chainDb, address, from, to, pagination)
+}
+
+// accountTransactions returns a page of the indexed transactions of an account
+// in [from, to].
+func accountTransactions(db ethdb.Database, address common.Address, from, to uint64, pagination *AccountTxPagination) (*AccountTransactions, error) {
+	limit, number, index := uint64(maxAccountTransactions), from, uint64(0)
+	if pagination != nil {
+		if pagination.Limit != nil && uint64(*pagination.Limit) > 0 && uint64(*pagination.Limit) < limit {
+			limit = uint64(*pagination.Limit)
+		}
+		if pagination.Cursor != nil {
+			if len(pagination.Cursor) != 12 {
+				return nil, errInvalidCursor
+			}
+			number, index = binary.BigEndian.Uint64(pagination.Cursor[:8]), uint64(binary.BigEndian.Uint32(pagination.Cursor[8:]))
+			if number < from || number > to {
+				return nil, errInvalidCursor
+			}
+		}
+	}
+	txs := rawdb.ReadAccountTransactions(db, address, number, index, to, int(limit)+1)
+
+	result := &AccountTransactions{
+		FromBlock:    hexutil.Uint64(from),
+		ToBlock:      hexutil.Uint64(to),
+		Transactions: make([]*AccountTransaction, 0, len(txs)),
+	}
+	if uint64(len(txs)) > limit {
+		next := txs[limit]
+		result.Cursor = make(hexutil.Bytes, 12)
+		binary.BigEndian.PutUint64(result.Cursor[:8], next.Number)
+		binary.BigEndian.PutUint32(result.Cursor[8:], uint32(next.Index))
+		txs = txs[:limit]
+	}
+	for _, tx := range txs {
+		// Skip the transactions of the blocks reorged out after being indexed
+		if rawdb.ReadCanonicalHash(db, tx.Number) != tx.BlockHash {
+			continue
+		}
+		result.Transactions = append(result.Transactions, &AccountTransaction{
+			BlockNumber:      hexutil.Uint64(tx.Number),
+			BlockHash:        tx.BlockHash,
+			TransactionIndex: hexutil.Uint64(tx.Index),
+			Hash:             tx.Hash,
+			Sender:           tx.Sender,
+			Recipient:        tx.Recipient,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+)
+
+func TestAccountTransactions(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		account = common.Address{1}
+		want    []common.Hash
+	)
+	// Two transactions in every block, the ones of block 3 reorged out
+	for number := uint64(0); number < 5; number++ {
+		block := common.Hash{byte(number + 1)}
+		rawdb.WriteCanonicalHash(db, block, number)
+		for index := uint64(0); index < 2; index++ {
+			tx := &rawdb.AccountTransaction{Number: number, Index: index, Hash: common.Hash{byte(number), byte(index)}, BlockHash: block, Sender: true}
+			if number == 3 {
+				tx.BlockHash = common.Hash{0xff}
+			} else if number <= 3 {
+				want = append(want, tx.Hash)
+			}
+			rawdb.WriteAccountTransaction(db, account, tx)
+		}
+	}
+	var (
+		have       []common.Hash
+		pagination = &AccountTxPagination{Limit: new(hexutil.Uint64)}
+	)
+	*pagination.Limit = 3
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("too many pages")
+		}
+		page, err := accountTransactions(db, account, 0, 3, pagination)
+		if err != nil {
+			t.Fatalf("failed to get the transactions: %v", err)
+		}
+		if len(page.Transactions) > 3 {
+			t.Fatalf("page too large: have %d transactions, want at most 3", len(page.Transactions))
+		}
+		for _, tx := range page.Transactions {
+			have = append(have, tx.Hash)
+		}
+		if page.Cursor == nil {
+			break
+		}
+		pagination.Cursor = page.Cursor
+	}
+	if len(have) != len(want) {
+		t.Fatalf("transactions mismatch: have %v, want %v", have, want)
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Fatalf("transaction %d mismatch: have %x, want %x", i, have[i], want[i])
+		}
+	}
+	if _, err := accountTransactions(db, account, 0, 3, &AccountTxPagination{Cursor: hexutil.Bytes{1}}); err != errInvalidCursor {
+		t.Errorf("error mismatch: have %v, want %v", err, errInvalidCursor)
+	}
+}
@@ -33,6 +33,8 @@ import (
 	"github.com/celo-org/celo-blockchain/core/state"
 	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/internal/ethapi"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/miner"
 	"github.com/celo-org/celo-blockchain/rlp"
 	"github.com/celo-org/celo-blockchain/rpc"
 	"github.com/celo-org/celo-blockchain/trie"
@@ -73,6 +75,43 @@ func (api *PublicEthereumAPI) ChainId() hexutil.Uint64 {
 	return (hexutil.Uint64)(chainID.Uint64())
 }
 
+// SendBundleArgs represents the arguments to submit a bundle of transactions.
+type SendBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	MinTimestamp      *hexutil.Uint64 `json:"minTimestamp"`
+	MaxTimestamp      *hexutil.Uint64 `json:"maxTimestamp"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+}
+
+// SendBundle submits an ordered set of signed transactions to be included atomically
+// at the top of the given block if this node proposes it, and returns the bundle hash.
+// Transactions of the bundle may only revert if listed in revertingTxHashes.
+func (api *PublicEthereumAPI) SendBundle(args SendBundleArgs) (common.Hash, error) {
+	bundle := &miner.Bundle{
+		Txs:               make(types.Transactions, len(args.Txs)),
+		BlockNumber:       uint64(args.BlockNumber),
+		RevertingTxHashes: args.RevertingTxHashes,
+	}
+	for i, raw := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(raw); err != nil {
+			return common.Hash{}, fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+		bundle.Txs[i] = tx
+	}
+	if args.MinTimestamp != nil {
+		bundle.MinTimestamp = uint64(*args.MinTimestamp)
+	}
+	if args.MaxTimestamp != nil {
+		bundle.MaxTimestamp = uint64(*args.MaxTimestamp)
+	}
+	if err := api.e.Miner().SendBundle(bundle); err != nil {
+		return common.Hash{}, err
+	}
+	return bundle.Hash(), nil
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -89,6 +128,68 @@ func (api *PublicMinerAPI) Mining() bool {
 	return api.e.IsMining()
 }
 
+// PendingBlock creates a subscription that is triggered each time the miner
+// assembles a new pending block. Notifications carry the block with the hashes
+// of its transactions, the fee currency and effective tip of every transaction,
+// and the gas left for every fee currency.
+func (api *PublicMinerAPI) PendingBlock(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		blocks := make(chan *miner.PendingBlock, 16)
+		blocksSub := api.e.Miner().SubscribePendingBlock(blocks)
+		defer blocksSub.Unsubscribe()
+
+		for {
+			select {
+			case block := <-blocks:
+				fields, err := rpcMarshalPendingBlock(block)
+				if err != nil {
+					log.Warn("Failed to marshal pending block", "number", block.Block.Number(), "err", err)
+					continue
+				}
+				notifier.Notify(rpcSub.ID, fields)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// rpcMarshalPendingBlock converts a pending block to the RPC output of the
+// pending block subscription.
+func rpcMarshalPendingBlock(pending *miner.PendingBlock) (map[string]interface{}, error) {
+	fields, err := ethapi.RPCMarshalBlock(pending.Block, true, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	fees := make([]map[string]interface{}, len(pending.Fees))
+	for i, fee := range pending.Fees {
+		fees[i] = map[string]interface{}{
+			"hash":               fee.Hash,
+			"feeCurrency":        fee.FeeCurrency,
+			"gasUsed":            hexutil.Uint64(fee.GasUsed),
+			"effectiveTip":       (*hexutil.Big)(fee.EffectiveTip),
+			"effectiveTipInCelo": (*hexutil.Big)(fee.EffectiveTipInCelo),
+		}
+	}
+	remaining := make(map[common.Address]hexutil.Uint64, len(pending.FeeCurrencyGasRemaining))
+	for currency, gas := range pending.FeeCurrencyGasRemaining {
+		remaining[currency] = hexutil.Uint64(gas)
+	}
+	fields["transactionFees"] = fees
+	fields["celoGasRemaining"] = hexutil.Uint64(pending.CeloGasRemaining)
+	fields["feeCurrencyGasRemaining"] = remaining
+	return fields, nil
+}
+
 // PrivateMinerAPI provides private RPC methods to control the miner.
 // These methods can be abused by external users and must be considered insecure for use by untrusted users.
 type PrivateMinerAPI struct {
@@ -136,6 +237,138 @@ func (api *PrivateMinerAPI) SetEtherbase(etherbase common.Address) bool {
 	return true
 }
 
+// TxFeeRecipientScheduleArgs rotates the tx fee recipient between Recipients every
+// Period blocks sealed by the validator from block Start, in proportion to their
+// Weights if given, see miner.TxFeeRecipientSchedule.
+type TxFeeRecipientScheduleArgs struct {
+	Start      hexutil.Uint64   `json:"start"`
+	Period     hexutil.Uint64   `json:"period"`
+	Recipients []common.Address `json:"recipients"`
+	Weights    []hexutil.Uint64 `json:"weights,omitempty"`
+}
+
+// SetTxFeeRecipientSchedule sets the schedule of the addresses receiving the tx fees of the
+// mined blocks, an empty list of recipients removes it.
+func (api *PrivateMinerAPI) SetTxFeeRecipientSchedule(args TxFeeRecipientScheduleArgs) (bool, error) {
+	schedule := &miner.TxFeeRecipientSchedule{
+		Start:      uint64(args.Start),
+		Period:     uint64(args.Period),
+		Recipients: args.Recipients,
+	}
+	for _, weight := range args.Weights {
+		schedule.Weights = append(schedule.Weights, uint64(weight))
+	}
+	if err := api.e.Miner().SetTxFeeRecipientSchedule(schedule); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetGasLimitOverride sets the cap of the gas limit of the blocks built while not
+// validating, such as the pending block, zero removing it.
+func (api *PrivateMinerAPI) SetGasLimitOverride(limit hexutil.Uint64) bool {
+	api.e.Miner().SetGasLimitOverride(uint64(limit))
+	return true
+}
+
+// GasLimitOverride returns the cap of the gas limit of the blocks built while not
+// validating, zero if there is none.
+func (api *PrivateMinerAPI) GasLimitOverride() hexutil.Uint64 {
+	return hexutil.Uint64(api.e.Miner().GasLimitOverride())
+}
+
+// FeeCurrencyLimits are the fractions of the block gas limit usable by the
+// transactions paying fees in each fee currency.
+type FeeCurrencyLimits struct {
+	Default float64                    `json:"default"`
+	Limits  map[common.Address]float64 `json:"limits"`
+}
+
+// SetFeeCurrencyLimits replaces the per fee currency limits of the mined blocks, the
+// default limit applying to the currencies without one is kept if not given. The new
+// limits are used from the next block built.
+func (api *PrivateMinerAPI) SetFeeCurrencyLimits(limits map[common.Address]float64, defaultLimit *float64) (bool, error) {
+	current, _ := api.e.Miner().FeeCurrencyLimits()
+	if defaultLimit != nil {
+		current = *defaultLimit
+	}
+	if err := api.e.Miner().SetFeeCurrencyLimits(current, limits); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FeeCurrencyLimits returns the fee currency limits used for the mined blocks.
+func (api *PrivateMinerAPI) FeeCurrencyLimits() FeeCurrencyLimits {
+	defaultLimit, limits := api.e.Miner().FeeCurrencyLimits()
+	return FeeCurrencyLimits{Default: defaultLimit, Limits: limits}
+}
+
+// BuildStatus returns the status of the block being built, or of the last one
+// built, which shows how full it got and why transactions were left out.
+func (api *PrivateMinerAPI) BuildStatus() *miner.BuildStatus {
+	return api.e.Miner().BuildStatus()
+}
+
+// SetDenylist replaces the addresses and contract code hashes left out of the
+// blocks built by this node. The change isn't written to the denylist file.
+func (api *PrivateMinerAPI) SetDenylist(list miner.Denylist) bool {
+	api.e.Miner().SetDenylist(&list)
+	return true
+}
+
+// Denylist returns the addresses and contract code hashes left out of the
+// blocks built by this node.
+func (api *PrivateMinerAPI) Denylist() *miner.Denylist {
+	return api.e.Miner().Denylist()
+}
+
+// ReloadDenylist replaces the denylist with the content of the denylist file.
+func (api *PrivateMinerAPI) ReloadDenylist() (bool, error) {
+	if err := api.e.Miner().ReloadDenylist(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DryRunBlock assembles a block with the pending transactions on top of the
+// current head, without sealing it, and returns it with the fee currency
+// breakdown of its transactions, the fees paid by the block in CELO and the gas
+// cost of every transaction in CELO. It works whether the node mines or not, to
+// check the fee currency limits and gas price minimums before mining is enabled.
+func (api *PrivateMinerAPI) DryRunBlock(ctx context.Context) (map[string]interface{}, error) {
+	result, err := api.e.Miner().DryRunBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := rpcMarshalPendingBlock(result.PendingBlock)
+	if err != nil {
+		return nil, err
+	}
+	for i, fee := range fields["transactionFees"].([]map[string]interface{}) {
+		fee["gasCostInCelo"] = (*hexutil.Big)(result.GasCostsInCelo[i])
+	}
+	fields["totalFees"] = result.TotalFees.Text('f', 18)
+	fields["truncated"] = result.Truncated
+	fields["skipped"] = result.Skipped
+	return fields, nil
+}
+
+// SubmitBlock sets an RLP encoded block built by a trusted external builder as the
+// candidate for the next block, and returns its hash. Only its parent and its
+// transactions matter: they are re-executed in order in the block proposed, which
+// falls back on the local selection if any of them can't be included.
+func (api *PrivateMinerAPI) SubmitBlock(raw hexutil.Bytes) (common.Hash, error) {
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(raw, block); err != nil {
+		return common.Hash{}, fmt.Errorf("invalid block: %v", err)
+	}
+	if err := api.e.Miner().SubmitBlockCandidate(block); err != nil {
+		return common.Hash{}, err
+	}
+	return block.Hash(), nil
+}
+
 // PrivateAdminAPI is the collection of Ethereum full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -148,6 +381,17 @@ func NewPrivateAdminAPI(eth *Ethereum) *PrivateAdminAPI {
 	return &PrivateAdminAPI{eth: eth}
 }
 
+// NodeHealth reports the status of the node, running normally as the chain
+// database passed its integrity check.
+func (api *PrivateAdminAPI) NodeHealth() *ethapi.NodeHealth {
+	return &ethapi.NodeHealth{
+		Mode:   ethapi.NodeModeNormal,
+		Head:   hexutil.Uint64(api.eth.BlockChain().CurrentBlock().NumberU64()),
+		Synced: api.eth.Synced(),
+		Mining: api.eth.IsMining(),
+	}
+}
+
 // ExportChain exports the current blockchain into a local file,
 // or a range of blocks if first and last are non-nil
 func (api *PrivateAdminAPI) ExportChain(file string, first *uint64, last *uint64) (bool, error) {
@@ -187,6 +431,62 @@ func (api *PrivateAdminAPI) ExportChain(file string, first *uint64, last *uint64
 	return true, nil
 }
 
+// PinnedState is a historical state kept from pruning.
+type PinnedState struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+	Root   common.Hash    `json:"root"`
+}
+
+func newPinnedState(state rawdb.PinnedState) *PinnedState {
+	return &PinnedState{Number: hexutil.Uint64(state.Number), Hash: state.Hash, Root: state.Root}
+}
+
+// pinnedNumber resolves the number of a block to pin or unpin.
+func (api *PrivateAdminAPI) pinnedNumber(blockNr rpc.BlockNumber) uint64 {
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		return api.eth.blockchain.CurrentBlock().NumberU64()
+	}
+	return uint64(blockNr)
+}
+
+// PinState keeps the state of the given block from pruning, so that it remains
+// queryable on a pruned node.
+func (api *PrivateAdminAPI) PinState(blockNr rpc.BlockNumber) (*PinnedState, error) {
+	state, err := api.eth.blockchain.PinState(api.pinnedNumber(blockNr))
+	if err != nil {
+		return nil, err
+	}
+	return newPinnedState(state), nil
+}
+
+// UnpinState lets the state of the given block be pruned again, and reports
+// whether it was pinned.
+func (api *PrivateAdminAPI) UnpinState(blockNr rpc.BlockNumber) (bool, error) {
+	return api.eth.blockchain.UnpinState(api.pinnedNumber(blockNr))
+}
+
+// PinnedStates lists the states kept from pruning.
+func (api *PrivateAdminAPI) PinnedStates() []*PinnedState {
+	states := make([]*PinnedState, 0)
+	for _, state := range api.eth.blockchain.PinnedStates() {
+		states = append(states, newPinnedState(state))
+	}
+	return states
+}
+
+// ClockStatus reports the skew of the local clock measured against the NTP
+// server and the timestamps of the recent blocks.
+func (api *PrivateAdminAPI) ClockStatus() *ClockStatus {
+	return api.eth.clock.status()
+}
+
+// HeadLagStatus reports the lag of the chain head behind the local clock and the
+// peers measured by the last check, and the state of the head lag alarm.
+func (api *PrivateAdminAPI) HeadLagStatus() HeadLagStatus {
+	return api.eth.headLag.lagStatus()
+}
+
 func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	for _, b := range bs {
 		if !chain.HasBlock(b.Hash(), b.NumberU64()) {
@@ -352,7 +652,7 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 const AccountRangeMaxResults = 256
 
 // AccountRange enumerates all accounts in the given block and start point in paging request
-func (api *PublicDebugAPI) AccountRange(blockNrOrHash rpc.BlockNumberOrHash, start []byte, maxResults int, nocode, nostorage, incompletes bool) (state.IteratorDump, error) {
+func (api *PublicDebugAPI) AccountRange(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, start []byte, maxResults int, nocode, nostorage, incompletes bool) (state.IteratorDump, error) {
 	var stateDb *state.StateDB
 	var err error
 
@@ -400,6 +700,9 @@ func (api *PublicDebugAPI) AccountRange(blockNrOrHash rpc.BlockNumberOrHash, sta
 	if maxResults > AccountRangeMaxResults || maxResults <= 0 {
 		opts.Max = AccountRangeMaxResults
 	}
+	if err := api.eth.stateRange.wait(ctx, int(opts.Max)); err != nil {
+		return state.IteratorDump{}, err
+	}
 	return stateDb.IteratorDump(opts), nil
 }
 
@@ -416,8 +719,15 @@ type storageEntry struct {
 	Value common.Hash  `json:"value"`
 }
 
-// StorageRangeAt returns the storage at the given block height and transaction index.
-func (api *PrivateDebugAPI) StorageRangeAt(blockHash common.Hash, txIndex int, contractAddress common.Address, keyStart hexutil.Bytes, maxResult int) (StorageRangeResult, error) {
+// StorageRangeAt returns the storage at the given block height and transaction index,
+// at most AccountRangeMaxResults slots.
+func (api *PrivateDebugAPI) StorageRangeAt(ctx context.Context, blockHash common.Hash, txIndex int, contractAddress common.Address, keyStart hexutil.Bytes, maxResult int) (StorageRangeResult, error) {
+	if maxResult > AccountRangeMaxResults || maxResult <= 0 {
+		maxResult = AccountRangeMaxResults
+	}
+	if err := api.eth.stateRange.wait(ctx, maxResult); err != nil {
+		return StorageRangeResult{}, err
+	}
 	// Retrieve the block
 	block := api.eth.blockchain.GetBlockByHash(blockHash)
 	if block == nil {
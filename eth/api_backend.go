@@ -26,7 +26,12 @@ import (
 	"github.com/celo-org/celo-blockchain/accounts"
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/consensus"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	istanbulBackend "github.com/celo-org/celo-blockchain/consensus/istanbul/backend"
+	"github.com/celo-org/celo-blockchain/contracts"
 	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/currency"
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/bloombits"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
@@ -222,6 +227,25 @@ func (b *EthAPIBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.
 	return b.eth.miner.SubscribePendingLogs(ch)
 }
 
+// SubscribeTxLifecycleEvent subscribes to the transactions queued by the pool, and
+// to the ones proposed and sealed by the miner.
+func (b *EthAPIBackend) SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	poolSub := b.eth.TxPool().SubscribeTxLifecycleEvent(ch)
+	minerSub := b.eth.miner.SubscribeTxLifecycle(ch)
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer poolSub.Unsubscribe()
+		defer minerSub.Unsubscribe()
+		select {
+		case <-quit:
+			return nil
+		case err := <-poolSub.Err():
+			return err
+		case err := <-minerSub.Err():
+			return err
+		}
+	})
+}
+
 func (b *EthAPIBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.eth.BlockChain().SubscribeChainEvent(ch)
 }
@@ -239,7 +263,16 @@ func (b *EthAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 }
 
 func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
-	return b.eth.txPool.AddLocal(signedTx)
+	stem := b.eth.handler.stem
+	if stem == nil {
+		return b.eth.txPool.AddLocal(signedTx)
+	}
+	stem.markLocal(signedTx.Hash())
+	err := b.eth.txPool.AddLocal(signedTx)
+	if err != nil {
+		stem.unmarkLocal(signedTx.Hash())
+	}
+	return err
 }
 
 func (b *EthAPIBackend) GetPoolTransactions() (types.Transactions, error) {
@@ -343,6 +376,45 @@ func (b *EthAPIBackend) RealGasPriceMinimumForHeader(ctx context.Context, curren
 	return gp.GetRealBaseFeeForCurrency(vmRunner, currencyAddress, header.BaseFee)
 }
 
+func (b *EthAPIBackend) FeeCurrencyWhitelistForHeader(ctx context.Context, header *types.Header) (common.Address, []common.Address, error) {
+	if governance, err := b.eth.BlockChain().Governance().Params(header); err == nil {
+		contract := governance.ContractAddress(config.FeeCurrencyWhitelistRegistryId)
+		if contract == (common.Address{}) {
+			return common.Address{}, nil, contracts.ErrSmartContractNotDeployed
+		}
+		return contract, governance.Whitelist, nil
+	}
+	state, _, err := b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(header.Hash(), false))
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	vmRunner := b.eth.BlockChain().NewEVMRunner(header, state)
+	contract, err := contracts.GetRegisteredAddress(vmRunner, config.FeeCurrencyWhitelistRegistryId)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	whitelist, err := currency.CurrencyWhitelist(vmRunner)
+	return contract, whitelist, err
+}
+
+func (b *EthAPIBackend) ConversionFunctionsForHeader(ctx context.Context, header *types.Header) (func(*common.Address) *big.Int, types.ToCELOFn, error) {
+	state, _, err := b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(header.Hash(), false))
+	if err != nil {
+		return nil, nil, err
+	}
+	sysCtx := core.NewSysContractCallCtx(b.ChainConfig(), header, state, b.eth.BlockChain())
+	baseFeeFn, toCELO := core.CreateConversionFunctions(sysCtx, b.eth.BlockChain(), header, state.Copy())
+	return baseFeeFn, toCELO, nil
+}
+
+func (b *EthAPIBackend) ValidatorsForHeader(ctx context.Context, header *types.Header) ([]common.Address, error) {
+	engine, ok := b.eth.Engine().(*istanbulBackend.Backend)
+	if !ok {
+		return nil, errors.New("consensus engine is not istanbul")
+	}
+	return istanbul.MapValidatorsToAddresses(engine.GetValidators(header.Number, header.Hash())), nil
+}
+
 func (b *EthAPIBackend) SuggestPrice(ctx context.Context, currencyAddress *common.Address) (*big.Int, error) {
 	vmRunner, err := b.eth.BlockChain().NewEVMRunnerForCurrentBlock()
 	if err != nil {
@@ -364,6 +436,11 @@ func (b *EthAPIBackend) GetBlockGasLimit(ctx context.Context, blockNrOrHash rpc.
 	// not the end of it (the state_root of the header is the a state resulted of applying the block). So, the state to
 	// be used, MUST be the state result of the parent block unless this is the genesis block.
 	h := header.ParentOrGenesisHash()
+	if parent := b.eth.BlockChain().GetHeaderByHash(h); parent != nil {
+		if governance, err := b.eth.BlockChain().Governance().Params(parent); err == nil {
+			return governance.BlockGasLimit
+		}
+	}
 	state, parent, err := b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHash{BlockHash: &h})
 	if err != nil {
 		log.Warn("Cannot create evmCaller to get blockGasLimit", "err", err)
@@ -402,6 +479,9 @@ func (b *EthAPIBackend) NewEVMRunner(header *types.Header, state vm.StateDB) vm.
 }
 
 func (b *EthAPIBackend) GetIntrinsicGasForAlternativeFeeCurrency(ctx context.Context) uint64 {
+	if governance, err := b.eth.BlockChain().Governance().Params(b.CurrentHeader()); err == nil {
+		return governance.IntrinsicGasForAlternativeFeeCurrency
+	}
 	vmRunner, err := b.eth.BlockChain().NewEVMRunnerForCurrentBlock()
 	if err != nil {
 		log.Warn("Cannot create evmCaller to get intrinsic gas for alternative fee currency", "err", err)
@@ -442,6 +522,10 @@ func (b *EthAPIBackend) RPCEthCompatibility() bool {
 	return b.eth.config.RPCEthCompatibility
 }
 
+func (b *EthAPIBackend) RPCReceiptFeeFields() bool {
+	return b.eth.config.RPCReceiptFeeFields
+}
+
 func (b *EthAPIBackend) RPCTxFeeCap() float64 {
 	return b.eth.config.RPCTxFeeCap
 }
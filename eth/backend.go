@@ -32,6 +32,7 @@ import (
 	"github.com/celo-org/celo-blockchain/consensus"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
 	istanbulBackend "github.com/celo-org/celo-blockchain/consensus/istanbul/backend"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/remotesigner"
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/bloombits"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
@@ -47,6 +48,7 @@ import (
 	// "github.com/celo-org/celo-blockchain/eth/protocols/snap"
 	"github.com/celo-org/celo-blockchain/ethdb"
 	"github.com/celo-org/celo-blockchain/event"
+	"github.com/celo-org/celo-blockchain/internal/chaos"
 	"github.com/celo-org/celo-blockchain/internal/ethapi"
 	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/miner"
@@ -81,9 +83,12 @@ type Ethereum struct {
 	engine         consensus.Engine
 	accountManager *accounts.Manager
 
-	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
-	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
-	closeBloomHandler chan struct{}
+	bloomRequests       chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
+	bloomIndexer        *core.ChainIndexer             // Bloom indexer operating during block imports
+	gasUsageIndexer     *core.ChainIndexer             // Gas usage indexer, nil if disabled
+	exchangeRateIndexer *core.ChainIndexer             // Exchange rate indexer, nil if disabled
+	accountTxIndexer    *core.ChainIndexer             // Account transaction indexer, nil if disabled
+	closeBloomHandler   chan struct{}
 
 	APIBackend *EthAPIBackend
 
@@ -97,6 +102,12 @@ type Ethereum struct {
 	netRPCService *ethapi.PublicNetAPI
 
 	p2pServer *p2p.Server
+	clock     *clockMonitor
+	headLag   *headLagMonitor
+
+	stateRange *stateRangeLimiter // Rate limit of the state range APIs
+
+	remoteSigner *remotesigner.Client // Signer of the validator keys, if remote
 
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price, validator and txFeeRecipient)
 }
@@ -111,6 +122,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
+	if config.SyncCheckpoint != "" && config.SyncMode != downloader.FastSync {
+		return nil, errors.New("can't sync from a checkpoint in another sync mode than fast sync")
+	}
 	if config.NoPruning && config.TrieDirtyCache > 0 {
 		if config.SnapshotCache > 0 {
 			config.TrieCleanCache += config.TrieDirtyCache * 3 / 5
@@ -131,6 +145,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	if chaos.Enabled {
+		chainDb = chaos.WrapDatabase(chainDb)
+	}
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithOverride(chainDb, config.Genesis, config.OverrideHFork)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
@@ -145,6 +162,11 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err := pruner.RecoverPruning(stack.ResolvePath(""), chainDb, stack.ResolvePath(config.TrieCleanCacheJournal)); err != nil {
 		log.Error("Failed to recover state", "error", err)
 	}
+	if config.SyncCheckpoint != "" {
+		if err := importSyncCheckpoint(chainDb, chainConfig, config.SyncCheckpoint); err != nil {
+			return nil, err
+		}
+	}
 	if config.RPCGasInflationRate == 0 {
 		// if it was not set, default it as 1
 		config.RPCGasInflationRate = 1
@@ -186,6 +208,8 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	var (
 		vmConfig = vm.Config{
 			EnablePreimageRecording: config.EnablePreimageRecording,
+			SyntheticFeeLogs:        config.SyntheticFeeLogs,
+			SystemCallFastPath:      config.SystemCallFastPath,
 		}
 		cacheConfig = &core.CacheConfig{
 			TrieCleanLimit:      config.TrieCleanCache,
@@ -197,6 +221,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			InternalTransfers:   config.InternalTransferIndex,
 		}
 	)
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit)
@@ -209,11 +234,30 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		eth.blockchain.SetHead(compat.RewindTo)
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
+	if err := checkChainConsistency(eth.blockchain, config.StrictConfigCheck, config.ConfigCheckReport); err != nil {
+		return nil, err
+	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.GasUsageIndex && chainConfig.Istanbul != nil {
+		eth.gasUsageIndexer = core.NewGasUsageIndexer(chainDb, chainConfig.Istanbul.Epoch, chainConfig.FullHeaderChainAvailable)
+		eth.gasUsageIndexer.Start(eth.blockchain)
+	}
+	if config.ExchangeRateIndex {
+		eth.exchangeRateIndexer = core.NewExchangeRateIndexer(chainDb, eth.blockchain, chainConfig.FullHeaderChainAvailable)
+		eth.exchangeRateIndexer.Start(eth.blockchain)
+	}
+	if config.AccountTxIndex {
+		eth.accountTxIndexer = core.NewAccountTxIndexer(chainDb, chainConfig, chainConfig.FullHeaderChainAvailable)
+		eth.accountTxIndexer.Start(eth.blockchain)
+	}
+	eth.clock = newClockMonitor(config.NTPServer, eth.blockchain)
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.RemoteJournal != "" {
+		config.TxPool.RemoteJournal = stack.ResolvePath(config.TxPool.RemoteJournal)
+	}
 
 	eth.txPool = core.NewTxPool(config.TxPool, chainConfig, eth.blockchain)
 
@@ -223,6 +267,11 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if checkpoint == nil {
 		checkpoint = params.TrustedCheckpoints[genesisHash]
 	}
+	// Proxied validators receive every broadcast through each of their proxies
+	var knownCache uint64
+	if config.Istanbul.Proxied {
+		knownCache = config.ProxiedKnownCache
+	}
 	if eth.handler, err = newHandler(&handlerConfig{
 		Database:     chainDb,
 		Chain:        eth.blockchain,
@@ -236,9 +285,13 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		server:       stack.Server(),
 		proxyServer:  stack.ProxyServer(),
 		MinSyncPeers: config.MinSyncPeers,
+		KnownCache:   knownCache,
+		TxStemRelay:  config.TxStemRelay,
 	}); err != nil {
 		return nil, err
 	}
+	eth.stateRange = newStateRangeLimiter(config.RPCStateRangeRate)
+	eth.headLag = newHeadLagMonitor(config.HeadLagTimeThreshold, config.HeadLagBlockThreshold, config.HeadLagWebhook, config.HeadLagProxyFailover, (*headLagEthereum)(eth))
 
 	// If the engine is istanbul, then inject the blockchain
 	if istanbul, isIstanbul := eth.engine.(*istanbulBackend.Backend); isIstanbul {
@@ -248,10 +301,21 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 				stateRoot := eth.blockchain.GetHeaderByHash(hash).Root
 				return eth.blockchain.StateAt(stateRoot)
 			})
+		if istanbul.IsProxiedValidator() {
+			istanbul.GetProxiedValidatorEngine().SetHealthProbe(eth.proxyHealthProbe(config.Istanbul.ProxyHealthCheckMaxLag))
+		}
 	}
 
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine, chainDb)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
+	eth.miner.SetForcedTxSigner(func(from common.Address, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+		account := accounts.Account{Address: from}
+		wallet, err := eth.accountManager.Find(account)
+		if err != nil {
+			return nil, err
+		}
+		return wallet.SignTx(account, tx, chainID)
+	})
 
 	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), true, eth}
 
@@ -336,11 +400,41 @@ func (s *Ethereum) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPrivateMinerAPI(s),
 			Public:    false,
+		}, {
+			Namespace: "engine",
+			Version:   "1.0",
+			Service:   NewPrivateEngineAPI(s),
+			Public:    false,
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
 			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute),
 			Public:    true,
+		}, {
+			Namespace: "celo",
+			Version:   "1.0",
+			Service:   NewPublicGasUsageAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "celo",
+			Version:   "1.0",
+			Service:   NewPublicExchangeRateAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "celo",
+			Version:   "1.0",
+			Service:   NewPublicInternalTransferAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPublicAccountTxAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "txpool",
+			Version:   "1.0",
+			Service:   NewPublicTxPoolStreamAPI(s),
+			Public:    true,
 		}, {
 			Namespace: "admin",
 			Version:   "1.0",
@@ -479,24 +573,14 @@ func (s *Ethereum) StartMining() error {
 		}
 
 		if istanbul, isIstanbul := s.engine.(*istanbulBackend.Backend); isIstanbul {
-			valAccount := accounts.Account{Address: validator}
-			wallet, err := s.accountManager.Find(valAccount)
-			if wallet == nil || err != nil {
-				log.Error("Validator account unavailable locally", "err", err)
-				return fmt.Errorf("signer missing: %v", err)
-			}
-			publicKey, err := wallet.GetPublicKey(valAccount)
+			signer, blsSigner, err := s.validatorSigners(validator, blsbase)
 			if err != nil {
-				return fmt.Errorf("ECDSA public key missing: %v", err)
+				return err
 			}
-			blswallet, err := s.accountManager.Find(accounts.Account{Address: blsbase})
-			if blswallet == nil || err != nil {
-				log.Error("BLSbase account unavailable locally", "err", err)
-				return fmt.Errorf("BLS signer missing: %v", err)
+			if err := istanbul.AuthorizeSigner(validator, blsbase, signer, blsSigner); err != nil {
+				return err
 			}
 
-			istanbul.Authorize(validator, blsbase, publicKey, wallet.Decrypt, wallet.SignData, blswallet.SignBLS, wallet.SignHash)
-
 			if istanbul.IsProxiedValidator() {
 				if err := istanbul.StartProxiedValidatorEngine(); err != nil {
 					log.Error("Error in starting proxied validator engine", "err", err)
@@ -566,6 +650,35 @@ func (s *Ethereum) Synced() bool                        { return atomic.LoadUint
 func (s *Ethereum) ArchiveMode() bool                   { return s.config.NoPruning }
 func (s *Ethereum) BloomIndexer() *core.ChainIndexer    { return s.bloomIndexer }
 
+// validatorSigners returns the signers of the validator and BLS keys: the remote
+// signing service if configured, the local wallets otherwise.
+func (s *Ethereum) validatorSigners(validator, blsbase common.Address) (istanbul.Signer, istanbul.Signer, error) {
+	if endpoint := s.config.Istanbul.RemoteSigner; endpoint != "" {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.remoteSigner == nil {
+			client, err := remotesigner.Dial(endpoint)
+			if err != nil {
+				log.Error("Cannot connect to the remote signer", "err", err)
+				return nil, nil, fmt.Errorf("remote signer unavailable: %v", err)
+			}
+			s.remoteSigner = client
+		}
+		return s.remoteSigner, s.remoteSigner, nil
+	}
+	wallet, err := s.accountManager.Find(accounts.Account{Address: validator})
+	if wallet == nil || err != nil {
+		log.Error("Validator account unavailable locally", "err", err)
+		return nil, nil, fmt.Errorf("signer missing: %v", err)
+	}
+	blswallet, err := s.accountManager.Find(accounts.Account{Address: blsbase})
+	if blswallet == nil || err != nil {
+		log.Error("BLSbase account unavailable locally", "err", err)
+		return nil, nil, fmt.Errorf("BLS signer missing: %v", err)
+	}
+	return wallet, blswallet, nil
+}
+
 // Protocols returns all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
@@ -583,6 +696,8 @@ func (s *Ethereum) Start() error {
 
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers(params.BloomBitsBlocks)
+	s.clock.start()
+	s.headLag.start()
 
 	// Figure out a max peers count based on the server limits
 	maxPeers := s.p2pServer.MaxPeers
@@ -613,12 +728,26 @@ func (s *Ethereum) Stop() error {
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
+	if s.gasUsageIndexer != nil {
+		s.gasUsageIndexer.Close()
+	}
+	if s.exchangeRateIndexer != nil {
+		s.exchangeRateIndexer.Close()
+	}
+	if s.accountTxIndexer != nil {
+		s.accountTxIndexer.Close()
+	}
 	close(s.closeBloomHandler)
+	s.clock.stop()
+	s.headLag.stop()
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.miner.Close()
 	s.blockchain.Stop()
 	s.engine.Close()
+	if s.remoteSigner != nil {
+		s.remoteSigner.Close()
+	}
 	rawdb.PopUncleanShutdownMarker(s.chainDb)
 	s.chainDb.Close()
 	s.eventMux.Stop()
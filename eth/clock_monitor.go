@@ -0,0 +1,251 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/event"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+	"github.com/celo-org/celo-blockchain/p2p/discover"
+)
+
+const (
+	clockCheckInterval = 10 * time.Minute // Interval between the checks against the NTP server
+	clockNTPChecks     = 3                // Number of measurements of a check
+
+	clockBlockWindow = 64               // Number of recent blocks the skew is measured against
+	clockBlockMaxAge = 30 * time.Second // Blocks older when imported are synced, their delay says nothing of the clock
+
+	// clockSkewWarnThreshold is the skew warned about. Blocks are rejected as
+	// future ones as soon as their timestamp, in seconds, passes the local clock,
+	// and prepareBlock stamps the proposals with it, so a second of skew already
+	// gets blocks rejected.
+	clockSkewWarnThreshold = 500 * time.Millisecond
+)
+
+var (
+	ntpOffsetGauge   = metrics.NewRegisteredGauge("eth/clock/ntp_offset", nil)
+	blockOffsetGauge = metrics.NewRegisteredGauge("eth/clock/block_offset", nil)
+)
+
+// ClockStatus reports the skew of the local clock, in milliseconds.
+type ClockStatus struct {
+	NTPServer     string `json:"ntpServer"`
+	NTPOffset     *int64 `json:"ntpOffset"`          // Local clock minus the NTP time, nil until checked
+	NTPError      string `json:"ntpError,omitempty"` // Failure of the last check
+	NTPChecked    uint64 `json:"ntpChecked"`         // Unix time of the last check
+	BlockOffset   *int64 `json:"blockOffset"`        // Least delay of the recent blocks past their timestamp, negative if the clock is behind their proposers
+	BlockSamples  int    `json:"blockSamples"`
+	WarnThreshold int64  `json:"warnThreshold"`
+	Skewed        bool   `json:"skewed"` // Whether either offset is past the threshold
+}
+
+// chainHeadSubscriber is the part of the blockchain the clock monitor follows.
+type chainHeadSubscriber interface {
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// clockMonitor measures the skew of the local clock against an NTP server and
+// against the timestamps of the blocks imported, and warns when it comes close
+// to getting blocks rejected.
+type clockMonitor struct {
+	server string // NTP server, none if empty
+	chain  chainHeadSubscriber
+
+	mu         sync.Mutex
+	ntpOffset  time.Duration
+	ntpChecked time.Time
+	ntpErr     error
+	delays     []time.Duration // Delays of the recent blocks past their timestamp, as a ring
+	next       int
+	skewed     bool
+
+	now      func() time.Time
+	ntpDrift func(server string, measurements int) (time.Duration, error)
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newClockMonitor(server string, chain chainHeadSubscriber) *clockMonitor {
+	return &clockMonitor{
+		server:   server,
+		chain:    chain,
+		now:      time.Now,
+		ntpDrift: discover.SNTPDrift,
+		quit:     make(chan struct{}),
+	}
+}
+
+// start launches the goroutines following the chain and querying the NTP server.
+func (m *clockMonitor) start() {
+	m.wg.Add(1)
+	go m.headLoop()
+	if m.server != "" {
+		m.wg.Add(1)
+		go m.ntpLoop()
+	}
+}
+
+// stop terminates the goroutines of the monitor.
+func (m *clockMonitor) stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// headLoop measures the delay of every imported head. The NTP checks are kept
+// out of it, blocking on the server would hold up the chain head feed.
+func (m *clockMonitor) headLoop() {
+	defer m.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := m.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case head := <-heads:
+			m.observeBlock(head.Block.Header())
+		case <-sub.Err():
+			return
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// ntpLoop checks the clock against the NTP server periodically.
+func (m *clockMonitor) ntpLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(clockCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		m.checkNTP()
+		select {
+		case <-ticker.C:
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// checkNTP measures the offset of the clock against the NTP server.
+func (m *clockMonitor) checkNTP() {
+	drift, err := m.ntpDrift(m.server, clockNTPChecks)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ntpChecked, m.ntpErr = m.now(), err
+	if err != nil {
+		log.Debug("Failed to check the clock against NTP", "server", m.server, "err", err)
+		return
+	}
+	m.ntpOffset = drift
+	ntpOffsetGauge.Update(drift.Milliseconds())
+	m.checkSkew()
+}
+
+// observeBlock records the delay of an imported block past its timestamp.
+func (m *clockMonitor) observeBlock(header *types.Header) {
+	delay := m.now().Sub(time.Unix(int64(header.Time), 0))
+	if delay > clockBlockMaxAge {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.delays) < clockBlockWindow {
+		m.delays = append(m.delays, delay)
+	} else {
+		m.delays[m.next] = delay
+	}
+	m.next = (m.next + 1) % clockBlockWindow
+	blockOffsetGauge.Update(m.blockOffset().Milliseconds())
+	m.checkSkew()
+}
+
+// blockOffset returns the least delay of the recent blocks, the one closest to
+// the skew of the clock since the blocks can't be imported before they're made.
+// It must be called with the lock held.
+func (m *clockMonitor) blockOffset() time.Duration {
+	offset := m.delays[0]
+	for _, delay := range m.delays[1:] {
+		if delay < offset {
+			offset = delay
+		}
+	}
+	return offset
+}
+
+// checkSkew warns when the clock goes past the skew threshold, and when it's
+// back within. It must be called with the lock held.
+func (m *clockMonitor) checkSkew() {
+	var (
+		ntpSkewed   = !m.ntpChecked.IsZero() && m.ntpErr == nil && (m.ntpOffset >= clockSkewWarnThreshold || m.ntpOffset <= -clockSkewWarnThreshold)
+		blockSkewed = len(m.delays) > 0 && m.blockOffset() <= -clockSkewWarnThreshold
+		skewed      = ntpSkewed || blockSkewed
+	)
+	if skewed && !m.skewed {
+		ctx := []interface{}{"threshold", clockSkewWarnThreshold}
+		if ntpSkewed {
+			ctx = append(ctx, "ntpOffset", m.ntpOffset)
+		}
+		if blockSkewed {
+			ctx = append(ctx, "blockOffset", m.blockOffset())
+		}
+		log.Warn("System clock is skewed, blocks may get rejected as future ones", ctx...)
+		log.Warn("Please enable network time synchronisation in system settings.")
+	} else if !skewed && m.skewed {
+		log.Info("System clock skew back within threshold")
+	}
+	m.skewed = skewed
+}
+
+// status reports the skew measured.
+func (m *clockMonitor) status() *ClockStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := &ClockStatus{
+		NTPServer:     m.server,
+		BlockSamples:  len(m.delays),
+		WarnThreshold: clockSkewWarnThreshold.Milliseconds(),
+		Skewed:        m.skewed,
+	}
+	if !m.ntpChecked.IsZero() {
+		status.NTPChecked = uint64(m.ntpChecked.Unix())
+		if m.ntpErr != nil {
+			status.NTPError = m.ntpErr.Error()
+		} else {
+			offset := m.ntpOffset.Milliseconds()
+			status.NTPOffset = &offset
+		}
+	}
+	if len(m.delays) > 0 {
+		offset := m.blockOffset().Milliseconds()
+		status.BlockOffset = &offset
+	}
+	return status
+}
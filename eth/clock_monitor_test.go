@@ -0,0 +1,55 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/core/types"
+)
+
+func TestClockMonitorSkew(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := newClockMonitor("ntp.test", nil)
+	m.now = func() time.Time { return now }
+	m.ntpDrift = func(server string, measurements int) (time.Duration, error) { return 100 * time.Millisecond, nil }
+
+	// Synced blocks are left out, the others measure the offset by the least delay
+	m.observeBlock(&types.Header{Time: 900})
+	m.observeBlock(&types.Header{Time: 998})
+	m.observeBlock(&types.Header{Time: 1000})
+	if status := m.status(); status.BlockSamples != 2 || *status.BlockOffset != 0 || status.Skewed {
+		t.Fatalf("status mismatch: have %d samples, offset %d, skewed %v, want 2, 0, false", status.BlockSamples, *status.BlockOffset, status.Skewed)
+	}
+	m.checkNTP()
+	if status := m.status(); *status.NTPOffset != 100 || status.Skewed {
+		t.Fatalf("status mismatch: have ntp offset %d, skewed %v, want 100, false", *status.NTPOffset, status.Skewed)
+	}
+	// A block from the future means the clock is behind its proposer
+	m.observeBlock(&types.Header{Time: 1001})
+	if status := m.status(); *status.BlockOffset != -1000 || !status.Skewed {
+		t.Fatalf("status mismatch: have offset %d, skewed %v, want -1000, true", *status.BlockOffset, status.Skewed)
+	}
+	// It's forgotten once out of the window
+	for i := 0; i < clockBlockWindow; i++ {
+		m.observeBlock(&types.Header{Time: 1000})
+	}
+	if status := m.status(); status.BlockSamples != clockBlockWindow || *status.BlockOffset != 0 || status.Skewed {
+		t.Fatalf("status mismatch: have %d samples, offset %d, skewed %v, want %d, 0, false", status.BlockSamples, *status.BlockOffset, status.Skewed, clockBlockWindow)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/log"
+)
+
+// checkChainConsistency runs the startup chain config consistency checks, writes the
+// report if requested and, in strict mode, refuses to start when any check failed.
+func checkChainConsistency(chain *core.BlockChain, strict bool, reportFile string) error {
+	report := core.CheckChainConsistency(chain)
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if reportFile != "" {
+		if err := os.WriteFile(reportFile, encoded, 0644); err != nil {
+			log.Warn("Failed to write chain config consistency report", "file", reportFile, "err", err)
+		}
+	}
+
+	failed := report.Failed()
+	if len(failed) == 0 {
+		log.Debug("Chain config consistency checks passed", "report", string(encoded))
+		return nil
+	}
+	for _, check := range failed {
+		log.Error("Chain config consistency check failed", "check", check.Name, "reason", check.Message)
+	}
+	log.Warn("Chain config consistency report", "report", string(encoded))
+	if strict {
+		return fmt.Errorf("chain config consistency check %q failed: %s", failed[0].Name, failed[0].Message)
+	}
+	return nil
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/celo-org/celo-blockchain/core/rawdb"
 	"github.com/celo-org/celo-blockchain/core/state/snapshot"
 	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
 	"github.com/celo-org/celo-blockchain/eth/protocols/snap"
 	"github.com/celo-org/celo-blockchain/ethdb"
 	"github.com/celo-org/celo-blockchain/event"
@@ -85,6 +86,7 @@ var (
 	errNoSyncActive            = errors.New("no sync active")
 	errTooOld                  = errors.New("peer's protocol version too old")
 	errNoAncestorFound         = errors.New("no common ancestor found")
+	errPivotBelowCheckpoint    = errors.New("fast sync pivot below the sync checkpoint")
 )
 
 // If you adding a new variable add it at the bottom. Otherwise, you can end up making some uint64 unaligned to 8-byte
@@ -267,6 +269,11 @@ func New(checkpoint uint64, stateDb ethdb.Database, stateBloom *trie.SyncBloom,
 		ibftConsensus: ibftConsensus,
 		epoch:         epoch,
 	}
+	if factory, ok := chain.(vm.EVMRunnerFactory); ok && ibftConsensus {
+		dl.SnapSyncer.SetStateVerifier(func(root common.Hash) ([]snap.MissingNode, error) {
+			return dl.verifySysContractState(factory, root)
+		})
+	}
 	go dl.stateFetcher()
 	return dl
 }
@@ -296,12 +303,20 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 		log.Error("Unknown downloader chain/mode combo", "light", d.lightchain != nil, "full", d.blockchain != nil, "mode", mode)
 	}
 	log.Debug(fmt.Sprintf("Current head is %v", current))
+	heal := d.SnapSyncer.HealProgress()
 	return ethereum.SyncProgress{
-		StartingBlock: d.syncStatsChainOrigin,
-		CurrentBlock:  current,
-		HighestBlock:  d.syncStatsChainHeight,
-		PulledStates:  d.syncStatsState.processed,
-		KnownStates:   d.syncStatsState.processed + d.syncStatsState.pending,
+		StartingBlock:       d.syncStatsChainOrigin,
+		CurrentBlock:        current,
+		HighestBlock:        d.syncStatsChainHeight,
+		PulledStates:        d.syncStatsState.processed,
+		KnownStates:         d.syncStatsState.processed + d.syncStatsState.pending,
+		HealedTrienodes:     heal.HealedTrienodes,
+		HealedTrienodeBytes: heal.HealedTrienodeBytes,
+		HealedBytecodes:     heal.HealedBytecodes,
+		HealedBytecodeBytes: heal.HealedBytecodeBytes,
+		HealingNodes:        heal.HealingNodes,
+		HealRate:            uint64(heal.HealRate),
+		HealETA:             uint64(heal.HealETA.Seconds()),
 	}
 }
 
@@ -522,6 +537,12 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	// Ensure our origin point is below any fast sync pivot point
 	if mode == FastSync {
 		pivotNumber := pivot.Number.Uint64()
+		// The state can't be synced at a pivot before the checkpoint the chain was
+		// bootstrapped from, its blocks being missing.
+		if checkpoint := rawdb.ReadSyncCheckpoint(d.stateDB); checkpoint != nil && origin == checkpoint.Number && pivotNumber <= origin {
+			p.log.Debug("Pivot below the sync checkpoint", "pivot", pivotNumber, "checkpoint", checkpoint.Number)
+			return errPivotBelowCheckpoint
+		}
 		// Write out the pivot into the database so a rollback beyond it will
 		// reenable fast sync
 		rawdb.WriteLastPivotNumber(d.stateDB, pivotNumber)
@@ -557,6 +578,10 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		} else {
 			d.ancientLimit = 0
 		}
+		// The blocks of a chain bootstrapped from a checkpoint are never frozen.
+		if rawdb.ReadSyncCheckpoint(d.stateDB) != nil {
+			d.ancientLimit = 0
+		}
 		frozen, _ := d.stateDB.Ancients() // Ignore the error here since light client can also hit here.
 
 		// If a part of blockchain data has already been written into active store,
@@ -832,6 +857,14 @@ func (d *Downloader) findAncestor(p *peerConnection, remoteHeader *types.Header)
 
 	// TODO(tim) TODO(ashishb) see https://github.com/celo-org/celo-blockchain/commit/6c312a24b6041385c33eca066ff5604af315a41e
 
+	// If the chain was bootstrapped from a checkpoint, the blocks before it are
+	// missing: until the fast sync goes past it, the checkpoint is the ancestor.
+	if mode == FastSync {
+		if checkpoint := rawdb.ReadSyncCheckpoint(d.stateDB); checkpoint != nil && localHeight < checkpoint.Number {
+			return d.findCheckpointAncestor(p, checkpoint)
+		}
+	}
+
 	// If we're doing a light sync, ensure the floor doesn't go below the CHT, as
 	// all headers before that point will be missing.
 	if !mode.SyncFullBlockChain() {
@@ -1038,6 +1071,48 @@ func (d *Downloader) findAncestorBinarySearch(p *peerConnection, mode SyncMode,
 	return start, nil
 }
 
+// findCheckpointAncestor checks that the peer has the block of the checkpoint
+// the chain was bootstrapped from, and returns it as the common ancestor.
+func (d *Downloader) findCheckpointAncestor(p *peerConnection, checkpoint *rawdb.SyncCheckpoint) (uint64, error) {
+	p.log.Trace("Checking the sync checkpoint", "number", checkpoint.Number, "hash", checkpoint.Hash)
+	go p.peer.RequestHeadersByNumber(checkpoint.Number, 1, 0, false)
+
+	ttl := d.peers.rates.TargetTimeout()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return 0, errCanceled
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				log.Debug("Received headers from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			headers := packet.(*headerPack).headers
+			if len(headers) != 1 {
+				p.log.Warn("Multiple headers for single request", "headers", len(headers))
+				return 0, fmt.Errorf("%w: multiple headers (%d) for single request", errBadPeer, len(headers))
+			}
+			if headers[0].Number.Uint64() != checkpoint.Number || headers[0].Hash() != checkpoint.Hash {
+				p.log.Warn("Sync checkpoint mismatch", "number", headers[0].Number, "hash", headers[0].Hash(), "want", checkpoint.Hash)
+				return 0, errInvalidAncestor
+			}
+			p.log.Debug("Found common ancestor at the sync checkpoint", "number", checkpoint.Number, "hash", checkpoint.Hash)
+			return checkpoint.Number, nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for checkpoint header timed out", "elapsed", ttl)
+			return 0, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
 // fetchHeaders keeps retrieving headers concurrently from the number
 // requested, until no more are returned, potentially throttling on the way. To
 // facilitate concurrency but still protect against malicious nodes sending bad
@@ -374,7 +374,10 @@ func (dl *downloadTester) InsertReceiptChain(blocks types.Blocks, receipts []typ
 		}
 		if _, ok := dl.ancientBlocks[blocks[i].ParentHash()]; !ok {
 			if _, ok := dl.ownBlocks[blocks[i].ParentHash()]; !ok {
-				return i, errors.New("InsertReceiptChain: unknown parent")
+				// The block of a sync checkpoint is missing, the chain starting after it
+				if checkpoint := rawdb.ReadSyncCheckpoint(dl.stateDb); checkpoint == nil || checkpoint.Hash != blocks[i].ParentHash() {
+					return i, errors.New("InsertReceiptChain: unknown parent")
+				}
 			}
 		}
 		if blocks[i].NumberU64() <= ancientLimit {
@@ -595,6 +598,53 @@ func testCanonSync(t *testing.T, protocol uint, mode SyncMode) {
 	assertOwnChain(t, tester, chain.len())
 }
 
+// Tests that a chain bootstrapped from a sync checkpoint fast syncs from it,
+// without the blocks before it, and only from peers having the checkpoint.
+func TestCheckpointSynchronisation(t *testing.T) {
+	t.Parallel()
+
+	chain := testChainBase.shorten(blockCacheMaxItems - 15)
+	number := uint64(chain.len() / 2)
+	checkpoint := chain.headerm[chain.chain[number]]
+
+	newCheckpointTester := func(hash common.Hash) *downloadTester {
+		tester := newTester()
+		tester.ownHashes = append(tester.ownHashes, checkpoint.Hash())
+		tester.ownHeaders[checkpoint.Hash()] = checkpoint
+		tester.ownChainTd[checkpoint.Hash()] = new(big.Int).SetUint64(number + 1)
+		rawdb.WriteSyncCheckpoint(tester.stateDb, &rawdb.SyncCheckpoint{Number: number, Hash: hash})
+		return tester
+	}
+	tester := newCheckpointTester(checkpoint.Hash())
+	defer tester.terminate()
+
+	tester.newPeer("peer", istanbul.Celo67, chain)
+	if err := tester.sync("peer", nil, FastSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	if head := tester.CurrentBlock(); head.Hash() != chain.headBlock().Hash() {
+		t.Fatalf("head block mismatch: have %d, want %d", head.NumberU64(), chain.headBlock().NumberU64())
+	}
+	// The genesis, the checkpoint and the headers after it
+	if hs := len(tester.ownHeaders); hs != chain.len()-int(number)+1 {
+		t.Fatalf("synchronised headers mismatch: have %v, want %v", hs, chain.len()-int(number)+1)
+	}
+	for n := uint64(1); n <= number; n++ {
+		if tester.HasBlock(chain.chain[n], n) {
+			t.Fatalf("block %d before the checkpoint synchronised", n)
+		}
+	}
+
+	// Peers of another chain are rejected
+	mismatch := newCheckpointTester(common.Hash{1})
+	defer mismatch.terminate()
+
+	mismatch.newPeer("peer", istanbul.Celo67, chain)
+	if err := mismatch.sync("peer", nil, FastSync); err != errInvalidAncestor {
+		t.Fatalf("sync failure mismatch: have %v, want %v", err, errInvalidAncestor)
+	}
+}
+
 // Tests that if a large batch of blocks are being downloaded, it is throttled
 // until the cached blocks are retrieved.
 func TestThrottling67Full(t *testing.T) { testThrottling(t, istanbul.Celo67, FullSync) }
@@ -414,7 +414,7 @@ func (ps *peerSet) HeaderIdlePeers() ([]*peerConnection, int) {
 	throughput := func(p *peerConnection) int {
 		return p.rates.Capacity(eth.BlockHeadersMsg, time.Second)
 	}
-	return ps.idlePeers(istanbul.Celo67, istanbul.Celo67, idle, throughput)
+	return ps.idlePeers(istanbul.Celo67, istanbul.Celo68, idle, throughput)
 }
 
 // BodyIdlePeers retrieves a flat list of all the currently body-idle peers within
@@ -426,7 +426,7 @@ func (ps *peerSet) BodyIdlePeers() ([]*peerConnection, int) {
 	throughput := func(p *peerConnection) int {
 		return p.rates.Capacity(eth.BlockBodiesMsg, time.Second)
 	}
-	return ps.idlePeers(istanbul.Celo67, istanbul.Celo67, idle, throughput)
+	return ps.idlePeers(istanbul.Celo67, istanbul.Celo68, idle, throughput)
 }
 
 // ReceiptIdlePeers retrieves a flat list of all the currently receipt-idle peers
@@ -438,7 +438,7 @@ func (ps *peerSet) ReceiptIdlePeers() ([]*peerConnection, int) {
 	throughput := func(p *peerConnection) int {
 		return p.rates.Capacity(eth.ReceiptsMsg, time.Second)
 	}
-	return ps.idlePeers(istanbul.Celo67, istanbul.Celo67, idle, throughput)
+	return ps.idlePeers(istanbul.Celo67, istanbul.Celo68, idle, throughput)
 }
 
 // NodeDataIdlePeers retrieves a flat list of all the currently node-data-idle
@@ -450,7 +450,7 @@ func (ps *peerSet) NodeDataIdlePeers() ([]*peerConnection, int) {
 	throughput := func(p *peerConnection) int {
 		return p.rates.Capacity(eth.NodeDataMsg, time.Second)
 	}
-	return ps.idlePeers(istanbul.Celo67, istanbul.Celo67, idle, throughput)
+	return ps.idlePeers(istanbul.Celo67, istanbul.Celo68, idle, throughput)
 }
 
 // idlePeers retrieves a flat list of all currently idle peers satisfying the
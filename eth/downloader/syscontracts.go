@@ -0,0 +1,90 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/eth/protocols/snap"
+	"github.com/celo-org/celo-blockchain/log"
+)
+
+// verifySysContractState is the snap.StateVerifier of the downloader, checking
+// that the state synced to the pivot holds the whole state of the registry and
+// of the core contracts. The system calls of every block need it, and fail on
+// the first missing trie node long after a heal missed it.
+func (d *Downloader) verifySysContractState(factory vm.EVMRunnerFactory, root common.Hash) ([]snap.MissingNode, error) {
+	d.pivotLock.RLock()
+	pivot := d.pivotHeader
+	d.pivotLock.RUnlock()
+
+	if pivot == nil || pivot.Root != root {
+		return nil, nil
+	}
+	statedb, err := state.New(root, state.NewDatabase(d.stateDB), nil)
+	if err != nil {
+		return nil, err
+	}
+	// The registry is verified first, the core contracts being resolved through it
+	deployed, missing, err := d.missingContractState(statedb, root, config.RegistrySmartContractAddress)
+	if err != nil || len(missing) > 0 || !deployed {
+		return missing, err
+	}
+	vmRunner := factory.NewEVMRunner(pivot, statedb)
+	for _, contract := range config.CoreContracts {
+		address, err := contracts.GetRegisteredAddress(vmRunner, contract.Id)
+		if err == contracts.ErrSmartContractNotDeployed || err == contracts.ErrRegistryContractNotDeployed {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %v", contract.Name, err)
+		}
+		_, contractMissing, err := d.missingContractState(statedb, root, address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify %s: %v", contract.Name, err)
+		}
+		if len(contractMissing) > 0 {
+			log.Warn("Core contract state incomplete", "name", contract.Name, "address", address, "missing", len(contractMissing))
+		}
+		missing = append(missing, contractMissing...)
+	}
+	return missing, nil
+}
+
+// missingContractState returns whether the core contract proxy with the given
+// address exists, and the parts of its state and of the state of its
+// implementation missing from the database.
+func (d *Downloader) missingContractState(statedb *state.StateDB, root common.Hash, address common.Address) (bool, []snap.MissingNode, error) {
+	account, missing, err := snap.MissingAccountState(d.stateDB, root, crypto.Keccak256Hash(address.Bytes()))
+	if err != nil || len(missing) > 0 {
+		return true, missing, err
+	}
+	if account == nil {
+		return false, nil, nil
+	}
+	impl := statedb.GetState(address, config.ProxyImplementationSlot)
+	if impl == (common.Hash{}) {
+		return true, nil, nil
+	}
+	_, missing, err = snap.MissingAccountState(d.stateDB, root, crypto.Keccak256Hash(common.BytesToAddress(impl.Bytes()).Bytes()))
+	return true, missing, err
+}
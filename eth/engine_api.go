@@ -0,0 +1,69 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/miner"
+	"github.com/celo-org/celo-blockchain/rlp"
+)
+
+// PrivateEngineAPI lets external validators propose the blocks built by this
+// node, over an analogue of the engine API: the validator sets the head and the
+// attributes of its next block with engine_forkchoiceUpdated, and retrieves the
+// block with engine_getPayload, leaving it to run the consensus only.
+type PrivateEngineAPI struct {
+	e *Ethereum
+}
+
+// NewPrivateEngineAPI creates a new engine API.
+func NewPrivateEngineAPI(e *Ethereum) *PrivateEngineAPI {
+	return &PrivateEngineAPI{e: e}
+}
+
+// ForkchoiceUpdated starts building a block on top of the head with the given
+// attributes, if any, returning the id of its payload. The head must be the
+// current one, the status is SYNCING while it isn't known. Istanbul blocks are
+// final, the head isn't changed.
+func (api *PrivateEngineAPI) ForkchoiceUpdated(state miner.ForkchoiceState, attrs *miner.PayloadAttributes) (*miner.ForkchoiceResponse, error) {
+	if api.e.BlockChain().GetHeaderByHash(state.HeadBlockHash) == nil {
+		return &miner.ForkchoiceResponse{PayloadStatus: miner.PayloadStatus{Status: miner.PayloadStatusSyncing}}, nil
+	}
+	res := &miner.ForkchoiceResponse{PayloadStatus: miner.PayloadStatus{Status: miner.PayloadStatusValid, LatestValidHash: &state.HeadBlockHash}}
+	if attrs == nil {
+		return res, nil
+	}
+	id, err := api.e.Miner().StartPayload(state.HeadBlockHash, attrs)
+	if err != nil {
+		return nil, err
+	}
+	res.PayloadID = &id
+	return res, nil
+}
+
+// GetPayload returns the RLP encoded block of a payload started with
+// ForkchoiceUpdated, waiting for it to be built. The block is to be sealed by
+// the validator, that adds the parent seal.
+func (api *PrivateEngineAPI) GetPayload(ctx context.Context, id miner.PayloadID) (hexutil.Bytes, error) {
+	block, err := api.e.Miner().GetPayload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(block)
+}
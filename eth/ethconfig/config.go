@@ -52,12 +52,17 @@ var Defaults = Config{
 	TrieTimeout:             60 * time.Minute,
 	SnapshotCache:           102,
 	GatewayFee:              big.NewInt(0),
+	ProxiedKnownCache:       8,
+	NTPServer:               "pool.ntp.org",
+	HeadLagTimeThreshold:    30 * time.Second,
+	HeadLagBlockThreshold:   6,
 
 	TxPool:                core.DefaultTxPoolConfig,
 	RPCGasInflationRate:   1.3,
 	RPCGasPriceMultiplier: big.NewInt(200),
 	RPCGasCap:             25000000,
 	RPCTxFeeCap:           500, // 500 celo
+	RPCStateRangeRate:     10000,
 
 	Istanbul: *istanbul.DefaultConfig,
 }
@@ -74,6 +79,10 @@ type Config struct {
 	NetworkId uint64 // Network ID to use for selecting peers to connect to
 	SyncMode  downloader.SyncMode
 
+	// SyncCheckpoint is the epoch snapshots file whose last epoch block a new
+	// node fast syncs from, instead of the genesis.
+	SyncCheckpoint string `toml:",omitempty"`
+
 	// This can be set to list of enrtree:// URLs which will be queried for
 	// for nodes to connect to.
 	EthDiscoveryURLs  []string
@@ -151,11 +160,20 @@ type Config struct {
 	// send-transction variants. The unit is ether.
 	RPCTxFeeCap float64
 
+	// RPCStateRangeRate is the number of accounts and storage slots the state
+	// range iterators of the debug API serve per second (0 = no limit).
+	RPCStateRangeRate uint64
+
 	// RPCEthCompatibility is used to determine whether the 'gaslimit' end
 	// 'baseFeePerGas' fields should be added to blocks returned by the RPC
 	// API. Where true indicates the fields should be added.
 	RPCEthCompatibility bool
 
+	// RPCReceiptFeeFields is used to determine whether the fee currency, the
+	// base fee in the fee currency and the fee converted to CELO should be
+	// added to the transaction receipts returned by the RPC API.
+	RPCReceiptFeeFields bool
+
 	// Checkpoint is a hardcoded checkpoint which can be nil.
 	Checkpoint *params.TrustedCheckpoint `toml:",omitempty"`
 
@@ -171,6 +189,70 @@ type Config struct {
 	// The minimum required peers in order for syncing to be initiated, if left
 	// at 0 then the default will be used.
 	MinSyncPeers int `toml:",omitempty"`
+
+	// ProxiedKnownCache is the memory budget in megabytes of the transaction and
+	// block hashes set shared across the proxies and peers of a proxied validator,
+	// used to process every broadcast only once.
+	ProxiedKnownCache uint64 `toml:",omitempty"`
+
+	// TxStemRelay sends the locally submitted transactions to a single random
+	// peer before broadcasting them, so that they can't be linked to the node.
+	TxStemRelay bool `toml:",omitempty"`
+
+	// StrictConfigCheck makes the node refuse to start if the startup chain
+	// config consistency checks fail, instead of only warning.
+	StrictConfigCheck bool `toml:",omitempty"`
+
+	// ConfigCheckReport is the file the startup chain config consistency
+	// report is written to as JSON, if set.
+	ConfigCheckReport string `toml:",omitempty"`
+
+	// NTPServer is the server the skew of the local clock is checked against,
+	// besides the timestamps of the blocks. None if empty.
+	NTPServer string `toml:",omitempty"`
+
+	// GasUsageIndex aggregates the gas used per destination contract in every
+	// epoch, served by celo_gasUsageByContract.
+	GasUsageIndex bool `toml:",omitempty"`
+
+	// ExchangeRateIndex records the exchange rates of the whitelisted currencies
+	// at every block, served by celo_exchangeRateAt.
+	ExchangeRateIndex bool `toml:",omitempty"`
+
+	// AccountTxIndex records the transactions of every account, sent and
+	// received, served by eth_getTransactionsByAddress.
+	AccountTxIndex bool `toml:",omitempty"`
+
+	// InternalTransferIndex records the CELO transfers made by the blocks imported,
+	// served by celo_getInternalTransfers.
+	InternalTransferIndex bool `toml:",omitempty"`
+
+	// SyntheticFeeLogs adds logs of the gas fee debits and credits to the receipts
+	// of the blocks processed, out of their consensus encoding and blooms.
+	SyntheticFeeLogs bool `toml:",omitempty"`
+
+	// SystemCallFastPath serves the read only system contract calls from their
+	// last outputs while the code and storage they read are unchanged.
+	SystemCallFastPath bool `toml:",omitempty"`
+
+	// SafeMode checks the integrity of the chain database at startup, and if it's
+	// corrupted starts without the Ethereum service, serving the intact history
+	// read-only instead of failing.
+	SafeMode bool `toml:",omitempty"`
+
+	// TxPoolStreamToken is the secret the subscribers of the pending transactions
+	// stream of the pool have to present. The stream is disabled if empty.
+	TxPoolStreamToken string `toml:",omitempty"`
+
+	// HeadLagTimeThreshold and HeadLagBlockThreshold are the lags of the chain
+	// head behind the local clock and behind the best peer head raising the head
+	// lag alarm while the node is an elected validator. The alarm is posted to
+	// HeadLagWebhook if set, and if HeadLagProxyFailover is set the lagging proxies
+	// of the validator are removed when it lasts.
+	HeadLagTimeThreshold  time.Duration
+	HeadLagBlockThreshold uint64
+	HeadLagWebhook        string `toml:",omitempty"`
+	HeadLagProxyFailover  bool   `toml:",omitempty"`
 }
 
 // CreateConsensusEngine creates the required type of consensus engine instance for an Ethereum service
@@ -20,6 +20,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
+		SyncCheckpoint          string `toml:",omitempty"`
 		EthDiscoveryURLs        []string
 		SnapDiscoveryURLs       []string
 		NoPruning               bool
@@ -60,16 +61,36 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		RPCGasPriceMultiplier   *big.Int
 		RPCGasCap               uint64
 		RPCTxFeeCap             float64
+		RPCStateRangeRate       uint64
 		RPCEthCompatibility     bool
+		RPCReceiptFeeFields     bool
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
 		OverrideHFork           *big.Int                       `toml:",omitempty"`
 		MinSyncPeers            int                            `toml:",omitempty"`
+		StrictConfigCheck       bool                           `toml:",omitempty"`
+		ConfigCheckReport       string                         `toml:",omitempty"`
+		NTPServer               string                         `toml:",omitempty"`
+		GasUsageIndex           bool                           `toml:",omitempty"`
+		ExchangeRateIndex       bool                           `toml:",omitempty"`
+		AccountTxIndex          bool                           `toml:",omitempty"`
+		InternalTransferIndex   bool                           `toml:",omitempty"`
+		SyntheticFeeLogs        bool                           `toml:",omitempty"`
+		SystemCallFastPath      bool                           `toml:",omitempty"`
+		SafeMode                bool                           `toml:",omitempty"`
+		TxPoolStreamToken       string                         `toml:",omitempty"`
+		HeadLagTimeThreshold    time.Duration
+		HeadLagBlockThreshold   uint64
+		HeadLagWebhook          string `toml:",omitempty"`
+		HeadLagProxyFailover    bool   `toml:",omitempty"`
+		ProxiedKnownCache       uint64 `toml:",omitempty"`
+		TxStemRelay             bool   `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
+	enc.SyncCheckpoint = c.SyncCheckpoint
 	enc.EthDiscoveryURLs = c.EthDiscoveryURLs
 	enc.SnapDiscoveryURLs = c.SnapDiscoveryURLs
 	enc.NoPruning = c.NoPruning
@@ -110,11 +131,30 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.RPCGasPriceMultiplier = c.RPCGasPriceMultiplier
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
+	enc.RPCStateRangeRate = c.RPCStateRangeRate
 	enc.RPCEthCompatibility = c.RPCEthCompatibility
+	enc.RPCReceiptFeeFields = c.RPCReceiptFeeFields
 	enc.Checkpoint = c.Checkpoint
 	enc.CheckpointOracle = c.CheckpointOracle
 	enc.OverrideHFork = c.OverrideHFork
 	enc.MinSyncPeers = c.MinSyncPeers
+	enc.StrictConfigCheck = c.StrictConfigCheck
+	enc.ConfigCheckReport = c.ConfigCheckReport
+	enc.NTPServer = c.NTPServer
+	enc.GasUsageIndex = c.GasUsageIndex
+	enc.ExchangeRateIndex = c.ExchangeRateIndex
+	enc.AccountTxIndex = c.AccountTxIndex
+	enc.InternalTransferIndex = c.InternalTransferIndex
+	enc.SyntheticFeeLogs = c.SyntheticFeeLogs
+	enc.SystemCallFastPath = c.SystemCallFastPath
+	enc.SafeMode = c.SafeMode
+	enc.TxPoolStreamToken = c.TxPoolStreamToken
+	enc.HeadLagTimeThreshold = c.HeadLagTimeThreshold
+	enc.HeadLagBlockThreshold = c.HeadLagBlockThreshold
+	enc.HeadLagWebhook = c.HeadLagWebhook
+	enc.HeadLagProxyFailover = c.HeadLagProxyFailover
+	enc.ProxiedKnownCache = c.ProxiedKnownCache
+	enc.TxStemRelay = c.TxStemRelay
 	return &enc, nil
 }
 
@@ -124,6 +164,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
+		SyncCheckpoint          *string `toml:",omitempty"`
 		EthDiscoveryURLs        []string
 		SnapDiscoveryURLs       []string
 		NoPruning               *bool
@@ -164,11 +205,30 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		RPCGasPriceMultiplier   *big.Int
 		RPCGasCap               *uint64
 		RPCTxFeeCap             *float64
+		RPCStateRangeRate       *uint64
 		RPCEthCompatibility     *bool
+		RPCReceiptFeeFields     *bool
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
 		OverrideHFork           *big.Int                       `toml:",omitempty"`
 		MinSyncPeers            *int                           `toml:",omitempty"`
+		StrictConfigCheck       *bool                          `toml:",omitempty"`
+		ConfigCheckReport       *string                        `toml:",omitempty"`
+		NTPServer               *string                        `toml:",omitempty"`
+		GasUsageIndex           *bool                          `toml:",omitempty"`
+		ExchangeRateIndex       *bool                          `toml:",omitempty"`
+		AccountTxIndex          *bool                          `toml:",omitempty"`
+		InternalTransferIndex   *bool                          `toml:",omitempty"`
+		SyntheticFeeLogs        *bool                          `toml:",omitempty"`
+		SystemCallFastPath      *bool                          `toml:",omitempty"`
+		SafeMode                *bool                          `toml:",omitempty"`
+		TxPoolStreamToken       *string                        `toml:",omitempty"`
+		HeadLagTimeThreshold    *time.Duration
+		HeadLagBlockThreshold   *uint64
+		HeadLagWebhook          *string `toml:",omitempty"`
+		HeadLagProxyFailover    *bool   `toml:",omitempty"`
+		ProxiedKnownCache       *uint64 `toml:",omitempty"`
+		TxStemRelay             *bool   `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -183,6 +243,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.SyncMode != nil {
 		c.SyncMode = *dec.SyncMode
 	}
+	if dec.SyncCheckpoint != nil {
+		c.SyncCheckpoint = *dec.SyncCheckpoint
+	}
 	if dec.EthDiscoveryURLs != nil {
 		c.EthDiscoveryURLs = dec.EthDiscoveryURLs
 	}
@@ -303,9 +366,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCTxFeeCap != nil {
 		c.RPCTxFeeCap = *dec.RPCTxFeeCap
 	}
+	if dec.RPCStateRangeRate != nil {
+		c.RPCStateRangeRate = *dec.RPCStateRangeRate
+	}
 	if dec.RPCEthCompatibility != nil {
 		c.RPCEthCompatibility = *dec.RPCEthCompatibility
 	}
+	if dec.RPCReceiptFeeFields != nil {
+		c.RPCReceiptFeeFields = *dec.RPCReceiptFeeFields
+	}
 	if dec.Checkpoint != nil {
 		c.Checkpoint = dec.Checkpoint
 	}
@@ -318,5 +387,56 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.MinSyncPeers != nil {
 		c.MinSyncPeers = *dec.MinSyncPeers
 	}
+	if dec.StrictConfigCheck != nil {
+		c.StrictConfigCheck = *dec.StrictConfigCheck
+	}
+	if dec.ConfigCheckReport != nil {
+		c.ConfigCheckReport = *dec.ConfigCheckReport
+	}
+	if dec.NTPServer != nil {
+		c.NTPServer = *dec.NTPServer
+	}
+	if dec.GasUsageIndex != nil {
+		c.GasUsageIndex = *dec.GasUsageIndex
+	}
+	if dec.ExchangeRateIndex != nil {
+		c.ExchangeRateIndex = *dec.ExchangeRateIndex
+	}
+	if dec.AccountTxIndex != nil {
+		c.AccountTxIndex = *dec.AccountTxIndex
+	}
+	if dec.InternalTransferIndex != nil {
+		c.InternalTransferIndex = *dec.InternalTransferIndex
+	}
+	if dec.SyntheticFeeLogs != nil {
+		c.SyntheticFeeLogs = *dec.SyntheticFeeLogs
+	}
+	if dec.SystemCallFastPath != nil {
+		c.SystemCallFastPath = *dec.SystemCallFastPath
+	}
+	if dec.SafeMode != nil {
+		c.SafeMode = *dec.SafeMode
+	}
+	if dec.TxPoolStreamToken != nil {
+		c.TxPoolStreamToken = *dec.TxPoolStreamToken
+	}
+	if dec.HeadLagTimeThreshold != nil {
+		c.HeadLagTimeThreshold = *dec.HeadLagTimeThreshold
+	}
+	if dec.HeadLagBlockThreshold != nil {
+		c.HeadLagBlockThreshold = *dec.HeadLagBlockThreshold
+	}
+	if dec.HeadLagWebhook != nil {
+		c.HeadLagWebhook = *dec.HeadLagWebhook
+	}
+	if dec.HeadLagProxyFailover != nil {
+		c.HeadLagProxyFailover = *dec.HeadLagProxyFailover
+	}
+	if dec.ProxiedKnownCache != nil {
+		c.ProxiedKnownCache = *dec.ProxiedKnownCache
+	}
+	if dec.TxStemRelay != nil {
+		c.TxStemRelay = *dec.TxStemRelay
+	}
 	return nil
 }
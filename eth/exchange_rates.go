@@ -0,0 +1,81 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+var errExchangeRateIndexDisabled = errors.New("exchange rate index disabled, see --exchangerateindex")
+
+// IndexedExchangeRate is the exchange rate of a currency to CELO at a block, as
+// the numerator and denominator of the median rate of the oracles.
+type IndexedExchangeRate struct {
+	Block       hexutil.Uint64 `json:"block"`
+	Numerator   *hexutil.Big   `json:"numerator"`
+	Denominator *hexutil.Big   `json:"denominator"`
+}
+
+// PublicExchangeRateAPI serves the exchange rate index.
+type PublicExchangeRateAPI struct {
+	eth *Ethereum
+}
+
+// NewPublicExchangeRateAPI creates a new exchange rate index API.
+func NewPublicExchangeRateAPI(eth *Ethereum) *PublicExchangeRateAPI {
+	return &PublicExchangeRateAPI{eth: eth}
+}
+
+// ExchangeRateAt returns the exchange rate of a whitelisted currency in the state
+// of a block, recorded by the exchange rate index. Only the blocks whose state
+// was available when they were indexed have their exchange rates recorded.
+func (api *PublicExchangeRateAPI) ExchangeRateAt(ctx context.Context, currency common.Address, blockNumber rpc.BlockNumber) (*IndexedExchangeRate, error) {
+	indexer := api.eth.exchangeRateIndexer
+	if indexer == nil {
+		return nil, errExchangeRateIndexDisabled
+	}
+	var (
+		head           = api.eth.blockchain.CurrentHeader().Number.Uint64()
+		number         = resolveGasUsageBlock(blockNumber, head)
+		sections, _, _ = indexer.Sections()
+	)
+	if number >= sections*core.ExchangeRateSectionSize {
+		return nil, fmt.Errorf("block %d not indexed yet", number)
+	}
+	rates, ok := rawdb.ReadExchangeRates(api.eth.chainDb, number)
+	if !ok {
+		return nil, fmt.Errorf("no exchange rates recorded at block %d", number)
+	}
+	for _, rate := range rates {
+		if rate.Currency == currency {
+			return &IndexedExchangeRate{
+				Block:       hexutil.Uint64(number),
+				Numerator:   (*hexutil.Big)(rate.Numerator),
+				Denominator: (*hexutil.Big)(rate.Denominator),
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("currency %s not whitelisted at block %d", currency.Hex(), number)
+}
@@ -28,6 +28,8 @@ import (
 	ethereum "github.com/celo-org/celo-blockchain"
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
 	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/ethdb"
 	"github.com/celo-org/celo-blockchain/internal/ethapi"
@@ -141,13 +143,18 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 }
 
 // NewPendingTransactions creates a subscription that is triggered each time a
-// transaction enters the transaction pool. If fullTx is true the full tx is
-// sent to the client, otherwise the hash is sent.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
+// transaction enters the transaction pool. The criteria are either the fullTx
+// boolean, or an object with it and filters on the transactions notified (see
+// PendingTxsCriteria). If fullTx is true the full tx is sent to the client,
+// otherwise the hash is sent.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, crit *PendingTxsCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
+	if crit == nil {
+		crit = new(PendingTxsCriteria)
+	}
 
 	rpcSub := notifier.CreateSubscription()
 
@@ -155,6 +162,7 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *
 		txs := make(chan []*types.Transaction, 128)
 		pendingTxSub := api.events.SubscribePendingTxs(txs)
 		chainConfig := api.backend.ChainConfig()
+		matcher := newPendingTxsMatcher(*crit, api.backend)
 
 		for {
 			select {
@@ -163,7 +171,10 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *
 				// TODO(rjl493456442) Send a batch of tx hashes in one notification
 				latest := api.backend.CurrentHeader()
 				for _, tx := range txs {
-					if fullTx != nil && *fullTx {
+					if !matcher.match(tx, latest) {
+						continue
+					}
+					if crit.FullTx {
 						rpcTx := ethapi.NewRPCPendingTransaction(tx, latest, chainConfig)
 						notifier.Notify(rpcSub.ID, rpcTx)
 					} else {
@@ -183,6 +194,51 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *
 	return rpcSub, nil
 }
 
+// TxStatus creates a subscription that is triggered each time the transaction with
+// the given hash reaches a stage of its lifecycle: queued or pending in the pool,
+// proposed in a block by the local miner, sealed by Istanbul with the local
+// validator and finalized in the chain. A transaction already in the chain is
+// notified finalized right away.
+func (api *PublicFilterAPI) TxStatus(ctx context.Context, hash common.Hash) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		statuses := make(chan *TxStatus, 16)
+		statusSub := api.events.SubscribeTxStatus(hash, statuses)
+
+		// Subscribed first, so that the stages reached meanwhile aren't missed
+		var last *TxStatus
+		if number := rawdb.ReadTxLookupEntry(api.chainDb, hash); number != nil {
+			blockHash, blockNumber := rawdb.ReadCanonicalHash(api.chainDb, *number), hexutil.Uint64(*number)
+			last = &TxStatus{Hash: hash, Status: core.TxStageFinalized, BlockHash: &blockHash, BlockNumber: &blockNumber}
+			notifier.Notify(rpcSub.ID, last)
+		}
+		for {
+			select {
+			case status := <-statuses:
+				if last != nil && last.same(status) {
+					continue
+				}
+				last = status
+				notifier.Notify(rpcSub.ID, status)
+			case <-rpcSub.Err():
+				statusSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				statusSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 //
@@ -296,6 +352,92 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	return rpcSub, nil
 }
 
+// FeeCurrencies is the fee currency whitelist at the end of a block.
+type FeeCurrencies struct {
+	BlockNumber   hexutil.Uint64   `json:"blockNumber"`
+	BlockHash     common.Hash      `json:"blockHash"`
+	FeeCurrencies []common.Address `json:"feeCurrencies"`
+}
+
+// FeeCurrencies creates a subscription that fires with the fee currency whitelist
+// every time a block changes it, found by filtering the logs of the whitelist
+// contract registered when subscribing.
+func (api *PublicFilterAPI) FeeCurrencies(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	contract, whitelist, err := api.backend.FeeCurrencyWhitelistForHeader(ctx, api.backend.CurrentHeader())
+	if err != nil {
+		return nil, err
+	}
+	var (
+		rpcSub      = notifier.CreateSubscription()
+		matchedLogs = make(chan []*types.Log)
+	)
+	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery{Addresses: []common.Address{contract}}, matchedLogs)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			select {
+			case logs := <-matchedLogs:
+				var blockHash common.Hash
+				for _, log := range logs {
+					if !log.Removed {
+						blockHash = log.BlockHash
+					}
+				}
+				if blockHash == (common.Hash{}) {
+					continue
+				}
+				header, err := api.backend.HeaderByHash(ctx, blockHash)
+				if header == nil || err != nil {
+					log.Debug("Failed to retrieve the block changing the fee currencies", "hash", blockHash, "err", err)
+					continue
+				}
+				_, current, err := api.backend.FeeCurrencyWhitelistForHeader(ctx, header)
+				if err != nil {
+					log.Debug("Failed to retrieve the fee currencies", "number", header.Number, "err", err)
+					continue
+				}
+				if sameAddresses(current, whitelist) {
+					continue
+				}
+				whitelist = current
+				notifier.Notify(rpcSub.ID, &FeeCurrencies{
+					BlockNumber:   hexutil.Uint64(header.Number.Uint64()),
+					BlockHash:     blockHash,
+					FeeCurrencies: current,
+				})
+			case <-rpcSub.Err(): // client send an unsubscribe request
+				logsSub.Unsubscribe()
+				return
+			case <-notifier.Closed(): // connection dropped
+				logsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// sameAddresses reports whether both lists hold the same addresses in the same order.
+func sameAddresses(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // FilterCriteria represents a request to create a new filter.
 // Same as ethereum.FilterQuery but with UnmarshalJSON() method.
 type FilterCriteria ethereum.FilterQuery
@@ -0,0 +1,202 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+var (
+	// validatorPaymentTopic is the topic of the Validators event logged when the
+	// epoch reward of a validator and its group is paid, in the stable token.
+	validatorPaymentTopic = crypto.Keccak256Hash([]byte("ValidatorEpochPaymentDistributed(address,uint256,address,uint256)"))
+
+	// voterRewardsTopic is the topic of the Election event logged when the epoch
+	// rewards of the voters of a group are distributed, in CELO.
+	voterRewardsTopic = crypto.Keccak256Hash([]byte("EpochRewardsDistributedToVoters(address,uint256)"))
+
+	errNotIstanbul = errors.New("chain is not running istanbul")
+)
+
+// EpochTransition is the validator set change and the rewards distributed at
+// the last block of an epoch.
+type EpochTransition struct {
+	Epoch       hexutil.Uint64 `json:"epoch"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+
+	// Validators is the validator set of the next epoch.
+	Validators []common.Address `json:"validators"`
+	// AddedValidators and RemovedValidators are the diff from the validator set of
+	// the ending epoch, as recorded in the istanbul extra of the block.
+	AddedValidators   []common.Address `json:"addedValidators"`
+	RemovedValidators []common.Address `json:"removedValidators"`
+
+	Rewards *EpochRewards `json:"rewards"`
+}
+
+// EpochRewards summarizes the rewards distributed at the end of an epoch, as
+// logged by the core contracts.
+type EpochRewards struct {
+	PaidValidators hexutil.Uint64 `json:"paidValidators"`
+	// ValidatorPayments and GroupPayments are the totals paid to the validators
+	// and to their groups as commission, in the stable token.
+	ValidatorPayments *hexutil.Big `json:"validatorPayments"`
+	GroupPayments     *hexutil.Big `json:"groupPayments"`
+	// VoterRewards are the rewards distributed to the voters of every group, in CELO.
+	VoterRewards      map[common.Address]*hexutil.Big `json:"voterRewards"`
+	TotalVoterRewards *hexutil.Big                    `json:"totalVoterRewards"`
+}
+
+// Epoch creates a subscription that fires at the last block of every epoch with
+// the validator set of the next epoch, the diff from the set of the ending one,
+// and a summary of the rewards distributed.
+func (api *PublicFilterAPI) Epoch(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	config := api.backend.ChainConfig()
+	if config.Istanbul == nil || config.Istanbul.Epoch == 0 {
+		return nil, errNotIstanbul
+	}
+	epochSize := config.Istanbul.Epoch
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		headers := make(chan *types.Header)
+		headersSub := api.events.SubscribeNewHeads(headers)
+
+		for {
+			select {
+			case h := <-headers:
+				if !istanbul.IsLastBlockOfEpoch(h.Number.Uint64(), epochSize) {
+					continue
+				}
+				transition, err := api.epochTransition(ctx, h, epochSize)
+				if err != nil {
+					log.Debug("Failed to retrieve the epoch transition", "number", h.Number, "hash", h.Hash(), "err", err)
+					continue
+				}
+				notifier.Notify(rpcSub.ID, transition)
+			case <-rpcSub.Err(): // client send an unsubscribe request
+				headersSub.Unsubscribe()
+				return
+			case <-notifier.Closed(): // connection dropped
+				headersSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// epochTransition returns the epoch transition of the last block of an epoch.
+func (api *PublicFilterAPI) epochTransition(ctx context.Context, header *types.Header, epochSize uint64) (*EpochTransition, error) {
+	extra, err := header.IstanbulExtra()
+	if err != nil {
+		return nil, err
+	}
+	parent, err := api.backend.HeaderByHash(ctx, header.ParentHash)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, errors.New("parent header not found")
+	}
+	previous, err := api.backend.ValidatorsForHeader(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	validators, err := api.backend.ValidatorsForHeader(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := api.backend.GetReceipts(ctx, header.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	transition := &EpochTransition{
+		Epoch:             hexutil.Uint64(istanbul.GetEpochNumber(header.Number.Uint64(), epochSize)),
+		BlockNumber:       hexutil.Uint64(header.Number.Uint64()),
+		BlockHash:         header.Hash(),
+		Validators:        validators,
+		AddedValidators:   extra.AddedValidators,
+		RemovedValidators: []common.Address{},
+		Rewards:           epochRewards(header.Hash(), receipts),
+	}
+	if transition.AddedValidators == nil {
+		transition.AddedValidators = []common.Address{}
+	}
+	if extra.RemovedValidators != nil {
+		for i, validator := range previous {
+			if extra.RemovedValidators.Bit(i) == 1 {
+				transition.RemovedValidators = append(transition.RemovedValidators, validator)
+			}
+		}
+	}
+	return transition, nil
+}
+
+// epochRewards sums the rewards logged by the block receipt of the epoch block,
+// ignoring the logs of its transactions that could mimic them.
+func epochRewards(blockHash common.Hash, receipts types.Receipts) *EpochRewards {
+	var (
+		validatorPayments = new(big.Int)
+		groupPayments     = new(big.Int)
+		totalVoterRewards = new(big.Int)
+	)
+	rewards := &EpochRewards{VoterRewards: make(map[common.Address]*hexutil.Big)}
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if log.TxHash != blockHash || len(log.Topics) < 2 {
+				continue
+			}
+			switch {
+			case log.Topics[0] == validatorPaymentTopic && len(log.Data) >= 64:
+				rewards.PaidValidators++
+				validatorPayments.Add(validatorPayments, new(big.Int).SetBytes(log.Data[:32]))
+				groupPayments.Add(groupPayments, new(big.Int).SetBytes(log.Data[32:64]))
+			case log.Topics[0] == voterRewardsTopic && len(log.Data) >= 32:
+				group := common.BytesToAddress(log.Topics[1].Bytes())
+				if rewards.VoterRewards[group] == nil {
+					rewards.VoterRewards[group] = new(hexutil.Big)
+				}
+				value := new(big.Int).SetBytes(log.Data[:32])
+				rewards.VoterRewards[group].ToInt().Add(rewards.VoterRewards[group].ToInt(), value)
+				totalVoterRewards.Add(totalVoterRewards, value)
+			}
+		}
+	}
+	rewards.ValidatorPayments = (*hexutil.Big)(validatorPayments)
+	rewards.GroupPayments = (*hexutil.Big)(groupPayments)
+	rewards.TotalVoterRewards = (*hexutil.Big)(totalVoterRewards)
+	return rewards
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/rlp"
+)
+
+// epochBackend serves the validators following the headers, and the receipts
+// of the blocks, from memory.
+type epochBackend struct {
+	testBackend
+	headers    map[common.Hash]*types.Header
+	validators map[common.Hash][]common.Address
+	receipts   map[common.Hash]types.Receipts
+}
+
+func (b *epochBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.headers[hash], nil
+}
+
+func (b *epochBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.receipts[hash], nil
+}
+
+func (b *epochBackend) ValidatorsForHeader(ctx context.Context, header *types.Header) ([]common.Address, error) {
+	return b.validators[header.Hash()], nil
+}
+
+func TestEpochTransition(t *testing.T) {
+	val := func(i byte) common.Address { return common.BytesToAddress([]byte{i}) }
+	word := func(v int64) []byte { return common.LeftPadBytes(big.NewInt(v).Bytes(), 32) }
+
+	parent := &types.Header{Number: big.NewInt(9)}
+	extra, _ := rlp.EncodeToBytes(&types.IstanbulExtra{
+		AddedValidators:   []common.Address{val(4)},
+		RemovedValidators: big.NewInt(0b010),
+	})
+	header := &types.Header{Number: big.NewInt(10), ParentHash: parent.Hash(), Extra: append(make([]byte, types.IstanbulExtraVanity), extra...)}
+	hash := header.Hash()
+
+	group := val(10)
+	paymentLog := func(txHash common.Hash, validatorPayment, groupPayment int64) *types.Log {
+		return &types.Log{
+			TxHash: txHash,
+			Topics: []common.Hash{validatorPaymentTopic, common.BytesToHash(val(1).Bytes()), common.BytesToHash(group.Bytes())},
+			Data:   append(word(validatorPayment), word(groupPayment)...),
+		}
+	}
+	voterLog := &types.Log{TxHash: hash, Topics: []common.Hash{voterRewardsTopic, common.BytesToHash(group.Bytes())}, Data: word(7)}
+	backend := &epochBackend{
+		headers: map[common.Hash]*types.Header{parent.Hash(): parent},
+		validators: map[common.Hash][]common.Address{
+			parent.Hash(): {val(1), val(2), val(3)},
+			hash:          {val(1), val(3), val(4)},
+		},
+		receipts: map[common.Hash]types.Receipts{hash: {
+			// Logs of transactions don't count as rewards
+			{Logs: []*types.Log{paymentLog(common.HexToHash("0x01"), 1000, 1000)}},
+			{Logs: []*types.Log{paymentLog(hash, 90, 10), paymentLog(hash, 45, 5), voterLog, voterLog}},
+		}},
+	}
+	api := &PublicFilterAPI{backend: backend}
+
+	transition, err := api.epochTransition(context.Background(), header, 10)
+	if err != nil {
+		t.Fatalf("failed to retrieve the epoch transition: %v", err)
+	}
+	if transition.Epoch != 1 || transition.BlockHash != hash {
+		t.Errorf("epoch mismatch: have %d/%x, want 1/%x", transition.Epoch, transition.BlockHash, hash)
+	}
+	if len(transition.Validators) != 3 || transition.Validators[2] != val(4) {
+		t.Errorf("validators mismatch: have %v", transition.Validators)
+	}
+	if len(transition.AddedValidators) != 1 || transition.AddedValidators[0] != val(4) {
+		t.Errorf("added validators mismatch: have %v, want %v", transition.AddedValidators, []common.Address{val(4)})
+	}
+	if len(transition.RemovedValidators) != 1 || transition.RemovedValidators[0] != val(2) {
+		t.Errorf("removed validators mismatch: have %v, want %v", transition.RemovedValidators, []common.Address{val(2)})
+	}
+
+	rewards := transition.Rewards
+	if rewards.PaidValidators != 2 || rewards.ValidatorPayments.ToInt().Int64() != 135 || rewards.GroupPayments.ToInt().Int64() != 15 {
+		t.Errorf("validator payments mismatch: have %d validators paid %v and %v", rewards.PaidValidators, rewards.ValidatorPayments, rewards.GroupPayments)
+	}
+	if rewards.TotalVoterRewards.ToInt().Int64() != 14 || len(rewards.VoterRewards) != 1 || rewards.VoterRewards[group].ToInt().Int64() != 14 {
+		t.Errorf("voter rewards mismatch: have %v in total, %v by group", rewards.TotalVoterRewards, rewards.VoterRewards)
+	}
+}
@@ -45,10 +45,22 @@ type Backend interface {
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
+	// SubscribeTxLifecycleEvent subscribes to the stages of the transactions not
+	// notified by the other subscriptions.
+	SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 	RealGasPriceMinimumForHeader(ctx context.Context, currencyAddress *common.Address, header *types.Header) (*big.Int, error)
+	// FeeCurrencyWhitelistForHeader returns the address of the fee currency whitelist
+	// contract and the currencies it lists at the end of the given block.
+	FeeCurrencyWhitelistForHeader(ctx context.Context, header *types.Header) (common.Address, []common.Address, error)
+	// ConversionFunctionsForHeader returns the gas price minimums and the exchange
+	// rates to CELO of the block following the given one, as used by the miner.
+	ConversionFunctionsForHeader(ctx context.Context, header *types.Header) (func(*common.Address) *big.Int, types.ToCELOFn, error)
+	// ValidatorsForHeader returns the validators of the block following the given one,
+	// in the order the removed validators bitmap of the next epoch block refers to.
+	ValidatorsForHeader(ctx context.Context, header *types.Header) ([]common.Address, error)
 }
 
 // Filter can be used to retrieve and filter logs.
@@ -61,6 +73,7 @@ type Filter struct {
 
 	block      common.Hash // Block hash if filtering a single block
 	begin, end int64       // Range interval if filtering multiple blocks
+	feeLogs    bool        // Whether the synthetic fee logs, absent from the blooms, are matched
 
 	matcher *bloombits.Matcher
 }
@@ -115,6 +128,7 @@ func newFilter(backend Backend, addresses []common.Address, topics [][]common.Ha
 		addresses: addresses,
 		topics:    topics,
 		db:        backend.ChainDb(),
+		feeLogs:   includes(addresses, types.FeeLogAddress),
 	}
 }
 
@@ -151,8 +165,9 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		logs []*types.Log
 		err  error
 	)
+	// The synthetic fee logs aren't in the bloom bits, all the blocks are searched
 	size, sections := f.backend.BloomStatus()
-	if indexed := sections * size; indexed > uint64(f.begin) {
+	if indexed := sections * size; indexed > uint64(f.begin) && !f.feeLogs {
 		if indexed > end {
 			logs, err = f.indexedLogs(ctx, end)
 		} else {
@@ -235,7 +250,7 @@ func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, e
 
 // blockLogs returns the logs matching the filter criteria within a single block.
 func (f *Filter) blockLogs(ctx context.Context, header *types.Header) (logs []*types.Log, err error) {
-	if bloomFilter(header.Bloom, f.addresses, f.topics) {
+	if f.feeLogs || bloomFilter(header.Bloom, f.addresses, f.topics) {
 		found, err := f.checkMatches(ctx, header)
 		if err != nil {
 			return logs, err
@@ -286,7 +301,8 @@ func includes(addresses []common.Address, a common.Address) bool {
 	return false
 }
 
-// filterLogs creates a slice of logs matching the given criteria.
+// filterLogs creates a slice of logs matching the given criteria. The synthetic
+// logs only match the criteria naming their address.
 func filterLogs(logs []*types.Log, fromBlock, toBlock *big.Int, addresses []common.Address, topics [][]common.Hash) []*types.Log {
 	var ret []*types.Log
 Logs:
@@ -301,6 +317,9 @@ Logs:
 		if len(addresses) > 0 && !includes(addresses, log.Address) {
 			continue
 		}
+		if log.Synthetic && len(addresses) == 0 {
+			continue
+		}
 		// If the to filtered topics is greater than the amount of topics in logs, skip.
 		if len(topics) > len(log.Topics) {
 			continue Logs
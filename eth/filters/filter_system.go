@@ -26,6 +26,7 @@ import (
 
 	ethereum "github.com/celo-org/celo-blockchain"
 	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
 	"github.com/celo-org/celo-blockchain/core/types"
@@ -52,6 +53,8 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// TxStatusSubscription queries the lifecycle stages of a transaction
+	TxStatusSubscription
 	// LastIndexSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -66,6 +69,8 @@ const (
 	logsChanSize = 10
 	// chainEvChanSize is the size of channel listening to ChainEvent.
 	chainEvChanSize = 10
+	// stageChanSize is the size of channel listening to TxLifecycleEvent.
+	stageChanSize = 100
 )
 
 type subscription struct {
@@ -76,6 +81,8 @@ type subscription struct {
 	logs      chan []*types.Log
 	txs       chan []*types.Transaction
 	headers   chan *types.Header
+	txHash    common.Hash
+	statuses  chan *TxStatus
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
 }
@@ -93,6 +100,7 @@ type EventSystem struct {
 	rmLogsSub      event.Subscription // Subscription for removed log event
 	pendingLogsSub event.Subscription // Subscription for pending log event
 	chainSub       event.Subscription // Subscription for new chain event
+	stageSub       event.Subscription // Subscription for transaction lifecycle event
 
 	// Channels
 	install       chan *subscription         // install filter for event notification
@@ -102,6 +110,7 @@ type EventSystem struct {
 	pendingLogsCh chan []*types.Log          // Channel to receive new log event
 	rmLogsCh      chan core.RemovedLogsEvent // Channel to receive removed log event
 	chainCh       chan core.ChainEvent       // Channel to receive new chain event
+	stageCh       chan core.TxLifecycleEvent // Channel to receive transaction lifecycle event
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -121,6 +130,7 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 		rmLogsCh:      make(chan core.RemovedLogsEvent, rmLogsChanSize),
 		pendingLogsCh: make(chan []*types.Log, logsChanSize),
 		chainCh:       make(chan core.ChainEvent, chainEvChanSize),
+		stageCh:       make(chan core.TxLifecycleEvent, stageChanSize),
 	}
 
 	// Subscribe events
@@ -129,9 +139,10 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 	m.rmLogsSub = m.backend.SubscribeRemovedLogsEvent(m.rmLogsCh)
 	m.chainSub = m.backend.SubscribeChainEvent(m.chainCh)
 	m.pendingLogsSub = m.backend.SubscribePendingLogsEvent(m.pendingLogsCh)
+	m.stageSub = m.backend.SubscribeTxLifecycleEvent(m.stageCh)
 
 	// Make sure none of the subscriptions are empty
-	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil {
+	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil || m.stageSub == nil {
 		log.Crit("Subscribe for event system failed")
 	}
 
@@ -167,6 +178,7 @@ func (sub *Subscription) Unsubscribe() {
 			case <-sub.f.logs:
 			case <-sub.f.txs:
 			case <-sub.f.headers:
+			case <-sub.f.statuses:
 			}
 		}
 
@@ -306,6 +318,52 @@ func (es *EventSystem) SubscribePendingTxs(txs chan []*types.Transaction) *Subsc
 	return es.subscribe(sub)
 }
 
+// TxStatus is a lifecycle stage of a transaction notified by the txStatus
+// subscription.
+type TxStatus struct {
+	Hash        common.Hash     `json:"hash"`
+	Status      core.TxStage    `json:"status"`
+	BlockHash   *common.Hash    `json:"blockHash,omitempty"`   // Block the transaction is in, unless in the pool
+	BlockNumber *hexutil.Uint64 `json:"blockNumber,omitempty"` // Number of the block, unless in the pool
+}
+
+// newTxStatus creates the status of a transaction that reached a stage in the
+// given block, nil if in the pool.
+func newTxStatus(hash common.Hash, stage core.TxStage, block *types.Block) *TxStatus {
+	status := &TxStatus{Hash: hash, Status: stage}
+	if block != nil {
+		blockHash, number := block.Hash(), hexutil.Uint64(block.NumberU64())
+		status.BlockHash, status.BlockNumber = &blockHash, &number
+	}
+	return status
+}
+
+// same reports whether both statuses are the same stage in the same block.
+func (s *TxStatus) same(other *TxStatus) bool {
+	if s.Status != other.Status || (s.BlockHash == nil) != (other.BlockHash == nil) {
+		return false
+	}
+	return s.BlockHash == nil || *s.BlockHash == *other.BlockHash
+}
+
+// SubscribeTxStatus creates a subscription that writes the lifecycle stages of
+// the transaction with the given hash.
+func (es *EventSystem) SubscribeTxStatus(hash common.Hash, statuses chan *TxStatus) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       TxStatusSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		txs:       make(chan []*types.Transaction),
+		headers:   make(chan *types.Header),
+		txHash:    hash,
+		statuses:  statuses,
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
 type filterIndex map[Type]map[rpc.ID]*subscription
 
 func (es *EventSystem) handleLogs(filters filterIndex, ev []*types.Log) {
@@ -345,12 +403,48 @@ func (es *EventSystem) handleTxsEvent(filters filterIndex, ev core.NewTxsEvent)
 	for _, f := range filters[PendingTransactionsSubscription] {
 		f.txs <- ev.Txs
 	}
+	if len(filters[TxStatusSubscription]) > 0 {
+		hashes := make([]common.Hash, len(ev.Txs))
+		for i, tx := range ev.Txs {
+			hashes[i] = tx.Hash()
+		}
+		es.handleTxStages(filters, core.TxStagePending, hashes, nil)
+	}
+}
+
+func (es *EventSystem) handleTxLifecycleEvent(filters filterIndex, ev core.TxLifecycleEvent) {
+	es.handleTxStages(filters, ev.Stage, ev.Txs, ev.Block)
+}
+
+// handleTxStages notifies the txStatus subscriptions of the transactions that
+// reached a stage.
+func (es *EventSystem) handleTxStages(filters filterIndex, stage core.TxStage, hashes []common.Hash, block *types.Block) {
+	if len(filters[TxStatusSubscription]) == 0 {
+		return
+	}
+	reached := make(map[common.Hash]struct{}, len(hashes))
+	for _, hash := range hashes {
+		reached[hash] = struct{}{}
+	}
+	for _, f := range filters[TxStatusSubscription] {
+		if _, ok := reached[f.txHash]; ok {
+			f.statuses <- newTxStatus(f.txHash, stage, block)
+		}
+	}
 }
 
 func (es *EventSystem) handleChainEvent(filters filterIndex, ev core.ChainEvent) {
 	for _, f := range filters[BlocksSubscription] {
 		f.headers <- ev.Block.Header()
 	}
+	if len(filters[TxStatusSubscription]) > 0 {
+		txs := ev.Block.Transactions()
+		hashes := make([]common.Hash, len(txs))
+		for i, tx := range txs {
+			hashes[i] = tx.Hash()
+		}
+		es.handleTxStages(filters, core.TxStageFinalized, hashes, ev.Block)
+	}
 	if es.lightMode && len(filters[LogsSubscription]) > 0 {
 		es.lightFilterNewHead(ev.Block.Header(), func(header *types.Header, remove bool) {
 			for _, f := range filters[LogsSubscription] {
@@ -444,6 +538,7 @@ func (es *EventSystem) eventLoop() {
 		es.rmLogsSub.Unsubscribe()
 		es.pendingLogsSub.Unsubscribe()
 		es.chainSub.Unsubscribe()
+		es.stageSub.Unsubscribe()
 	}()
 
 	index := make(filterIndex)
@@ -463,6 +558,8 @@ func (es *EventSystem) eventLoop() {
 			es.handlePendingLogs(index, ev)
 		case ev := <-es.chainCh:
 			es.handleChainEvent(index, ev)
+		case ev := <-es.stageCh:
+			es.handleTxLifecycleEvent(index, ev)
 
 		case f := <-es.install:
 			if f.typ == MinedAndPendingLogsSubscription {
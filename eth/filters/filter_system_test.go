@@ -51,6 +51,7 @@ type testBackend struct {
 	rmLogsFeed      event.Feed
 	pendingLogsFeed event.Feed
 	chainFeed       event.Feed
+	stageFeed       event.Feed
 }
 
 func (b *testBackend) ChainConfig() *params.ChainConfig {
@@ -133,6 +134,10 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	return b.stageFeed.Subscribe(ch)
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
@@ -168,6 +173,18 @@ func (b *testBackend) RealGasPriceMinimumForHeader(ctx context.Context, currency
 	return nil, fmt.Errorf("testBackend does not implement RealGasPriceMinimumForHeader")
 }
 
+func (b *testBackend) FeeCurrencyWhitelistForHeader(ctx context.Context, header *types.Header) (common.Address, []common.Address, error) {
+	return common.Address{}, nil, fmt.Errorf("testBackend does not implement FeeCurrencyWhitelistForHeader")
+}
+
+func (b *testBackend) ConversionFunctionsForHeader(ctx context.Context, header *types.Header) (func(*common.Address) *big.Int, types.ToCELOFn, error) {
+	return nil, nil, fmt.Errorf("testBackend does not implement ConversionFunctionsForHeader")
+}
+
+func (b *testBackend) ValidatorsForHeader(ctx context.Context, header *types.Header) ([]common.Address, error) {
+	return nil, fmt.Errorf("testBackend does not implement ValidatorsForHeader")
+}
+
 // TestBlockSubscription tests if a block subscription returns block hashes for posted chain events.
 // It creates multiple subscriptions:
 // - one at the start and should receive all posted chain events and a second (blockHashes)
@@ -280,6 +297,53 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestTxStatusSubscription tests whether the lifecycle stages of a transaction are
+// notified in order, leaving out the other transactions.
+func TestTxStatusSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false, deadline)
+
+		tx    = types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil)
+		other = types.NewTransaction(1, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil)
+		block = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}).WithBody(types.Transactions{tx}, nil, nil)
+	)
+	statuses := make(chan *TxStatus)
+	sub := api.events.SubscribeTxStatus(tx.Hash(), statuses)
+	defer sub.Unsubscribe()
+
+	go func() {
+		backend.stageFeed.Send(core.TxLifecycleEvent{Stage: core.TxStageQueued, Txs: []common.Hash{other.Hash()}})
+		backend.stageFeed.Send(core.TxLifecycleEvent{Stage: core.TxStageQueued, Txs: []common.Hash{tx.Hash()}})
+		// Wait for the queued stage to be handled, the feeds are read concurrently
+		time.Sleep(100 * time.Millisecond)
+		backend.txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{other, tx}})
+		time.Sleep(100 * time.Millisecond)
+		backend.stageFeed.Send(core.TxLifecycleEvent{Stage: core.TxStageProposed, Txs: []common.Hash{tx.Hash()}, Block: block})
+		backend.stageFeed.Send(core.TxLifecycleEvent{Stage: core.TxStageSealed, Txs: []common.Hash{tx.Hash()}, Block: block})
+		time.Sleep(100 * time.Millisecond)
+		backend.chainFeed.Send(core.ChainEvent{Block: block, Hash: block.Hash()})
+	}()
+
+	want := []core.TxStage{core.TxStageQueued, core.TxStagePending, core.TxStageProposed, core.TxStageSealed, core.TxStageFinalized}
+	for i, stage := range want {
+		select {
+		case status := <-statuses:
+			if status.Hash != tx.Hash() || status.Status != stage {
+				t.Fatalf("status %d mismatch: have %x %s, want %x %s", i, status.Hash, status.Status, tx.Hash(), stage)
+			}
+			if inBlock := status.BlockHash != nil; inBlock != (i >= 2) || (inBlock && *status.BlockHash != block.Hash()) {
+				t.Errorf("status %d block mismatch: have %v", i, status.BlockHash)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("status %d (%s) not notified", i, stage)
+		}
+	}
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
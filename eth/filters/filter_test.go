@@ -49,7 +49,7 @@ func BenchmarkFilters(b *testing.B) {
 	defer os.RemoveAll(dir)
 
 	var (
-		db, _   = rawdb.NewLevelDBDatabase(dir, 0, 0, "", false)
+		db, _   = rawdb.NewLevelDBDatabase(dir, 0, 0, "", false, 0)
 		backend = &testBackend{db: db}
 		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
@@ -103,7 +103,7 @@ func TestFilters(t *testing.T) {
 	defer os.RemoveAll(dir)
 
 	var (
-		db, _   = rawdb.NewLevelDBDatabase(dir, 0, 0, "", false)
+		db, _   = rawdb.NewLevelDBDatabase(dir, 0, 0, "", false, 0)
 		backend = &testBackend{db: db}
 		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr    = crypto.PubkeyToAddress(key1.PublicKey)
@@ -223,3 +223,26 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+func TestFilterSyntheticLogs(t *testing.T) {
+	var (
+		addr  = common.HexToAddress("0x11")
+		topic = common.HexToHash("0x22")
+		logs  = []*types.Log{
+			{Address: addr, Topics: []common.Hash{topic}},
+			{Address: types.FeeLogAddress, Topics: []common.Hash{types.GasFeeDebitedTopic}, Synthetic: true},
+		}
+	)
+	if have := filterLogs(logs, nil, nil, nil, nil); len(have) != 1 || have[0] != logs[0] {
+		t.Errorf("synthetic log matched without its address: have %v", have)
+	}
+	if have := filterLogs(logs, nil, nil, []common.Address{addr, types.FeeLogAddress}, nil); len(have) != 2 {
+		t.Errorf("logs mismatch: have %v, want both", have)
+	}
+	if have := filterLogs(logs, nil, nil, []common.Address{types.FeeLogAddress}, [][]common.Hash{{types.GasFeeDebitedTopic}}); len(have) != 1 || have[0] != logs[1] {
+		t.Errorf("synthetic log not matched by its address: have %v", have)
+	}
+	if filter := newFilter(&testBackend{db: rawdb.NewMemoryDatabase()}, []common.Address{types.FeeLogAddress}, nil); !filter.feeLogs {
+		t.Errorf("fee log filter checks the blooms")
+	}
+}
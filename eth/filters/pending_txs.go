@@ -0,0 +1,133 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/log"
+)
+
+// PendingTxsCriteria selects the pending transactions notified to a subscription,
+// all of them if empty. It is either given as the fullTx boolean, or as an object
+// with the filters.
+type PendingTxsCriteria struct {
+	FullTx        bool             // Notify the transactions instead of their hashes
+	From          []common.Address // Senders, any if empty
+	To            []common.Address // Recipients, any if empty, never matching contract creations
+	FeeCurrencies []common.Address // Fee currencies, the zero address for CELO, any if empty
+	MinPrice      *big.Int         // Minimum effective gas price in CELO for the next block, none if nil
+}
+
+// UnmarshalJSON sets *args fields with given data.
+func (args *PendingTxsCriteria) UnmarshalJSON(data []byte) error {
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] != '{' {
+		return json.Unmarshal(data, &args.FullTx)
+	}
+	var raw struct {
+		FullTx        bool             `json:"fullTx"`
+		From          []common.Address `json:"from"`
+		To            []common.Address `json:"to"`
+		FeeCurrencies []common.Address `json:"feeCurrencies"`
+		MinPrice      *hexutil.Big     `json:"minPrice"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	args.FullTx, args.From, args.To, args.FeeCurrencies = raw.FullTx, raw.From, raw.To, raw.FeeCurrencies
+	args.MinPrice = (*big.Int)(raw.MinPrice)
+	return nil
+}
+
+// pendingTxsMatcher matches the pending transactions against the criteria of a
+// subscription, pricing them for the block following the current head.
+type pendingTxsMatcher struct {
+	crit    PendingTxsCriteria
+	backend Backend
+	signer  types.Signer
+
+	head      common.Hash                    // Head the conversions are for
+	baseFeeFn func(*common.Address) *big.Int // Gas price minimums of the block following the head
+	toCELO    types.ToCELOFn
+}
+
+func newPendingTxsMatcher(crit PendingTxsCriteria, backend Backend) *pendingTxsMatcher {
+	return &pendingTxsMatcher{
+		crit:    crit,
+		backend: backend,
+		signer:  types.LatestSigner(backend.ChainConfig()),
+	}
+}
+
+// match returns whether a transaction meets the criteria.
+func (m *pendingTxsMatcher) match(tx *types.Transaction, latest *types.Header) bool {
+	if len(m.crit.From) > 0 {
+		from, err := types.Sender(m.signer, tx)
+		if err != nil || !includes(m.crit.From, from) {
+			return false
+		}
+	}
+	if len(m.crit.To) > 0 && (tx.To() == nil || !includes(m.crit.To, *tx.To())) {
+		return false
+	}
+	if len(m.crit.FeeCurrencies) > 0 {
+		feeCurrency := common.ZeroAddress
+		if tx.FeeCurrency() != nil {
+			feeCurrency = *tx.FeeCurrency()
+		}
+		if !includes(m.crit.FeeCurrencies, feeCurrency) {
+			return false
+		}
+	}
+	if m.crit.MinPrice != nil {
+		price, ok := m.effectivePrice(tx, latest)
+		if !ok || price.Cmp(m.crit.MinPrice) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// effectivePrice returns the gas price in CELO a transaction would pay in the
+// block following the given head, false if it's below the gas price minimum of
+// its currency or can't be converted.
+func (m *pendingTxsMatcher) effectivePrice(tx *types.Transaction, latest *types.Header) (*big.Int, bool) {
+	if hash := latest.Hash(); m.baseFeeFn == nil || hash != m.head {
+		baseFeeFn, toCELO, err := m.backend.ConversionFunctionsForHeader(context.Background(), latest)
+		if err != nil {
+			log.Debug("Failed to get conversion functions for pending transactions", "number", latest.Number, "err", err)
+			return nil, false
+		}
+		m.head, m.baseFeeFn, m.toCELO = hash, baseFeeFn, toCELO
+	}
+	baseFee := m.baseFeeFn(tx.FeeCurrency())
+	tip, err := tx.EffectiveGasTip(baseFee)
+	if err != nil {
+		return nil, false
+	}
+	price, err := m.toCELO(tip.Add(tip, baseFee), tx.FeeCurrency())
+	if err != nil {
+		return nil, false
+	}
+	return price, true
+}
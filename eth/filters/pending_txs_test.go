@@ -0,0 +1,112 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+// pricedBackend prices the fee currencies at a gas price minimum of 100, and
+// cUSD at 2 per CELO.
+type pricedBackend struct {
+	testBackend
+	cusd common.Address
+}
+
+func (b *pricedBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
+func (b *pricedBackend) ConversionFunctionsForHeader(ctx context.Context, header *types.Header) (func(*common.Address) *big.Int, types.ToCELOFn, error) {
+	baseFeeFn := func(*common.Address) *big.Int { return big.NewInt(100) }
+	toCELO := func(amount *big.Int, feeCurrency *common.Address) (*big.Int, error) {
+		if feeCurrency != nil && *feeCurrency == b.cusd {
+			return new(big.Int).Div(amount, big.NewInt(2)), nil
+		}
+		return amount, nil
+	}
+	return baseFeeFn, toCELO, nil
+}
+
+func TestUnmarshalJSONPendingTxsCriteria(t *testing.T) {
+	var crit PendingTxsCriteria
+	if err := json.Unmarshal([]byte("true"), &crit); err != nil || !crit.FullTx {
+		t.Fatalf("boolean criteria mismatch: have %v, %v, want full transactions", crit.FullTx, err)
+	}
+	vector := `{"fullTx":true,"to":["0x0000000000000000000000000000000000000001"],"feeCurrencies":["0x0000000000000000000000000000000000000000"],"minPrice":"0x64"}`
+	if err := json.Unmarshal([]byte(vector), &crit); err != nil {
+		t.Fatal(err)
+	}
+	if !crit.FullTx || len(crit.From) != 0 || len(crit.To) != 1 || len(crit.FeeCurrencies) != 1 || crit.MinPrice.Int64() != 100 {
+		t.Fatalf("object criteria mismatch: have %+v", crit)
+	}
+}
+
+func TestPendingTxsMatcher(t *testing.T) {
+	var (
+		backend = &pricedBackend{cusd: common.HexToAddress("0x02")}
+		key, _  = crypto.GenerateKey()
+		signer  = types.LatestSigner(params.TestChainConfig)
+		to      = common.Address{1}
+		latest  = &types.Header{Number: big.NewInt(1)}
+	)
+	tx := func(feeCap int64, feeCurrency *common.Address) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.CeloDynamicFeeTxV2{
+			ChainID:     params.TestChainConfig.ChainID,
+			GasTipCap:   big.NewInt(50),
+			GasFeeCap:   big.NewInt(feeCap),
+			Gas:         21000,
+			FeeCurrency: feeCurrency,
+			To:          &to,
+			Value:       big.NewInt(1),
+		})
+	}
+	tests := []struct {
+		crit PendingTxsCriteria
+		tx   *types.Transaction
+		want bool
+	}{
+		{PendingTxsCriteria{}, tx(200, nil), true},
+		{PendingTxsCriteria{From: []common.Address{crypto.PubkeyToAddress(key.PublicKey)}}, tx(200, nil), true},
+		{PendingTxsCriteria{From: []common.Address{to}}, tx(200, nil), false},
+		{PendingTxsCriteria{To: []common.Address{to}}, tx(200, nil), true},
+		{PendingTxsCriteria{To: []common.Address{{2}}}, tx(200, nil), false},
+		{PendingTxsCriteria{FeeCurrencies: []common.Address{common.ZeroAddress}}, tx(200, nil), true},
+		{PendingTxsCriteria{FeeCurrencies: []common.Address{common.ZeroAddress}}, tx(200, &backend.cusd), false},
+		// Paying the gas price minimum of 100 plus the tip of 50
+		{PendingTxsCriteria{MinPrice: big.NewInt(150)}, tx(200, nil), true},
+		{PendingTxsCriteria{MinPrice: big.NewInt(120)}, tx(120, nil), true},
+		{PendingTxsCriteria{MinPrice: big.NewInt(121)}, tx(120, nil), false},
+		{PendingTxsCriteria{MinPrice: big.NewInt(0)}, tx(99, nil), false},
+		// Half of it in CELO
+		{PendingTxsCriteria{MinPrice: big.NewInt(75)}, tx(200, &backend.cusd), true},
+		{PendingTxsCriteria{MinPrice: big.NewInt(76)}, tx(200, &backend.cusd), false},
+	}
+	for i, test := range tests {
+		if have := newPendingTxsMatcher(test.crit, backend).match(test.tx, latest); have != test.want {
+			t.Errorf("test %d: match mismatch: have %v, want %v", i, have, test.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+var errGasUsageIndexDisabled = errors.New("gas usage index disabled, see --gasusageindex")
+
+// ContractGasUsage is the gas used by the transactions sent to a contract.
+type ContractGasUsage struct {
+	Contract     common.Address `json:"contract"` // Zero for contract creations
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	Transactions hexutil.Uint64 `json:"transactions"`
+}
+
+// GasUsageByContract is the gas used per contract in a range of epochs, by
+// decreasing gas used.
+type GasUsageByContract struct {
+	FromBlock hexutil.Uint64      `json:"fromBlock"`
+	ToBlock   hexutil.Uint64      `json:"toBlock"`
+	Contracts []*ContractGasUsage `json:"contracts"`
+}
+
+// PublicGasUsageAPI serves the gas usage index.
+type PublicGasUsageAPI struct {
+	eth *Ethereum
+}
+
+// NewPublicGasUsageAPI creates a new gas usage index API.
+func NewPublicGasUsageAPI(eth *Ethereum) *PublicGasUsageAPI {
+	return &PublicGasUsageAPI{eth: eth}
+}
+
+// GasUsageByContract returns the gas used per contract in the indexed sections
+// of the chain overlapping [fromBlock, toBlock], each of them an epoch long, and
+// the range of blocks they cover. Only the contracts that used the most gas in
+// every section are kept (see core.GasUsageSectionContracts), and the result is
+// cut to the first limit contracts if given.
+func (api *PublicGasUsageAPI) GasUsageByContract(ctx context.Context, fromBlock, toBlock rpc.BlockNumber, limit *hexutil.Uint) (*GasUsageByContract, error) {
+	indexer := api.eth.gasUsageIndexer
+	if indexer == nil {
+		return nil, errGasUsageIndexDisabled
+	}
+	var (
+		size           = api.eth.blockchain.Config().Istanbul.Epoch
+		head           = api.eth.blockchain.CurrentHeader().Number.Uint64()
+		from           = resolveGasUsageBlock(fromBlock, head)
+		to             = resolveGasUsageBlock(toBlock, head)
+		first          = from / size
+		last           = to / size
+		sections, _, _ = indexer.Sections()
+	)
+	if last >= sections {
+		last = sections - 1
+	}
+	if sections == 0 || first > last {
+		return nil, fmt.Errorf("blocks %d to %d not indexed yet", from, to)
+	}
+	usage := make(map[common.Address]*rawdb.ContractGasUsage)
+	for section := first; section <= last; section++ {
+		for _, contract := range rawdb.ReadGasUsageSection(api.eth.chainDb, section) {
+			if total, ok := usage[contract.Contract]; ok {
+				total.GasUsed += contract.GasUsed
+				total.Transactions += contract.Transactions
+			} else {
+				usage[contract.Contract] = contract
+			}
+		}
+	}
+	sorted := make([]*rawdb.ContractGasUsage, 0, len(usage))
+	for _, contract := range usage {
+		sorted = append(sorted, contract)
+	}
+	core.SortGasUsage(sorted)
+	if limit != nil && int(*limit) < len(sorted) {
+		sorted = sorted[:*limit]
+	}
+	result := &GasUsageByContract{
+		FromBlock: hexutil.Uint64(first * size),
+		ToBlock:   hexutil.Uint64((last+1)*size - 1),
+		Contracts: make([]*ContractGasUsage, len(sorted)),
+	}
+	for i, contract := range sorted {
+		result.Contracts[i] = &ContractGasUsage{
+			Contract:     contract.Contract,
+			GasUsed:      hexutil.Uint64(contract.GasUsed),
+			Transactions: hexutil.Uint64(contract.Transactions),
+		}
+	}
+	return result, nil
+}
+
+// resolveGasUsageBlock returns the number of a block of the range, the head for
+// the latest and pending ones.
+func resolveGasUsageBlock(number rpc.BlockNumber, head uint64) uint64 {
+	switch {
+	case number == rpc.EarliestBlockNumber:
+		return 0
+	case number < 0 || uint64(number) > head:
+		return head
+	}
+	return uint64(number)
+}
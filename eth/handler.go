@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/mclock"
 	"github.com/celo-org/celo-blockchain/consensus"
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/forkid"
@@ -89,7 +90,9 @@ type handlerConfig struct {
 	Whitelist    map[uint64]common.Hash    // Hard coded whitelist for sync challenged
 	server       *p2p.Server
 	proxyServer  *p2p.Server
-	MinSyncPeers int // The minimum peers required to sstart syncing
+	MinSyncPeers int    // The minimum peers required to sstart syncing
+	KnownCache   uint64 // Megabytes to alloc for the known txs and blocks shared across peers (0 = disabled)
+	TxStemRelay  bool   // Whether to relay local txs through a single peer before broadcasting them
 }
 
 type handler struct {
@@ -113,6 +116,9 @@ type handler struct {
 	blockFetcher *fetcher.BlockFetcher
 	txFetcher    *fetcher.TxFetcher
 	peers        *peerSet
+	knownCache   *sharedKnownCache // Broadcasts received from any peer, nil if not deduplicating
+	stem         *stemRelay        // Stem phase of the local txs, nil if disabled
+	mempoolSync  *mempoolSyncer
 
 	eventMux      *event.TypeMux
 	txsCh         chan core.NewTxsEvent
@@ -145,11 +151,18 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		txpool:      config.TxPool,
 		chain:       config.Chain,
 		peers:       newPeerSet(),
+		mempoolSync: newMempoolSyncer(mclock.System{}),
 		whitelist:   config.Whitelist,
 		quitSync:    make(chan struct{}),
 		server:      config.server,
 		proxyServer: config.proxyServer,
 	}
+	if config.KnownCache > 0 {
+		h.knownCache = newSharedKnownCache(config.KnownCache)
+	}
+	if config.TxStemRelay {
+		h.stem = newStemRelay(h, mclock.System{})
+	}
 
 	if consensusHandler, ok := h.chain.Engine().(consensus.Handler); ok {
 		consensusHandler.SetBroadcaster(h)
@@ -472,6 +485,9 @@ func (h *handler) Start(maxPeers int) {
 func (h *handler) Stop() {
 	h.txsSub.Unsubscribe()        // quits txBroadcastLoop
 	h.minedBlockSub.Unsubscribe() // quits blockBroadcastLoop
+	if h.stem != nil {
+		h.stem.close()
+	}
 
 	// Quit chainSync and txsync64.
 	// After this is done, no new peers will be accepted.
@@ -540,6 +556,10 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		annos = make(map[*ethPeer][]common.Hash) // Set peer->hash to announce
 
 	)
+	// Local transactions first go through their stem phase, if enabled
+	if h.stem != nil {
+		txs = h.stem.relay(txs)
+	}
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
 		peers := h.peers.peersWithoutTransaction(tx.Hash())
@@ -94,7 +94,14 @@ func (h *ethHandler) Handle(peer *eth.Peer, packet eth.Packet) error {
 		return h.txFetcher.Notify(peer.ID(), *packet)
 
 	case *eth.TransactionsPacket:
-		return h.txFetcher.Enqueue(peer.ID(), *packet, false)
+		txs := []*types.Transaction(*packet)
+		if h.knownCache != nil {
+			// Drop the copies already received through another proxy or peer
+			if txs = h.knownCache.filterTxs(txs); len(txs) == 0 {
+				return nil
+			}
+		}
+		return h.txFetcher.Enqueue(peer.ID(), txs, false)
 
 	case *eth.PooledTransactionsPacket:
 		return h.txFetcher.Enqueue(peer.ID(), *packet, true)
@@ -200,8 +207,10 @@ func (h *ethHandler) handleBlockAnnounces(peer *eth.Peer, hashes []common.Hash,
 // handleBlockBroadcast is invoked from a peer's message handler when it transmits a
 // block broadcast for the local node to process.
 func (h *ethHandler) handleBlockBroadcast(peer *eth.Peer, block *types.Block, td *big.Int) error {
-	// Schedule the block for import
-	h.blockFetcher.Enqueue(peer.ID(), block)
+	// Schedule the block for import, unless already received through another proxy or peer
+	if h.knownCache == nil || h.knownCache.markBlock(block.Hash()) {
+		h.blockFetcher.Enqueue(peer.ID(), block)
+	}
 
 	// Assuming the block is importable by the peer, but possibly not yet done so,
 	// calculate the head hash and TD that the peer truly must have.
@@ -0,0 +1,340 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	istanbulBackend "github.com/celo-org/celo-blockchain/consensus/istanbul/backend"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/proxy"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+	"github.com/celo-org/celo-blockchain/metrics/alerts"
+	"github.com/celo-org/celo-blockchain/p2p/enode"
+)
+
+const (
+	headLagCheckInterval  = 5 * time.Second  // Interval between the checks of the head
+	headLagFailoverDelay  = time.Minute      // Time the alarm must be firing before failing over the lagging proxies
+	headLagWebhookTimeout = 10 * time.Second // Timeout of the posts to the webhook
+)
+
+var (
+	headTimeLagGauge     = metrics.NewRegisteredGauge("eth/headlag/time", nil)
+	headPeerLagGauge     = metrics.NewRegisteredGauge("eth/headlag/peers", nil)
+	headLagAlarmMeter    = metrics.NewRegisteredMeter("eth/headlag/alarms", nil)
+	headLagFailoverMeter = metrics.NewRegisteredMeter("eth/headlag/failovers", nil)
+)
+
+// HeadLagStatus reports the lag of the chain head behind the clock and the
+// peers, and the alarm raised when it's past the thresholds while the node is an
+// elected validator.
+type HeadLagStatus struct {
+	Head           uint64 `json:"head"`
+	TimeLag        int64  `json:"timeLag"`        // Milliseconds from the head timestamp to the local clock
+	BestPeerHead   uint64 `json:"bestPeerHead"`   // Highest head advertised by the peers
+	PeerLag        uint64 `json:"peerLag"`        // Blocks behind the best peer head
+	TimeThreshold  int64  `json:"timeThreshold"`  // Milliseconds
+	BlockThreshold uint64 `json:"blockThreshold"` // Blocks
+	Elected        bool   `json:"elected"`        // Whether the node is validating in the current validator set
+	Alarm          bool   `json:"alarm"`
+	AlarmSince     uint64 `json:"alarmSince,omitempty"` // Unix time the alarm fired
+	FailedOver     bool   `json:"failedOver"`           // Whether the lagging proxies were removed during the alarm
+}
+
+// HeadLagAlarm is posted to the webhook when the head lag alarm fires, and when
+// it resolves.
+type HeadLagAlarm struct {
+	Firing    bool           `json:"firing"`
+	Validator common.Address `json:"validator"`
+	Head      uint64         `json:"head"`
+	TimeLag   int64          `json:"timeLag"` // Milliseconds
+	PeerLag   uint64         `json:"peerLag"` // Blocks
+	Time      uint64         `json:"time"`
+}
+
+// headLagPeer is the head advertised by a peer.
+type headLagPeer struct {
+	id   enode.ID
+	head uint64
+}
+
+// headLagProxy is a proxy of the validator the monitor can fail over.
+type headLagProxy struct {
+	node   *enode.Node
+	peered bool
+}
+
+// headLagBackend is the part of the node the head lag monitor follows.
+type headLagBackend interface {
+	CurrentHeader() *types.Header
+	PeerHeads() []headLagPeer
+	// Elected returns the address of the validator and whether it's validating
+	// in the validator set of the header.
+	Elected(header *types.Header) (common.Address, bool)
+	// Proxies returns the proxies of the validator, none if it isn't proxied.
+	Proxies() []headLagProxy
+	RemoveProxy(node *enode.Node) error
+}
+
+// headLagMonitor compares the chain head to the local clock and to the heads of
+// the peers, and raises an alarm when it lags past the thresholds while the node
+// is an elected validator: a lagging validator misses its proposals and slows
+// down the rounds of the whole network. The alarm is logged, metered and posted
+// to the webhook, and if it lasts the proxies lagging behind the others are
+// removed so that the validator is assigned to the remaining ones.
+type headLagMonitor struct {
+	timeThreshold  time.Duration
+	blockThreshold uint64
+	webhook        string // None if empty
+	failover       bool
+	backend        headLagBackend
+
+	mu           sync.Mutex
+	status       HeadLagStatus
+	alarmSince   time.Time
+	failoverDone bool // Whether the failover was attempted during the alarm
+
+	now  func() time.Time
+	post func(ctx context.Context, url string, body interface{}) error
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newHeadLagMonitor(timeThreshold time.Duration, blockThreshold uint64, webhook string, failover bool, backend headLagBackend) *headLagMonitor {
+	return &headLagMonitor{
+		timeThreshold:  timeThreshold,
+		blockThreshold: blockThreshold,
+		webhook:        webhook,
+		failover:       failover,
+		backend:        backend,
+		now:            time.Now,
+		post:           alerts.PostJSON,
+		quit:           make(chan struct{}),
+	}
+}
+
+// start launches the goroutine checking the head.
+func (m *headLagMonitor) start() {
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// stop terminates the goroutine of the monitor.
+func (m *headLagMonitor) stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+func (m *headLagMonitor) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(headLagCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if alarm := m.check(); alarm != nil && m.webhook != "" {
+				m.notify(alarm)
+			}
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// check measures the lag of the head, and fires or resolves the alarm. It returns
+// the alarm to post if its state changed.
+func (m *headLagMonitor) check() *HeadLagAlarm {
+	var (
+		now          = m.now()
+		header       = m.backend.CurrentHeader()
+		head         = header.Number.Uint64()
+		timeLag      = now.Sub(time.Unix(int64(header.Time), 0))
+		bestPeerHead = head
+	)
+	for _, peer := range m.backend.PeerHeads() {
+		if peer.head > bestPeerHead {
+			bestPeerHead = peer.head
+		}
+	}
+	validator, elected := m.backend.Elected(header)
+	headTimeLagGauge.Update(timeLag.Milliseconds())
+	headPeerLagGauge.Update(int64(bestPeerHead - head))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lagging := timeLag > m.timeThreshold || bestPeerHead-head > m.blockThreshold
+	m.status = HeadLagStatus{
+		Head:           head,
+		TimeLag:        timeLag.Milliseconds(),
+		BestPeerHead:   bestPeerHead,
+		PeerLag:        bestPeerHead - head,
+		TimeThreshold:  m.timeThreshold.Milliseconds(),
+		BlockThreshold: m.blockThreshold,
+		Elected:        elected,
+		Alarm:          m.status.Alarm,
+		FailedOver:     m.status.FailedOver,
+	}
+	alarm := &HeadLagAlarm{
+		Validator: validator,
+		Head:      head,
+		TimeLag:   timeLag.Milliseconds(),
+		PeerLag:   bestPeerHead - head,
+		Time:      uint64(now.Unix()),
+	}
+	switch {
+	case lagging && elected && !m.status.Alarm:
+		log.Error("Chain head lagging while elected validator", "head", head, "lag", common.PrettyDuration(timeLag), "behind", bestPeerHead-head, "validator", validator)
+		headLagAlarmMeter.Mark(1)
+		m.status.Alarm, m.alarmSince = true, now
+		m.status.AlarmSince = uint64(now.Unix())
+		alarm.Firing = true
+		return alarm
+
+	case m.status.Alarm && (!lagging || !elected):
+		log.Info("Chain head lag alarm resolved", "head", head, "lag", common.PrettyDuration(timeLag), "behind", bestPeerHead-head)
+		m.status.Alarm, m.status.FailedOver, m.alarmSince, m.failoverDone = false, false, time.Time{}, false
+		return alarm
+
+	case m.status.Alarm:
+		m.status.AlarmSince = uint64(m.alarmSince.Unix())
+		if m.failover && !m.failoverDone && now.Sub(m.alarmSince) >= headLagFailoverDelay {
+			m.status.FailedOver, m.failoverDone = m.failoverProxies(bestPeerHead), true
+		}
+	}
+	return nil
+}
+
+// failoverProxies removes the peered proxies whose heads lag past the threshold
+// behind the best peer head, as long as another peered proxy is left to take
+// their validators over. It must be called with the lock held.
+func (m *headLagMonitor) failoverProxies(bestPeerHead uint64) bool {
+	heads := make(map[enode.ID]uint64)
+	for _, peer := range m.backend.PeerHeads() {
+		heads[peer.id] = peer.head
+	}
+	var lagging, synced []*enode.Node
+	for _, proxy := range m.backend.Proxies() {
+		if !proxy.peered {
+			continue
+		}
+		if head, ok := heads[proxy.node.ID()]; ok && bestPeerHead-head <= m.blockThreshold {
+			synced = append(synced, proxy.node)
+		} else {
+			lagging = append(lagging, proxy.node)
+		}
+	}
+	if len(lagging) == 0 || len(synced) == 0 {
+		log.Warn("No proxy to fail over to", "lagging", len(lagging), "synced", len(synced))
+		return false
+	}
+	for _, node := range lagging {
+		if err := m.backend.RemoveProxy(node); err != nil {
+			log.Warn("Failed to remove lagging proxy", "proxy", node, "err", err)
+			continue
+		}
+		log.Warn("Removed lagging proxy", "proxy", node)
+		headLagFailoverMeter.Mark(1)
+	}
+	return true
+}
+
+// notify posts an alarm to the webhook.
+func (m *headLagMonitor) notify(alarm *HeadLagAlarm) {
+	ctx, cancel := context.WithTimeout(context.Background(), headLagWebhookTimeout)
+	defer cancel()
+
+	if err := m.post(ctx, m.webhook, alarm); err != nil {
+		log.Warn("Failed to post head lag alarm", "err", err)
+	}
+}
+
+// lagStatus reports the lag of the last check.
+func (m *headLagMonitor) lagStatus() HeadLagStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// headLagEthereum follows the chain, peers and proxies of the Ethereum service
+// for the head lag monitor.
+type headLagEthereum Ethereum
+
+func (s *headLagEthereum) CurrentHeader() *types.Header {
+	return s.blockchain.CurrentHeader()
+}
+
+// PeerHeads returns the heads of the peers, numbered from their total difficulty
+// since every Istanbul block adds one to it.
+func (s *headLagEthereum) PeerHeads() []headLagPeer {
+	var heads []headLagPeer
+	for _, peer := range s.handler.peers.Peers() {
+		if _, td := peer.Head(); td != nil && td.Sign() > 0 {
+			heads = append(heads, headLagPeer{id: peer.Node().ID(), head: td.Uint64() - 1})
+		}
+	}
+	return heads
+}
+
+func (s *headLagEthereum) Elected(header *types.Header) (common.Address, bool) {
+	istanbul, ok := s.engine.(*istanbulBackend.Backend)
+	if !ok {
+		return common.Address{}, false
+	}
+	validator := istanbul.ValidatorAddress()
+	if !istanbul.IsValidating() {
+		return validator, false
+	}
+	for _, val := range istanbul.GetValidators(header.Number, header.Hash()) {
+		if val.Address() == validator {
+			return validator, true
+		}
+	}
+	return validator, false
+}
+
+func (s *headLagEthereum) Proxies() []headLagProxy {
+	istanbul, ok := s.engine.(*istanbulBackend.Backend)
+	if !ok || !istanbul.IsProxiedValidator() {
+		return nil
+	}
+	proxies, _, err := istanbul.GetProxiedValidatorEngine().GetProxiesAndValAssignments()
+	if err != nil {
+		return nil
+	}
+	list := make([]headLagProxy, 0, len(proxies))
+	for _, proxy := range proxies {
+		list = append(list, headLagProxy{node: proxy.Node(), peered: proxy.IsPeered()})
+	}
+	return list
+}
+
+func (s *headLagEthereum) RemoveProxy(node *enode.Node) error {
+	istanbul, ok := s.engine.(*istanbulBackend.Backend)
+	if !ok || !istanbul.IsProxiedValidator() {
+		return proxy.ErrNodeNotProxiedValidator
+	}
+	return istanbul.GetProxiedValidatorEngine().RemoveProxy(node)
+}
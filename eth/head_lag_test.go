@@ -0,0 +1,133 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/p2p/enode"
+)
+
+type testHeadLagBackend struct {
+	header  *types.Header
+	peers   []headLagPeer
+	elected bool
+	proxies []headLagProxy
+	removed []*enode.Node
+}
+
+func (b *testHeadLagBackend) CurrentHeader() *types.Header { return b.header }
+func (b *testHeadLagBackend) PeerHeads() []headLagPeer     { return b.peers }
+func (b *testHeadLagBackend) Proxies() []headLagProxy      { return b.proxies }
+
+func (b *testHeadLagBackend) Elected(header *types.Header) (common.Address, bool) {
+	return common.HexToAddress("0x01"), b.elected
+}
+
+func (b *testHeadLagBackend) RemoveProxy(node *enode.Node) error {
+	b.removed = append(b.removed, node)
+	return nil
+}
+
+func testProxyNode(t *testing.T) *enode.Node {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return enode.NewV4(&key.PublicKey, nil, 0, 0)
+}
+
+func TestHeadLagMonitorAlarm(t *testing.T) {
+	var (
+		now     = time.Unix(1000, 0)
+		synced  = testProxyNode(t)
+		lagging = testProxyNode(t)
+		backend = &testHeadLagBackend{
+			header: &types.Header{Number: big.NewInt(100), Time: 995},
+			peers:  []headLagPeer{{id: synced.ID(), head: 102}, {id: lagging.ID(), head: 100}},
+			proxies: []headLagProxy{
+				{node: synced, peered: true},
+				{node: lagging, peered: true},
+			},
+		}
+		m = newHeadLagMonitor(30*time.Second, 1, "", true, backend)
+	)
+	m.now = func() time.Time { return now }
+
+	// Lagging nodes that aren't elected validators don't raise the alarm
+	if alarm := m.check(); alarm != nil {
+		t.Fatalf("alarm raised while not elected: %+v", alarm)
+	}
+	if status := m.lagStatus(); status.PeerLag != 2 || status.TimeLag != 5000 || status.Alarm {
+		t.Fatalf("status mismatch: have peer lag %d, time lag %d, alarm %v, want 2, 5000, false", status.PeerLag, status.TimeLag, status.Alarm)
+	}
+	backend.elected = true
+	if alarm := m.check(); alarm == nil || !alarm.Firing || alarm.PeerLag != 2 {
+		t.Fatalf("alarm mismatch: have %+v, want firing 2 blocks behind", alarm)
+	}
+	// Lagging proxies are removed once the alarm lasts
+	now = now.Add(headLagFailoverDelay / 2)
+	if alarm := m.check(); alarm != nil || len(backend.removed) != 0 {
+		t.Fatalf("failed over early: alarm %+v, removed %d proxies", alarm, len(backend.removed))
+	}
+	now = now.Add(headLagFailoverDelay)
+	m.check()
+	if len(backend.removed) != 1 || backend.removed[0] != lagging || !m.lagStatus().FailedOver {
+		t.Fatalf("failover mismatch: have %v removed, want %v", backend.removed, lagging)
+	}
+	m.check()
+	if len(backend.removed) != 1 {
+		t.Fatalf("failed over twice: have %d proxies removed", len(backend.removed))
+	}
+	// The alarm resolves once the head catches up
+	backend.header = &types.Header{Number: big.NewInt(102), Time: uint64(now.Unix())}
+	if alarm := m.check(); alarm == nil || alarm.Firing {
+		t.Fatalf("alarm mismatch: have %+v, want resolved", alarm)
+	}
+	if status := m.lagStatus(); status.Alarm || status.FailedOver {
+		t.Fatalf("status mismatch: have alarm %v, failed over %v, want false", status.Alarm, status.FailedOver)
+	}
+}
+
+func TestHeadLagMonitorNoFailoverTarget(t *testing.T) {
+	var (
+		now     = time.Unix(1000, 0)
+		proxy   = testProxyNode(t)
+		backend = &testHeadLagBackend{
+			header:  &types.Header{Number: big.NewInt(100), Time: 900},
+			peers:   []headLagPeer{{id: proxy.ID(), head: 100}},
+			elected: true,
+			proxies: []headLagProxy{{node: proxy, peered: true}},
+		}
+		m = newHeadLagMonitor(30*time.Second, 1, "", true, backend)
+	)
+	m.now = func() time.Time { return now }
+	if alarm := m.check(); alarm == nil || !alarm.Firing {
+		t.Fatalf("alarm mismatch: have %+v, want firing", alarm)
+	}
+	// The only proxy is as far behind as the validator, it's kept
+	now = now.Add(2 * headLagFailoverDelay)
+	m.check()
+	if len(backend.removed) != 0 {
+		t.Fatalf("removed the last proxy")
+	}
+}
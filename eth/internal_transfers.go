@@ -0,0 +1,172 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+const (
+	// maxInternalTransferBlocks is the most blocks whose transfers are returned
+	// when they aren't filtered by account.
+	maxInternalTransferBlocks = 1024
+
+	// maxInternalTransfers is the most transfers of an account returned at once.
+	maxInternalTransfers = 10000
+)
+
+var errInternalTransferIndexDisabled = errors.New("internal transfer index disabled, see --internaltransferindex")
+
+// InternalTransfer is a move of CELO made by a block.
+type InternalTransfer struct {
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	BlockHash        common.Hash    `json:"blockHash"`
+	TransactionHash  common.Hash    `json:"transactionHash"` // Zero for the system calls of the block
+	TransactionIndex hexutil.Uint   `json:"transactionIndex"`
+	TransferIndex    hexutil.Uint   `json:"transferIndex"`
+	Kind             string         `json:"kind"` // One of call, mint, selfdestruct and fee
+	From             common.Address `json:"from"`
+	To               common.Address `json:"to"`
+	Value            *hexutil.Big   `json:"value"`
+}
+
+// InternalTransferQuery selects the transfers of a block or of a range of blocks,
+// optionally only the ones made or received by an account.
+type InternalTransferQuery struct {
+	BlockHash *common.Hash     `json:"blockHash"` // Excludes the range
+	FromBlock *rpc.BlockNumber `json:"fromBlock"` // Latest by default
+	ToBlock   *rpc.BlockNumber `json:"toBlock"`   // Latest by default
+	Address   *common.Address  `json:"address"`
+}
+
+// PublicInternalTransferAPI serves the internal transfer index.
+type PublicInternalTransferAPI struct {
+	eth *Ethereum
+}
+
+// NewPublicInternalTransferAPI creates a new internal transfer index API.
+func NewPublicInternalTransferAPI(eth *Ethereum) *PublicInternalTransferAPI {
+	return &PublicInternalTransferAPI{eth: eth}
+}
+
+// GetInternalTransfers returns the CELO transfers made by the canonical blocks
+// selected, in chain order: the values of the calls, the selfdestructs, the mints
+// of the epoch rewards and the gas fees paid in CELO. The blocks imported before
+// the index was enabled, or synced without being processed, have no transfers.
+func (api *PublicInternalTransferAPI) GetInternalTransfers(ctx context.Context, query InternalTransferQuery) ([]*InternalTransfer, error) {
+	if !api.eth.config.InternalTransferIndex {
+		return nil, errInternalTransferIndexDisabled
+	}
+	db := api.eth.chainDb
+	if query.BlockHash != nil {
+		if query.FromBlock != nil || query.ToBlock != nil {
+			return nil, errors.New("block hash and block range are mutually exclusive")
+		}
+		number := rawdb.ReadHeaderNumber(db, *query.BlockHash)
+		if number == nil {
+			return nil, fmt.Errorf("block %x not found", *query.BlockHash)
+		}
+		transfers, ok := rawdb.ReadInternalTransfers(db, *query.BlockHash, *number)
+		if !ok {
+			return nil, fmt.Errorf("transfers of block %x not indexed", *query.BlockHash)
+		}
+		return blockInternalTransfers(*number, *query.BlockHash, transfers, query.Address), nil
+	}
+	var (
+		head = api.eth.blockchain.CurrentHeader().Number.Uint64()
+		from = head
+		to   = head
+	)
+	if query.FromBlock != nil {
+		from = resolveGasUsageBlock(*query.FromBlock, head)
+	}
+	if query.ToBlock != nil {
+		to = resolveGasUsageBlock(*query.ToBlock, head)
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d to %d", from, to)
+	}
+	if query.Address != nil {
+		return accountInternalTransfers(db, *query.Address, from, to)
+	}
+	if to-from >= maxInternalTransferBlocks {
+		return nil, fmt.Errorf("block range %d to %d too large, the most is %d blocks without an address", from, to, maxInternalTransferBlocks)
+	}
+	result := []*InternalTransfer{}
+	for number := from; number <= to; number++ {
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if transfers, ok := rawdb.ReadInternalTransfers(db, hash, number); ok {
+			result = append(result, blockInternalTransfers(number, hash, transfers, nil)...)
+		}
+	}
+	return result, nil
+}
+
+// blockInternalTransfers returns the transfers of a block, only the ones made or
+// received by the address if any.
+func blockInternalTransfers(number uint64, hash common.Hash, transfers []*types.InternalTransfer, address *common.Address) []*InternalTransfer {
+	result := make([]*InternalTransfer, 0, len(transfers))
+	for _, transfer := range transfers {
+		if address != nil && transfer.From != *address && transfer.To != *address {
+			continue
+		}
+		result = append(result, newInternalTransfer(number, hash, transfer))
+	}
+	return result
+}
+
+// accountInternalTransfers returns the indexed transfers of an account made by
+// the canonical blocks in [from, to].
+func accountInternalTransfers(db ethdb.Database, address common.Address, from, to uint64) ([]*InternalTransfer, error) {
+	transfers := rawdb.ReadAccountTransfers(db, address, from, to, maxInternalTransfers+1)
+	if len(transfers) > maxInternalTransfers {
+		return nil, fmt.Errorf("more than %d transfers in blocks %d to %d, narrow the range", maxInternalTransfers, from, to)
+	}
+	result := make([]*InternalTransfer, 0, len(transfers))
+	for _, transfer := range transfers {
+		// Skip the transfers of the blocks reorged out
+		if rawdb.ReadCanonicalHash(db, transfer.Number) != transfer.BlockHash {
+			continue
+		}
+		result = append(result, newInternalTransfer(transfer.Number, transfer.BlockHash, transfer.Transfer))
+	}
+	return result, nil
+}
+
+// newInternalTransfer returns the RPC representation of a transfer of a block.
+func newInternalTransfer(number uint64, hash common.Hash, transfer *types.InternalTransfer) *InternalTransfer {
+	return &InternalTransfer{
+		BlockNumber:      hexutil.Uint64(number),
+		BlockHash:        hash,
+		TransactionHash:  transfer.TxHash,
+		TransactionIndex: hexutil.Uint(transfer.TxIndex),
+		TransferIndex:    hexutil.Uint(transfer.Index),
+		Kind:             transfer.Kind.String(),
+		From:             transfer.From,
+		To:               transfer.To,
+		Value:            (*hexutil.Big)(transfer.Value),
+	}
+}
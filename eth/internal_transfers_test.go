@@ -0,0 +1,69 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+)
+
+func TestAccountInternalTransfers(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		account = common.Address{1}
+		other   = common.Address{2}
+	)
+	// A transfer from and one to the account in every block, both blocks of
+	// height 2 being indexed
+	for number := uint64(0); number < 4; number++ {
+		block := common.Hash{byte(number + 1)}
+		rawdb.WriteCanonicalHash(db, block, number)
+		transfers := []*types.InternalTransfer{
+			{Kind: types.TransferCall, From: account, To: other, Value: big.NewInt(int64(number + 1))},
+			{Kind: types.TransferMint, To: account, Value: big.NewInt(int64(number + 1))},
+		}
+		rawdb.WriteInternalTransfers(db, block, number, transfers)
+		if number == 2 {
+			rawdb.WriteInternalTransfers(db, common.Hash{0xff}, number, transfers)
+		}
+	}
+	have, err := accountInternalTransfers(db, account, 1, 2)
+	if err != nil {
+		t.Fatalf("failed to get the transfers: %v", err)
+	}
+	if len(have) != 4 {
+		t.Fatalf("transfers mismatch: have %d, want 4", len(have))
+	}
+	for i, transfer := range have {
+		number := uint64(1 + i/2)
+		if uint64(transfer.BlockNumber) != number || transfer.BlockHash != (common.Hash{byte(number + 1)}) || uint(transfer.TransferIndex) != uint(i%2) {
+			t.Errorf("transfer %d mismatch: have %+v", i, transfer)
+		}
+	}
+	if have[1].Kind != "mint" || have[1].To != account || have[1].Value.ToInt().Int64() != 2 {
+		t.Errorf("mint mismatch: have %+v", have[1])
+	}
+	// The other account only received
+	transfers, _ := rawdb.ReadInternalTransfers(db, common.Hash{4}, 3)
+	if have := blockInternalTransfers(3, common.Hash{4}, transfers, &other); len(have) != 1 || have[0].From != account {
+		t.Errorf("transfers of the other account mismatch: have %v", have)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/metrics"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// knownEntrySize is the approximate memory used by every hash held in the
+	// shared known set (key, list element and map bucket of the LRU).
+	knownEntrySize = 128
+
+	// knownBlocksDivisor is the share (1/n) of the memory budget used for blocks,
+	// the rest goes to transactions which are far more numerous.
+	knownBlocksDivisor = 16
+)
+
+var (
+	knownTxDuplicateMeter    = metrics.NewRegisteredMeter("eth/known/txs/duplicate", nil)
+	knownTxUniqueMeter       = metrics.NewRegisteredMeter("eth/known/txs/unique", nil)
+	knownBlockDuplicateMeter = metrics.NewRegisteredMeter("eth/known/blocks/duplicate", nil)
+	knownBlockUniqueMeter    = metrics.NewRegisteredMeter("eth/known/blocks/unique", nil)
+	knownTxsGauge            = metrics.NewRegisteredGauge("eth/known/txs/size", nil)
+	knownBlocksGauge         = metrics.NewRegisteredGauge("eth/known/blocks/size", nil)
+)
+
+// sharedKnownCache tracks the transactions and blocks broadcast to the node by
+// any of its peers. A proxied validator receives the same broadcasts through
+// each of its proxies and its direct peers, so checking them against a single
+// set lets it process only the first copy.
+type sharedKnownCache struct {
+	txs    *lru.Cache
+	blocks *lru.Cache
+}
+
+// newSharedKnownCache creates a known set using at most budget megabytes.
+func newSharedKnownCache(budget uint64) *sharedKnownCache {
+	entries := int(budget * 1024 * 1024 / knownEntrySize)
+	blockEntries := entries / knownBlocksDivisor
+	if blockEntries < 1 {
+		blockEntries = 1
+	}
+	txEntries := entries - blockEntries
+	if txEntries < 1 {
+		txEntries = 1
+	}
+	txs, _ := lru.New(txEntries)
+	blocks, _ := lru.New(blockEntries)
+	return &sharedKnownCache{txs: txs, blocks: blocks}
+}
+
+// filterTxs marks the given transactions as known, returning only those which
+// were not already known.
+func (c *sharedKnownCache) filterTxs(txs []*types.Transaction) []*types.Transaction {
+	unique := make([]*types.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if known, _ := c.txs.ContainsOrAdd(tx.Hash(), nil); !known {
+			unique = append(unique, tx)
+		}
+	}
+	knownTxUniqueMeter.Mark(int64(len(unique)))
+	knownTxDuplicateMeter.Mark(int64(len(txs) - len(unique)))
+	knownTxsGauge.Update(int64(c.txs.Len()))
+	return unique
+}
+
+// markBlock marks the block as known, returning whether it was not already.
+func (c *sharedKnownCache) markBlock(hash common.Hash) bool {
+	known, _ := c.blocks.ContainsOrAdd(hash, nil)
+	if known {
+		knownBlockDuplicateMeter.Mark(1)
+	} else {
+		knownBlockUniqueMeter.Mark(1)
+	}
+	knownBlocksGauge.Update(int64(c.blocks.Len()))
+	return !known
+}
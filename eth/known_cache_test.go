@@ -0,0 +1,64 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
+)
+
+// Tests that the shared known cache only lets through the first copy of every
+// broadcast and stays within its memory budget.
+func TestSharedKnownCache(t *testing.T) {
+	cache := newSharedKnownCache(1)
+
+	txs := make([]*types.Transaction, 4)
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil)
+	}
+	// The first copy of each transaction goes through, subsequent ones are dropped
+	if unique := cache.filterTxs(txs[:2]); len(unique) != 2 {
+		t.Fatalf("unique transactions mismatch: have %d, want %d", len(unique), 2)
+	}
+	unique := cache.filterTxs(txs)
+	if len(unique) != 2 || unique[0] != txs[2] || unique[1] != txs[3] {
+		t.Fatalf("unique transactions mismatch: have %v, want %v", unique, txs[2:])
+	}
+	if unique := cache.filterTxs(txs); len(unique) != 0 {
+		t.Fatalf("duplicate transactions let through: %d", len(unique))
+	}
+
+	hash := common.HexToHash("0x01")
+	if !cache.markBlock(hash) {
+		t.Fatalf("first block copy reported as known")
+	}
+	if cache.markBlock(hash) {
+		t.Fatalf("duplicate block copy reported as unknown")
+	}
+
+	// The number of hashes held is bounded by the budget
+	limit := 1024 * 1024 / knownEntrySize
+	for i := 0; i < 2*limit; i++ {
+		cache.markBlock(common.BigToHash(big.NewInt(int64(i + 2))))
+	}
+	if size := cache.txs.Len() + cache.blocks.Len(); size > limit {
+		t.Fatalf("known cache exceeds its budget: have %d entries, limit %d", size, limit)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/mclock"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+const (
+	// maxMempoolSyncHashes is the maximum number of pooled transaction hashes
+	// announced to a newly connected peer. It matches the announcements the
+	// remote tx fetcher tracks per peer, anything above would be dropped.
+	maxMempoolSyncHashes = 4096
+
+	// mempoolSyncCooldown is the minimum time between two mempool syncs with the
+	// same peer, so that reconnecting repeatedly doesn't make us resend the pool.
+	mempoolSyncCooldown = time.Minute
+
+	// mempoolSyncCacheTTL is how long the announced hash set is reused across
+	// peers, so that a burst of connections sorts the pool only once.
+	mempoolSyncCacheTTL = time.Second
+)
+
+var (
+	mempoolSyncSentMeter    = metrics.NewRegisteredMeter("eth/mempoolsync/sent", nil)
+	mempoolSyncSkippedMeter = metrics.NewRegisteredMeter("eth/mempoolsync/skipped", nil)
+)
+
+// mempoolSyncer rate limits the pooled transaction announcements made to newly
+// connected peers, letting restarted nodes repopulate their pool quickly without
+// turning connections into an amplification vector.
+type mempoolSyncer struct {
+	clock mclock.Clock
+
+	mu       sync.Mutex
+	synced   map[string]mclock.AbsTime // Last sync time of the recent peers
+	hashes   []common.Hash             // Cached announcement set
+	cachedAt mclock.AbsTime
+}
+
+func newMempoolSyncer(clock mclock.Clock) *mempoolSyncer {
+	return &mempoolSyncer{
+		clock:  clock,
+		synced: make(map[string]mclock.AbsTime),
+	}
+}
+
+// allow reports whether the given peer can be synced now, and records the sync.
+func (s *mempoolSyncer) allow(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if last, ok := s.synced[id]; ok && now.Sub(last) < mempoolSyncCooldown {
+		return false
+	}
+	for peer, last := range s.synced {
+		if now.Sub(last) >= mempoolSyncCooldown {
+			delete(s.synced, peer)
+		}
+	}
+	s.synced[id] = now
+	return true
+}
+
+// announcement returns the hashes to announce, assembling them with collect if
+// the cached set is stale.
+func (s *mempoolSyncer) announcement(collect func() []common.Hash) []common.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if s.hashes == nil || now.Sub(s.cachedAt) >= mempoolSyncCacheTTL {
+		s.hashes, s.cachedAt = collect(), now
+	}
+	return s.hashes
+}
+
+// selectMempoolSyncHashes picks up to limit hashes from the pending transactions,
+// taking them round robin across accounts in nonce order, so that a few busy
+// accounts can't crowd out the others and every account's executable prefix is
+// announced first. Hashes for which skip returns true are left out.
+func selectMempoolSyncHashes(pending map[common.Address]types.Transactions, limit int, skip func(common.Hash) bool) []common.Hash {
+	hashes := make([]common.Hash, 0)
+	for depth := 0; len(hashes) < limit; depth++ {
+		found := false
+		for _, txs := range pending {
+			if depth >= len(txs) {
+				continue
+			}
+			found = true
+			if hash := txs[depth].Hash(); skip == nil || !skip(hash) {
+				hashes = append(hashes, hash)
+				if len(hashes) == limit {
+					break
+				}
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return hashes
+}
@@ -0,0 +1,104 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/mclock"
+	"github.com/celo-org/celo-blockchain/core/types"
+)
+
+// Tests that the mempool sync announcement is capped and spread across accounts.
+func TestSelectMempoolSyncHashes(t *testing.T) {
+	pending := make(map[common.Address]types.Transactions)
+	for i := 0; i < 3; i++ {
+		account := common.BigToAddress(big.NewInt(int64(i)))
+		for nonce := 0; nonce < 10; nonce++ {
+			pending[account] = append(pending[account], types.NewTransaction(uint64(nonce), account, big.NewInt(int64(i)), 21000, big.NewInt(1), nil))
+		}
+	}
+	hashes := selectMempoolSyncHashes(pending, 7, nil)
+	if len(hashes) != 7 {
+		t.Fatalf("announced hashes mismatch: have %d, want %d", len(hashes), 7)
+	}
+	// The first two nonces of every account must be in, before any third one
+	for account, txs := range pending {
+		for _, tx := range txs[:2] {
+			if !containsHash(hashes, tx.Hash()) {
+				t.Errorf("account %x: nonce %d not announced", account, tx.Nonce())
+			}
+		}
+		for _, tx := range txs[3:] {
+			if containsHash(hashes, tx.Hash()) {
+				t.Errorf("account %x: nonce %d announced", account, tx.Nonce())
+			}
+		}
+	}
+	// Skipped hashes are left out, without reducing the announcement
+	skipped := pending[common.BigToAddress(big.NewInt(0))][0].Hash()
+	hashes = selectMempoolSyncHashes(pending, 30, func(hash common.Hash) bool { return hash == skipped })
+	if len(hashes) != 29 || containsHash(hashes, skipped) {
+		t.Errorf("skipped hash announced or announcement mismatch: have %d hashes, want %d", len(hashes), 29)
+	}
+}
+
+// Tests that a peer is synced at most once per cooldown, and that the announced
+// set is reused across peers for a short while.
+func TestMempoolSyncerLimits(t *testing.T) {
+	clock := new(mclock.Simulated)
+	syncer := newMempoolSyncer(clock)
+
+	if !syncer.allow("a") || !syncer.allow("b") {
+		t.Fatal("first sync refused")
+	}
+	clock.Run(mempoolSyncCooldown / 2)
+	if syncer.allow("a") {
+		t.Fatal("sync allowed within the cooldown")
+	}
+	clock.Run(mempoolSyncCooldown / 2)
+	if !syncer.allow("a") {
+		t.Fatal("sync refused after the cooldown")
+	}
+
+	collected := 0
+	collect := func() []common.Hash {
+		collected++
+		return []common.Hash{{byte(collected)}}
+	}
+	syncer.announcement(collect)
+	syncer.announcement(collect)
+	if collected != 1 {
+		t.Fatalf("announcement collected %d times within its ttl, want 1", collected)
+	}
+	clock.Run(mempoolSyncCacheTTL + time.Millisecond)
+	if hashes := syncer.announcement(collect); collected != 2 || hashes[0] != (common.Hash{2}) {
+		t.Fatalf("stale announcement reused")
+	}
+}
+
+func containsHash(hashes []common.Hash, hash common.Hash) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
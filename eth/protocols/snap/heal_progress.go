@@ -0,0 +1,112 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"time"
+)
+
+const (
+	// maxHealRequestsPerPeer is the maximum number of trie node heal requests in
+	// flight to a single peer.
+	maxHealRequestsPerPeer = 4
+
+	// healRateSamplePeriod is the minimum time between two heal rate samples.
+	healRateSamplePeriod = time.Second
+
+	// healRateSmoothing is the weight of the newest sample in the heal rate.
+	healRateSmoothing = 0.2
+)
+
+// HealProgress is the progress of the state healing phase of a snap sync.
+type HealProgress struct {
+	Healing bool // Whether the heal phase is running
+
+	HealedTrienodes     uint64 // Number of state trie nodes downloaded
+	HealedTrienodeBytes uint64 // Number of state trie bytes persisted to disk
+	HealedBytecodes     uint64 // Number of bytecodes downloaded
+	HealedBytecodeBytes uint64 // Number of bytecodes persisted to disk
+
+	HealingNodes uint64        // Number of trie nodes and bytecodes known to be missing
+	HealRate     float64       // Recent number of trie nodes and bytecodes healed per second
+	HealETA      time.Duration // Estimated time to heal the known missing nodes, 0 if unknown
+}
+
+// HealProgress returns the progress of the state healing phase.
+func (s *Syncer) HealProgress() HealProgress {
+	s.progressLock.RLock()
+	defer s.progressLock.RUnlock()
+	return s.healProgress
+}
+
+// updateHealProgress refreshes the heal progress reported outside of the sync
+// runloop. It must be called from the runloop.
+func (s *Syncer) updateHealProgress(healing bool) {
+	var (
+		now     = time.Now()
+		healed  = s.trienodeHealSynced + s.bytecodeHealSynced
+		pending uint64
+	)
+	if healing && s.healer != nil {
+		pending = uint64(s.healer.scheduler.Pending())
+	}
+	s.progressLock.Lock()
+	defer s.progressLock.Unlock()
+
+	progress := &s.healProgress
+	if !healing {
+		progress.Healing, progress.HealingNodes, progress.HealRate, progress.HealETA = false, 0, 0, 0
+		s.healRateTime = time.Time{}
+		return
+	}
+	progress.Healing = true
+	progress.HealedTrienodes, progress.HealedTrienodeBytes = s.trienodeHealSynced, uint64(s.trienodeHealBytes)
+	progress.HealedBytecodes, progress.HealedBytecodeBytes = s.bytecodeHealSynced, uint64(s.bytecodeHealBytes)
+	progress.HealingNodes = pending
+
+	// Sample the heal rate periodically, smoothing out the bursty deliveries
+	switch elapsed := now.Sub(s.healRateTime); {
+	case s.healRateTime.IsZero():
+		s.healRateTime, s.healRateCount = now, healed
+	case elapsed >= healRateSamplePeriod:
+		rate := float64(healed-s.healRateCount) / elapsed.Seconds()
+		if progress.HealRate == 0 {
+			progress.HealRate = rate
+		} else {
+			progress.HealRate = (1-healRateSmoothing)*progress.HealRate + healRateSmoothing*rate
+		}
+		s.healRateTime, s.healRateCount = now, healed
+	}
+	progress.HealETA = 0
+	if progress.HealRate > 0 {
+		progress.HealETA = time.Duration(float64(pending) / progress.HealRate * float64(time.Second))
+	}
+}
+
+// healRequestSlots returns the number of trie node heal requests a peer able to
+// deliver capacity items within the target round trip may have in flight. Peers
+// with latencies low enough to deliver more than a full request get extra ones.
+func healRequestSlots(capacity int) int {
+	slots := capacity / maxTrieRequestCount
+	if slots < 1 {
+		return 1
+	}
+	if slots > maxHealRequestsPerPeer {
+		return maxHealRequestsPerPeer
+	}
+	return slots
+}
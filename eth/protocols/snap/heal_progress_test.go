@@ -0,0 +1,60 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+)
+
+func TestHealRequestSlots(t *testing.T) {
+	for _, tt := range []struct {
+		capacity, slots int
+	}{
+		{1, 1}, {maxTrieRequestCount - 1, 1}, {2 * maxTrieRequestCount, 2}, {100 * maxTrieRequestCount, maxHealRequestsPerPeer},
+	} {
+		if have := healRequestSlots(tt.capacity); have != tt.slots {
+			t.Errorf("capacity %d: slots mismatch: have %d, want %d", tt.capacity, have, tt.slots)
+		}
+	}
+}
+
+func TestHealProgressRate(t *testing.T) {
+	s := NewSyncer(rawdb.NewMemoryDatabase())
+
+	s.updateHealProgress(true)
+	if progress := s.HealProgress(); !progress.Healing || progress.HealRate != 0 || progress.HealETA != 0 {
+		t.Fatalf("unexpected initial progress: %+v", progress)
+	}
+	// Pretend 100 nodes were healed over two seconds
+	s.healRateTime = s.healRateTime.Add(-2 * time.Second)
+	s.trienodeHealSynced, s.bytecodeHealSynced = 90, 10
+	s.updateHealProgress(true)
+	progress := s.HealProgress()
+	if progress.HealedTrienodes != 90 || progress.HealedBytecodes != 10 {
+		t.Errorf("healed counts mismatch: have %d/%d, want %d/%d", progress.HealedTrienodes, progress.HealedBytecodes, 90, 10)
+	}
+	if progress.HealRate < 49 || progress.HealRate > 51 {
+		t.Errorf("heal rate mismatch: have %v, want ~%v", progress.HealRate, 50)
+	}
+	s.updateHealProgress(false)
+	if progress := s.HealProgress(); progress.Healing || progress.HealRate != 0 {
+		t.Errorf("progress not reset after healing: %+v", progress)
+	}
+}
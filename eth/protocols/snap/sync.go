@@ -397,6 +397,9 @@ type Syncer struct {
 	healer  *healTask      // Current state healing task being executed
 	update  chan struct{}  // Notification channel for possible sync progression
 
+	verifier      StateVerifier // Verifier of the healed states, if any
+	verifications int           // Number of times the current state was healed again after a verification
+
 	peers    map[string]SyncPeer // Currently active peers to download from
 	peerJoin *event.Feed         // Event feed to react to peers joining
 	peerDrop *event.Feed         // Event feed to react to peers dropping
@@ -444,6 +447,11 @@ type Syncer struct {
 	startTime time.Time // Time instance when snapshot sync started
 	logTime   time.Time // Time instance when status was last reported
 
+	healProgress  HealProgress // Heal phase progress, as reported outside of the runloop
+	healRateTime  time.Time    // Time instance when the heal rate was last sampled
+	healRateCount uint64       // Number of healed items when the heal rate was last sampled
+	progressLock  sync.RWMutex // Protects the heal progress
+
 	pend sync.WaitGroup // Tracks network request goroutines for graceful shutdown
 	lock sync.RWMutex   // Protects fields that can change outside of sync (peers, reqs, root)
 }
@@ -548,6 +556,7 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 		codeTasks: make(map[common.Hash]struct{}),
 	}
 	s.statelessPeers = make(map[string]struct{})
+	s.verifications = 0
 	s.lock.Unlock()
 
 	if s.startTime == (time.Time{}) {
@@ -556,8 +565,13 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 	// Retrieve the previous sync status from LevelDB and abort if already synced
 	s.loadSyncStatus()
 	if len(s.tasks) == 0 && s.healer.scheduler.Pending() == 0 {
-		log.Debug("Snapshot sync already completed")
-		return nil
+		// The state may have been healed but not verified, or be missing parts
+		if complete, err := s.verifyState(); err != nil {
+			return err
+		} else if complete {
+			log.Debug("Snapshot sync already completed")
+			return nil
+		}
 	}
 	defer func() { // Persist any progress, independent of failure
 		for _, task := range s.tasks {
@@ -577,6 +591,7 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 		}
 	}()
 	defer s.report(true)
+	defer s.updateHealProgress(false)
 
 	// Whether sync completed or not, disregard any future packets
 	defer func() {
@@ -618,7 +633,9 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 		s.cleanStorageTasks()
 		s.cleanAccountTasks()
 		if len(s.tasks) == 0 && s.healer.scheduler.Pending() == 0 {
-			return nil
+			if complete, err := s.verifyState(); err != nil || complete {
+				return err
+			}
 		}
 		// Assign all the data retrieval tasks to any free peers
 		s.assignAccountTasks(accountResps, accountReqFails, cancel)
@@ -665,6 +682,7 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 		}
 		// Report stats if something meaningful happened
 		s.report(false)
+		s.updateHealProgress(len(s.tasks) == 0)
 	}
 }
 
@@ -1221,12 +1239,26 @@ func (s *Syncer) assignTrienodeHealTasks(success chan *trienodeHealResponse, fai
 		caps: make([]int, 0, len(s.trienodeHealIdlers)),
 	}
 	targetTTL := s.rates.TargetTimeout()
-	for id := range s.trienodeHealIdlers {
+
+	// Peers fast enough to serve more than a full request may get several ones
+	// in flight. Busy peers with nothing in flight timed out, skip them until
+	// their late delivery.
+	inflight := make(map[string]int)
+	for _, req := range s.trienodeHealReqs {
+		inflight[req.peer]++
+	}
+	for id := range s.peers {
 		if _, ok := s.statelessPeers[id]; ok {
 			continue
 		}
-		idlers.ids = append(idlers.ids, id)
-		idlers.caps = append(idlers.caps, s.rates.Capacity(id, TrieNodesMsg, targetTTL))
+		if _, idle := s.trienodeHealIdlers[id]; !idle && inflight[id] == 0 {
+			continue
+		}
+		cap := s.rates.Capacity(id, TrieNodesMsg, targetTTL)
+		for slot := inflight[id]; slot < healRequestSlots(cap); slot++ {
+			idlers.ids = append(idlers.ids, id)
+			idlers.caps = append(idlers.caps, cap)
+		}
 	}
 	if len(idlers.ids) == 0 {
 		return
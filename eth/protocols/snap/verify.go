@@ -0,0 +1,208 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/rlp"
+	"github.com/celo-org/celo-blockchain/trie"
+)
+
+const (
+	// maxStateVerifications is the number of times the parts of a state found
+	// missing by the StateVerifier are healed again before the sync fails.
+	maxStateVerifications = 3
+
+	// maxMissingNodes bounds the missing trie nodes of a trie reported at once,
+	// the nodes below them being healed along with them anyway.
+	maxMissingNodes = 1024
+)
+
+// errStateIncomplete is returned by a sync whose verified state is still missing
+// parts after maxStateVerifications heals.
+var errStateIncomplete = errors.New("verified state incomplete after healing")
+
+// MissingNode is a trie node or a code of a state missing from the database.
+type MissingNode struct {
+	Hash common.Hash
+	Path []byte // Hex path of a trie node, a storage trie node path starting with the one of its account
+	Code bool   // Whether the node is a contract code
+}
+
+// StateVerifier checks parts of the state synced to the given root once it is
+// healed, returning what it found missing from the database.
+type StateVerifier func(root common.Hash) ([]MissingNode, error)
+
+// SetStateVerifier sets the verifier of the synced states, which are complete
+// once healed and verified. The parts the verifier finds missing are healed
+// again, up to maxStateVerifications times.
+func (s *Syncer) SetStateVerifier(verifier StateVerifier) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.verifier = verifier
+}
+
+// verifyState runs the state verifier on the healed state, and schedules the
+// heal of the parts it found missing. It returns whether the state is complete.
+func (s *Syncer) verifyState() (bool, error) {
+	if s.verifier == nil {
+		return true, nil
+	}
+	for s.healer.scheduler.Pending() == 0 {
+		missing, err := s.verifier(s.root)
+		if err != nil {
+			return false, err
+		}
+		if len(missing) == 0 {
+			if s.verifications > 0 {
+				log.Info("Verified state healed", "root", s.root, "heals", s.verifications)
+			}
+			return true, nil
+		}
+		if s.verifications >= maxStateVerifications {
+			return false, fmt.Errorf("%w: %d items of %x missing after %d heals", errStateIncomplete, len(missing), s.root, s.verifications)
+		}
+		s.verifications++
+		log.Warn("Verified state incomplete, healing again", "root", s.root, "missing", len(missing), "heal", s.verifications)
+
+		// The parts already in the database aren't scheduled, and are reported
+		// again by the next verification if the verifier can't find them
+		for _, node := range missing {
+			if node.Code {
+				s.healer.scheduler.AddCodeEntry(node.Hash, node.Path, common.Hash{})
+			} else {
+				state.AddStateSubTrie(s.healer.scheduler, node.Hash, node.Path, s.onHealState)
+			}
+		}
+	}
+	return false, nil
+}
+
+// MissingAccountState returns the account with the given hash in the state with
+// the given root, and the parts of its state missing from the database: the
+// account trie nodes leading to it, its code and its storage trie nodes. The
+// account is nil if it doesn't exist or can't be reached.
+func MissingAccountState(db ethdb.KeyValueStore, root common.Hash, account common.Hash) (*types.StateAccount, []MissingNode, error) {
+	triedb := trie.NewDatabase(db)
+	accTrie, err := trie.New(root, triedb)
+	if err != nil {
+		missing, err := missingNode(err, nil)
+		return nil, missing, err
+	}
+	blob, err := accTrie.TryGet(account[:])
+	if err != nil {
+		missing, err := missingNode(err, nil)
+		return nil, missing, err
+	}
+	if len(blob) == 0 {
+		return nil, nil, nil
+	}
+	acc := new(types.StateAccount)
+	if err := rlp.DecodeBytes(blob, acc); err != nil {
+		return nil, nil, err
+	}
+	var (
+		missing []MissingNode
+		path    = keyToHex(account[:])
+	)
+	if codeHash := common.BytesToHash(acc.CodeHash); codeHash != emptyCode && len(rawdb.ReadCodeWithPrefix(db, codeHash)) == 0 {
+		missing = append(missing, MissingNode{Hash: codeHash, Path: path, Code: true})
+	}
+	if acc.Root == emptyRoot {
+		return acc, missing, nil
+	}
+	nodes, err := missingTrieNodes(triedb, acc.Root, path)
+	return acc, append(missing, nodes...), err
+}
+
+// missingNode returns the missing node reported by a trie error, prefixed with
+// the path of its trie.
+func missingNode(err error, prefix []byte) ([]MissingNode, error) {
+	var missing *trie.MissingNodeError
+	if !errors.As(err, &missing) {
+		return nil, err
+	}
+	return []MissingNode{{Hash: missing.NodeHash, Path: append(common.CopyBytes(prefix), missing.Path...)}}, nil
+}
+
+// missingTrieNodes iterates over the trie with the given root, and returns its
+// nodes missing from the database, up to maxMissingNodes. The iteration resumes
+// after the subtrie of every missing node.
+func missingTrieNodes(triedb *trie.Database, root common.Hash, prefix []byte) ([]MissingNode, error) {
+	tr, err := trie.New(root, triedb)
+	if err != nil {
+		return missingNode(err, prefix)
+	}
+	var (
+		missing []MissingNode
+		start   []byte
+	)
+	for len(missing) < maxMissingNodes {
+		iter := tr.NodeIterator(start)
+		for iter.Next(true) {
+		}
+		if iter.Error() == nil {
+			break
+		}
+		node, err := missingNode(iter.Error(), prefix)
+		if err != nil {
+			return missing, err
+		}
+		missing = append(missing, node...)
+		if start = nextSubtrieKey(node[0].Path[len(prefix):]); start == nil {
+			break
+		}
+	}
+	return missing, nil
+}
+
+// nextSubtrieKey returns the first key after the ones of the subtrie with the
+// given hex path, nil if there is none.
+func nextSubtrieKey(path []byte) []byte {
+	next := common.CopyBytes(path)
+	for len(next) > 0 && next[len(next)-1] == 0x0f {
+		next = next[:len(next)-1]
+	}
+	if len(next) == 0 {
+		return nil
+	}
+	next[len(next)-1]++
+
+	key := make([]byte, common.HashLength)
+	for i, nibble := range next {
+		key[i/2] |= nibble << (4 * (1 - i%2))
+	}
+	return key
+}
+
+// keyToHex returns the hex path of a key.
+func keyToHex(key []byte) []byte {
+	path := make([]byte, 2*len(key))
+	for i, b := range key {
+		path[2*i], path[2*i+1] = b>>4, b&0x0f
+	}
+	return path
+}
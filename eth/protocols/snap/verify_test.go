@@ -0,0 +1,130 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/rlp"
+	"github.com/celo-org/celo-blockchain/trie"
+)
+
+func TestNextSubtrieKey(t *testing.T) {
+	for _, tt := range []struct {
+		path, key []byte
+	}{
+		{nil, nil},
+		{[]byte{0x0f, 0x0f}, nil},
+		{[]byte{0x01}, append([]byte{0x20}, make([]byte, 31)...)},
+		{[]byte{0x01, 0x02}, append([]byte{0x13}, make([]byte, 31)...)},
+		{[]byte{0x01, 0x0f, 0x0f}, append([]byte{0x20}, make([]byte, 31)...)},
+		{[]byte{0x01, 0x02, 0x03}, append([]byte{0x12, 0x40}, make([]byte, 30)...)},
+	} {
+		if have := nextSubtrieKey(tt.path); !bytes.Equal(have, tt.key) {
+			t.Errorf("path %x: key mismatch: have %x, want %x", tt.path, have, tt.key)
+		}
+	}
+}
+
+// TestSyncHealsVerifiedState tests that the parts of a synced state found
+// missing by the state verifier are healed again.
+func TestSyncHealsVerifiedState(t *testing.T) {
+	t.Parallel()
+
+	var (
+		once   sync.Once
+		cancel = make(chan struct{})
+		term   = func() {
+			once.Do(func() {
+				close(cancel)
+			})
+		}
+	)
+	sourceAccountTrie, elems, storageTries, storageElems := makeAccountTrieWithStorage(3, 300, true, false)
+
+	source := newTestPeer("source", t, term)
+	source.accountTrie = sourceAccountTrie
+	source.accountValues = elems
+	source.storageTries = storageTries
+	source.storageValues = storageElems
+
+	syncer := setupSyncer(source)
+	root := sourceAccountTrie.Hash()
+	if err := syncer.Sync(root, cancel); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	// Lose the code and two storage trie nodes of different subtries of an account
+	account := common.BytesToHash(elems[0].k)
+	var acc types.StateAccount
+	if err := rlp.DecodeBytes(elems[0].v, &acc); err != nil {
+		t.Fatal(err)
+	}
+	rawdb.DeleteCode(syncer.db, common.BytesToHash(acc.CodeHash))
+	storageTrie, _ := trie.New(acc.Root, trie.NewDatabase(syncer.db))
+	lost := make(map[common.Hash]bool)
+	for iter := storageTrie.NodeIterator(nil); iter.Next(true) && len(lost) < 2; {
+		if len(iter.Path()) == 1 && iter.Hash() != (common.Hash{}) && (len(lost) == 0 || iter.Path()[0] > 4) {
+			lost[iter.Hash()] = true
+		}
+	}
+	for hash := range lost {
+		rawdb.DeleteTrieNode(syncer.db, hash)
+	}
+	_, missing, err := MissingAccountState(syncer.db, root, account)
+	if err != nil {
+		t.Fatalf("failed to verify account: %v", err)
+	}
+	if len(missing) != 3 || !missing[0].Code {
+		t.Fatalf("missing state mismatch: have %v, want the code and 2 trie nodes", missing)
+	}
+	for _, node := range missing[1:] {
+		if !lost[node.Hash] || !bytes.Equal(node.Path[:2*common.HashLength], keyToHex(account[:])) {
+			t.Errorf("missing node mismatch: have %x at %x", node.Hash, node.Path)
+		}
+	}
+	// Healed again once verified
+	var verifications int
+	syncer.SetStateVerifier(func(root common.Hash) ([]MissingNode, error) {
+		verifications++
+		_, missing, err := MissingAccountState(syncer.db, root, account)
+		return missing, err
+	})
+	if err := syncer.Sync(root, cancel); err != nil {
+		t.Fatalf("verified sync failed: %v", err)
+	}
+	if verifications != 2 {
+		t.Errorf("verifications mismatch: have %d, want 2", verifications)
+	}
+	if _, missing, _ := MissingAccountState(syncer.db, root, account); len(missing) != 0 {
+		t.Errorf("state still missing %v", missing)
+	}
+	verifyTrie(syncer.db, root, t)
+
+	// Fails when the verified state can't be healed
+	syncer.SetStateVerifier(func(root common.Hash) ([]MissingNode, error) {
+		return []MissingNode{{Hash: root}}, nil
+	})
+	if err := syncer.Sync(root, cancel); !errors.Is(err, errStateIncomplete) {
+		t.Errorf("error mismatch: have %v, want %v", err, errStateIncomplete)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/proxy"
+)
+
+var (
+	errProxyNotEthPeer     = errors.New("proxy not peered over the eth protocol")
+	errProxyHeadNotRelayed = errors.New("proxy head not announced")
+)
+
+// proxyHealthProbe returns the probe failing the proxies whose head, announced
+// over the eth protocol, lags more than maxLag blocks behind the head of the
+// chain. Proxies relaying blocks late relay the consensus messages late too.
+func (s *Ethereum) proxyHealthProbe(maxLag uint64) proxy.HealthProbe {
+	return func(p *proxy.Proxy) error {
+		peer := s.handler.peers.peer(p.ID().String())
+		if peer == nil {
+			return errProxyNotEthPeer
+		}
+		// Every Istanbul block adds one to the total difficulty
+		_, td := peer.Head()
+		if td == nil || td.Sign() <= 0 {
+			return errProxyHeadNotRelayed
+		}
+		head := td.Uint64() - 1
+		if current := s.blockchain.CurrentHeader().Number.Uint64(); current > head+maxLag {
+			return fmt.Errorf("proxy head %d lags %d blocks behind", head, current-head)
+		}
+		return nil
+	}
+}
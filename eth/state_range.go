@@ -0,0 +1,381 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state/snapshot"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/light"
+	"github.com/celo-org/celo-blockchain/rlp"
+	"github.com/celo-org/celo-blockchain/rpc"
+	"github.com/celo-org/celo-blockchain/trie"
+	"golang.org/x/time/rate"
+)
+
+// StateRangeMaxResults is the maximum number of accounts or storage slots
+// returned per page by the state range iterators.
+const StateRangeMaxResults = 1024
+
+// stateRangeLimiter caps the accounts and storage slots served per second by the
+// state range APIs, so that walking the whole state doesn't stall the node.
+type stateRangeLimiter struct {
+	limiter *rate.Limiter // Nil if the rate is unlimited
+}
+
+func newStateRangeLimiter(perSecond uint64) *stateRangeLimiter {
+	if perSecond == 0 {
+		return &stateRangeLimiter{}
+	}
+	return &stateRangeLimiter{limiter: rate.NewLimiter(rate.Limit(perSecond), StateRangeMaxResults)}
+}
+
+// wait blocks until n more entries may be served, or the context is done.
+func (l *stateRangeLimiter) wait(ctx context.Context, n int) error {
+	if l == nil || l.limiter == nil {
+		return nil
+	}
+	return l.limiter.WaitN(ctx, n)
+}
+
+// stateRangeIterator iterates over the accounts of a state, or the storage slots
+// of an account, by hash.
+type stateRangeIterator interface {
+	Next() bool
+	Hash() common.Hash
+	Value() ([]byte, error) // Consensus RLP of the account, or RLP of the slot value
+	Error() error
+	Release()
+}
+
+type snapAccountIterator struct{ snapshot.AccountIterator }
+
+func (it snapAccountIterator) Value() ([]byte, error) {
+	return snapshot.FullAccountRLP(it.Account())
+}
+
+type snapStorageIterator struct{ snapshot.StorageIterator }
+
+func (it snapStorageIterator) Value() ([]byte, error) { return it.Slot(), nil }
+
+type trieRangeIterator struct{ it *trie.Iterator }
+
+func (it trieRangeIterator) Next() bool             { return it.it.Next() }
+func (it trieRangeIterator) Hash() common.Hash      { return common.BytesToHash(it.it.Key) }
+func (it trieRangeIterator) Value() ([]byte, error) { return it.it.Value, nil }
+func (it trieRangeIterator) Error() error           { return it.it.Err }
+func (it trieRangeIterator) Release()               {}
+
+// AccountRangePage is a page of the accounts of a state, ordered by hash.
+type AccountRangePage struct {
+	Root     common.Hash     `json:"root"`
+	Accounts []*RangeAccount `json:"accounts"`
+	Next     *common.Hash    `json:"next"`            // Cursor of the next page, nil after the last account
+	Proof    []hexutil.Bytes `json:"proof,omitempty"` // Merkle proof of the cursor and the last account, if requested
+}
+
+// RangeAccount is an account of a state range page.
+type RangeAccount struct {
+	Hash     common.Hash     `json:"hash"`
+	Address  *common.Address `json:"address,omitempty"` // Preimage of the hash, if known
+	Nonce    uint64          `json:"nonce"`
+	Balance  *hexutil.Big    `json:"balance"`
+	Root     common.Hash     `json:"storageRoot"`
+	CodeHash common.Hash     `json:"codeHash"`
+}
+
+// StorageRangePage is a page of the storage slots of an account, ordered by hash.
+type StorageRangePage struct {
+	Root    common.Hash     `json:"root"`
+	Account common.Address  `json:"account"`
+	Slots   []*RangeSlot    `json:"slots"`
+	Next    *common.Hash    `json:"next"`            // Cursor of the next page, nil after the last slot
+	Proof   []hexutil.Bytes `json:"proof,omitempty"` // Merkle proof of the cursor and the last slot, if requested
+}
+
+// RangeSlot is a storage slot of a storage range page.
+type RangeSlot struct {
+	Hash  common.Hash   `json:"hash"`
+	Key   *common.Hash  `json:"key,omitempty"` // Preimage of the hash, if known
+	Value hexutil.Bytes `json:"value"`
+}
+
+// IterateAccounts returns a page of at most limit accounts of the state of a
+// block, from the cursor returned with the previous page on. Pages are served
+// from the snapshot, or from the trie if the snapshot doesn't cover the state,
+// within the rate set by --rpc.staterangerate. Walking a whole state should use
+// the hash of its block, staying on the same root from the first page to the last.
+func (api *PrivateDebugAPI) IterateAccounts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, cursor *common.Hash, limit int, proof bool) (*AccountRangePage, error) {
+	root, err := api.eth.stateRangeRoot(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	limit = clampStateRangeLimit(limit)
+	if err := api.eth.stateRange.wait(ctx, limit); err != nil {
+		return nil, err
+	}
+	var origin common.Hash
+	if cursor != nil {
+		origin = *cursor
+	}
+	return api.eth.stateRangeReader().accounts(root, origin, limit, proof)
+}
+
+// IterateStorage returns a page of at most limit storage slots of an account in
+// the state of a block, from the cursor returned with the previous page on, like
+// IterateAccounts.
+func (api *PrivateDebugAPI) IterateStorage(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, address common.Address, cursor *common.Hash, limit int, proof bool) (*StorageRangePage, error) {
+	root, err := api.eth.stateRangeRoot(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	limit = clampStateRangeLimit(limit)
+	if err := api.eth.stateRange.wait(ctx, limit); err != nil {
+		return nil, err
+	}
+	var origin common.Hash
+	if cursor != nil {
+		origin = *cursor
+	}
+	return api.eth.stateRangeReader().storage(root, address, origin, limit, proof)
+}
+
+// stateRangeReader builds the pages of the state range iterators.
+type stateRangeReader struct {
+	db     ethdb.KeyValueReader // Database of the preimages
+	triedb *trie.Database
+	snaps  *snapshot.Tree // Nil if the snapshot is disabled
+}
+
+func (s *Ethereum) stateRangeReader() *stateRangeReader {
+	return &stateRangeReader{db: s.chainDb, triedb: s.blockchain.StateCache().TrieDB(), snaps: s.blockchain.Snapshots()}
+}
+
+// accounts returns the page of at most limit accounts of the state of root
+// from origin on.
+func (r *stateRangeReader) accounts(root, origin common.Hash, limit int, proof bool) (*AccountRangePage, error) {
+	var it stateRangeIterator
+	if r.snaps != nil {
+		if snapIt, err := r.snaps.AccountIterator(root, origin); err == nil {
+			it = snapAccountIterator{snapIt}
+		}
+	}
+	if it == nil {
+		tr, err := trie.New(root, r.triedb)
+		if err != nil {
+			return nil, err
+		}
+		it = trieRangeIterator{trie.NewIterator(tr.NodeIterator(origin[:]))}
+	}
+	defer it.Release()
+
+	page := &AccountRangePage{Root: root, Accounts: []*RangeAccount{}}
+	for it.Next() {
+		hash := it.Hash()
+		if len(page.Accounts) == limit {
+			page.Next = &hash
+			break
+		}
+		data, err := it.Value()
+		if err != nil {
+			return nil, err
+		}
+		var account types.StateAccount
+		if err := rlp.DecodeBytes(data, &account); err != nil {
+			return nil, fmt.Errorf("invalid account %x: %v", hash, err)
+		}
+		entry := &RangeAccount{
+			Hash:     hash,
+			Nonce:    account.Nonce,
+			Balance:  (*hexutil.Big)(account.Balance),
+			Root:     account.Root,
+			CodeHash: common.BytesToHash(account.CodeHash),
+		}
+		if preimage := rawdb.ReadPreimage(r.db, hash); len(preimage) == common.AddressLength {
+			address := common.BytesToAddress(preimage)
+			entry.Address = &address
+		}
+		page.Accounts = append(page.Accounts, entry)
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	if proof {
+		last := common.Hash{}
+		if n := len(page.Accounts); n > 0 {
+			last = page.Accounts[n-1].Hash
+		}
+		var err error
+		if page.Proof, err = r.prove(root, origin, last); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// storage returns the page of at most limit storage slots of an account in the
+// state of root from origin on.
+func (r *stateRangeReader) storage(root common.Hash, address common.Address, origin common.Hash, limit int, proof bool) (*StorageRangePage, error) {
+	accountHash := crypto.Keccak256Hash(address[:])
+
+	var it stateRangeIterator
+	if r.snaps != nil {
+		if snapIt, err := r.snaps.StorageIterator(root, accountHash, origin); err == nil {
+			it = snapStorageIterator{snapIt}
+		}
+	}
+	var storageRoot common.Hash
+	if it == nil || proof {
+		var err error
+		if storageRoot, err = r.storageRoot(root, accountHash); err != nil {
+			if it != nil {
+				it.Release()
+			}
+			return nil, err
+		}
+	}
+	if it == nil {
+		tr, err := trie.New(storageRoot, r.triedb)
+		if err != nil {
+			return nil, err
+		}
+		it = trieRangeIterator{trie.NewIterator(tr.NodeIterator(origin[:]))}
+	}
+	defer it.Release()
+
+	page := &StorageRangePage{Root: root, Account: address, Slots: []*RangeSlot{}}
+	for it.Next() {
+		hash := it.Hash()
+		if len(page.Slots) == limit {
+			page.Next = &hash
+			break
+		}
+		data, err := it.Value()
+		if err != nil {
+			return nil, err
+		}
+		_, content, _, err := rlp.Split(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage slot %x: %v", hash, err)
+		}
+		slot := &RangeSlot{Hash: hash, Value: common.CopyBytes(content)}
+		if preimage := rawdb.ReadPreimage(r.db, hash); len(preimage) == common.HashLength {
+			key := common.BytesToHash(preimage)
+			slot.Key = &key
+		}
+		page.Slots = append(page.Slots, slot)
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	if proof {
+		last := common.Hash{}
+		if n := len(page.Slots); n > 0 {
+			last = page.Slots[n-1].Hash
+		}
+		var err error
+		if page.Proof, err = r.prove(storageRoot, origin, last); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// clampStateRangeLimit returns the number of entries to serve in a page.
+func clampStateRangeLimit(limit int) int {
+	if limit <= 0 || limit > StateRangeMaxResults {
+		return StateRangeMaxResults
+	}
+	return limit
+}
+
+// stateRangeRoot returns the state root of a block. The pending state isn't
+// iterable, it's neither in the snapshot nor committed to the trie database.
+func (s *Ethereum) stateRangeRoot(blockNrOrHash rpc.BlockNumberOrHash) (common.Hash, error) {
+	var header *types.Header
+	if number, ok := blockNrOrHash.Number(); ok {
+		switch number {
+		case rpc.PendingBlockNumber:
+			return common.Hash{}, errors.New("the pending state can't be iterated")
+		case rpc.LatestBlockNumber:
+			header = s.blockchain.CurrentHeader()
+		default:
+			header = s.blockchain.GetHeaderByNumber(uint64(number))
+		}
+		if header == nil {
+			return common.Hash{}, fmt.Errorf("block #%d not found", number)
+		}
+	} else if hash, ok := blockNrOrHash.Hash(); ok {
+		if header = s.blockchain.GetHeaderByHash(hash); header == nil {
+			return common.Hash{}, fmt.Errorf("block %s not found", hash.Hex())
+		}
+	} else {
+		return common.Hash{}, errors.New("either block number or block hash must be specified")
+	}
+	return header.Root, nil
+}
+
+// storageRoot returns the storage root of an account in the state of root.
+func (r *stateRangeReader) storageRoot(root common.Hash, accountHash common.Hash) (common.Hash, error) {
+	tr, err := trie.New(root, r.triedb)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	data, err := tr.TryGet(accountHash[:])
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(data) == 0 {
+		return types.EmptyRootHash, nil
+	}
+	var account types.StateAccount
+	if err := rlp.DecodeBytes(data, &account); err != nil {
+		return common.Hash{}, err
+	}
+	return account.Root, nil
+}
+
+// prove returns the Merkle proof of the first and last keys of a range of the
+// trie of root, the nodes proving both being merged.
+func (r *stateRangeReader) prove(root common.Hash, first, last common.Hash) ([]hexutil.Bytes, error) {
+	tr, err := trie.New(root, r.triedb)
+	if err != nil {
+		return nil, err
+	}
+	nodes := light.NewNodeSet()
+	if err := tr.Prove(first[:], 0, nodes); err != nil {
+		return nil, err
+	}
+	if last != (common.Hash{}) {
+		if err := tr.Prove(last[:], 0, nodes); err != nil {
+			return nil, err
+		}
+	}
+	proof := []hexutil.Bytes{}
+	for _, node := range nodes.NodeList() {
+		proof = append(proof, hexutil.Bytes(node))
+	}
+	return proof, nil
+}
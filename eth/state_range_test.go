@@ -0,0 +1,169 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/state/snapshot"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/ethdb/memorydb"
+	"github.com/celo-org/celo-blockchain/trie"
+)
+
+// newStateRangeTest creates a state of count accounts, the first one with count
+// storage slots, and returns its root.
+func newStateRangeTest(t *testing.T, count int) (*stateRangeReader, common.Hash, common.Address) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		statedb = state.NewDatabaseWithConfig(db, &trie.Config{Preimages: true})
+		sdb, _  = state.New(common.Hash{}, statedb, nil)
+		owner   = common.Address{0xff}
+	)
+	sdb.SetNonce(owner, 1)
+	for i := 0; i < count; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		sdb.SetBalance(addr, big.NewInt(int64(i+1)))
+		sdb.SetState(owner, common.BigToHash(big.NewInt(int64(i+1))), common.Hash{0x01})
+	}
+	root, err := sdb.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := statedb.TrieDB().Commit(root, false, nil); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	return &stateRangeReader{db: db, triedb: statedb.TrieDB()}, root, owner
+}
+
+func TestStateRangeAccounts(t *testing.T) {
+	reader, root, owner := newStateRangeTest(t, 10)
+
+	walk := func(limit int) []*RangeAccount {
+		var (
+			accounts []*RangeAccount
+			cursor   common.Hash
+		)
+		for {
+			page, err := reader.accounts(root, cursor, limit, false)
+			if err != nil {
+				t.Fatalf("failed to iterate accounts: %v", err)
+			}
+			if len(page.Accounts) > limit {
+				t.Fatalf("page too large: have %d accounts, want at most %d", len(page.Accounts), limit)
+			}
+			accounts = append(accounts, page.Accounts...)
+			if page.Next == nil {
+				return accounts
+			}
+			cursor = *page.Next
+		}
+	}
+	// The trie and the snapshot serve the same accounts, page by page
+	fromTrie := walk(3)
+	if len(fromTrie) != 11 {
+		t.Fatalf("account count mismatch: have %d, want 11", len(fromTrie))
+	}
+	for i := 1; i < len(fromTrie); i++ {
+		if bytes.Compare(fromTrie[i-1].Hash[:], fromTrie[i].Hash[:]) >= 0 {
+			t.Fatalf("accounts out of order at %d", i)
+		}
+	}
+	for _, account := range fromTrie {
+		if account.Address == nil || crypto.Keccak256Hash(account.Address[:]) != account.Hash {
+			t.Fatalf("missing address preimage of %x", account.Hash)
+		}
+		if *account.Address == owner && account.Root == (common.Hash{}) {
+			t.Fatalf("missing storage root of %x", owner)
+		}
+	}
+	snaps, err := snapshot.New(memorydb.New(), reader.triedb, 16, root, false, true, false)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	reader.snaps = snaps
+	fromSnap := walk(4)
+	if len(fromSnap) != len(fromTrie) {
+		t.Fatalf("snapshot account count mismatch: have %d, want %d", len(fromSnap), len(fromTrie))
+	}
+	for i := range fromSnap {
+		if fromSnap[i].Hash != fromTrie[i].Hash || fromSnap[i].Balance.ToInt().Cmp(fromTrie[i].Balance.ToInt()) != 0 || fromSnap[i].Root != fromTrie[i].Root {
+			t.Fatalf("snapshot account %d mismatch: have %+v, want %+v", i, fromSnap[i], fromTrie[i])
+		}
+	}
+	// Pages are provable against the state root
+	page, err := reader.accounts(root, common.Hash{}, 5, true)
+	if err != nil {
+		t.Fatalf("failed to iterate accounts: %v", err)
+	}
+	proof := memorydb.New()
+	for _, node := range page.Proof {
+		proof.Put(crypto.Keccak256(node), node)
+	}
+	last := page.Accounts[len(page.Accounts)-1].Hash
+	if _, err := trie.VerifyProof(root, last[:], proof); err != nil {
+		t.Fatalf("invalid proof of the last account: %v", err)
+	}
+}
+
+func TestStateRangeStorage(t *testing.T) {
+	reader, root, owner := newStateRangeTest(t, 7)
+
+	page, err := reader.storage(root, owner, common.Hash{}, 5, true)
+	if err != nil {
+		t.Fatalf("failed to iterate storage: %v", err)
+	}
+	if len(page.Slots) != 5 || page.Next == nil || len(page.Proof) == 0 {
+		t.Fatalf("first page mismatch: have %d slots, next %v, %d proof nodes", len(page.Slots), page.Next, len(page.Proof))
+	}
+	for _, slot := range page.Slots {
+		if slot.Key == nil || crypto.Keccak256Hash(slot.Key[:]) != slot.Hash {
+			t.Fatalf("missing key preimage of %x", slot.Hash)
+		}
+		if want := (common.Hash{0x01}); !bytes.Equal(slot.Value, want[:]) {
+			t.Fatalf("slot value mismatch: have %v, want %v", slot.Value, want)
+		}
+	}
+	page, err = reader.storage(root, owner, *page.Next, 5, false)
+	if err != nil {
+		t.Fatalf("failed to iterate storage: %v", err)
+	}
+	if len(page.Slots) != 2 || page.Next != nil {
+		t.Fatalf("last page mismatch: have %d slots, next %v", len(page.Slots), page.Next)
+	}
+	// Accounts without storage have empty pages
+	page, err = reader.storage(root, common.Address{0xee}, common.Hash{}, 5, false)
+	if err != nil {
+		t.Fatalf("failed to iterate storage of a missing account: %v", err)
+	}
+	if len(page.Slots) != 0 || page.Next != nil {
+		t.Fatalf("missing account page mismatch: have %d slots, next %v", len(page.Slots), page.Next)
+	}
+}
+
+func TestClampStateRangeLimit(t *testing.T) {
+	for limit, want := range map[int]int{0: StateRangeMaxResults, -1: StateRangeMaxResults, 10: 10, StateRangeMaxResults + 1: StateRangeMaxResults} {
+		if have := clampStateRangeLimit(limit); have != want {
+			t.Errorf("limit %d: have %d, want %d", limit, have, want)
+		}
+	}
+}
@@ -23,7 +23,6 @@ import (
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
-	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/eth/downloader"
 	"github.com/celo-org/celo-blockchain/eth/protocols/eth"
 	"github.com/celo-org/celo-blockchain/log"
@@ -34,29 +33,30 @@ const (
 	defaultMinSyncPeers = 5                // Amount of peers desired to start syncing
 )
 
-// syncTransactions starts sending all currently pending transactions to the given peer.
+// syncTransactions announces the currently pending transactions to the given
+// peer, so that a freshly (re)started node can quickly repopulate its pool. The
+// announcement is capped and a peer is synced at most once per cooldown.
 func (h *handler) syncTransactions(p *eth.Peer) {
-	// Assemble the set of transaction to broadcast or announce to the remote
-	// peer. Fun fact, this is quite an expensive operation as it needs to sort
-	// the transactions if the sorting is not cached yet. However, with a random
-	// order, insertions could overflow the non-executable queues and get dropped.
-	//
-	// TODO(karalabe): Figure out if we could get away with random order somehow
-	var txs types.Transactions
-	pending, _ := h.txpool.Pending(false)
-	for _, batch := range pending {
-		txs = append(txs, batch...)
-	}
-	if len(txs) == 0 {
+	if !h.mempoolSync.allow(p.ID()) {
+		mempoolSyncSkippedMeter.Mark(1)
 		return
 	}
 	// The eth/65 (celo/66) protocol introduces proper transaction announcements, so instead
-	// of dripping transactions across multiple peers, just send the entire list as
-	// an announcement and let the remote side decide what they need (likely nothing).
-	hashes := make([]common.Hash, len(txs))
-	for i, tx := range txs {
-		hashes[i] = tx.Hash()
+	// of dripping transactions across multiple peers, just send the list as an
+	// announcement and let the remote side decide what they need (likely nothing).
+	hashes := h.mempoolSync.announcement(func() []common.Hash {
+		// Fun fact, this is quite an expensive operation as it needs to sort the
+		// transactions if the sorting is not cached yet, hence the cached result.
+		pending, _ := h.txpool.Pending(false)
+		return selectMempoolSyncHashes(pending, maxMempoolSyncHashes, func(hash common.Hash) bool {
+			// Local transactions in their stem phase are only known to their stem peer
+			return h.stem != nil && h.stem.inStem(hash)
+		})
+	})
+	if len(hashes) == 0 {
+		return
 	}
+	mempoolSyncSentMeter.Mark(int64(len(hashes)))
 	p.AsyncSendPooledTransactionHashes(hashes)
 }
 
@@ -0,0 +1,52 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	istanbulBackend "github.com/celo-org/celo-blockchain/consensus/istanbul/backend"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+// importSyncCheckpoint bootstraps the empty chain in db from the last epoch of
+// the epoch snapshots file, for the node to fast sync from it. Restarting with
+// the checkpoint the chain was bootstrapped from is a no-op.
+func importSyncCheckpoint(db ethdb.Database, chainConfig *params.ChainConfig, file string) error {
+	if chainConfig.Istanbul == nil {
+		return errors.New("sync checkpoints require istanbul consensus")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read sync checkpoint: %v", err)
+	}
+	var snapshots istanbulBackend.EpochSnapshots
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return fmt.Errorf("invalid sync checkpoint: %v", err)
+	}
+	head, err := istanbulBackend.ImportSyncCheckpoint(db, chainConfig.Istanbul.Epoch, &snapshots)
+	if err != nil {
+		return fmt.Errorf("failed to import sync checkpoint: %v", err)
+	}
+	log.Info("Bootstrapped chain from sync checkpoint", "number", head.Number, "hash", head.Hash(), "file", file)
+	return nil
+}
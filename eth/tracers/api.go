@@ -130,6 +130,22 @@ func (context *chainContext) Config() *params.ChainConfig {
 	return context.api.backend.ChainConfig()
 }
 
+func (context *chainContext) CurrentHeader() *types.Header {
+	header, err := context.api.backend.HeaderByNumber(context.ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil
+	}
+	return header
+}
+
+func (context *chainContext) GetHeaderByHash(hash common.Hash) *types.Header {
+	header, err := context.api.backend.HeaderByHash(context.ctx, hash)
+	if err != nil {
+		return nil
+	}
+	return header
+}
+
 // chainContext construts the context reader which is used by the evm for reading
 // the necessary chain context.
 func (api *API) chainContext(ctx context.Context) core.ChainContext {
@@ -184,16 +200,20 @@ type TraceConfig struct {
 	Tracer  *string
 	Timeout *string
 	Reexec  *uint64
+	// SystemCalls also traces the core contract calls made while processing the
+	// blocks, in debug_traceBlock*.
+	SystemCalls bool
 }
 
-// TraceCallConfig is the config for traceCall API. It holds one more
-// field to override the state for tracing.
+// TraceCallConfig is the config for traceCall API. It holds more fields to
+// override the state and the values read from the core contracts for tracing.
 type TraceCallConfig struct {
 	*vm.LogConfig
 	Tracer         *string
 	Timeout        *string
 	Reexec         *uint64
 	StateOverrides *ethapi.StateOverride
+	CeloOverrides  *ethapi.CeloOverrides
 }
 
 // StdTraceConfig holds extra parameters to standard-json trace functions.
@@ -205,9 +225,11 @@ type StdTraceConfig struct {
 
 // txTraceResult is the result of a single transaction trace.
 type txTraceResult struct {
-	TxHash common.Hash `json:"txHash"`           // transaction hash
-	Result interface{} `json:"result,omitempty"` // Trace results produced by the tracer
-	Error  string      `json:"error,omitempty"`  // Trace failure produced by the tracer
+	TxHash        common.Hash              `json:"txHash"`                // transaction hash
+	Result        interface{}              `json:"result,omitempty"`      // Trace results produced by the tracer
+	Error         string                   `json:"error,omitempty"`       // Trace failure produced by the tracer
+	SystemCalls   []*systemCallTraceResult `json:"systemCalls,omitempty"` // Core contract calls made for the transaction, if traced
+	SchemaVersion uint64                   `json:"schemaVersion"`         // Version of the layout of Result, see SchemaVersion
 }
 
 // blockTraceTask represents a single block trace task when an entire chain is
@@ -309,7 +331,7 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 				isEspresso := api.backend.ChainConfig().IsEspresso(blockCtx.BlockNumber)
 				var sysCtx *core.SysContractCallCtx
 				if isEspresso {
-					sysCtx = core.NewSysContractCallCtx(task.block.Header(), task.statedb, api.backend)
+					sysCtx = core.NewSysContractCallCtx(api.backend.ChainConfig(), task.block.Header(), task.statedb, api.backend)
 				}
 				// Trace all the transactions contained within
 				for i, tx := range task.block.Transactions() {
@@ -323,13 +345,13 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 					vmRunner := api.backend.NewEVMRunner(task.block.Header(), task.statedb)
 					res, err := api.traceTx(localctx, msg, txctx, blockCtx, vmRunner, task.statedb, sysCtx, config)
 					if err != nil {
-						task.results[i] = &txTraceResult{TxHash: tx.Hash(), Error: err.Error()}
+						task.results[i] = &txTraceResult{TxHash: tx.Hash(), Error: err.Error(), SchemaVersion: SchemaVersion}
 						log.Warn("Tracing failed", "hash", tx.Hash(), "block", task.block.NumberU64(), "err", err)
 						break
 					}
 					// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
 					task.statedb.Finalise(api.backend.ChainConfig().IsEIP158(task.block.Number()))
-					task.results[i] = &txTraceResult{TxHash: tx.Hash(), Result: res}
+					task.results[i] = &txTraceResult{TxHash: tx.Hash(), Result: res, SchemaVersion: SchemaVersion}
 				}
 				// Stream the result back to the user or abort on teardown
 				select {
@@ -573,7 +595,7 @@ func (api *API) IntermediateRoots(ctx context.Context, hash common.Hash, config
 	isEspresso := api.backend.ChainConfig().IsEspresso(block.Number())
 	var sysCtx *core.SysContractCallCtx
 	if isEspresso {
-		sysCtx = core.NewSysContractCallCtx(block.Header(), statedb, api.backend)
+		sysCtx = core.NewSysContractCallCtx(api.backend.ChainConfig(), block.Header(), statedb, api.backend)
 	}
 	vmRunner := api.backend.NewEVMRunner(block.Header(), statedb)
 	for i, tx := range block.Transactions() {
@@ -614,7 +636,9 @@ func (api *API) StandardTraceBadBlockToFile(ctx context.Context, hash common.Has
 
 // traceBlock configures a new tracer according to the provided configuration, and
 // executes all the transactions contained within. The return value will be one item
-// per transaction, dependent on the requestd tracer.
+// per transaction, dependent on the requestd tracer. If the system calls are traced,
+// the ones made before and after the transactions are returned in an item with the
+// hash of the block first and last, like the block receipt.
 func (api *API) traceBlock(ctx context.Context, block *types.Block, config *TraceConfig) ([]*txTraceResult, error) {
 	if block.NumberU64() == 0 {
 		return nil, errors.New("genesis is not traceable")
@@ -627,7 +651,9 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
 	}
-	statedb, err := api.backend.StateAtBlock(ctx, parent, reexec, nil, true, false, true)
+	systemCalls := config != nil && config.SystemCalls
+	// The randomness is committed with the block when its system calls are traced
+	statedb, err := api.backend.StateAtBlock(ctx, parent, reexec, nil, true, false, !systemCalls)
 	if err != nil {
 		return nil, err
 	}
@@ -646,15 +672,36 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	}
 	blockHash := block.Hash()
 
+	var (
+		chain      = &chainContext{api: api, ctx: ctx}
+		labels     systemCallLabels
+		blockCalls *systemCallTracer
+		factory    vm.EVMRunnerFactory = api.backend
+	)
+	if systemCalls {
+		// Check the tracer of the config before tracing anything with it
+		_, cancel, err := newTracer(ctx, new(Context), config)
+		if err != nil {
+			return nil, err
+		}
+		cancel()
+		labels = newSystemCallLabels(chain, block.Header(), statedb)
+		blockCalls = newSystemCallTracer(ctx, chain, config, blockHash, labels, phaseInitialization)
+		factory = blockCalls
+		vmRunner := blockCalls.NewEVMRunner(block.Header(), statedb)
+		if err := core.ApplyBlockRandomnessTx(block, &vmRunner, statedb, chain); err != nil {
+			return nil, err
+		}
+	}
 	isEspresso := api.backend.ChainConfig().IsEspresso(block.Number())
 	var sysCtx *core.SysContractCallCtx
 	if isEspresso {
-		sysCtx = core.NewSysContractCallCtx(block.Header(), statedb, api.backend)
+		sysCtx = core.NewSysContractCallCtx(api.backend.ChainConfig(), block.Header(), statedb, factory)
 	}
 	for th := 0; th < threads; th++ {
 		pend.Add(1)
 		go func() {
-			blockCtx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+			blockCtx := core.NewEVMBlockContext(block.Header(), chain, nil)
 			defer pend.Done()
 			// Fetch and execute the next transaction trace tasks
 			for task := range jobs {
@@ -665,20 +712,30 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 					TxIndex:   task.index,
 					TxHash:    txs[task.index].Hash(),
 				}
+				// The workers run concurrently, so each task traces its own system calls
+				var txCalls *systemCallTracer
 				vmRunner := api.backend.NewEVMRunner(block.Header(), task.statedb)
+				if systemCalls {
+					txCalls = newSystemCallTracer(ctx, chain, config, blockHash, labels, phaseTransaction)
+					txCalls.txIndex = task.index
+					vmRunner = txCalls.NewEVMRunner(block.Header(), task.statedb)
+				}
 				res, err := api.traceTx(ctx, msg, txctx, blockCtx, vmRunner, task.statedb, sysCtx, config)
 				if err != nil {
-					results[task.index] = &txTraceResult{TxHash: txs[task.index].Hash(), Error: err.Error()}
-					continue
+					results[task.index] = &txTraceResult{TxHash: txs[task.index].Hash(), Error: err.Error(), SchemaVersion: SchemaVersion}
+				} else {
+					results[task.index] = &txTraceResult{TxHash: txs[task.index].Hash(), Result: res, SchemaVersion: SchemaVersion}
+				}
+				if txCalls != nil {
+					results[task.index].SystemCalls = txCalls.results
 				}
-				results[task.index] = &txTraceResult{TxHash: txs[task.index].Hash(), Result: res}
 			}
 		}()
 	}
 	vmRunner := api.backend.NewEVMRunner(block.Header(), statedb)
 	// Feed the transactions into the tracers and return
 	var failed error
-	blockCtx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+	blockCtx := core.NewEVMBlockContext(block.Header(), chain, nil)
 	for i, tx := range txs {
 		// Send the trace task over for execution
 		jobs <- &txTraceTask{statedb: statedb.Copy(), index: i}
@@ -703,6 +760,22 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	if failed != nil {
 		return nil, failed
 	}
+	if !systemCalls {
+		return results, nil
+	}
+	// Finalize the block on top of the transactions, tracing the system calls of
+	// the consensus engine
+	initCalls := blockCalls.results
+	blockCalls.phase, blockCalls.results = phaseFinalization, []*systemCallTraceResult{}
+	if finalizer, ok := api.backend.Engine().(systemCallFinalizer); ok {
+		finalizer.FinalizeWithEVMRunnerFactory(chain, types.CopyHeader(block.Header()), statedb, txs, blockCalls)
+	}
+	if len(initCalls) > 0 {
+		results = append([]*txTraceResult{{TxHash: blockHash, SystemCalls: initCalls, SchemaVersion: SchemaVersion}}, results...)
+	}
+	if len(blockCalls.results) > 0 {
+		results = append(results, &txTraceResult{TxHash: blockHash, SystemCalls: blockCalls.results, SchemaVersion: SchemaVersion})
+	}
 	return results, nil
 }
 
@@ -770,7 +843,7 @@ func (api *API) standardTraceBlockToFile(ctx context.Context, block *types.Block
 	isEspresso := api.backend.ChainConfig().IsEspresso(block.Number())
 	var sysCtx *core.SysContractCallCtx
 	if isEspresso {
-		sysCtx = core.NewSysContractCallCtx(block.Header(), statedb, api.backend)
+		sysCtx = core.NewSysContractCallCtx(api.backend.ChainConfig(), block.Header(), statedb, api.backend)
 	}
 	for i, tx := range block.Transactions() {
 		// Prepare the transaction for un-traced execution
@@ -880,7 +953,7 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 		if err != nil {
 			return nil, err
 		}
-		sysCtx = core.NewSysContractCallCtx(block.Header(), sysStateDB, api.backend)
+		sysCtx = core.NewSysContractCallCtx(api.backend.ChainConfig(), block.Header(), sysStateDB, api.backend)
 	}
 
 	msg, vmctx, vmRunner, statedb, err := api.backend.StateAtTransaction(ctx, block, int(index), reexec)
@@ -927,10 +1000,15 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 		return nil, err
 	}
 	// Apply the customized state rules if required.
+	var factory vm.EVMRunnerFactory = api.backend
 	if config != nil {
 		if err := config.StateOverrides.Apply(statedb); err != nil {
 			return nil, err
 		}
+		if err := config.CeloOverrides.Validate(); err != nil {
+			return nil, err
+		}
+		factory = config.CeloOverrides.EVMRunnerFactory(api.backend)
 	}
 	// Execute the trace
 	msg, err := args.ToMessage(api.backend.RPCGasCap(), block.Header().BaseFee)
@@ -939,7 +1017,7 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 	}
 	var sysCtx *core.SysContractCallCtx
 	if api.backend.ChainConfig().IsEspresso(block.Number()) {
-		sysCtx = core.NewSysContractCallCtx(block.Header(), statedb, api.backend)
+		sysCtx = core.NewSysContractCallCtx(api.backend.ChainConfig(), block.Header(), statedb, factory)
 	}
 	var traceConfig *TraceConfig
 	if config != nil {
@@ -951,7 +1029,7 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 		}
 	}
 	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
-	vmRunner := api.backend.NewEVMRunner(block.Header(), statedb)
+	vmRunner := factory.NewEVMRunner(block.Header(), statedb)
 	return api.traceTx(ctx, msg, new(Context), vmctx, vmRunner, statedb, sysCtx, traceConfig)
 }
 
@@ -960,62 +1038,89 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 // be tracer dependent.
 func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Context, vmctx vm.BlockContext, vmRunner vm.EVMRunner, statedb *state.StateDB, sysCtx *core.SysContractCallCtx, config *TraceConfig) (interface{}, error) {
 	// Assemble the structured logger or the JavaScript tracer
-	var (
-		tracer    vm.EVMLogger
-		err       error
-		txContext = core.NewEVMTxContext(message)
-	)
+	txContext := core.NewEVMTxContext(message)
+	tracer, cancel, err := newTracer(ctx, txctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	// Run the transaction with tracing enabled.
+	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.backend.ChainConfig(), vm.Config{Debug: true, Tracer: tracer, NoBaseFee: true})
+
+	// Call Prepare to clear out the statedb access list
+	statedb.Prepare(txctx.TxHash, txctx.TxIndex)
+
+	diffTracer, isDiffTracer := tracer.(StateDiffTracer)
+	var pre *state.StateDB
+	if isDiffTracer {
+		pre = statedb.Copy()
+	}
+	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()), vmRunner, sysCtx)
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %w", err)
+	}
+	if isDiffTracer {
+		var feeCurrencies []common.Address
+		if sysCtx != nil {
+			feeCurrencies = sysCtx.GetWhitelistedCurrencies()
+		} else if message.FeeCurrency() != nil {
+			feeCurrencies = []common.Address{*message.FeeCurrency()}
+		}
+		diffTracer.CaptureStateDiff(pre, statedb, statedb.DirtyAccounts(), feeCurrencies)
+	}
+
+	// If the result contains a revert reason, return it.
+	returnVal := result.Return()
+	if len(result.Revert()) > 0 {
+		returnVal = result.Revert()
+	}
+	return traceResult(tracer, result.UsedGas, result.Failed(), returnVal)
+}
+
+// newTracer assembles the structured logger or the JavaScript tracer of the given
+// configuration. The returned function releases the timeout of the tracer.
+func newTracer(ctx context.Context, txctx *Context, config *TraceConfig) (vm.EVMLogger, context.CancelFunc, error) {
 	switch {
 	case config == nil:
-		tracer = vm.NewStructLogger(nil)
+		return vm.NewStructLogger(nil), func() {}, nil
 	case config.Tracer != nil:
 		// Define a meaningful timeout of a single transaction trace
+		var err error
 		timeout := defaultTraceTimeout
 		if config.Timeout != nil {
 			if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		}
-		if t, err := New(*config.Tracer, txctx); err != nil {
-			return nil, err
-		} else {
-			deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
-			go func() {
-				<-deadlineCtx.Done()
-				if errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
-					t.Stop(errors.New("execution timeout"))
-				}
-			}()
-			defer cancel()
-			tracer = t
+		t, err := New(*config.Tracer, txctx)
+		if err != nil {
+			return nil, nil, err
 		}
+		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+		go func() {
+			<-deadlineCtx.Done()
+			if errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
+				t.Stop(errors.New("execution timeout"))
+			}
+		}()
+		return t, cancel, nil
 	default:
-		tracer = vm.NewStructLogger(config.LogConfig)
-	}
-	// Run the transaction with tracing enabled.
-	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.backend.ChainConfig(), vm.Config{Debug: true, Tracer: tracer, NoBaseFee: true})
-
-	// Call Prepare to clear out the statedb access list
-	statedb.Prepare(txctx.TxHash, txctx.TxIndex)
-
-	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()), vmRunner, sysCtx)
-	if err != nil {
-		return nil, fmt.Errorf("tracing failed: %w", err)
+		return vm.NewStructLogger(config.LogConfig), func() {}, nil
 	}
+}
 
-	// Depending on the tracer type, format and return the output.
+// traceResult formats the result of a tracer created by newTracer, depending on
+// its type, once the traced execution is over.
+func traceResult(tracer vm.EVMLogger, gas uint64, failed bool, returnVal []byte) (interface{}, error) {
 	switch tracer := tracer.(type) {
 	case *vm.StructLogger:
-		// If the result contains a revert reason, return it.
-		returnVal := fmt.Sprintf("%x", result.Return())
-		if len(result.Revert()) > 0 {
-			returnVal = fmt.Sprintf("%x", result.Revert())
-		}
 		return &ethapi.ExecutionResult{
-			Gas:         result.UsedGas,
-			Failed:      result.Failed(),
-			ReturnValue: returnVal,
-			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
+			Gas:           gas,
+			Failed:        failed,
+			ReturnValue:   fmt.Sprintf("%x", returnVal),
+			StructLogs:    ethapi.FormatLogs(tracer.StructLogs()),
+			SchemaVersion: SchemaVersion,
 		}, nil
 
 	case Tracer:
@@ -1026,6 +1131,13 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 	}
 }
 
+// TraceSchemaVersion returns the version of the layout of the trace results, see
+// SchemaVersion. It lets clients check the layout of the results of custom and
+// built-in tracers, which are returned as is by TraceTransaction and TraceCall.
+func (api *API) TraceSchemaVersion() hexutil.Uint64 {
+	return SchemaVersion
+}
+
 // APIs return the collection of RPC services the tracer package offers.
 func APIs(backend Backend) []rpc.API {
 	// Append all the local APIs and return
@@ -229,10 +229,11 @@ func TestTraceCall(t *testing.T) {
 			config:    nil,
 			expectErr: nil,
 			expect: &ethapi.ExecutionResult{
-				Gas:         params.TxGas,
-				Failed:      false,
-				ReturnValue: "",
-				StructLogs:  []ethapi.StructLogRes{},
+				Gas:           params.TxGas,
+				Failed:        false,
+				ReturnValue:   "",
+				StructLogs:    []ethapi.StructLogRes{},
+				SchemaVersion: SchemaVersion,
 			},
 		},
 		// Standard JSON trace upon the head, plain transfer.
@@ -246,10 +247,11 @@ func TestTraceCall(t *testing.T) {
 			config:    nil,
 			expectErr: nil,
 			expect: &ethapi.ExecutionResult{
-				Gas:         params.TxGas,
-				Failed:      false,
-				ReturnValue: "",
-				StructLogs:  []ethapi.StructLogRes{},
+				Gas:           params.TxGas,
+				Failed:        false,
+				ReturnValue:   "",
+				StructLogs:    []ethapi.StructLogRes{},
+				SchemaVersion: SchemaVersion,
 			},
 		},
 		// Standard JSON trace upon the non-existent block, error expects
@@ -275,10 +277,11 @@ func TestTraceCall(t *testing.T) {
 			config:    nil,
 			expectErr: nil,
 			expect: &ethapi.ExecutionResult{
-				Gas:         params.TxGas,
-				Failed:      false,
-				ReturnValue: "",
-				StructLogs:  []ethapi.StructLogRes{},
+				Gas:           params.TxGas,
+				Failed:        false,
+				ReturnValue:   "",
+				StructLogs:    []ethapi.StructLogRes{},
+				SchemaVersion: SchemaVersion,
 			},
 		},
 		// Standard JSON trace upon the pending block
@@ -292,10 +295,11 @@ func TestTraceCall(t *testing.T) {
 			config:    nil,
 			expectErr: nil,
 			expect: &ethapi.ExecutionResult{
-				Gas:         params.TxGas,
-				Failed:      false,
-				ReturnValue: "",
-				StructLogs:  []ethapi.StructLogRes{},
+				Gas:           params.TxGas,
+				Failed:        false,
+				ReturnValue:   "",
+				StructLogs:    []ethapi.StructLogRes{},
+				SchemaVersion: SchemaVersion,
 			},
 		},
 	}
@@ -345,10 +349,11 @@ func TestTraceTransaction(t *testing.T) {
 		t.Errorf("Failed to trace transaction %v", err)
 	}
 	if !reflect.DeepEqual(result, &ethapi.ExecutionResult{
-		Gas:         params.TxGas,
-		Failed:      false,
-		ReturnValue: "",
-		StructLogs:  []ethapi.StructLogRes{},
+		Gas:           params.TxGas,
+		Failed:        false,
+		ReturnValue:   "",
+		StructLogs:    []ethapi.StructLogRes{},
+		SchemaVersion: SchemaVersion,
 	}) {
 		t.Error("Transaction tracing result is different")
 	}
@@ -390,7 +395,7 @@ func TestTraceBlock(t *testing.T) {
 		// Trace head block
 		{
 			blockNumber: rpc.BlockNumber(genBlocks),
-			want:        fmt.Sprintf(`[{"txHash":"%v","result":{"gas":21000,"failed":false,"returnValue":"","structLogs":[]}}]`, txHash),
+			want:        fmt.Sprintf(`[{"txHash":"%v","result":{"gas":21000,"failed":false,"returnValue":"","structLogs":[],"schemaVersion":1},"schemaVersion":1}]`, txHash),
 		},
 		// Trace non-existent block
 		{
@@ -400,12 +405,12 @@ func TestTraceBlock(t *testing.T) {
 		// Trace latest block
 		{
 			blockNumber: rpc.LatestBlockNumber,
-			want:        fmt.Sprintf(`[{"txHash":"%v","result":{"gas":21000,"failed":false,"returnValue":"","structLogs":[]}}]`, txHash),
+			want:        fmt.Sprintf(`[{"txHash":"%v","result":{"gas":21000,"failed":false,"returnValue":"","structLogs":[],"schemaVersion":1},"schemaVersion":1}]`, txHash),
 		},
 		// Trace pending block
 		{
 			blockNumber: rpc.PendingBlockNumber,
-			want:        fmt.Sprintf(`[{"txHash":"%v","result":{"gas":21000,"failed":false,"returnValue":"","structLogs":[]}}]`, txHash),
+			want:        fmt.Sprintf(`[{"txHash":"%v","result":{"gas":21000,"failed":false,"returnValue":"","structLogs":[],"schemaVersion":1},"schemaVersion":1}]`, txHash),
 		},
 	}
 	for i, tc := range testSuite {
@@ -668,10 +673,11 @@ func TestTraceTransactionWithRegistryDeployed(t *testing.T) {
 		t.Errorf("Failed to trace transaction %v", err)
 	}
 	if !reflect.DeepEqual(result, &ethapi.ExecutionResult{
-		Gas:         params.TxGas,
-		Failed:      false,
-		ReturnValue: "",
-		StructLogs:  []ethapi.StructLogRes{},
+		Gas:           params.TxGas,
+		Failed:        false,
+		ReturnValue:   "",
+		StructLogs:    []ethapi.StructLogRes{},
+		SchemaVersion: SchemaVersion,
 	}) {
 		t.Error("Transaction tracing result is different")
 	}
@@ -0,0 +1,205 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/core/vm/vmcontext"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/eth/tracers"
+	"github.com/celo-org/celo-blockchain/internal/ethapi"
+	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/tests"
+)
+
+var updateGolden = flag.Bool("update", false, "regenerate the golden trace files")
+
+var (
+	goldenKey, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	goldenCaller    = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	goldenCallee    = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	goldenCurrency  = common.HexToAddress("0x02") // Whitelisted by the celo mock
+	goldenRecipient = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+)
+
+// goldenTrace is the content of a golden file: the outputs of the call tracer
+// and of the struct logger for a transaction.
+type goldenTrace struct {
+	SchemaVersion uint64                  `json:"schemaVersion"`
+	CallTracer    json.RawMessage         `json:"callTracer"`
+	StructLogger  *ethapi.ExecutionResult `json:"structLogger"`
+}
+
+// goldenTxs returns the transactions traced by the golden tests, one for every
+// Celo transaction type and fee currency. Celo denominated transactions are left
+// out as no signer accepts them yet.
+func goldenTxs(chainID *big.Int) map[string]types.TxData {
+	var (
+		currency = &goldenCurrency
+		price    = big.NewInt(params.GWei)
+		gas      = uint64(200000)
+		input    = []byte{0x01}
+	)
+	return map[string]types.TxData{
+		"legacy":                   &types.LegacyTx{Nonce: 0, GasPrice: price, Gas: gas, To: &goldenCaller, Value: big.NewInt(1), Data: input, EthCompatible: true},
+		"celo_legacy":              &types.LegacyTx{Nonce: 0, GasPrice: price, Gas: gas, To: &goldenCaller, Value: big.NewInt(1), Data: input},
+		"celo_legacy_fee_currency": &types.LegacyTx{Nonce: 0, GasPrice: price, Gas: gas, FeeCurrency: currency, To: &goldenCaller, Value: big.NewInt(1), Data: input},
+		"access_list": &types.AccessListTx{ChainID: chainID, Nonce: 0, GasPrice: price, Gas: gas, To: &goldenCaller, Value: big.NewInt(1), Data: input,
+			AccessList: types.AccessList{{Address: goldenCallee, StorageKeys: []common.Hash{{}}}}},
+		"dynamic_fee":                      &types.DynamicFeeTx{ChainID: chainID, Nonce: 0, GasTipCap: price, GasFeeCap: price, Gas: gas, To: &goldenCaller, Value: big.NewInt(1), Data: input},
+		"celo_dynamic_fee":                 &types.CeloDynamicFeeTx{ChainID: chainID, Nonce: 0, GasTipCap: price, GasFeeCap: price, Gas: gas, To: &goldenCaller, Value: big.NewInt(1), Data: input},
+		"celo_dynamic_fee_fee_currency":    &types.CeloDynamicFeeTx{ChainID: chainID, Nonce: 0, GasTipCap: price, GasFeeCap: price, Gas: gas, FeeCurrency: currency, To: &goldenCaller, Value: big.NewInt(1), Data: input},
+		"celo_dynamic_fee_v2":              &types.CeloDynamicFeeTxV2{ChainID: chainID, Nonce: 0, GasTipCap: price, GasFeeCap: price, Gas: gas, To: &goldenCaller, Value: big.NewInt(1), Data: input},
+		"celo_dynamic_fee_v2_fee_currency": &types.CeloDynamicFeeTxV2{ChainID: chainID, Nonce: 0, GasTipCap: price, GasFeeCap: price, Gas: gas, FeeCurrency: currency, To: &goldenCaller, Value: big.NewInt(1), Data: input},
+	}
+}
+
+// goldenAlloc is the prestate of the golden tests: the caller contract calls
+// the callee with its input, which stores it and emits it in a log.
+func goldenAlloc() core.GenesisAlloc {
+	caller := []byte{
+		byte(vm.CALLDATASIZE), byte(vm.PUSH1), 0x00, byte(vm.DUP1), byte(vm.CALLDATACOPY), // copy the input to memory
+		byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.CALLDATASIZE), byte(vm.PUSH1), 0x00, // out and ins
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL),
+		byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.RETURN),
+	}
+	callee := []byte{
+		byte(vm.PUSH1), 0x00, byte(vm.CALLDATALOAD), byte(vm.DUP1), byte(vm.PUSH1), 0x00, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x00, byte(vm.MSTORE), byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.LOG0),
+		byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x00, byte(vm.RETURN),
+	}
+	return core.GenesisAlloc{
+		goldenCaller: {Code: caller, Balance: new(big.Int)},
+		goldenCallee: {Code: callee, Balance: new(big.Int)},
+		crypto.PubkeyToAddress(goldenKey.PublicKey): {Balance: big.NewInt(params.Ether)},
+	}
+}
+
+// Tests that the output of the call tracer and of the struct logger for every
+// Celo transaction type matches the golden files of the current schema version,
+// so that any change of the trace layout is caught and comes with a version bump.
+// Run with -update to regenerate the golden files after bumping the version.
+func TestGoldenTraces(t *testing.T) {
+	config := params.TestChainConfig
+	for name, data := range goldenTxs(config.ChainID) {
+		name, data := name, data
+		t.Run(camel(name), func(t *testing.T) {
+			have, err := json.MarshalIndent(traceGolden(t, config, data), "", "  ")
+			if err != nil {
+				t.Fatalf("failed to encode traces: %v", err)
+			}
+			have = append(have, '\n')
+
+			path := filepath.Join("testdata", "golden", name+".json")
+			if *updateGolden {
+				if err := os.WriteFile(path, have, 0644); err != nil {
+					t.Fatalf("failed to write golden file: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read golden file: %v", err)
+			}
+			var golden goldenTrace
+			if err := json.Unmarshal(want, &golden); err != nil {
+				t.Fatalf("failed to parse golden file: %v", err)
+			}
+			if golden.SchemaVersion != tracers.SchemaVersion {
+				t.Fatalf("golden file of schema version %d, want %d: regenerate it with -update", golden.SchemaVersion, tracers.SchemaVersion)
+			}
+			if !bytes.Equal(have, want) {
+				t.Fatalf("trace output changed without bumping tracers.SchemaVersion:\nhave %s\nwant %s", have, want)
+			}
+		})
+	}
+}
+
+// traceGolden runs the transaction on the golden prestate with the call tracer
+// and with the struct logger.
+func traceGolden(t *testing.T, config *params.ChainConfig, data types.TxData) *goldenTrace {
+	var (
+		number = big.NewInt(1)
+		signer = types.MakeSigner(config, number)
+		tx     = types.MustSignNewTx(goldenKey, signer, data)
+		trace  = &goldenTrace{SchemaVersion: tracers.SchemaVersion}
+	)
+	run := func(tracer vm.EVMLogger) *core.ExecutionResult {
+		celoMock := testutil.NewCeloMockFromFixture(&testutil.SystemContractsFixture{
+			GasPriceMinimums: map[common.Address]*big.Int{{}: big.NewInt(params.GWei / 10), goldenCurrency: big.NewInt(params.GWei / 10)},
+		})
+		msg, err := tx.AsMessage(signer, nil)
+		if err != nil {
+			t.Fatalf("failed to prepare transaction for tracing: %v", err)
+		}
+		var (
+			txContext = core.NewEVMTxContext(msg)
+			context   = vm.BlockContext{
+				CanTransfer: vmcontext.CanTransfer,
+				Transfer:    vmcontext.TobinTransfer,
+				Coinbase:    goldenRecipient,
+				BlockNumber: number,
+				Time:        big.NewInt(1),
+			}
+			_, statedb = tests.MakePreState(rawdb.NewMemoryDatabase(), goldenAlloc(), false)
+		)
+		evm := vm.NewEVM(context, txContext, statedb, config, vm.Config{Debug: true, Tracer: tracer, NoBaseFee: true})
+		sysCtx := core.NewSysContractCallCtx(config, &types.Header{Number: number}, statedb, celoMock.RunnerFactory())
+		result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(tx.Gas()), celoMock.Runner, sysCtx)
+		if err != nil {
+			t.Fatalf("failed to execute transaction: %v", err)
+		}
+		return result
+	}
+	// Trace the transaction with the call tracer
+	tracer, err := tracers.New("callTracer", new(tracers.Context))
+	if err != nil {
+		t.Fatalf("failed to create call tracer: %v", err)
+	}
+	run(tracer)
+	if trace.CallTracer, err = tracer.GetResult(); err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	// Trace it again with the struct logger, formatted like debug_traceTransaction
+	logger := vm.NewStructLogger(nil)
+	result := run(logger)
+	if result.Failed() {
+		t.Fatalf("transaction failed: %v", result.Err)
+	}
+	trace.StructLogger = &ethapi.ExecutionResult{
+		Gas:           result.UsedGas,
+		Failed:        result.Failed(),
+		ReturnValue:   fmt.Sprintf("%x", result.Return()),
+		StructLogs:    ethapi.FormatLogs(logger.StructLogs()),
+		SchemaVersion: tracers.SchemaVersion,
+	}
+	return trace
+}
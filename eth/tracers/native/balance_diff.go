@@ -0,0 +1,252 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/eth/tracers"
+)
+
+func init() {
+	register("balanceDiffTracer", newBalanceDiffTracer)
+}
+
+// maxBalanceMappingIndex bounds the storage indexes of the mappings considered
+// balances. Those of a fee currency whose code didn't run traced, like when only
+// its gas fees were debited and credited, are all searched.
+const maxBalanceMappingIndex = 64
+
+// balanceSlot is a storage slot of a mapping keyed by address, as in
+// keccak256(holder . index).
+type balanceSlot struct {
+	holder common.Address
+	index  common.Hash
+}
+
+// balanceDiffResult is the balance changes of a transaction, by account. The
+// changes are signed, the decreases being encoded as negative hex numbers.
+type balanceDiffResult struct {
+	Celo map[common.Address]*hexutil.Big `json:"celo"`
+	// FeeCurrencies are the changes of the balances of the fee currencies, by
+	// currency and by holder.
+	FeeCurrencies map[common.Address]map[common.Address]*hexutil.Big `json:"feeCurrencies"`
+	// UnresolvedSlots are the storage slots of the fee currencies that changed
+	// but couldn't be attributed to a holder, with their values after the change.
+	UnresolvedSlots map[common.Address]map[common.Hash]common.Hash `json:"unresolvedSlots,omitempty"`
+}
+
+// balanceDiffTracer is a native go tracer reporting the changes of the native
+// CELO balances made by a transaction, along with the ones of the balances of the
+// whitelisted fee currencies. The latter are found by watching the storage slots
+// of the currencies: the slots hashed from an address by their code, and the
+// ones of the parties of the fee debit and credit.
+type balanceDiffTracer struct {
+	slots   map[common.Address]map[common.Hash]balanceSlot // Slots hashed by the code of a contract
+	indexes map[common.Address]map[common.Hash]struct{}    // Mapping indexes hashed by the code of a contract
+	parties map[common.Address]map[common.Address]struct{} // Parties of the fee calls by currency
+
+	result *balanceDiffResult
+	reason error // Textual reason for the interruption
+}
+
+// newBalanceDiffTracer returns a native go tracer which reports the balance
+// changes of a transaction, and implements vm.EVMLogger, vm.FeeCurrencyLogger and
+// tracers.StateDiffTracer.
+func newBalanceDiffTracer() tracers.Tracer {
+	return &balanceDiffTracer{
+		slots:   make(map[common.Address]map[common.Hash]balanceSlot),
+		indexes: make(map[common.Address]map[common.Hash]struct{}),
+		parties: make(map[common.Address]map[common.Address]struct{}),
+	}
+}
+
+// CaptureState implements the EVMLogger interface to record the slots hashed from
+// an address and an index, the way the slots of a mapping are.
+func (t *balanceDiffTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if op != vm.SHA3 || err != nil {
+		return
+	}
+	stack := scope.Stack
+	offset, size := stack.Back(0), stack.Back(1)
+	if !size.IsUint64() || size.Uint64() != 64 || !offset.IsUint64() || offset.Uint64()+64 > uint64(scope.Memory.Len()) {
+		return
+	}
+	// Only the top level mappings are kept, not the nested ones indexed by slots
+	input := scope.Memory.GetCopy(int64(offset.Uint64()), 64)
+	index := new(big.Int).SetBytes(input[32:])
+	if !bytes.Equal(input[:12], common.Hash{}.Bytes()[:12]) || index.Cmp(big.NewInt(maxBalanceMappingIndex)) >= 0 {
+		return
+	}
+	contract := scope.Contract.Address()
+	slot := balanceSlot{holder: common.BytesToAddress(input[:32]), index: common.BytesToHash(input[32:])}
+	if t.slots[contract] == nil {
+		t.slots[contract] = make(map[common.Hash]balanceSlot)
+		t.indexes[contract] = make(map[common.Hash]struct{})
+	}
+	t.slots[contract][crypto.Keccak256Hash(input)] = slot
+	t.indexes[contract][slot.index] = struct{}{}
+}
+
+// CaptureFeeCurrencyCall implements the FeeCurrencyLogger interface to record the
+// parties whose fee currency balances the fee debit and credit changed.
+func (t *balanceDiffTracer) CaptureFeeCurrencyCall(feeCurrency common.Address, input []byte, gasUsed uint64, err error) {
+	if err != nil || len(input) < 4 {
+		return
+	}
+	if t.parties[feeCurrency] == nil {
+		t.parties[feeCurrency] = make(map[common.Address]struct{})
+	}
+	// The parties are the address arguments leading the calls
+	args, parties := input[4:], 0
+	switch {
+	case bytes.HasPrefix(input, debitGasFeesSelector):
+		parties = 1
+	case bytes.HasPrefix(input, creditGasFeesSelector):
+		parties = 4
+	}
+	for i := 0; i < parties && len(args) >= (i+1)*32; i++ {
+		t.parties[feeCurrency][common.BytesToAddress(args[i*32:(i+1)*32])] = struct{}{}
+	}
+}
+
+// CaptureStateDiff implements the StateDiffTracer interface to compute the
+// balance changes from the states before and after the transaction.
+func (t *balanceDiffTracer) CaptureStateDiff(pre, post vm.StateDB, dirties map[common.Address][]common.Hash, feeCurrencies []common.Address) {
+	result := &balanceDiffResult{
+		Celo:          make(map[common.Address]*hexutil.Big),
+		FeeCurrencies: make(map[common.Address]map[common.Address]*hexutil.Big),
+	}
+	for addr := range dirties {
+		if delta := new(big.Int).Sub(post.GetBalance(addr), pre.GetBalance(addr)); delta.Sign() != 0 {
+			result.Celo[addr] = (*hexutil.Big)(delta)
+		}
+	}
+	for _, currency := range feeCurrencies {
+		slots, ok := dirties[currency]
+		if !ok {
+			continue
+		}
+		known := t.feeCurrencySlots(currency)
+		for _, slot := range slots {
+			before, after := pre.GetState(currency, slot), post.GetState(currency, slot)
+			if before == after {
+				continue
+			}
+			balance, ok := known[slot]
+			if !ok {
+				if result.UnresolvedSlots == nil {
+					result.UnresolvedSlots = make(map[common.Address]map[common.Hash]common.Hash)
+				}
+				if result.UnresolvedSlots[currency] == nil {
+					result.UnresolvedSlots[currency] = make(map[common.Hash]common.Hash)
+				}
+				result.UnresolvedSlots[currency][slot] = after
+				continue
+			}
+			if result.FeeCurrencies[currency] == nil {
+				result.FeeCurrencies[currency] = make(map[common.Address]*hexutil.Big)
+			}
+			delta := new(big.Int).Sub(after.Big(), before.Big())
+			if previous := result.FeeCurrencies[currency][balance.holder]; previous != nil {
+				delta.Add(delta, previous.ToInt())
+			}
+			result.FeeCurrencies[currency][balance.holder] = (*hexutil.Big)(delta)
+		}
+	}
+	t.result = result
+}
+
+// feeCurrencySlots returns the slots of a fee currency attributable to a holder:
+// the ones hashed by its code, and the ones of the parties of its fee calls at
+// the indexes hashed by its code, or at any small index if none was.
+func (t *balanceDiffTracer) feeCurrencySlots(currency common.Address) map[common.Hash]balanceSlot {
+	known := make(map[common.Hash]balanceSlot, len(t.slots[currency]))
+	for hash, slot := range t.slots[currency] {
+		known[hash] = slot
+	}
+	var indexes []common.Hash
+	if len(t.indexes[currency]) > 0 {
+		for index := range t.indexes[currency] {
+			indexes = append(indexes, index)
+		}
+	} else {
+		for i := int64(0); i < maxBalanceMappingIndex; i++ {
+			indexes = append(indexes, common.BigToHash(big.NewInt(i)))
+		}
+	}
+	for party := range t.parties[currency] {
+		for _, index := range indexes {
+			hash := crypto.Keccak256Hash(common.LeftPadBytes(party.Bytes(), 32), index.Bytes())
+			if _, ok := known[hash]; !ok {
+				known[hash] = balanceSlot{holder: party, index: index}
+			}
+		}
+	}
+	return known
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *balanceDiffTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *balanceDiffTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *balanceDiffTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *balanceDiffTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *balanceDiffTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+}
+
+// GetResult returns the json-encoded balance changes, and any error arising from
+// the encoding or forceful termination (via `Stop`).
+func (t *balanceDiffTracer) GetResult() (json.RawMessage, error) {
+	result := t.result
+	if result == nil {
+		// The state diff is only captured when tracing transactions
+		result = &balanceDiffResult{
+			Celo:          make(map[common.Address]*hexutil.Big),
+			FeeCurrencies: make(map[common.Address]map[common.Address]*hexutil.Big),
+		}
+	}
+	res, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *balanceDiffTracer) Stop(err error) {
+	t.reason = err
+}
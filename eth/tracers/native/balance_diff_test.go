@@ -0,0 +1,96 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/eth/tracers"
+)
+
+func TestBalanceDiffTracer(t *testing.T) {
+	tracer, err := tracers.New("balanceDiffTracer", new(tracers.Context))
+	if err != nil {
+		t.Fatalf("failed to create the tracer: %v", err)
+	}
+	diffTracer, ok := tracer.(tracers.StateDiffTracer)
+	if !ok {
+		t.Fatalf("tracer doesn't capture the state diff")
+	}
+
+	var (
+		currency  = common.HexToAddress("0xcafe")
+		other     = common.HexToAddress("0xbeef")
+		sender    = common.HexToAddress("0x01")
+		coinbase  = common.HexToAddress("0x02")
+		unknown   = common.HexToHash("0x03")
+		balanceOf = func(holder common.Address) common.Hash {
+			return crypto.Keccak256Hash(common.LeftPadBytes(holder.Bytes(), 32), common.BigToHash(big.NewInt(5)).Bytes())
+		}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetBalance(sender, big.NewInt(100))
+	statedb.SetCode(currency, []byte{0x00})
+	statedb.SetCode(other, []byte{0x00})
+	statedb.SetState(currency, balanceOf(sender), common.BigToHash(big.NewInt(1000)))
+	statedb.Finalise(true)
+
+	pre := statedb.Copy()
+	statedb.SubBalance(sender, big.NewInt(30))
+	statedb.AddBalance(coinbase, big.NewInt(30))
+	statedb.SetState(currency, balanceOf(sender), common.BigToHash(big.NewInt(800)))
+	statedb.SetState(currency, balanceOf(coinbase), common.BigToHash(big.NewInt(200)))
+	statedb.SetState(currency, unknown, common.HexToHash("0x2a"))
+	// Not a whitelisted fee currency
+	statedb.SetState(other, balanceOf(sender), common.HexToHash("0x01"))
+
+	logger := tracer.(vm.FeeCurrencyLogger)
+	logger.CaptureFeeCurrencyCall(currency, append(common.CopyBytes(debitGasFeesSelector), common.AddressToAbi(sender)...), 20000, nil)
+	logger.CaptureFeeCurrencyCall(currency, append(append(common.CopyBytes(creditGasFeesSelector), common.AddressToAbi(sender)...), common.AddressToAbi(coinbase)...), 30000, nil)
+	diffTracer.CaptureStateDiff(pre, statedb, statedb.DirtyAccounts(), []common.Address{currency})
+
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to get the result: %v", err)
+	}
+	// The deltas are signed, which the hex decoding of hexutil.Big rejects
+	var diff struct {
+		Celo            map[common.Address]string
+		FeeCurrencies   map[common.Address]map[common.Address]string
+		UnresolvedSlots map[common.Address]map[common.Hash]common.Hash
+	}
+	if err := json.Unmarshal(res, &diff); err != nil {
+		t.Fatalf("failed to decode the result: %v", err)
+	}
+	if len(diff.Celo) != 2 || diff.Celo[sender] != "-0x1e" || diff.Celo[coinbase] != "0x1e" {
+		t.Errorf("CELO deltas mismatch: have %v", diff.Celo)
+	}
+	deltas := diff.FeeCurrencies[currency]
+	if len(diff.FeeCurrencies) != 1 || len(deltas) != 2 || deltas[sender] != "-0xc8" || deltas[coinbase] != "0xc8" {
+		t.Errorf("fee currency deltas mismatch: have %s", res)
+	}
+	if len(diff.UnresolvedSlots) != 1 || diff.UnresolvedSlots[currency][unknown] != common.HexToHash("0x2a") {
+		t.Errorf("unresolved slots mismatch: have %v", diff.UnresolvedSlots)
+	}
+}
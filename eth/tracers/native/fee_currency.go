@@ -0,0 +1,148 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/eth/tracers"
+)
+
+func init() {
+	register("feeCurrencyTracer", newFeeCurrencyTracer)
+}
+
+var (
+	debitGasFeesSelector  = crypto.Keccak256([]byte("debitGasFees(address,uint256)"))[:4]
+	creditGasFeesSelector = crypto.Keccak256([]byte("creditGasFees(address,address,address,address,uint256,uint256,uint256,uint256)"))[:4]
+)
+
+// feeCurrencyFlow is the gas fees moved in a fee currency, by party.
+type feeCurrencyFlow struct {
+	Debited  map[common.Address]*hexutil.Big `json:"debited"`          // Fees debited up front from the sender
+	Refunded map[common.Address]*hexutil.Big `json:"refunded"`         // Unused fees refunded to the sender
+	Credited map[common.Address]*hexutil.Big `json:"credited"`         // Fees credited to the coinbase, the gateway and the fee handler
+	Errors   []string                        `json:"errors,omitempty"` // Errors of the failed calls, whose fees didn't move
+}
+
+// add adds an amount to the one of a party, ignoring zero amounts.
+func (f *feeCurrencyFlow) add(amounts map[common.Address]*hexutil.Big, party common.Address, amount *big.Int) {
+	if amount.Sign() == 0 {
+		return
+	}
+	if amounts[party] == nil {
+		amounts[party] = new(hexutil.Big)
+	}
+	amounts[party].ToInt().Add(amounts[party].ToInt(), amount)
+}
+
+// feeCurrencyTracer is a native go tracer which decodes the debitGasFees and
+// creditGasFees calls made on the fee currencies of a transaction, and reports
+// the fees they moved per party and per currency.
+type feeCurrencyTracer struct {
+	flows  map[common.Address]*feeCurrencyFlow
+	reason error // Textual reason for the interruption
+}
+
+// newFeeCurrencyTracer returns a native go tracer which tracks the gas fees paid
+// in fee currencies, and implements vm.EVMLogger and vm.FeeCurrencyLogger.
+func newFeeCurrencyTracer() tracers.Tracer {
+	return &feeCurrencyTracer{flows: make(map[common.Address]*feeCurrencyFlow)}
+}
+
+// CaptureFeeCurrencyCall implements the FeeCurrencyLogger interface to decode a
+// fee debit or credit call.
+func (t *feeCurrencyTracer) CaptureFeeCurrencyCall(feeCurrency common.Address, input []byte, gasUsed uint64, err error) {
+	flow, ok := t.flows[feeCurrency]
+	if !ok {
+		flow = &feeCurrencyFlow{
+			Debited:  make(map[common.Address]*hexutil.Big),
+			Refunded: make(map[common.Address]*hexutil.Big),
+			Credited: make(map[common.Address]*hexutil.Big),
+		}
+		t.flows[feeCurrency] = flow
+	}
+	if err != nil {
+		flow.Errors = append(flow.Errors, err.Error())
+		return
+	}
+	if len(input) < 4 {
+		return
+	}
+	args := input[4:]
+	address := func(i int) common.Address { return common.BytesToAddress(args[i*32 : (i+1)*32]) }
+	amount := func(i int) *big.Int { return new(big.Int).SetBytes(args[i*32 : (i+1)*32]) }
+
+	switch {
+	case bytes.Equal(input[:4], debitGasFeesSelector) && len(args) >= 2*32:
+		// debitGasFees(from, value)
+		flow.add(flow.Debited, address(0), amount(1))
+	case bytes.Equal(input[:4], creditGasFeesSelector) && len(args) >= 8*32:
+		// creditGasFees(from, feeRecipient, gatewayFeeRecipient, feeHandler, refund, tipTxFee, gatewayFee, baseTxFee)
+		flow.add(flow.Refunded, address(0), amount(4))
+		flow.add(flow.Credited, address(1), amount(5))
+		flow.add(flow.Credited, address(2), amount(6))
+		flow.add(flow.Credited, address(3), amount(7))
+	}
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *feeCurrencyTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *feeCurrencyTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *feeCurrencyTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *feeCurrencyTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *feeCurrencyTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *feeCurrencyTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+}
+
+// GetResult returns the json-encoded fee flows by fee currency, and any error
+// arising from the encoding or forceful termination (via `Stop`).
+func (t *feeCurrencyTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.flows)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *feeCurrencyTracer) Stop(err error) {
+	t.reason = err
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/eth/tracers"
+)
+
+func TestFeeCurrencyTracer(t *testing.T) {
+	if common.Bytes2Hex(debitGasFeesSelector) != "58cf9672" || common.Bytes2Hex(creditGasFeesSelector) != "6a30b253" {
+		t.Fatalf("selectors mismatch: have %x and %x", debitGasFeesSelector, creditGasFeesSelector)
+	}
+	tracer, err := tracers.New("feeCurrencyTracer", new(tracers.Context))
+	if err != nil {
+		t.Fatalf("failed to create the tracer: %v", err)
+	}
+	logger, ok := tracer.(vm.FeeCurrencyLogger)
+	if !ok {
+		t.Fatalf("tracer doesn't capture the fee currency calls")
+	}
+
+	var (
+		currency    = common.HexToAddress("0xcafe")
+		sender      = common.HexToAddress("0x01")
+		coinbase    = common.HexToAddress("0x02")
+		feeHandler  = common.HexToAddress("0x03")
+		noRecipient = common.Address{}
+	)
+	encode := func(selector []byte, args ...interface{}) []byte {
+		input := common.CopyBytes(selector)
+		for _, arg := range args {
+			switch arg := arg.(type) {
+			case common.Address:
+				input = append(input, common.AddressToAbi(arg)...)
+			case int64:
+				input = append(input, common.AmountToAbi(big.NewInt(arg))...)
+			}
+		}
+		return input
+	}
+	logger.CaptureFeeCurrencyCall(currency, encode(debitGasFeesSelector, sender, int64(1000)), 20000, nil)
+	logger.CaptureFeeCurrencyCall(currency, encode(creditGasFeesSelector, sender, coinbase, noRecipient, feeHandler, int64(300), int64(200), int64(0), int64(500)), 30000, nil)
+	// The fees of the failed calls didn't move
+	logger.CaptureFeeCurrencyCall(currency, encode(debitGasFeesSelector, sender, int64(1)), 20000, errors.New("execution reverted"))
+
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to get the result: %v", err)
+	}
+	var flows map[common.Address]*feeCurrencyFlow
+	if err := json.Unmarshal(res, &flows); err != nil {
+		t.Fatalf("failed to decode the result: %v", err)
+	}
+	flow := flows[currency]
+	if len(flows) != 1 || flow == nil {
+		t.Fatalf("flows mismatch: have %s", res)
+	}
+	amount := func(amounts map[common.Address]*hexutil.Big, party common.Address) int64 {
+		if amounts[party] == nil {
+			return 0
+		}
+		return amounts[party].ToInt().Int64()
+	}
+	if len(flow.Debited) != 1 || amount(flow.Debited, sender) != 1000 {
+		t.Errorf("debited fees mismatch: have %v", flow.Debited)
+	}
+	if len(flow.Refunded) != 1 || amount(flow.Refunded, sender) != 300 {
+		t.Errorf("refunded fees mismatch: have %v", flow.Refunded)
+	}
+	if len(flow.Credited) != 2 || amount(flow.Credited, coinbase) != 200 || amount(flow.Credited, feeHandler) != 500 {
+		t.Errorf("credited fees mismatch: have %v", flow.Credited)
+	}
+	if len(flow.Errors) != 1 {
+		t.Errorf("errors mismatch: have %v", flow.Errors)
+	}
+}
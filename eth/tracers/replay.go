@@ -0,0 +1,143 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+// blockReplayResult is the result of re-executing a block with ReplayBlock.
+type blockReplayResult struct {
+	Block        hexutil.Uint64           `json:"block"`
+	Hash         common.Hash              `json:"hash"`
+	Transactions []*txTraceResult         `json:"transactions"`
+	SystemCalls  []*systemCallTraceResult `json:"systemCalls"`
+	StateRoot    common.Hash              `json:"stateRoot"`   // Root of the state after the replay
+	RootMatches  bool                     `json:"rootMatches"` // Whether the replay reproduced the state root of the block
+}
+
+// ReplayBlock re-executes a block the way the state processor does, tracing its
+// transactions along with the system contract calls around them: the randomness
+// reveal and commit and the block parameter reads, the fee debits and credits of
+// the transactions, and the finalization ones like the gas price minimum update
+// and the epoch rewards distribution.
+func (api *API) ReplayBlock(ctx context.Context, hash common.Hash, config *TraceConfig) (*blockReplayResult, error) {
+	block, err := api.blockByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	// Check the tracer of the config before starting
+	_, cancel, err := newTracer(ctx, new(Context), config)
+	if err != nil {
+		return nil, err
+	}
+	cancel()
+	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return nil, err
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	// The randomness is replayed with the block
+	statedb, err := api.backend.StateAtBlock(ctx, parent, reexec, nil, true, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		chainConfig = api.backend.ChainConfig()
+		header      = types.CopyHeader(block.Header())
+		chain       = &chainContext{api: api, ctx: ctx}
+		txs         = block.Transactions()
+		replay      = newSystemCallTracer(ctx, chain, config, hash, newSystemCallLabels(chain, header, statedb), phaseInitialization)
+		vmRunner    = replay.NewEVMRunner(header, statedb)
+		result      = &blockReplayResult{
+			Block:        hexutil.Uint64(block.NumberU64()),
+			Hash:         hash,
+			Transactions: make([]*txTraceResult, 0, len(txs)),
+		}
+	)
+
+	if err := core.ApplyBlockRandomnessTx(block, &vmRunner, statedb, chain); err != nil {
+		return nil, err
+	}
+	var sysCtx *core.SysContractCallCtx
+	if chainConfig.IsEspresso(block.Number()) {
+		sysCtx = core.NewSysContractCallCtx(chainConfig, header, statedb, replay)
+		if chainConfig.FakeBaseFee != nil {
+			sysCtx = core.MockSysContractCallCtx(chainConfig.FakeBaseFee)
+		}
+	}
+	var (
+		usedGas = new(uint64)
+		gp      = new(core.GasPool).AddGas(blockchain_parameters.GetBlockGasLimitOrDefault(vmRunner))
+	)
+
+	replay.phase = phaseTransaction
+	for i, tx := range txs {
+		replay.txIndex = i
+		txctx := &Context{BlockHash: hash, TxIndex: i, TxHash: tx.Hash()}
+		tracer, cancel, err := newTracer(ctx, txctx, config)
+		if err != nil {
+			return nil, err
+		}
+		statedb.Prepare(tx.Hash(), i)
+		receipt, err := core.ApplyTransaction(chainConfig, chain, nil, gp, statedb, header, tx, usedGas, vm.Config{Debug: true, Tracer: tracer}, vmRunner, sysCtx)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+		var returnVal []byte
+		if logger, ok := tracer.(*vm.StructLogger); ok {
+			returnVal = logger.Output()
+		}
+		res, err := traceResult(tracer, receipt.GasUsed, receipt.Status == types.ReceiptStatusFailed, returnVal)
+		cancel()
+		if err != nil {
+			result.Transactions = append(result.Transactions, &txTraceResult{TxHash: tx.Hash(), Error: err.Error(), SchemaVersion: SchemaVersion})
+			continue
+		}
+		result.Transactions = append(result.Transactions, &txTraceResult{TxHash: tx.Hash(), Result: res, SchemaVersion: SchemaVersion})
+	}
+
+	replay.phase = phaseFinalization
+	if finalizer, ok := api.backend.Engine().(systemCallFinalizer); ok {
+		finalizer.FinalizeWithEVMRunnerFactory(chain, header, statedb, txs, replay)
+	} else {
+		api.backend.Engine().Finalize(chain, header, statedb, txs)
+	}
+
+	result.SystemCalls = replay.results
+	result.StateRoot = header.Root
+	result.RootMatches = header.Root == block.Root()
+	return result, nil
+}
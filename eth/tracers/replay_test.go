@@ -0,0 +1,222 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/accounts/abi"
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/abis"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/internal/ethapi"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+func TestReplayBlock(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		accounts[1].addr: {Balance: big.NewInt(params.Ether)},
+	}}
+	signer := types.HomesteadSigner{}
+	var txHash common.Hash
+	backend := newTestBackend(t, 2, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), accounts[1].addr, big.NewInt(1000), params.TxGas, b.MinimumGasPrice(nil), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+		txHash = tx.Hash()
+	})
+	api := NewAPI(backend)
+
+	head := backend.chain.CurrentBlock()
+	result, err := api.ReplayBlock(context.Background(), head.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to replay block: %v", err)
+	}
+	if !result.RootMatches || result.StateRoot != head.Root() {
+		t.Errorf("state root mismatch: have %x, want %x", result.StateRoot, head.Root())
+	}
+	if len(result.Transactions) != 1 || result.Transactions[0].TxHash != txHash {
+		t.Fatalf("transaction traces mismatch: have %v", result.Transactions)
+	}
+	if res, ok := result.Transactions[0].Result.(*ethapi.ExecutionResult); !ok || res.Gas != params.TxGas || res.Failed {
+		t.Errorf("transaction trace mismatch: have %v", result.Transactions[0].Result)
+	}
+	// Without core contracts, the only system calls are the registry lookups
+	for _, call := range result.SystemCalls {
+		if call.Type != "STATICCALL" || call.To != config.RegistrySmartContractAddress || call.Label != "Registry.getAddressFor" {
+			t.Errorf("system call traced without core contracts: %+v", call)
+		}
+	}
+
+	if _, err := api.ReplayBlock(context.Background(), backend.chain.Genesis().Hash(), nil); err == nil {
+		t.Errorf("genesis replayed")
+	}
+}
+
+func TestTracingEVMRunner(t *testing.T) {
+	t.Parallel()
+
+	// The contract stores 1 at slot 0
+	contract := common.HexToAddress("0xc0de")
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		contract: {Code: common.FromHex("0x600160005500"), Balance: big.NewInt(0)},
+	}}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {})
+	api := NewAPI(backend)
+
+	head := backend.chain.CurrentBlock()
+	statedb, err := backend.chain.StateAt(head.Root())
+	if err != nil {
+		t.Fatalf("failed to get the state: %v", err)
+	}
+	statedb.Prepare(common.Hash{}, 0)
+	statedb.AddAddressToAccessList(contract)
+	calls := newSystemCallTracer(context.Background(), api.chainContext(context.Background()), nil, head.Hash(), systemCallLabels{}, phaseTransaction)
+	calls.txIndex = 3
+	vmRunner := calls.NewEVMRunner(head.Header(), statedb)
+
+	if _, err := vmRunner.Query(contract, nil, 100000); err == nil {
+		t.Errorf("static call wrote state")
+	}
+	if _, err := vmRunner.Execute(contract, []byte{0x01}, 100000, big.NewInt(0)); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+	if statedb.GetState(contract, common.Hash{}) != common.BigToHash(big.NewInt(1)) {
+		t.Errorf("execution didn't write state")
+	}
+
+	if _, err := vmRunner.ExecuteAndDiscardChanges(contract, nil, 100000, big.NewInt(0)); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	if len(calls.results) != 3 {
+		t.Fatalf("traced calls mismatch: have %d, want 3", len(calls.results))
+	}
+	if query := calls.results[0]; query.Type != "STATICCALL" || query.Error == "" {
+		t.Errorf("traced query mismatch: have %+v", query)
+	}
+	trace := calls.results[1]
+	if trace.Phase != phaseTransaction || trace.TxIndex == nil || *trace.TxIndex != 3 || trace.Type != "CALL" || trace.Discarded || trace.To != contract || len(trace.Input) != 1 || trace.Error != "" {
+		t.Errorf("traced call mismatch: have %+v", trace)
+	}
+	if res, ok := trace.Result.(*ethapi.ExecutionResult); !ok || len(res.StructLogs) != 4 || res.Failed {
+		t.Errorf("call trace mismatch: have %+v", trace.Result)
+	}
+	if discarded := calls.results[2]; discarded.Type != "CALL" || !discarded.Discarded {
+		t.Errorf("traced discarded call mismatch: have %+v", discarded)
+	}
+}
+
+func TestSystemCallLabels(t *testing.T) {
+	random := common.HexToAddress("0xce12")
+	labels := systemCallLabels{
+		config.RegistrySmartContractAddress: {name: "Registry", abi: abis.Registry},
+		random:                              {name: "Random", abi: abis.Random},
+		common.HexToAddress("0xce13"):       {name: "Reserve"},
+	}
+	input := func(contract *abi.ABI, method string) []byte { return contract.Methods[method].ID }
+
+	tests := []struct {
+		to    common.Address
+		input []byte
+		want  string
+	}{
+		{random, input(abis.Random, "revealAndCommit"), "Random.revealAndCommit"},
+		{config.RegistrySmartContractAddress, input(abis.Registry, "getAddressFor"), "Registry.getAddressFor"},
+		{random, []byte{0x01, 0x02, 0x03, 0x04}, "Random"},
+		{common.HexToAddress("0xce13"), input(abis.Random, "revealAndCommit"), "Reserve"},
+		{common.HexToAddress("0xcafe"), input(abis.FeeCurrency, "debitGasFees"), "FeeCurrency.debitGasFees"},
+		{common.HexToAddress("0xcafe"), nil, ""},
+	}
+	for i, tt := range tests {
+		if have := labels.label(tt.to, tt.input); have != tt.want {
+			t.Errorf("test %d: label mismatch: have %q, want %q", i, have, tt.want)
+		}
+	}
+}
+
+func TestTraceBlockSystemCalls(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		accounts[1].addr: {Balance: big.NewInt(params.Ether)},
+		config.RegistrySmartContractAddress: { // Registry Proxy
+			Code: testutil.RegistryProxyOpcodes,
+			Storage: map[common.Hash]common.Hash{
+				common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc"): common.HexToHash("0xce11"), // Registry Implementation
+			},
+			Balance: big.NewInt(0),
+		},
+		common.HexToAddress("0xce11"): { // Registry Implementation
+			Code:    testutil.RegistryOpcodes,
+			Balance: big.NewInt(0),
+		},
+	}}
+	signer := types.HomesteadSigner{}
+	var txHash common.Hash
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), accounts[1].addr, big.NewInt(1000), params.TxGas, b.MinimumGasPrice(nil), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+		txHash = tx.Hash()
+	})
+	api := NewAPI(backend)
+	head := backend.chain.CurrentBlock()
+
+	// The system calls are only traced on demand
+	results, err := api.traceBlock(context.Background(), head, nil)
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	if len(results) != 1 || results[0].SystemCalls != nil {
+		t.Fatalf("block traces mismatch: have %v", results)
+	}
+
+	results, err = api.traceBlock(context.Background(), head, &TraceConfig{SystemCalls: true})
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	if len(results) != 2 || results[0].TxHash != head.Hash() || results[1].TxHash != txHash {
+		t.Fatalf("block traces mismatch: have %v", results)
+	}
+	if res, ok := results[1].Result.(*ethapi.ExecutionResult); !ok || res.Gas != params.TxGas || res.Failed {
+		t.Errorf("transaction trace mismatch: have %v", results[1].Result)
+	}
+	for _, call := range results[0].SystemCalls {
+		if call.Phase != phaseInitialization || call.TxIndex != nil {
+			t.Errorf("initialization call mismatch: have %+v", call)
+		}
+		// Nothing is registered, so only the registry is called
+		if call.Type != "STATICCALL" || call.To != config.RegistrySmartContractAddress || call.Label != "Registry.getAddressFor" {
+			t.Errorf("initialization call mismatch: have %+v", call)
+		}
+	}
+	for _, call := range results[1].SystemCalls {
+		if call.Phase != phaseTransaction || call.TxIndex == nil || *call.TxIndex != 0 {
+			t.Errorf("transaction call mismatch: have %+v", call)
+		}
+	}
+}
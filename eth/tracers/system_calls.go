@@ -0,0 +1,287 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/accounts/abi"
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/consensus"
+	"github.com/celo-org/celo-blockchain/contracts"
+	"github.com/celo-org/celo-blockchain/contracts/abis"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/core/vm/vmcontext"
+)
+
+// Phases of the processing of a block the system calls are made in
+const (
+	phaseInitialization = "initialization" // Randomness reveal and commit, and reads of the block parameters, before the transactions
+	phaseTransaction    = "transaction"    // Fee debits and credits of a transaction
+	phaseFinalization   = "finalization"   // Gas price minimum update and epoch rewards, after the transactions
+)
+
+// systemCallFinalizer is implemented by the consensus engines making system calls
+// when finalizing a block, letting them be made through a tracing EVMRunner.
+type systemCallFinalizer interface {
+	FinalizeWithEVMRunnerFactory(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, factory vm.EVMRunnerFactory)
+}
+
+// systemContracts are the registered core contracts the system calls are labeled
+// with. The stable token is called as a fee currency.
+var systemContracts = []struct {
+	name string
+	id   common.Hash
+	abi  *abi.ABI
+}{
+	{"BlockchainParameters", config.BlockchainParametersRegistryId, abis.BlockchainParameters},
+	{"Election", config.ElectionRegistryId, abis.Elections},
+	{"EpochRewards", config.EpochRewardsRegistryId, abis.EpochRewards},
+	{"FeeCurrencyWhitelist", config.FeeCurrencyWhitelistRegistryId, abis.FeeCurrencyWhitelist},
+	{"FeeHandler", config.FeeHandlerId, nil},
+	{"Freezer", config.FreezerRegistryId, abis.Freezer},
+	{"GasPriceMinimum", config.GasPriceMinimumRegistryId, abis.GasPriceMinimum},
+	{"GoldToken", config.GoldTokenRegistryId, abis.GoldToken},
+	{"LockedGold", config.LockedGoldRegistryId, nil},
+	{"Random", config.RandomRegistryId, abis.Random},
+	{"Reserve", config.ReserveRegistryId, nil},
+	{"SortedOracles", config.SortedOraclesRegistryId, abis.SortedOracles},
+	{"StableToken", config.StableTokenRegistryId, abis.FeeCurrency},
+	{"Validators", config.ValidatorsRegistryId, abis.Validators},
+}
+
+// systemCallTraceResult is the trace of a system contract call made while
+// processing a block.
+type systemCallTraceResult struct {
+	Phase         string          `json:"phase"`
+	TxIndex       *hexutil.Uint64 `json:"txIndex,omitempty"` // Transaction the call was made for, in the transaction phase
+	Type          string          `json:"type"`              // CALL, or STATICCALL for the queries
+	Label         string          `json:"label,omitempty"`   // Core contract and method called, like Random.revealAndCommit
+	Discarded     bool            `json:"discarded,omitempty"`
+	From          common.Address  `json:"from"`
+	To            common.Address  `json:"to"`
+	Input         hexutil.Bytes   `json:"input"`
+	Result        interface{}     `json:"result,omitempty"` // Trace results produced by the tracer
+	Error         string          `json:"error,omitempty"`  // Error of the call or of the tracer
+	SchemaVersion uint64          `json:"schemaVersion"`    // Version of the layout of Result, see SchemaVersion
+}
+
+// systemContract is a core contract system calls are labeled with.
+type systemContract struct {
+	name string
+	abi  *abi.ABI // Nil if the methods of the contract aren't known
+}
+
+// systemCallLabels labels the system calls with the core contracts registered at
+// the start of a block.
+type systemCallLabels map[common.Address]*systemContract
+
+// newSystemCallLabels resolves the addresses of the core contracts from the
+// registry, on top of the given header and state.
+func newSystemCallLabels(chain core.ChainContext, header *types.Header, state vm.StateDB) systemCallLabels {
+	labels := systemCallLabels{
+		config.RegistrySmartContractAddress: {name: "Registry", abi: abis.Registry},
+	}
+	vmRunner := &tracingEVMRunner{chain: chain, header: header, state: state}
+	for _, c := range systemContracts {
+		if address, err := contracts.GetRegisteredAddress(vmRunner, c.id); err == nil {
+			labels[address] = &systemContract{name: c.name, abi: c.abi}
+		}
+	}
+	return labels
+}
+
+// label returns the contract and the method a system call is made to. The calls
+// to unregistered contracts are assumed to be made to fee currencies.
+func (l systemCallLabels) label(to common.Address, input []byte) string {
+	contract, ok := l[to]
+	if !ok {
+		contract = &systemContract{abi: abis.FeeCurrency}
+	}
+	if contract.abi != nil && len(input) >= 4 {
+		if method, err := contract.abi.MethodById(input[:4]); err == nil {
+			if !ok {
+				return "FeeCurrency." + method.RawName
+			}
+			return contract.name + "." + method.RawName
+		}
+	}
+	return contract.name
+}
+
+// systemCallTracer hands out the EVMRunners tracing the system calls made while
+// processing a block, and collects their traces.
+type systemCallTracer struct {
+	ctx       context.Context
+	chain     core.ChainContext
+	config    *TraceConfig
+	blockHash common.Hash
+	labels    systemCallLabels
+
+	phase   string
+	txIndex int
+	results []*systemCallTraceResult
+}
+
+// newSystemCallTracer creates a tracer of the system calls of the block of the
+// given header, tracing each call with a tracer of the given configuration.
+func newSystemCallTracer(ctx context.Context, chain core.ChainContext, config *TraceConfig, blockHash common.Hash, labels systemCallLabels, phase string) *systemCallTracer {
+	return &systemCallTracer{
+		ctx:       ctx,
+		chain:     chain,
+		config:    config,
+		blockHash: blockHash,
+		labels:    labels,
+		phase:     phase,
+		results:   []*systemCallTraceResult{},
+	}
+}
+
+// NewEVMRunner implements vm.EVMRunnerFactory.
+func (t *systemCallTracer) NewEVMRunner(header *types.Header, state vm.StateDB) vm.EVMRunner {
+	return &tracingEVMRunner{calls: t, chain: t.chain, header: header, state: state}
+}
+
+// record appends the trace of a system call to the results.
+func (t *systemCallTracer) record(trace *systemCallTraceResult, tracer vm.EVMLogger, gas uint64, ret []byte, callErr error) {
+	trace.Phase = t.phase
+	trace.Label = t.labels.label(trace.To, trace.Input)
+	trace.SchemaVersion = SchemaVersion
+	if t.phase == phaseTransaction {
+		txIndex := hexutil.Uint64(t.txIndex)
+		trace.TxIndex = &txIndex
+	}
+	res, err := traceResult(tracer, gas, callErr != nil, ret)
+	switch {
+	case callErr != nil:
+		trace.Result, trace.Error = res, callErr.Error()
+	case err != nil:
+		trace.Error = err.Error()
+	default:
+		trace.Result = res
+	}
+	t.results = append(t.results, trace)
+}
+
+// tracingEVMRunner is an EVMRunner tracing its calls, each with a tracer of the
+// configuration of the system call tracer, if any.
+type tracingEVMRunner struct {
+	calls  *systemCallTracer // Untraced runner if nil
+	chain  core.ChainContext
+	header *types.Header
+	state  vm.StateDB
+
+	dontMeterGas bool
+}
+
+// call runs a call, or a static one if readOnly, reverting its changes if discard.
+func (ev *tracingEVMRunner) call(sender, recipient common.Address, input []byte, gas uint64, value *big.Int, readOnly, discard bool) ([]byte, error) {
+	var (
+		tracer vm.EVMLogger
+		cfg    vm.Config
+	)
+	if ev.calls != nil {
+		txctx := &Context{BlockHash: ev.calls.blockHash}
+		if ev.calls.phase == phaseTransaction {
+			txctx.TxIndex = ev.calls.txIndex
+		}
+		// The tracer config is checked before the tracing starts, so an invalid one
+		// is just left untraced.
+		if t, cancel, err := newTracer(ev.calls.ctx, txctx, ev.calls.config); err == nil {
+			defer cancel()
+			tracer, cfg = t, vm.Config{Debug: true, Tracer: t}
+		}
+	}
+
+	blockContext := core.NewEVMBlockContext(ev.header, ev.chain, nil)
+	txContext := vm.TxContext{
+		Origin:   sender,
+		GasPrice: common.Big0,
+	}
+	evm := vm.NewEVM(blockContext, txContext, ev.state, ev.chain.Config(), cfg)
+	if ev.dontMeterGas {
+		evm.StopGasMetering()
+	}
+
+	var (
+		ret         []byte
+		leftOverGas uint64
+		err         error
+	)
+	if readOnly {
+		// Unlike calls, static calls are only traced from within a frame, so the
+		// frame of the query is opened here
+		start := time.Now()
+		if tracer != nil {
+			tracer.CaptureStart(evm, sender, recipient, false, input, gas, common.Big0)
+		}
+		ret, leftOverGas, err = evm.StaticCall(vm.AccountRef(sender), recipient, input, gas)
+		if tracer != nil {
+			tracer.CaptureEnd(ret, gas-leftOverGas, time.Since(start), err)
+		}
+	} else {
+		snapshot := ev.state.Snapshot()
+		ret, leftOverGas, err = evm.Call(vm.AccountRef(sender), recipient, input, gas, value)
+		if discard {
+			ev.state.RevertToSnapshot(snapshot)
+		}
+	}
+	if tracer != nil {
+		trace := &systemCallTraceResult{Type: "CALL", Discarded: discard, From: sender, To: recipient, Input: common.CopyBytes(input)}
+		if readOnly {
+			trace.Type = "STATICCALL"
+		}
+		ev.calls.record(trace, tracer, gas-leftOverGas, ret, err)
+	}
+	return ret, err
+}
+
+func (ev *tracingEVMRunner) Execute(recipient common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, err error) {
+	return ev.call(vmcontext.VMAddress, recipient, input, gas, value, false, false)
+}
+
+func (ev *tracingEVMRunner) ExecuteFrom(sender, recipient common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, err error) {
+	return ev.call(sender, recipient, input, gas, value, false, false)
+}
+
+func (ev *tracingEVMRunner) ExecuteAndDiscardChanges(recipient common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, err error) {
+	return ev.call(vmcontext.VMAddress, recipient, input, gas, value, false, true)
+}
+
+func (ev *tracingEVMRunner) Query(recipient common.Address, input []byte, gas uint64) (ret []byte, err error) {
+	return ev.call(vmcontext.VMAddress, recipient, input, gas, nil, true, false)
+}
+
+func (ev *tracingEVMRunner) StopGasMetering() {
+	ev.dontMeterGas = true
+}
+
+func (ev *tracingEVMRunner) StartGasMetering() {
+	ev.dontMeterGas = false
+}
+
+// GetStateDB returns the state the calls are made on.
+func (ev *tracingEVMRunner) GetStateDB() vm.StateDB {
+	return ev.state
+}
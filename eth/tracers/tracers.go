@@ -25,6 +25,14 @@ import (
 	"github.com/celo-org/celo-blockchain/core/vm"
 )
 
+// SchemaVersion is the version of the layout of the trace results. It is bumped
+// whenever a release changes the output of the struct logger or of the built-in
+// tracers, so that indexers can tell which layout they are decoding. The golden
+// files of internal/tracetest pin the output of every version.
+//
+//   - 1: layout of the upstream struct logger and call tracer.
+const SchemaVersion = 1
+
 // Context contains some contextual infos for a transaction execution that is not
 // available from within the EVM object.
 type Context struct {
@@ -42,6 +50,15 @@ type Tracer interface {
 	Stop(err error)
 }
 
+// StateDiffTracer is implemented by the tracers also reporting the changes the
+// traced transaction made to the state. CaptureStateDiff is handed the state
+// before and after the transaction, the accounts and storage slots it modified,
+// and the fee currencies whitelisted at its block.
+type StateDiffTracer interface {
+	Tracer
+	CaptureStateDiff(pre, post vm.StateDB, dirties map[common.Address][]common.Hash, feeCurrencies []common.Address)
+}
+
 type lookupFunc func(string, *Context) (Tracer, error)
 
 var (
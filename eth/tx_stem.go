@@ -0,0 +1,164 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/mclock"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+const (
+	// stemEmbargoMin is the minimum time a transaction stays in its stem phase
+	// before being broadcast by the local node, unless the network picked it up.
+	stemEmbargoMin = 10 * time.Second
+
+	// stemEmbargoRand is the maximum random time added to stemEmbargoMin, so that
+	// the fluff of the originating node can't be told apart by its timing.
+	stemEmbargoRand = 20 * time.Second
+)
+
+var (
+	stemRelayedMeter = metrics.NewRegisteredMeter("eth/stem/relayed", nil)
+	stemFluffedMeter = metrics.NewRegisteredMeter("eth/stem/fluffed", nil)
+)
+
+// stemRelay implements a Dandelion style two phase relay for the transactions
+// submitted to the local node. In the stem phase a transaction is only sent to
+// a single random peer, which broadcasts it (the fluff phase) as if it were its
+// originator. Should the transaction not reach any other peer before its embargo
+// expires, the local node broadcasts it itself.
+type stemRelay struct {
+	h     *handler
+	clock mclock.Clock
+
+	mu     sync.Mutex
+	local  map[common.Hash]struct{}     // Locally submitted txs, not relayed yet
+	stems  map[common.Hash]mclock.Timer // Txs in their stem phase, with their embargo
+	closed bool
+}
+
+func newStemRelay(h *handler, clock mclock.Clock) *stemRelay {
+	return &stemRelay{
+		h:     h,
+		clock: clock,
+		local: make(map[common.Hash]struct{}),
+		stems: make(map[common.Hash]mclock.Timer),
+	}
+}
+
+// markLocal flags a transaction about to be added to the pool as submitted
+// locally, its broadcast will go through the stem phase.
+func (s *stemRelay) markLocal(hash common.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.local[hash] = struct{}{}
+}
+
+// unmarkLocal drops a transaction that could not be added to the pool.
+func (s *stemRelay) unmarkLocal(hash common.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.local, hash)
+}
+
+// inStem reports whether a transaction must not be broadcast or announced yet.
+func (s *stemRelay) inStem(hash common.Hash) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, local := s.local[hash]
+	_, stem := s.stems[hash]
+	return local || stem
+}
+
+// relay sends the local transactions among txs to a single stem peer each, and
+// returns the remaining ones, to be broadcast as usual.
+func (s *stemRelay) relay(txs types.Transactions) types.Transactions {
+	s.mu.Lock()
+	var (
+		fluff = make(types.Transactions, 0, len(txs))
+		stems = make(map[*ethPeer][]common.Hash)
+	)
+	for _, tx := range txs {
+		hash := tx.Hash()
+		if _, ok := s.local[hash]; !ok {
+			if _, ok := s.stems[hash]; !ok {
+				fluff = append(fluff, tx)
+			}
+			continue
+		}
+		delete(s.local, hash)
+
+		peers := s.h.peers.peersWithoutTransaction(hash)
+		if len(peers) == 0 || s.closed {
+			fluff = append(fluff, tx)
+			continue
+		}
+		peer := peers[rand.Intn(len(peers))]
+		stems[peer] = append(stems[peer], hash)
+		embargo := stemEmbargoMin + time.Duration(rand.Int63n(int64(stemEmbargoRand)))
+		s.stems[hash] = s.clock.AfterFunc(embargo, func() { s.expire(tx) })
+	}
+	s.mu.Unlock()
+
+	for peer, hashes := range stems {
+		stemRelayedMeter.Mark(int64(len(hashes)))
+		peer.AsyncSendTransactions(hashes)
+	}
+	return fluff
+}
+
+// expire ends the stem phase of a transaction, broadcasting it if no peer but
+// the stem one knows about it yet.
+func (s *stemRelay) expire(tx *types.Transaction) {
+	hash := tx.Hash()
+
+	s.mu.Lock()
+	if _, ok := s.stems[hash]; !ok || s.closed {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.stems, hash)
+	s.mu.Unlock()
+
+	if s.h.txpool.Get(hash) == nil {
+		return // Already included or dropped
+	}
+	if known := s.h.peers.len() - len(s.h.peers.peersWithoutTransaction(hash)); known > 1 {
+		return // Propagated by the network
+	}
+	log.Debug("Stem embargo expired, broadcasting transaction", "hash", hash)
+	stemFluffedMeter.Mark(1)
+	s.h.BroadcastTransactions(types.Transactions{tx})
+}
+
+// close stops the pending embargo timers.
+func (s *stemRelay) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for hash, timer := range s.stems {
+		timer.Stop()
+		delete(s.stems, hash)
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/mclock"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/eth/protocols/eth"
+	"github.com/celo-org/celo-blockchain/p2p"
+	"github.com/celo-org/celo-blockchain/p2p/enode"
+)
+
+// stemTest is a source handler relaying its local transactions through their stem
+// phase, connected to a number of sinks.
+type stemTest struct {
+	clock  *mclock.Simulated
+	source *testHandler
+	sinks  []*testHandler
+	txChs  []chan core.NewTxsEvent
+}
+
+func newStemTest(t *testing.T, numSinks int) *stemTest {
+	st := &stemTest{clock: new(mclock.Simulated), source: newTestHandler()}
+	st.source.handler.acceptTxs = 1
+	st.source.handler.stem = newStemRelay(st.source.handler, st.clock)
+	t.Cleanup(st.source.close)
+
+	for i := 0; i < numSinks; i++ {
+		sink := newTestHandler()
+		sink.handler.acceptTxs = 1
+		t.Cleanup(sink.close)
+
+		sourcePipe, sinkPipe := p2p.MsgPipe()
+		t.Cleanup(func() { sourcePipe.Close(); sinkPipe.Close() })
+		sourcePeer := eth.NewPeer(istanbul.Celo67, p2p.NewPeerPipe(enode.ID{byte(i + 1)}, "", nil, sourcePipe), sourcePipe, st.source.txpool)
+		sinkPeer := eth.NewPeer(istanbul.Celo67, p2p.NewPeerPipe(enode.ID{0}, "", nil, sinkPipe), sinkPipe, sink.txpool)
+		t.Cleanup(func() { sourcePeer.Close(); sinkPeer.Close() })
+
+		go st.source.handler.runEthPeer(sourcePeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(st.source.handler), peer)
+		})
+		go sink.handler.runEthPeer(sinkPeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(sink.handler), peer)
+		})
+		txCh := make(chan core.NewTxsEvent, 16)
+		sub := sink.txpool.SubscribeNewTxsEvent(txCh)
+		t.Cleanup(sub.Unsubscribe)
+
+		st.sinks = append(st.sinks, sink)
+		st.txChs = append(st.txChs, txCh)
+	}
+	for start := time.Now(); st.source.handler.peers.len() < numSinks; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("peers not connected: have %d, want %d", st.source.handler.peers.len(), numSinks)
+		}
+	}
+	return st
+}
+
+// submit adds a local transaction to the source pool, like the API backend does.
+func (st *stemTest) submit(nonce uint64) *types.Transaction {
+	tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil), types.HomesteadSigner{}, testKey)
+	st.source.handler.stem.markLocal(tx.Hash())
+	st.source.txpool.AddRemotes([]*types.Transaction{tx})
+	return tx
+}
+
+// received returns the sinks having received tx within wait.
+func (st *stemTest) received(t *testing.T, tx *types.Transaction, wait time.Duration) map[int]bool {
+	received := make(map[int]bool)
+	timeout := time.After(wait)
+	for len(received) < len(st.sinks) {
+		select {
+		case <-timeout:
+			return received
+		case <-time.After(10 * time.Millisecond):
+			for i, ch := range st.txChs {
+				select {
+				case ev := <-ch:
+					if len(ev.Txs) != 1 || ev.Txs[0].Hash() != tx.Hash() {
+						t.Fatalf("sink %d: unexpected txs %v", i, ev.Txs)
+					}
+					received[i] = true
+				default:
+				}
+			}
+		}
+	}
+	return received
+}
+
+// Tests that a local transaction is only sent to a single stem peer, and that the
+// source broadcasts it itself once the embargo expires without other peer knowing it.
+func TestStemRelayFluffsOnEmbargo(t *testing.T) {
+	st := newStemTest(t, 3)
+	tx := st.submit(0)
+
+	received := st.received(t, tx, 500*time.Millisecond)
+	if len(received) != 1 {
+		t.Fatalf("stem phase reached %d peers, want 1", len(received))
+	}
+	if !st.source.handler.stem.inStem(tx.Hash()) {
+		t.Fatal("relayed transaction not in its stem phase")
+	}
+	// Nothing happens before the minimum embargo
+	st.clock.Run(stemEmbargoMin - time.Millisecond)
+	if again := st.received(t, tx, 200*time.Millisecond); len(again) != 0 {
+		t.Fatalf("transaction fluffed before the embargo to %d peers", len(again))
+	}
+	// Nobody else got it, the source fluffs it to the remaining peers
+	st.clock.Run(stemEmbargoRand)
+	if st.source.handler.stem.inStem(tx.Hash()) {
+		t.Fatal("transaction still in its stem phase after the embargo")
+	}
+	fluffed := st.received(t, tx, 5*time.Second)
+	if len(fluffed) != 2 {
+		t.Fatalf("fluff reached %d more peers, want 2", len(fluffed))
+	}
+	for i := range fluffed {
+		if received[i] {
+			t.Errorf("sink %d: transaction sent again to the stem peer", i)
+		}
+	}
+}
+
+// Tests that the source doesn't broadcast a transaction picked up by the network
+// during its stem phase.
+func TestStemRelayNetworkFluff(t *testing.T) {
+	st := newStemTest(t, 3)
+	tx := st.submit(0)
+
+	received := st.received(t, tx, 500*time.Millisecond)
+	if len(received) != 1 {
+		t.Fatalf("stem phase reached %d peers, want 1", len(received))
+	}
+	// Another sink learns about the transaction from elsewhere and sends it to the source
+	var relayer int
+	for relayer = range st.sinks {
+		if !received[relayer] {
+			break
+		}
+	}
+	st.sinks[relayer].txpool.AddRemotes([]*types.Transaction{tx})
+	<-st.txChs[relayer]
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		known := st.source.handler.peers.len() - len(st.source.handler.peers.peersWithoutTransaction(tx.Hash()))
+		if known > 1 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("source didn't receive the transaction back")
+		}
+	}
+	st.clock.Run(stemEmbargoMin + stemEmbargoRand)
+	if again := st.received(t, tx, 500*time.Millisecond); len(again) != 0 {
+		t.Fatalf("source fluffed a propagated transaction to %d peers", len(again))
+	}
+}
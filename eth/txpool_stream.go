@@ -0,0 +1,135 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+// pendingStreamChanSize is the size of channel listening to PendingChangesEvent.
+// The number is referenced from the size of tx pool.
+const pendingStreamChanSize = 4096
+
+var (
+	errPendingStreamDisabled = errors.New("pending transactions stream disabled, see --txpool.streamtoken")
+	errPendingStreamToken    = errors.New("invalid pending transactions stream token")
+)
+
+// PendingStreamTx is a pending transaction of the pool added or removed, in a
+// notification of the pending transactions stream.
+type PendingStreamTx struct {
+	Hash    common.Hash         `json:"hash"`
+	From    *common.Address     `json:"from,omitempty"`    // Sender of the transaction added
+	Raw     hexutil.Bytes       `json:"raw,omitempty"`     // Encoding of the transaction added
+	Removed core.PendingRemoval `json:"removed,omitempty"` // Reason of the removal, empty if added
+}
+
+// PendingStreamMessage is a notification of the pending transactions stream. The
+// first one is the snapshot of the pending transactions of the pool, the next
+// ones their changes in order.
+type PendingStreamMessage struct {
+	Snapshot bool               `json:"snapshot"`
+	Txs      []*PendingStreamTx `json:"txs"`
+}
+
+// PublicTxPoolStreamAPI streams the pending transactions of the pool to the
+// subscribers presenting the configured token, such as external block builders
+// mirroring the pool.
+type PublicTxPoolStreamAPI struct {
+	eth *Ethereum
+}
+
+// NewPublicTxPoolStreamAPI creates a new pending transactions stream API.
+func NewPublicTxPoolStreamAPI(eth *Ethereum) *PublicTxPoolStreamAPI {
+	return &PublicTxPoolStreamAPI{eth: eth}
+}
+
+// Pending streams a snapshot of the pending transactions of the pool, then the
+// transactions added to them and removed, with the reason. Applying the changes
+// in order on the snapshot mirrors the pending transactions: an addition sets a
+// transaction, a removal deletes it, either being a no-op if already done.
+func (api *PublicTxPoolStreamAPI) Pending(ctx context.Context, token string) (*rpc.Subscription, error) {
+	expected := api.eth.config.TxPoolStreamToken
+	if expected == "" {
+		return nil, errPendingStreamDisabled
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return nil, errPendingStreamToken
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	var (
+		rpcSub       = notifier.CreateSubscription()
+		signer       = types.LatestSigner(api.eth.blockchain.Config())
+		changes      = make(chan core.PendingChangesEvent, pendingStreamChanSize)
+		pending, sub = api.eth.txPool.SubscribePendingChanges(changes)
+	)
+	go func() {
+		defer sub.Unsubscribe()
+
+		snapshot := &PendingStreamMessage{Snapshot: true, Txs: []*PendingStreamTx{}}
+		for _, txs := range pending {
+			for _, tx := range txs {
+				snapshot.Txs = append(snapshot.Txs, newPendingStreamTx(signer, tx))
+			}
+		}
+		notifier.Notify(rpcSub.ID, snapshot)
+
+		for {
+			select {
+			case ev := <-changes:
+				msg := &PendingStreamMessage{Txs: make([]*PendingStreamTx, 0, len(ev.Changes))}
+				for _, change := range ev.Changes {
+					if change.Tx == nil {
+						msg.Txs = append(msg.Txs, &PendingStreamTx{Hash: change.Hash, Removed: change.Reason})
+					} else {
+						msg.Txs = append(msg.Txs, newPendingStreamTx(signer, change.Tx))
+					}
+				}
+				notifier.Notify(rpcSub.ID, msg)
+			case <-sub.Err():
+				// Fell behind the pool, the mirror can't be kept in sync anymore
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// newPendingStreamTx returns the addition of a transaction in the stream.
+func newPendingStreamTx(signer types.Signer, tx *types.Transaction) *PendingStreamTx {
+	added := &PendingStreamTx{Hash: tx.Hash()}
+	if from, err := types.Sender(signer, tx); err == nil {
+		added.From = &from
+	}
+	added.Raw, _ = tx.MarshalBinary()
+	return added
+}
@@ -477,6 +477,70 @@ func (t *Transaction) V(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*v), nil
 }
 
+func (t *Transaction) FeeCurrency(ctx context.Context) (*common.Address, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	return tx.FeeCurrency(), nil
+}
+
+func (t *Transaction) GatewayFee(ctx context.Context) (*hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	switch tx.Type() {
+	case types.LegacyTxType, types.CeloDynamicFeeTxType:
+		return (*hexutil.Big)(tx.GatewayFee()), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (t *Transaction) GatewayFeeRecipient(ctx context.Context) (*common.Address, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	return tx.GatewayFeeRecipient(), nil
+}
+
+// EffectiveGasPriceInCelo returns the effective gas price of a mined
+// transaction converted to CELO at the exchange rate of its block.
+func (t *Transaction) EffectiveGasPriceInCelo(ctx context.Context) (*hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil || t.block == nil {
+		return nil, err
+	}
+	price := tx.GasPrice()
+	switch tx.Type() {
+	case types.DynamicFeeTxType, types.CeloDynamicFeeTxType, types.CeloDynamicFeeTxV2Type, types.CeloDenominatedTxType:
+		effectivePrice, err := t.EffectiveGasPrice(ctx)
+		if err != nil || effectivePrice == nil {
+			return nil, err
+		}
+		price = effectivePrice.ToInt()
+	}
+	currency := tx.DenominatedFeeCurrency()
+	if currency == nil {
+		return (*hexutil.Big)(price), nil
+	}
+	header, err := t.block.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, toCELO, err := t.backend.ConversionFunctionsForHeader(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	celoPrice, err := toCELO(price, currency)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(celoPrice), nil
+}
+
 type BlockType int
 
 // Block represents an Ethereum block.
@@ -579,11 +643,10 @@ func (b *Block) GasUsed(ctx context.Context) (Long, error) {
 	return Long(header.GasUsed), nil
 }
 
-func (b *Block) BaseFeePerGas(ctx context.Context) (*hexutil.Big, error) {
+func (b *Block) BaseFeePerGas(ctx context.Context, args struct{ Currency *common.Address }) (*hexutil.Big, error) {
 	// To have an external API as compatible to geth as possible, we return the
-	// base for celo gold, here. We can add a separate variable for the base
-	// fees for each currency.
-	return b.BaseFeePerGasForCurrency(ctx, nil)
+	// base for celo gold when no currency is given.
+	return b.BaseFeePerGasForCurrency(ctx, args.Currency)
 }
 
 func (b *Block) BaseFeePerGasForCurrency(ctx context.Context, feeCurrency *common.Address) (*hexutil.Big, error) {
@@ -680,6 +743,52 @@ func (b *Block) TotalDifficulty(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*td), nil
 }
 
+// Randomness represents the randomness revealed and committed in a block.
+type Randomness struct {
+	randomness *types.Randomness
+}
+
+func (r *Randomness) Revealed(ctx context.Context) common.Hash {
+	return r.randomness.Revealed
+}
+
+func (r *Randomness) Committed(ctx context.Context) common.Hash {
+	return r.randomness.Committed
+}
+
+func (b *Block) Randomness(ctx context.Context) (*Randomness, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil || block.Randomness() == nil {
+		return nil, err
+	}
+	return &Randomness{randomness: block.Randomness()}, nil
+}
+
+// EpochSnarkData represents the epoch SNARK data of the last block of an epoch.
+type EpochSnarkData struct {
+	epochSnarkData *types.EpochSnarkData
+}
+
+func (e *EpochSnarkData) Bitmap(ctx context.Context) hexutil.Bytes {
+	return e.epochSnarkData.Bitmap.Bytes()
+}
+
+func (e *EpochSnarkData) Signature(ctx context.Context) hexutil.Bytes {
+	return e.epochSnarkData.Signature
+}
+
+func (b *Block) EpochSnarkData(ctx context.Context) (*EpochSnarkData, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	epochSnarkData := block.EpochSnarkData()
+	if epochSnarkData == nil || epochSnarkData.IsEmpty() {
+		return nil, nil
+	}
+	return &EpochSnarkData{epochSnarkData: epochSnarkData}, nil
+}
+
 // BlockNumberArgs encapsulates arguments to accessors that specify a block number.
 type BlockNumberArgs struct {
 	// TODO: Ideally we could use input unions to allow the query to specify the
@@ -880,7 +989,7 @@ func (b *Block) Call(ctx context.Context, args struct {
 			return nil, err
 		}
 	}
-	result, err := ethapi.DoCall(ctx, b.backend, args.Data, *b.numberOrHash, nil, 5*time.Second, b.backend.RPCGasCap(), false)
+	result, err := ethapi.DoCall(ctx, b.backend, args.Data, *b.numberOrHash, nil, nil, 5*time.Second, b.backend.RPCGasCap(), false)
 	if err != nil {
 		return nil, err
 	}
@@ -950,7 +1059,7 @@ func (p *Pending) Call(ctx context.Context, args struct {
 	Data ethapi.TransactionArgs
 }) (*CallResult, error) {
 	pendingBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
-	result, err := ethapi.DoCall(ctx, p.backend, args.Data, pendingBlockNr, nil, 5*time.Second, p.backend.RPCGasCap(), false)
+	result, err := ethapi.DoCall(ctx, p.backend, args.Data, pendingBlockNr, nil, nil, 5*time.Second, p.backend.RPCGasCap(), false)
 	if err != nil {
 		return nil, err
 	}
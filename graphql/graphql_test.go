@@ -179,6 +179,16 @@ func TestGraphQLTransactionSerialization(t *testing.T) {
 			want: `{"data":{"transaction":{"gas":"0xc350","gasUsed":25204,"gasPrice":"0xa","maxFeePerGas":"0x7530","maxPriorityFeePerGas":"0xa","effectiveGasPrice":"0xa","index":2,"from":{"address":"0x71562b71999873db5b286df957af199ec94617f7"},"to":{"address":"0x0000000000000000000000000000000000000dad"},"value":"0x32","inputData":"0x","block":{"transactionCount":3,"baseFeePerGas":"0x0"},"status":1,"type":2}}}`,
 			code: 200,
 		},
+		{
+			body: `{"query":"{ transaction(hash: \"0x22f565cfeb33d5e6f81c8923ef0633a49fef0848a089a6d8564b655d5605fb13\") { feeCurrency gatewayFee gatewayFeeRecipient effectiveGasPriceInCelo }}"}`,
+			want: `{"data":{"transaction":{"feeCurrency":null,"gatewayFee":null,"gatewayFeeRecipient":null,"effectiveGasPriceInCelo":"0xa"}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query":"{ transaction(hash: \"0xa863609020c7651e840465da231bcfd1c853c295d62dae6551624f800c118e5a\") { feeCurrency gatewayFee gatewayFeeRecipient effectiveGasPriceInCelo block { randomness { revealed committed } epochSnarkData { bitmap signature } baseFeePerGas(currency: \"0x0000000000000000000000000000000000000000\") } }}"}`,
+			want: `{"data":{"transaction":{"feeCurrency":null,"gatewayFee":"0x0","gatewayFeeRecipient":null,"effectiveGasPriceInCelo":"0x0","block":{"randomness":{"revealed":"0x0000000000000000000000000000000000000000000000000000000000000000","committed":"0x0000000000000000000000000000000000000000000000000000000000000000"},"epochSnarkData":null,"baseFeePerGas":"0x0"}}}}`,
+			code: 200,
+		},
 	} {
 		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", strings.NewReader(tt.body))
 		if err != nil {
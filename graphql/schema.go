@@ -138,6 +138,37 @@ const schema string = `
         #Envelope transaction support
         type: Int
         accessList: [AccessTuple!]
+        # FeeCurrency is the currency the fees of this transaction are paid in.
+        # This is null for the transactions paying their fees in CELO.
+        feeCurrency: Address
+        # GatewayFee is the fee paid to the gateway fee recipient, in the fee
+        # currency. This is null for the transaction types without a gateway fee.
+        gatewayFee: BigInt
+        # GatewayFeeRecipient is the account the gateway fee is paid to, or null
+        # if there is none.
+        gatewayFeeRecipient: Address
+        # EffectiveGasPriceInCelo is the effective gas price converted to CELO at
+        # the exchange rate of the block this transaction was mined in. If the
+        # transaction has not yet been mined, this field will be null.
+        effectiveGasPriceInCelo: BigInt
+    }
+
+    # Randomness is the randomness revealed by the proposer of a block, along with
+    # the commitment to the one it will reveal next.
+    type Randomness {
+        # Revealed is the randomness revealed in this block.
+        revealed: Bytes32!
+        # Committed is the commitment to the randomness revealed next.
+        committed: Bytes32!
+    }
+
+    # EpochSnarkData is the aggregated signature of the validators of the next
+    # epoch, carried by the last block of an epoch.
+    type EpochSnarkData {
+        # Bitmap is the bitmap of the validators who signed.
+        bitmap: Bytes!
+        # Signature is the aggregated signature.
+        signature: Bytes!
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -184,7 +215,8 @@ const schema string = `
         # GasUsed is the amount of gas that was used executing transactions in this block.
         gasUsed: Long!
         # BaseFeePerGas is the fee perunit of gas burned by the protocol in this block.
-		baseFeePerGas: BigInt
+        # It is denominated in the given fee currency, or in CELO if none is given.
+		baseFeePerGas(currency: Address): BigInt
         # Timestamp is the unix timestamp at which this block was mined.
         timestamp: Long!
         # LogsBloom is a bloom filter that can be used to check if a block may
@@ -193,6 +225,12 @@ const schema string = `
         # TotalDifficulty is the sum of all difficulty values up to and including
         # this block.
         totalDifficulty: BigInt!
+        # Randomness is the randomness revealed and committed in this block. If
+        # the block is not available, this field will be null.
+        randomness: Randomness
+        # EpochSnarkData is the epoch SNARK data of this block. This is null for
+        # the blocks which are not the last of an epoch.
+        epochSnarkData: EpochSnarkData
         transactions: [Transaction!]
         # TransactionAt returns the transaction at the specified index. If
         # transactions are unavailable for this block, or if the index is out of
@@ -103,6 +103,15 @@ type SyncProgress struct {
 	HighestBlock  uint64 // Highest alleged block number in the chain
 	PulledStates  uint64 // Number of state trie entries already downloaded
 	KnownStates   uint64 // Total number of state trie entries known about
+
+	// Snap sync state healing progress
+	HealedTrienodes     uint64 // Number of state trie nodes downloaded
+	HealedTrienodeBytes uint64 // Number of state trie bytes persisted to disk
+	HealedBytecodes     uint64 // Number of bytecodes downloaded
+	HealedBytecodeBytes uint64 // Number of bytecodes persisted to disk
+	HealingNodes        uint64 // Number of state trie nodes and bytecodes known to be missing
+	HealRate            uint64 // Recent number of state trie nodes and bytecodes healed per second
+	HealETA             uint64 // Estimated seconds to heal the known missing nodes, 0 if unknown
 }
 
 // ChainSyncReader wraps access to the node's current sync status. If there's no
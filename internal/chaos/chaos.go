@@ -0,0 +1,143 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package chaos injects faults into a running node, such as slow disks, lost
+// consensus messages or garbage collection pauses, for soak tests to check that
+// the node recovers from them. It must never be enabled in production.
+package chaos
+
+import (
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+// Enabled is the flag the components with injectable faults check when they're
+// created, so that nothing is wrapped in a node running without chaos flags. It
+// has to be set before the node is created.
+var Enabled = false
+
+var (
+	dbLatency        int64  // Delay of the database operations, in nanoseconds
+	dropConsensusPct uint32 // Percentage of the received consensus messages dropped
+
+	droppedMeter  = metrics.NewRegisteredMeter("chaos/consensus/dropped", nil)
+	gcPausesMeter = metrics.NewRegisteredMeter("chaos/gc/pauses", nil)
+)
+
+// SetDBLatency delays every read and write of the wrapped databases by d, none
+// if zero.
+func SetDBLatency(d time.Duration) {
+	atomic.StoreInt64(&dbLatency, int64(d))
+}
+
+// SetDropConsensusPercent makes the validators drop pct percent of the consensus
+// messages they receive, none if zero.
+func SetDropConsensusPercent(pct uint32) {
+	if pct > 100 {
+		pct = 100
+	}
+	atomic.StoreUint32(&dropConsensusPct, pct)
+}
+
+// DropConsensusMsg reports whether a received consensus message is to be dropped.
+func DropConsensusMsg() bool {
+	pct := atomic.LoadUint32(&dropConsensusPct)
+	if pct == 0 || uint32(rand.Intn(100)) >= pct {
+		return false
+	}
+	droppedMeter.Mark(1)
+	return true
+}
+
+// StartGCPauses forces a full garbage collection every interval, until the
+// returned function is called.
+func StartGCPauses(interval time.Duration) (stop func()) {
+	quit := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				start := time.Now()
+				runtime.GC()
+				gcPausesMeter.Mark(1)
+				log.Trace("Injected garbage collection pause", "elapsed", time.Since(start))
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return func() { close(quit) }
+}
+
+// delay waits for the configured database latency.
+func delay() {
+	if d := atomic.LoadInt64(&dbLatency); d > 0 {
+		time.Sleep(time.Duration(d))
+	}
+}
+
+// database delays the key-value operations of a database.
+type database struct {
+	ethdb.Database
+}
+
+// WrapDatabase returns db with its reads and writes delayed by the latency set
+// with SetDBLatency.
+func WrapDatabase(db ethdb.Database) ethdb.Database {
+	return &database{Database: db}
+}
+
+func (db *database) Has(key []byte) (bool, error) {
+	delay()
+	return db.Database.Has(key)
+}
+
+func (db *database) Get(key []byte) ([]byte, error) {
+	delay()
+	return db.Database.Get(key)
+}
+
+func (db *database) Put(key []byte, value []byte) error {
+	delay()
+	return db.Database.Put(key, value)
+}
+
+func (db *database) Delete(key []byte) error {
+	delay()
+	return db.Database.Delete(key)
+}
+
+func (db *database) NewBatch() ethdb.Batch {
+	return &batch{Batch: db.Database.NewBatch()}
+}
+
+// batch delays the writes of a database batch.
+type batch struct {
+	ethdb.Batch
+}
+
+func (b *batch) Write() error {
+	delay()
+	return b.Batch.Write()
+}
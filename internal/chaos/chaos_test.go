@@ -0,0 +1,52 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package chaos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+)
+
+func TestFaults(t *testing.T) {
+	defer SetDropConsensusPercent(0)
+	defer SetDBLatency(0)
+
+	if DropConsensusMsg() {
+		t.Fatalf("consensus message dropped without fault")
+	}
+	SetDropConsensusPercent(100)
+	if !DropConsensusMsg() {
+		t.Fatalf("consensus message kept with all of them dropped")
+	}
+
+	db := WrapDatabase(rawdb.NewMemoryDatabase())
+	SetDBLatency(20 * time.Millisecond)
+	start := time.Now()
+	batch := db.NewBatch()
+	batch.Put([]byte("key"), []byte("value"))
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	if _, err := db.Get([]byte("key")); err != nil {
+		t.Fatalf("failed to read written value: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("database operations not delayed: %v", elapsed)
+	}
+}
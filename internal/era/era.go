@@ -0,0 +1,377 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package era implements the era-style flat files distributing the history of
+// a Celo chain out-of-band: the blocks of an epoch with their receipts, in the
+// e2store format of the era1 files. An era file is made of the entries
+//
+//	Version | (CompressedHeader | CompressedBody | CompressedReceipts | TotalDifficulty)* | Accumulator | BlockIndex
+//
+// The headers carry the Istanbul extra of the blocks, with their aggregated
+// seals, and the bodies their randomness and epoch SNARK data. Unlike in era1
+// files the accumulator is the keccak256 hash of the hashes and total
+// difficulties of the blocks, so that files can be verified without SSZ.
+package era
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/rlp"
+	"github.com/golang/snappy"
+)
+
+// The types of the entries of an era file.
+const (
+	TypeVersion            uint16 = 0x3265
+	TypeCompressedHeader   uint16 = 0x03
+	TypeCompressedBody     uint16 = 0x04
+	TypeCompressedReceipts uint16 = 0x05
+	TypeTotalDifficulty    uint16 = 0x06
+	TypeAccumulator        uint16 = 0x07
+	TypeBlockIndex         uint16 = 0x3266
+)
+
+// Filename returns the name of the era file of an epoch of a network, ending with
+// the first bytes of its accumulator.
+func Filename(network string, epoch uint64, root common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%x.era1", network, epoch, root[:4])
+}
+
+// ComputeAccumulator returns the accumulator of the blocks with the hashes and
+// total difficulties: the keccak256 hash of their concatenation, the difficulties
+// as 32 bytes big endian integers.
+func ComputeAccumulator(hashes []common.Hash, tds []*big.Int) (common.Hash, error) {
+	if len(hashes) != len(tds) {
+		return common.Hash{}, fmt.Errorf("%d hashes for %d total difficulties", len(hashes), len(tds))
+	}
+	hasher := crypto.NewKeccakState()
+	for i, hash := range hashes {
+		if tds[i].Sign() < 0 || tds[i].BitLen() > 256 {
+			return common.Hash{}, fmt.Errorf("invalid total difficulty of block %x", hash)
+		}
+		hasher.Write(hash[:])
+		hasher.Write(common.BigToHash(tds[i]).Bytes())
+	}
+	var root common.Hash
+	hasher.Read(root[:])
+	return root, nil
+}
+
+// Builder writes the contiguous blocks of an era file.
+type Builder struct {
+	w       *writer
+	start   *uint64
+	offsets []int64
+	hashes  []common.Hash
+	tds     []*big.Int
+	written int64
+
+	buf    *bytes.Buffer
+	snappy *snappy.Writer
+}
+
+// NewBuilder returns a builder of an era file written into w.
+func NewBuilder(w io.Writer) *Builder {
+	buf := new(bytes.Buffer)
+	return &Builder{w: newWriter(w), buf: buf, snappy: snappy.NewBufferedWriter(buf)}
+}
+
+// Add appends a block with its receipts and total difficulty. The receipts are
+// stored in their consensus encoding.
+func (b *Builder) Add(block *types.Block, receipts types.Receipts, td *big.Int) error {
+	if b.start == nil {
+		if err := b.write(TypeVersion, nil); err != nil {
+			return err
+		}
+		start := block.NumberU64()
+		b.start = &start
+	} else if want := *b.start + uint64(len(b.offsets)); block.NumberU64() != want {
+		return fmt.Errorf("non contiguous block %d, want %d", block.NumberU64(), want)
+	}
+	header, err := rlp.EncodeToBytes(block.Header())
+	if err != nil {
+		return err
+	}
+	body, err := rlp.EncodeToBytes(block.Body())
+	if err != nil {
+		return err
+	}
+	encReceipts, err := rlp.EncodeToBytes(receipts)
+	if err != nil {
+		return err
+	}
+	b.offsets = append(b.offsets, b.written)
+	for _, entry := range []Entry{{TypeCompressedHeader, header}, {TypeCompressedBody, body}, {TypeCompressedReceipts, encReceipts}} {
+		if err := b.writeCompressed(entry.Type, entry.Value); err != nil {
+			return err
+		}
+	}
+	if err := b.write(TypeTotalDifficulty, bigToLittleEndian(td)); err != nil {
+		return err
+	}
+	b.hashes, b.tds = append(b.hashes, block.Hash()), append(b.tds, new(big.Int).Set(td))
+	return nil
+}
+
+// Finalize writes the accumulator and the block index of the file, returning the
+// accumulator.
+func (b *Builder) Finalize() (common.Hash, error) {
+	if b.start == nil {
+		return common.Hash{}, errors.New("finalizing empty era file")
+	}
+	root, err := ComputeAccumulator(b.hashes, b.tds)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := b.write(TypeAccumulator, root.Bytes()); err != nil {
+		return common.Hash{}, err
+	}
+	// The offsets of the blocks are relative to the index.
+	index := make([]byte, 16+8*len(b.offsets))
+	binary.LittleEndian.PutUint64(index, *b.start)
+	for i, offset := range b.offsets {
+		binary.LittleEndian.PutUint64(index[8+i*8:], uint64(offset-b.written))
+	}
+	binary.LittleEndian.PutUint64(index[8+len(b.offsets)*8:], uint64(len(b.offsets)))
+	if err := b.write(TypeBlockIndex, index); err != nil {
+		return common.Hash{}, err
+	}
+	return root, nil
+}
+
+// write writes an entry, accounting for its size.
+func (b *Builder) write(typ uint16, value []byte) error {
+	n, err := b.w.Write(typ, value)
+	b.written += int64(n)
+	return err
+}
+
+// writeCompressed writes an entry with its value snappy framed.
+func (b *Builder) writeCompressed(typ uint16, value []byte) error {
+	b.buf.Reset()
+	b.snappy.Reset(b.buf)
+	if _, err := b.snappy.Write(value); err != nil {
+		return err
+	}
+	if err := b.snappy.Close(); err != nil {
+		return err
+	}
+	return b.write(typ, b.buf.Bytes())
+}
+
+// ReadAtSeekCloser is the file an era is read from.
+type ReadAtSeekCloser interface {
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// Era is an era file opened for reading.
+type Era struct {
+	f     ReadAtSeekCloser
+	r     *reader
+	start uint64
+	count uint64
+	index int64 // Offset of the block index
+}
+
+// Open opens the era file with the given name.
+func Open(filename string) (*Era, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	e, err := From(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// From returns the era read from f, checking its version and block index.
+func From(f ReadAtSeekCloser) (*Era, error) {
+	e := &Era{f: f, r: newReader(f)}
+	if typ, length, err := e.r.ReadMetadataAt(0); err != nil {
+		return nil, fmt.Errorf("missing version: %v", err)
+	} else if typ != TypeVersion || length != 0 {
+		return nil, fmt.Errorf("invalid version entry %#x of length %d", typ, length)
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	var count [8]byte
+	if size < headerSize+16 {
+		return nil, errors.New("missing block index")
+	}
+	if _, err := f.ReadAt(count[:], size-8); err != nil {
+		return nil, err
+	}
+	e.count = binary.LittleEndian.Uint64(count[:])
+	if e.count == 0 || e.count > uint64(size)/8 {
+		return nil, fmt.Errorf("invalid block count %d", e.count)
+	}
+	e.index = size - headerSize - 16 - 8*int64(e.count)
+	index, _, err := e.r.ReadAt(e.index)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block index: %v", err)
+	}
+	if index.Type != TypeBlockIndex || uint64(len(index.Value)) != 16+8*e.count {
+		return nil, fmt.Errorf("invalid block index entry %#x of length %d", index.Type, len(index.Value))
+	}
+	e.start = binary.LittleEndian.Uint64(index.Value)
+	return e, nil
+}
+
+// Close closes the file of the era.
+func (e *Era) Close() error {
+	return e.f.Close()
+}
+
+// Start returns the number of the first block of the era.
+func (e *Era) Start() uint64 {
+	return e.start
+}
+
+// Count returns the number of blocks of the era.
+func (e *Era) Count() uint64 {
+	return e.count
+}
+
+// GetHeaderByNumber returns the header of the block with the given number.
+func (e *Era) GetHeaderByNumber(num uint64) (*types.Header, error) {
+	data, err := e.readEntry(num, 0, TypeCompressedHeader)
+	if err != nil {
+		return nil, err
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		return nil, fmt.Errorf("invalid header of block %d: %v", num, err)
+	}
+	return header, nil
+}
+
+// GetBlockByNumber returns the block with the given number.
+func (e *Era) GetBlockByNumber(num uint64) (*types.Block, error) {
+	header, err := e.GetHeaderByNumber(num)
+	if err != nil {
+		return nil, err
+	}
+	data, err := e.readEntry(num, 1, TypeCompressedBody)
+	if err != nil {
+		return nil, err
+	}
+	var body types.Body
+	if err := rlp.DecodeBytes(data, &body); err != nil {
+		return nil, fmt.Errorf("invalid body of block %d: %v", num, err)
+	}
+	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Randomness, body.EpochSnarkData), nil
+}
+
+// GetReceiptsByNumber returns the consensus fields of the receipts of the block
+// with the given number.
+func (e *Era) GetReceiptsByNumber(num uint64) (types.Receipts, error) {
+	data, err := e.readEntry(num, 2, TypeCompressedReceipts)
+	if err != nil {
+		return nil, err
+	}
+	var receipts types.Receipts
+	if err := rlp.DecodeBytes(data, &receipts); err != nil {
+		return nil, fmt.Errorf("invalid receipts of block %d: %v", num, err)
+	}
+	return receipts, nil
+}
+
+// GetTotalDifficultyByNumber returns the total difficulty of the block with the
+// given number.
+func (e *Era) GetTotalDifficultyByNumber(num uint64) (*big.Int, error) {
+	data, err := e.readEntry(num, 3, TypeTotalDifficulty)
+	if err != nil {
+		return nil, err
+	}
+	return littleEndianToBig(data), nil
+}
+
+// Accumulator returns the accumulator of the era as stored.
+func (e *Era) Accumulator() (common.Hash, error) {
+	entry, _, err := e.r.ReadAt(e.index - headerSize - common.HashLength)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if entry.Type != TypeAccumulator || len(entry.Value) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("invalid accumulator entry %#x", entry.Type)
+	}
+	return common.BytesToHash(entry.Value), nil
+}
+
+// readEntry returns the value of the entry of a block at the position, after the
+// ones before it, uncompressing it if needed.
+func (e *Era) readEntry(num uint64, position int, typ uint16) ([]byte, error) {
+	if num < e.start || num >= e.start+e.count {
+		return nil, fmt.Errorf("block %d out of the era %d-%d", num, e.start, e.start+e.count-1)
+	}
+	var relative [8]byte
+	if _, err := e.f.ReadAt(relative[:], e.index+headerSize+8+8*int64(num-e.start)); err != nil {
+		return nil, err
+	}
+	offset := e.index + int64(binary.LittleEndian.Uint64(relative[:]))
+	for i := 0; i < position; i++ {
+		_, length, err := e.r.ReadMetadataAt(offset)
+		if err != nil {
+			return nil, err
+		}
+		offset += headerSize + int64(length)
+	}
+	entry, _, err := e.r.ReadAt(offset)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Type != typ {
+		return nil, fmt.Errorf("invalid entry %#x of block %d, want %#x", entry.Type, num, typ)
+	}
+	if typ == TypeTotalDifficulty {
+		return entry.Value, nil
+	}
+	return io.ReadAll(snappy.NewReader(bytes.NewReader(entry.Value)))
+}
+
+// bigToLittleEndian encodes a total difficulty as a 32 bytes little endian integer.
+func bigToLittleEndian(n *big.Int) []byte {
+	b := common.BigToHash(n).Bytes()
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
+
+// littleEndianToBig decodes a little endian integer.
+func littleEndianToBig(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
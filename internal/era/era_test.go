@@ -0,0 +1,108 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
+)
+
+func TestEraRoundTrip(t *testing.T) {
+	var (
+		dir, _   = os.MkdirTemp("", "era")
+		blocks   []*types.Block
+		receipts []types.Receipts
+		parent   common.Hash
+	)
+	defer os.RemoveAll(dir)
+	for i := 0; i < 8; i++ {
+		header := &types.Header{Number: big.NewInt(int64(100 + i)), ParentHash: parent, Extra: bytes.Repeat([]byte{byte(i)}, 40)}
+		tx := types.NewTransaction(uint64(i), common.Address{0x01}, big.NewInt(int64(i)), 21000, big.NewInt(1), nil)
+		randomness := &types.Randomness{Revealed: common.Hash{byte(i)}, Committed: common.Hash{byte(i + 1)}}
+		epochSnarkData := &types.EpochSnarkData{Bitmap: big.NewInt(int64(i)), Signature: []byte{byte(i)}}
+		block := types.NewBlockWithHeader(header).WithBody([]*types.Transaction{tx}, randomness, epochSnarkData)
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: uint64(21000 * i), Logs: []*types.Log{{Address: common.Address{byte(i)}, Data: []byte{byte(i)}}}}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		blocks, receipts, parent = append(blocks, block), append(receipts, types.Receipts{receipt}), block.Hash()
+	}
+	f, err := os.Create(filepath.Join(dir, "test.era1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := NewBuilder(f)
+	for i, block := range blocks {
+		if err := builder.Add(block, receipts[i], big.NewInt(int64(101+i))); err != nil {
+			t.Fatalf("failed to add block %d: %v", i, err)
+		}
+	}
+	if err := builder.Add(blocks[0], receipts[0], big.NewInt(1)); err == nil {
+		t.Fatalf("non contiguous block added")
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		t.Fatalf("failed to finalize: %v", err)
+	}
+	f.Close()
+
+	e, err := Open(f.Name())
+	if err != nil {
+		t.Fatalf("failed to open era: %v", err)
+	}
+	defer e.Close()
+	if e.Start() != 100 || e.Count() != 8 {
+		t.Fatalf("era range mismatch: have %d blocks from %d, want 8 from 100", e.Count(), e.Start())
+	}
+	if have, err := e.Accumulator(); err != nil || have != root {
+		t.Errorf("accumulator mismatch: have %x, want %x (%v)", have, root, err)
+	}
+	for i, want := range blocks {
+		number := want.NumberU64()
+		block, err := e.GetBlockByNumber(number)
+		if err != nil {
+			t.Fatalf("failed to read block %d: %v", number, err)
+		}
+		if block.Hash() != want.Hash() || !bytes.Equal(block.Extra(), want.Extra()) || block.Transactions()[0].Hash() != want.Transactions()[0].Hash() {
+			t.Errorf("block %d mismatch", number)
+		}
+		if !reflect.DeepEqual(block.Randomness(), want.Randomness()) || !reflect.DeepEqual(block.EpochSnarkData(), want.EpochSnarkData()) {
+			t.Errorf("celo body of block %d mismatch: have %v %v", number, block.Randomness(), block.EpochSnarkData())
+		}
+		rs, err := e.GetReceiptsByNumber(number)
+		if err != nil {
+			t.Fatalf("failed to read receipts %d: %v", number, err)
+		}
+		if len(rs) != 1 || rs[0].CumulativeGasUsed != receipts[i][0].CumulativeGasUsed || rs[0].Bloom != receipts[i][0].Bloom || len(rs[0].Logs) != 1 {
+			t.Errorf("receipts of block %d mismatch", number)
+		}
+		if td, err := e.GetTotalDifficultyByNumber(number); err != nil || td.Uint64() != number+1 {
+			t.Errorf("total difficulty of block %d mismatch: have %v (%v)", number, td, err)
+		}
+	}
+	if _, err := e.GetBlockByNumber(108); err == nil {
+		t.Errorf("block out of the era read")
+	}
+	if name := Filename("mainnet", 3, root); name != "mainnet-00003-"+common.Bytes2Hex(root[:4])+".era1" {
+		t.Errorf("filename mismatch: %s", name)
+	}
+}
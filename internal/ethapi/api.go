@@ -41,17 +41,20 @@ import (
 	"github.com/celo-org/celo-blockchain/rlp"
 	"github.com/celo-org/celo-blockchain/rpc"
 	"github.com/davecgh/go-spew/spew"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 // PublicEthereumAPI provides an API to access Ethereum related information.
 // It offers only methods that operate on public data that is freely available to anyone.
 type PublicEthereumAPI struct {
-	b Backend
+	b       Backend
+	sysCtxs *lru.Cache // System contract values of recent blocks, for the fee currency histories
 }
 
 // NewPublicEthereumAPI creates a new Ethereum protocol API.
 func NewPublicEthereumAPI(b Backend) *PublicEthereumAPI {
-	return &PublicEthereumAPI{b}
+	sysCtxs, _ := lru.New(sysCtxCacheSize)
+	return &PublicEthereumAPI{b: b, sysCtxs: sysCtxs}
 }
 
 // GasPrice returns a suggestion for a gas price for legacy transactions.
@@ -125,6 +128,14 @@ func (s *PublicEthereumAPI) Syncing() (interface{}, error) {
 		"highestBlock":  hexutil.Uint64(progress.HighestBlock),
 		"pulledStates":  hexutil.Uint64(progress.PulledStates),
 		"knownStates":   hexutil.Uint64(progress.KnownStates),
+		// Snap sync state healing progress
+		"healedTrienodes":     hexutil.Uint64(progress.HealedTrienodes),
+		"healedTrienodeBytes": hexutil.Uint64(progress.HealedTrienodeBytes),
+		"healedBytecodes":     hexutil.Uint64(progress.HealedBytecodes),
+		"healedBytecodeBytes": hexutil.Uint64(progress.HealedBytecodeBytes),
+		"healingNodes":        hexutil.Uint64(progress.HealingNodes),
+		"healRate":            hexutil.Uint64(progress.HealRate),
+		"healEta":             hexutil.Uint64(progress.HealETA),
 	}, nil
 }
 
@@ -207,18 +218,11 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	}
 	pending, queue := s.b.TxPoolContent()
 
-	// Define a formatter to flatten a transaction into a string
-	var format = func(tx *types.Transaction) string {
-		if to := tx.To(); to != nil {
-			return fmt.Sprintf("%s: %v wei + %v gas × %v wei", tx.To().Hex(), tx.Value(), tx.Gas(), tx.GasPrice())
-		}
-		return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value(), tx.Gas(), tx.GasPrice())
-	}
 	// Flatten the pending transactions
 	for account, txs := range pending {
 		dump := make(map[string]string)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = inspectTx(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -226,13 +230,21 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	for account, txs := range queue {
 		dump := make(map[string]string)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = inspectTx(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
 	return content
 }
 
+// inspectTx flattens a transaction into a string.
+func inspectTx(tx *types.Transaction) string {
+	if to := tx.To(); to != nil {
+		return fmt.Sprintf("%s: %v wei + %v gas × %v wei", tx.To().Hex(), tx.Value(), tx.Gas(), tx.GasPrice())
+	}
+	return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value(), tx.Gas(), tx.GasPrice())
+}
+
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type PublicAccountAPI struct {
@@ -912,7 +924,7 @@ func (diff *StateOverride) Apply(state *state.StateDB) error {
 	return nil
 }
 
-func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, timeout time.Duration, globalGasCap uint64, skipDebitCredit bool) (*core.ExecutionResult, error) {
+func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, celoOverrides *CeloOverrides, timeout time.Duration, globalGasCap uint64, skipDebitCredit bool) (*core.ExecutionResult, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
 	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
@@ -922,6 +934,10 @@ func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash
 	if err := overrides.Apply(state); err != nil {
 		return nil, err
 	}
+	if err := celoOverrides.Validate(); err != nil {
+		return nil, err
+	}
+	factory := celoOverrides.EVMRunnerFactory(b)
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
 	var cancel context.CancelFunc
@@ -937,7 +953,7 @@ func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash
 	// Create SysContractCallCtx
 	var sysCtx *core.SysContractCallCtx
 	if b.ChainConfig().IsEspresso(header.Number) {
-		sysCtx = core.NewSysContractCallCtx(header, state, b)
+		sysCtx = core.NewSysContractCallCtx(b.ChainConfig(), header, state, factory)
 	}
 
 	// Get a new instance of the EVM.
@@ -958,7 +974,7 @@ func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash
 
 	// Execute the message.
 	gp := new(core.GasPool).AddGas(math.MaxUint64)
-	vmRunner := b.NewEVMRunner(header, state)
+	vmRunner := factory.NewEVMRunner(header, state)
 	result, err := core.ApplyMessage(evm, msg, gp, vmRunner, sysCtx)
 	if err := vmError(); err != nil {
 		return nil, err
@@ -1006,12 +1022,13 @@ func (e *revertError) ErrorData() interface{} {
 
 // Call executes the given transaction on the state for the given block number.
 //
-// Additionally, the caller can specify a batch of contract for fields overriding.
+// Additionally, the caller can specify a batch of contract for fields overriding,
+// and the exchange rates and gas price minimums the protocol reads.
 //
 // Note, this function doesn't make and changes in the state/blockchain and is
 // useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) (hexutil.Bytes, error) {
-	result, err := DoCall(ctx, s.b, args, blockNrOrHash, overrides, 50*time.Second, s.b.RPCGasCap(), false)
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, celoOverrides *CeloOverrides) (hexutil.Bytes, error) {
+	result, err := DoCall(ctx, s.b, args, blockNrOrHash, overrides, celoOverrides, 50*time.Second, s.b.RPCGasCap(), false)
 	if err != nil {
 		return nil, err
 	}
@@ -1025,9 +1042,8 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args TransactionArgs, bl
 func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, gasCap uint64) (hexutil.Uint64, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
-		lo  uint64 = params.TxGas - 1
-		hi  uint64
-		cap uint64
+		lo uint64 = params.TxGas - 1
+		hi uint64
 	)
 	// Use zero address if sender unspecified.
 	if args.From == nil {
@@ -1044,7 +1060,8 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 		log.Warn("Caller gas above allowance, capping", "requested", hi, "cap", gasCap)
 		hi = gasCap
 	}
-	cap = hi
+	// Remember the fee cap, the funds of the sender bound the gas it can pay for
+	feeCap := args.feeCap()
 	// Set gas price to nil (which will lead to it being zero), because the binary search
 	// assumes that if the transaction fails with gas limit A, and B < A, then it would
 	// also fail with gas limit B, which may not be the case if the gas price is non-zero,
@@ -1053,54 +1070,37 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 	// If a fee tip or fee cap are passed, those will override the gas price, so zero them out as well.
 	args.MaxFeePerGas = nil
 	args.MaxPriorityFeePerGas = nil
-	// Create a helper to check if a gas allowance results in an executable transaction
-	executable := func(gas uint64) (bool, *core.ExecutionResult, error) {
-		args.Gas = (*hexutil.Uint64)(&gas)
-
-		result, err := DoCall(ctx, b, args, blockNrOrHash, nil, 0, gasCap, true)
-		if err != nil {
-			if errors.Is(err, core.ErrIntrinsicGas) {
-				return true, nil, nil // Special case, raise gas limit
-			}
-			return true, nil, err // Bail out
-		}
-		return result.Failed(), result, nil
+	// Set up the execution environment once, every gas allowance tried runs on it
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	estimator, err := newGasEstimator(ctx, b, args, blockNrOrHash, gasCap)
+	if err != nil {
+		return 0, err
 	}
-	// Execute the binary search and hone in on an executable gas limit
-	for lo+1 < hi {
-		mid := (hi + lo) / 2
-		failed, _, err := executable(mid)
-
-		// If the error is not nil(consensus error), it means the provided message
-		// call or transaction will never be accepted no matter how much gas it is
-		// assigned. Return the error directly, don't struggle any more.
-		if err != nil {
-			return 0, err
-		}
-		if failed {
-			lo = mid
-		} else {
-			hi = mid
-		}
+	// No gas limit below the intrinsic gas of the message, which includes the
+	// surcharge of its fee currency, can succeed
+	intrinsic, err := estimator.intrinsicGas()
+	if err != nil {
+		return 0, err
 	}
-	// Reject the transaction as invalid if it still fails at the highest allowance
-	if hi == cap {
-		failed, result, err := executable(hi)
+	if intrinsic > lo+1 {
+		lo = intrinsic - 1
+	}
+	if feeCap != nil && feeCap.Sign() > 0 {
+		allowance, err := estimator.allowance(feeCap, intrinsic)
 		if err != nil {
 			return 0, err
 		}
-		if failed {
-			if result != nil && result.Err != vm.ErrOutOfGas {
-				if len(result.Revert()) > 0 {
-					return 0, newRevertError(result)
-				}
-				return 0, result.Err
-			}
-			// Otherwise, the specified gas cap is too low
-			return 0, fmt.Errorf("gas required exceeds allowance (%d)", cap)
+		if hi > allowance {
+			log.Warn("Gas estimation capped by limited funds", "original", hi, "feecap", feeCap, "feecurrency", args.FeeCurrency, "fundable", allowance)
+			hi = allowance
 		}
 	}
-	inflatedGas := hexutil.Uint64(uint64(float64(hi) * b.RPCGasInflationRate()))
+	gas, err := estimator.estimate(lo, hi)
+	if err != nil {
+		return 0, err
+	}
+	inflatedGas := hexutil.Uint64(uint64(float64(gas) * b.RPCGasInflationRate()))
 	return inflatedGas, nil
 }
 
@@ -1118,10 +1118,11 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args TransactionA
 // while replaying a transaction in debug mode as well as transaction
 // execution status, the amount of gas used and the return value
 type ExecutionResult struct {
-	Gas         uint64         `json:"gas"`
-	Failed      bool           `json:"failed"`
-	ReturnValue string         `json:"returnValue"`
-	StructLogs  []StructLogRes `json:"structLogs"`
+	Gas           uint64         `json:"gas"`
+	Failed        bool           `json:"failed"`
+	ReturnValue   string         `json:"returnValue"`
+	StructLogs    []StructLogRes `json:"structLogs"`
+	SchemaVersion uint64         `json:"schemaVersion"` // Version of the layout of the trace
 }
 
 // StructLogRes stores a structured log emitted by the EVM while replaying a
@@ -1489,7 +1490,7 @@ func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrH
 	// Create SysContractCallCtx
 	var sysCtx *core.SysContractCallCtx
 	if b.ChainConfig().IsEspresso(header.Number) {
-		sysCtx = core.NewSysContractCallCtx(header, db, b)
+		sysCtx = core.NewSysContractCallCtx(b.ChainConfig(), header, db, b)
 	}
 
 	// Create an initial tracer
@@ -2157,7 +2158,65 @@ func generateReceiptResponse(ctx context.Context, backend Backend, receipt *type
 				fields["effectiveGasPrice"] = hexutil.Uint64(tx.GasPrice().Uint64())
 			}
 		}
+		if backend.RPCReceiptFeeFields() {
+			if err := addReceiptFeeFields(ctx, backend, fields, receipt, tx, blockHash); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return fields, nil
 }
+
+// addReceiptFeeFields adds the fee currency of a transaction to its receipt
+// fields, along with the base fee in that currency and the fee paid converted
+// to CELO. Like the gas price minimums, the exchange rates are the ones at the
+// beginning of the block. The fees are left out when the state they are read
+// from was pruned.
+func addReceiptFeeFields(ctx context.Context, backend Backend, fields map[string]interface{}, receipt *types.Receipt, tx *types.Transaction, blockHash common.Hash) error {
+	fields["feeCurrency"] = tx.FeeCurrency()
+	header, err := backend.HeaderByHash(ctx, blockHash)
+	if err != nil {
+		return err
+	}
+	baseFee, err := backend.GasPriceMinimumForHeader(ctx, tx.FeeCurrency(), header)
+	if err != nil {
+		return nil
+	}
+	fields["baseFeeInFeeCurrency"] = (*hexutil.Big)(baseFee)
+
+	// The gas price is denominated in CELO for the celo denominated transactions
+	gasPrice := tx.GasPrice()
+	switch tx.Type() {
+	case types.DynamicFeeTxType, types.CeloDynamicFeeTxType, types.CeloDynamicFeeTxV2Type, types.CeloDenominatedTxType:
+		denominatedBaseFee := baseFee
+		if tx.DenominatedFeeCurrency() == nil && tx.FeeCurrency() != nil {
+			if denominatedBaseFee, err = backend.GasPriceMinimumForHeader(ctx, nil, header); err != nil {
+				return nil
+			}
+		}
+		gasPrice = new(big.Int).Add(denominatedBaseFee, tx.EffectiveGasTipValue(denominatedBaseFee))
+	}
+	parent, err := backend.HeaderByHash(ctx, header.ParentOrGenesisHash())
+	if err != nil {
+		return err
+	}
+	_, toCELO, err := backend.ConversionFunctionsForHeader(ctx, parent)
+	if err != nil {
+		return nil
+	}
+	fee, err := toCELO(new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(receipt.GasUsed)), tx.DenominatedFeeCurrency())
+	if err != nil {
+		return nil
+	}
+	// The gateway fee is only charged when there is a recipient
+	if tx.GatewayFeeRecipient() != nil {
+		gatewayFee, err := toCELO(tx.GatewayFee(), tx.FeeCurrency())
+		if err != nil {
+			return nil
+		}
+		fee.Add(fee, gatewayFee)
+	}
+	fields["feeInCeloEquivalent"] = (*hexutil.Big)(fee)
+	return nil
+}
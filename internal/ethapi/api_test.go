@@ -1,6 +1,7 @@
 package ethapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/common/hexutil"
 	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/params"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -201,3 +203,99 @@ func jsonRoundtripToMap(t *testing.T, tx *RPCTransaction) map[string]interface{}
 	require.NoError(t, err)
 	return m
 }
+
+// receiptBackend serves the headers, gas price minimums and exchange rates of a
+// block for the receipt fee fields.
+type receiptBackend struct {
+	Backend
+	header, parent   *types.Header
+	gasPriceMinimums map[common.Address]*big.Int
+	rates            map[common.Address]int64 // Units of CELO per unit of currency
+}
+
+func (b *receiptBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
+func (b *receiptBackend) RPCReceiptFeeFields() bool {
+	return true
+}
+
+func (b *receiptBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	if hash == b.parent.Hash() {
+		return b.parent, nil
+	}
+	return b.header, nil
+}
+
+func (b *receiptBackend) GasPriceMinimumForHeader(ctx context.Context, currency *common.Address, header *types.Header) (*big.Int, error) {
+	if currency == nil {
+		return b.gasPriceMinimums[common.ZeroAddress], nil
+	}
+	return b.gasPriceMinimums[*currency], nil
+}
+
+func (b *receiptBackend) ConversionFunctionsForHeader(ctx context.Context, header *types.Header) (func(*common.Address) *big.Int, types.ToCELOFn, error) {
+	if header != b.parent {
+		return nil, nil, errors.New("rates not read at the beginning of the block")
+	}
+	toCELO := func(amount *big.Int, currency *common.Address) (*big.Int, error) {
+		if currency == nil {
+			return new(big.Int).Set(amount), nil
+		}
+		return new(big.Int).Mul(amount, big.NewInt(b.rates[*currency])), nil
+	}
+	return nil, toCELO, nil
+}
+
+func TestReceiptFeeFields(t *testing.T) {
+	currency := common.HexToAddress("0xCAFE")
+	gateway := common.HexToAddress("0xDAD")
+	parent := &types.Header{Number: big.NewInt(1)}
+	backend := &receiptBackend{
+		parent:           parent,
+		header:           &types.Header{Number: big.NewInt(2), ParentHash: parent.Hash()},
+		gasPriceMinimums: map[common.Address]*big.Int{common.ZeroAddress: big.NewInt(10), currency: big.NewInt(20)},
+		rates:            map[common.Address]int64{currency: 2},
+	}
+	receipt := &types.Receipt{GasUsed: 1000}
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+
+	t.Run("Fee currency with a gateway fee", func(t *testing.T) {
+		tx := types.NewTx(&types.CeloDynamicFeeTx{
+			FeeCurrency:         &currency,
+			GatewayFeeRecipient: &gateway,
+			GatewayFee:          big.NewInt(500),
+			GasFeeCap:           big.NewInt(100),
+			GasTipCap:           big.NewInt(5),
+		})
+		fields, err := generateReceiptResponse(context.Background(), backend, receipt, signer, tx, backend.header.Hash(), 2, 0)
+		require.NoError(t, err)
+		assert.Equal(t, &currency, fields["feeCurrency"])
+		assert.Equal(t, (*hexutil.Big)(big.NewInt(20)), fields["baseFeeInFeeCurrency"])
+		// (20 + 5) * 1000 + 500 in the currency, at 2 CELO per unit
+		assert.Equal(t, (*hexutil.Big)(big.NewInt(51000)), fields["feeInCeloEquivalent"])
+	})
+
+	t.Run("Celo denominated", func(t *testing.T) {
+		tx := types.NewTx(&types.CeloDenominatedTx{
+			FeeCurrency: &currency,
+			GasFeeCap:   big.NewInt(100),
+			GasTipCap:   big.NewInt(5),
+		})
+		fields, err := generateReceiptResponse(context.Background(), backend, receipt, signer, tx, backend.header.Hash(), 2, 0)
+		require.NoError(t, err)
+		assert.Equal(t, (*hexutil.Big)(big.NewInt(20)), fields["baseFeeInFeeCurrency"])
+		// (10 + 5) * 1000 denominated in CELO
+		assert.Equal(t, (*hexutil.Big)(big.NewInt(15000)), fields["feeInCeloEquivalent"])
+	})
+
+	t.Run("CELO", func(t *testing.T) {
+		tx := types.NewTx(&types.LegacyTx{GasPrice: big.NewInt(12)})
+		fields, err := generateReceiptResponse(context.Background(), backend, receipt, signer, tx, backend.header.Hash(), 2, 0)
+		require.NoError(t, err)
+		assert.Nil(t, fields["feeCurrency"])
+		assert.Equal(t, (*hexutil.Big)(big.NewInt(10)), fields["baseFeeInFeeCurrency"])
+		assert.Equal(t, (*hexutil.Big)(big.NewInt(12000)), fields["feeInCeloEquivalent"])
+	})
+}
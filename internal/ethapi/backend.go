@@ -58,6 +58,7 @@ type Backend interface {
 	RPCGasCap() uint64            // global gas cap for eth_call over rpc: DoS protection
 	RPCTxFeeCap() float64         // global tx fee cap for all transaction related APIs
 	RPCEthCompatibility() bool    // determines if the fields 'gasLimit' and 'baseFeePerGas' should be returned by the RPC API.
+	RPCReceiptFeeFields() bool    // determines if the fee currency and CELO equivalent fee fields should be added to the receipts.
 	UnprotectedAllowed() bool     // allows only for EIP155 transactions.
 
 	// Blockchain API
@@ -97,6 +98,10 @@ type Backend interface {
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
+	SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription
+	FeeCurrencyWhitelistForHeader(ctx context.Context, header *types.Header) (common.Address, []common.Address, error)
+	ConversionFunctionsForHeader(ctx context.Context, header *types.Header) (func(*common.Address) *big.Int, types.ToCELOFn, error)
+	ValidatorsForHeader(ctx context.Context, header *types.Header) ([]common.Address, error)
 
 	ChainConfig() *params.ChainConfig
 
@@ -150,6 +155,11 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicAccountAPI(apiBackend.AccountManager()),
 			Public:    true,
+		}, {
+			Namespace: "celo",
+			Version:   "1.0",
+			Service:   NewPublicCeloAPI(apiBackend),
+			Public:    true,
 		}, {
 			Namespace: "personal",
 			Version:   "1.0",
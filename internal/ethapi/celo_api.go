@@ -0,0 +1,373 @@
+package ethapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/consensus/misc"
+	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
+	"github.com/celo-org/celo-blockchain/contracts/currency"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+const (
+	// maxGasPriceMinimumForecast is the most blocks a gas price minimum forecast covers.
+	maxGasPriceMinimumForecast = 128
+
+	// gasPriceMinimumForecastWindow is the number of recent blocks whose fullness is
+	// averaged for the forecast, smoothing out the odd full or empty block.
+	gasPriceMinimumForecastWindow = 8
+)
+
+// PublicCeloAPI provides an API to access Celo specific chain information.
+// It offers only methods that operate on public data that is freely available to anyone.
+type PublicCeloAPI struct {
+	b Backend
+}
+
+// NewPublicCeloAPI creates a new Celo API.
+func NewPublicCeloAPI(b Backend) *PublicCeloAPI {
+	return &PublicCeloAPI{b}
+}
+
+// FeeCurrencyBaseFee is the base fee of a fee currency in the multidimensional fee market.
+type FeeCurrencyBaseFee struct {
+	BaseFee    *hexutil.Big `json:"baseFee"`
+	Multiplier *hexutil.Big `json:"multiplier"` // Fixed point, 1e18 is a multiplier of one
+}
+
+// GetFeeCurrencyBaseFees returns the base fee of CELO (keyed by the zero address) and of every
+// whitelisted fee currency for the transactions of the given block. Outside of the
+// multidimensional fee market every multiplier is one.
+func (s *PublicCeloAPI) GetFeeCurrencyBaseFees(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*FeeCurrencyBaseFee, error) {
+	header, err := s.b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	if header.Number.Sign() == 0 {
+		return nil, fmt.Errorf("no base fees for the genesis block")
+	}
+	// Base fees are set by the state at the end of the parent block
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(header.ParentHash, false))
+	if state == nil || err != nil {
+		return nil, err
+	}
+	sysCtx := core.NewSysContractCallCtx(s.b.ChainConfig(), header, state, s.b)
+	baseFees := make(map[common.Address]*FeeCurrencyBaseFee)
+	for feeCurrency, baseFee := range sysCtx.GetCurrentGasPriceMinimumMap() {
+		feeCurrency := feeCurrency
+		baseFees[feeCurrency] = &FeeCurrencyBaseFee{
+			BaseFee:    (*hexutil.Big)(baseFee),
+			Multiplier: (*hexutil.Big)(core.GetFeeCurrencyMultiplier(state, &feeCurrency)),
+		}
+	}
+	return baseFees, state.Error()
+}
+
+// CeloForks reports which Celo hard forks are active at a block.
+type CeloForks struct {
+	Churrito      bool `json:"churrito"`
+	Donut         bool `json:"donut"`
+	Espresso      bool `json:"espresso"`
+	Gingerbread   bool `json:"gingerbread"`
+	GingerbreadP2 bool `json:"gingerbreadP2"`
+}
+
+// CeloChainConfig is the chain configuration as seen by a block, with the
+// parameters set on chain for it.
+type CeloChainConfig struct {
+	Config           *params.ChainConfig             `json:"config"`
+	Number           hexutil.Uint64                  `json:"number"`
+	Forks            CeloForks                       `json:"forks"`
+	GasLimit         hexutil.Uint64                  `json:"gasLimit"`
+	GasPriceMinimum  *hexutil.Big                    `json:"gasPriceMinimum"` // In CELO
+	FeeCurrencies    []common.Address                `json:"feeCurrencies"`
+	GasPriceMinimums map[common.Address]*hexutil.Big `json:"gasPriceMinimums"` // Per whitelisted fee currency
+}
+
+// GetChainConfig returns the chain configuration, the Celo forks active at the given
+// block and the gas limit and gas price minimums applying to its transactions, which
+// are set by the state at the end of the parent block.
+func (s *PublicCeloAPI) GetChainConfig(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*CeloChainConfig, error) {
+	header, err := s.b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	gasLimit, err := s.b.GetRealBlockGasLimit(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(header.ParentOrGenesisHash(), false))
+	if state == nil || err != nil {
+		return nil, err
+	}
+	config := s.b.ChainConfig()
+	sysCtx := core.NewSysContractCallCtx(config, header, state, s.b)
+	gpms := make(map[common.Address]*hexutil.Big)
+	for feeCurrency, gpm := range sysCtx.GetCurrentGasPriceMinimumMap() {
+		if feeCurrency != (common.Address{}) {
+			gpms[feeCurrency] = (*hexutil.Big)(gpm)
+		}
+	}
+	return &CeloChainConfig{
+		Config: config,
+		Number: hexutil.Uint64(header.Number.Uint64()),
+		Forks: CeloForks{
+			Churrito:      config.IsChurrito(header.Number),
+			Donut:         config.IsDonut(header.Number),
+			Espresso:      config.IsEspresso(header.Number),
+			Gingerbread:   config.IsGingerbread(header.Number),
+			GingerbreadP2: config.IsGingerbreadP2(header.Number),
+		},
+		GasLimit:         hexutil.Uint64(gasLimit),
+		GasPriceMinimum:  (*hexutil.Big)(sysCtx.GetGasPriceMinimum(nil)),
+		FeeCurrencies:    sysCtx.GetWhitelistedCurrencies(),
+		GasPriceMinimums: gpms,
+	}, state.Error()
+}
+
+// ExchangeRate is the exchange rate of CELO to a fee currency, an amount of CELO
+// times Numerator / Denominator is worth that amount of the currency.
+type ExchangeRate struct {
+	Numerator   *hexutil.Big `json:"numerator"`
+	Denominator *hexutil.Big `json:"denominator"`
+}
+
+// GetExchangeRates returns the exchange rates of the whitelisted fee currencies as
+// reported by the oracles at the end of the given block.
+func (s *PublicCeloAPI) GetExchangeRates(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*ExchangeRate, error) {
+	manager, whitelist, err := s.currencyManager(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[common.Address]*ExchangeRate, len(whitelist))
+	for _, feeCurrency := range whitelist {
+		feeCurrency := feeCurrency
+		curr, err := manager.GetCurrency(&feeCurrency)
+		if err != nil {
+			return nil, fmt.Errorf("exchange rate of %s: %w", feeCurrency.Hex(), err)
+		}
+		rate := curr.ToCELORate()
+		rates[feeCurrency] = &ExchangeRate{
+			Numerator:   (*hexutil.Big)(rate.Numerator()),
+			Denominator: (*hexutil.Big)(rate.Denominator()),
+		}
+	}
+	return rates, nil
+}
+
+// ConvertToCelo returns the CELO value of an amount of a whitelisted fee currency at
+// the exchange rate of the given block, the latest if none.
+func (s *PublicCeloAPI) ConvertToCelo(ctx context.Context, amount hexutil.Big, feeCurrency common.Address, blockNrOrHash *rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	curr, err := s.feeCurrency(ctx, feeCurrency, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(curr.ToCELO(amount.ToInt())), nil
+}
+
+// ConvertFromCelo returns the value in a whitelisted fee currency of an amount of
+// CELO at the exchange rate of the given block, the latest if none.
+func (s *PublicCeloAPI) ConvertFromCelo(ctx context.Context, amount hexutil.Big, feeCurrency common.Address, blockNrOrHash *rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	curr, err := s.feeCurrency(ctx, feeCurrency, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(curr.FromCELO(amount.ToInt())), nil
+}
+
+// FeeCurrencyWhitelist returns the fee currencies whitelisted at the end of the
+// given block, as notified by the feeCurrencies subscription.
+func (s *PublicCeloAPI) FeeCurrencyWhitelist(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]common.Address, error) {
+	_, whitelist, err := s.currencyManager(ctx, blockNrOrHash)
+	return whitelist, err
+}
+
+// feeCurrency returns a fee currency with its exchange rate at the end of the given
+// block, the latest if nil, failing if it isn't whitelisted there.
+func (s *PublicCeloAPI) feeCurrency(ctx context.Context, feeCurrency common.Address, blockNrOrHash *rpc.BlockNumberOrHash) (*currency.Currency, error) {
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	manager, whitelist, err := s.currencyManager(ctx, bNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return whitelistedCurrency(manager, whitelist, feeCurrency)
+}
+
+// whitelistedCurrency returns a fee currency from the manager, failing if it isn't
+// in the whitelist.
+func whitelistedCurrency(manager *currency.CurrencyManager, whitelist []common.Address, feeCurrency common.Address) (*currency.Currency, error) {
+	for _, whitelisted := range whitelist {
+		if whitelisted == feeCurrency {
+			return manager.GetCurrency(&feeCurrency)
+		}
+	}
+	return nil, errFeeCurrencyNotWhitelisted
+}
+
+// currencyManager returns a currency manager on the state at the end of the given
+// block, along with the fee currency whitelist of that state.
+func (s *PublicCeloAPI) currencyManager(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*currency.CurrencyManager, []common.Address, error) {
+	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if state == nil {
+		return nil, nil, fmt.Errorf("state of block %s not available", blockNrOrHash.String())
+	}
+	vmRunner := s.b.NewEVMRunner(header, state)
+	whitelist, err := currency.CurrencyWhitelist(vmRunner)
+	if err != nil {
+		return nil, nil, err
+	}
+	return currency.NewManager(vmRunner), whitelist, nil
+}
+
+// GasPriceMinimumForecast is the projection of the gas price minimums of the
+// blocks following the latest one.
+type GasPriceMinimumForecast struct {
+	FeeCurrency      *common.Address `json:"feeCurrency"`
+	FirstBlock       hexutil.Uint64  `json:"firstBlock"`
+	GasUsedRatio     float64         `json:"gasUsedRatio"` // Fullness assumed for every block
+	GasPriceMinimums []*hexutil.Big  `json:"gasPriceMinimums"`
+}
+
+// GasPriceMinimumForecast projects the gas price minimums of a fee currency, CELO if
+// nil, in the blockCount blocks following the latest one. Every block is assumed to
+// be as full as the recent ones on average, and the gas price minimums follow the
+// on chain adjustment from there, at the current exchange rate of the currency.
+func (s *PublicCeloAPI) GasPriceMinimumForecast(ctx context.Context, feeCurrency *common.Address, blockCount rpc.DecimalOrHex) (*GasPriceMinimumForecast, error) {
+	head, err := s.b.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil || err != nil {
+		return nil, err
+	}
+	forecast := &GasPriceMinimumForecast{FeeCurrency: feeCurrency, FirstBlock: hexutil.Uint64(head.Number.Uint64() + 1)}
+	if blockCount == 0 {
+		return forecast, nil
+	}
+	if blockCount > maxGasPriceMinimumForecast {
+		blockCount = maxGasPriceMinimumForecast
+	}
+	bNrOrHash := rpc.BlockNumberOrHashWithHash(head.Hash(), false)
+	manager, whitelist, err := s.currencyManager(ctx, bNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	feeCurrencyRate := &currency.CELOCurrency
+	if feeCurrency != nil {
+		if feeCurrencyRate, err = whitelistedCurrency(manager, whitelist, *feeCurrency); err != nil {
+			return nil, err
+		}
+	}
+	gasUsed, err := s.recentGasUsed(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+	// The forecast updates the gas price minimum in the state, on a copy of its own
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, bNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	vmRunner := s.b.NewEVMRunner(head, state)
+	if gasLimit := blockchain_parameters.GetBlockGasLimitOrDefault(vmRunner); gasLimit > 0 {
+		forecast.GasUsedRatio = float64(gasUsed) / float64(gasLimit)
+	}
+	baseFees, err := misc.ForecastBaseFees(s.b.ChainConfig(), head, vmRunner, gasUsed, int(blockCount))
+	if err != nil {
+		return nil, err
+	}
+	for _, baseFee := range baseFees {
+		forecast.GasPriceMinimums = append(forecast.GasPriceMinimums, (*hexutil.Big)(feeCurrencyRate.FromCELO(baseFee)))
+	}
+	return forecast, nil
+}
+
+// recentGasUsed returns the average gas used by the last blocks up to head.
+func (s *PublicCeloAPI) recentGasUsed(ctx context.Context, head *types.Header) (uint64, error) {
+	var (
+		header       = head
+		total, count uint64
+	)
+	for {
+		total, count = total+header.GasUsed, count+1
+		if count == gasPriceMinimumForecastWindow || header.Number.Sign() == 0 {
+			return total / count, nil
+		}
+		parent, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(header.Number.Int64()-1))
+		if parent == nil || err != nil {
+			return 0, fmt.Errorf("block %d not found: %v", header.Number.Int64()-1, err)
+		}
+		header = parent
+	}
+}
+
+const (
+	// maxBatchStateAccounts is the most accounts a batch state read covers.
+	maxBatchStateAccounts = 1024
+
+	// maxBatchStateSlots is the most storage slots a batch state read covers, over
+	// all its accounts.
+	maxBatchStateSlots = 16384
+)
+
+// StateQuery selects an account, and some of its storage slots, for a batch state
+// read.
+type StateQuery struct {
+	Address common.Address `json:"address"`
+	Slots   []common.Hash  `json:"slots"`
+}
+
+// AccountState is the state of an account read by celo_batchGetState.
+type AccountState struct {
+	Address  common.Address              `json:"address"`
+	Balance  *hexutil.Big                `json:"balance"`
+	Nonce    hexutil.Uint64              `json:"nonce"`
+	CodeHash common.Hash                 `json:"codeHash"` // Zero if the account doesn't exist
+	Storage  map[common.Hash]common.Hash `json:"storage"`
+}
+
+// BatchGetState returns the balances, nonces, code hashes and the selected storage
+// slots of many accounts at the end of the given block, in the order of the
+// queries. They are read from the state directly, without executing any contract.
+func (s *PublicCeloAPI) BatchGetState(ctx context.Context, queries []StateQuery, blockNrOrHash rpc.BlockNumberOrHash) ([]*AccountState, error) {
+	if len(queries) > maxBatchStateAccounts {
+		return nil, fmt.Errorf("too many accounts: have %d, max %d", len(queries), maxBatchStateAccounts)
+	}
+	slots := 0
+	for _, query := range queries {
+		slots += len(query.Slots)
+	}
+	if slots > maxBatchStateSlots {
+		return nil, fmt.Errorf("too many storage slots: have %d, max %d", slots, maxBatchStateSlots)
+	}
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	accounts := make([]*AccountState, len(queries))
+	for i, query := range queries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		account := &AccountState{
+			Address:  query.Address,
+			Balance:  (*hexutil.Big)(state.GetBalance(query.Address)),
+			Nonce:    hexutil.Uint64(state.GetNonce(query.Address)),
+			CodeHash: state.GetCodeHash(query.Address),
+			Storage:  make(map[common.Hash]common.Hash, len(query.Slots)),
+		}
+		for _, slot := range query.Slots {
+			account.Storage[slot] = state.GetState(query.Address, slot)
+		}
+		accounts[i] = account
+	}
+	return accounts, state.Error()
+}
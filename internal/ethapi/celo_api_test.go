@@ -0,0 +1,227 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/contracts/abis"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+func TestCurrencyConversion(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	backend := &historyBackend{state: statedb, celo: testutil.NewCeloMock()}
+	var (
+		cusd = common.HexToAddress("0x02") // 4 cUSD per 2 CELO
+		ceur = common.HexToAddress("0x05") // 3 cEUR per 4 CELO
+	)
+	oracles := testutil.NewSingleMethodContract(config.SortedOraclesRegistryId, "medianRate",
+		func(currency common.Address) (*big.Int, *big.Int) {
+			if currency == cusd {
+				return big.NewInt(4), big.NewInt(2)
+			}
+			return big.NewInt(3), big.NewInt(4)
+		},
+	)
+	backend.celo.Registry.AddContract(config.SortedOraclesRegistryId, common.HexToAddress("0x06"))
+	backend.celo.Runner.RegisterContract(common.HexToAddress("0x06"), oracles)
+	api := NewPublicCeloAPI(backend)
+
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	rates, err := api.GetExchangeRates(context.Background(), latest)
+	if err != nil {
+		t.Fatalf("failed to get exchange rates: %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("exchange rates mismatch: have %d, want 2", len(rates))
+	}
+	if rate := rates[ceur]; rate.Numerator.ToInt().Int64() != 3 || rate.Denominator.ToInt().Int64() != 4 {
+		t.Errorf("cEUR exchange rate mismatch: have %v/%v, want 3/4", rate.Numerator, rate.Denominator)
+	}
+	amount := hexutil.Big(*big.NewInt(1000))
+	if celo, err := api.ConvertToCelo(context.Background(), amount, cusd, nil); err != nil || celo.ToInt().Int64() != 500 {
+		t.Errorf("cUSD to CELO mismatch: have %v, %v, want 500", celo, err)
+	}
+	if ceurs, err := api.ConvertFromCelo(context.Background(), amount, ceur, &latest); err != nil || ceurs.ToInt().Int64() != 750 {
+		t.Errorf("CELO to cEUR mismatch: have %v, %v, want 750", ceurs, err)
+	}
+	if _, err := api.ConvertToCelo(context.Background(), amount, common.HexToAddress("0x04"), nil); err != errFeeCurrencyNotWhitelisted {
+		t.Errorf("error mismatch: have %v, want %v", err, errFeeCurrencyNotWhitelisted)
+	}
+	whitelist, err := api.FeeCurrencyWhitelist(context.Background(), latest)
+	if err != nil || len(whitelist) != 2 || whitelist[0] != cusd || whitelist[1] != ceur {
+		t.Errorf("whitelist mismatch: have %v, %v, want %v", whitelist, err, []common.Address{cusd, ceur})
+	}
+}
+
+// forecastGasPriceMinimumMock adjusts the gas price minimum by an eighth of the
+// gap of the block fullness to one half.
+type forecastGasPriceMinimumMock struct {
+	testutil.ContractMock
+	gasPriceMinimum *big.Int
+}
+
+func (m *forecastGasPriceMinimumMock) GetGasPriceMinimum(currency common.Address) *big.Int {
+	return m.gasPriceMinimum
+}
+
+func (m *forecastGasPriceMinimumMock) GetUpdatedGasPriceMinimum(gasUsed, gasLimit *big.Int) *big.Int {
+	delta := new(big.Int).Mul(m.gasPriceMinimum, new(big.Int).Sub(new(big.Int).Mul(gasUsed, common.Big2), gasLimit))
+	return delta.Add(m.gasPriceMinimum, delta.Div(delta, new(big.Int).Mul(gasLimit, big.NewInt(8))))
+}
+
+func (m *forecastGasPriceMinimumMock) UpdateGasPriceMinimum(gasUsed, gasLimit *big.Int) *big.Int {
+	m.gasPriceMinimum = m.GetUpdatedGasPriceMinimum(gasUsed, gasLimit)
+	return m.gasPriceMinimum
+}
+
+func TestGasPriceMinimumForecast(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	backend := &historyBackend{state: statedb, celo: testutil.NewCeloMock()}
+	for i, gasUsed := range []uint64{20_000_000, 15_000_000, 15_000_000} {
+		backend.headers = append(backend.headers, &types.Header{Number: big.NewInt(int64(i)), GasUsed: gasUsed, GasLimit: 20_000_000})
+	}
+	backend.config = params.IstanbulTestChainConfig.DeepCopy()
+	backend.config.FakeBaseFee = nil
+	gpm := &forecastGasPriceMinimumMock{gasPriceMinimum: big.NewInt(1_000_000)}
+	gpm.ContractMock = testutil.NewContractMock(abis.GasPriceMinimum, gpm)
+	backend.celo.Registry.AddContract(config.GasPriceMinimumRegistryId, common.HexToAddress("0x07"))
+	backend.celo.Runner.RegisterContract(common.HexToAddress("0x07"), gpm)
+	api := NewPublicCeloAPI(backend)
+
+	forecast, err := api.GasPriceMinimumForecast(context.Background(), nil, 3)
+	if err != nil {
+		t.Fatalf("failed to forecast: %v", err)
+	}
+	// The blocks are assumed to be as full as the last three on average, 5/6
+	if forecast.FirstBlock != 3 || forecast.GasUsedRatio < 0.83 || forecast.GasUsedRatio > 0.84 {
+		t.Errorf("forecast mismatch: have first block %d, ratio %f, want 3, 5/6", forecast.FirstBlock, forecast.GasUsedRatio)
+	}
+	// The next block follows the last one, 3/4 full, and the others 5/6 full
+	for i, want := range []int64{1_062_500, 1_173_610, 1_271_410} {
+		if have := forecast.GasPriceMinimums[i].ToInt().Int64(); have != want {
+			t.Errorf("block %d: gas price minimum mismatch: have %d, want %d", i, have, want)
+		}
+	}
+	unknown := common.HexToAddress("0x04")
+	if _, err := api.GasPriceMinimumForecast(context.Background(), &unknown, 3); err != errFeeCurrencyNotWhitelisted {
+		t.Errorf("error mismatch: have %v, want %v", err, errFeeCurrencyNotWhitelisted)
+	}
+}
+
+func TestGetChainConfig(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	backend := &historyBackend{state: statedb, celo: testutil.NewCeloMock()}
+	for i := 0; i < 3; i++ {
+		backend.headers = append(backend.headers, &types.Header{Number: big.NewInt(int64(i)), GasLimit: 20_000_000})
+	}
+	backend.config = params.IstanbulTestChainConfig.DeepCopy()
+	backend.config.GingerbreadBlock = big.NewInt(2)
+	backend.config.GingerbreadP2Block = nil
+	gpm := &forecastGasPriceMinimumMock{gasPriceMinimum: big.NewInt(1_000_000)}
+	gpm.ContractMock = testutil.NewContractMock(abis.GasPriceMinimum, gpm)
+	backend.celo.Registry.AddContract(config.GasPriceMinimumRegistryId, common.HexToAddress("0x07"))
+	backend.celo.Runner.RegisterContract(common.HexToAddress("0x07"), gpm)
+	backend.celo.Registry.AddContract(config.GoldTokenRegistryId, common.HexToAddress("0x08"))
+	api := NewPublicCeloAPI(backend)
+
+	before, err := api.GetChainConfig(context.Background(), rpc.BlockNumberOrHashWithNumber(1))
+	if err != nil {
+		t.Fatalf("failed to get the chain config: %v", err)
+	}
+	if before.Number != 1 || before.Config != backend.config {
+		t.Errorf("config mismatch: have block %d, config %v", before.Number, before.Config)
+	}
+	if want := (CeloForks{Churrito: true, Donut: true, Espresso: true}); before.Forks != want {
+		t.Errorf("forks mismatch: have %+v, want %+v", before.Forks, want)
+	}
+	if before.GasLimit != 20_000_000 || before.GasPriceMinimum.ToInt().Int64() != 1_000_000 {
+		t.Errorf("parameters mismatch: have gas limit %d, gas price minimum %v", before.GasLimit, before.GasPriceMinimum)
+	}
+	cusd, ceur := common.HexToAddress("0x02"), common.HexToAddress("0x05") // Whitelisted by the mock
+	if fcs := before.FeeCurrencies; len(fcs) != 2 || !(fcs[0] == cusd && fcs[1] == ceur || fcs[0] == ceur && fcs[1] == cusd) {
+		t.Errorf("fee currencies mismatch: have %v, want %v", before.FeeCurrencies, []common.Address{cusd, ceur})
+	}
+	if len(before.GasPriceMinimums) != 2 || before.GasPriceMinimums[cusd] == nil || before.GasPriceMinimums[ceur] == nil {
+		t.Errorf("fee currency gas price minimums mismatch: have %v", before.GasPriceMinimums)
+	}
+
+	// The forks are the ones of the block, looked up by hash as well
+	latest, err := api.GetChainConfig(context.Background(), rpc.BlockNumberOrHashWithHash(backend.headers[2].Hash(), false))
+	if err != nil {
+		t.Fatalf("failed to get the chain config: %v", err)
+	}
+	if want := (CeloForks{Churrito: true, Donut: true, Espresso: true, Gingerbread: true}); latest.Number != 2 || latest.Forks != want {
+		t.Errorf("block 2 forks mismatch: have %d %+v, want %+v", latest.Number, latest.Forks, want)
+	}
+	if missing, err := api.GetChainConfig(context.Background(), rpc.BlockNumberOrHashWithNumber(3)); missing != nil || err != nil {
+		t.Errorf("unknown block: have %v, %v, want nil", missing, err)
+	}
+}
+
+func TestBatchGetState(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	var (
+		eoa      = common.HexToAddress("0xaa")
+		contract = common.HexToAddress("0xbb")
+		missing  = common.HexToAddress("0xcc")
+		code     = []byte{0x60, 0x00}
+	)
+	statedb.SetBalance(eoa, big.NewInt(1000))
+	statedb.SetNonce(eoa, 3)
+	statedb.SetCode(contract, code)
+	statedb.SetState(contract, common.Hash{1}, common.Hash{2})
+	backend := &historyBackend{state: statedb}
+	api := NewPublicCeloAPI(backend)
+
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	queries := []StateQuery{{Address: eoa}, {Address: contract, Slots: []common.Hash{{1}, {3}}}, {Address: missing}}
+	accounts, err := api.BatchGetState(context.Background(), queries, latest)
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	if backend.states != 1 {
+		t.Errorf("states retrieved mismatch: have %d, want 1", backend.states)
+	}
+	if len(accounts) != 3 {
+		t.Fatalf("accounts mismatch: have %d, want 3", len(accounts))
+	}
+	if a := accounts[0]; a.Address != eoa || a.Balance.ToInt().Int64() != 1000 || a.Nonce != 3 {
+		t.Errorf("account mismatch: have %v %v %d, want %v 1000 3", a.Address, a.Balance, a.Nonce, eoa)
+	}
+	if a := accounts[1]; a.CodeHash != crypto.Keccak256Hash(code) || a.Storage[common.Hash{1}] != (common.Hash{2}) || a.Storage[common.Hash{3}] != (common.Hash{}) {
+		t.Errorf("contract mismatch: have code hash %x, storage %v", a.CodeHash, a.Storage)
+	}
+	if a := accounts[2]; a.Balance.ToInt().Sign() != 0 || a.CodeHash != (common.Hash{}) || len(a.Storage) != 0 {
+		t.Errorf("missing account mismatch: have %v %x %v", a.Balance, a.CodeHash, a.Storage)
+	}
+	if _, err := api.BatchGetState(context.Background(), make([]StateQuery, maxBatchStateAccounts+1), latest); err == nil {
+		t.Errorf("too many accounts read")
+	}
+}
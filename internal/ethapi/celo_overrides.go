@@ -0,0 +1,137 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/contracts"
+	"github.com/celo-org/celo-blockchain/contracts/abis"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+)
+
+var (
+	medianRateMethod         = abis.SortedOracles.Methods["medianRate"]
+	getGasPriceMinimumMethod = abis.GasPriceMinimum.Methods["getGasPriceMinimum"]
+)
+
+// ExchangeRateOverride is an exchange rate of a fee currency, as the numerator
+// and the denominator returned by SortedOracles.medianRate.
+type ExchangeRateOverride struct {
+	Numerator   *hexutil.Big `json:"numerator"`
+	Denominator *hexutil.Big `json:"denominator"`
+}
+
+// CeloOverrides overrides the values the protocol reads from the core contracts
+// during the execution of a message call, to simulate it under hypothetical fee
+// conditions. The calls the contracts make between themselves are not affected.
+type CeloOverrides struct {
+	// ExchangeRates are the exchange rates of fee currencies to CELO, by currency.
+	ExchangeRates map[common.Address]*ExchangeRateOverride `json:"exchangeRates"`
+	// GasPriceMinimums are the gas price minimums by currency, the zero address
+	// for CELO.
+	GasPriceMinimums map[common.Address]*hexutil.Big `json:"gasPriceMinimums"`
+}
+
+// Validate checks that the overridden values are ones the contracts could return.
+func (o *CeloOverrides) Validate() error {
+	if o == nil {
+		return nil
+	}
+	for currency, rate := range o.ExchangeRates {
+		if rate == nil || rate.Numerator == nil || rate.Numerator.ToInt().Sign() <= 0 || rate.Denominator == nil || rate.Denominator.ToInt().Sign() <= 0 {
+			return fmt.Errorf("invalid exchange rate override for currency %s", currency.Hex())
+		}
+	}
+	for currency, gasPriceMinimum := range o.GasPriceMinimums {
+		if gasPriceMinimum == nil || gasPriceMinimum.ToInt().Sign() < 0 {
+			return fmt.Errorf("invalid gas price minimum override for currency %s", currency.Hex())
+		}
+	}
+	return nil
+}
+
+// EVMRunnerFactory wraps a factory so that the EVMRunners it creates return the
+// overridden values. The factory is returned as is without overrides.
+func (o *CeloOverrides) EVMRunnerFactory(factory vm.EVMRunnerFactory) vm.EVMRunnerFactory {
+	if o == nil || (len(o.ExchangeRates) == 0 && len(o.GasPriceMinimums) == 0) {
+		return factory
+	}
+	return &overridingEVMRunnerFactory{factory: factory, overrides: o}
+}
+
+type overridingEVMRunnerFactory struct {
+	factory   vm.EVMRunnerFactory
+	overrides *CeloOverrides
+}
+
+func (f *overridingEVMRunnerFactory) NewEVMRunner(header *types.Header, state vm.StateDB) vm.EVMRunner {
+	return &overridingEVMRunner{EVMRunner: f.factory.NewEVMRunner(header, state), overrides: f.overrides}
+}
+
+// overridingEVMRunner answers the queries of the overridden values, and runs the
+// other calls on the runner it wraps.
+type overridingEVMRunner struct {
+	vm.EVMRunner
+	overrides *CeloOverrides
+
+	resolved        bool
+	sortedOracles   common.Address // Zero if not deployed
+	gasPriceMinimum common.Address // Zero if not deployed
+	goldToken       common.Address // Zero if not deployed
+}
+
+// resolve looks the core contracts up in the registry the first time they are
+// needed.
+func (ev *overridingEVMRunner) resolve() {
+	if ev.resolved {
+		return
+	}
+	ev.resolved = true
+	ev.sortedOracles, _ = contracts.GetRegisteredAddress(ev.EVMRunner, config.SortedOraclesRegistryId)
+	ev.gasPriceMinimum, _ = contracts.GetRegisteredAddress(ev.EVMRunner, config.GasPriceMinimumRegistryId)
+	ev.goldToken, _ = contracts.GetRegisteredAddress(ev.EVMRunner, config.GoldTokenRegistryId)
+}
+
+func (ev *overridingEVMRunner) Query(recipient common.Address, input []byte, gas uint64) (ret []byte, err error) {
+	// The overridden methods all take the currency as their only argument
+	if recipient != config.RegistrySmartContractAddress && len(input) == 4+32 {
+		ev.resolve()
+		currency := common.BytesToAddress(input[4:])
+		switch {
+		case recipient == ev.sortedOracles && ev.sortedOracles != common.ZeroAddress && bytes.Equal(input[:4], medianRateMethod.ID):
+			if rate, ok := ev.overrides.ExchangeRates[currency]; ok {
+				return medianRateMethod.Outputs.Pack(rate.Numerator.ToInt(), rate.Denominator.ToInt())
+			}
+		case recipient == ev.gasPriceMinimum && ev.gasPriceMinimum != common.ZeroAddress && bytes.Equal(input[:4], getGasPriceMinimumMethod.ID):
+			gasPriceMinimum, ok := ev.overrides.GasPriceMinimums[currency]
+			if !ok && currency == ev.goldToken {
+				gasPriceMinimum, ok = ev.overrides.GasPriceMinimums[common.ZeroAddress]
+			}
+			if ok {
+				return getGasPriceMinimumMethod.Outputs.Pack((*big.Int)(gasPriceMinimum))
+			}
+		}
+	}
+	return ev.EVMRunner.Query(recipient, input, gas)
+}
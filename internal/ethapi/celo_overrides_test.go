@@ -0,0 +1,101 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/currency"
+	"github.com/celo-org/celo-blockchain/contracts/gasprice_minimum"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+)
+
+// mockRunnerFactory hands out the same runner for every header and state.
+type mockRunnerFactory struct {
+	runner vm.EVMRunner
+}
+
+func (f *mockRunnerFactory) NewEVMRunner(header *types.Header, state vm.StateDB) vm.EVMRunner {
+	return f.runner
+}
+
+func TestCeloOverrides(t *testing.T) {
+	var (
+		goldToken     = common.HexToAddress("0x10")
+		overridden    = common.HexToAddress("0x02")
+		notOverridden = common.HexToAddress("0x05")
+		celo          = testutil.NewCeloMock()
+		sortedOracles = testutil.NewSortedOraclesMock()
+		gpmMock       = testutil.NewGasPriceMinimumMock()
+	)
+	celo.Registry.AddContract(config.SortedOraclesRegistryId, common.HexToAddress("0x11"))
+	celo.Runner.RegisterContract(common.HexToAddress("0x11"), sortedOracles)
+	celo.Registry.AddContract(config.GasPriceMinimumRegistryId, common.HexToAddress("0x12"))
+	celo.Runner.RegisterContract(common.HexToAddress("0x12"), gpmMock)
+	celo.Registry.AddContract(config.GoldTokenRegistryId, goldToken)
+	sortedOracles.Rates[notOverridden] = [2]*big.Int{big.NewInt(3), big.NewInt(1)}
+
+	overrides := &CeloOverrides{
+		ExchangeRates: map[common.Address]*ExchangeRateOverride{
+			overridden: {Numerator: (*hexutil.Big)(big.NewInt(5)), Denominator: (*hexutil.Big)(big.NewInt(2))},
+		},
+		GasPriceMinimums: map[common.Address]*hexutil.Big{
+			overridden:         (*hexutil.Big)(big.NewInt(42)),
+			common.ZeroAddress: (*hexutil.Big)(big.NewInt(7)),
+		},
+	}
+	if err := overrides.Validate(); err != nil {
+		t.Fatalf("valid overrides rejected: %v", err)
+	}
+	vmRunner := overrides.EVMRunnerFactory(&mockRunnerFactory{celo.Runner}).NewEVMRunner(nil, nil)
+
+	rate, err := currency.GetExchangeRate(vmRunner, &overridden)
+	if err != nil || rate.Numerator().Int64() != 5 || rate.Denominator().Int64() != 2 {
+		t.Errorf("overridden exchange rate mismatch: have %v, %v", rate, err)
+	}
+	rate, err = currency.GetExchangeRate(vmRunner, &notOverridden)
+	if err != nil || rate.Numerator().Int64() != 3 || rate.Denominator().Int64() != 1 {
+		t.Errorf("exchange rate mismatch: have %v, %v", rate, err)
+	}
+	if gasPriceMinimum, err := gasprice_minimum.GetGasPriceMinimum(vmRunner, &overridden); err != nil || gasPriceMinimum.Int64() != 42 {
+		t.Errorf("overridden gas price minimum mismatch: have %v, %v", gasPriceMinimum, err)
+	}
+	// CELO is overridden with the zero address
+	if gasPriceMinimum, err := gasprice_minimum.GetGasPriceMinimum(vmRunner, nil); err != nil || gasPriceMinimum.Int64() != 7 {
+		t.Errorf("overridden CELO gas price minimum mismatch: have %v, %v", gasPriceMinimum, err)
+	}
+	if gasPriceMinimum, err := gasprice_minimum.GetGasPriceMinimum(vmRunner, &notOverridden); err != nil || gasPriceMinimum.Cmp(gpmMock.DefaultGasPriceMinimum) != 0 {
+		t.Errorf("gas price minimum mismatch: have %v, %v", gasPriceMinimum, err)
+	}
+
+	invalid := &CeloOverrides{ExchangeRates: map[common.Address]*ExchangeRateOverride{
+		overridden: {Numerator: (*hexutil.Big)(big.NewInt(1)), Denominator: (*hexutil.Big)(big.NewInt(0))},
+	}}
+	if err := invalid.Validate(); err == nil {
+		t.Errorf("zero denominator accepted")
+	}
+	var none *CeloOverrides
+	if factory := (&mockRunnerFactory{celo.Runner}); none.EVMRunnerFactory(factory) != factory {
+		t.Errorf("factory wrapped without overrides")
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+const (
+	// maxFeeCurrencyHistory is the most blocks a fee currency history covers.
+	maxFeeCurrencyHistory = 1024
+
+	// sysCtxCacheSize is the number of blocks whose system contract values are
+	// kept for the fee currency histories, enough for the largest one.
+	sysCtxCacheSize = maxFeeCurrencyHistory
+)
+
+var errFeeCurrencyNotWhitelisted = errors.New("fee currency not whitelisted")
+
+type feeCurrencyHistoryResult struct {
+	OldestBlock  *hexutil.Big    `json:"oldestBlock"`
+	FeeCurrency  *common.Address `json:"feeCurrency"`
+	BaseFee      []*hexutil.Big  `json:"baseFeePerGas"` // Gas price minimums, nil where the currency wasn't whitelisted
+	GasUsedRatio []float64       `json:"gasUsedRatio"`
+}
+
+// FeeCurrencyHistory returns the gas price minimums of a fee currency, CELO if
+// nil, in the blockCount blocks up to lastBlock, along with how full the blocks
+// were. The currency must be whitelisted at lastBlock.
+func (s *PublicEthereumAPI) FeeCurrencyHistory(ctx context.Context, blockCount rpc.DecimalOrHex, lastBlock rpc.BlockNumber, feeCurrency *common.Address) (*feeCurrencyHistoryResult, error) {
+	if blockCount == 0 {
+		return &feeCurrencyHistoryResult{FeeCurrency: feeCurrency}, nil
+	}
+	if blockCount > maxFeeCurrencyHistory {
+		blockCount = maxFeeCurrencyHistory
+	}
+	last, err := s.b.HeaderByNumber(ctx, lastBlock)
+	if err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, fmt.Errorf("block %d not found", lastBlock)
+	}
+	count := uint64(blockCount)
+	if lastNumber := last.Number.Uint64(); count > lastNumber+1 {
+		count = lastNumber + 1
+	}
+	var (
+		oldest = last.Number.Uint64() + 1 - count
+		result = &feeCurrencyHistoryResult{
+			OldestBlock:  (*hexutil.Big)(new(big.Int).SetUint64(oldest)),
+			FeeCurrency:  feeCurrency,
+			BaseFee:      make([]*hexutil.Big, count),
+			GasUsedRatio: make([]float64, count),
+		}
+	)
+	for i := uint64(0); i < count; i++ {
+		header := last
+		if number := oldest + i; number != last.Number.Uint64() {
+			if header, err = s.b.HeaderByNumber(ctx, rpc.BlockNumber(number)); err != nil {
+				return nil, err
+			}
+			if header == nil {
+				return nil, fmt.Errorf("block %d not found", number)
+			}
+		}
+		sysCtx, err := s.sysContractCallCtx(ctx, header)
+		if err != nil {
+			return nil, err
+		}
+		if sysCtx.IsWhitelisted(feeCurrency) {
+			result.BaseFee[i] = (*hexutil.Big)(sysCtx.GetGasPriceMinimum(feeCurrency))
+		} else if header == last {
+			return nil, errFeeCurrencyNotWhitelisted
+		}
+		gasLimit := header.GasLimit
+		if gasLimit == 0 {
+			gasLimit = s.b.GetBlockGasLimit(ctx, rpc.BlockNumberOrHashWithHash(header.Hash(), false))
+		}
+		if gasLimit > 0 {
+			result.GasUsedRatio[i] = float64(header.GasUsed) / float64(gasLimit)
+		}
+	}
+	return result, nil
+}
+
+// sysContractCallCtx returns the system contract values the given block was
+// processed with, read from the state of its parent and cached.
+func (s *PublicEthereumAPI) sysContractCallCtx(ctx context.Context, header *types.Header) (*core.SysContractCallCtx, error) {
+	hash := header.Hash()
+	if sysCtx, ok := s.sysCtxs.Get(hash); ok {
+		return sysCtx.(*core.SysContractCallCtx), nil
+	}
+	parentHash := header.ParentOrGenesisHash()
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHash{BlockHash: &parentHash})
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, fmt.Errorf("state of block %d not available", header.Number)
+	}
+	sysCtx := core.NewSysContractCallCtx(s.b.ChainConfig(), header, state, s.b)
+	s.sysCtxs.Add(hash, sysCtx)
+	return sysCtx, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+// historyBackend serves a chain of headers, all on the same empty state, with
+// the system contracts of the celo mock.
+type historyBackend struct {
+	Backend
+	headers []*types.Header
+	state   *state.StateDB
+	celo    testutil.CeloMock
+	config  *params.ChainConfig
+	states  int // Number of states retrieved
+}
+
+func (b *historyBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.LatestBlockNumber {
+		number = rpc.BlockNumber(len(b.headers) - 1)
+	}
+	if int(number) >= len(b.headers) {
+		return nil, nil
+	}
+	return b.headers[number], nil
+}
+
+func (b *historyBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	if number, ok := blockNrOrHash.Number(); ok {
+		return b.HeaderByNumber(ctx, number)
+	}
+	hash, _ := blockNrOrHash.Hash()
+	for _, header := range b.headers {
+		if header.Hash() == hash {
+			return header, nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *historyBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	b.states++
+	return b.state, nil, nil
+}
+
+func (b *historyBackend) GetBlockGasLimit(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) uint64 {
+	return 20_000_000
+}
+
+func (b *historyBackend) GetRealBlockGasLimit(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (uint64, error) {
+	return b.GetBlockGasLimit(ctx, blockNrOrHash), nil
+}
+
+func (b *historyBackend) ChainConfig() *params.ChainConfig {
+	return b.config
+}
+
+func (b *historyBackend) NewEVMRunner(header *types.Header, state vm.StateDB) vm.EVMRunner {
+	return b.celo.Runner
+}
+
+func TestFeeCurrencyHistory(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	backend := &historyBackend{state: statedb, celo: testutil.NewCeloMock(), config: params.IstanbulTestChainConfig}
+	for i := 0; i < 4; i++ {
+		backend.headers = append(backend.headers, &types.Header{Number: big.NewInt(int64(i)), GasUsed: 5_000_000, BaseFee: big.NewInt(int64(100 * (i + 1)))})
+	}
+	api := NewPublicEthereumAPI(backend)
+
+	cusd := common.HexToAddress("0x02") // Whitelisted by the mock, at a rate of 1
+	history, err := api.FeeCurrencyHistory(context.Background(), 3, rpc.LatestBlockNumber, &cusd)
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if oldest := history.OldestBlock.ToInt().Int64(); oldest != 1 {
+		t.Errorf("oldest block mismatch: have %d, want 1", oldest)
+	}
+	for i, want := range []int64{200, 300, 400} {
+		if fee := history.BaseFee[i].ToInt().Int64(); fee != want {
+			t.Errorf("block %d: base fee mismatch: have %d, want %d", i+1, fee, want)
+		}
+		if ratio := history.GasUsedRatio[i]; ratio != 0.25 {
+			t.Errorf("block %d: gas used ratio mismatch: have %f, want 0.25", i+1, ratio)
+		}
+	}
+	// The system contract values of the blocks are cached
+	if _, err := api.FeeCurrencyHistory(context.Background(), 10, rpc.LatestBlockNumber, &cusd); err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if backend.states != 4 {
+		t.Errorf("states retrieved mismatch: have %d, want 4", backend.states)
+	}
+	unknown := common.HexToAddress("0x04")
+	if _, err := api.FeeCurrencyHistory(context.Background(), 1, rpc.LatestBlockNumber, &unknown); err != errFeeCurrencyNotWhitelisted {
+		t.Errorf("error mismatch: have %v, want %v", err, errFeeCurrencyNotWhitelisted)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
+	"github.com/celo-org/celo-blockchain/contracts/currency"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+// gasEstimator executes a message with varying gas limits on a single state and
+// EVM, rolling the state back after every execution, so that the environment
+// of a gas estimation is only set up once.
+type gasEstimator struct {
+	args   TransactionArgs
+	gasCap uint64
+
+	state    *state.StateDB
+	header   *types.Header
+	sysCtx   *core.SysContractCallCtx
+	evm      *vm.EVM
+	vmError  func() error
+	vmRunner vm.EVMRunner
+	txCtx    vm.TxContext
+}
+
+// newGasEstimator prepares the environment to execute args at the given block.
+// The EVM is cancelled once ctx is done.
+func newGasEstimator(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, gasCap uint64) (*gasEstimator, error) {
+	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	msg, err := args.ToMessage(gasCap, header.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	evm, vmError, err := b.GetEVM(ctx, msg, state, header, &vm.Config{NoBaseFee: true, SkipDebitCredit: true})
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+	e := &gasEstimator{
+		args:     args,
+		gasCap:   gasCap,
+		state:    state,
+		header:   header,
+		evm:      evm,
+		vmError:  vmError,
+		vmRunner: b.NewEVMRunner(header, state),
+		txCtx:    evm.TxContext,
+	}
+	if b.ChainConfig().IsEspresso(header.Number) {
+		e.sysCtx = core.NewSysContractCallCtx(b.ChainConfig(), header, state, b)
+	}
+	return e, nil
+}
+
+// intrinsicGas returns the gas the message is charged before any execution,
+// including the surcharge of its fee currency for the debit and credit calls.
+func (e *gasEstimator) intrinsicGas() (uint64, error) {
+	var currencyGas uint64
+	if e.args.FeeCurrency != nil {
+		if e.sysCtx != nil {
+			currencyGas = e.sysCtx.GetIntrinsicGasForAlternativeFeeCurrency()
+		} else {
+			currencyGas = blockchain_parameters.GetIntrinsicGasForAlternativeFeeCurrencyOrDefault(e.vmRunner)
+		}
+	}
+	var accessList types.AccessList
+	if e.args.AccessList != nil {
+		accessList = *e.args.AccessList
+	}
+	istanbul := e.evm.ChainConfig().IsIstanbul(e.header.Number)
+	return core.IntrinsicGas(e.args.data(), accessList, e.args.To == nil, e.args.FeeCurrency, currencyGas, istanbul)
+}
+
+// allowance returns the most gas the sender can pay for at the given fee cap,
+// in the currency the fees are paid in. It fails with an insufficientFundsError
+// if the balance doesn't even cover the intrinsic gas.
+func (e *gasEstimator) allowance(feeCap *big.Int, intrinsic uint64) (uint64, error) {
+	from := e.args.from()
+
+	var balance *big.Int
+	if e.args.FeeCurrency == nil {
+		balance = new(big.Int).Set(e.state.GetBalance(from))
+		if e.args.Value != nil {
+			if e.args.Value.ToInt().Cmp(balance) >= 0 {
+				return 0, fmt.Errorf("%w: address %v", core.ErrInsufficientFundsForTransfer, from.Hex())
+			}
+			balance.Sub(balance, e.args.Value.ToInt())
+		}
+	} else {
+		var err error
+		if balance, err = currency.GetBalanceOf(e.vmRunner, from, *e.args.FeeCurrency); err != nil {
+			return 0, err
+		}
+		// The fee cap of CELO denominated transactions is converted at the
+		// current rate, as the state transition does.
+		if e.args.MaxFeeInFeeCurrency != nil {
+			rate, err := currency.GetExchangeRate(e.vmRunner, e.args.FeeCurrency)
+			if err != nil {
+				return 0, err
+			}
+			feeCap = rate.FromBase(feeCap)
+		}
+	}
+	if feeCap.Sign() <= 0 {
+		return math.MaxUint64, nil
+	}
+	if cost := new(big.Int).Mul(feeCap, new(big.Int).SetUint64(intrinsic)); balance.Cmp(cost) < 0 {
+		return 0, &insufficientFundsError{
+			error:       fmt.Errorf("%w: address %v have %v want %v", core.ErrInsufficientFunds, from.Hex(), balance, cost),
+			feeCurrency: e.args.FeeCurrency,
+			balance:     balance,
+			cost:        cost,
+		}
+	}
+	allowance := new(big.Int).Div(balance, feeCap)
+	if !allowance.IsUint64() {
+		return math.MaxUint64, nil
+	}
+	return allowance.Uint64(), nil
+}
+
+// insufficientFundsError is an API error returned when the sender can't pay the
+// fees of a message, with the balance and the cost in the fee currency.
+type insufficientFundsError struct {
+	error
+	feeCurrency *common.Address
+	balance     *big.Int
+	cost        *big.Int
+}
+
+// ErrorCode returns the JSON error code of a failed transaction.
+func (e *insufficientFundsError) ErrorCode() int {
+	return -32000
+}
+
+// ErrorData returns the balance of the sender and the cost of the intrinsic gas,
+// both in the fee currency.
+func (e *insufficientFundsError) ErrorData() interface{} {
+	return map[string]interface{}{
+		"feeCurrency": e.feeCurrency,
+		"balance":     (*hexutil.Big)(e.balance),
+		"cost":        (*hexutil.Big)(e.cost),
+	}
+}
+
+// Unwrap returns the underlying core.ErrInsufficientFunds error.
+func (e *insufficientFundsError) Unwrap() error {
+	return e.error
+}
+
+// execute runs the message with the given gas limit, and reports whether it failed.
+func (e *gasEstimator) execute(gas uint64) (bool, *core.ExecutionResult, error) {
+	e.args.Gas = (*hexutil.Uint64)(&gas)
+	msg, err := e.args.ToMessage(e.gasCap, e.header.BaseFee)
+	if err != nil {
+		return true, nil, err
+	}
+	snap := e.state.Snapshot()
+	defer e.state.RevertToSnapshot(snap)
+
+	e.evm.Reset(e.txCtx, e.state)
+	result, err := core.ApplyMessage(e.evm, msg, new(core.GasPool).AddGas(math.MaxUint64), e.vmRunner, e.sysCtx)
+	if err := e.vmError(); err != nil {
+		return true, nil, err
+	}
+	if e.evm.Cancelled() {
+		return true, nil, errors.New("execution aborted")
+	}
+	if err != nil {
+		if errors.Is(err, core.ErrIntrinsicGas) {
+			return true, nil, nil // Special case, raise gas limit
+		}
+		return true, nil, fmt.Errorf("err: %w (supplied gas %d)", err, gas)
+	}
+	return result.Failed(), result, nil
+}
+
+// estimate binary searches the lowest gas limit in (lo, hi] the message executes
+// with. The message is first run with hi, which both rejects messages that can't
+// succeed and bounds the search with the gas it used.
+func (e *gasEstimator) estimate(lo, hi uint64) (uint64, error) {
+	failed, result, err := e.execute(hi)
+	if err != nil {
+		return 0, err
+	}
+	if failed {
+		if result != nil && result.Err != vm.ErrOutOfGas {
+			if len(result.Revert()) > 0 {
+				return 0, newRevertError(result)
+			}
+			return 0, result.Err
+		}
+		// Otherwise, the specified gas cap is too low
+		return 0, fmt.Errorf("gas required exceeds allowance (%d)", hi)
+	}
+	// The message can't run with less than the gas it used
+	if result.UsedGas > lo+1 {
+		lo = result.UsedGas - 1
+	}
+	// Most messages only need the gas they used, plus what sub calls withhold
+	// (see EIP-150), try that before bisecting.
+	if optimistic := (result.UsedGas + params.CallStipend) * 64 / 63; optimistic > lo && optimistic < hi {
+		failed, _, err := e.execute(optimistic)
+		if err != nil {
+			return 0, err
+		}
+		if failed {
+			lo = optimistic
+		} else {
+			hi = optimistic
+		}
+	}
+	for lo+1 < hi {
+		mid := (hi + lo) / 2
+		failed, _, err := e.execute(mid)
+
+		// If the error is not nil(consensus error), it means the provided message
+		// call or transaction will never be accepted no matter how much gas it is
+		// assigned. Return the error directly, don't struggle any more.
+		if err != nil {
+			return 0, err
+		}
+		if failed {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi, nil
+}
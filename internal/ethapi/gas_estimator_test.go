@@ -0,0 +1,129 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/core/vm/vmcontext"
+	"github.com/celo-org/celo-blockchain/params"
+)
+
+// Tests that the estimator finds the exact gas requirement of a message, running
+// every attempt on the same state without leaking any change.
+func TestGasEstimatorReusesState(t *testing.T) {
+	var (
+		from     = common.HexToAddress("0x1000")
+		contract = common.HexToAddress("0x2000")
+		header   = &types.Header{Number: big.NewInt(1), GasLimit: 20_000_000, BaseFee: big.NewInt(0)}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	// PUSH1 1, PUSH1 0, SSTORE, STOP: a fresh storage slot write
+	statedb.SetCode(contract, common.FromHex("0x600160005500"))
+
+	args := TransactionArgs{From: &from, To: &contract}
+	msg, err := args.ToMessage(0, header.BaseFee)
+	if err != nil {
+		t.Fatalf("failed to create message: %v", err)
+	}
+	celoMock := testutil.NewCeloMock()
+	evm := vm.NewEVM(vmcontext.NewBlockContext(header, nil, nil), vm.TxContext{Origin: msg.From(), GasPrice: msg.GasPrice()}, statedb, params.IstanbulTestChainConfig, vm.Config{NoBaseFee: true, SkipDebitCredit: true})
+	e := &gasEstimator{
+		args:     args,
+		state:    statedb,
+		header:   header,
+		evm:      evm,
+		vmError:  func() error { return nil },
+		sysCtx:   core.NewSysContractCallCtx(params.IstanbulTestChainConfig, header, statedb, celoMock.RunnerFactory()),
+		vmRunner: celoMock.Runner,
+		txCtx:    evm.TxContext,
+	}
+	gas, err := e.estimate(params.TxGas-1, header.GasLimit)
+	if err != nil {
+		t.Fatalf("failed to estimate gas: %v", err)
+	}
+	if failed, _, err := e.execute(gas); failed || err != nil {
+		t.Errorf("execution with the estimate failed: %v", err)
+	}
+	if failed, _, _ := e.execute(gas - 1); !failed {
+		t.Errorf("execution with less than the estimate succeeded")
+	}
+	if value := statedb.GetState(contract, common.Hash{}); value != (common.Hash{}) {
+		t.Errorf("estimation changed the state: slot is %x", value)
+	}
+}
+
+// Tests that the estimate of a message paying fees in another currency includes
+// the intrinsic gas of the currency, and that the funds of the sender in that
+// currency bound the gas.
+func TestGasEstimatorFeeCurrency(t *testing.T) {
+	var (
+		from        = common.HexToAddress("0x1000")
+		to          = common.HexToAddress("0x2000")
+		feeCurrency = common.HexToAddress("0x02") // Whitelisted by the mock, with a balance of 1e15
+		header      = &types.Header{Number: big.NewInt(1), GasLimit: 20_000_000, BaseFee: big.NewInt(0)}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	args := TransactionArgs{From: &from, To: &to, FeeCurrency: &feeCurrency}
+	msg, err := args.ToMessage(0, header.BaseFee)
+	if err != nil {
+		t.Fatalf("failed to create message: %v", err)
+	}
+	celoMock := testutil.NewCeloMock()
+	evm := vm.NewEVM(vmcontext.NewBlockContext(header, nil, nil), vm.TxContext{Origin: msg.From(), GasPrice: msg.GasPrice()}, statedb, params.IstanbulTestChainConfig, vm.Config{NoBaseFee: true, SkipDebitCredit: true})
+	e := &gasEstimator{
+		args:     args,
+		state:    statedb,
+		header:   header,
+		evm:      evm,
+		vmError:  func() error { return nil },
+		sysCtx:   core.NewSysContractCallCtx(params.IstanbulTestChainConfig, header, statedb, celoMock.RunnerFactory()),
+		vmRunner: celoMock.Runner,
+		txCtx:    evm.TxContext,
+	}
+	want := params.TxGas + celoMock.BlockchainParameters.IntrinsicGasForAlternativeFeeCurrencyValue.Uint64()
+	intrinsic, err := e.intrinsicGas()
+	if err != nil || intrinsic != want {
+		t.Fatalf("intrinsic gas mismatch: have %d, %v, want %d", intrinsic, err, want)
+	}
+	if gas, err := e.estimate(intrinsic-1, header.GasLimit); err != nil || gas != want {
+		t.Errorf("estimate mismatch: have %d, %v, want %d", gas, err, want)
+	}
+	if allowance, err := e.allowance(big.NewInt(1_000_000_000), intrinsic); err != nil || allowance != 1_000_000 {
+		t.Errorf("allowance mismatch: have %d, %v, want %d", allowance, err, 1_000_000)
+	}
+	// At this fee cap the balance doesn't cover the intrinsic gas
+	_, err = e.allowance(big.NewInt(100_000_000_000), intrinsic)
+	if !errors.Is(err, core.ErrInsufficientFunds) {
+		t.Fatalf("error mismatch: have %v, want %v", err, core.ErrInsufficientFunds)
+	}
+	data := err.(*insufficientFundsError).ErrorData().(map[string]interface{})
+	if cost := data["cost"].(*hexutil.Big).ToInt(); cost.Cmp(new(big.Int).Mul(big.NewInt(100_000_000_000), new(big.Int).SetUint64(want))) != 0 {
+		t.Errorf("cost mismatch: have %v, want %d", cost, 100_000_000_000*want)
+	}
+}
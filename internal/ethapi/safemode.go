@@ -0,0 +1,161 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+// Modes reported by admin_nodeHealth.
+const (
+	NodeModeNormal = "normal"
+	NodeModeSafe   = "safe" // Read-only after the chain database was found corrupted
+)
+
+var errSafeMode = errors.New("node in read-only safe mode")
+
+// NodeHealth is the status of the node reported by admin_nodeHealth.
+type NodeHealth struct {
+	Mode       string         `json:"mode"`
+	Corruption string         `json:"corruption,omitempty"` // Integrity check failure that started the safe mode
+	Head       hexutil.Uint64 `json:"head"`                 // Head block in the database
+	Synced     bool           `json:"synced"`
+	Mining     bool           `json:"mining"`
+}
+
+// SafeModeAPIs returns the APIs served while the node is in safe mode, after the
+// integrity check of the chain database failed with corruption: the status of
+// the node, and the blocks and transactions of db that can still be decoded. No
+// database is served if nil.
+func SafeModeAPIs(db ethdb.Database, corruption error) []rpc.API {
+	api := &SafeModeAPI{db: db, corruption: corruption}
+	apis := []rpc.API{
+		{
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   &PrivateSafeModeAdminAPI{api},
+		},
+	}
+	if db != nil {
+		api.config = rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+		apis = append(apis, rpc.API{
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   api,
+			Public:    true,
+		})
+	}
+	return apis
+}
+
+// SafeModeAPI serves the intact history of a corrupted chain database read-only.
+type SafeModeAPI struct {
+	db         ethdb.Database
+	config     *params.ChainConfig
+	corruption error
+}
+
+// PrivateSafeModeAdminAPI reports the status of a node in safe mode.
+type PrivateSafeModeAdminAPI struct {
+	api *SafeModeAPI
+}
+
+// NodeHealth reports the node is in safe mode, and why.
+func (s *PrivateSafeModeAdminAPI) NodeHealth() *NodeHealth {
+	health := &NodeHealth{Mode: NodeModeSafe, Corruption: s.api.corruption.Error()}
+	if s.api.db != nil {
+		health.Head = s.api.head()
+	}
+	return health
+}
+
+// head returns the number of the head block recorded in the database.
+func (s *SafeModeAPI) head() hexutil.Uint64 {
+	if number := rawdb.ReadHeaderNumber(s.db, rawdb.ReadHeadBlockHash(s.db)); number != nil {
+		return hexutil.Uint64(*number)
+	}
+	return 0
+}
+
+// ChainId returns the chain ID of the database, nil if its config is missing.
+func (s *SafeModeAPI) ChainId() *hexutil.Big {
+	if s.config == nil {
+		return nil
+	}
+	return (*hexutil.Big)(s.config.ChainID)
+}
+
+// BlockNumber returns the head block recorded in the database.
+func (s *SafeModeAPI) BlockNumber() hexutil.Uint64 {
+	return s.head()
+}
+
+// GetBlockByNumber returns the requested canonical block, nil if it's missing or
+// can't be decoded. Pending and latest are the head block.
+func (s *SafeModeAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	n := uint64(s.head())
+	if number >= 0 {
+		n = uint64(number)
+	} else if number == rpc.EarliestBlockNumber {
+		n = 0
+	}
+	return s.GetBlockByHash(rawdb.ReadCanonicalHash(s.db, n), fullTx)
+}
+
+// GetBlockByHash returns the requested block, nil if it's missing or can't be
+// decoded. The gas prices of the full transactions paid in other currencies than
+// CELO are left out, without state to read their base fee from.
+func (s *SafeModeAPI) GetBlockByHash(hash common.Hash, fullTx bool) (map[string]interface{}, error) {
+	number := rawdb.ReadHeaderNumber(s.db, hash)
+	if number == nil {
+		return nil, nil
+	}
+	block := rawdb.ReadBlock(s.db, hash, *number)
+	if block == nil {
+		return nil, nil
+	}
+	baseFeeFn := func(feeCurrency *common.Address) (*big.Int, error) {
+		if feeCurrency == nil && block.BaseFee() != nil {
+			return block.BaseFee(), nil
+		}
+		return nil, errSafeMode
+	}
+	return RPCMarshalBlock(block, true, fullTx, baseFeeFn)
+}
+
+// GetRawTransactionByHash returns the bytes of an indexed transaction, nil if
+// it's missing or can't be decoded.
+func (s *SafeModeAPI) GetRawTransactionByHash(hash common.Hash) (hexutil.Bytes, error) {
+	tx, _, _, _ := rawdb.ReadTransaction(s.db, hash)
+	if tx == nil {
+		return nil, nil
+	}
+	return tx.MarshalBinary()
+}
+
+// SendRawTransaction refuses transactions in safe mode.
+func (s *SafeModeAPI) SendRawTransaction(input hexutil.Bytes) (common.Hash, error) {
+	return common.Hash{}, errSafeMode
+}
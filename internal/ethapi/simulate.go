@@ -0,0 +1,218 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+const (
+	// maxSimulatedCalls is the most calls a bundle simulation executes.
+	maxSimulatedCalls = 256
+
+	// simulateTimeout bounds the execution of all the calls of a bundle.
+	simulateTimeout = 50 * time.Second
+)
+
+// simulatedAccount is the part of an account a simulated call changed.
+type simulatedAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   *hexutil.Uint64             `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// simulatedStateDiff is the state changed by a simulated call, as the values of
+// the changed fields before and after the call.
+type simulatedStateDiff struct {
+	Pre  map[common.Address]*simulatedAccount `json:"pre"`
+	Post map[common.Address]*simulatedAccount `json:"post"`
+}
+
+type simulatedCallResult struct {
+	ReturnValue hexutil.Bytes       `json:"returnValue"`
+	GasUsed     hexutil.Uint64      `json:"gasUsed"`
+	Status      hexutil.Uint64      `json:"status"`
+	Error       string              `json:"error,omitempty"`
+	Logs        []*types.Log        `json:"logs"`
+	StateDiff   *simulatedStateDiff `json:"stateDiff"`
+}
+
+// SimulateBundle executes the given calls in order on top of a block, each on
+// the state left by the previous ones, and returns the results, the logs and the
+// state changes of every call. The calls are executed as transactions would,
+// paying their fees in their fee currencies along with the intrinsic gas of the
+// currencies, but without checking their nonces. The pending block simulates
+// them on top of the pending transactions of the miner. State and celo overrides
+// are applied before the first call.
+func (s *PublicBlockChainAPI) SimulateBundle(ctx context.Context, calls []TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride, celoOverrides *CeloOverrides) ([]*simulatedCallResult, error) {
+	if len(calls) > maxSimulatedCalls {
+		return nil, fmt.Errorf("too many calls: %d, the most is %d", len(calls), maxSimulatedCalls)
+	}
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	statedb, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, bNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(statedb); err != nil {
+		return nil, err
+	}
+	if err := celoOverrides.Validate(); err != nil {
+		return nil, err
+	}
+	// The overrides aren't part of the state changes of the first call
+	statedb.Finalise(true)
+
+	ctx, cancel := context.WithTimeout(ctx, simulateTimeout)
+	defer cancel()
+
+	factory := celoOverrides.EVMRunnerFactory(s.b)
+	var sysCtx *core.SysContractCallCtx
+	if s.b.ChainConfig().IsEspresso(header.Number) {
+		sysCtx = core.NewSysContractCallCtx(s.b.ChainConfig(), header, statedb, factory)
+	}
+	vmRunner := factory.NewEVMRunner(header, statedb)
+	gp := new(core.GasPool).AddGas(math.MaxUint64)
+
+	results := make([]*simulatedCallResult, 0, len(calls))
+	for i, args := range calls {
+		msg, err := args.ToMessage(s.b.RPCGasCap(), header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		pre := statedb.Copy()
+		statedb.Prepare(common.Hash{}, i)
+		result, err := s.simulateCall(ctx, msg, statedb, header, vmRunner, gp, sysCtx)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		result.Logs = statedb.GetLogs(common.Hash{}, header.Hash())[len(pre.GetLogs(common.Hash{}, header.Hash())):]
+		if result.Logs == nil {
+			result.Logs = []*types.Log{}
+		}
+		result.StateDiff = stateDiff(pre, statedb, statedb.DirtyAccounts())
+		statedb.Finalise(true)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// simulateCall executes a call of a bundle on the state. A call that couldn't be
+// executed, for instance because its sender can't pay for it, fails with the
+// error without changing the state.
+func (s *PublicBlockChainAPI) simulateCall(ctx context.Context, msg types.Message, statedb *state.StateDB, header *types.Header, vmRunner vm.EVMRunner, gp *core.GasPool, sysCtx *core.SysContractCallCtx) (*simulatedCallResult, error) {
+	evm, vmError, err := s.b.GetEVM(ctx, msg, statedb, header, &vm.Config{NoBaseFee: true})
+	if err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			evm.Cancel()
+		case <-done:
+		}
+	}()
+
+	snapshot := statedb.Snapshot()
+	result, err := core.ApplyMessage(evm, msg, gp, vmRunner, sysCtx)
+	if err := vmError(); err != nil {
+		return nil, err
+	}
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", simulateTimeout)
+	}
+	if err != nil {
+		statedb.RevertToSnapshot(snapshot)
+		return &simulatedCallResult{ReturnValue: hexutil.Bytes{}, Status: hexutil.Uint64(types.ReceiptStatusFailed), Error: err.Error()}, nil
+	}
+	res := &simulatedCallResult{
+		ReturnValue: result.Return(),
+		GasUsed:     hexutil.Uint64(result.UsedGas),
+		Status:      hexutil.Uint64(types.ReceiptStatusSuccessful),
+	}
+	if res.ReturnValue == nil {
+		res.ReturnValue = hexutil.Bytes{}
+	}
+	if result.Failed() {
+		res.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+		if len(result.Revert()) > 0 {
+			res.ReturnValue = result.Revert()
+			res.Error = newRevertError(result).Error()
+		} else {
+			res.Error = result.Err.Error()
+		}
+	}
+	return res, nil
+}
+
+// stateDiff returns the changes of the dirty accounts and slots of a state from
+// the state before.
+func stateDiff(pre, post *state.StateDB, dirties map[common.Address][]common.Hash) *simulatedStateDiff {
+	diff := &simulatedStateDiff{
+		Pre:  make(map[common.Address]*simulatedAccount),
+		Post: make(map[common.Address]*simulatedAccount),
+	}
+	for addr, slots := range dirties {
+		var (
+			before  = new(simulatedAccount)
+			after   = new(simulatedAccount)
+			changed bool
+		)
+		if a, b := pre.GetBalance(addr), post.GetBalance(addr); a.Cmp(b) != 0 {
+			before.Balance, after.Balance = (*hexutil.Big)(a), (*hexutil.Big)(b)
+			changed = true
+		}
+		if a, b := pre.GetNonce(addr), post.GetNonce(addr); a != b {
+			before.Nonce, after.Nonce = (*hexutil.Uint64)(&a), (*hexutil.Uint64)(&b)
+			changed = true
+		}
+		if a, b := pre.GetCode(addr), post.GetCode(addr); !bytes.Equal(a, b) {
+			before.Code, after.Code = a, b
+			changed = true
+		}
+		for _, slot := range slots {
+			if a, b := pre.GetState(addr, slot), post.GetState(addr, slot); a != b {
+				if before.Storage == nil {
+					before.Storage, after.Storage = make(map[common.Hash]common.Hash), make(map[common.Hash]common.Hash)
+				}
+				before.Storage[slot], after.Storage[slot] = a, b
+				changed = true
+			}
+		}
+		if changed {
+			diff.Pre[addr], diff.Post[addr] = before, after
+		}
+	}
+	return diff
+}
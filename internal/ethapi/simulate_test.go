@@ -0,0 +1,62 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+)
+
+func TestStateDiff(t *testing.T) {
+	var (
+		sender   = common.HexToAddress("0x01")
+		token    = common.HexToAddress("0x02")
+		touched  = common.HexToAddress("0x03")
+		slot     = common.HexToHash("0x04")
+		sameSlot = common.HexToHash("0x05")
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetBalance(sender, big.NewInt(100))
+	statedb.SetCode(token, []byte{0x00})
+	statedb.SetState(token, sameSlot, common.HexToHash("0x01"))
+	statedb.Finalise(true)
+
+	pre := statedb.Copy()
+	statedb.SubBalance(sender, big.NewInt(30))
+	statedb.SetNonce(sender, 1)
+	statedb.SetState(token, slot, common.HexToHash("0x2a"))
+	statedb.SetState(token, sameSlot, common.HexToHash("0x01"))
+	// Touched without any change
+	statedb.AddBalance(touched, new(big.Int))
+
+	diff := stateDiff(pre, statedb, statedb.DirtyAccounts())
+	if len(diff.Pre) != 2 || len(diff.Post) != 2 {
+		t.Fatalf("changed accounts mismatch: have %d before and %d after, want 2", len(diff.Pre), len(diff.Post))
+	}
+	before, after := diff.Pre[sender], diff.Post[sender]
+	if before.Balance.ToInt().Int64() != 100 || after.Balance.ToInt().Int64() != 70 || uint64(*before.Nonce) != 0 || uint64(*after.Nonce) != 1 || before.Storage != nil {
+		t.Errorf("sender diff mismatch: have %+v before, %+v after", before, after)
+	}
+	before, after = diff.Pre[token], diff.Post[token]
+	if before.Balance != nil || before.Nonce != nil || before.Code != nil || len(before.Storage) != 1 || before.Storage[slot] != (common.Hash{}) || after.Storage[slot] != common.HexToHash("0x2a") {
+		t.Errorf("token diff mismatch: have %+v before, %+v after", before, after)
+	}
+}
@@ -78,6 +78,18 @@ func (arg *TransactionArgs) data() []byte {
 	return nil
 }
 
+// feeCap retrieves the most the sender pays per unit of gas, in its fee currency
+// unless MaxFeeInFeeCurrency is set, or nil if no price is given.
+func (arg *TransactionArgs) feeCap() *big.Int {
+	if arg.GasPrice != nil {
+		return arg.GasPrice.ToInt()
+	}
+	if arg.MaxFeePerGas != nil {
+		return arg.MaxFeePerGas.ToInt()
+	}
+	return nil
+}
+
 // setDefaults fills in default values for unspecified tx fields.
 func (args *TransactionArgs) setDefaults(ctx context.Context, b Backend) error {
 	if err := args.checkEthCompatibility(); err != nil {
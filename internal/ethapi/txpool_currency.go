@@ -0,0 +1,115 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+// txPoolEligibility reports which pending transactions of a fee currency the
+// miner would consider for the next block.
+type txPoolEligibility struct {
+	GasPriceMinimum *hexutil.Big                 `json:"gasPriceMinimum"`
+	Eligible        hexutil.Uint                 `json:"eligible"`
+	Ineligible      hexutil.Uint                 `json:"ineligible"`   // Below the gas price minimum, or without exchange rate
+	Transactions    map[string]map[string]string `json:"transactions"` // Summaries by account and nonce
+}
+
+// ContentByCurrency returns the pending transactions of the pool grouped by fee
+// currency, CELO being keyed by the zero address, then by account and nonce.
+func (s *PublicTxPoolAPI) ContentByCurrency() map[common.Address]map[string]map[string]*RPCTransaction {
+	content := make(map[common.Address]map[string]map[string]*RPCTransaction)
+	pending, _ := s.b.TxPoolContent()
+	curHeader := s.b.CurrentHeader()
+	for account, txs := range pending {
+		for _, tx := range txs {
+			feeCurrency := feeCurrencyKey(tx)
+			if content[feeCurrency] == nil {
+				content[feeCurrency] = make(map[string]map[string]*RPCTransaction)
+			}
+			dump := content[feeCurrency][account.Hex()]
+			if dump == nil {
+				dump = make(map[string]*RPCTransaction)
+				content[feeCurrency][account.Hex()] = dump
+			}
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
+		}
+	}
+	return content
+}
+
+// InspectEligible summarizes the pending transactions of the pool by fee currency
+// like Inspect, flagging those the miner would leave out of the next block for
+// paying less than the gas price minimum of their currency, or for lacking an
+// exchange rate to CELO. The minimums and rates are read from the head state,
+// with the same conversions as the miner.
+func (s *PublicTxPoolAPI) InspectEligible(ctx context.Context) (map[common.Address]*txPoolEligibility, error) {
+	state, head, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	var (
+		sysCtx            = core.NewSysContractCallCtx(s.b.ChainConfig(), head, state, s.b)
+		baseFeeFn, toCELO = core.CreateConversionFunctions(sysCtx, s.b, head, state.Copy())
+		content           = make(map[common.Address]*txPoolEligibility)
+	)
+	pending, _ := s.b.TxPoolContent()
+	for account, txs := range pending {
+		for _, tx := range txs {
+			feeCurrency := feeCurrencyKey(tx)
+			eligibility := content[feeCurrency]
+			if eligibility == nil {
+				eligibility = &txPoolEligibility{
+					GasPriceMinimum: (*hexutil.Big)(new(big.Int).Set(baseFeeFn(tx.FeeCurrency()))),
+					Transactions:    make(map[string]map[string]string),
+				}
+				content[feeCurrency] = eligibility
+			}
+			status := "eligible"
+			if _, err := types.NewTxWithMinerFee(tx, baseFeeFn, toCELO); err != nil {
+				status = fmt.Sprintf("ineligible: %v", err)
+				eligibility.Ineligible++
+			} else {
+				eligibility.Eligible++
+			}
+			dump := eligibility.Transactions[account.Hex()]
+			if dump == nil {
+				dump = make(map[string]string)
+				eligibility.Transactions[account.Hex()] = dump
+			}
+			dump[fmt.Sprintf("%d", tx.Nonce())] = fmt.Sprintf("%s (%s)", inspectTx(tx), status)
+		}
+	}
+	return content, nil
+}
+
+// feeCurrencyKey returns the fee currency of a transaction, the zero address for
+// CELO.
+func feeCurrencyKey(tx *types.Transaction) common.Address {
+	if feeCurrency := tx.FeeCurrency(); feeCurrency != nil {
+		return *feeCurrency
+	}
+	return common.ZeroAddress
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
+	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+// poolBackend serves a pool with pending transactions on the head of a history
+// backend.
+type poolBackend struct {
+	*historyBackend
+	pending map[common.Address]types.Transactions
+}
+
+func (b *poolBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	return b.state, b.CurrentHeader(), nil
+}
+
+func (b *poolBackend) CurrentHeader() *types.Header {
+	return b.headers[len(b.headers)-1]
+}
+
+func (b *poolBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	return b.pending, nil
+}
+
+func TestTxPoolEligibility(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	backend := &poolBackend{
+		historyBackend: &historyBackend{
+			headers: []*types.Header{{Number: big.NewInt(1), BaseFee: big.NewInt(100)}},
+			state:   statedb,
+			celo:    testutil.NewCeloMock(),
+			config:  params.TestChainConfig,
+		},
+		pending: make(map[common.Address]types.Transactions),
+	}
+	var (
+		key, _ = crypto.GenerateKey()
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		cusd   = common.HexToAddress("0x02") // Whitelisted by the mock, at a rate of 1
+		signer = types.LatestSignerForChainID(params.TestChainConfig.ChainID)
+	)
+	for _, feeCap := range []int64{150, 50} {
+		for _, feeCurrency := range []*common.Address{nil, &cusd} {
+			tx, _ := types.SignNewTx(key, signer, &types.CeloDynamicFeeTxV2{
+				ChainID:     params.TestChainConfig.ChainID,
+				Nonce:       uint64(len(backend.pending[sender])),
+				GasTipCap:   big.NewInt(1),
+				GasFeeCap:   big.NewInt(feeCap),
+				Gas:         100000,
+				FeeCurrency: feeCurrency,
+				To:          &common.Address{},
+				Value:       big.NewInt(1),
+			})
+			backend.pending[sender] = append(backend.pending[sender], tx)
+		}
+	}
+	api := NewPublicTxPoolAPI(backend)
+
+	content := api.ContentByCurrency()
+	if len(content) != 2 {
+		t.Fatalf("currencies mismatch: have %d, want 2", len(content))
+	}
+	for _, feeCurrency := range []common.Address{common.ZeroAddress, cusd} {
+		if txs := content[feeCurrency][sender.Hex()]; len(txs) != 2 {
+			t.Errorf("currency %x: transactions mismatch: have %d, want 2", feeCurrency, len(txs))
+		}
+	}
+	eligibility, err := api.InspectEligible(context.Background())
+	if err != nil {
+		t.Fatalf("failed to inspect eligibility: %v", err)
+	}
+	for _, feeCurrency := range []common.Address{common.ZeroAddress, cusd} {
+		report := eligibility[feeCurrency]
+		if report == nil {
+			t.Fatalf("currency %x: missing eligibility", feeCurrency)
+		}
+		if gpm := report.GasPriceMinimum.ToInt().Int64(); gpm != 100 {
+			t.Errorf("currency %x: gas price minimum mismatch: have %d, want 100", feeCurrency, gpm)
+		}
+		if report.Eligible != 1 || report.Ineligible != 1 {
+			t.Errorf("currency %x: eligibility mismatch: have %d eligible and %d ineligible, want 1 and 1", feeCurrency, report.Eligible, report.Ineligible)
+		}
+	}
+}
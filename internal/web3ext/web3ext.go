@@ -19,6 +19,7 @@ package web3ext
 
 var Modules = map[string]string{
 	"admin":    AdminJs,
+	"celo":     CeloJs,
 	"debug":    DebugJs,
 	"eth":      EthJs,
 	"istanbul": Istanbul_JS,
@@ -55,6 +56,10 @@ web3._extend({
 			call: 'admin_removeTrustedPeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'nodeHealth',
+			call: 'admin_nodeHealth'
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -66,6 +71,18 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'pinState',
+			call: 'admin_pinState',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'unpinState',
+			call: 'admin_unpinState',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -121,6 +138,100 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'pinnedStates',
+			getter: 'admin_pinnedStates'
+		}),
+		new web3._extend.Property({
+			name: 'clockStatus',
+			getter: 'admin_clockStatus'
+		}),
+		new web3._extend.Property({
+			name: 'headLagStatus',
+			getter: 'admin_headLagStatus'
+		}),
+		new web3._extend.Property({
+			name: 'peerDiversity',
+			getter: 'admin_peerDiversity'
+		}),
+	]
+});
+`
+
+const CeloJs = `
+web3._extend({
+	property: 'celo',
+	methods: [
+		new web3._extend.Method({
+			name: 'estimateEpochRewards',
+			call: 'celo_estimateEpochRewards',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getFeeCurrencyBaseFees',
+			call: 'celo_getFeeCurrencyBaseFees',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getChainConfig',
+			call: 'celo_getChainConfig',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'gasPriceMinimumForecast',
+			call: 'celo_gasPriceMinimumForecast',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'feeCurrencyWhitelist',
+			call: 'celo_feeCurrencyWhitelist',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getExchangeRates',
+			call: 'celo_getExchangeRates',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'convertToCelo',
+			call: 'celo_convertToCelo',
+			params: 3,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'convertFromCelo',
+			call: 'celo_convertFromCelo',
+			params: 3,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'gasUsageByContract',
+			call: 'celo_gasUsageByContract',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'exchangeRateAt',
+			call: 'celo_exchangeRateAt',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getInternalTransfers',
+			call: 'celo_getInternalTransfers',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'batchGetState',
+			call: 'celo_batchGetState',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 	]
 });
 `
@@ -135,6 +246,12 @@ web3._extend({
 			params: 6,
 			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter, null, null, null, null, null],
 		}),
+		new web3._extend.Method({
+			name: 'forceFork',
+			call: 'debug_forceFork',
+			params: 1,
+			inputFormatter: [null]
+		}),
 		new web3._extend.Method({
 			name: 'printBlock',
 			call: 'debug_printBlock',
@@ -339,6 +456,17 @@ web3._extend({
 			params: 3,
 			inputFormatter: [null, null, null]
 		}),
+		new web3._extend.Method({
+			name: 'replayBlock',
+			call: 'debug_replayBlock',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'traceSchemaVersion',
+			call: 'debug_traceSchemaVersion',
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -355,6 +483,18 @@ web3._extend({
 			call: 'debug_storageRangeAt',
 			params: 5,
 		}),
+		new web3._extend.Method({
+			name: 'iterateAccounts',
+			call: 'debug_iterateAccounts',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter, null, null, null],
+		}),
+		new web3._extend.Method({
+			name: 'iterateStorage',
+			call: 'debug_iterateStorage',
+			params: 5,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter, web3._extend.formatters.inputAddressFormatter, null, null, null],
+		}),
 		new web3._extend.Method({
 			name: 'getModifiedAccountsByNumber',
 			call: 'debug_getModifiedAccountsByNumber',
@@ -398,6 +538,23 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'sendBundle',
+			call: 'eth_sendBundle',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionsByAddress',
+			call: 'eth_getTransactionsByAddress',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'simulateBundle',
+			call: 'eth_simulateBundle',
+			params: 4,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'signTransaction',
 			call: 'eth_signTransaction',
@@ -492,6 +649,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter],
 		}),
+		new web3._extend.Method({
+			name: 'feeCurrencyHistory',
+			call: 'eth_feeCurrencyHistory',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -530,6 +693,64 @@ web3._extend({
 			call: 'miner_setExtra',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setTxFeeRecipientSchedule',
+			call: 'miner_setTxFeeRecipientSchedule',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setFeeCurrencyLimits',
+			call: 'miner_setFeeCurrencyLimits',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getFeeCurrencyLimits',
+			call: 'miner_feeCurrencyLimits',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'setGasLimitOverride',
+			call: 'miner_setGasLimitOverride',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getGasLimitOverride',
+			call: 'miner_gasLimitOverride',
+			params: 0,
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'getBuildStatus',
+			call: 'miner_buildStatus',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'dryRunBlock',
+			call: 'miner_dryRunBlock',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'submitBlock',
+			call: 'miner_submitBlock',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setDenylist',
+			call: 'miner_setDenylist',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getDenylist',
+			call: 'miner_denylist',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'reloadDenylist',
+			call: 'miner_reloadDenylist',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'setGasPrice',
 			call: 'miner_setGasPrice',
@@ -664,6 +885,14 @@ web3._extend({
 				return status;
 			}
 		}),
+		new web3._extend.Property({
+			name: 'contentByCurrency',
+			getter: 'txpool_contentByCurrency'
+		}),
+		new web3._extend.Property({
+			name: 'inspectEligible',
+			getter: 'txpool_inspectEligible'
+		}),
 		new web3._extend.Method({
 			name: 'contentFrom',
 			call: 'txpool_contentFrom',
@@ -702,12 +931,35 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'getBlockSigners',
+			call: 'istanbul_getBlockSigners',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'getLookbackWindow',
 			call: 'istanbul_getLookbackWindow',
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'myUptime',
+			call: 'istanbul_myUptime',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'exportEpochSnapshots',
+			call: 'istanbul_exportEpochSnapshots',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'importEpochSnapshots',
+			call: 'istanbul_importEpochSnapshots',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'addProxy',
 			call: 'istanbul_addProxy',
@@ -771,9 +1023,17 @@ web3._extend({
 			name: 'currentRoundChangeSet',
 			getter: 'istanbul_getCurrentRoundChangeSet',
 		}),
+		new web3._extend.Property({
+			name: 'currentRoundTimeouts',
+			getter: 'istanbul_getCurrentRoundTimeouts',
+		}),
+		new web3._extend.Property({
+			name: 'consensusStatus',
+			getter: 'istanbul_consensusStatus',
+		}),
 		new web3._extend.Property({
 			name: 'proxies',
-			getter: 'istanbul_getProxiesInfo',
+			getter: 'istanbul_proxies',
 		}),
 		new web3._extend.Property({
 			name: 'proxiedValidators',
@@ -26,7 +26,12 @@ import (
 	"github.com/celo-org/celo-blockchain/accounts"
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/consensus"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	istanbulBackend "github.com/celo-org/celo-blockchain/consensus/istanbul/backend"
+	"github.com/celo-org/celo-blockchain/contracts"
 	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/currency"
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/bloombits"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
@@ -37,6 +42,7 @@ import (
 	gp "github.com/celo-org/celo-blockchain/eth/gasprice"
 	"github.com/celo-org/celo-blockchain/ethdb"
 	"github.com/celo-org/celo-blockchain/event"
+	"github.com/celo-org/celo-blockchain/les/downloader"
 	"github.com/celo-org/celo-blockchain/light"
 	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/params"
@@ -256,6 +262,13 @@ func (b *LesApiBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.
 	})
 }
 
+func (b *LesApiBackend) SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.eth.blockchain.SubscribeRemovedLogsEvent(ch)
 }
@@ -268,7 +281,29 @@ func (b *LesApiBackend) ProtocolVersion() int {
 	return b.eth.LesVersion() + 10000
 }
 
+func (b *LesApiBackend) ConversionFunctionsForHeader(ctx context.Context, header *types.Header) (func(*common.Address) *big.Int, types.ToCELOFn, error) {
+	state, _, err := b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(header.Hash(), false))
+	if err != nil {
+		return nil, nil, err
+	}
+	sysCtx := core.NewSysContractCallCtx(b.ChainConfig(), header, state, b.eth.BlockChain())
+	baseFeeFn, toCELO := core.CreateConversionFunctions(sysCtx, b.eth.BlockChain(), header, state.Copy())
+	return baseFeeFn, toCELO, nil
+}
+
+func (b *LesApiBackend) ValidatorsForHeader(ctx context.Context, header *types.Header) ([]common.Address, error) {
+	engine, ok := b.eth.engine.(*istanbulBackend.Backend)
+	if !ok {
+		return nil, errors.New("consensus engine is not istanbul")
+	}
+	return istanbul.MapValidatorsToAddresses(engine.GetValidators(header.Number, header.Hash())), nil
+}
+
 func (b *LesApiBackend) SuggestPrice(ctx context.Context, currencyAddress *common.Address) (*big.Int, error) {
+	if gasPriceMinimum := b.announcedGasPriceMinimum(currencyAddress); gasPriceMinimum != nil {
+		gasPriceWithMultiplier := new(big.Int).Mul(gasPriceMinimum, b.eth.config.RPCGasPriceMultiplier)
+		return gasPriceWithMultiplier.Div(gasPriceWithMultiplier, big.NewInt(100)), nil
+	}
 	vmRunner, err := b.eth.BlockChain().NewEVMRunnerForCurrentBlock()
 	if err != nil {
 		return nil, err
@@ -348,6 +383,9 @@ func (b *LesApiBackend) CurrentGasPriceMinimum(ctx context.Context, currencyAddr
 	if header.BaseFee != nil && currencyAddress == nil {
 		return header.BaseFee, nil
 	}
+	if gasPriceMinimum := b.announcedGasPriceMinimum(currencyAddress); gasPriceMinimum != nil {
+		return gasPriceMinimum, nil
+	}
 	vmRunner, err := b.eth.BlockChain().NewEVMRunnerForCurrentBlock()
 	if err != nil {
 		return nil, err
@@ -355,6 +393,16 @@ func (b *LesApiBackend) CurrentGasPriceMinimum(ctx context.Context, currencyAddr
 	return gp.GetBaseFeeForCurrency(vmRunner, currencyAddress, header.BaseFee)
 }
 
+// announcedGasPriceMinimum returns the gas price minimum of the currency
+// announced by the validators, for the lightest clients which can't run the
+// contracts, or nil if there isn't any.
+func (b *LesApiBackend) announcedGasPriceMinimum(currencyAddress *common.Address) *big.Int {
+	if b.eth.handler.syncMode != downloader.LightestSync {
+		return nil
+	}
+	return b.eth.handler.gasPriceMinimums.gasPriceMinimum(currencyAddress)
+}
+
 func (b *LesApiBackend) GasPriceMinimumForHeader(ctx context.Context, currencyAddress *common.Address, header *types.Header) (*big.Int, error) {
 	if header.BaseFee != nil && currencyAddress == nil {
 		return header.BaseFee, nil
@@ -387,6 +435,20 @@ func (b *LesApiBackend) RealGasPriceMinimumForHeader(ctx context.Context, curren
 	return gp.GetRealBaseFeeForCurrency(vmRunner, currencyAddress, header.BaseFee)
 }
 
+func (b *LesApiBackend) FeeCurrencyWhitelistForHeader(ctx context.Context, header *types.Header) (common.Address, []common.Address, error) {
+	state, _, err := b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(header.Hash(), false))
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	vmRunner := b.eth.BlockChain().NewEVMRunner(header, state)
+	contract, err := contracts.GetRegisteredAddress(vmRunner, config.FeeCurrencyWhitelistRegistryId)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	whitelist, err := currency.CurrencyWhitelist(vmRunner)
+	return contract, whitelist, err
+}
+
 func (b *LesApiBackend) ChainDb() ethdb.Database {
 	return b.eth.chainDb
 }
@@ -419,6 +481,10 @@ func (b *LesApiBackend) RPCEthCompatibility() bool {
 	return b.eth.config.RPCEthCompatibility
 }
 
+func (b *LesApiBackend) RPCReceiptFeeFields() bool {
+	return b.eth.config.RPCReceiptFeeFields
+}
+
 func (b *LesApiBackend) BloomStatus() (uint64, uint64) {
 	if b.eth.bloomIndexer == nil {
 		return 0, 0
@@ -58,7 +58,8 @@ type clientHandler struct {
 	syncStart func(header *types.Header) // Hook called when the syncing is started
 	syncEnd   func(header *types.Header) // Hook called when the syncing is done
 
-	gatewayFeeCache *gatewayFeeCache
+	gatewayFeeCache  *gatewayFeeCache
+	gasPriceMinimums *gasPriceMinimumsCache
 }
 
 type GatewayFeeInformation struct {
@@ -150,6 +151,7 @@ func newClientHandler(syncMode downloader.SyncMode, ulcServers []string, ulcFrac
 	handler.backend.peers.subscribe((*downloaderPeerNotify)(handler))
 
 	handler.gatewayFeeCache = newGatewayFeeCache()
+	handler.gasPriceMinimums = newGasPriceMinimumsCache(backend.blockchain, backend.engine)
 	return handler
 }
 
@@ -420,6 +422,22 @@ func (h *clientHandler) handleMsg(p *serverPeer) error {
 			ReqID:   resp.ReqID,
 			Obj:     resp.Data,
 		}
+	case msg.Code == CeloStateProofsMsg && p.version >= lpv6:
+		p.Log().Trace("Received celo state proofs response")
+		var resp struct {
+			ReqID, BV uint64
+			Data      light.NodeList
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.fcServer.ReceivedReply(resp.ReqID, resp.BV)
+		p.answeredRequest(resp.ReqID)
+		deliverMsg = &Msg{
+			MsgType: MsgCeloStateProofs,
+			ReqID:   resp.ReqID,
+			Obj:     resp.Data,
+		}
 	case msg.Code == HelperTrieProofsMsg:
 		p.Log().Trace("Received helper trie proof response")
 		var resp struct {
@@ -492,6 +510,18 @@ func (h *clientHandler) handleMsg(p *serverPeer) error {
 		p.fcServer.ReceivedReply(resp.ReqID, resp.BV)
 		h.gatewayFeeCache.update(p.id, &resp.Data)
 
+	case msg.Code == GasPriceMinimumsMsg && p.version >= lpv6:
+		p.Log().Trace("Received gas price minimums")
+		var announcement istanbul.GasPriceMinimumAnnouncement
+		if err := msg.Decode(&announcement); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		// The server can't tell the epoch of the client, so the announcements of
+		// other epochs are dropped without penalty
+		if err := h.gasPriceMinimums.update(&announcement); err != nil {
+			p.Log().Debug("Dropped gas price minimums", "number", announcement.Number, "from", announcement.Address(), "err", err)
+		}
+
 	default:
 		p.Log().Trace("Received invalid message", "code", msg.Code)
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
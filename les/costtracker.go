@@ -45,6 +45,7 @@ var (
 		SendTxV2Msg:            {0, 450000},
 		GetTxStatusMsg:         {0, 250000},
 		GetEtherbaseMsg:        {10000, 1},
+		GetCeloStateProofsMsg:  {0, 1000000},
 	}
 	// maximum incoming message size estimates
 	reqMaxInSize = requestCostTable{
@@ -57,6 +58,7 @@ var (
 		SendTxV2Msg:            {0, 16500},
 		GetTxStatusMsg:         {0, 50},
 		GetEtherbaseMsg:        {0, 10},
+		GetCeloStateProofsMsg:  {0, 80},
 	}
 	// maximum outgoing message size estimates
 	reqMaxOutSize = requestCostTable{
@@ -69,6 +71,7 @@ var (
 		SendTxV2Msg:            {0, 100},
 		GetTxStatusMsg:         {0, 100},
 		GetEtherbaseMsg:        {0, 100},
+		GetCeloStateProofsMsg:  {0, 20000},
 	}
 	// request amounts that have to fit into the minimum buffer size minBufferMultiplier times
 	minBufferReqAmount = map[uint64]uint64{
@@ -81,6 +84,7 @@ var (
 		SendTxV2Msg:            8,
 		GetTxStatusMsg:         64,
 		GetEtherbaseMsg:        1,
+		GetCeloStateProofsMsg:  1,
 	}
 	minBufferMultiplier = 3
 )
@@ -0,0 +1,123 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/event"
+)
+
+var (
+	errGasPriceMinimumsEpoch        = errors.New("gas price minimums of another epoch")
+	errGasPriceMinimumsFuture       = errors.New("gas price minimums of a future block")
+	errUnauthorizedGasPriceMinimums = errors.New("gas price minimums not signed by an elected validator")
+)
+
+// gasPriceMinimumsSource is implemented by the consensus engines verifying the
+// gas price minimums announced by the validators, which the servers push to the
+// les/6 clients.
+type gasPriceMinimumsSource interface {
+	SubscribeGasPriceMinimums(ch chan<- istanbul.GasPriceMinimumsEvent) event.Subscription
+}
+
+// gasPriceMinimumsChain is the part of the light chain the gas price minimum
+// announcements are verified against.
+type gasPriceMinimumsChain interface {
+	CurrentHeader() *types.Header
+	GetHeader(hash common.Hash, number uint64) *types.Header
+}
+
+// gasPriceMinimumsCache keeps the latest gas price minimum announcement pushed
+// by the servers, for the lightest clients which can't run the contracts. The
+// announcements are signed by the proposer of their block, who must be elected
+// in the epoch of the head of the client.
+type gasPriceMinimumsCache struct {
+	chain  gasPriceMinimumsChain
+	engine consensus.Engine
+
+	lock   sync.RWMutex
+	latest *istanbul.GasPriceMinimumAnnouncement
+}
+
+func newGasPriceMinimumsCache(chain gasPriceMinimumsChain, engine consensus.Engine) *gasPriceMinimumsCache {
+	return &gasPriceMinimumsCache{chain: chain, engine: engine}
+}
+
+// update verifies the announcement and keeps it if it's newer than the latest one.
+func (c *gasPriceMinimumsCache) update(announcement *istanbul.GasPriceMinimumAnnouncement) error {
+	head := c.chain.CurrentHeader()
+	if !c.sameEpoch(head, announcement) {
+		return errGasPriceMinimumsEpoch
+	}
+	// Only the block after the head can be proposed already, a later announcement
+	// would be kept over the ones of the blocks up to it
+	if announcement.Number > head.Number.Uint64()+1 {
+		return errGasPriceMinimumsFuture
+	}
+	// The validators elected for the block after the head are the ones of the
+	// whole epoch, any of them can be the proposer of the announced block
+	var elected bool
+	for _, val := range c.engine.GetValidators(head.Number, head.Hash()) {
+		if val.Address() == announcement.Address() {
+			elected = true
+			break
+		}
+	}
+	if !elected {
+		return errUnauthorizedGasPriceMinimums
+	}
+	if header := c.chain.GetHeader(announcement.Hash, announcement.Number); header != nil {
+		if proposer, err := c.engine.Author(header); err != nil || proposer != announcement.Address() {
+			return errUnauthorizedGasPriceMinimums
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.latest == nil || c.latest.Number < announcement.Number {
+		c.latest = announcement
+	}
+	return nil
+}
+
+// gasPriceMinimum returns the latest announced gas price minimum of the
+// currency, nil standing for CELO, or nil if there isn't any in the epoch of the
+// head.
+func (c *gasPriceMinimumsCache) gasPriceMinimum(currency *common.Address) *big.Int {
+	c.lock.RLock()
+	latest := c.latest
+	c.lock.RUnlock()
+
+	if latest == nil || !c.sameEpoch(c.chain.CurrentHeader(), latest) {
+		return nil
+	}
+	return latest.GasPriceMinimum(currency)
+}
+
+// sameEpoch returns whether the announced block is in the epoch of the block
+// after the head.
+func (c *gasPriceMinimumsCache) sameEpoch(head *types.Header, announcement *istanbul.GasPriceMinimumAnnouncement) bool {
+	epochSize := c.engine.EpochSize()
+	return istanbul.GetEpochNumber(announcement.Number, epochSize) == istanbul.GetEpochNumber(head.Number.Uint64()+1, epochSize)
+}
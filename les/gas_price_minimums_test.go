@@ -0,0 +1,127 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/consensus"
+	mockEngine "github.com/celo-org/celo-blockchain/consensus/consensustest"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul/validator"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
+)
+
+// electedEngine is an engine electing the same validators at every block, the
+// proposers of the blocks being the signers of their seals rather than their
+// coinbases.
+type electedEngine struct {
+	consensus.Engine
+	validators []common.Address
+	proposers  map[common.Hash]common.Address
+}
+
+func (e *electedEngine) Author(header *types.Header) (common.Address, error) {
+	return e.proposers[header.Hash()], nil
+}
+
+func (e *electedEngine) GetValidators(blockNumber *big.Int, headerHash common.Hash) []istanbul.Validator {
+	validators := make([]istanbul.Validator, len(e.validators))
+	for i, addr := range e.validators {
+		validators[i] = validator.New(addr, blscrypto.SerializedPublicKey{})
+	}
+	return validators
+}
+
+// headersChain is a chain of the given headers, the last one being the head.
+type headersChain []*types.Header
+
+func (c headersChain) CurrentHeader() *types.Header { return c[len(c)-1] }
+
+func (c headersChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	for _, header := range c {
+		if header.Hash() == hash && header.Number.Uint64() == number {
+			return header
+		}
+	}
+	return nil
+}
+
+func TestGasPriceMinimumsCache(t *testing.T) {
+	var (
+		proposerKey, _ = crypto.GenerateKey()
+		otherKey, _    = crypto.GenerateKey()
+		proposer       = crypto.PubkeyToAddress(proposerKey.PublicKey)
+		cUSD           = common.HexToAddress("0x765de816845861e75a25fca122bb6898b8b1282a")
+		other          = crypto.PubkeyToAddress(otherKey.PublicKey)
+		known          = &types.Header{Number: big.NewInt(110), Coinbase: other}
+		engine         = &electedEngine{Engine: mockEngine.NewFaker(), validators: []common.Address{proposer, other}, proposers: map[common.Hash]common.Address{known.Hash(): proposer}}
+		chain          = headersChain{known, {Number: big.NewInt(120)}}
+		cache          = newGasPriceMinimumsCache(chain, engine)
+	)
+	announce := func(key *ecdsa.PrivateKey, number uint64, hash common.Hash, gasPriceMinimum int64) *istanbul.GasPriceMinimumAnnouncement {
+		announcement, err := istanbul.NewGasPriceMinimumAnnouncement(number, hash, []common.Address{common.ZeroAddress, cUSD}, []*big.Int{big.NewInt(gasPriceMinimum), big.NewInt(2 * gasPriceMinimum)}, func(data []byte) ([]byte, error) {
+			return crypto.Sign(crypto.Keccak256(data), key)
+		})
+		if err != nil {
+			t.Fatalf("failed to sign the announcement: %v", err)
+		}
+		return announcement
+	}
+	if gasPriceMinimum := cache.gasPriceMinimum(nil); gasPriceMinimum != nil {
+		t.Fatalf("gas price minimum without announcement: %v", gasPriceMinimum)
+	}
+
+	unelectedKey, _ := crypto.GenerateKey()
+	tests := []struct {
+		announcement *istanbul.GasPriceMinimumAnnouncement
+		wantErr      error
+	}{
+		// Announcements of unelected validators, of other epochs, of blocks after the next one,
+		// or of known blocks not proposed by their signer, even if their coinbase, are rejected
+		{announce(unelectedKey, 121, common.Hash{}, 1), errUnauthorizedGasPriceMinimums},
+		{announce(proposerKey, 100, common.Hash{}, 1), errGasPriceMinimumsEpoch},
+		{announce(proposerKey, 201, common.Hash{}, 1), errGasPriceMinimumsEpoch},
+		{announce(proposerKey, 122, common.Hash{}, 1), errGasPriceMinimumsFuture},
+		{announce(otherKey, 110, known.Hash(), 1), errUnauthorizedGasPriceMinimums},
+		// The other ones are kept if they're newer
+		{announce(proposerKey, 110, known.Hash(), 5), nil},
+		{announce(otherKey, 121, common.Hash{}, 7), nil},
+		{announce(otherKey, 115, common.Hash{}, 6), nil},
+	}
+	for i, tt := range tests {
+		if err := cache.update(tt.announcement); err != tt.wantErr {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.wantErr)
+		}
+	}
+	if gasPriceMinimum := cache.gasPriceMinimum(nil); gasPriceMinimum == nil || gasPriceMinimum.Int64() != 7 {
+		t.Errorf("CELO gas price minimum mismatch: have %v, want 7", gasPriceMinimum)
+	}
+	if gasPriceMinimum := cache.gasPriceMinimum(&cUSD); gasPriceMinimum == nil || gasPriceMinimum.Int64() != 14 {
+		t.Errorf("cUSD gas price minimum mismatch: have %v, want 14", gasPriceMinimum)
+	}
+	// The announcement isn't used anymore once the head is in the next epoch
+	cache.chain = append(chain, &types.Header{Number: big.NewInt(200)})
+	if gasPriceMinimum := cache.gasPriceMinimum(nil); gasPriceMinimum != nil {
+		t.Errorf("gas price minimum of the previous epoch: %v", gasPriceMinimum)
+	}
+}
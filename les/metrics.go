@@ -44,6 +44,8 @@ var (
 	miscInEtherbaseTrafficMeter  = metrics.NewRegisteredMeter("les/misc/in/traffic/etherbase", nil)
 	miscInGatewayFeePacketsMeter = metrics.NewRegisteredMeter("les/misc/in/packets/gatewayFee", nil)
 	miscInGatewayFeeTrafficMeter = metrics.NewRegisteredMeter("les/misc/in/traffic/gatewayFee", nil)
+	miscInCeloStatePacketsMeter  = metrics.NewRegisteredMeter("les/misc/in/packets/celoState", nil)
+	miscInCeloStateTrafficMeter  = metrics.NewRegisteredMeter("les/misc/in/traffic/celoState", nil)
 
 	miscOutPacketsMeter           = metrics.NewRegisteredMeter("les/misc/out/packets/total", nil)
 	miscOutTrafficMeter           = metrics.NewRegisteredMeter("les/misc/out/traffic/total", nil)
@@ -67,6 +69,8 @@ var (
 	miscOutEtherbaseTrafficMeter  = metrics.NewRegisteredMeter("les/misc/out/traffic/etherbase", nil)
 	miscOutGatewayFeePacketsMeter = metrics.NewRegisteredMeter("les/misc/out/packets/gatewayFee", nil)
 	miscOutGatewayFeeTrafficMeter = metrics.NewRegisteredMeter("les/misc/out/traffic/gatewayFee", nil)
+	miscOutCeloStatePacketsMeter  = metrics.NewRegisteredMeter("les/misc/out/packets/celoState", nil)
+	miscOutCeloStateTrafficMeter  = metrics.NewRegisteredMeter("les/misc/out/traffic/celoState", nil)
 
 	miscServingTimeHeaderTimer     = metrics.NewRegisteredTimer("les/misc/serve/header", nil)
 	miscServingTimeBodyTimer       = metrics.NewRegisteredTimer("les/misc/serve/body", nil)
@@ -78,6 +82,7 @@ var (
 	miscServingTimeTxStatusTimer   = metrics.NewRegisteredTimer("les/misc/serve/txStatus", nil)
 	miscServingTimeEtherbaseTimer  = metrics.NewRegisteredTimer("les/misc/serve/etherbase", nil)
 	miscServingTimeGatewayFeeTimer = metrics.NewRegisteredTimer("les/misc/serve/gatewayFee", nil)
+	miscServingTimeCeloStateTimer  = metrics.NewRegisteredTimer("les/misc/serve/celoState", nil)
 
 	connectionTimer       = metrics.NewRegisteredTimer("les/connection/duration", nil)
 	serverConnectionGauge = metrics.NewRegisteredGauge("les/connection/server", nil)
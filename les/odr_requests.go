@@ -23,9 +23,11 @@ import (
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
+	"github.com/celo-org/celo-blockchain/core/state"
 	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/crypto"
 	"github.com/celo-org/celo-blockchain/ethdb"
+	"github.com/celo-org/celo-blockchain/ethdb/memorydb"
 	"github.com/celo-org/celo-blockchain/light"
 	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/rlp"
@@ -42,6 +44,7 @@ var (
 	errCHTHashMismatch         = errors.New("cht hash mismatch")
 	errCHTNumberMismatch       = errors.New("cht number mismatch")
 	errUselessNodes            = errors.New("useless nodes in merkle proof nodeset")
+	errMissingNodes            = errors.New("missing nodes in merkle proof nodeset")
 	errRequestResponseMismatch = errors.New("header and request mismatch")
 )
 
@@ -70,6 +73,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*BloomRequest)(r)
 	case *light.TxStatusRequest:
 		return (*TxStatusRequest)(r)
+	case *light.CeloStateRequest:
+		return (*CeloStateRequest)(r)
 	default:
 		return nil
 	}
@@ -348,6 +353,71 @@ func (r *CodeRequest) Validate(db ethdb.Database, msg *Msg) error {
 	return nil
 }
 
+type CeloStateReq struct {
+	BHash common.Hash
+	Query light.CeloStateQuery
+}
+
+// ODR request type for the results of celo state queries, see LesOdrRequest interface
+type CeloStateRequest light.CeloStateRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *CeloStateRequest) GetCost(peer *serverPeer) uint64 {
+	return peer.getRequestCost(GetCeloStateProofsMsg, len(r.Queries))
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *CeloStateRequest) CanSend(peer *serverPeer) bool {
+	number := r.Header.Number.Uint64()
+	return peer.version >= lpv6 && peer.HasBlock(r.Header.Hash(), &number, true)
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *CeloStateRequest) Request(reqID uint64, peer *serverPeer) error {
+	peer.Log().Debug("Requesting celo state proofs", "number", r.Header.Number, "queries", len(r.Queries))
+	reqs := make([]CeloStateReq, len(r.Queries))
+	for i, query := range r.Queries {
+		reqs[i] = CeloStateReq{BHash: r.Header.Hash(), Query: query}
+	}
+	return peer.requestCeloStateProofs(reqID, reqs)
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *CeloStateRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating celo state proofs", "number", r.Header.Number, "root", r.Header.Root)
+
+	if msg.MsgType != MsgCeloStateProofs {
+		return errInvalidMessageType
+	}
+	proofs := msg.Obj.(light.NodeList)
+	nodeSet := proofs.NodeSet()
+
+	// Make the queries again on the state of the proofs, tracing the nodes they
+	// read. Every query runs on its own copy of the state, as it does on the server.
+	reads := &proofTraceDB{KeyValueStore: memorydb.New(), reads: make(map[string]struct{})}
+	nodeSet.Store(reads)
+	statedb, err := state.New(r.Header.Root, state.NewDatabase(rawdb.NewDatabase(reads)), nil)
+	if err != nil {
+		return fmt.Errorf("celo state proof verification failed: %v", err)
+	}
+	results := make([]light.CeloStateResult, len(r.Queries))
+	for i, query := range r.Queries {
+		results[i] = light.RunCeloStateQuery(r.NewRunner(r.Header, statedb.Copy()), query)
+	}
+	// check if the queries read all the nodes, and only them
+	if reads.missing {
+		return errMissingNodes
+	}
+	if len(reads.reads) != nodeSet.KeyCount() {
+		return errUselessNodes
+	}
+	r.Results, r.Proof = results, nodeSet
+	return nil
+}
+
 const (
 	// helper trie type constants
 	htCanonical = iota // Canonical hash trie
@@ -592,3 +662,26 @@ func (db *readTraceDB) Has(key []byte) (bool, error) {
 	_, err := db.Get(key)
 	return err == nil, nil
 }
+
+// proofTraceDB is a database of merkle proof nodes and codes recording those
+// read from it, and whether missing ones were looked up
+type proofTraceDB struct {
+	ethdb.KeyValueStore
+	reads   map[string]struct{}
+	missing bool
+}
+
+// Get returns a stored node or code, tracing the lookup
+func (db *proofTraceDB) Get(key []byte) ([]byte, error) {
+	value, err := db.KeyValueStore.Get(key)
+	// Nodes and legacy codes are keyed by their hashes, other lookups don't
+	// read the state of the proofs.
+	if len(key) == common.HashLength {
+		if err != nil {
+			db.missing = true
+		} else {
+			db.reads[string(key)] = struct{}{}
+		}
+	}
+	return value, err
+}
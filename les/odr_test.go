@@ -28,6 +28,8 @@ import (
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/common/math"
+	mockEngine "github.com/celo-org/celo-blockchain/consensus/consensustest"
+	"github.com/celo-org/celo-blockchain/contracts/config"
 	"github.com/celo-org/celo-blockchain/contracts/testutil"
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
@@ -194,6 +196,100 @@ func odrTxStatus(ctx context.Context, db ethdb.Database, config *params.ChainCon
 	return rlp
 }
 
+func TestOdrCeloStateLes6(t *testing.T) { testOdr(t, 6, 0, false, odrCeloState) }
+
+func odrCeloState(ctx context.Context, db ethdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte {
+	queries := []light.CeloStateQuery{{Kind: light.GasPriceMinimumKind}, {Kind: light.RandomnessCommitmentKind, Address: bankAddr}}
+
+	var results []light.CeloStateResult
+	if bc != nil {
+		header := bc.GetHeaderByHash(bhash)
+		statedb, err := bc.StateAt(header.Root)
+		if err != nil {
+			return nil
+		}
+		for _, query := range queries {
+			results = append(results, light.RunCeloStateQuery(bc.NewEVMRunner(header, statedb.Copy()), query))
+		}
+	} else {
+		header := lc.GetHeaderByHash(bhash)
+		var err error
+		if results, err = light.GetCeloState(ctx, lc.Odr(), header.Number.Uint64(), lc.NewEVMRunner, queries); err != nil {
+			return nil
+		}
+	}
+	var res []byte
+	for _, result := range results {
+		res = append(res, result.Value...)
+		if result.Err != nil {
+			res = append(res, result.Err.Error()...)
+		}
+	}
+	return res
+}
+
+func TestOdrCeloStateProofs(t *testing.T) {
+	var (
+		randomAddr = common.HexToAddress("0xfa01")
+		validator  = common.HexToAddress("0xfa02")
+		// valueCode returns the storage slot 0, whatever the call
+		valueCode = []byte{0x60, 0x00, 0x54, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+		db        = rawdb.NewMemoryDatabase()
+	)
+	// The registry looks up the value contract for every registry id, whose
+	// queries all return 42.
+	gspec := core.Genesis{Config: params.IstanbulTestChainConfig, Alloc: core.GenesisAlloc{
+		config.RegistrySmartContractAddress: {Code: valueCode, Balance: common.Big0, Storage: map[common.Hash]common.Hash{{}: common.BytesToHash(randomAddr.Bytes())}},
+		randomAddr:                          {Code: valueCode, Balance: common.Big0, Storage: map[common.Hash]common.Hash{{}: common.HexToHash("0x2a")}},
+	}}
+	header := gspec.MustCommit(db).Header()
+	bc, _ := core.NewBlockChain(db, nil, gspec.Config, mockEngine.NewFaker(), vm.Config{}, nil, nil)
+	defer bc.Stop()
+	statedb, _ := bc.StateAt(header.Root)
+
+	queries := []light.CeloStateQuery{
+		{Kind: light.RandomnessCommitmentKind, Address: validator},
+		{Kind: light.GasPriceMinimumKind},
+		{Kind: light.GasPriceMinimumKind, Address: common.HexToAddress("0xfa03")},
+	}
+	nodes := light.NewNodeSet()
+	for _, query := range queries {
+		if err := proveCeloStateQuery(bc, header, statedb, query, nodes); err != nil {
+			t.Fatalf("failed to prove query %v: %v", query, err)
+		}
+	}
+	validate := func(proofs light.NodeList) (*CeloStateRequest, error) {
+		req := &CeloStateRequest{Header: header, Queries: queries, NewRunner: bc.NewEVMRunner}
+		return req, req.Validate(nil, &Msg{MsgType: MsgCeloStateProofs, Obj: proofs})
+	}
+	req, err := validate(nodes.NodeList())
+	if err != nil {
+		t.Fatalf("failed to validate proofs: %v", err)
+	}
+	for i, query := range queries {
+		want := light.RunCeloStateQuery(bc.NewEVMRunner(header, statedb.Copy()), query)
+		if have := req.Results[i]; have.Err != nil || want.Err != nil || !bytes.Equal(have.Value, want.Value) {
+			t.Fatalf("query %v result mismatch: have %x, %v, want %x, %v", query, have.Value, have.Err, want.Value, want.Err)
+		}
+	}
+	var commitment common.Hash
+	if err := rlp.DecodeBytes(req.Results[0].Value, &commitment); err != nil || commitment != common.HexToHash("0x2a") {
+		t.Fatalf("commitment mismatch: have %x, %v", commitment, err)
+	}
+	// Every node of the proofs is needed to make the queries
+	proofs := nodes.NodeList()
+	for i := range proofs {
+		partial := append(append(light.NodeList{}, proofs[:i]...), proofs[i+1:]...)
+		if _, err := validate(partial); err == nil {
+			t.Fatalf("proofs validated without node %d", i)
+		}
+	}
+	// And useless ones are rejected
+	if _, err := validate(append(append(light.NodeList{}, proofs...), valueCode[1:])); err != errUselessNodes {
+		t.Fatalf("proofs with a useless node validated: %v", err)
+	}
+}
+
 // testOdr tests odr requests whose validation guaranteed by block headers.
 func testOdr(t *testing.T, protocol int, expFail uint64, checkCached bool, fn odrTestFn) {
 	// Assemble the test environment
@@ -29,6 +29,7 @@ import (
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/common/mclock"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/forkid"
 	"github.com/celo-org/celo-blockchain/core/types"
@@ -479,6 +480,12 @@ func (p *serverPeer) requestHelperTrieProofs(reqID uint64, reqs []HelperTrieReq)
 	return p.sendRequest(GetHelperTrieProofsMsg, reqID, reqs, len(reqs))
 }
 
+// requestCeloStateProofs fetches a batch of celo state proofs from a remote node.
+func (p *serverPeer) requestCeloStateProofs(reqID uint64, reqs []CeloStateReq) error {
+	p.Log().Debug("Fetching batch of celo state proofs", "count", len(reqs))
+	return p.sendRequest(GetCeloStateProofsMsg, reqID, reqs, len(reqs))
+}
+
 // requestTxStatus fetches a batch of transaction status records from a remote node.
 func (p *serverPeer) requestTxStatus(reqID uint64, txHashes []common.Hash) error {
 	p.Log().Debug("Requesting transaction status", "count", len(txHashes))
@@ -751,7 +758,8 @@ func (p *serverPeer) Handshake(genesis common.Hash, forkid forkid.ID, forkFilter
 
 		if !p.onlyAnnounce {
 			for msgCode := range reqAvgTimeCost {
-				if p.fcCosts[msgCode] == nil {
+				// Messages of later protocol versions are not in the cost table
+				if msgCode < ProtocolLengths[uint(p.version)] && p.fcCosts[msgCode] == nil {
 					return errResp(ErrUselessPeer, "peer does not support message %d", msgCode)
 				}
 			}
@@ -1003,6 +1011,12 @@ func (p *clientPeer) replyHelperTrieProofs(reqID uint64, resp HelperTrieResps) *
 	return &reply{p.rw, HelperTrieProofsMsg, reqID, data}
 }
 
+// replyCeloStateProofs creates a reply with the merkle proofs and codes of the state read by the celo state queries requested.
+func (p *clientPeer) replyCeloStateProofs(reqID uint64, proofs light.NodeList) *reply {
+	data, _ := rlp.EncodeToBytes(proofs)
+	return &reply{p.rw, CeloStateProofsMsg, reqID, data}
+}
+
 // replyTxStatus creates a reply with a batch of transaction status records, corresponding to the ones requested.
 func (p *clientPeer) replyTxStatus(reqID uint64, stats []light.TxStatus) *reply {
 	data, _ := rlp.EncodeToBytes(stats)
@@ -1026,6 +1040,17 @@ func (p *clientPeer) sendAnnounce(request announceData) error {
 	return p2p.Send(p.rw, AnnounceMsg, request)
 }
 
+// sendGasPriceMinimums pushes the gas price minimum announcement of a validator
+// to the client if it's active and supports it.
+func (p *clientPeer) sendGasPriceMinimums(announcement *istanbul.GasPriceMinimumAnnouncement) {
+	if p.version < lpv6 || p.getCapacity() == 0 {
+		return
+	}
+	if !p.queueSend(func() { p2p.Send(p.rw, GasPriceMinimumsMsg, announcement) }) {
+		p.Log().Debug("Dropped gas price minimums because queue is full", "number", announcement.Number)
+	}
+}
+
 // InactiveAllowance implements vfs.clientPeer
 func (p *clientPeer) InactiveAllowance() time.Duration {
 	return 0 // will return more than zero for les/5 clients
@@ -1509,6 +1534,17 @@ func (ps *clientPeerSet) announceOrStore(p *clientPeer) {
 	}
 }
 
+// broadcastGasPriceMinimums pushes the gas price minimum announcement to all
+// active peers supporting it
+func (ps *clientPeerSet) broadcastGasPriceMinimums(announcement *istanbul.GasPriceMinimumAnnouncement) {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	for _, peer := range ps.peers {
+		peer.sendGasPriceMinimums(announcement)
+	}
+}
+
 // allClientPeers returns all client peers in a list.
 func (ps *clientPeerSet) allPeers() []*clientPeer {
 	ps.lock.RLock()
@@ -37,17 +37,18 @@ const (
 	lpv3 = 3
 	lpv4 = 4 // Work in progress. Breaking changes expected.
 	lpv5 = 5 // eth lpv4
+	lpv6 = 6
 )
 
 // Supported versions of the les protocol (first is primary)
 var (
-	ClientProtocolVersions    = []uint{lpv2, lpv3, lpv4, lpv5}
-	ServerProtocolVersions    = []uint{lpv2, lpv3, lpv4, lpv5}
+	ClientProtocolVersions    = []uint{lpv2, lpv3, lpv4, lpv5, lpv6}
+	ServerProtocolVersions    = []uint{lpv2, lpv3, lpv4, lpv5, lpv6}
 	AdvertiseProtocolVersions = []uint{lpv2} // clients are searching for the first advertised protocol in the list
 )
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = map[uint]uint64{lpv2: 24, lpv3: 26, lpv4: 28, lpv5: 28}
+var ProtocolLengths = map[uint]uint64{lpv2: 24, lpv3: 26, lpv4: 28, lpv5: 28, lpv6: 31}
 
 const (
 	NetworkId          = 1
@@ -89,6 +90,10 @@ const (
 	// Protocol messages to be introduced in LPV4
 	GetGatewayFeeMsg = 0x1A
 	GatewayFeeMsg    = 0x1B
+	// Protocol messages introduced in LPV6
+	GetCeloStateProofsMsg = 0x1C
+	CeloStateProofsMsg    = 0x1D
+	GasPriceMinimumsMsg   = 0x1E
 )
 
 // GetBlockHeadersData represents a block header query (the request ID is not included)
@@ -157,6 +162,12 @@ type GetGatewayFeePacket struct {
 	ReqID uint64
 }
 
+// GetCeloStateProofsPacket represents a celo state proof request
+type GetCeloStateProofsPacket struct {
+	ReqID uint64
+	Reqs  []CeloStateReq
+}
+
 type requestInfo struct {
 	name                          string
 	maxCount                      uint64
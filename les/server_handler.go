@@ -26,6 +26,7 @@ import (
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/common/mclock"
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/forkid"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
@@ -54,6 +55,7 @@ const (
 	MaxTxStatus              = 256 // Amount of transactions to queried per request
 	MaxEtherbase             = 1
 	MaxGatewayFee            = 1
+	MaxCeloStateFetch        = 16 // Amount of celo state queries to be proven per retrieval request
 )
 
 var (
@@ -426,6 +428,14 @@ func (h *serverHandler) broadcastLoop() {
 	headSub := h.blockchain.SubscribeChainHeadEvent(headCh)
 	defer headSub.Unsubscribe()
 
+	// Relay the gas price minimums announced by the validators, if the engine verifies them
+	var gasPriceMinimumsCh chan istanbul.GasPriceMinimumsEvent
+	if source, ok := h.blockchain.Engine().(gasPriceMinimumsSource); ok {
+		gasPriceMinimumsCh = make(chan istanbul.GasPriceMinimumsEvent, 10)
+		gasPriceMinimumsSub := source.SubscribeGasPriceMinimums(gasPriceMinimumsCh)
+		defer gasPriceMinimumsSub.Unsubscribe()
+	}
+
 	var (
 		lastHead = h.blockchain.CurrentHeader()
 		lastTd   = common.Big0
@@ -446,6 +456,9 @@ func (h *serverHandler) broadcastLoop() {
 			lastHead, lastTd = header, td
 			log.Debug("Announcing block to peers", "number", number, "hash", hash, "td", td, "reorg", reorg)
 			h.server.peers.broadcast(announceData{Hash: hash, Number: number, Td: td, ReorgDepth: reorg})
+		case ev := <-gasPriceMinimumsCh:
+			log.Trace("Relaying gas price minimums to peers", "number", ev.Announcement.Number, "from", ev.Announcement.Address())
+			h.server.peers.broadcastGasPriceMinimums(ev.Announcement)
 		case <-h.closeCh:
 			return
 		}
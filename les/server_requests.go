@@ -25,6 +25,8 @@ import (
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/state"
 	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/crypto"
 	"github.com/celo-org/celo-blockchain/light"
 	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/metrics"
@@ -169,6 +171,16 @@ var Les3 = map[uint64]RequestType{
 		ServingTimeMeter: miscServingTimeGatewayFeeTimer,
 		Handle:           handleGetGatewayFee,
 	},
+	GetCeloStateProofsMsg: {
+		Name:             "celo state proofs request",
+		MaxCount:         MaxCeloStateFetch,
+		InPacketsMeter:   miscInCeloStatePacketsMeter,
+		InTrafficMeter:   miscInCeloStateTrafficMeter,
+		OutPacketsMeter:  miscOutCeloStatePacketsMeter,
+		OutTrafficMeter:  miscOutCeloStateTrafficMeter,
+		ServingTimeMeter: miscServingTimeCeloStateTimer,
+		Handle:           handleGetCeloStateProofs,
+	},
 }
 
 // handleGetBlockHeaders handles a block header request
@@ -620,3 +632,208 @@ func handleGetGatewayFee(msg Decoder) (serveRequestFn, uint64, uint64, error) {
 		return p.ReplyGatewayFee(r.ReqID, GatewayFeeInformation{GatewayFee: backend.GetGatewayFee(), Etherbase: backend.GetEtherbase()})
 	}, r.ReqID, 1, nil
 }
+
+// handleGetCeloStateProofs handles a celo state proof request
+func handleGetCeloStateProofs(msg Decoder) (serveRequestFn, uint64, uint64, error) {
+	var r GetCeloStateProofsPacket
+	if err := msg.Decode(&r); err != nil {
+		return nil, 0, 0, err
+	}
+	return func(backend serverBackend, p *clientPeer, waitOrStop func() bool) *reply {
+		var (
+			lastBHash common.Hash
+			header    *types.Header
+			statedb   *state.StateDB
+			err       error
+		)
+		bc := backend.BlockChain()
+		nodes := light.NewNodeSet()
+
+		for i, request := range r.Reqs {
+			if i != 0 && !waitOrStop() {
+				return nil
+			}
+			// Look up the state belonging to the request
+			if request.BHash != lastBHash {
+				statedb, lastBHash = nil, request.BHash
+
+				if header = bc.GetHeaderByHash(request.BHash); header == nil {
+					p.Log().Warn("Failed to retrieve header for celo state proof", "hash", request.BHash)
+					p.bumpInvalid()
+					continue
+				}
+				// Refuse to run the queries on stale state, which isn't kept in memory.
+				local := bc.CurrentHeader().Number.Uint64()
+				if !backend.ArchiveMode() && header.Number.Uint64()+core.TriesInMemory <= local {
+					p.Log().Debug("Reject stale celo state request", "number", header.Number.Uint64(), "head", local)
+					p.bumpInvalid()
+					continue
+				}
+				if statedb, err = bc.StateAt(header.Root); err != nil {
+					p.Log().Warn("Failed to open state for celo state proof", "block", header.Number, "hash", header.Hash(), "err", err)
+					statedb = nil
+					continue
+				}
+			}
+			// If a state lookup failed, ignore subsequent requests for the same header
+			if statedb == nil {
+				p.bumpInvalid()
+				continue
+			}
+			if err := proveCeloStateQuery(bc, header, statedb, request.Query, nodes); err != nil {
+				p.Log().Warn("Failed to prove celo state request", "block", header.Number, "hash", header.Hash(), "err", err)
+				continue
+			}
+			if nodes.DataSize() >= softResponseLimit {
+				break
+			}
+		}
+		return p.replyCeloStateProofs(r.ReqID, nodes.NodeList())
+	}, r.ReqID, uint64(len(r.Reqs)), nil
+}
+
+// proveCeloStateQuery makes the query on a copy of the state, recording what it
+// reads, and adds the proofs of the reads from the untouched state to the nodes.
+func proveCeloStateQuery(bc *core.BlockChain, header *types.Header, statedb *state.StateDB, query light.CeloStateQuery, nodes *light.NodeSet) error {
+	recorder := newStateRecorder(statedb.Copy())
+	light.RunCeloStateQuery(bc.NewEVMRunner(header, recorder), query)
+	return recorder.prove(statedb, nodes)
+}
+
+// stateRecorder is a vm.StateDB recording the accounts and storage slots read
+// through it, which clients need the proofs of to make the same queries.
+type stateRecorder struct {
+	vm.StateDB
+	accounts map[common.Address]map[common.Hash]struct{}
+}
+
+func newStateRecorder(state vm.StateDB) *stateRecorder {
+	return &stateRecorder{StateDB: state, accounts: make(map[common.Address]map[common.Hash]struct{})}
+}
+
+func (r *stateRecorder) account(addr common.Address) {
+	if r.accounts[addr] == nil {
+		r.accounts[addr] = make(map[common.Hash]struct{})
+	}
+}
+
+func (r *stateRecorder) slot(addr common.Address, key common.Hash) {
+	r.account(addr)
+	r.accounts[addr][key] = struct{}{}
+}
+
+// prove adds the proofs of the recorded accounts and slots in the state, along
+// with the codes of the accounts, to the node set.
+func (r *stateRecorder) prove(statedb *state.StateDB, nodes *light.NodeSet) error {
+	for addr, slots := range r.accounts {
+		proof, err := statedb.GetProof(addr)
+		if err != nil {
+			return err
+		}
+		for _, node := range proof {
+			nodes.Put(crypto.Keccak256(node), node)
+		}
+		if !statedb.Exist(addr) {
+			// The proof of absence is enough for the slots of missing accounts
+			continue
+		}
+		if code := statedb.GetCode(addr); len(code) > 0 {
+			nodes.Put(crypto.Keccak256(code), code)
+		}
+		for key := range slots {
+			proof, err := statedb.GetStorageProof(addr, key)
+			if err != nil {
+				return err
+			}
+			for _, node := range proof {
+				nodes.Put(crypto.Keccak256(node), node)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *stateRecorder) CreateAccount(addr common.Address) {
+	r.account(addr)
+	r.StateDB.CreateAccount(addr)
+}
+
+func (r *stateRecorder) SubBalance(addr common.Address, amount *big.Int) {
+	r.account(addr)
+	r.StateDB.SubBalance(addr, amount)
+}
+
+func (r *stateRecorder) AddBalance(addr common.Address, amount *big.Int) {
+	r.account(addr)
+	r.StateDB.AddBalance(addr, amount)
+}
+
+func (r *stateRecorder) GetBalance(addr common.Address) *big.Int {
+	r.account(addr)
+	return r.StateDB.GetBalance(addr)
+}
+
+func (r *stateRecorder) GetNonce(addr common.Address) uint64 {
+	r.account(addr)
+	return r.StateDB.GetNonce(addr)
+}
+
+func (r *stateRecorder) SetNonce(addr common.Address, nonce uint64) {
+	r.account(addr)
+	r.StateDB.SetNonce(addr, nonce)
+}
+
+func (r *stateRecorder) GetCodeHash(addr common.Address) common.Hash {
+	r.account(addr)
+	return r.StateDB.GetCodeHash(addr)
+}
+
+func (r *stateRecorder) GetCode(addr common.Address) []byte {
+	r.account(addr)
+	return r.StateDB.GetCode(addr)
+}
+
+func (r *stateRecorder) SetCode(addr common.Address, code []byte) {
+	r.account(addr)
+	r.StateDB.SetCode(addr, code)
+}
+
+func (r *stateRecorder) GetCodeSize(addr common.Address) int {
+	r.account(addr)
+	return r.StateDB.GetCodeSize(addr)
+}
+
+func (r *stateRecorder) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	r.slot(addr, key)
+	return r.StateDB.GetCommittedState(addr, key)
+}
+
+func (r *stateRecorder) GetState(addr common.Address, key common.Hash) common.Hash {
+	r.slot(addr, key)
+	return r.StateDB.GetState(addr, key)
+}
+
+func (r *stateRecorder) SetState(addr common.Address, key, value common.Hash) {
+	r.slot(addr, key)
+	r.StateDB.SetState(addr, key, value)
+}
+
+func (r *stateRecorder) Suicide(addr common.Address) bool {
+	r.account(addr)
+	return r.StateDB.Suicide(addr)
+}
+
+func (r *stateRecorder) HasSuicided(addr common.Address) bool {
+	r.account(addr)
+	return r.StateDB.HasSuicided(addr)
+}
+
+func (r *stateRecorder) Exist(addr common.Address) bool {
+	r.account(addr)
+	return r.StateDB.Exist(addr)
+}
+
+func (r *stateRecorder) Empty(addr common.Address) bool {
+	r.account(addr)
+	return r.StateDB.Empty(addr)
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts/election"
+	gpm "github.com/celo-org/celo-blockchain/contracts/gasprice_minimum"
+	"github.com/celo-org/celo-blockchain/contracts/random"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/rlp"
+)
+
+// CeloStateKind identifies the system contract query of a CeloStateQuery
+type CeloStateKind uint
+
+const (
+	RandomnessCommitmentKind CeloStateKind = iota // Last randomness commitment of the validator at Address
+	ElectedValidatorsKind                         // Validator signers elected at the state of the block
+	GasPriceMinimumKind                           // Gas price minimum of the fee currency at Address, CELO if zero
+)
+
+var errUnknownCeloStateKind = errors.New("unknown celo state query kind")
+
+// CeloStateQuery is a read only query of the Celo system contracts, which light
+// clients retrieve the results of along with the proof of the state they read.
+type CeloStateQuery struct {
+	Kind    CeloStateKind
+	Address common.Address
+}
+
+// CeloStateResult is the outcome of a CeloStateQuery, RLP encoded
+type CeloStateResult struct {
+	Value rlp.RawValue
+	Err   error
+}
+
+// RunCeloStateQuery makes the query with the given runner. Servers make it to
+// record the state it reads, and clients to get its result from the proof of
+// that state.
+func RunCeloStateQuery(vmRunner vm.EVMRunner, query CeloStateQuery) CeloStateResult {
+	var (
+		value interface{}
+		err   error
+	)
+	switch query.Kind {
+	case RandomnessCommitmentKind:
+		value, err = random.GetLastCommitment(vmRunner, query.Address)
+	case ElectedValidatorsKind:
+		value, err = election.GetElectedValidators(vmRunner)
+	case GasPriceMinimumKind:
+		var currency *common.Address
+		if query.Address != (common.Address{}) {
+			currency = &query.Address
+		}
+		value, err = gpm.GetGasPriceMinimum(vmRunner, currency)
+	default:
+		return CeloStateResult{Err: errUnknownCeloStateKind}
+	}
+	if err != nil {
+		return CeloStateResult{Err: err}
+	}
+	enc, err := rlp.EncodeToBytes(value)
+	return CeloStateResult{Value: enc, Err: err}
+}
+
+// GetCeloState retrieves the results of the queries at the state of the
+// canonical block with the given number, whose header is proven by the local
+// CHT. The results hold the errors of the failed queries.
+func GetCeloState(ctx context.Context, odr OdrBackend, number uint64, newRunner func(*types.Header, vm.StateDB) vm.EVMRunner, queries []CeloStateQuery) ([]CeloStateResult, error) {
+	header, err := GetHeaderByNumber(ctx, odr, number)
+	if err != nil {
+		return nil, err
+	}
+	r := &CeloStateRequest{Header: header, Queries: queries, NewRunner: newRunner}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	return r.Results, nil
+}
+
+// GetRandomnessCommitment retrieves the last randomness commitment of the
+// validator at the given block.
+func (lc *LightChain) GetRandomnessCommitment(ctx context.Context, number uint64, validator common.Address) (common.Hash, error) {
+	var commitment common.Hash
+	err := lc.getCeloState(ctx, number, CeloStateQuery{Kind: RandomnessCommitmentKind, Address: validator}, &commitment)
+	return commitment, err
+}
+
+// GetElectedValidators retrieves the validator signers elected at the state of
+// the given block. At the last block of an epoch, they are the validators of the
+// next one.
+func (lc *LightChain) GetElectedValidators(ctx context.Context, number uint64) ([]common.Address, error) {
+	var validators []common.Address
+	err := lc.getCeloState(ctx, number, CeloStateQuery{Kind: ElectedValidatorsKind}, &validators)
+	return validators, err
+}
+
+// GetGasPriceMinimum retrieves the gas price minimum of the fee currency at the
+// given block, that of CELO if the currency is nil.
+func (lc *LightChain) GetGasPriceMinimum(ctx context.Context, number uint64, currency *common.Address) (*big.Int, error) {
+	query := CeloStateQuery{Kind: GasPriceMinimumKind}
+	if currency != nil {
+		query.Address = *currency
+	}
+	gasPriceMinimum := new(big.Int)
+	if err := lc.getCeloState(ctx, number, query, gasPriceMinimum); err != nil {
+		return nil, err
+	}
+	return gasPriceMinimum, nil
+}
+
+func (lc *LightChain) getCeloState(ctx context.Context, number uint64, query CeloStateQuery, result interface{}) error {
+	results, err := GetCeloState(ctx, lc.odr, number, lc.NewEVMRunner, []CeloStateQuery{query})
+	if err != nil {
+		return err
+	}
+	if results[0].Err != nil {
+		return results[0].Err
+	}
+	return rlp.DecodeBytes(results[0].Value, result)
+}
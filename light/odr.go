@@ -25,6 +25,7 @@ import (
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
 	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
 	"github.com/celo-org/celo-blockchain/ethdb"
 )
 
@@ -105,6 +106,21 @@ func (req *CodeRequest) StoreResult(db ethdb.Database) {
 	rawdb.WriteCode(db, req.Hash, req.Data)
 }
 
+// CeloStateRequest is the ODR request type for the results of Celo system
+// contract queries, proven by the state trie nodes and contract codes they read
+type CeloStateRequest struct {
+	Header    *types.Header
+	Queries   []CeloStateQuery
+	NewRunner func(header *types.Header, state vm.StateDB) vm.EVMRunner // Runners the queries are made with
+	Results   []CeloStateResult
+	Proof     *NodeSet
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *CeloStateRequest) StoreResult(db ethdb.Database) {
+	req.Proof.Store(db)
+}
+
 // BlockRequest is the ODR request type for retrieving block bodies
 type BlockRequest struct {
 	Hash   common.Hash
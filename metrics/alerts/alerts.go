@@ -0,0 +1,399 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alerts evaluates alerting rules over the node metrics and notifies
+// operators directly from the node, without an external monitoring stack.
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+// BalanceMetric is the metric name of the rules on the CELO balance of an account.
+const BalanceMetric = "balance"
+
+const (
+	// defaultInterval is the time between two evaluations of the rules.
+	defaultInterval = 30 * time.Second
+
+	// notifyTimeout is the maximum time spent delivering a notification.
+	notifyTimeout = 10 * time.Second
+)
+
+var (
+	errNoRuleName    = errors.New("alert rule without name")
+	errNoRuleMetric  = errors.New("alert rule without metric")
+	errNoRuleAccount = errors.New("balance alert rule without account")
+)
+
+// Config is the set of alerting rules and notifiers of the node.
+type Config struct {
+	Interval  time.Duration `toml:",omitempty"` // Time between two evaluations of the rules
+	Rules     []Rule        `toml:",omitempty"`
+	Notifiers []Notifier    `toml:",omitempty"`
+}
+
+// Rule fires an alert when the value of a metric compares to a threshold for
+// longer than a given duration, and resolves it once it no longer does.
+type Rule struct {
+	Name      string
+	Metric    string         // Registered metric name, or BalanceMetric
+	Field     string         `toml:",omitempty"` // Value of the metric to compare, see fields, defaults by metric type
+	Account   common.Address `toml:",omitempty"` // Account whose balance is compared, for BalanceMetric
+	Op        string         // Comparison with the threshold, one of <, <=, >, >=, == and !=
+	Threshold float64
+	For       time.Duration `toml:",omitempty"` // Time the comparison must hold before firing
+	Notifiers []string      `toml:",omitempty"` // Notifiers to alert, all if empty
+	Message   string        `toml:",omitempty"`
+}
+
+// Alert is a notification of a rule firing or resolving.
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Firing    bool      `json:"firing"`
+	Metric    string    `json:"metric"`
+	Value     float64   `json:"value"`
+	Op        string    `json:"op"`
+	Threshold float64   `json:"threshold"`
+	Message   string    `json:"message,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// String returns a human readable description of the alert.
+func (a *Alert) String() string {
+	state := "RESOLVED"
+	if a.Firing {
+		state = "FIRING"
+	}
+	desc := fmt.Sprintf("[%s] %s: %s = %v (%s %v)", state, a.Rule, a.Metric, a.Value, a.Op, a.Threshold)
+	if a.Message != "" {
+		desc += ": " + a.Message
+	}
+	return desc
+}
+
+var comparisons = map[string]func(a, b float64) bool{
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+// BalanceFunc returns the current balance of an account, in wei.
+type BalanceFunc func(ctx context.Context, account common.Address) (*big.Int, error)
+
+// ruleState is the evaluation state of a rule.
+type ruleState struct {
+	rule      Rule
+	compare   func(a, b float64) bool
+	notifiers []notifier
+
+	since     time.Time // Time the comparison started to hold, zero if it doesn't
+	firing    bool
+	lastCount int64 // Count of the metric at the previous evaluation, for the delta field
+	counted   bool
+}
+
+// Engine periodically evaluates the alerting rules.
+type Engine struct {
+	interval time.Duration
+	registry metrics.Registry
+	balance  BalanceFunc
+	rules    []*ruleState
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates an engine evaluating the rules of the configuration over the
+// metrics of the given registry. The balance function is only needed for the
+// balance rules.
+func New(cfg Config, registry metrics.Registry, balance BalanceFunc) (*Engine, error) {
+	notifiers := make(map[string]notifier, len(cfg.Notifiers))
+	all := make([]notifier, 0, len(cfg.Notifiers))
+	for _, nc := range cfg.Notifiers {
+		n, err := newNotifier(nc)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := notifiers[nc.Name]; ok {
+			return nil, fmt.Errorf("duplicate alert notifier %q", nc.Name)
+		}
+		notifiers[nc.Name] = n
+		all = append(all, n)
+	}
+	e := &Engine{
+		interval: cfg.Interval,
+		registry: registry,
+		balance:  balance,
+		quit:     make(chan struct{}),
+	}
+	if e.interval <= 0 {
+		e.interval = defaultInterval
+	}
+	for _, rule := range cfg.Rules {
+		switch {
+		case rule.Name == "":
+			return nil, errNoRuleName
+		case rule.Metric == "":
+			return nil, fmt.Errorf("%w: %s", errNoRuleMetric, rule.Name)
+		case rule.Metric == BalanceMetric && rule.Account == (common.Address{}):
+			return nil, fmt.Errorf("%w: %s", errNoRuleAccount, rule.Name)
+		}
+		if rule.Field != "" && !validField(rule.Field) {
+			return nil, fmt.Errorf("alert rule %s: unknown field %q", rule.Name, rule.Field)
+		}
+		compare, ok := comparisons[rule.Op]
+		if !ok {
+			return nil, fmt.Errorf("alert rule %s: unknown comparison %q", rule.Name, rule.Op)
+		}
+		state := &ruleState{rule: rule, compare: compare, notifiers: all}
+		if len(rule.Notifiers) > 0 {
+			state.notifiers = make([]notifier, 0, len(rule.Notifiers))
+			for _, name := range rule.Notifiers {
+				n, ok := notifiers[name]
+				if !ok {
+					return nil, fmt.Errorf("alert rule %s: unknown notifier %q", rule.Name, name)
+				}
+				state.notifiers = append(state.notifiers, n)
+			}
+		}
+		e.rules = append(e.rules, state)
+	}
+	return e, nil
+}
+
+// Start implements node.Lifecycle, starting the evaluation loop.
+func (e *Engine) Start() error {
+	if len(e.rules) > 0 && !metrics.Enabled {
+		log.Warn("Alerting rules configured with metrics disabled, metric values will stay zero")
+	}
+	e.wg.Add(1)
+	go e.loop()
+	log.Info("Started alerting rules engine", "rules", len(e.rules), "interval", e.interval)
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the evaluation loop.
+func (e *Engine) Stop() error {
+	close(e.quit)
+	e.wg.Wait()
+	return nil
+}
+
+func (e *Engine) loop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, alert := range e.evaluate(now) {
+				e.notify(alert)
+			}
+		case <-e.quit:
+			return
+		}
+	}
+}
+
+// evaluate checks every rule at the given time, returning the alerts of the
+// rules which started firing or resolved.
+func (e *Engine) evaluate(now time.Time) []*alertDelivery {
+	var alerts []*alertDelivery
+	for _, state := range e.rules {
+		value, err := e.value(state)
+		if err != nil {
+			log.Debug("Failed to evaluate alert rule", "rule", state.rule.Name, "err", err)
+			continue
+		}
+		holds := state.compare(value, state.rule.Threshold)
+		switch {
+		case holds && state.since.IsZero():
+			state.since = now
+		case !holds:
+			state.since = time.Time{}
+		}
+		fire := holds && now.Sub(state.since) >= state.rule.For
+		if fire == state.firing {
+			continue
+		}
+		state.firing = fire
+		alerts = append(alerts, &alertDelivery{
+			alert: &Alert{
+				Rule:      state.rule.Name,
+				Firing:    fire,
+				Metric:    state.rule.Metric,
+				Value:     value,
+				Op:        state.rule.Op,
+				Threshold: state.rule.Threshold,
+				Message:   state.rule.Message,
+				Time:      now,
+			},
+			notifiers: state.notifiers,
+		})
+	}
+	return alerts
+}
+
+// alertDelivery is an alert along with the notifiers to deliver it to.
+type alertDelivery struct {
+	alert     *Alert
+	notifiers []notifier
+}
+
+// notify delivers an alert, logging it whether or not notifiers are configured.
+func (e *Engine) notify(delivery *alertDelivery) {
+	if delivery.alert.Firing {
+		log.Warn("Alert firing", "rule", delivery.alert.Rule, "value", delivery.alert.Value, "threshold", delivery.alert.Threshold)
+	} else {
+		log.Info("Alert resolved", "rule", delivery.alert.Rule, "value", delivery.alert.Value, "threshold", delivery.alert.Threshold)
+	}
+	for _, n := range delivery.notifiers {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		if err := n.notify(ctx, delivery.alert); err != nil {
+			log.Warn("Failed to deliver alert", "rule", delivery.alert.Rule, "notifier", n.name(), "err", err)
+		}
+		cancel()
+	}
+}
+
+// value returns the current value of the metric of a rule.
+func (e *Engine) value(state *ruleState) (float64, error) {
+	if state.rule.Metric == BalanceMetric {
+		if e.balance == nil {
+			return 0, errors.New("balances unavailable")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), e.interval)
+		defer cancel()
+		balance, err := e.balance(ctx, state.rule.Account)
+		if err != nil {
+			return 0, err
+		}
+		celo, _ := new(big.Float).Quo(new(big.Float).SetInt(balance), big.NewFloat(1e18)).Float64()
+		return celo, nil
+	}
+	metric := e.registry.Get(state.rule.Metric)
+	if metric == nil {
+		return 0, fmt.Errorf("unknown metric %s", state.rule.Metric)
+	}
+	value, count, err := metricValue(metric, state.rule.Field)
+	if err != nil {
+		return 0, err
+	}
+	if state.rule.Field == "delta" {
+		delta := int64(0)
+		if state.counted {
+			delta = count - state.lastCount
+		}
+		state.lastCount, state.counted = count, true
+		return float64(delta), nil
+	}
+	return value, nil
+}
+
+// validField reports whether a rule field is known.
+func validField(field string) bool {
+	switch field {
+	case "value", "count", "delta", "rate1", "rate5", "rate15", "mean", "max", "p50", "p95", "p99":
+		return true
+	}
+	return false
+}
+
+// metricValue returns the requested field of a metric along with its count,
+// used by the delta field.
+func metricValue(metric interface{}, field string) (float64, int64, error) {
+	switch m := metric.(type) {
+	case metrics.Gauge:
+		v := m.Snapshot().Value()
+		return float64(v), v, checkField(field, "value")
+	case metrics.GaugeFloat64:
+		return m.Snapshot().Value(), 0, checkField(field, "value")
+	case metrics.Counter:
+		v := m.Snapshot().Count()
+		return float64(v), v, checkField(field, "count", "delta")
+	case metrics.Meter:
+		s := m.Snapshot()
+		switch field {
+		case "", "rate1":
+			return s.Rate1(), s.Count(), nil
+		case "rate5":
+			return s.Rate5(), s.Count(), nil
+		case "rate15":
+			return s.Rate15(), s.Count(), nil
+		case "count", "delta":
+			return float64(s.Count()), s.Count(), nil
+		}
+	case metrics.Histogram:
+		return sampledValue(m.Snapshot(), field)
+	case metrics.Timer:
+		return sampledValue(m.Snapshot(), field)
+	default:
+		return 0, 0, fmt.Errorf("unsupported metric type %T", metric)
+	}
+	return 0, 0, fmt.Errorf("field %q not available on %T", field, metric)
+}
+
+// sampled is the common part of histogram and timer snapshots.
+type sampled interface {
+	Count() int64
+	Mean() float64
+	Max() int64
+	Percentile(float64) float64
+}
+
+func sampledValue(s sampled, field string) (float64, int64, error) {
+	switch field {
+	case "", "mean":
+		return s.Mean(), s.Count(), nil
+	case "max":
+		return float64(s.Max()), s.Count(), nil
+	case "p50":
+		return s.Percentile(0.5), s.Count(), nil
+	case "p95":
+		return s.Percentile(0.95), s.Count(), nil
+	case "p99":
+		return s.Percentile(0.99), s.Count(), nil
+	case "count", "delta":
+		return float64(s.Count()), s.Count(), nil
+	}
+	return 0, 0, fmt.Errorf("field %q not available on sampled metrics", field)
+}
+
+func checkField(field string, allowed ...string) error {
+	if field == "" {
+		return nil
+	}
+	for _, a := range allowed {
+		if field == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("field %q not available", field)
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+func TestRuleFiresAfterDurationAndResolves(t *testing.T) {
+	registry := metrics.NewRegistry()
+	peers := new(metrics.StandardGauge)
+	registry.Register("p2p/peers", peers)
+
+	engine, err := New(Config{Rules: []Rule{{Name: "lowPeers", Metric: "p2p/peers", Op: "<", Threshold: 3, For: time.Minute}}}, registry, nil)
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	start := time.Now()
+	peers.Update(1)
+	if alerts := engine.evaluate(start); len(alerts) != 0 {
+		t.Fatalf("alert fired before its duration: %v", alerts[0].alert)
+	}
+	alerts := engine.evaluate(start.Add(time.Minute))
+	if len(alerts) != 1 || !alerts[0].alert.Firing || alerts[0].alert.Value != 1 {
+		t.Fatalf("alert not fired after its duration")
+	}
+	if alerts := engine.evaluate(start.Add(2 * time.Minute)); len(alerts) != 0 {
+		t.Fatalf("firing alert notified again")
+	}
+	peers.Update(5)
+	alerts = engine.evaluate(start.Add(3 * time.Minute))
+	if len(alerts) != 1 || alerts[0].alert.Firing {
+		t.Fatalf("alert not resolved")
+	}
+}
+
+func TestDeltaAndBalanceRules(t *testing.T) {
+	registry := metrics.NewRegistry()
+	errors := metrics.NewCounterForced()
+	registry.Register("miner/randomness/errors", errors)
+
+	account := common.Address{0x01}
+	balance := func(ctx context.Context, addr common.Address) (*big.Int, error) {
+		return new(big.Int).Mul(big.NewInt(2), big.NewInt(1e18)), nil
+	}
+	engine, err := New(Config{Rules: []Rule{
+		{Name: "randomness", Metric: "miner/randomness/errors", Field: "delta", Op: ">", Threshold: 0},
+		{Name: "lowBalance", Metric: BalanceMetric, Account: account, Op: "<", Threshold: 5},
+	}}, registry, balance)
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	now := time.Now()
+	alerts := engine.evaluate(now)
+	if len(alerts) != 1 || alerts[0].alert.Rule != "lowBalance" || alerts[0].alert.Value != 2 {
+		t.Fatalf("balance alert mismatch: have %d alerts", len(alerts))
+	}
+	errors.Inc(2)
+	if alerts := engine.evaluate(now.Add(time.Second)); len(alerts) != 1 || alerts[0].alert.Rule != "randomness" || alerts[0].alert.Value != 2 {
+		t.Fatalf("delta alert not fired")
+	}
+	if alerts := engine.evaluate(now.Add(2 * time.Second)); len(alerts) != 1 || alerts[0].alert.Firing {
+		t.Fatalf("delta alert not resolved without new errors")
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for i, cfg := range []Config{
+		{Rules: []Rule{{Metric: "p2p/peers", Op: "<"}}},
+		{Rules: []Rule{{Name: "a", Metric: "p2p/peers", Op: "~"}}},
+		{Rules: []Rule{{Name: "a", Metric: BalanceMetric, Op: "<"}}},
+		{Rules: []Rule{{Name: "a", Metric: "p2p/peers", Op: "<", Notifiers: []string{"missing"}}}},
+		{Notifiers: []Notifier{{Name: "a", Type: "telegram"}}},
+	} {
+		if _, err := New(cfg, metrics.NewRegistry(), nil); err == nil {
+			t.Errorf("config %d: invalid config accepted", i)
+		}
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	requests := make(chan map[string]interface{}, 3)
+	paths := make(chan string, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string]interface{})
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid notification body: %v", err)
+		}
+		requests <- body
+		paths <- r.URL.Path
+	}))
+	defer server.Close()
+
+	alert := &Alert{Rule: "lowPeers", Firing: true, Metric: "p2p/peers", Value: 1, Op: "<", Threshold: 3}
+	for _, cfg := range []Notifier{
+		{Name: "hook", Type: "webhook", URL: server.URL + "/hook"},
+		{Name: "pd", Type: "pagerduty", URL: server.URL + "/pd", RoutingKey: "key"},
+		{Name: "tg", Type: "telegram", URL: server.URL, BotToken: "token", ChatID: "42"},
+	} {
+		n, err := newNotifier(cfg)
+		if err != nil {
+			t.Fatalf("failed to create notifier %s: %v", cfg.Name, err)
+		}
+		if err := n.notify(context.Background(), alert); err != nil {
+			t.Fatalf("failed to notify %s: %v", cfg.Name, err)
+		}
+	}
+	if body, path := <-requests, <-paths; path != "/hook" || body["rule"] != "lowPeers" || body["firing"] != true {
+		t.Errorf("webhook notification mismatch: %s %v", path, body)
+	}
+	if body, path := <-requests, <-paths; path != "/pd" || body["event_action"] != "trigger" || body["dedup_key"] != "lowPeers" {
+		t.Errorf("pagerduty notification mismatch: %s %v", path, body)
+	}
+	if body, path := <-requests, <-paths; path != "/bottoken/sendMessage" || body["chat_id"] != "42" || body["text"] != alert.String() {
+		t.Errorf("telegram notification mismatch: %s %v", path, body)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+)
+
+const (
+	pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	telegramAPIURL     = "https://api.telegram.org"
+)
+
+// Notifier configures a destination of the alerts.
+type Notifier struct {
+	Name string
+	Type string // One of webhook, pagerduty and telegram
+	URL  string `toml:",omitempty"` // Endpoint of webhooks, overrides the service API for the others
+
+	RoutingKey string `toml:",omitempty"` // PagerDuty integration key
+	BotToken   string `toml:",omitempty"` // Telegram bot token
+	ChatID     string `toml:",omitempty"` // Telegram chat to post to
+}
+
+// notifier delivers alerts to an external service.
+type notifier interface {
+	name() string
+	notify(ctx context.Context, alert *Alert) error
+}
+
+func newNotifier(cfg Notifier) (notifier, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("alert notifier of type %q without name", cfg.Type)
+	}
+	switch cfg.Type {
+	case "webhook":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("webhook notifier %s without url", cfg.Name)
+		}
+		return &webhookNotifier{cfg}, nil
+	case "pagerduty":
+		if cfg.RoutingKey == "" {
+			return nil, fmt.Errorf("pagerduty notifier %s without routing key", cfg.Name)
+		}
+		if cfg.URL == "" {
+			cfg.URL = pagerDutyEventsURL
+		}
+		return &pagerDutyNotifier{cfg}, nil
+	case "telegram":
+		if cfg.BotToken == "" || cfg.ChatID == "" {
+			return nil, fmt.Errorf("telegram notifier %s without bot token or chat id", cfg.Name)
+		}
+		if cfg.URL == "" {
+			cfg.URL = telegramAPIURL
+		}
+		return &telegramNotifier{cfg}, nil
+	}
+	return nil, fmt.Errorf("alert notifier %s of unknown type %q", cfg.Name, cfg.Type)
+}
+
+// webhookNotifier posts the alerts as JSON.
+type webhookNotifier struct{ cfg Notifier }
+
+func (n *webhookNotifier) name() string { return n.cfg.Name }
+
+func (n *webhookNotifier) notify(ctx context.Context, alert *Alert) error {
+	return PostJSON(ctx, n.cfg.URL, alert)
+}
+
+// pagerDutyNotifier triggers and resolves PagerDuty incidents, deduplicated by rule.
+type pagerDutyNotifier struct{ cfg Notifier }
+
+func (n *pagerDutyNotifier) name() string { return n.cfg.Name }
+
+func (n *pagerDutyNotifier) notify(ctx context.Context, alert *Alert) error {
+	action := "resolve"
+	if alert.Firing {
+		action = "trigger"
+	}
+	event := map[string]interface{}{
+		"routing_key":  n.cfg.RoutingKey,
+		"event_action": action,
+		"dedup_key":    alert.Rule,
+		"payload": map[string]interface{}{
+			"summary":  alert.String(),
+			"source":   "celo-blockchain",
+			"severity": "error",
+		},
+	}
+	return PostJSON(ctx, n.cfg.URL, event)
+}
+
+// telegramNotifier posts the alerts to a Telegram chat.
+type telegramNotifier struct{ cfg Notifier }
+
+func (n *telegramNotifier) name() string { return n.cfg.Name }
+
+func (n *telegramNotifier) notify(ctx context.Context, alert *Alert) error {
+	message := map[string]string{
+		"chat_id": n.cfg.ChatID,
+		"text":    alert.String(),
+	}
+	return PostJSON(ctx, fmt.Sprintf("%s/bot%s/sendMessage", n.cfg.URL, n.cfg.BotToken), message)
+}
+
+// PostJSON posts body as JSON to url, failing unless the response has a 2xx status.
+func PostJSON(ctx context.Context, url string, body interface{}) error {
+	blob, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Don't leak the endpoint in the logs, it may embed credentials
+		if uerr, ok := err.(*neturl.Error); ok {
+			return uerr.Err
+		}
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
@@ -27,16 +27,20 @@ import (
 	"github.com/celo-org/celo-blockchain/consensus"
 	"github.com/celo-org/celo-blockchain/consensus/misc"
 	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
-	"github.com/celo-org/celo-blockchain/contracts/currency"
 	"github.com/celo-org/celo-blockchain/contracts/random"
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
 	"github.com/celo-org/celo-blockchain/core/state"
 	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
 	"github.com/celo-org/celo-blockchain/params"
 )
 
+// feeCurrencyUnusedGasMeter counts the gas charged to the fee currency pools
+// beyond the gas used, when accounting them by reserved gas.
+var feeCurrencyUnusedGasMeter = metrics.NewRegisteredMeter("miner/feecurrency/unusedgas", nil)
+
 // blockState is the collection of modified state that is used to assemble a block
 type blockState struct {
 	signer types.Signer
@@ -46,6 +50,7 @@ type blockState struct {
 	gasPool      *core.GasPool     // available gas used to pack transactions
 	bytesBlock   *core.BytesBlock  // available bytes used to pack transactions
 	multiGasPool core.MultiGasPool // available gas to pay for with currency
+	multiGasCaps core.MultiGasPool // gas allowance of every currency in the whole block, read only
 	gasLimit     uint64
 	sysCtx       *core.SysContractCallCtx
 
@@ -54,16 +59,32 @@ type blockState struct {
 	receipts       []*types.Receipt
 	randomness     *types.Randomness // The types.Randomness of the last block by mined by this worker.
 	txFeeRecipient common.Address
+
+	fillDeadline time.Time // Time after which no further transactions are applied, zero if unbounded
+	truncated    bool      // Whether transactions were left out because of fillDeadline
+
+	localGasReserve uint64 // Gas left in the block for local transactions only, see Config.LocalGasQuotient
+
+	skipped map[string]uint64 // Number of transactions left out of the block by reason
+
+	lane *laneState // Set on the speculative copies applying a fee currency lane
 }
 
 // prepareBlock intializes a new blockState that is ready to have transaction included to.
 // Note that if blockState is not nil, blockState.close() needs to be called to shut down the state prefetcher.
-func prepareBlock(w *worker) (*blockState, error) {
+// The preparation returns errBlockAborted once ctx is done.
+func prepareBlock(ctx context.Context, w *worker) (*blockState, error) {
+	return prepareBlockWith(ctx, w, w.chain.CurrentBlock(), nil)
+}
+
+// prepareBlockWith is like prepareBlock, on top of parent. If attrs is set, the
+// block is built for an external validator proposing it, with its timestamp, tx
+// fee recipient and randomness instead of the local ones.
+func prepareBlockWith(ctx context.Context, w *worker, parent *types.Block, attrs *PayloadAttributes) (*blockState, error) {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
 	timestamp := time.Now().Unix()
-	parent := w.chain.CurrentBlock()
 
 	if parent.Time() >= uint64(timestamp) {
 		timestamp = int64(parent.Time() + 1)
@@ -77,24 +98,35 @@ func prepareBlock(w *worker) (*blockState, error) {
 		Time:       uint64(timestamp),
 	}
 
-	txFeeRecipient := w.txFeeRecipient
-	if !w.chainConfig.IsDonut(header.Number) && w.txFeeRecipient != w.validator {
-		txFeeRecipient = w.validator
-		log.Warn("TxFeeRecipient and Validator flags set before split etherbase fork is active. Defaulting to the given validator address for the coinbase.")
-	}
+	txFeeRecipient := w.txFeeRecipientAt(header)
 
-	// Only set the coinbase if our consensus engine is running (avoid spurious block rewards)
-	if w.isRunning() {
+	if attrs != nil {
+		txFeeRecipient = attrs.FeeRecipient
+		header.Coinbase = txFeeRecipient
+	} else if w.isRunning() {
+		// Only set the coinbase if our consensus engine is running (avoid spurious block rewards)
 		if txFeeRecipient == (common.Address{}) {
 			return nil, errors.New("Refusing to mine without etherbase")
 		}
 		header.Coinbase = txFeeRecipient
 	}
 	// Note: The parent seal will not be set when not validating
-	if err := w.engine.Prepare(w.chain, header); err != nil {
+	// Prepare waits for the block time and the late parent commits, while the
+	// worker fields can be updated.
+	w.mu.RUnlock()
+	err := prepareHeader(ctx, w.engine, w.chain, header)
+	w.mu.RLock()
+	if ctx.Err() != nil {
+		return nil, errBlockAborted
+	}
+	if err != nil {
 		log.Error("Failed to prepare header for mining", "err", err)
 		return nil, fmt.Errorf("Failed to prepare header for mining: %w", err)
 	}
+	if attrs != nil {
+		// Prepare sets the local timestamp
+		header.Time = uint64(attrs.Timestamp)
+	}
 
 	// Initialize the block state itself
 	state, err := w.chain.StateAt(parent.Root())
@@ -102,17 +134,35 @@ func prepareBlock(w *worker) (*blockState, error) {
 		return nil, fmt.Errorf("Failed to get the parent state: %w:", err)
 	}
 	state.StartPrefetcher("miner")
-
-	vmRunner := w.chain.NewEVMRunner(header, state)
+	w.chain.SysContractPrefetcher().Prefetch(state)
+
+	vmRunner := w.runnerFactory.NewEVMRunner(header, state)
+	// The blocks built while not validating are never sealed, unless built for an
+	// external validator: the gas limit of the others can be capped without
+	// fetching the one set on chain.
+	override := w.gasLimitOverride
+	if w.isRunning() || attrs != nil {
+		override = 0
+	}
+	var gasLimit uint64
+	if governance, err := w.chain.Governance().Params(parent.Header()); err == nil {
+		gasLimit = governance.BlockGasLimit
+	} else if override == 0 {
+		gasLimit = blockchain_parameters.GetBlockGasLimitOrDefault(vmRunner)
+	}
+	if override > 0 && (gasLimit == 0 || override < gasLimit) {
+		gasLimit = override
+	}
 	b := &blockState{
 		signer:         types.LatestSigner(w.chainConfig),
 		state:          state,
 		tcount:         0,
-		gasLimit:       blockchain_parameters.GetBlockGasLimitOrDefault(vmRunner),
+		gasLimit:       gasLimit,
 		header:         header,
 		txFeeRecipient: txFeeRecipient,
 	}
 	b.gasPool = new(core.GasPool).AddGas(b.gasLimit)
+	b.localGasReserve = uint64(float64(b.gasLimit) * w.config.LocalGasQuotient)
 
 	if w.chainConfig.IsGingerbread(header.Number) {
 		header.GasLimit = b.gasLimit
@@ -121,35 +171,64 @@ func prepareBlock(w *worker) (*blockState, error) {
 		header.UncleHash = types.EmptyUncleHash
 		header.MixDigest = types.EmptyMixDigest
 		// Needs the baseFee at the final state of the last block
-		parentVmRunner := w.chain.NewEVMRunner(parent.Header(), state.Copy())
+		parentVmRunner := w.runnerFactory.NewEVMRunner(parent.Header(), state.Copy())
 		header.BaseFee = misc.CalcBaseFee(w.chainConfig, parent.Header(), parentVmRunner)
 	}
 	if w.chainConfig.IsGingerbreadP2(header.Number) {
 		b.bytesBlock = new(core.BytesBlock).SetLimit(params.MaxTxDataPerBlock)
 	}
-	b.sysCtx = core.NewSysContractCallCtx(header, state.Copy(), w.chain)
+	// The system calls are made once for all the recommits on the parent
+	b.sysCtx = w.chain.SysContractCallCtxCache().Get(parent.Hash(), header, state)
+	w.chain.SysContractPrefetcher().Record(b.sysCtx)
 
 	b.multiGasPool = core.NewMultiGasPool(
 		b.gasLimit,
 		b.sysCtx.GetWhitelistedCurrencies(),
-		w.config.FeeCurrencyDefault,
-		w.config.FeeCurrencyLimits,
+		w.feeCurrencyDefault,
+		w.staleRateLimits(vmRunner, header, b.sysCtx.GetWhitelistedCurrencies()),
 	)
+	b.multiGasCaps = b.multiGasPool.Copy()
 
-	// Play our part in generating the random beacon.
-	if w.isRunning() && random.IsRunning(vmRunner) {
-		istanbul, ok := w.engine.(consensus.Istanbul)
-		if !ok {
-			log.Crit("Istanbul consensus engine must be in use for the randomness beacon")
+	if attrs != nil {
+		// The randomness is revealed and committed by the validator proposing the block
+		b.randomness = &types.EmptyRandomness
+		if random.IsRunning(vmRunner) {
+			if err := random.RevealAndCommit(vmRunner, attrs.RevealedRandomness, attrs.CommittedRandomness, attrs.Proposer); err != nil {
+				return b, fmt.Errorf("Failed to reveal and commit the proposer randomness: %w", err)
+			}
+			b.state.IntermediateRoot(true)
+			b.randomness = &types.Randomness{Revealed: attrs.RevealedRandomness, Committed: attrs.CommittedRandomness}
 		}
+		return b, nil
+	}
 
-		lastCommitment, err := random.GetLastCommitment(vmRunner, w.validator)
+	// Play our part in generating the random beacon.
+	var (
+		randomRunning  bool
+		lastCommitment common.Hash
+	)
+	if w.isRunning() {
+		var err error
+		randomRunning, lastCommitment, err = random.GetLastCommitmentIfRunning(vmRunner, w.validator)
 		if err != nil {
+			randomnessErrorMeter.Mark(1)
 			return b, fmt.Errorf("Failed to get last commitment: %w", err)
 		}
+	}
+	if randomRunning {
+		istanbul, ok := w.engine.(consensus.Istanbul)
+		if !ok {
+			log.Crit("Istanbul consensus engine must be in use for the randomness beacon")
+		}
 
 		lastRandomness := common.Hash{}
+		var journaled bool
 		if (lastCommitment != common.Hash{}) {
+			lastRandomness, journaled = w.journaledRandomness(lastCommitment)
+		}
+		// The randomness journaled when the commitment was made is revealed as is
+		if !journaled && (lastCommitment != common.Hash{}) {
+			// Commitments made before the journal existed are found from the cache
 			lastRandomnessParentHash := rawdb.ReadRandomCommitmentCache(w.db, lastCommitment)
 			if (lastRandomnessParentHash == common.Hash{}) {
 				log.Warn("Randomness cache miss while building a block. Attempting to recover.", "number", header.Number.Uint64())
@@ -158,11 +237,13 @@ func prepareBlock(w *worker) (*blockState, error) {
 				err := w.chain.RecoverRandomnessCache(lastCommitment, b.header.ParentHash)
 				if err != nil {
 					log.Error("Error in recovering randomness cache", "error", err, "number", header.Number.Uint64())
+					randomnessErrorMeter.Mark(1)
 					return b, errors.New("failed to recover the randomness cache after miss")
 				}
 				lastRandomnessParentHash = rawdb.ReadRandomCommitmentCache(w.db, lastCommitment)
 				if (lastRandomnessParentHash == common.Hash{}) {
 					// Recover failed to fix the issue. Bail.
+					randomnessErrorMeter.Mark(1)
 					return b, errors.New("failed to get last randomness cache entry and failed to recover")
 				}
 			}
@@ -170,17 +251,25 @@ func prepareBlock(w *worker) (*blockState, error) {
 			var err error
 			lastRandomness, _, err = istanbul.GenerateRandomness(lastRandomnessParentHash)
 			if err != nil {
+				randomnessErrorMeter.Mark(1)
 				return b, fmt.Errorf("Failed to generate last randomness: %w", err)
 			}
 		}
 
-		_, newCommitment, err := istanbul.GenerateRandomness(b.header.ParentHash)
+		newRandomness, newCommitment, err := istanbul.GenerateRandomness(b.header.ParentHash)
 		if err != nil {
+			randomnessErrorMeter.Mark(1)
 			return b, fmt.Errorf("Failed to generate new randomness: %w", err)
 		}
+		// Journal the commitment before it's used, so that it can be revealed even
+		// if the node stops before the block is imported.
+		if rawdb.ReadRandomCommitmentJournal(w.db, newCommitment) == nil {
+			w.journalRandomness(newCommitment, b.header.ParentHash, newRandomness)
+		}
 
 		err = random.RevealAndCommit(vmRunner, lastRandomness, newCommitment, w.validator)
 		if err != nil {
+			randomnessErrorMeter.Mark(1)
 			return b, fmt.Errorf("Failed to reveal and commit randomness: %w", err)
 		}
 		// always true (EIP158)
@@ -196,6 +285,26 @@ func prepareBlock(w *worker) (*blockState, error) {
 
 // selectAndApplyTransactions selects and applies transactions to the in flight block state.
 func (b *blockState) selectAndApplyTransactions(ctx context.Context, w *worker) error {
+	// A block candidate of an external builder takes the place of the local
+	// selection, which only applies if the candidate can't be included
+	if candidate := w.candidates.candidateFor(b.header); candidate != nil {
+		if err := b.commitCandidate(w, candidate); err == nil {
+			return nil
+		}
+		w.candidates.drop(candidate)
+	}
+	// Forced transactions go first, then bundles, before any pool transaction
+	if err := b.commitForcedTxs(ctx, w); err != nil {
+		return err
+	}
+	if bundles := w.bundles.bundlesFor(b.header.Number.Uint64(), b.header.Time); len(bundles) > 0 {
+		err := b.withLocalGasReserved(func() error {
+			return b.commitBundles(ctx, w, bundles)
+		})
+		if err != nil {
+			return err
+		}
+	}
 	// Fill the block with all available pending transactions.
 	pending, err := w.eth.TxPool().Pending(true)
 
@@ -209,6 +318,11 @@ func (b *blockState) selectAndApplyTransactions(ctx context.Context, w *worker)
 	if len(pending) == 0 {
 		return nil
 	}
+	// Bound the time spent applying transactions, a partially filled block is
+	// better than missing the proposal slot when state access is slow.
+	if budget := w.config.RecommitBudget; budget > 0 {
+		b.fillDeadline = time.Now().Add(budget)
+	}
 	// Split the pending transactions into locals and remotes
 	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
 	for _, account := range w.eth.TxPool().Locals() {
@@ -221,14 +335,21 @@ func (b *blockState) selectAndApplyTransactions(ctx context.Context, w *worker)
 	// TODO: Properly inject the basefee & toCELO function here
 	// txComparator := createTxCmp(w.chain, b.header, b.state)
 	if len(localTxs) > 0 {
-		baseFeeFn, toCElOFn := createConversionFunctions(b.sysCtx, w.chain, b.header, b.state)
+		baseFeeFn, toCElOFn := core.CreateConversionFunctions(b.sysCtx, w.runnerFactory, b.header, b.state)
 		txs := types.NewTransactionsByPriceAndNonce(b.signer, localTxs, baseFeeFn, toCElOFn)
-		if err := b.commitTransactions(ctx, w, txs, b.txFeeRecipient); err != nil {
+		if err := b.commitLocalTransactions(ctx, w, txs); err != nil {
 			return fmt.Errorf("Failed to commit local transactions: %w", err)
 		}
 	}
+	// Apply the remote transactions of every fee currency in parallel, leaving
+	// the conflicting ones to the serial pass below
+	if w.config.ParallelLanes && len(remoteTxs) > 0 {
+		if remoteTxs, err = b.commitLanes(ctx, w, remoteTxs); err != nil {
+			return fmt.Errorf("Failed to commit fee currency lanes: %w", err)
+		}
+	}
 	if len(remoteTxs) > 0 {
-		baseFeeFn, toCElOFn := createConversionFunctions(b.sysCtx, w.chain, b.header, b.state)
+		baseFeeFn, toCElOFn := core.CreateConversionFunctions(b.sysCtx, w.runnerFactory, b.header, b.state)
 		txs := types.NewTransactionsByPriceAndNonce(b.signer, remoteTxs, baseFeeFn, toCElOFn)
 		if err := b.commitTransactions(ctx, w, txs, b.txFeeRecipient); err != nil {
 			return fmt.Errorf("Failed to commit remote transactions: %w", err)
@@ -237,6 +358,33 @@ func (b *blockState) selectAndApplyTransactions(ctx context.Context, w *worker)
 	return nil
 }
 
+// commitLocalTransactions commits local transactions, which may use the gas
+// reserved for them.
+func (b *blockState) commitLocalTransactions(ctx context.Context, w *worker, txs *types.TransactionsByPriceAndNonce) error {
+	available := b.gasPool.Gas()
+	err := b.commitTransactions(ctx, w, txs, b.txFeeRecipient)
+	if used := available - b.gasPool.Gas(); used < b.localGasReserve {
+		b.localGasReserve -= used
+	} else {
+		b.localGasReserve = 0
+	}
+	return err
+}
+
+// withLocalGasReserved runs fn with the gas reserved for local transactions
+// withheld from the block, so that the transactions fn applies can't use it.
+func (b *blockState) withLocalGasReserved(fn func() error) error {
+	reserved := b.localGasReserve
+	if available := b.gasPool.Gas(); reserved > available {
+		reserved = available
+	}
+	if err := b.gasPool.SubGas(reserved); err != nil {
+		return err
+	}
+	defer b.gasPool.AddGas(reserved)
+	return fn()
+}
+
 // commitTransactions attempts to commit every transaction in the transactions list until the block is full or there are no more valid transactions.
 func (b *blockState) commitTransactions(ctx context.Context, w *worker, txs *types.TransactionsByPriceAndNonce, txFeeRecipient common.Address) error {
 	var coalescedLogs []*types.Log
@@ -249,6 +397,15 @@ loop:
 		default:
 			// pass
 		}
+		// If the fill budget is exhausted, seal what we have
+		if !b.fillDeadline.IsZero() && time.Now().After(b.fillDeadline) {
+			if !b.truncated {
+				b.truncated = true
+				fillTruncatedMeter.Mark(1)
+				log.Debug("Block fill budget exhausted, sealing partially filled block", "number", b.header.Number, "txs", b.tcount, "budget", w.config.RecommitBudget)
+			}
+			break
+		}
 		// If we don't have enough gas for any further transactions then we're done
 		if b.gasPool.Gas() < params.TxGas {
 			log.Trace("Not enough gas for further transactions", "have", b.gasPool, "want", params.TxGas)
@@ -259,6 +416,18 @@ loop:
 		if tx == nil {
 			break
 		}
+		// Skip the transactions already rejected by a previous pass on this parent
+		if reason, ok := w.rejected.reason(b.header.ParentHash, tx.Hash()); ok {
+			rejectedTxHitMeter.Mark(1)
+			if reason == skipBelowGasPriceMinimum {
+				b.skip(reason)
+				break
+			}
+			from, _ := types.Sender(b.signer, tx)
+			b.dropSender(from, reason)
+			txs.Pop()
+			continue
+		}
 		// Short-circuit if the transaction is using more gas allocated for the
 		// given fee currency.
 		if b.multiGasPool.PoolFor(tx.FeeCurrency()).Gas() < tx.Gas() {
@@ -267,6 +436,10 @@ loop:
 				"currency", tx.FeeCurrency(), "tx hash", tx.Hash(),
 				"gas", b.multiGasPool.PoolFor(tx.FeeCurrency()).Gas(), "txgas", tx.Gas(),
 			)
+			b.skip(skipFeeCurrencyGasLimit)
+			if b.multiGasCaps.PoolFor(tx.FeeCurrency()).Gas() < tx.Gas() {
+				b.reject(w, tx.Hash(), skipFeeCurrencyGasLimit)
+			}
 			txs.Pop()
 			continue
 		}
@@ -276,12 +449,14 @@ loop:
 		// anyway due to the block not having enough gas left.
 		if b.gasPool.Gas() < tx.Gas() {
 			log.Trace("Skipping transaction which requires more gas than is left in the block", "hash", tx.Hash(), "gas", b.gasPool.Gas(), "txgas", tx.Gas())
+			b.skip(skipBlockGasLimit)
 			txs.Pop()
 			continue
 		}
 		// Same short-circuit of the gas above, but for bytes in the block (b.bytesBlock != nil => GingerbreadP2)
 		if b.bytesBlock != nil && b.bytesBlock.BytesLeft() < uint64(tx.Size()) {
 			log.Trace("Skipping transaction which requires more bytes than is left in the block", "hash", tx.Hash(), "bytes", b.bytesBlock.BytesLeft(), "txbytes", uint64(tx.Size()))
+			b.skip(skipBlockBytesLimit)
 			txs.Pop()
 			continue
 		}
@@ -294,43 +469,67 @@ loop:
 		// phase, start ignoring the sender until we do.
 		if tx.Protected() && !w.chainConfig.IsEIP155(b.header.Number) {
 			log.Trace("Ignoring reply protected transaction", "hash", tx.Hash(), "eip155", w.chainConfig.EIP155Block)
-
+			b.dropSender(from, skipReplayProtected)
 			txs.Pop()
 			continue
 		}
 		if tx.GatewaySet() && w.chainConfig.IsGingerbread(b.header.Number) {
 			log.Trace("Ignoring transaction with gateway fee", "hash", tx.Hash(), "gingerbread", w.chainConfig.GingerbreadBlock)
-
+			b.dropSender(from, skipGatewayFee)
+			txs.Pop()
+			continue
+		}
+		// Leave out the denylisted accounts, and the later transactions of their senders
+		if b.denies(w, from, tx) {
+			log.Trace("Ignoring denylisted transaction", "hash", tx.Hash(), "sender", from)
+			denylistedTxMeter.Mark(1)
+			b.dropSender(from, skipDenylisted)
 			txs.Pop()
 			continue
 		}
+		// Reserve the gas limit of the transaction in the pool of its fee currency,
+		// checked above, and refund what isn't charged once it has been executed.
+		currencyPool := b.multiGasPool.PoolFor(tx.FeeCurrency())
+		if err := currencyPool.SubGas(tx.Gas()); err != nil {
+			return err
+		}
 		// Start executing the transaction
 		b.state.Prepare(tx.Hash(), b.tcount)
 
 		availableGas := b.gasPool.Gas()
 		logs, err := b.commitTransaction(w, tx, txFeeRecipient)
 		gasUsed := availableGas - b.gasPool.Gas()
+		if err == nil {
+			currencyPool.AddGas(tx.Gas() - w.feeCurrencyGas(tx, gasUsed))
+		} else {
+			currencyPool.AddGas(tx.Gas())
+		}
 
 		switch {
 		case errors.Is(err, core.ErrGasLimitReached):
 			// Pop the current out-of-gas transaction without shifting in the next from the account
 			log.Trace("Gas limit exceeded for current block", "sender", from)
+			b.skip(skipBlockGasLimit)
 			txs.Pop()
 
 		case errors.Is(err, core.ErrNonceTooLow):
 			// New head notification data race between the transaction pool and miner, shift
 			log.Trace("Skipping transaction with low nonce", "sender", from, "nonce", tx.Nonce())
+			b.skip(skipNonceTooLow)
 			txs.Shift()
 
 		case errors.Is(err, core.ErrNonceTooHigh):
 			// Reorg notification data race between the transaction pool and miner, skip account =
 			log.Trace("Skipping account with hight nonce", "sender", from, "nonce", tx.Nonce())
+			b.skip(skipNonceTooHigh)
 			txs.Pop()
 
 		case errors.Is(err, core.ErrGasPriceDoesNotExceedMinimum):
 			// We are below the GPM, so we can stop (the rest of the transactions will either have
 			// even lower gas price or won't be mineable yet due to their nonce)
 			log.Trace("Skipping remaining transaction below the gas price minimum")
+			b.skip(skipBelowGasPriceMinimum)
+			b.reject(w, tx.Hash(), skipBelowGasPriceMinimum)
 			break loop
 
 		case errors.Is(err, nil):
@@ -344,50 +543,56 @@ loop:
 					return err
 				}
 			}
-
-			err = b.multiGasPool.PoolFor(tx.FeeCurrency()).SubGas(gasUsed)
-			// Should never happen as we check it above
-			if err != nil {
-				log.Warn(
-					"Unexpectedly reached limit for fee currency",
-					"hash", tx.Hash(), "gas", b.multiGasPool.PoolFor(tx.FeeCurrency()).Gas(),
-					"tx gas used", gasUsed,
-				)
-				return err
-			}
-
 			txs.Shift()
 
 		default:
 			// Strange error, discard the transaction and get the next in line (note, the
 			// nonce-too-high clause will prevent us from executing in vain).
 			log.Debug("Transaction failed, account skipped", "hash", tx.Hash(), "err", err)
+			b.skip(skipInvalid)
 			txs.Shift()
 		}
+		b.updateStatus(w)
 	}
+	b.updateStatus(w)
 
-	if !w.isRunning() && len(coalescedLogs) > 0 {
+	if b.lane == nil && !w.isRunning() && len(coalescedLogs) > 0 {
 		// We don't push the pendingLogsEvent while we are mining. The reason is that
 		// when we are mining, the worker will regenerate a mining block every 3 seconds.
 		// In order to avoid pushing the repeated pendingLog, we disable the pending log pushing.
-
-		// make a copy, the state caches the logs and these logs get "upgraded" from pending to mined
-		// logs by filling in the block hash when the block was mined by the local miner. This can
-		// cause a race condition if a log was "upgraded" before the PendingLogsEvent is processed.
-		cpy := make([]*types.Log, len(coalescedLogs))
-		for i, l := range coalescedLogs {
-			cpy[i] = new(types.Log)
-			*cpy[i] = *l
-		}
-		w.pendingLogsFeed.Send(cpy)
+		w.pendingLogsFeed.Send(copyLogs(coalescedLogs))
 	}
 	return nil
 }
 
+// copyLogs makes a copy of the logs to publish. The state caches the logs and these logs get
+// "upgraded" from pending to mined logs by filling in the block hash when the block was mined
+// by the local miner. This can cause a race condition if a log was "upgraded" before the
+// PendingLogsEvent is processed.
+func copyLogs(logs []*types.Log) []*types.Log {
+	cpy := make([]*types.Log, len(logs))
+	for i, l := range logs {
+		cpy[i] = new(types.Log)
+		*cpy[i] = *l
+	}
+	return cpy
+}
+
+// feeCurrencyGas returns the gas charged to the fee currency pool of an included
+// transaction which used gasUsed: the gas used, or its gas limit if the pools are
+// accounted by reserved gas.
+func (w *worker) feeCurrencyGas(tx *types.Transaction, gasUsed uint64) uint64 {
+	if !w.config.FeeCurrencyReservedGas || gasUsed >= tx.Gas() {
+		return gasUsed
+	}
+	feeCurrencyUnusedGasMeter.Mark(int64(tx.Gas() - gasUsed))
+	return tx.Gas()
+}
+
 // commitTransaction attempts to appply a single transaction. If the transaction fails, it's modifications are reverted.
 func (b *blockState) commitTransaction(w *worker, tx *types.Transaction, txFeeRecipient common.Address) ([]*types.Log, error) {
 	snap := b.state.Snapshot()
-	vmRunner := w.chain.NewEVMRunner(b.header, b.state)
+	vmRunner := w.runnerFactory.NewEVMRunner(b.header, b.state)
 
 	receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &txFeeRecipient, b.gasPool, b.state, b.header, tx, &b.header.GasUsed, *w.chain.GetVMConfig(), vmRunner, b.sysCtx)
 	if err != nil {
@@ -401,12 +606,19 @@ func (b *blockState) commitTransaction(w *worker, tx *types.Transaction, txFeeRe
 }
 
 // finalizeAndAssemble runs post-transaction state modification and assembles the final block.
-func (b *blockState) finalizeAndAssemble(w *worker) (*types.Block, error) {
+// It returns errBlockAborted if ctx is done before the block is assembled.
+func (b *blockState) finalizeAndAssemble(ctx context.Context, w *worker) (*types.Block, error) {
+	if ctx.Err() != nil {
+		return nil, errBlockAborted
+	}
 	block, err := w.engine.FinalizeAndAssemble(w.chain, b.header, b.state, b.txs, b.receipts, b.randomness)
 	if err != nil {
 		return nil, fmt.Errorf("Error in FinalizeAndAssemble: %w", err)
 	}
 
+	if ctx.Err() != nil {
+		return nil, errBlockAborted
+	}
 	// Set the validator set diff in the new header if we're using Istanbul and it's the last block of the epoch
 	if istanbul, ok := w.engine.(consensus.Istanbul); ok {
 		if err := istanbul.UpdateValSetDiff(w.chain, block.MutableHeader(), b.state); err != nil {
@@ -421,6 +633,15 @@ func (b *blockState) finalizeAndAssemble(w *worker) (*types.Block, error) {
 	return block, nil
 }
 
+// prepareHeader runs the Prepare of the engine, which stops waiting once ctx is done
+// if the engine implements consensus.ContextPreparer.
+func prepareHeader(ctx context.Context, engine consensus.Engine, chain consensus.ChainHeaderReader, header *types.Header) error {
+	if p, ok := engine.(consensus.ContextPreparer); ok {
+		return p.PrepareContext(ctx, chain, header)
+	}
+	return engine.Prepare(chain, header)
+}
+
 // totalFees computes total consumed fees in CELO. Block transactions and receipts have to have the same order.
 func totalFees(block *types.Block, receipts []*types.Receipt, baseFeeFn func(*common.Address) *big.Int, toCELO types.ToCELOFn, espresso bool) *big.Float {
 	feesWei := new(big.Int)
@@ -439,26 +660,44 @@ func totalFees(block *types.Block, receipts []*types.Receipt, baseFeeFn func(*co
 	return new(big.Float).Quo(new(big.Float).SetInt(feesWei), new(big.Float).SetInt(big.NewInt(params.Ether)))
 }
 
-// createConversionFunctions creates a function to convert any currency to Celo and a function to get the gas price minimum for that currency.
-// Both functions internally cache their results.
-func createConversionFunctions(sysCtx *core.SysContractCallCtx, chain *core.BlockChain, header *types.Header, state *state.StateDB) (func(feeCurrency *common.Address) *big.Int, types.ToCELOFn) {
-	vmRunner := chain.NewEVMRunner(header, state)
-	currencyManager := currency.NewManager(vmRunner)
+func (b *blockState) close() {
+	b.state.StopPrefetcher()
+}
 
-	baseFeeFn := func(feeCurrency *common.Address) *big.Int {
-		return sysCtx.GetGasPriceMinimum(feeCurrency)
+// journalRandomness journals a randomness commitment, with the randomness sealed
+// if a key is configured.
+func (w *worker) journalRandomness(commitment, parentHash, randomness common.Hash) {
+	entry := &rawdb.RandomCommitmentJournalEntry{
+		Commitment: commitment,
+		ParentHash: parentHash,
+		Randomness: randomness,
 	}
-	toCeloFn := func(amount *big.Int, feeCurrency *common.Address) (*big.Int, error) {
-		curr, err := currencyManager.GetCurrency(feeCurrency)
-		if err != nil {
-			return nil, fmt.Errorf("toCeloFn: %w", err)
-		}
-		return curr.ToCELO(amount), nil
+	if key := w.config.RandomnessKey; key != nil {
+		entry.Randomness = common.Hash{}
+		entry.SealedRandomness = key.Seal(randomness.Bytes(), commitment.Bytes())
 	}
-
-	return baseFeeFn, toCeloFn
+	rawdb.WriteRandomCommitmentJournal(w.db, entry)
 }
 
-func (b *blockState) close() {
-	b.state.StopPrefetcher()
+// journaledRandomness returns the journaled randomness of a commitment, false if
+// it wasn't journaled or can't be unsealed with the configured key, in which case
+// the randomness has to be generated again from the commitment's parent hash.
+func (w *worker) journaledRandomness(commitment common.Hash) (common.Hash, bool) {
+	entry := rawdb.ReadRandomCommitmentJournal(w.db, commitment)
+	if entry == nil {
+		return common.Hash{}, false
+	}
+	if entry.SealedRandomness == nil {
+		return entry.Randomness, true
+	}
+	if w.config.RandomnessKey == nil {
+		log.Warn("Journaled randomness is sealed, no key configured", "commitment", commitment)
+		return common.Hash{}, false
+	}
+	randomness, err := w.config.RandomnessKey.Open(entry.SealedRandomness, commitment.Bytes())
+	if err != nil {
+		log.Warn("Failed to unseal journaled randomness", "commitment", commitment, "err", err)
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(randomness), true
 }
@@ -0,0 +1,99 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+var (
+	errBlockRelayDisabled = errors.New("block relay disabled")
+	errCandidateStale     = errors.New("block candidate not on top of the current head")
+
+	candidateIncludedMeter = metrics.NewRegisteredMeter("miner/relay/included", nil)
+	candidateRejectedMeter = metrics.NewRegisteredMeter("miner/relay/rejected", nil)
+)
+
+// candidatePool holds the latest block candidate submitted by an external builder
+// for the next block. Only the parent and the transactions of a candidate are
+// used: the worker re-executes them in order in a block of its own, with its own
+// header, and seals that one.
+type candidatePool struct {
+	mu    sync.Mutex
+	block *types.Block
+}
+
+// set replaces the candidate, which must extend the current head.
+func (p *candidatePool) set(block *types.Block, head *types.Header) error {
+	if block.ParentHash() != head.Hash() || block.NumberU64() != head.Number.Uint64()+1 {
+		return errCandidateStale
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.block = block
+	return nil
+}
+
+// candidateFor returns the candidate for the block with the given header, nil if
+// none, dropping the candidate of any other parent.
+func (p *candidatePool) candidateFor(header *types.Header) *types.Block {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.block == nil {
+		return nil
+	}
+	if p.block.ParentHash() != header.ParentHash {
+		if p.block.NumberU64() <= header.Number.Uint64() {
+			p.block = nil
+		}
+		return nil
+	}
+	return p.block
+}
+
+// drop forgets the given candidate, unless it was replaced already.
+func (p *candidatePool) drop(block *types.Block) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.block == block {
+		p.block = nil
+	}
+}
+
+// commitCandidate applies the transactions of a block candidate in its order, all
+// of them or none. They may revert, as they would in the candidate itself.
+func (b *blockState) commitCandidate(w *worker, candidate *types.Block) error {
+	bundle := &Bundle{Txs: candidate.Transactions(), BlockNumber: b.header.Number.Uint64()}
+	for _, tx := range bundle.Txs {
+		bundle.RevertingTxHashes = append(bundle.RevertingTxHashes, tx.Hash())
+	}
+	if err := b.commitBundle(w, bundle); err != nil {
+		log.Warn("Rejected block candidate", "number", b.header.Number, "hash", candidate.Hash(), "err", err)
+		candidateRejectedMeter.Mark(1)
+		return err
+	}
+	log.Debug("Applied block candidate", "number", b.header.Number, "hash", candidate.Hash(), "txs", len(bundle.Txs))
+	candidateIncludedMeter.Mark(1)
+	w.build.update(b)
+	return nil
+}
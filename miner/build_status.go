@@ -0,0 +1,154 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+)
+
+// Reasons for which transactions are left out of a block, see BuildStatus.Skipped.
+const (
+	skipFeeCurrencyGasLimit  = "feeCurrencyGasLimit"
+	skipBlockGasLimit        = "blockGasLimit"
+	skipBlockBytesLimit      = "blockBytesLimit"
+	skipReplayProtected      = "replayProtected"
+	skipGatewayFee           = "gatewayFee"
+	skipNonceTooLow          = "nonceTooLow"
+	skipNonceTooHigh         = "nonceTooHigh"
+	skipBelowGasPriceMinimum = "belowGasPriceMinimum"
+	skipInvalid              = "invalid"
+	skipBundleDropped        = "bundleDropped"
+	skipDenylisted           = "denylisted"
+	skipForcedTxFailed       = "forcedTxFailed"
+)
+
+// BuildStatus is a snapshot of the block being built by the worker, or of the
+// last one built once its construction is over.
+type BuildStatus struct {
+	Number    uint64    `json:"number"`
+	Stage     string    `json:"stage"` // Construction stage the block is in
+	Done      bool      `json:"done"`  // Whether the construction is over
+	StartedAt time.Time `json:"startedAt"`
+
+	GasLimit                uint64                    `json:"gasLimit"`
+	GasRemaining            uint64                    `json:"gasRemaining"`
+	CeloGasRemaining        uint64                    `json:"celoGasRemaining"`        // Gas left for the CELO and unconfigured currencies pool, see Config.FeeCurrencyReservedGas
+	FeeCurrencyGasRemaining map[common.Address]uint64 `json:"feeCurrencyGasRemaining"` // Gas left for every configured fee currency, see Config.FeeCurrencyReservedGas
+	BytesRemaining          *uint64                   `json:"bytesRemaining"`          // Bytes left, nil before Gingerbread P2
+
+	TxCount   int               `json:"txCount"`
+	Truncated bool              `json:"truncated"` // Whether transactions were left out because of the fill budget
+	Skipped   map[string]uint64 `json:"skipped"`   // Number of transactions left out by reason
+}
+
+// buildTracker keeps the status of the latest block started by the worker. The
+// goroutine building a block reports its progress, while the status is read by
+// RPC handlers.
+type buildTracker struct {
+	mu      sync.Mutex
+	gen     uint32      // Generation of the pipeline building the block
+	current *blockState // Block being reported, only updates from it are accepted
+	status  *BuildStatus
+}
+
+// start begins tracking the given block, unless a newer pipeline already started.
+func (t *buildTracker) start(gen uint32, b *blockState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current != nil && gen < t.gen {
+		return
+	}
+	t.gen, t.current = gen, b
+	t.status = &BuildStatus{Number: b.header.Number.Uint64(), Stage: stagePrepare.String(), StartedAt: time.Now()}
+	t.fill(b)
+}
+
+// update refreshes the status from the given block, if it's the tracked one.
+func (t *buildTracker) update(b *blockState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current == b {
+		t.fill(b)
+	}
+}
+
+// setStage records the construction stage the block is in, if it's the tracked one.
+func (t *buildTracker) setStage(b *blockState, stage blockStage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current == b {
+		t.status.Stage = stage.String()
+	}
+}
+
+// finish marks the construction of the block as over, if it's the tracked one.
+func (t *buildTracker) finish(b *blockState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current == b {
+		t.fill(b)
+		t.status.Done = true
+		t.current = nil
+	}
+}
+
+// fill copies the counters of the block into the status. Note that t.mu must be held.
+func (t *buildTracker) fill(b *blockState) {
+	status := t.status
+	status.GasLimit = b.gasLimit
+	status.GasRemaining = b.gasPool.Gas()
+	status.CeloGasRemaining = b.multiGasPool.PoolFor(nil).Gas()
+	status.FeeCurrencyGasRemaining = b.multiGasPool.Remaining()
+	status.BytesRemaining = nil
+	if b.bytesBlock != nil {
+		left := b.bytesBlock.BytesLeft()
+		status.BytesRemaining = &left
+	}
+	status.TxCount = b.tcount
+	status.Truncated = b.truncated
+	status.Skipped = make(map[string]uint64, len(b.skipped))
+	for reason, count := range b.skipped {
+		status.Skipped[reason] = count
+	}
+}
+
+// snapshot returns a copy of the status, nil if no block was built yet.
+func (t *buildTracker) snapshot() *BuildStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status == nil {
+		return nil
+	}
+	status := *t.status
+	return &status
+}
+
+// skip counts a transaction left out of the block for the given reason.
+func (b *blockState) skip(reason string) {
+	if b.skipped == nil {
+		b.skipped = make(map[string]uint64)
+	}
+	b.skipped[reason]++
+}
@@ -0,0 +1,242 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+const (
+	// maxBundleTxs is the maximum number of transactions in a single bundle.
+	maxBundleTxs = 16
+
+	// maxPendingBundles is the maximum number of bundles waiting for their block.
+	maxPendingBundles = 256
+)
+
+var (
+	errBundleEmpty    = errors.New("bundle contains no transactions")
+	errBundleTooLarge = fmt.Errorf("bundle exceeds %d transactions", maxBundleTxs)
+	errBundleStale    = errors.New("bundle targets a past block")
+	errBundleKnown    = errors.New("bundle already known")
+	errBundlePoolFull = errors.New("bundle pool is full")
+	errBundleReverted = errors.New("bundle transaction reverted")
+
+	bundleIncludedMeter = metrics.NewRegisteredMeter("miner/bundles/included", nil)
+	bundleDroppedMeter  = metrics.NewRegisteredMeter("miner/bundles/dropped", nil)
+)
+
+// Bundle is an ordered set of transactions to be included atomically at the top
+// of a given block: either all of them are included in order, or none is.
+type Bundle struct {
+	Txs               types.Transactions
+	BlockNumber       uint64        // Number of the block to include the bundle in
+	MinTimestamp      uint64        // Minimum timestamp of the block, 0 for any
+	MaxTimestamp      uint64        // Maximum timestamp of the block, 0 for any
+	RevertingTxHashes []common.Hash // Transactions allowed to revert without dropping the bundle
+}
+
+// Hash returns the hash identifying the bundle, the hash of its transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txs)*common.HashLength)
+	for _, tx := range b.Txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// canRevert reports whether a failed execution of the given transaction is allowed.
+func (b *Bundle) canRevert(hash common.Hash) bool {
+	for _, allowed := range b.RevertingTxHashes {
+		if allowed == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// bundlePool holds the bundles submitted for the upcoming blocks.
+type bundlePool struct {
+	mu      sync.Mutex
+	bundles []*Bundle
+}
+
+// add queues a bundle for its target block, head being the current chain head.
+func (p *bundlePool) add(bundle *Bundle, head uint64) error {
+	if len(bundle.Txs) == 0 {
+		return errBundleEmpty
+	}
+	if len(bundle.Txs) > maxBundleTxs {
+		return errBundleTooLarge
+	}
+	if bundle.BlockNumber <= head {
+		return errBundleStale
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.prune(head + 1)
+	hash := bundle.Hash()
+	for _, queued := range p.bundles {
+		if queued.BlockNumber == bundle.BlockNumber && queued.Hash() == hash {
+			return errBundleKnown
+		}
+	}
+	if len(p.bundles) >= maxPendingBundles {
+		return errBundlePoolFull
+	}
+	p.bundles = append(p.bundles, bundle)
+	return nil
+}
+
+// bundlesFor returns the bundles to include in the block with the given number
+// and timestamp, in submission order.
+func (p *bundlePool) bundlesFor(number, timestamp uint64) []*Bundle {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.prune(number)
+	var bundles []*Bundle
+	for _, bundle := range p.bundles {
+		if bundle.BlockNumber != number {
+			continue
+		}
+		if (bundle.MinTimestamp != 0 && timestamp < bundle.MinTimestamp) || (bundle.MaxTimestamp != 0 && timestamp > bundle.MaxTimestamp) {
+			continue
+		}
+		bundles = append(bundles, bundle)
+	}
+	return bundles
+}
+
+// prune drops the bundles targeting blocks before number. Note that p.mu must be held.
+func (p *bundlePool) prune(number uint64) {
+	bundles := p.bundles[:0]
+	for _, bundle := range p.bundles {
+		if bundle.BlockNumber >= number {
+			bundles = append(bundles, bundle)
+		}
+	}
+	for i := len(bundles); i < len(p.bundles); i++ {
+		p.bundles[i] = nil
+	}
+	p.bundles = bundles
+}
+
+// commitBundles applies the given bundles to the block, dropping the ones that
+// can't be included as a whole.
+func (b *blockState) commitBundles(ctx context.Context, w *worker, bundles []*Bundle) error {
+	for _, bundle := range bundles {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := b.commitBundle(w, bundle); err != nil {
+			log.Debug("Dropped bundle", "hash", bundle.Hash(), "number", b.header.Number, "err", err)
+			bundleDroppedMeter.Mark(1)
+			b.skip(skipBundleDropped)
+			continue
+		}
+		bundleIncludedMeter.Mark(1)
+	}
+	w.build.update(b)
+	return nil
+}
+
+// commitBundle applies all the transactions of a bundle in order. If any of them
+// can't be included, or reverts without being allowed to, the block is restored
+// to its state before the bundle.
+func (b *blockState) commitBundle(w *worker, bundle *Bundle) error {
+	// The state journal is flushed after every transaction, so a snapshot can't
+	// roll back several of them, keep a copy of the whole state instead.
+	var (
+		state        = b.state.Copy()
+		gasPool      = *b.gasPool
+		multiGasPool = b.multiGasPool.Copy()
+		gasUsed      = b.header.GasUsed
+		tcount       = b.tcount
+		txs          = len(b.txs)
+		bytesBlock   core.BytesBlock
+	)
+	if b.bytesBlock != nil {
+		bytesBlock = *b.bytesBlock
+	}
+	revert := func(err error) error {
+		b.state.StopPrefetcher()
+		b.state = state
+		b.state.StartPrefetcher("miner")
+		*b.gasPool = gasPool
+		b.multiGasPool = multiGasPool
+		b.header.GasUsed = gasUsed
+		b.tcount = tcount
+		b.txs, b.receipts = b.txs[:txs], b.receipts[:txs]
+		if b.bytesBlock != nil {
+			*b.bytesBlock = bytesBlock
+		}
+		return err
+	}
+	for _, tx := range bundle.Txs {
+		if b.multiGasPool.PoolFor(tx.FeeCurrency()).Gas() < tx.Gas() {
+			return revert(fmt.Errorf("%w: fee currency gas limit reached by %s", core.ErrGasLimitReached, tx.Hash().Hex()))
+		}
+		if b.bytesBlock != nil && b.bytesBlock.BytesLeft() < uint64(tx.Size()) {
+			return revert(fmt.Errorf("%w: %s", core.ErrBytesLimitReached, tx.Hash().Hex()))
+		}
+		if tx.Protected() && !w.chainConfig.IsEIP155(b.header.Number) {
+			return revert(fmt.Errorf("replay protected transaction %s before eip155", tx.Hash().Hex()))
+		}
+		if tx.GatewaySet() && w.chainConfig.IsGingerbread(b.header.Number) {
+			return revert(fmt.Errorf("transaction %s with gateway fee after gingerbread", tx.Hash().Hex()))
+		}
+		from, err := types.Sender(b.signer, tx)
+		if err != nil {
+			return revert(fmt.Errorf("transaction %s: %w", tx.Hash().Hex(), err))
+		}
+		if w.denylist.denies(b.state, from, tx) {
+			denylistedTxMeter.Mark(1)
+			return revert(fmt.Errorf("denylisted transaction %s", tx.Hash().Hex()))
+		}
+		b.state.Prepare(tx.Hash(), b.tcount)
+
+		availableGas := b.gasPool.Gas()
+		if _, err := b.commitTransaction(w, tx, b.txFeeRecipient); err != nil {
+			return revert(fmt.Errorf("transaction %s: %w", tx.Hash().Hex(), err))
+		}
+		if receipt := b.receipts[len(b.receipts)-1]; receipt.Status == types.ReceiptStatusFailed && !bundle.canRevert(tx.Hash()) {
+			return revert(fmt.Errorf("%w: %s", errBundleReverted, tx.Hash().Hex()))
+		}
+		b.tcount++
+		if b.bytesBlock != nil {
+			if err := b.bytesBlock.SubBytes(uint64(tx.Size())); err != nil {
+				return revert(err)
+			}
+		}
+		if err := b.multiGasPool.PoolFor(tx.FeeCurrency()).SubGas(w.feeCurrencyGas(tx, availableGas-b.gasPool.Gas())); err != nil {
+			return revert(err)
+		}
+	}
+	return nil
+}
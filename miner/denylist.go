@@ -0,0 +1,143 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/state"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+var (
+	errNoDenylistFile = errors.New("no denylist file configured")
+
+	denylistedTxMeter = metrics.NewRegisteredMeter("miner/denylisted", nil)
+)
+
+// Denylist lists the accounts whose transactions the worker leaves out of the
+// blocks it builds. It only applies to the local proposals: the transactions are
+// still accepted by the pool and in the blocks of other validators.
+type Denylist struct {
+	Addresses  []common.Address `json:"addresses"`  // Senders and recipients to exclude
+	CodeHashes []common.Hash    `json:"codeHashes"` // Code hashes of the recipient contracts to exclude
+}
+
+// LoadDenylist reads a denylist from a JSON file.
+func LoadDenylist(path string) (*Denylist, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	list := new(Denylist)
+	if err := json.Unmarshal(data, list); err != nil {
+		return nil, fmt.Errorf("invalid denylist %s: %w", path, err)
+	}
+	return list, nil
+}
+
+// denylist is the denylist enforced by the worker, replaced from the file or
+// by RPC while blocks are being built.
+type denylist struct {
+	mu         sync.RWMutex
+	addresses  map[common.Address]struct{}
+	codeHashes map[common.Hash]struct{}
+}
+
+// set replaces the enforced denylist.
+func (d *denylist) set(list *Denylist) {
+	addresses := make(map[common.Address]struct{}, len(list.Addresses))
+	for _, address := range list.Addresses {
+		addresses[address] = struct{}{}
+	}
+	codeHashes := make(map[common.Hash]struct{}, len(list.CodeHashes))
+	for _, hash := range list.CodeHashes {
+		codeHashes[hash] = struct{}{}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.addresses, d.codeHashes = addresses, codeHashes
+}
+
+// get returns a copy of the enforced denylist.
+func (d *denylist) get() *Denylist {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	list := &Denylist{Addresses: []common.Address{}, CodeHashes: []common.Hash{}}
+	for address := range d.addresses {
+		list.Addresses = append(list.Addresses, address)
+	}
+	for hash := range d.codeHashes {
+		list.CodeHashes = append(list.CodeHashes, hash)
+	}
+	return list
+}
+
+// copy returns a copy of the enforced denylist, unaffected by later replacements.
+func (d *denylist) copy() *denylist {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return &denylist{addresses: d.addresses, codeHashes: d.codeHashes}
+}
+
+// denies reports whether a transaction from the given sender must be left out,
+// because of its sender, its recipient or the code of its recipient in statedb.
+func (d *denylist) denies(statedb *state.StateDB, from common.Address, tx *types.Transaction) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if len(d.addresses) == 0 && len(d.codeHashes) == 0 {
+		return false
+	}
+	if _, ok := d.addresses[from]; ok {
+		return true
+	}
+	to := tx.To()
+	if to == nil {
+		return false
+	}
+	if _, ok := d.addresses[*to]; ok {
+		return true
+	}
+	if len(d.codeHashes) > 0 {
+		if _, ok := d.codeHashes[statedb.GetCodeHash(*to)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadDenylist replaces the enforced denylist with the content of the
+// configured file.
+func (w *worker) reloadDenylist() error {
+	if w.config.DenylistFile == "" {
+		return errNoDenylistFile
+	}
+	list, err := LoadDenylist(w.config.DenylistFile)
+	if err != nil {
+		return err
+	}
+	w.denylist.set(list)
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/log"
+)
+
+// DryRunBlock is a block assembled on top of the current head like the worker
+// would, but neither sealed nor published.
+type DryRunBlock struct {
+	*PendingBlock
+	Receipts types.Receipts
+
+	TotalFees      *big.Float        // Tips paid to the validator by the block transactions, in CELO
+	GasCostsInCelo []*big.Int        // Gas used times effective gas price of every transaction in CELO, nil if unconvertible
+	Truncated      bool              // Whether transactions were left out because of the fill budget
+	Skipped        map[string]uint64 // Number of transactions left out by reason
+}
+
+// dryRunBlock assembles a block with the pending transactions on top of the
+// current head, without sealing it. The worker doesn't need to be running, so
+// that the fee currency limits and gas price minimums can be checked before
+// mining is enabled.
+func (w *worker) dryRunBlock(ctx context.Context) (*DryRunBlock, error) {
+	b, err := prepareBlock(ctx, w)
+	if b != nil {
+		defer b.close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := b.selectAndApplyTransactions(ctx, w); err != nil {
+		return nil, err
+	}
+	block, err := b.finalizeAndAssemble(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+	baseFeeFn, toCELOFn := core.CreateConversionFunctions(b.sysCtx, w.runnerFactory, b.header, b.state.Copy())
+	espresso := w.chainConfig.IsEspresso(b.header.Number)
+
+	result := &DryRunBlock{
+		PendingBlock:   w.newPendingBlock(block, b),
+		Receipts:       b.receipts,
+		TotalFees:      totalFees(block, b.receipts, baseFeeFn, toCELOFn, espresso),
+		GasCostsInCelo: make([]*big.Int, len(b.txs)),
+		Truncated:      b.truncated,
+		Skipped:        make(map[string]uint64, len(b.skipped)),
+	}
+	for reason, count := range b.skipped {
+		result.Skipped[reason] = count
+	}
+	for i, tx := range b.txs {
+		var baseFee *big.Int
+		if espresso {
+			baseFee = baseFeeFn(tx.FeeCurrency())
+		}
+		price := tx.EffectiveGasTipValue(baseFee)
+		if baseFee != nil {
+			price = new(big.Int).Add(price, baseFee)
+		}
+		cost, err := toCELOFn(new(big.Int).Mul(price, new(big.Int).SetUint64(b.receipts[i].GasUsed)), tx.FeeCurrency())
+		if err != nil {
+			log.Debug("Failed to convert dry run transaction cost", "hash", tx.Hash(), "currency", tx.FeeCurrency(), "err", err)
+			continue
+		}
+		result.GasCostsInCelo[i] = cost
+	}
+	return result, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/contracts"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/currency"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+var (
+	errInvalidFeeCurrencyLimit = errors.New("fee currency limit must be a fraction between 0 and 1")
+
+	staleRateMeter = metrics.NewRegisteredMeter("miner/feecurrency/stalerate", nil)
+)
+
+// setFeeCurrencyLimits replaces the fractions of the block gas limit usable by the
+// transactions paying fees in each currency, they apply from the next block built.
+func (w *worker) setFeeCurrencyLimits(defaultLimit float64, limits map[common.Address]float64) error {
+	if defaultLimit < 0 || defaultLimit > 1 {
+		return fmt.Errorf("%w: default %v", errInvalidFeeCurrencyLimit, defaultLimit)
+	}
+	for currency, limit := range limits {
+		if limit < 0 || limit > 1 {
+			return fmt.Errorf("%w: %v for %s", errInvalidFeeCurrencyLimit, limit, currency.Hex())
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.feeCurrencyDefault, w.feeCurrencyLimits = defaultLimit, copyFeeCurrencyLimits(limits)
+	return nil
+}
+
+// feeCurrencyLimitsConfig returns the fee currency limits currently in use.
+func (w *worker) feeCurrencyLimitsConfig() (float64, map[common.Address]float64) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.feeCurrencyDefault, copyFeeCurrencyLimits(w.feeCurrencyLimits)
+}
+
+// staleRateLimits returns the fee currency limits of a block, zero for the
+// currencies whose exchange rate is older than the configured bound at the time
+// of the block: their transactions might not pay the fees they appear to.
+func (w *worker) staleRateLimits(vmRunner vm.EVMRunner, header *types.Header, currencies []common.Address) map[common.Address]float64 {
+	if w.config.RateStaleness <= 0 {
+		return w.feeCurrencyLimits
+	}
+	if _, err := contracts.GetRegisteredAddress(vmRunner, config.SortedOraclesRegistryId); err != nil {
+		return w.feeCurrencyLimits
+	}
+	var limits map[common.Address]float64
+	timestamps, errs := currency.GetMedianTimestamps(vmRunner, currencies)
+	for i, feeCurrency := range currencies {
+		timestamp := timestamps[i]
+		if errs[i] != nil {
+			log.Debug("Failed to get exchange rate timestamp", "currency", feeCurrency, "err", errs[i])
+		}
+		if !currency.RateStale(timestamp, header.Time, w.config.RateStaleness) {
+			continue
+		}
+		if limits == nil {
+			limits = copyFeeCurrencyLimits(w.feeCurrencyLimits)
+		}
+		limits[feeCurrency] = 0
+		log.Warn("Excluding fee currency with stale exchange rate", "currency", feeCurrency, "reported", timestamp, "number", header.Number)
+		staleRateMeter.Mark(1)
+	}
+	if limits == nil {
+		return w.feeCurrencyLimits
+	}
+	return limits
+}
+
+func copyFeeCurrencyLimits(limits map[common.Address]float64) map[common.Address]float64 {
+	cpy := make(map[common.Address]float64, len(limits))
+	for currency, limit := range limits {
+		cpy[currency] = limit
+	}
+	return cpy
+}
@@ -0,0 +1,202 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/log"
+)
+
+var (
+	errScheduleZeroRecipient = errors.New("tx fee recipient schedule contains the zero address")
+	errScheduleNoPeriod      = errors.New("tx fee recipient schedule rotating between several recipients needs a period")
+	errScheduleBeforeDonut   = errors.New("tx fee recipient must be the validator before the split etherbase (donut) fork")
+	errScheduleWeights       = errors.New("tx fee recipient schedule needs a non zero weight for every recipient")
+)
+
+// TxFeeRecipientSchedule rotates the tx fee recipient of the mined blocks between
+// Recipients, moving to the next one every Period blocks sealed by the validator
+// from block Start. The blocks sealed by the other validators don't move it, so
+// its turns are the same whichever blocks the validator proposes. Before Start the
+// tx fee recipient set with SetTxFeeRecipient is used. Rotating with a period of
+// one block splits the fees evenly between the recipients.
+//
+// Weights gives every recipient a number of consecutive periods in proportion to
+// its share of the fees, e.g. 80 and 20 for four blocks out of five to the first
+// recipient. The fees can't be split within a block, they all go to its coinbase.
+type TxFeeRecipientSchedule struct {
+	Start      uint64
+	Period     uint64
+	Recipients []common.Address
+	Weights    []uint64 // Relative share of every recipient, even shares if empty
+}
+
+// active returns whether the schedule sets the tx fee recipient of the given block.
+func (s *TxFeeRecipientSchedule) active(number uint64) bool {
+	return s != nil && len(s.Recipients) > 0 && number >= s.Start
+}
+
+// recipientAt returns the scheduled tx fee recipient of the block sealed after
+// the given number of blocks sealed by the validator since Start.
+func (s *TxFeeRecipientSchedule) recipientAt(sealed uint64) common.Address {
+	if len(s.Recipients) == 1 {
+		return s.Recipients[0]
+	}
+	period := sealed / s.Period
+	if len(s.Weights) == 0 {
+		return s.Recipients[period%uint64(len(s.Recipients))]
+	}
+	var total uint64
+	for _, weight := range s.Weights {
+		total += weight
+	}
+	slot := period % total
+	for i, weight := range s.Weights {
+		if slot < weight {
+			return s.Recipients[i]
+		}
+		slot -= weight
+	}
+	return s.Recipients[len(s.Recipients)-1] // Unreachable, the slot is below the total
+}
+
+// sealedCounter counts the blocks sealed by a validator from the start of the tx
+// fee recipient schedule up to the last header counted, so that only the blocks
+// after it are looked up for the next block.
+type sealedCounter struct {
+	mu        sync.Mutex
+	validator common.Address
+	start     uint64
+	hash      common.Hash // Last header counted, zero if none
+	number    uint64
+	count     uint64
+}
+
+// sealedBefore returns the number of blocks sealed by the validator from block
+// start up to the parent of the given header. The count is rebuilt from the
+// chain when the validator or start change, or the parent doesn't descend from
+// the last header counted.
+// Note that w.mu must be held, at least for reading.
+func (w *worker) sealedBefore(start uint64, header *types.Header) uint64 {
+	c := &w.sealed
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.validator != w.validator || c.start != start {
+		c.validator, c.start = w.validator, start
+		c.hash, c.number, c.count = common.Hash{}, 0, 0
+	}
+	if header.Number.Uint64() <= start {
+		return 0
+	}
+	var count uint64
+	hash, number := header.ParentHash, header.Number.Uint64()-1
+	for {
+		if c.hash == hash && c.number == number {
+			count += c.count
+			break
+		}
+		parent := w.chain.GetHeader(hash, number)
+		if parent == nil {
+			log.Warn("Missing header counting the sealed blocks of the tx fee recipient schedule", "number", number, "hash", hash)
+			break
+		}
+		if author, err := w.engine.Author(parent); err == nil && author == w.validator {
+			count++
+		}
+		if number == start {
+			break
+		}
+		hash, number = parent.ParentHash, number-1
+	}
+	c.hash, c.number, c.count = header.ParentHash, header.Number.Uint64()-1, count
+	return count
+}
+
+// setTxFeeRecipientSchedule replaces the tx fee recipient schedule, an empty one
+// removes it. Schedules paying anything but the validator before the split
+// etherbase fork are refused.
+func (w *worker) setTxFeeRecipientSchedule(schedule *TxFeeRecipientSchedule) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if schedule == nil || len(schedule.Recipients) == 0 {
+		w.txFeeRecipientSchedule = nil
+		return nil
+	}
+	if len(schedule.Recipients) > 1 && schedule.Period == 0 {
+		return errScheduleNoPeriod
+	}
+	if len(schedule.Weights) > 0 && len(schedule.Weights) != len(schedule.Recipients) {
+		return errScheduleWeights
+	}
+	var divisor uint64
+	for _, weight := range schedule.Weights {
+		if weight == 0 {
+			return errScheduleWeights
+		}
+		divisor = gcd(divisor, weight)
+	}
+	for _, recipient := range schedule.Recipients {
+		if recipient == (common.Address{}) {
+			return errScheduleZeroRecipient
+		}
+		if !w.chainConfig.IsDonut(new(big.Int).SetUint64(schedule.Start)) && recipient != w.validator {
+			return errScheduleBeforeDonut
+		}
+	}
+	w.txFeeRecipientSchedule = &TxFeeRecipientSchedule{
+		Start:      schedule.Start,
+		Period:     schedule.Period,
+		Recipients: append([]common.Address{}, schedule.Recipients...),
+	}
+	// Reduce the weights, so that the recipients alternate as often as possible
+	for _, weight := range schedule.Weights {
+		w.txFeeRecipientSchedule.Weights = append(w.txFeeRecipientSchedule.Weights, weight/divisor)
+	}
+	return nil
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// txFeeRecipientAt returns the address receiving the tx fees of the given block.
+// Note that w.mu must be held.
+func (w *worker) txFeeRecipientAt(header *types.Header) common.Address {
+	txFeeRecipient := w.txFeeRecipient
+	if schedule := w.txFeeRecipientSchedule; schedule.active(header.Number.Uint64()) {
+		var sealed uint64
+		if len(schedule.Recipients) > 1 {
+			sealed = w.sealedBefore(schedule.Start, header)
+		}
+		txFeeRecipient = schedule.recipientAt(sealed)
+	}
+	if !w.chainConfig.IsDonut(header.Number) && txFeeRecipient != w.validator {
+		txFeeRecipient = w.validator
+		log.Warn("TxFeeRecipient and Validator flags set before split etherbase fork is active. Defaulting to the given validator address for the coinbase.")
+	}
+	return txFeeRecipient
+}
@@ -0,0 +1,214 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+// Policies applied when a forced transaction can't be included or reverts.
+const (
+	ForcedTxSkip    = "skip"    // Leave it out of the block, the default
+	ForcedTxKeep    = "keep"    // Keep it in the block if it reverts, paying for its gas
+	ForcedTxDisable = "disable" // Leave it out, and stop forcing it until the node restarts
+)
+
+var (
+	errNoForcedTxSigner = errors.New("no forced transaction signer")
+
+	forcedTxIncludedMeter = metrics.NewRegisteredMeter("miner/forced/included", nil)
+	forcedTxFailedMeter   = metrics.NewRegisteredMeter("miner/forced/failed", nil)
+)
+
+// ForcedTx is the template of a transaction the worker signs and places at the
+// top of every block it builds, such as the heartbeat of an oracle. The nonce is
+// taken from the state of the block, so the sender account should be dedicated
+// to it, and its fee cap from the base fee of its currency.
+type ForcedTx struct {
+	From        common.Address  `json:"from"` // Local account signing the transaction
+	To          *common.Address `json:"to"`
+	Value       *hexutil.Big    `json:"value"`
+	Data        hexutil.Bytes   `json:"data"`
+	Gas         hexutil.Uint64  `json:"gas"`
+	FeeCurrency *common.Address `json:"feeCurrency"` // Currency the fees are paid in, nil for CELO
+	GasTipCap   *hexutil.Big    `json:"gasTipCap"`   // Tip paid above the base fee, none if nil
+	OnFailure   string          `json:"onFailure"`   // Policy on failures, ForcedTxSkip if empty
+}
+
+// ForcedTxSigner signs a forced transaction with the account of its sender.
+type ForcedTxSigner func(from common.Address, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+
+// LoadForcedTxs reads the forced transaction templates from a JSON file.
+func LoadForcedTxs(path string) ([]*ForcedTx, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var forced []*ForcedTx
+	if err := json.Unmarshal(data, &forced); err != nil {
+		return nil, fmt.Errorf("invalid forced transactions %s: %w", path, err)
+	}
+	for i, tx := range forced {
+		if tx.Gas == 0 {
+			return nil, fmt.Errorf("forced transaction %d without gas", i)
+		}
+		switch tx.OnFailure {
+		case "":
+			tx.OnFailure = ForcedTxSkip
+		case ForcedTxSkip, ForcedTxKeep, ForcedTxDisable:
+		default:
+			return nil, fmt.Errorf("forced transaction %d with unknown failure policy %q", i, tx.OnFailure)
+		}
+	}
+	return forced, nil
+}
+
+// forcedTxs holds the forced transaction templates of the worker.
+type forcedTxs struct {
+	mu       sync.Mutex
+	txs      []*ForcedTx
+	disabled map[*ForcedTx]struct{}
+	sign     ForcedTxSigner
+}
+
+// set replaces the templates, enabling all of them.
+func (f *forcedTxs) set(txs []*ForcedTx) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.txs, f.disabled = txs, make(map[*ForcedTx]struct{})
+}
+
+// setSigner sets the function signing the forced transactions.
+func (f *forcedTxs) setSigner(sign ForcedTxSigner) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sign = sign
+}
+
+// active returns the enabled templates and the signer.
+func (f *forcedTxs) active() ([]*ForcedTx, ForcedTxSigner) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var txs []*ForcedTx
+	for _, tx := range f.txs {
+		if _, ok := f.disabled[tx]; !ok {
+			txs = append(txs, tx)
+		}
+	}
+	return txs, f.sign
+}
+
+// disable stops forcing the given template.
+func (f *forcedTxs) disable(tx *ForcedTx) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.disabled[tx] = struct{}{}
+}
+
+// commitForcedTxs applies the forced transactions at the top of the block, each
+// of them on its own so that a failure doesn't affect the others.
+func (b *blockState) commitForcedTxs(ctx context.Context, w *worker) error {
+	forced, sign := w.forced.active()
+	if len(forced) == 0 {
+		return nil
+	}
+	for _, template := range forced {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tx, err := b.forcedTx(w, template, sign)
+		if err == nil {
+			bundle := &Bundle{Txs: types.Transactions{tx}, BlockNumber: b.header.Number.Uint64()}
+			if template.OnFailure == ForcedTxKeep {
+				bundle.RevertingTxHashes = []common.Hash{tx.Hash()}
+			}
+			err = b.commitBundle(w, bundle)
+		}
+		if err != nil {
+			log.Debug("Failed to include forced transaction", "from", template.From, "number", b.header.Number, "err", err)
+			forcedTxFailedMeter.Mark(1)
+			b.skip(skipForcedTxFailed)
+			if template.OnFailure == ForcedTxDisable {
+				log.Warn("Disabled forced transaction", "from", template.From, "to", template.To, "err", err)
+				w.forced.disable(template)
+			}
+			continue
+		}
+		forcedTxIncludedMeter.Mark(1)
+	}
+	w.build.update(b)
+	return nil
+}
+
+// forcedTx signs the transaction of a template for the block, with the next
+// nonce of its sender and a fee cap of the base fee of its currency plus its tip.
+func (b *blockState) forcedTx(w *worker, template *ForcedTx, sign ForcedTxSigner) (*types.Transaction, error) {
+	if sign == nil {
+		return nil, errNoForcedTxSigner
+	}
+	var (
+		nonce = b.state.GetNonce(template.From)
+		value = new(big.Int)
+		tip   = new(big.Int)
+	)
+	if template.Value != nil {
+		value = template.Value.ToInt()
+	}
+	if template.GasTipCap != nil {
+		tip = template.GasTipCap.ToInt()
+	}
+	feeCap := new(big.Int).Add(b.sysCtx.GetGasPriceMinimum(template.FeeCurrency), tip)
+
+	var data types.TxData
+	if w.chainConfig.IsGingerbread(b.header.Number) {
+		data = &types.CeloDynamicFeeTxV2{
+			ChainID:     w.chainConfig.ChainID,
+			Nonce:       nonce,
+			GasTipCap:   tip,
+			GasFeeCap:   feeCap,
+			Gas:         uint64(template.Gas),
+			FeeCurrency: template.FeeCurrency,
+			To:          template.To,
+			Value:       value,
+			Data:        template.Data,
+		}
+	} else {
+		data = &types.LegacyTx{
+			Nonce:       nonce,
+			GasPrice:    feeCap,
+			Gas:         uint64(template.Gas),
+			FeeCurrency: template.FeeCurrency,
+			To:          template.To,
+			Value:       value,
+			Data:        template.Data,
+		}
+	}
+	return sign(template.From, types.NewTx(data), w.chainConfig.ChainID)
+}
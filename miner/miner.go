@@ -18,7 +18,9 @@
 package miner
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/celo-org/celo-blockchain/common"
 	"github.com/celo-org/celo-blockchain/common/hexutil"
@@ -28,6 +30,7 @@ import (
 	"github.com/celo-org/celo-blockchain/core/rawdb"
 	"github.com/celo-org/celo-blockchain/core/state"
 	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto/atrest"
 	"github.com/celo-org/celo-blockchain/eth/downloader"
 	"github.com/celo-org/celo-blockchain/ethdb"
 	"github.com/celo-org/celo-blockchain/event"
@@ -47,6 +50,41 @@ type Config struct {
 	ExtraData          hexutil.Bytes              `toml:",omitempty"` // Block extra data set by the miner
 	FeeCurrencyDefault float64                    // Default fraction of block gas limit
 	FeeCurrencyLimits  map[common.Address]float64 // Fee currency-to-limit fraction mapping
+
+	StopOnOutdatedVersion bool          // Stop proposing blocks while below the minimum client version set on chain
+	RecommitBudget        time.Duration // Maximum time spent applying transactions to a block, 0 for unbounded
+	ParallelLanes         bool          // Apply the transactions of each fee currency in parallel, speculatively
+	LocalGasQuotient      float64       // Fraction of the block gas limit reserved for local transactions
+	DenylistFile          string        // JSON file of the accounts left out of the blocks built, see Denylist
+	ForcedTxsFile         string        // JSON file of the transactions placed at the top of the blocks built, see ForcedTx
+	BlockRelay            bool          // Accept block candidates from external builders, see Miner.SubmitBlockCandidate
+	PayloadBuilder        bool          // Build the blocks of external validators, see Miner.StartPayload
+	RandomnessKey         *atrest.Key   `toml:"-"` // Encrypts the journaled randomness if set
+
+	// TxFeeRecipientSplit shares the tx fees between weighted recipients, taking
+	// turns from the split etherbase (donut) fork, see TxFeeRecipientSchedule.
+	TxFeeRecipientSplit *TxFeeRecipientSchedule `toml:",omitempty"`
+
+	// FeeCurrencyReservedGas charges the fee currency pools the gas limit of the
+	// included transactions instead of the gas they used. The pools then bound the
+	// gas the transactions of a currency reserve, at the cost of fitting fewer of
+	// them when they overestimate their gas, measured by miner/feecurrency/unusedgas.
+	FeeCurrencyReservedGas bool
+
+	// RateStaleness is the maximum age of the exchange rate of a fee currency for
+	// its transactions to be included, its limit is zero for the blocks built
+	// while the rate is older. Zero for no bound.
+	RateStaleness time.Duration
+
+	// GasLimitOverride caps the gas limit of the blocks built while not validating,
+	// such as the pending block, which then don't need the gas limit set on chain
+	// to be fetched. Zero for no cap.
+	GasLimitOverride uint64
+
+	// Builder is the engine API endpoint of an external builder supplying the
+	// blocks proposed, which are executed before being sealed. The worker builds
+	// its own block whenever the builder fails to, see PayloadAttributes.
+	Builder string `toml:",omitempty"`
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -231,11 +269,115 @@ func (miner *Miner) SetTxFeeRecipient(addr common.Address) {
 	miner.worker.setTxFeeRecipient(addr)
 }
 
+// SetTxFeeRecipientSchedule sets the schedule rotating the address receiving fees,
+// overriding the one set with SetTxFeeRecipient from its start block.
+func (miner *Miner) SetTxFeeRecipientSchedule(schedule *TxFeeRecipientSchedule) error {
+	return miner.worker.setTxFeeRecipientSchedule(schedule)
+}
+
+// SetFeeCurrencyLimits sets the fractions of the block gas limit usable by the
+// transactions paying fees in each fee currency, defaultLimit applying to the
+// currencies not in limits. The new limits apply from the next block built.
+func (miner *Miner) SetFeeCurrencyLimits(defaultLimit float64, limits map[common.Address]float64) error {
+	return miner.worker.setFeeCurrencyLimits(defaultLimit, limits)
+}
+
+// SetGasLimitOverride sets the cap of the gas limit of the blocks built while not
+// validating, zero removing it. The new cap applies from the next block built.
+func (miner *Miner) SetGasLimitOverride(limit uint64) {
+	miner.worker.setGasLimitOverride(limit)
+}
+
+// GasLimitOverride returns the cap of the gas limit of the blocks built while not
+// validating, zero if there is none.
+func (miner *Miner) GasLimitOverride() uint64 {
+	return miner.worker.gasLimitOverrideConfig()
+}
+
+// FeeCurrencyLimits returns the default fee currency limit and the per currency ones.
+func (miner *Miner) FeeCurrencyLimits() (float64, map[common.Address]float64) {
+	return miner.worker.feeCurrencyLimitsConfig()
+}
+
+// SendBundle queues a bundle of transactions to be included atomically at the top
+// of its target block.
+func (miner *Miner) SendBundle(bundle *Bundle) error {
+	return miner.worker.bundles.add(bundle, miner.worker.chain.CurrentBlock().NumberU64())
+}
+
+// SubmitBlockCandidate sets the block candidate of an external builder for the
+// next block. The worker re-executes its transactions in the block it builds in
+// place of its own selection, falling back on it if they can't all be included.
+func (miner *Miner) SubmitBlockCandidate(block *types.Block) error {
+	if !miner.worker.config.BlockRelay {
+		return errBlockRelayDisabled
+	}
+	return miner.worker.candidates.set(block, miner.worker.chain.CurrentHeader())
+}
+
+// StartPayload starts building a block on top of the head, which must be the
+// current one, for the external validator proposing it with the attributes. It
+// returns the id to retrieve the block with GetPayload.
+func (miner *Miner) StartPayload(head common.Hash, attrs *PayloadAttributes) (PayloadID, error) {
+	return miner.worker.startPayload(head, attrs)
+}
+
+// GetPayload waits for the block of a payload started with StartPayload to be
+// built. The block is unsealed, and lacks the parent seal of the validator.
+func (miner *Miner) GetPayload(ctx context.Context, id PayloadID) (*types.Block, error) {
+	return miner.worker.getPayload(ctx, id)
+}
+
+// SetDenylist replaces the denylist enforced on the blocks built.
+func (miner *Miner) SetDenylist(list *Denylist) {
+	miner.worker.denylist.set(list)
+}
+
+// Denylist returns the denylist enforced on the blocks built.
+func (miner *Miner) Denylist() *Denylist {
+	return miner.worker.denylist.get()
+}
+
+// ReloadDenylist replaces the denylist enforced on the blocks built with the
+// content of the configured file.
+func (miner *Miner) ReloadDenylist() error {
+	return miner.worker.reloadDenylist()
+}
+
+// SetForcedTxSigner sets the function signing the forced transactions.
+func (miner *Miner) SetForcedTxSigner(sign ForcedTxSigner) {
+	miner.worker.forced.setSigner(sign)
+}
+
+// BuildStatus returns the status of the block being built, or of the last one
+// built, nil if none was started yet.
+func (miner *Miner) BuildStatus() *BuildStatus {
+	return miner.worker.build.snapshot()
+}
+
+// DryRunBlock assembles a block with the pending transactions on top of the
+// current head without sealing it, whether the miner is running or not.
+func (miner *Miner) DryRunBlock(ctx context.Context) (*DryRunBlock, error) {
+	return miner.worker.dryRunBlock(ctx)
+}
+
 // PendingBlockAndReceipts returns the currently pending block and corresponding receipts.
 func (miner *Miner) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
 	return miner.worker.pendingBlockAndReceipts()
 }
 
+// SubscribePendingBlock starts delivering the pending blocks assembled by the
+// miner, with the fee currency breakdown of their transactions, to the given channel.
+func (miner *Miner) SubscribePendingBlock(ch chan<- *PendingBlock) event.Subscription {
+	return miner.worker.subscribePendingBlock(ch)
+}
+
+// SubscribeTxLifecycle starts delivering the lifecycle events of the transactions
+// in the blocks proposed by the miner and sealed by the consensus engine.
+func (miner *Miner) SubscribeTxLifecycle(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	return miner.worker.stageFeed.Subscribe(ch)
+}
+
 // SubscribePendingLogs starts delivering logs from pending transactions
 // to the given channel.
 func (miner *Miner) SubscribePendingLogs(ch chan<- []*types.Log) event.Subscription {
@@ -0,0 +1,387 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+var (
+	laneMergedMeter   = metrics.NewRegisteredMeter("miner/lanes/merged", nil)
+	laneConflictMeter = metrics.NewRegisteredMeter("miner/lanes/conflict", nil)
+)
+
+// feeCurrencyLane is the set of pending transactions paying fees in a single
+// currency, applied speculatively on its own copy of the block.
+type feeCurrencyLane struct {
+	currency *common.Address // Fee currency of the lane, nil for CELO
+	txs      map[common.Address]types.Transactions
+	gas      uint64 // Sum of the gas limits of the transactions
+	size     uint64 // Sum of the sizes of the transactions
+
+	block *blockState // Speculative block the lane was applied to
+	err   error
+}
+
+// laneState is what a speculative copy applying a lane keeps to itself instead of
+// sharing it with the other lanes, merged into the block and the worker once all
+// the lanes were applied.
+type laneState struct {
+	denylist *denylist                 // Denylist enforced when the lanes started
+	rejected map[common.Hash]string    // Transactions rejected on top of the parent, see rejectedTxs
+	dropped  map[common.Address]string // Senders left out of the block whatever its content, with the reason
+}
+
+func newFeeCurrencyLane(currency *common.Address) *feeCurrencyLane {
+	return &feeCurrencyLane{currency: currency, txs: make(map[common.Address]types.Transactions)}
+}
+
+// add queues the transactions of an account in the lane.
+func (l *feeCurrencyLane) add(from common.Address, txs types.Transactions) {
+	l.txs[from] = txs
+	for _, tx := range txs {
+		l.gas += tx.Gas()
+		l.size += uint64(tx.Size())
+	}
+}
+
+// splitFeeCurrencyLanes groups the pending transactions by fee currency, CELO
+// first and then by currency address. Accounts paying with several currencies
+// are left out of the lanes.
+func splitFeeCurrencyLanes(pending map[common.Address]types.Transactions) []*feeCurrencyLane {
+	lanes := make(map[common.Address]*feeCurrencyLane)
+	for from, txs := range pending {
+		if len(txs) == 0 {
+			continue
+		}
+		currency := txs[0].FeeCurrency()
+		mixed := false
+		for _, tx := range txs[1:] {
+			if !sameFeeCurrency(tx.FeeCurrency(), currency) {
+				mixed = true
+				break
+			}
+		}
+		if mixed {
+			continue
+		}
+		var key common.Address
+		if currency != nil {
+			key = *currency
+		}
+		lane := lanes[key]
+		if lane == nil {
+			lane = newFeeCurrencyLane(currency)
+			lanes[key] = lane
+		}
+		lane.add(from, txs)
+	}
+	sorted := make([]*feeCurrencyLane, 0, len(lanes))
+	for _, lane := range lanes {
+		sorted = append(sorted, lane)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].currency == nil || sorted[j].currency == nil {
+			return sorted[i].currency == nil && sorted[j].currency != nil
+		}
+		return bytes.Compare(sorted[i].currency[:], sorted[j].currency[:]) < 0
+	})
+	return sorted
+}
+
+func sameFeeCurrency(a, b *common.Address) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// speculativeCopy returns a copy of the block to apply a lane to, with the given
+// gas and bytes available and the denylist to enforce. The copy tracks the accounts
+// its transactions access.
+func (b *blockState) speculativeCopy(gas, size uint64, denylist *denylist) *blockState {
+	cpy := &blockState{
+		signer:         b.signer,
+		state:          b.state.Copy(),
+		gasPool:        new(core.GasPool).AddGas(gas),
+		multiGasPool:   b.multiGasPool.Copy(),
+		multiGasCaps:   b.multiGasCaps,
+		gasLimit:       b.gasLimit,
+		sysCtx:         b.sysCtx,
+		header:         types.CopyHeader(b.header),
+		randomness:     b.randomness,
+		txFeeRecipient: b.txFeeRecipient,
+		fillDeadline:   b.fillDeadline,
+		lane: &laneState{
+			denylist: denylist,
+			rejected: make(map[common.Hash]string),
+			dropped:  make(map[common.Address]string),
+		},
+	}
+	if b.bytesBlock != nil {
+		cpy.bytesBlock = new(core.BytesBlock).SetLimit(size)
+	}
+	cpy.state.TrackAccessedAccounts()
+	return cpy
+}
+
+// denies reports whether the transaction must be left out because of the denylist,
+// the one the lanes started with for a speculative copy.
+func (b *blockState) denies(w *worker, from common.Address, tx *types.Transaction) bool {
+	if b.lane != nil {
+		return b.lane.denylist.denies(b.state, from, tx)
+	}
+	return w.denylist.denies(b.state, from, tx)
+}
+
+// reject records a transaction which can't be included on top of the parent, see
+// rejectedTxs. The rejections of a lane are recorded once all the lanes are applied.
+func (b *blockState) reject(w *worker, hash common.Hash, reason string) {
+	if b.lane != nil {
+		b.lane.rejected[hash] = reason
+		return
+	}
+	w.rejected.add(b.header.ParentHash, hash, reason)
+}
+
+// dropSender counts the transactions of a sender left out for a reason which
+// doesn't depend on the rest of the block. A lane reports them to the block if it
+// is merged, and its dropped senders aren't retried by the serial pass.
+func (b *blockState) dropSender(from common.Address, reason string) {
+	if b.lane != nil {
+		b.lane.dropped[from] = reason
+		return
+	}
+	b.skip(reason)
+}
+
+// updateStatus reports the progress of the block to the build status. The lanes
+// report through the block once merged.
+func (b *blockState) updateStatus(w *worker) {
+	if b.lane == nil {
+		w.build.update(b)
+	}
+}
+
+// commitLanes applies the fee currency lanes of the pending transactions in
+// parallel, each on a copy of the block, and merges the lanes which touched
+// disjoint accounts back into the block. It returns the transactions left to
+// apply serially: the ones of conflicting lanes, of accounts paying with several
+// currencies, and the ones the lanes didn't include.
+func (b *blockState) commitLanes(ctx context.Context, w *worker, pending map[common.Address]types.Transactions) (map[common.Address]types.Transactions, error) {
+	lanes := splitFeeCurrencyLanes(pending)
+	if len(lanes) < 2 {
+		return pending, nil
+	}
+	return b.applyLanes(ctx, w, lanes, pending)
+}
+
+// applyLanes applies the given lanes of the pending transactions in parallel and
+// merges them into the block, returning the transactions left to apply.
+func (b *blockState) applyLanes(ctx context.Context, w *worker, lanes []*feeCurrencyLane, pending map[common.Address]types.Transactions) (map[common.Address]types.Transactions, error) {
+	// Share the block between the lanes according to their demand, so that the
+	// merged lanes always fit in it
+	var (
+		gas, size       = b.gasPool.Gas(), uint64(0)
+		demand, demandB uint64
+	)
+	if b.bytesBlock != nil {
+		size = b.bytesBlock.BytesLeft()
+	}
+	for _, lane := range lanes {
+		demand += lane.gas
+		demandB += lane.size
+	}
+	denylist := w.denylist.copy()
+	for _, lane := range lanes {
+		laneGas, laneSize := lane.gas, lane.size
+		if demand > gas {
+			laneGas = shareOf(gas, lane.gas, demand)
+		}
+		if demandB > size {
+			laneSize = shareOf(size, lane.size, demandB)
+		}
+		if limit := b.multiGasPool.PoolFor(lane.currency).Gas(); laneGas > limit {
+			laneGas = limit
+		}
+		lane.block = b.speculativeCopy(laneGas, laneSize, denylist)
+	}
+	var wg sync.WaitGroup
+	for _, lane := range lanes {
+		wg.Add(1)
+		go func(lane *feeCurrencyLane) {
+			defer wg.Done()
+			lb := lane.block
+			baseFeeFn, toCElOFn := core.CreateConversionFunctions(lb.sysCtx, w.runnerFactory, lb.header, lb.state)
+			txs := types.NewTransactionsByPriceAndNonce(lb.signer, lane.txs, baseFeeFn, toCElOFn)
+			lane.err = lb.commitTransactions(ctx, w, txs, lb.txFeeRecipient)
+		}(lane)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// The rejections don't depend on the other transactions, whether the lane is merged or not
+	for _, lane := range lanes {
+		for hash, reason := range lane.block.lane.rejected {
+			w.rejected.add(b.header.ParentHash, hash, reason)
+		}
+	}
+	// Merge the lanes in order, skipping the ones which read an account written
+	// by an earlier lane, or wrote one it read
+	var (
+		accessed = make(map[common.Address]struct{})
+		modified = make(map[common.Address]struct{})
+		dropped  = make(map[common.Address]struct{})
+		logs     []*types.Log
+	)
+	for _, lane := range lanes {
+		lb := lane.block
+		if lane.err != nil {
+			log.Debug("Speculative fee currency lane failed", "currency", lane.currency, "err", lane.err)
+			continue
+		}
+		if lb.truncated {
+			b.truncated = true
+		}
+		if len(lb.txs) == 0 && len(lb.lane.dropped) == 0 {
+			continue
+		}
+		laneAccessed, laneModified := lb.state.AccessedAccounts(), lb.state.ModifiedAccounts()
+		if intersects(laneAccessed, modified) || intersects(laneModified, accessed) {
+			log.Trace("Fee currency lane conflicts with an earlier one", "currency", lane.currency, "txs", len(lb.txs))
+			laneConflictMeter.Mark(1)
+			continue
+		}
+		for addr := range laneAccessed {
+			accessed[addr] = struct{}{}
+		}
+		for addr := range laneModified {
+			modified[addr] = struct{}{}
+		}
+		merged, err := b.mergeLane(w, lane, laneModified)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, merged...)
+		// The other skipped transactions are counted by the serial pass retrying them
+		for from, reason := range lb.lane.dropped {
+			b.skip(reason)
+			dropped[from] = struct{}{}
+		}
+		laneMergedMeter.Mark(1)
+	}
+	if !w.isRunning() && len(logs) > 0 {
+		w.pendingLogsFeed.Send(copyLogs(logs))
+	}
+	w.build.update(b)
+
+	// Whatever wasn't included nor dropped is left to the serial pass
+	leftover := make(map[common.Address]types.Transactions)
+	for from, txs := range pending {
+		if _, ok := dropped[from]; ok {
+			continue
+		}
+		nonce := b.state.GetNonce(from)
+		for i, tx := range txs {
+			if tx.Nonce() >= nonce {
+				leftover[from] = txs[i:]
+				break
+			}
+		}
+	}
+	return leftover, nil
+}
+
+// mergeLane adds the transactions of a lane to the block, taking the accounts
+// they modified from the lane's state, along with their logs and transfers. It
+// returns the logs of the transactions.
+func (b *blockState) mergeLane(w *worker, lane *feeCurrencyLane, modified map[common.Address]struct{}) ([]*types.Log, error) {
+	var (
+		lb          = lane.block
+		gasUsed     uint64
+		currencyGas uint64
+		size        uint64
+		logs        []*types.Log
+	)
+	b.state.MergeAccounts(lb.state, modified)
+	for i, tx := range lb.txs {
+		receipt := lb.receipts[i]
+
+		// Logs and receipts are indexed within the lane, reindex them in the block
+		b.state.Prepare(tx.Hash(), b.tcount)
+		for _, l := range receipt.Logs {
+			b.state.AddLog(l)
+		}
+		for _, t := range lb.state.GetTransfers(tx.Hash()) {
+			b.state.AddTransfer(t)
+		}
+		logs = append(logs, receipt.Logs...)
+		b.header.GasUsed += receipt.GasUsed
+		receipt.CumulativeGasUsed = b.header.GasUsed
+		receipt.TransactionIndex = uint(b.tcount)
+
+		b.txs = append(b.txs, tx)
+		b.receipts = append(b.receipts, receipt)
+		b.tcount++
+		gasUsed += receipt.GasUsed
+		currencyGas += w.feeCurrencyGas(tx, receipt.GasUsed)
+		size += uint64(tx.Size())
+	}
+	if err := b.gasPool.SubGas(gasUsed); err != nil {
+		return nil, err
+	}
+	if err := b.multiGasPool.PoolFor(lane.currency).SubGas(currencyGas); err != nil {
+		return nil, err
+	}
+	if b.bytesBlock != nil {
+		if err := b.bytesBlock.SubBytes(size); err != nil {
+			return nil, err
+		}
+	}
+	return logs, nil
+}
+
+// shareOf returns the part of total proportional to part/whole.
+func shareOf(total, part, whole uint64) uint64 {
+	share := new(big.Int).SetUint64(total)
+	share.Mul(share, new(big.Int).SetUint64(part))
+	return share.Div(share, new(big.Int).SetUint64(whole)).Uint64()
+}
+
+func intersects(a, b map[common.Address]struct{}) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	for addr := range a {
+		if _, ok := b[addr]; ok {
+			return true
+		}
+	}
+	return false
+}
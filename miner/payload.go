@@ -0,0 +1,322 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
+	"github.com/celo-org/celo-blockchain/consensus"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+	"github.com/celo-org/celo-blockchain/rlp"
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+const (
+	// payloadTimeout bounds the payload requests to the external builder, which
+	// hold up the proposal.
+	payloadTimeout = 2 * time.Second
+
+	// maxPayloads is the number of payloads kept by the builder until retrieved.
+	maxPayloads = 10
+)
+
+// Statuses of the head set with engine_forkchoiceUpdated, see PayloadStatus.
+const (
+	PayloadStatusValid   = "VALID"
+	PayloadStatusSyncing = "SYNCING"
+)
+
+var (
+	errPayloadBuilderDisabled = errors.New("payload builder disabled")
+	errPayloadStale           = errors.New("payload head not the current head")
+	errPayloadTimestamp       = errors.New("payload timestamp not after the head one")
+	errUnknownPayload         = errors.New("unknown payload")
+	errPayloadParent          = errors.New("payload not on top of the prepared block parent")
+	errPayloadRandomness      = errors.New("payload randomness mismatch")
+
+	payloadAcceptedMeter = metrics.NewRegisteredMeter("miner/builder/accepted", nil)
+	payloadRejectedMeter = metrics.NewRegisteredMeter("miner/builder/rejected", nil)
+)
+
+// PayloadID identifies a payload started by engine_forkchoiceUpdated.
+type PayloadID [8]byte
+
+func (id PayloadID) String() string {
+	return hexutil.Encode(id[:])
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (id PayloadID) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(id[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (id *PayloadID) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("PayloadID", input, id[:])
+}
+
+// ForkchoiceState is the head a validator builds its next block on. Istanbul
+// blocks are final once committed, so there are no safe and finalized blocks.
+type ForkchoiceState struct {
+	HeadBlockHash common.Hash `json:"headBlockHash"`
+}
+
+// PayloadAttributes are the fields of the next block set by the validator
+// proposing it: the reveal and commitment of its randomness can only be computed
+// with its key.
+type PayloadAttributes struct {
+	Timestamp           hexutil.Uint64 `json:"timestamp"`
+	FeeRecipient        common.Address `json:"feeRecipient"`        // Coinbase of the block
+	Proposer            common.Address `json:"proposer"`            // Validator revealing and committing the randomness
+	RevealedRandomness  common.Hash    `json:"revealedRandomness"`  // Unused while the randomness beacon isn't running
+	CommittedRandomness common.Hash    `json:"committedRandomness"` // Unused while the randomness beacon isn't running
+}
+
+// PayloadStatus is the status of the head set with engine_forkchoiceUpdated.
+type PayloadStatus struct {
+	Status          string       `json:"status"`
+	LatestValidHash *common.Hash `json:"latestValidHash"`
+}
+
+// ForkchoiceResponse is the result of engine_forkchoiceUpdated, with the id of
+// the payload started if attributes were given.
+type ForkchoiceResponse struct {
+	PayloadStatus PayloadStatus `json:"payloadStatus"`
+	PayloadID     *PayloadID    `json:"payloadId"`
+}
+
+// payloadID computes the id of the payload built on the parent with the attributes.
+func payloadID(parent common.Hash, attrs *PayloadAttributes) PayloadID {
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], uint64(attrs.Timestamp))
+	hash := crypto.Keccak256(parent[:], timestamp[:], attrs.FeeRecipient[:], attrs.Proposer[:], attrs.RevealedRandomness[:], attrs.CommittedRandomness[:])
+
+	var id PayloadID
+	copy(id[:], hash)
+	return id
+}
+
+// payload is a block being built for an external validator.
+type payload struct {
+	done  chan struct{} // Closed once the block is built
+	block *types.Block
+	err   error
+}
+
+// payloadStore holds the latest payloads started, until retrieved or replaced.
+type payloadStore struct {
+	mu       sync.Mutex
+	payloads map[PayloadID]*payload
+	order    []PayloadID // Ids of the payloads, oldest first
+}
+
+// start returns the payload with the given id, and whether it was just added.
+func (s *payloadStore) start(id PayloadID) (*payload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.payloads[id]; ok {
+		return p, false
+	}
+	if s.payloads == nil {
+		s.payloads = make(map[PayloadID]*payload)
+	}
+	if len(s.order) == maxPayloads {
+		delete(s.payloads, s.order[0])
+		s.order = s.order[1:]
+	}
+	p := &payload{done: make(chan struct{})}
+	s.payloads[id] = p
+	s.order = append(s.order, id)
+	return p, true
+}
+
+func (s *payloadStore) get(id PayloadID) *payload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.payloads[id]
+}
+
+// startPayload starts building a block on top of the current head for the
+// validator proposing it, returning the id to retrieve it with.
+func (w *worker) startPayload(head common.Hash, attrs *PayloadAttributes) (PayloadID, error) {
+	if !w.config.PayloadBuilder {
+		return PayloadID{}, errPayloadBuilderDisabled
+	}
+	parent := w.chain.CurrentBlock()
+	if parent.Hash() != head {
+		return PayloadID{}, errPayloadStale
+	}
+	if uint64(attrs.Timestamp) <= parent.Time() {
+		return PayloadID{}, errPayloadTimestamp
+	}
+	id := payloadID(head, attrs)
+	p, added := w.payloads.start(id)
+	if added {
+		go func() {
+			defer close(p.done)
+			p.block, p.err = w.buildPayload(parent, attrs)
+		}()
+	}
+	return id, nil
+}
+
+// getPayload waits for the block of the payload with the given id to be built.
+func (w *worker) getPayload(ctx context.Context, id PayloadID) (*types.Block, error) {
+	p := w.payloads.get(id)
+	if p == nil {
+		return nil, errUnknownPayload
+	}
+	select {
+	case <-p.done:
+		return p.block, p.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// buildPayload assembles a block with the pending transactions on top of
+// parent with the attributes of the validator proposing it. The block is left
+// unsealed, and without the parent seal the validator adds.
+func (w *worker) buildPayload(parent *types.Block, attrs *PayloadAttributes) (*types.Block, error) {
+	b, err := prepareBlockWith(context.Background(), w, parent, attrs)
+	if b != nil {
+		defer b.close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := b.selectAndApplyTransactions(context.Background(), w); err != nil {
+		return nil, err
+	}
+	block, err := b.finalizeAndAssemble(context.Background(), w)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Built payload", "number", block.Number(), "proposer", attrs.Proposer, "txs", b.tcount, "gas", block.GasUsed())
+	return block, nil
+}
+
+// payloadBuilder supplies the blocks proposed by the worker, see Config.Builder.
+type payloadBuilder interface {
+	// BuildPayload returns a block built on top of parent with the attributes.
+	BuildPayload(ctx context.Context, parent common.Hash, attrs *PayloadAttributes) (*types.Block, error)
+}
+
+// rpcPayloadBuilder requests the blocks from the engine API of an external builder.
+type rpcPayloadBuilder struct {
+	client *rpc.Client
+}
+
+// BuildPayload implements payloadBuilder, starting the payload with
+// engine_forkchoiceUpdated and retrieving it with engine_getPayload.
+func (b *rpcPayloadBuilder) BuildPayload(ctx context.Context, parent common.Hash, attrs *PayloadAttributes) (*types.Block, error) {
+	var res ForkchoiceResponse
+	if err := b.client.CallContext(ctx, &res, "engine_forkchoiceUpdated", ForkchoiceState{HeadBlockHash: parent}, attrs); err != nil {
+		return nil, err
+	}
+	if res.PayloadStatus.Status != PayloadStatusValid {
+		return nil, fmt.Errorf("builder head status %s", res.PayloadStatus.Status)
+	}
+	if res.PayloadID == nil {
+		return nil, errors.New("builder started no payload")
+	}
+	var raw hexutil.Bytes
+	if err := b.client.CallContext(ctx, &raw, "engine_getPayload", *res.PayloadID); err != nil {
+		return nil, err
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(raw, block); err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+	return block, nil
+}
+
+// payloadAttributes returns the attributes of the prepared block for the builder.
+func (w *worker) payloadAttributes(b *blockState) *PayloadAttributes {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return &PayloadAttributes{
+		Timestamp:           hexutil.Uint64(b.header.Time),
+		FeeRecipient:        b.header.Coinbase,
+		Proposer:            w.validator,
+		RevealedRandomness:  b.randomness.Revealed,
+		CommittedRandomness: b.randomness.Committed,
+	}
+}
+
+// adoptPayload validates a block of the external builder against the prepared
+// one by executing it on the parent state, and makes it the content of the
+// block. Only its transactions and the roots they lead to are taken: the header
+// fields set by the validator, its parent seal and its validator set diff are
+// the local ones.
+func (b *blockState) adoptPayload(w *worker, payload *types.Block) (*types.Block, error) {
+	if payload.ParentHash() != b.header.ParentHash || payload.NumberU64() != b.header.Number.Uint64() {
+		return nil, errPayloadParent
+	}
+	if randomness := payload.Randomness(); randomness == nil || *randomness != *b.randomness {
+		return nil, errPayloadRandomness
+	}
+	header := types.CopyHeader(b.header)
+	header.Root = payload.Root()
+	header.TxHash = payload.TxHash()
+	header.ReceiptHash = payload.ReceiptHash()
+	header.Bloom = payload.Bloom()
+	header.GasUsed = payload.GasUsed()
+
+	block := types.NewBlockWithHeader(header).WithBody(payload.Transactions(), b.randomness, nil)
+	if err := w.chain.Validator().ValidateBody(block); err != nil {
+		return nil, err
+	}
+	parent := w.chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	statedb, err := w.chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	receipts, _, usedGas, err := w.chain.Processor().Process(block, statedb, *w.chain.GetVMConfig())
+	if err != nil {
+		return nil, err
+	}
+	if err := w.chain.Validator().ValidateState(block, statedb, receipts, usedGas); err != nil {
+		return nil, err
+	}
+	if istanbul, ok := w.engine.(consensus.Istanbul); ok {
+		if err := istanbul.UpdateValSetDiff(w.chain, header, statedb); err != nil {
+			return nil, fmt.Errorf("Unable to update Validator Set Diff: %w", err)
+		}
+		block = types.NewBlockWithHeader(header).WithBody(payload.Transactions(), b.randomness, nil)
+	}
+
+	b.state.StopPrefetcher()
+	b.state, b.header, b.txs, b.receipts = statedb, header, block.Transactions(), receipts
+	b.tcount = len(b.txs)
+	b.gasPool.SubGas(usedGas)
+	return block, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"sync/atomic"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/core"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/event"
+	"github.com/celo-org/celo-blockchain/log"
+)
+
+// PendingBlock is the block assembled by the worker, with the fee currency
+// breakdown of its transactions, so that the inclusion likelihood of a
+// transaction can be estimated by currency.
+type PendingBlock struct {
+	Block *types.Block
+	Fees  []PendingTxFee // Fees of the transactions of the block, in order
+
+	CeloGasRemaining        uint64                    // Gas left for the CELO and unconfigured currencies pool
+	FeeCurrencyGasRemaining map[common.Address]uint64 // Gas left for every configured fee currency
+}
+
+// PendingTxFee is the fee paid by a transaction of the pending block.
+type PendingTxFee struct {
+	Hash        common.Hash
+	FeeCurrency *common.Address // nil for CELO
+	GasUsed     uint64
+
+	EffectiveTip       *big.Int // Tip per gas paid to the validator, in the fee currency
+	EffectiveTipInCelo *big.Int // Tip per gas in CELO, nil if the currency can't be converted
+}
+
+// subscribePendingBlock delivers the blocks assembled by the worker to ch. Slow
+// subscribers only get the latest block, so that they can't stall the worker.
+// The fee breakdown is only computed while there are subscribers.
+func (w *worker) subscribePendingBlock(ch chan<- *PendingBlock) event.Subscription {
+	atomic.AddInt32(&w.pendingBlockSubs, 1)
+	in := make(chan *PendingBlock, 1)
+	sub := w.pendingBlockFeed.Subscribe(in)
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer atomic.AddInt32(&w.pendingBlockSubs, -1)
+		defer sub.Unsubscribe()
+
+		var latest *PendingBlock
+		for {
+			var out chan<- *PendingBlock
+			if latest != nil {
+				out = ch
+			}
+			select {
+			case block := <-in:
+				latest = block
+			case out <- latest:
+				latest = nil
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	})
+}
+
+// postPendingBlock sends the given block, assembled from b, to the pending block subscribers.
+func (w *worker) postPendingBlock(block *types.Block, b *blockState) {
+	if atomic.LoadInt32(&w.pendingBlockSubs) == 0 {
+		return
+	}
+	w.pendingBlockFeed.Send(w.newPendingBlock(block, b))
+}
+
+// newPendingBlock computes the fee currency breakdown of the given block, assembled from b.
+func (w *worker) newPendingBlock(block *types.Block, b *blockState) *PendingBlock {
+	baseFeeFn, toCELOFn := core.CreateConversionFunctions(b.sysCtx, w.runnerFactory, b.header, b.state.Copy())
+
+	pending := &PendingBlock{
+		Block:                   block,
+		Fees:                    make([]PendingTxFee, len(b.txs)),
+		CeloGasRemaining:        b.multiGasPool.PoolFor(nil).Gas(),
+		FeeCurrencyGasRemaining: b.multiGasPool.Remaining(),
+	}
+	for i, tx := range b.txs {
+		fee := PendingTxFee{Hash: tx.Hash(), FeeCurrency: tx.FeeCurrency(), GasUsed: b.receipts[i].GasUsed}
+		if tip, err := tx.EffectiveGasTip(baseFeeFn(tx.FeeCurrency())); err == nil {
+			fee.EffectiveTip = tip
+			if fee.EffectiveTipInCelo, err = toCELOFn(tip, tx.FeeCurrency()); err != nil {
+				log.Debug("Failed to convert pending transaction tip", "hash", tx.Hash(), "currency", tx.FeeCurrency(), "err", err)
+			}
+		}
+		pending.Fees[i] = fee
+	}
+	return pending
+}
@@ -0,0 +1,198 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+// blockStage is one of the steps a block goes through, from its construction by
+// the worker to its broadcast once the engine has committed it.
+type blockStage int
+
+const (
+	stagePrepare   blockStage = iota // Header and parent state setup
+	stageFill                        // Transaction selection and execution
+	stageFinalize                    // Post-transaction state changes and block assembly
+	stageSeal                        // Submission to the consensus engine
+	stageBroadcast                   // Insertion and announcement of the committed block
+)
+
+var stageNames = [...]string{"prepare", "fill", "finalize", "seal", "broadcast"}
+
+func (s blockStage) String() string {
+	return stageNames[s]
+}
+
+var (
+	stageTimers = func() []metrics.Timer {
+		timers := make([]metrics.Timer, len(stageNames))
+		for i, name := range stageNames {
+			timers[i] = metrics.NewRegisteredTimer("miner/worker/stage/"+name, nil)
+		}
+		return timers
+	}()
+	stageAbortedMeter  = metrics.NewRegisteredMeter("miner/worker/stage/aborted", nil)
+	fillTruncatedMeter = metrics.NewRegisteredMeter("miner/worker/stage/fill/truncated", nil)
+
+	// randomnessErrorMeter counts the blocks which couldn't be prepared because of
+	// the randomness beacon, which would make the validator miss its proposals.
+	randomnessErrorMeter = metrics.NewRegisteredMeter("miner/randomness/errors", nil)
+
+	// errBlockAborted is returned by the stages of a pipeline that got cancelled.
+	errBlockAborted = errors.New("block construction aborted")
+)
+
+// blockPipeline runs the construction stages of a single block. Every stage runs
+// with its own context derived from the pipeline one, so that cancelling the
+// pipeline (on a new chain head) interrupts the running stage and skips the
+// remaining ones. The worker waits for a cancelled pipeline to exit before starting
+// the next one, which is quick since the prepare and finalize stages return once
+// their context is done. Pipelines are numbered so that a stale one never overwrites
+// the pending block published by its successor.
+type blockPipeline struct {
+	w   *worker
+	ctx context.Context
+	gen uint32 // Generation of the pipeline, see worker.generation
+
+	b     *blockState  // Block being constructed, owned by the pipeline until close
+	block *types.Block // Assembled block, set by the finalize stage
+}
+
+func newBlockPipeline(ctx context.Context, w *worker, gen uint32) *blockPipeline {
+	return &blockPipeline{w: w, ctx: ctx, gen: gen}
+}
+
+// run executes a stage unless the pipeline was cancelled, and accounts its duration.
+func (p *blockPipeline) run(stage blockStage, fn func(ctx context.Context) error) error {
+	if p.ctx.Err() != nil {
+		stageAbortedMeter.Mark(1)
+		return errBlockAborted
+	}
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+	if p.b != nil {
+		p.w.build.setStage(p.b, stage)
+	}
+
+	start := time.Now()
+	err := fn(ctx)
+	stageTimers[stage].UpdateSince(start)
+	return err
+}
+
+// prepare initializes the block on top of the current head.
+func (p *blockPipeline) prepare() error {
+	return p.run(stagePrepare, func(ctx context.Context) error {
+		b, err := prepareBlock(ctx, p.w)
+		if err != nil {
+			return err
+		}
+		p.b = b
+		p.w.build.start(p.gen, b)
+		p.publish()
+		return nil
+	})
+}
+
+// fill applies the pending transactions to the block.
+func (p *blockPipeline) fill() error {
+	return p.run(stageFill, func(ctx context.Context) error {
+		if err := p.b.selectAndApplyTransactions(ctx, p.w); err != nil {
+			return err
+		}
+		p.publish()
+		return nil
+	})
+}
+
+// payload requests the block from the external builder, if there is one, and
+// adopts it once validated in place of the fill and finalize stages. It returns
+// whether the block was adopted, the pipeline building its own otherwise.
+func (p *blockPipeline) payload() bool {
+	if p.w.builder == nil {
+		return false
+	}
+	err := p.run(stageFill, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, payloadTimeout)
+		defer cancel()
+		payload, err := p.w.builder.BuildPayload(ctx, p.b.header.ParentHash, p.w.payloadAttributes(p.b))
+		if err != nil {
+			return err
+		}
+		block, err := p.b.adoptPayload(p.w, payload)
+		if err != nil {
+			return err
+		}
+		p.block = block
+		p.w.build.update(p.b)
+		p.publish()
+		return nil
+	})
+	if err != nil {
+		if err != errBlockAborted {
+			log.Warn("Building the block locally, external payload failed", "number", p.b.header.Number, "err", err)
+			payloadRejectedMeter.Mark(1)
+		}
+		return false
+	}
+	payloadAcceptedMeter.Mark(1)
+	return true
+}
+
+// finalize runs the post-transaction state changes and assembles the block.
+func (p *blockPipeline) finalize() error {
+	return p.run(stageFinalize, func(ctx context.Context) error {
+		block, err := p.b.finalizeAndAssemble(ctx, p.w)
+		if err != nil {
+			return err
+		}
+		p.block = block
+		p.w.build.update(p.b)
+		p.publish()
+		return nil
+	})
+}
+
+// seal submits the assembled block to the consensus engine.
+func (p *blockPipeline) seal() error {
+	return p.run(stageSeal, func(ctx context.Context) error {
+		p.w.submitTaskToEngine(&task{receipts: p.b.receipts, state: p.b.state, block: p.block, createdAt: time.Now()})
+		return nil
+	})
+}
+
+// publish updates the pending block of the worker, unless a newer pipeline was started.
+func (p *blockPipeline) publish() {
+	if block := p.w.updatePendingBlock(p.gen, p.b); block != nil {
+		p.w.postPendingBlock(block, p.b)
+	}
+}
+
+// close shuts down the state prefetcher of the block, see blockState.close.
+func (p *blockPipeline) close() {
+	if p.b != nil {
+		p.w.build.finish(p.b)
+		p.b.close()
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"sync"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+// maxRejectedTxs is the maximum number of rejected transactions remembered for
+// a parent block.
+const maxRejectedTxs = 4096
+
+var rejectedTxHitMeter = metrics.NewRegisteredMeter("miner/rejected/hits", nil)
+
+// rejectedTxs remembers the transactions which can't be included in any block
+// built on top of a given parent, with the reason they were rejected for, so
+// that the recommits of a proposal round skip them without evaluating them
+// again. Only rejections which don't depend on the other transactions of the
+// block are recorded: a gas price below the minimum, or more gas than the whole
+// allowance of the fee currency.
+type rejectedTxs struct {
+	mu     sync.Mutex
+	parent common.Hash
+	txs    map[common.Hash]string // Rejection reasons by transaction hash
+}
+
+// reason returns why the given transaction was rejected on top of parent, if it was.
+func (r *rejectedTxs) reason(parent, hash common.Hash) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.parent != parent {
+		return "", false
+	}
+	reason, ok := r.txs[hash]
+	return reason, ok
+}
+
+// add records the rejection of a transaction on top of parent, forgetting the
+// rejections on top of any other parent.
+func (r *rejectedTxs) add(parent, hash common.Hash, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.parent != parent || r.txs == nil {
+		r.parent, r.txs = parent, make(map[common.Hash]string)
+	}
+	if len(r.txs) < maxRejectedTxs {
+		r.txs[hash] = reason
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/celo-org/celo-blockchain/consensus/istanbul"
+	"github.com/celo-org/celo-blockchain/contracts"
+	"github.com/celo-org/celo-blockchain/contracts/blockchain_parameters"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/log"
+	"github.com/celo-org/celo-blockchain/metrics"
+)
+
+var (
+	// outdatedVersionGauge is 1 while the running client is below the minimum
+	// client version set in BlockchainParameters, 0 otherwise.
+	outdatedVersionGauge = metrics.NewRegisteredGauge("miner/version/outdated", nil)
+	minVersionMajorGauge = metrics.NewRegisteredGauge("miner/version/minimum/major", nil)
+	minVersionMinorGauge = metrics.NewRegisteredGauge("miner/version/minimum/minor", nil)
+	minVersionPatchGauge = metrics.NewRegisteredGauge("miner/version/minimum/patch", nil)
+)
+
+// versionChecker tracks whether the running client satisfies the minimum client
+// version set in BlockchainParameters. The version is read once per epoch.
+type versionChecker struct {
+	epochSize    uint64
+	checkedEpoch uint64
+	checked      bool
+	outdated     int32 // atomic, 1 if the client is outdated
+}
+
+// check reads the minimum client version on the first block seen of every epoch,
+// alerting through logs and metrics if the running client is below it.
+func (vc *versionChecker) check(header *types.Header, w *worker) {
+	epoch := header.Number.Uint64()
+	if vc.epochSize > 0 {
+		epoch = istanbul.GetEpochNumber(header.Number.Uint64(), vc.epochSize)
+	}
+	if vc.checked && vc.checkedEpoch == epoch {
+		return
+	}
+	minVersion, err := vc.minimumClientVersion(header, w)
+	if err != nil {
+		if !errors.Is(err, contracts.ErrRegistryContractNotDeployed) && !errors.Is(err, contracts.ErrSmartContractNotDeployed) {
+			log.Warn("Failed to read the minimum client version", "number", header.Number, "err", err)
+			return
+		}
+		// Nothing to enforce without BlockchainParameters
+		minVersion = &config.VersionInfo{}
+	}
+	vc.checked, vc.checkedEpoch = true, epoch
+
+	minVersionMajorGauge.Update(int64(minVersion.Major))
+	minVersionMinorGauge.Update(int64(minVersion.Minor))
+	minVersionPatchGauge.Update(int64(minVersion.Patch))
+	if config.CurrentVersionInfo.Cmp(minVersion) < 0 {
+		atomic.StoreInt32(&vc.outdated, 1)
+		outdatedVersionGauge.Update(1)
+		log.Error("Client version is below the minimum required by the network, please upgrade",
+			"version", config.CurrentVersionInfo, "minimum", minVersion, "number", header.Number, "stopproposing", w.config.StopOnOutdatedVersion)
+	} else {
+		atomic.StoreInt32(&vc.outdated, 0)
+		outdatedVersionGauge.Update(0)
+	}
+}
+
+// minimumClientVersion reads the minimum client version in the state of the block
+// of header, from the governance parameters mirrored by the chain if they have it.
+func (vc *versionChecker) minimumClientVersion(header *types.Header, w *worker) (*config.VersionInfo, error) {
+	if governance, err := w.chain.Governance().Params(header); err == nil && governance.MinimumClientVersion != nil {
+		return governance.MinimumClientVersion, nil
+	}
+	state, err := w.chain.StateAt(header.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state: %w", err)
+	}
+	return blockchain_parameters.GetMinimumClientVersion(w.runnerFactory.NewEVMRunner(header, state))
+}
+
+// isOutdated returns whether the last check found the client to be below the minimum version.
+func (vc *versionChecker) isOutdated() bool {
+	return atomic.LoadInt32(&vc.outdated) == 1
+}
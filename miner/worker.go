@@ -27,11 +27,13 @@ import (
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/state"
 	"github.com/celo-org/celo-blockchain/core/types"
+	"github.com/celo-org/celo-blockchain/core/vm"
 	"github.com/celo-org/celo-blockchain/ethdb"
 	"github.com/celo-org/celo-blockchain/event"
 	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/metrics"
 	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/rpc"
 	"github.com/celo-org/celo-blockchain/trie"
 )
 
@@ -71,8 +73,14 @@ type worker struct {
 	eth         Backend
 	chain       *core.BlockChain
 
+	// runnerFactory creates the EVMRunners of the system contract calls, the
+	// chain unless overridden by tests
+	runnerFactory vm.EVMRunnerFactory
+
 	// Feeds
-	pendingLogsFeed event.Feed
+	pendingLogsFeed  event.Feed
+	pendingBlockFeed event.Feed
+	stageFeed        event.Feed // Lifecycle events of the transactions proposed and sealed
 
 	// Subscriptions
 	mux          *event.TypeMux
@@ -86,18 +94,38 @@ type worker struct {
 	exitCh  chan struct{}
 	wg      sync.WaitGroup
 
-	mu             sync.RWMutex // The lock used to protect the validator, txFeeRecipient and extra fields
-	validator      common.Address
-	txFeeRecipient common.Address
-	extra          []byte
+	mu                     sync.RWMutex // The lock used to protect the validator, txFeeRecipient(Schedule), extra, fee currency limit and gas limit override fields
+	validator              common.Address
+	txFeeRecipient         common.Address
+	txFeeRecipientSchedule *TxFeeRecipientSchedule
+	sealed                 sealedCounter // Blocks sealed by the validator, rotating the txFeeRecipientSchedule
+	extra                  []byte
+	feeCurrencyDefault     float64
+	feeCurrencyLimits      map[common.Address]float64
+	gasLimitOverride       uint64
 
 	snapshotMu       sync.RWMutex // The lock used to protect the snapshots below
 	snapshotBlock    *types.Block
 	snapshotReceipts types.Receipts
 	snapshotState    *state.StateDB
 
+	bundles  bundlePool   // Bundles waiting for their block
+	build    buildTracker // Status of the latest block built
+	rejected rejectedTxs  // Transactions known not to fit on top of the current head
+	denylist denylist     // Accounts left out of the blocks built
+	forced   forcedTxs    // Transactions placed at the top of the blocks built
+
+	candidates candidatePool // Block candidate of an external builder for the next block
+
+	payloads payloadStore   // Blocks built for external validators, see Config.PayloadBuilder
+	builder  payloadBuilder // External builder of the blocks proposed, nil if none
+
 	// atomic status counters
-	running int32 // The indicator whether the consensus engine is running or not.
+	running          int32  // The indicator whether the consensus engine is running or not.
+	pendingBlockSubs int32  // The number of pending block subscribers.
+	generation       uint32 // The number of the latest started block pipeline.
+
+	versionCheck versionChecker // Tracks the minimum client version set on chain
 
 	// Test hooks
 	newTaskHook  func(*task)      // Method to call upon receiving a new sealing task.
@@ -118,6 +146,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		eth:                 eth,
 		mux:                 mux,
 		chain:               eth.BlockChain(),
+		runnerFactory:       eth.BlockChain(),
 		txsCh:               make(chan core.NewTxsEvent, txChanSize),
 		chainHeadCh:         make(chan core.ChainHeadEvent, chainHeadChanSize),
 		exitCh:              make(chan struct{}),
@@ -125,6 +154,39 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		db:                  db,
 		blockConstructGauge: metrics.NewRegisteredGauge("miner/worker/block_construct", nil),
 	}
+	worker.feeCurrencyDefault, worker.feeCurrencyLimits = config.FeeCurrencyDefault, copyFeeCurrencyLimits(config.FeeCurrencyLimits)
+	worker.gasLimitOverride = config.GasLimitOverride
+	if chainConfig.Istanbul != nil {
+		worker.versionCheck.epochSize = chainConfig.Istanbul.Epoch
+	}
+	if config.DenylistFile != "" {
+		if err := worker.reloadDenylist(); err != nil {
+			log.Error("Failed to load miner denylist", "file", config.DenylistFile, "err", err)
+		}
+	}
+	if split := config.TxFeeRecipientSplit; split != nil {
+		schedule := *split
+		if donut := chainConfig.DonutBlock; donut != nil && donut.Uint64() > schedule.Start {
+			schedule.Start = donut.Uint64()
+		}
+		if err := worker.setTxFeeRecipientSchedule(&schedule); err != nil {
+			log.Error("Failed to set the tx fee recipient split", "err", err)
+		}
+	}
+	if config.ForcedTxsFile != "" {
+		if forced, err := LoadForcedTxs(config.ForcedTxsFile); err != nil {
+			log.Error("Failed to load forced transactions", "file", config.ForcedTxsFile, "err", err)
+		} else {
+			worker.forced.set(forced)
+		}
+	}
+	if config.Builder != "" {
+		if client, err := rpc.Dial(config.Builder); err != nil {
+			log.Error("Failed to connect to the external builder", "url", config.Builder, "err", err)
+		} else {
+			worker.builder = &rpcPayloadBuilder{client: client}
+		}
+	}
 	// Subscribe NewTxsEvent for tx pool
 	worker.txsSub = eth.TxPool().SubscribeNewTxsEvent(worker.txsCh)
 	// Subscribe events for blockchain
@@ -160,6 +222,20 @@ func (w *worker) setExtra(extra []byte) {
 	w.extra = extra
 }
 
+// setGasLimitOverride sets the cap of the gas limit of the blocks built while not
+// validating, zero for none.
+func (w *worker) setGasLimitOverride(limit uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.gasLimitOverride = limit
+}
+
+func (w *worker) gasLimitOverrideConfig() uint64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.gasLimitOverride
+}
+
 // pending returns the pending state and corresponding block.
 func (w *worker) pending() (*types.Block, *state.StateDB) {
 	// return a snapshot to avoid contention on currentMu mutex
@@ -216,6 +292,8 @@ func (w *worker) start() {
 			},
 			w.chain.Validator().ValidateState,
 			func(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB) {
+				defer stageTimers[stageBroadcast].UpdateSince(time.Now())
+				w.postTxStage(core.TxStageSealed, block)
 				if err := w.chain.InsertPreprocessedBlock(block, receipts, logs, state); err != nil {
 					log.Error("Failed to insert produced block", "blockNumber", block.Number(), "hash", block.Hash(), "err", err)
 					return
@@ -258,41 +336,42 @@ func (w *worker) close() {
 	atomic.StoreInt32(&w.running, 0)
 	close(w.exitCh)
 	w.wg.Wait()
+	if builder, ok := w.builder.(*rpcPayloadBuilder); ok {
+		builder.client.Close()
+	}
 }
 
 // constructAndSubmitNewBlock constructs a new block and if the worker is running, submits
 // a task to the engine
-func (w *worker) constructAndSubmitNewBlock(ctx context.Context) {
+func (w *worker) constructAndSubmitNewBlock(ctx context.Context, gen uint32) {
 	start := time.Now()
 
+	if w.config.StopOnOutdatedVersion && w.versionCheck.isOutdated() {
+		log.Error("Refusing to propose, client version is below the minimum required by the network")
+		return
+	}
+
+	p := newBlockPipeline(ctx, w, gen)
+	defer p.close()
+
 	// Initialize the block.
 	// Note: In the current implementation, this will sleep until the time of the next block.
-	b, err := prepareBlock(w)
-	defer func() {
-		if b != nil {
-			b.close()
-		}
-	}()
-	if err != nil {
+	if err := p.prepare(); err != nil {
 		log.Error("Failed to create mining context", "err", err)
 		return
 	}
-	w.updatePendingBlock(b)
 
 	startConstruction := time.Now()
-	err = b.selectAndApplyTransactions(ctx, w)
-	if err != nil {
-		log.Error("Failed to apply transactions to the block", "err", err)
-		return
-	}
-	w.updatePendingBlock(b)
-
-	block, err := b.finalizeAndAssemble(w)
-	if err != nil {
-		log.Error("Failed to finalize and assemble the block", "err", err)
-		return
+	if !p.payload() {
+		if err := p.fill(); err != nil {
+			log.Error("Failed to apply transactions to the block", "err", err)
+			return
+		}
+		if err := p.finalize(); err != nil {
+			log.Error("Failed to finalize and assemble the block", "err", err)
+			return
+		}
 	}
-	w.updatePendingBlock(b)
 
 	// We update the block construction metric here, rather than at the end of the function, because
 	// `submitTaskToEngine` may take a long time if the engine's handler is busy (e.g. if we are not
@@ -305,8 +384,12 @@ func (w *worker) constructAndSubmitNewBlock(ctx context.Context) {
 		if w.fullTaskHook != nil {
 			w.fullTaskHook()
 		}
-		w.submitTaskToEngine(&task{receipts: b.receipts, state: b.state, block: block, createdAt: time.Now()})
-		baseFeeFn, toCELO := createConversionFunctions(b.sysCtx, w.chain, b.header, b.state)
+		if err := p.seal(); err != nil {
+			log.Debug("Skipped sealing of stale block", "number", p.block.Number(), "err", err)
+			return
+		}
+		b, block := p.b, p.block
+		baseFeeFn, toCELO := core.CreateConversionFunctions(b.sysCtx, w.runnerFactory, b.header, b.state)
 		feesCelo := totalFees(block, b.receipts, baseFeeFn, toCELO, w.chainConfig.IsEspresso(b.header.Number))
 		log.Info("Commit new mining work", "number", block.Number(), "txs", b.tcount, "gas", block.GasUsed(),
 			"fees", feesCelo, "elapsed", common.PrettyDuration(time.Since(start)))
@@ -316,34 +399,20 @@ func (w *worker) constructAndSubmitNewBlock(ctx context.Context) {
 
 // constructPendingStateBlock constructs a new block and keeps applying new transactions to it.
 // until it is full or the context is cancelled.
-func (w *worker) constructPendingStateBlock(ctx context.Context, txsCh chan core.NewTxsEvent) {
+func (w *worker) constructPendingStateBlock(ctx context.Context, gen uint32, txsCh chan core.NewTxsEvent) {
+	p := newBlockPipeline(ctx, w, gen)
+	defer p.close()
+
 	// Initialize the block.
-	b, err := prepareBlock(w)
-	defer func() {
-		if b != nil {
-			b.close()
-		}
-	}()
-	if err != nil {
+	if err := p.prepare(); err != nil {
 		log.Error("Failed to create mining context", "err", err)
 		return
 	}
-	w.updatePendingBlock(b)
-
-	err = b.selectAndApplyTransactions(ctx, w)
-	if err != nil {
+	if err := p.fill(); err != nil {
 		log.Error("Failed to apply transactions to the block", "err", err)
 		return
 	}
-	w.updatePendingBlock(b)
-
-	w.mu.RLock()
-	txFeeRecipient := w.txFeeRecipient
-	if !w.chainConfig.IsDonut(b.header.Number) && w.txFeeRecipient != w.validator {
-		txFeeRecipient = w.validator
-		log.Warn("TxFeeRecipient and Validator flags set before split etherbase fork is active. Defaulting to the given validator address for the coinbase.")
-	}
-	w.mu.RUnlock()
+	b := p.b
 
 	for {
 		select {
@@ -362,14 +431,14 @@ func (w *worker) constructPendingStateBlock(ctx context.Context, txsCh chan core
 					txs[acc] = append(txs[acc], tx)
 				}
 
-				baseFeeFn, toCElOFn := createConversionFunctions(b.sysCtx, w.chain, b.header, b.state)
+				baseFeeFn, toCElOFn := core.CreateConversionFunctions(b.sysCtx, w.runnerFactory, b.header, b.state)
 				txset := types.NewTransactionsByPriceAndNonce(b.signer, txs, baseFeeFn, toCElOFn)
 				tcount := b.tcount
-				b.commitTransactions(ctx, w, txset, txFeeRecipient)
+				b.commitTransactions(ctx, w, txset, b.txFeeRecipient)
 				// Only update the snapshot if any new transactons were added
 				// to the pending block
 				if tcount != b.tcount {
-					w.updatePendingBlock(b)
+					p.publish()
 				}
 			}
 		}
@@ -386,6 +455,8 @@ func (w *worker) mainLoop() {
 	// because go struggles with analyzing lexical scoping.
 	var taskCtx context.Context
 	var cancel context.CancelFunc
+	// wg tracks the block construction. A cancelled one is waited for before the
+	// next one starts, its prepare and finalize stages return as soon as it is.
 	var wg sync.WaitGroup
 
 	// Ensure that block construction is complete before exiting this function.
@@ -397,7 +468,9 @@ func (w *worker) mainLoop() {
 			cancel()
 		}
 		wg.Wait()
+		w.versionCheck.check(w.chain.CurrentHeader(), w)
 		taskCtx, cancel = context.WithCancel(context.Background())
+		gen := w.nextGeneration()
 		wg.Add(1)
 
 		if w.isRunning() {
@@ -406,15 +479,15 @@ func (w *worker) mainLoop() {
 				h.NewWork()
 			}
 
-			go func() {
-				w.constructAndSubmitNewBlock(taskCtx)
+			go func(ctx context.Context) {
+				w.constructAndSubmitNewBlock(ctx, gen)
 				wg.Done()
-			}()
+			}(taskCtx)
 		} else {
-			go func() {
-				w.constructPendingStateBlock(taskCtx, txsCh)
+			go func(ctx context.Context) {
+				w.constructPendingStateBlock(ctx, gen, txsCh)
 				wg.Done()
-			}()
+			}(taskCtx)
 		}
 	}
 
@@ -465,13 +538,39 @@ func (w *worker) submitTaskToEngine(task *task) {
 	}
 	if err := w.engine.Seal(w.chain, task.block); err != nil {
 		log.Warn("Block sealing failed", "err", err)
+		return
 	}
+	w.postTxStage(core.TxStageProposed, task.block)
 }
 
-// updatePendingBlock updates pending snapshot block and state.
-func (w *worker) updatePendingBlock(b *blockState) {
+// postTxStage posts the lifecycle event of the transactions of a block.
+func (w *worker) postTxStage(stage core.TxStage, block *types.Block) {
+	txs := block.Transactions()
+	if len(txs) == 0 {
+		return
+	}
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	w.stageFeed.Send(core.TxLifecycleEvent{Stage: stage, Txs: hashes, Block: block})
+}
+
+// nextGeneration starts a new generation of block pipelines, after which the
+// pending block published by the older ones is ignored.
+func (w *worker) nextGeneration() uint32 {
+	return atomic.AddUint32(&w.generation, 1)
+}
+
+// updatePendingBlock updates pending snapshot block and state, unless the block
+// was constructed by a pipeline older than the latest started one. It returns
+// the new pending block, nil if it wasn't updated.
+func (w *worker) updatePendingBlock(gen uint32, b *blockState) *types.Block {
 	w.snapshotMu.Lock()
 	defer w.snapshotMu.Unlock()
+	if gen != atomic.LoadUint32(&w.generation) {
+		return nil
+	}
 
 	w.snapshotBlock = types.NewBlock(
 		b.header,
@@ -481,5 +580,5 @@ func (w *worker) updatePendingBlock(b *blockState) {
 		trie.NewStackTrie(nil),
 	)
 	w.snapshotState = b.state.Copy()
-
+	return w.snapshotBlock
 }
@@ -17,30 +17,46 @@
 package miner
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math"
 	"math/big"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/celo-org/celo-blockchain/accounts"
 	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/common/hexutil"
 	"github.com/celo-org/celo-blockchain/consensus"
 	"github.com/celo-org/celo-blockchain/consensus/consensustest"
 	mockEngine "github.com/celo-org/celo-blockchain/consensus/consensustest"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul"
 	"github.com/celo-org/celo-blockchain/consensus/istanbul/backend"
 	istanbulBackend "github.com/celo-org/celo-blockchain/consensus/istanbul/backend"
+	"github.com/celo-org/celo-blockchain/contracts/config"
+	"github.com/celo-org/celo-blockchain/contracts/testutil"
 	"github.com/celo-org/celo-blockchain/core"
 	"github.com/celo-org/celo-blockchain/core/rawdb"
 	"github.com/celo-org/celo-blockchain/core/state"
 	"github.com/celo-org/celo-blockchain/core/types"
 	"github.com/celo-org/celo-blockchain/core/vm"
 	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/crypto/atrest"
 	blscrypto "github.com/celo-org/celo-blockchain/crypto/bls"
 	"github.com/celo-org/celo-blockchain/crypto/ecies"
 	"github.com/celo-org/celo-blockchain/ethdb"
 	"github.com/celo-org/celo-blockchain/event"
 	"github.com/celo-org/celo-blockchain/params"
+	"github.com/celo-org/celo-blockchain/rlp"
+	"github.com/celo-org/celo-blockchain/rpc"
+	"github.com/celo-org/celo-blockchain/trie"
 )
 
 const (
@@ -415,3 +431,1087 @@ func testNoTxChDeadlock(t *testing.T, validating bool) {
 		t.Error("Deadlock in mainLoop's select statement")
 	}
 }
+
+// slowPrepareEngine blocks in Prepare until released, like the Istanbul engine
+// waiting for the block time and the late parent commits.
+type slowPrepareEngine struct {
+	consensus.Engine
+	entered     chan struct{}
+	enteredOnce sync.Once
+	release     chan struct{}
+}
+
+func (e *slowPrepareEngine) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	e.enteredOnce.Do(func() { close(e.entered) })
+	<-e.release
+	return e.Engine.Prepare(chain, header)
+}
+
+func TestPrepareDoesNotBlockWorkerUpdates(t *testing.T) {
+	backend := newTestWorkerBackend(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	engine := &slowPrepareEngine{Engine: mockEngine.NewFaker(), entered: make(chan struct{}), release: make(chan struct{})}
+	w := newWorker(testConfig, params.IstanbulTestChainConfig, engine, backend, new(event.TypeMux), backend.db)
+	defer w.close()
+	defer close(engine.release)
+
+	go func() {
+		if b, err := prepareBlock(context.Background(), w); err == nil {
+			b.close()
+		}
+	}()
+	select {
+	case <-engine.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending block not prepared")
+	}
+	done := make(chan struct{})
+	go func() {
+		w.setExtra([]byte("updated"))
+		w.setValidator(testBankAddress)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker updates blocked by the header preparation")
+	}
+}
+
+func TestStopProposingOnOutdatedVersion(t *testing.T) {
+	b := newTestWorkerBackend(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	w := newWorker(&Config{StopOnOutdatedVersion: true}, params.IstanbulTestChainConfig, mockEngine.NewFaker(), b, new(event.TypeMux), b.db)
+	defer w.close()
+	w.setTxFeeRecipient(testBankAddress)
+	w.setValidator(testBankAddress)
+
+	// Without BlockchainParameters deployed there is no minimum version to enforce
+	w.versionCheck.check(b.chain.CurrentHeader(), w)
+	if w.versionCheck.isOutdated() {
+		t.Fatal("client should not be outdated without a minimum client version")
+	}
+
+	atomic.StoreInt32(&w.versionCheck.outdated, 1)
+	atomic.StoreInt32(&w.running, 1)
+	w.newTaskHook = func(*task) { t.Error("outdated client should not submit a task") }
+	w.constructAndSubmitNewBlock(context.Background(), 0)
+}
+
+func TestStalePipelineIsAborted(t *testing.T) {
+	engine := mockEngine.NewFaker()
+	w, _ := newTestWorker(t, params.IstanbulTestChainConfig, engine, rawdb.NewMemoryDatabase(), 0, false)
+	defer w.close()
+
+	// Start a pipeline, then pretend a newer one was started
+	pending := w.pendingBlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	p := newBlockPipeline(ctx, w, w.nextGeneration())
+	w.nextGeneration()
+	defer p.close()
+	if err := p.prepare(); err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	if w.pendingBlock() != pending {
+		t.Error("stale pipeline published its pending block")
+	}
+
+	cancel()
+	if err := p.fill(); err != errBlockAborted {
+		t.Errorf("fill of cancelled pipeline: have %v, want %v", err, errBlockAborted)
+	}
+	w.newTaskHook = func(*task) { t.Error("cancelled pipeline should not submit a task") }
+	if err := p.seal(); err != errBlockAborted {
+		t.Errorf("seal of cancelled pipeline: have %v, want %v", err, errBlockAborted)
+	}
+}
+
+// ctxPrepareEngine blocks in PrepareContext until the context is done, and tracks
+// how many preparations run at once.
+type ctxPrepareEngine struct {
+	consensus.Engine
+	entered chan error // Receives the result of the previous preparation on every entry
+	active  int32
+	maxSeen int32
+	last    error
+}
+
+func (e *ctxPrepareEngine) PrepareContext(ctx context.Context, chain consensus.ChainHeaderReader, header *types.Header) error {
+	active := atomic.AddInt32(&e.active, 1)
+	defer atomic.AddInt32(&e.active, -1)
+	if active > atomic.LoadInt32(&e.maxSeen) {
+		atomic.StoreInt32(&e.maxSeen, active)
+	}
+	e.entered <- e.last
+	<-ctx.Done()
+	e.last = ctx.Err()
+	return e.last
+}
+
+func TestCancelledPipelineIsAwaited(t *testing.T) {
+	backend := newTestWorkerBackend(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	engine := &ctxPrepareEngine{Engine: mockEngine.NewFaker(), entered: make(chan error, 1)}
+	w := newWorker(testConfig, params.IstanbulTestChainConfig, engine, backend, new(event.TypeMux), backend.db)
+	defer w.close()
+
+	enter := func() error {
+		select {
+		case err := <-engine.entered:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("block not prepared")
+			return nil
+		}
+	}
+	w.startCh <- struct{}{}
+	enter()
+
+	// The new head cancels the waiting preparation, which exits before the next one starts
+	w.chainHeadCh <- core.ChainHeadEvent{}
+	if err := enter(); err != context.Canceled {
+		t.Errorf("cancelled preparation: have %v, want %v", err, context.Canceled)
+	}
+	if max := atomic.LoadInt32(&engine.maxSeen); max != 1 {
+		t.Errorf("concurrent preparations: have %d, want 1", max)
+	}
+}
+
+// headerAt returns a header of the given number on top of the chain of the worker.
+func headerAt(w *worker, number uint64) *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(number), ParentHash: w.chain.GetHeaderByNumber(number - 1).Hash()}
+}
+
+func TestTxFeeRecipientSchedule(t *testing.T) {
+	var (
+		fallback = common.HexToAddress("0x02")
+		first    = common.HexToAddress("0x03")
+		second   = common.HexToAddress("0x04")
+	)
+	// All the blocks of the chain are sealed by the validator, testBankAddress
+	w, _ := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 24, false)
+	defer w.close()
+	// Only the worker sees the late donut fork, the test chain is already built
+	w.chainConfig = w.chainConfig.DeepCopy()
+	w.chainConfig.DonutBlock = big.NewInt(10)
+	w.setTxFeeRecipient(fallback)
+
+	// Schedules are refused if they pay anyone but the validator before the donut fork
+	if err := w.setTxFeeRecipientSchedule(&TxFeeRecipientSchedule{Start: 5, Period: 2, Recipients: []common.Address{first, second}}); err != errScheduleBeforeDonut {
+		t.Fatalf("pre donut schedule error mismatch: have %v, want %v", err, errScheduleBeforeDonut)
+	}
+	if err := w.setTxFeeRecipientSchedule(&TxFeeRecipientSchedule{Start: 20, Recipients: []common.Address{first, second}}); err != errScheduleNoPeriod {
+		t.Fatalf("periodless schedule error mismatch: have %v, want %v", err, errScheduleNoPeriod)
+	}
+	if err := w.setTxFeeRecipientSchedule(&TxFeeRecipientSchedule{Start: 20, Period: 2, Recipients: []common.Address{first, second}}); err != nil {
+		t.Fatalf("failed to set schedule: %v", err)
+	}
+	for _, tt := range []struct {
+		number uint64
+		want   common.Address
+	}{
+		{5, testBankAddress}, {15, fallback}, {20, first}, {21, first}, {22, second}, {23, second}, {24, first},
+	} {
+		if have := w.txFeeRecipientAt(headerAt(w, tt.number)); have != tt.want {
+			t.Errorf("block %d: tx fee recipient mismatch: have %x, want %x", tt.number, have, tt.want)
+		}
+	}
+
+	// An empty schedule removes it
+	if err := w.setTxFeeRecipientSchedule(&TxFeeRecipientSchedule{}); err != nil {
+		t.Fatalf("failed to remove schedule: %v", err)
+	}
+	if have := w.txFeeRecipientAt(headerAt(w, 22)); have != fallback {
+		t.Errorf("tx fee recipient mismatch after removal: have %x, want %x", have, fallback)
+	}
+}
+
+func TestWeightedTxFeeRecipientSchedule(t *testing.T) {
+	var (
+		operator  = common.HexToAddress("0x01")
+		community = common.HexToAddress("0x02")
+	)
+	w, _ := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 6, false)
+	defer w.close()
+
+	if err := w.setTxFeeRecipientSchedule(&TxFeeRecipientSchedule{Period: 1, Recipients: []common.Address{operator, community}, Weights: []uint64{80}}); err != errScheduleWeights {
+		t.Fatalf("mismatched weights error mismatch: have %v, want %v", err, errScheduleWeights)
+	}
+	if err := w.setTxFeeRecipientSchedule(&TxFeeRecipientSchedule{Period: 1, Recipients: []common.Address{operator, community}, Weights: []uint64{80, 0}}); err != errScheduleWeights {
+		t.Fatalf("zero weight error mismatch: have %v, want %v", err, errScheduleWeights)
+	}
+	// An 80/20 split pays four blocks out of five to the operator
+	if err := w.setTxFeeRecipientSchedule(&TxFeeRecipientSchedule{Start: 1, Period: 1, Recipients: []common.Address{operator, community}, Weights: []uint64{80, 20}}); err != nil {
+		t.Fatalf("failed to set schedule: %v", err)
+	}
+	for i, want := range []common.Address{operator, operator, operator, operator, community, operator} {
+		number := uint64(i + 1)
+		if have := w.txFeeRecipientAt(headerAt(w, number)); have != want {
+			t.Errorf("block %d: tx fee recipient mismatch: have %x, want %x", number, have, want)
+		}
+	}
+}
+
+// Tests that the weights of the recipients hold when the validator seals every nth
+// block, n being a multiple of the total weight, which would always land on the
+// same recipient if the schedule rotated over the block numbers.
+func TestTxFeeRecipientScheduleStride(t *testing.T) {
+	var (
+		operator  = common.HexToAddress("0x01")
+		community = common.HexToAddress("0x02")
+		other     = common.HexToAddress("0x05")
+		stride    = 10
+		turns     = 20
+	)
+	engine := mockEngine.NewFaker()
+	db := rawdb.NewMemoryDatabase()
+	w, b := newTestWorker(t, params.IstanbulTestChainConfig, engine, db, 0, false)
+	defer w.close()
+
+	// The mock engine's author of a block is its coinbase
+	blocks, _ := core.GenerateChain(params.IstanbulTestChainConfig, b.chain.Genesis(), engine, db, stride*turns, func(i int, gen *core.BlockGen) {
+		if number := i + 1; number%stride == 0 {
+			gen.SetCoinbase(testBankAddress)
+		} else {
+			gen.SetCoinbase(other)
+		}
+	})
+	if _, err := b.chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if err := w.setTxFeeRecipientSchedule(&TxFeeRecipientSchedule{Period: 1, Recipients: []common.Address{operator, community}, Weights: []uint64{80, 20}}); err != nil {
+		t.Fatalf("failed to set schedule: %v", err)
+	}
+	recipients := func(numbers []uint64) map[uint64]common.Address {
+		have := make(map[uint64]common.Address)
+		for _, number := range numbers {
+			have[number] = w.txFeeRecipientAt(headerAt(w, number))
+		}
+		return have
+	}
+	// The turns of the validator, counted from the previous one, and rebuilt from
+	// the chain for every block when looked up backwards
+	var sealed, backwards []uint64
+	for number := stride; number <= stride*turns; number += stride {
+		sealed = append(sealed, uint64(number))
+		backwards = append([]uint64{uint64(number)}, backwards...)
+	}
+	have := recipients(sealed)
+	if rebuilt := recipients(backwards); !reflect.DeepEqual(have, rebuilt) {
+		t.Errorf("rebuilt tx fee recipients mismatch: have %v, want %v", rebuilt, have)
+	}
+	shares := make(map[common.Address]int)
+	for _, recipient := range have {
+		shares[recipient]++
+	}
+	if shares[operator] != turns*4/5 || shares[community] != turns/5 {
+		t.Errorf("tx fee recipient shares mismatch: have %d/%d, want %d/%d", shares[operator], shares[community], turns*4/5, turns/5)
+	}
+}
+
+func TestSetFeeCurrencyLimits(t *testing.T) {
+	currency := common.HexToAddress("0x765de816845861e75a25fca122bb6898b8b1282a")
+	w, _ := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0, false)
+	defer w.close()
+
+	if err := w.setFeeCurrencyLimits(1.5, nil); !errors.Is(err, errInvalidFeeCurrencyLimit) {
+		t.Fatalf("invalid default limit error mismatch: have %v, want %v", err, errInvalidFeeCurrencyLimit)
+	}
+	if err := w.setFeeCurrencyLimits(0.5, map[common.Address]float64{currency: -0.1}); !errors.Is(err, errInvalidFeeCurrencyLimit) {
+		t.Fatalf("invalid currency limit error mismatch: have %v, want %v", err, errInvalidFeeCurrencyLimit)
+	}
+	limits := map[common.Address]float64{currency: 0.2}
+	if err := w.setFeeCurrencyLimits(0.5, limits); err != nil {
+		t.Fatalf("failed to set limits: %v", err)
+	}
+	// The worker must not share the map of the caller
+	limits[currency] = 0.9
+	defaultLimit, have := w.feeCurrencyLimitsConfig()
+	if defaultLimit != 0.5 || len(have) != 1 || have[currency] != 0.2 {
+		t.Fatalf("limits mismatch: have %v %v, want %v %v", defaultLimit, have, 0.5, map[common.Address]float64{currency: 0.2})
+	}
+}
+
+func TestGasLimitOverride(t *testing.T) {
+	backend := newTestWorkerBackend(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	w := newWorker(&Config{GasLimitOverride: 100000}, params.IstanbulTestChainConfig, mockEngine.NewFaker(), backend, new(event.TypeMux), backend.db)
+	defer w.close()
+	w.setTxFeeRecipient(testBankAddress)
+	w.setValidator(testBankAddress)
+
+	gasLimit := func() uint64 {
+		t.Helper()
+		b, err := prepareBlock(context.Background(), w)
+		if err != nil {
+			t.Fatalf("failed to prepare block: %v", err)
+		}
+		defer b.close()
+		return b.gasLimit
+	}
+	if have := gasLimit(); have != 100000 {
+		t.Errorf("pending block gas limit mismatch: have %d, want %d", have, 100000)
+	}
+	// A cap above the on chain gas limit leaves it unchanged
+	w.setGasLimitOverride(math.MaxUint64)
+	onChain := gasLimit()
+	if onChain == math.MaxUint64 {
+		t.Fatalf("gas limit not capped by the on chain one")
+	}
+	// The blocks built while validating always use the on chain gas limit
+	w.setGasLimitOverride(100000)
+	atomic.StoreInt32(&w.running, 1)
+	defer atomic.StoreInt32(&w.running, 0)
+	if have := gasLimit(); have != onChain {
+		t.Errorf("validator block gas limit mismatch: have %d, want %d", have, onChain)
+	}
+}
+
+func TestRecommitBudgetSealsPartialBlock(t *testing.T) {
+	backend := newTestWorkerBackend(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	backend.txPool.AddLocals(pendingTxs)
+	w := newWorker(&Config{RecommitBudget: time.Nanosecond}, params.IstanbulTestChainConfig, mockEngine.NewFaker(), backend, new(event.TypeMux), backend.db)
+	defer w.close()
+	w.setTxFeeRecipient(testBankAddress)
+	w.setValidator(testBankAddress)
+
+	b, err := prepareBlock(context.Background(), w)
+	if err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	defer b.close()
+	if err := b.selectAndApplyTransactions(context.Background(), w); err != nil {
+		t.Fatalf("failed to apply transactions: %v", err)
+	}
+	if !b.truncated || b.tcount != 0 {
+		t.Errorf("exhausted budget: have truncated %v with %d txs, want truncated with 0 txs", b.truncated, b.tcount)
+	}
+	if _, err := b.finalizeAndAssemble(context.Background(), w); err != nil {
+		t.Fatalf("failed to assemble partially filled block: %v", err)
+	}
+}
+
+func TestBundleInclusion(t *testing.T) {
+	w, b := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0, false)
+	defer w.close()
+
+	signer := types.LatestSigner(params.IstanbulTestChainConfig)
+	transfer := func(nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(params.InitialBaseFee),
+		})
+	}
+	number := b.chain.CurrentBlock().NumberU64() + 1
+
+	// Bundles are validated on submission
+	if err := w.bundles.add(&Bundle{BlockNumber: number}, number-1); err != errBundleEmpty {
+		t.Fatalf("empty bundle error mismatch: have %v, want %v", err, errBundleEmpty)
+	}
+	if err := w.bundles.add(&Bundle{Txs: types.Transactions{transfer(0)}, BlockNumber: number - 1}, number-1); err != errBundleStale {
+		t.Fatalf("stale bundle error mismatch: have %v, want %v", err, errBundleStale)
+	}
+	// The second bundle has a nonce gap, none of its transactions may be included
+	var (
+		broken = &Bundle{Txs: types.Transactions{transfer(0), transfer(2)}, BlockNumber: number}
+		valid  = &Bundle{Txs: types.Transactions{transfer(0), transfer(1)}, BlockNumber: number}
+	)
+	for _, bundle := range []*Bundle{broken, valid} {
+		if err := w.bundles.add(bundle, number-1); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	if err := w.bundles.add(valid, number-1); err != errBundleKnown {
+		t.Fatalf("duplicate bundle error mismatch: have %v, want %v", err, errBundleKnown)
+	}
+
+	block, err := prepareBlock(context.Background(), w)
+	if err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	defer block.close()
+	if err := block.selectAndApplyTransactions(context.Background(), w); err != nil {
+		t.Fatalf("failed to apply transactions: %v", err)
+	}
+	if len(block.txs) != 2 || block.txs[0].Hash() != valid.Txs[0].Hash() || block.txs[1].Hash() != valid.Txs[1].Hash() {
+		t.Fatalf("included transactions mismatch: have %d txs, want the valid bundle", len(block.txs))
+	}
+	if have, want := block.header.GasUsed, 2*params.TxGas; have != want {
+		t.Errorf("gas used mismatch: have %d, want %d", have, want)
+	}
+	if have := block.state.GetNonce(testBankAddress); have != 2 {
+		t.Errorf("sender nonce mismatch: have %d, want %d", have, 2)
+	}
+	// Bundles for past blocks are pruned
+	if bundles := w.bundles.bundlesFor(number+1, 0); len(bundles) != 0 || len(w.bundles.bundles) != 0 {
+		t.Errorf("stale bundles not pruned: %d left", len(w.bundles.bundles))
+	}
+}
+
+func TestParallelLanesMatchSerialExecution(t *testing.T) {
+	w, _ := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0, false)
+	defer w.close()
+
+	var (
+		signer   = types.LatestSigner(params.IstanbulTestChainConfig)
+		receiver = common.Address{0x01}
+	)
+	transfer := func(key *ecdsa.PrivateKey, nonce uint64, to common.Address, value *big.Int) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &to,
+			Value:    value,
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(params.InitialBaseFee),
+		})
+	}
+	var (
+		fund = types.Transactions{transfer(testBankKey, 0, testUserAddress, big.NewInt(1e17))}
+		bank = types.Transactions{transfer(testBankKey, 1, receiver, big.NewInt(1000)), transfer(testBankKey, 2, receiver, big.NewInt(1000))}
+		user = types.Transactions{transfer(testUserKey, 0, receiver, big.NewInt(1000))}
+	)
+	commit := func(b *blockState, from common.Address, txs types.Transactions) {
+		baseFeeFn, toCELOFn := core.CreateConversionFunctions(b.sysCtx, w.runnerFactory, b.header, b.state)
+		ordered := types.NewTransactionsByPriceAndNonce(b.signer, map[common.Address]types.Transactions{from: txs}, baseFeeFn, toCELOFn)
+		if err := b.commitTransactions(context.Background(), w, ordered, b.txFeeRecipient); err != nil {
+			t.Fatalf("failed to commit transactions: %v", err)
+		}
+	}
+	serial, err := prepareBlock(context.Background(), w)
+	if err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	defer serial.close()
+	commit(serial, testBankAddress, fund)
+	commit(serial, testBankAddress, bank)
+	commit(serial, testUserAddress, user)
+
+	parallel, err := prepareBlock(context.Background(), w)
+	if err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	defer parallel.close()
+	commit(parallel, testBankAddress, fund)
+
+	// Both lanes credit the fee recipient, so the second one must be left out
+	bankLane, userLane := newFeeCurrencyLane(nil), newFeeCurrencyLane(nil)
+	bankLane.add(testBankAddress, bank)
+	userLane.add(testUserAddress, user)
+	pending := map[common.Address]types.Transactions{testBankAddress: bank, testUserAddress: user}
+
+	leftover, err := parallel.applyLanes(context.Background(), w, []*feeCurrencyLane{bankLane, userLane}, pending)
+	if err != nil {
+		t.Fatalf("failed to apply lanes: %v", err)
+	}
+	if len(parallel.txs) != 3 || len(leftover) != 1 || len(leftover[testUserAddress]) != 1 {
+		t.Fatalf("merged lanes mismatch: have %d txs and %d accounts left, want 3 txs and the conflicting account left", len(parallel.txs), len(leftover))
+	}
+	commit(parallel, testUserAddress, leftover[testUserAddress])
+
+	if have, want := parallel.state.IntermediateRoot(true), serial.state.IntermediateRoot(true); have != want {
+		t.Errorf("state root mismatch: have %x, want %x", have, want)
+	}
+	if have, want := parallel.header.GasUsed, serial.header.GasUsed; have != want {
+		t.Errorf("gas used mismatch: have %d, want %d", have, want)
+	}
+	for i, receipt := range parallel.receipts {
+		if want := serial.receipts[i]; receipt.TxHash != want.TxHash || receipt.TransactionIndex != want.TransactionIndex || receipt.CumulativeGasUsed != want.CumulativeGasUsed {
+			t.Errorf("receipt %d mismatch: have index %d and cumulative gas %d, want %d and %d", i, receipt.TransactionIndex, receipt.CumulativeGasUsed, want.TransactionIndex, want.CumulativeGasUsed)
+		}
+	}
+}
+
+// Tests that the lanes applied concurrently keep their rejections, denylist and
+// skips to themselves, and that these reach the block and the worker once.
+func TestParallelLanesState(t *testing.T) {
+	w, _ := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0, false)
+	defer w.close()
+
+	var (
+		signer           = types.LatestSigner(params.IstanbulTestChainConfig)
+		receiver         = common.Address{0x01}
+		denied           = common.Address{0x02}
+		oversizedKey, _  = crypto.GenerateKey()
+		oversizedAddress = crypto.PubkeyToAddress(oversizedKey.PublicKey)
+	)
+	transfer := func(key *ecdsa.PrivateKey, nonce uint64, to common.Address, gas uint64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &to,
+			Value:    big.NewInt(1000),
+			Gas:      gas,
+			GasPrice: big.NewInt(params.InitialBaseFee),
+		})
+	}
+	block, err := prepareBlock(context.Background(), w)
+	if err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	defer block.close()
+
+	var (
+		bank      = types.Transactions{transfer(testBankKey, 0, receiver, params.TxGas)}
+		user      = types.Transactions{transfer(testUserKey, 0, denied, params.TxGas)}
+		oversized = types.Transactions{transfer(oversizedKey, 0, receiver, block.gasLimit+1)}
+		pending   = map[common.Address]types.Transactions{testBankAddress: bank, testUserAddress: user, oversizedAddress: oversized}
+		lanes     []*feeCurrencyLane
+	)
+	for from, txs := range pending {
+		lane := newFeeCurrencyLane(nil)
+		lane.add(from, txs)
+		// Don't let the oversized transaction take the share of the other lanes
+		lane.gas = params.TxGas
+		lanes = append(lanes, lane)
+	}
+	w.denylist.set(&Denylist{Addresses: []common.Address{denied}})
+
+	// Replacing the denylist while the lanes run doesn't affect them
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			w.denylist.set(&Denylist{Addresses: []common.Address{denied}})
+		}
+	}()
+	leftover, err := block.applyLanes(context.Background(), w, lanes, pending)
+	<-done
+	if err != nil {
+		t.Fatalf("failed to apply lanes: %v", err)
+	}
+	if _, ok := w.rejected.reason(block.header.ParentHash, oversized[0].Hash()); !ok {
+		t.Error("oversized transaction not rejected")
+	}
+	if _, ok := leftover[testUserAddress]; ok {
+		t.Error("denylisted sender left to the serial pass")
+	}
+	// The serial pass counts whatever the merged lanes didn't
+	for from, txs := range leftover {
+		baseFeeFn, toCELOFn := core.CreateConversionFunctions(block.sysCtx, w.runnerFactory, block.header, block.state)
+		ordered := types.NewTransactionsByPriceAndNonce(block.signer, map[common.Address]types.Transactions{from: txs}, baseFeeFn, toCELOFn)
+		if err := block.commitTransactions(context.Background(), w, ordered, block.txFeeRecipient); err != nil {
+			t.Fatalf("failed to commit transactions: %v", err)
+		}
+	}
+	if len(block.txs) != 1 || block.txs[0].Hash() != bank[0].Hash() {
+		t.Errorf("included transactions mismatch: have %d txs, want the bank transfer", len(block.txs))
+	}
+	if have := block.skipped[skipDenylisted]; have != 1 {
+		t.Errorf("denylisted skips mismatch: have %d, want 1", have)
+	}
+	if have := block.skipped[skipFeeCurrencyGasLimit]; have != 1 {
+		t.Errorf("fee currency gas limit skips mismatch: have %d, want 1", have)
+	}
+}
+
+func TestBuildStatus(t *testing.T) {
+	w, b := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0, true)
+	defer w.close()
+
+	if status := w.build.snapshot(); status != nil {
+		t.Fatalf("status reported before any block: %+v", status)
+	}
+	// A bundle with a nonce gap is dropped and reported as skipped
+	signer := types.LatestSigner(params.IstanbulTestChainConfig)
+	gapped := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+		Nonce:    5,
+		To:       &testUserAddress,
+		Gas:      params.TxGas,
+		GasPrice: big.NewInt(params.InitialBaseFee),
+	})
+	number := b.chain.CurrentBlock().NumberU64() + 1
+	if err := w.bundles.add(&Bundle{Txs: types.Transactions{gapped}, BlockNumber: number}, number-1); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	p := newBlockPipeline(context.Background(), w, atomic.AddUint32(&w.generation, 1))
+	if err := p.prepare(); err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	if err := p.fill(); err != nil {
+		t.Fatalf("failed to fill block: %v", err)
+	}
+	status := w.build.snapshot()
+	if status == nil || status.Number != number || status.Stage != stageFill.String() || status.Done {
+		t.Fatalf("in-flight status mismatch: %+v", status)
+	}
+	if status.TxCount != len(pendingTxs) || status.Skipped[skipBundleDropped] != 1 {
+		t.Errorf("fill status mismatch: have %d txs and skips %v, want %d txs and a dropped bundle", status.TxCount, status.Skipped, len(pendingTxs))
+	}
+	if have, want := status.GasRemaining, status.GasLimit-p.b.header.GasUsed; have != want {
+		t.Errorf("remaining gas mismatch: have %d, want %d", have, want)
+	}
+	if status.CeloGasRemaining != status.GasLimit-p.b.header.GasUsed {
+		t.Errorf("remaining celo gas mismatch: have %d, want %d", status.CeloGasRemaining, status.GasLimit-p.b.header.GasUsed)
+	}
+	p.close()
+	if status := w.build.snapshot(); !status.Done {
+		t.Errorf("closed block not reported done")
+	}
+}
+
+func TestLocalGasQuotientReservesGas(t *testing.T) {
+	backend := newTestWorkerBackend(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	backend.txPool.AddLocals(pendingTxs)
+	w := newWorker(&Config{LocalGasQuotient: 1}, params.IstanbulTestChainConfig, mockEngine.NewFaker(), backend, new(event.TypeMux), backend.db)
+	defer w.close()
+	w.setTxFeeRecipient(testBankAddress)
+	w.setValidator(testBankAddress)
+
+	// The bundle can't use the gas reserved for the local transactions
+	number := backend.chain.CurrentBlock().NumberU64() + 1
+	signer := types.LatestSigner(params.IstanbulTestChainConfig)
+	bundled := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+		Nonce:    0,
+		To:       &testUserAddress,
+		Value:    big.NewInt(1),
+		Gas:      params.TxGas,
+		GasPrice: big.NewInt(params.InitialBaseFee),
+	})
+	if err := w.bundles.add(&Bundle{Txs: types.Transactions{bundled}, BlockNumber: number}, number-1); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	b, err := prepareBlock(context.Background(), w)
+	if err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	defer b.close()
+	if err := b.selectAndApplyTransactions(context.Background(), w); err != nil {
+		t.Fatalf("failed to apply transactions: %v", err)
+	}
+	if len(b.txs) != 1 || b.txs[0].Hash() != pendingTxs[0].Hash() || b.skipped[skipBundleDropped] != 1 {
+		t.Fatalf("included transactions mismatch: have %d txs and skips %v, want the local one only", len(b.txs), b.skipped)
+	}
+	if have, want := b.localGasReserve, b.gasLimit-b.header.GasUsed; have != want {
+		t.Errorf("remaining reserve mismatch: have %d, want %d", have, want)
+	}
+	if have, want := b.gasPool.Gas(), b.gasLimit-b.header.GasUsed; have != want {
+		t.Errorf("withheld gas not restored: have %d, want %d", have, want)
+	}
+}
+
+func TestRejectedTxsSkipped(t *testing.T) {
+	w, b := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0, true)
+	defer w.close()
+
+	// A transaction rejected on top of the head is skipped without being applied
+	head := b.chain.CurrentBlock().Hash()
+	w.rejected.add(head, pendingTxs[0].Hash(), skipBelowGasPriceMinimum)
+
+	block, err := prepareBlock(context.Background(), w)
+	if err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	defer block.close()
+	if err := block.selectAndApplyTransactions(context.Background(), w); err != nil {
+		t.Fatalf("failed to apply transactions: %v", err)
+	}
+	if block.tcount != 0 || block.skipped[skipBelowGasPriceMinimum] != 1 {
+		t.Errorf("rejected transaction mismatch: have %d txs and skips %v, want none included", block.tcount, block.skipped)
+	}
+	// Rejections are forgotten once building on another parent
+	w.rejected.add(common.Hash{1}, common.Hash{2}, skipFeeCurrencyGasLimit)
+	if _, ok := w.rejected.reason(head, pendingTxs[0].Hash()); ok {
+		t.Errorf("rejection kept across parents")
+	}
+	if reason, ok := w.rejected.reason(common.Hash{1}, common.Hash{2}); !ok || reason != skipFeeCurrencyGasLimit {
+		t.Errorf("rejection reason mismatch: have %q, want %q", reason, skipFeeCurrencyGasLimit)
+	}
+}
+
+func TestPendingBlockSubscription(t *testing.T) {
+	w, b := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0, true)
+	defer w.close()
+
+	blocks := make(chan *PendingBlock)
+	sub := w.subscribePendingBlock(blocks)
+
+	p := newBlockPipeline(context.Background(), w, atomic.AddUint32(&w.generation, 1))
+	defer p.close()
+	for _, stage := range []func() error{p.prepare, p.fill, p.finalize} {
+		if err := stage(); err != nil {
+			t.Fatalf("failed to build block: %v", err)
+		}
+	}
+	// Only the latest block is delivered to a subscriber lagging behind
+	var pending *PendingBlock
+	select {
+	case pending = <-blocks:
+	case <-time.After(time.Second):
+		t.Fatal("pending block not delivered")
+	}
+	if pending.Block.NumberU64() != b.chain.CurrentBlock().NumberU64()+1 || len(pending.Fees) != len(pendingTxs) {
+		t.Fatalf("pending block mismatch: number %d with %d fees", pending.Block.NumberU64(), len(pending.Fees))
+	}
+	for i, fee := range pending.Fees {
+		if fee.Hash != pendingTxs[i].Hash() || fee.FeeCurrency != nil || fee.GasUsed != params.TxGas {
+			t.Errorf("fee %d mismatch: %+v", i, fee)
+		}
+		// CELO tips convert to themselves
+		if fee.EffectiveTip == nil || fee.EffectiveTipInCelo == nil || fee.EffectiveTip.Cmp(fee.EffectiveTipInCelo) != 0 {
+			t.Errorf("fee %d tip mismatch: have %v CELO for %v", i, fee.EffectiveTipInCelo, fee.EffectiveTip)
+		}
+	}
+	if have, want := pending.CeloGasRemaining, pending.Block.GasLimit()-pending.Block.GasUsed(); have != want {
+		t.Errorf("remaining celo gas mismatch: have %d, want %d", have, want)
+	}
+	// The breakdown is no longer computed without subscribers
+	sub.Unsubscribe()
+	if subs := atomic.LoadInt32(&w.pendingBlockSubs); subs != 0 {
+		t.Errorf("subscribers left after unsubscribing: %d", subs)
+	}
+}
+
+// Tests that a dry run assembles the block the worker would, with its fees,
+// while the worker isn't mining, and without publishing it.
+func TestDryRunBlock(t *testing.T) {
+	w, b := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0, true)
+	defer w.close()
+
+	if w.isRunning() {
+		t.Fatal("worker running before the dry run")
+	}
+	head := b.chain.CurrentBlock()
+	result, err := w.dryRunBlock(context.Background())
+	if err != nil {
+		t.Fatalf("failed to dry run block: %v", err)
+	}
+	if result.Block.NumberU64() != head.NumberU64()+1 || result.Block.ParentHash() != head.Hash() {
+		t.Fatalf("dry run block mismatch: number %d, parent %x", result.Block.NumberU64(), result.Block.ParentHash())
+	}
+	if len(result.Block.Transactions()) != len(pendingTxs) || len(result.GasCostsInCelo) != len(pendingTxs) {
+		t.Fatalf("dry run transactions mismatch: have %d, want %d", len(result.Block.Transactions()), len(pendingTxs))
+	}
+	// CELO transactions pay their gas price, the tips are what's left above the base fee
+	var tips big.Int
+	for i, tx := range pendingTxs {
+		want := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(result.Receipts[i].GasUsed))
+		if have := result.GasCostsInCelo[i]; have == nil || have.Cmp(want) != 0 {
+			t.Errorf("transaction %d gas cost mismatch: have %v CELO, want %v", i, have, want)
+		}
+		tips.Add(&tips, new(big.Int).Mul(result.Fees[i].EffectiveTipInCelo, new(big.Int).SetUint64(result.Fees[i].GasUsed)))
+	}
+	wantFees := new(big.Float).Quo(new(big.Float).SetInt(&tips), new(big.Float).SetInt(big.NewInt(params.Ether)))
+	if result.TotalFees.Cmp(wantFees) != 0 {
+		t.Errorf("total fees mismatch: have %v, want %v", result.TotalFees, wantFees)
+	}
+	if b.chain.CurrentBlock().Hash() != head.Hash() {
+		t.Error("chain head moved by the dry run")
+	}
+}
+
+// Tests that denylisted transactions are left out of the blocks built, and that
+// the denylist can be replaced from its file.
+func TestDenylistedTxsSkipped(t *testing.T) {
+	w, _ := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0, true)
+	defer w.close()
+
+	build := func() *blockState {
+		block, err := prepareBlock(context.Background(), w)
+		if err != nil {
+			t.Fatalf("failed to prepare block: %v", err)
+		}
+		defer block.close()
+		if err := block.selectAndApplyTransactions(context.Background(), w); err != nil {
+			t.Fatalf("failed to apply transactions: %v", err)
+		}
+		return block
+	}
+	// Denying the recipient leaves out the transaction and the later ones of its sender
+	w.denylist.set(&Denylist{Addresses: []common.Address{testUserAddress}})
+	if block := build(); block.tcount != 0 || block.skipped[skipDenylisted] != 1 {
+		t.Errorf("denylisted recipient included: have %d txs and skips %v", block.tcount, block.skipped)
+	}
+	// The file replaces the list set by RPC
+	path := filepath.Join(t.TempDir(), "denylist.json")
+	if err := os.WriteFile(path, []byte(`{"addresses": [], "codeHashes": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	w.config.DenylistFile = path
+	if err := w.reloadDenylist(); err != nil {
+		t.Fatalf("failed to reload denylist: %v", err)
+	}
+	if block := build(); block.tcount != len(pendingTxs) || block.skipped[skipDenylisted] != 0 {
+		t.Errorf("transactions left out after clearing the denylist: have %d txs and skips %v", block.tcount, block.skipped)
+	}
+}
+
+func TestFeeCurrencyReservedGas(t *testing.T) {
+	backend := newTestWorkerBackend(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	signer := types.LatestSigner(params.IstanbulTestChainConfig)
+	tx := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+		Nonce:    0,
+		To:       &testUserAddress,
+		Value:    big.NewInt(1),
+		Gas:      10 * params.TxGas,
+		GasPrice: big.NewInt(params.InitialBaseFee),
+	})
+	backend.txPool.AddLocals(types.Transactions{tx})
+
+	build := func(config *Config) *blockState {
+		w := newWorker(config, params.IstanbulTestChainConfig, mockEngine.NewFaker(), backend, new(event.TypeMux), backend.db)
+		defer w.close()
+		w.setTxFeeRecipient(testBankAddress)
+		w.setValidator(testBankAddress)
+
+		block, err := prepareBlock(context.Background(), w)
+		if err != nil {
+			t.Fatalf("failed to prepare block: %v", err)
+		}
+		defer block.close()
+		if err := block.selectAndApplyTransactions(context.Background(), w); err != nil {
+			t.Fatalf("failed to apply transactions: %v", err)
+		}
+		if block.tcount != 1 {
+			t.Fatalf("included transactions mismatch: have %d, want 1", block.tcount)
+		}
+		return block
+	}
+	// By default the pool is charged the gas used, the unused reservation refunded
+	if block := build(&Config{}); block.multiGasPool.PoolFor(nil).Gas() != block.gasLimit-block.header.GasUsed {
+		t.Errorf("used gas accounting mismatch: have %d, want %d", block.multiGasPool.PoolFor(nil).Gas(), block.gasLimit-block.header.GasUsed)
+	}
+	// With reserved gas accounting the whole gas limit of the transaction is charged
+	if block := build(&Config{FeeCurrencyReservedGas: true}); block.multiGasPool.PoolFor(nil).Gas() != block.gasLimit-tx.Gas() {
+		t.Errorf("reserved gas accounting mismatch: have %d, want %d", block.multiGasPool.PoolFor(nil).Gas(), block.gasLimit-tx.Gas())
+	}
+}
+
+func TestForcedTxsAtTopOfBlock(t *testing.T) {
+	backend := newTestWorkerBackend(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	w := newWorker(&Config{}, params.IstanbulTestChainConfig, mockEngine.NewFaker(), backend, new(event.TypeMux), backend.db)
+	defer w.close()
+	w.setTxFeeRecipient(testBankAddress)
+	w.setValidator(testBankAddress)
+
+	build := func() *blockState {
+		block, err := prepareBlock(context.Background(), w)
+		if err != nil {
+			t.Fatalf("failed to prepare block: %v", err)
+		}
+		defer block.close()
+		if err := block.selectAndApplyTransactions(context.Background(), w); err != nil {
+			t.Fatalf("failed to apply transactions: %v", err)
+		}
+		return block
+	}
+	w.forced.set([]*ForcedTx{{From: testBankAddress, To: &testUserAddress, Gas: hexutil.Uint64(params.TxGas), OnFailure: ForcedTxDisable}})
+
+	// Without a signer the transaction can't be forced, and is disabled
+	if block := build(); block.tcount != 0 || block.skipped[skipForcedTxFailed] != 1 {
+		t.Fatalf("unsigned forced transaction mismatch: have %d txs and skips %v", block.tcount, block.skipped)
+	}
+	if forced, _ := w.forced.active(); len(forced) != 0 {
+		t.Fatalf("failed forced transaction not disabled")
+	}
+	// Once signed, it's placed at the top of the block with the next nonce
+	w.forced.set([]*ForcedTx{{From: testBankAddress, To: &testUserAddress, Gas: hexutil.Uint64(params.TxGas)}})
+	w.forced.setSigner(func(from common.Address, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+		return types.SignTx(tx, types.LatestSignerForChainID(chainID), testBankKey)
+	})
+	block := build()
+	if block.tcount != 1 || block.receipts[0].Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("forced transaction not included: have %d txs and skips %v", block.tcount, block.skipped)
+	}
+	if from, _ := types.Sender(block.signer, block.txs[0]); from != testBankAddress || block.txs[0].Nonce() != 0 {
+		t.Errorf("forced transaction mismatch: have sender %x and nonce %d", from, block.txs[0].Nonce())
+	}
+}
+
+func TestBlockCandidateReplacesSelection(t *testing.T) {
+	w, b := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0, true)
+	defer w.close()
+
+	signer := types.LatestSigner(params.IstanbulTestChainConfig)
+	transfer := func(nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(params.InitialBaseFee),
+		})
+	}
+	head := b.chain.CurrentHeader()
+	candidate := func(parent *types.Header, txs ...*types.Transaction) *types.Block {
+		header := &types.Header{ParentHash: parent.Hash(), Number: new(big.Int).Add(parent.Number, common.Big1)}
+		return types.NewBlock(header, txs, nil, nil, trie.NewStackTrie(nil))
+	}
+	build := func() *blockState {
+		block, err := prepareBlock(context.Background(), w)
+		if err != nil {
+			t.Fatalf("failed to prepare block: %v", err)
+		}
+		defer block.close()
+		if err := block.selectAndApplyTransactions(context.Background(), w); err != nil {
+			t.Fatalf("failed to apply transactions: %v", err)
+		}
+		return block
+	}
+	miner := &Miner{worker: w}
+	if err := miner.SubmitBlockCandidate(candidate(head, transfer(0))); err != errBlockRelayDisabled {
+		t.Fatalf("disabled relay error mismatch: have %v, want %v", err, errBlockRelayDisabled)
+	}
+	w.config.BlockRelay = true
+	if err := miner.SubmitBlockCandidate(candidate(&types.Header{Number: head.Number}, transfer(0))); err != errCandidateStale {
+		t.Fatalf("stale candidate error mismatch: have %v, want %v", err, errCandidateStale)
+	}
+	// The transactions of the candidate take the place of the pending ones
+	valid := candidate(head, transfer(0), transfer(1), transfer(2))
+	if err := miner.SubmitBlockCandidate(valid); err != nil {
+		t.Fatalf("failed to submit candidate: %v", err)
+	}
+	block := build()
+	if len(block.txs) != 3 || block.txs[2].Hash() != valid.Transactions()[2].Hash() {
+		t.Fatalf("included transactions mismatch: have %d txs, want the candidate ones", len(block.txs))
+	}
+	// A candidate which can't be included is dropped for the local selection
+	if err := miner.SubmitBlockCandidate(candidate(head, transfer(1))); err != nil {
+		t.Fatalf("failed to submit candidate: %v", err)
+	}
+	if block := build(); len(block.txs) != len(pendingTxs) {
+		t.Fatalf("fallback transactions mismatch: have %d txs, want %d", len(block.txs), len(pendingTxs))
+	}
+	if w.candidates.block != nil {
+		t.Errorf("rejected candidate not dropped")
+	}
+}
+
+// testEngineAPI serves the payloads of a miner over the engine API.
+type testEngineAPI struct {
+	miner *Miner
+}
+
+func (api *testEngineAPI) ForkchoiceUpdated(state ForkchoiceState, attrs *PayloadAttributes) (*ForkchoiceResponse, error) {
+	id, err := api.miner.StartPayload(state.HeadBlockHash, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return &ForkchoiceResponse{PayloadStatus: PayloadStatus{Status: PayloadStatusValid}, PayloadID: &id}, nil
+}
+
+func (api *testEngineAPI) GetPayload(ctx context.Context, id PayloadID) (hexutil.Bytes, error) {
+	block, err := api.miner.GetPayload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(block)
+}
+
+func TestExternalBuilderPayload(t *testing.T) {
+	// The builder has the pending transactions of the validator's network
+	bw, _ := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0, true)
+	defer bw.close()
+	vw, _ := newTestWorker(t, params.IstanbulTestChainConfig, mockEngine.NewFaker(), rawdb.NewMemoryDatabase(), 0, false)
+	defer vw.close()
+
+	builder := &Miner{worker: bw}
+	head := bw.chain.CurrentBlock()
+	attrs := &PayloadAttributes{Timestamp: hexutil.Uint64(head.Time() + 1), FeeRecipient: testUserAddress}
+	if _, err := builder.StartPayload(head.Hash(), attrs); err != errPayloadBuilderDisabled {
+		t.Fatalf("disabled builder error mismatch: have %v, want %v", err, errPayloadBuilderDisabled)
+	}
+	bw.config = &Config{PayloadBuilder: true}
+	if _, err := builder.StartPayload(common.Hash{1}, attrs); err != errPayloadStale {
+		t.Fatalf("stale head error mismatch: have %v, want %v", err, errPayloadStale)
+	}
+	if _, err := builder.GetPayload(context.Background(), PayloadID{1}); err != errUnknownPayload {
+		t.Fatalf("unknown payload error mismatch: have %v, want %v", err, errUnknownPayload)
+	}
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("engine", &testEngineAPI{miner: builder}); err != nil {
+		t.Fatalf("failed to register engine API: %v", err)
+	}
+	vw.builder = &rpcPayloadBuilder{client: rpc.DialInProc(server)}
+
+	b, err := prepareBlock(context.Background(), vw)
+	if err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	defer b.close()
+	b.header.Coinbase = testUserAddress
+	payload, err := vw.builder.BuildPayload(context.Background(), b.header.ParentHash, vw.payloadAttributes(b))
+	if err != nil {
+		t.Fatalf("failed to build payload: %v", err)
+	}
+	if len(payload.Transactions()) != len(pendingTxs) || payload.Coinbase() != testUserAddress || payload.Time() != b.header.Time {
+		t.Fatalf("payload mismatch: have %d txs, coinbase %x, time %d", len(payload.Transactions()), payload.Coinbase(), payload.Time())
+	}
+
+	// Payloads which don't match the prepared block or its execution are rejected
+	tampered := payload.Header()
+	tampered.Root = common.Hash{1}
+	other := *b.randomness
+	other.Committed = common.Hash{1}
+	for name, test := range map[string]struct {
+		payload *types.Block
+		want    error
+	}{
+		"parent":     {types.NewBlockWithHeader(&types.Header{ParentHash: common.Hash{1}, Number: b.header.Number}), errPayloadParent},
+		"randomness": {payload.WithRandomness(&other), errPayloadRandomness},
+		"root":       {types.NewBlockWithHeader(tampered).WithBody(payload.Transactions(), payload.Randomness(), nil), nil},
+	} {
+		if _, err := b.adoptPayload(vw, test.payload); err == nil || (test.want != nil && err != test.want) {
+			t.Errorf("%s: adoption error mismatch: have %v, want %v", name, err, test.want)
+		}
+	}
+	if b.tcount != 0 {
+		t.Fatalf("rejected payload adopted: have %d txs", b.tcount)
+	}
+
+	block, err := b.adoptPayload(vw, payload)
+	if err != nil {
+		t.Fatalf("failed to adopt payload: %v", err)
+	}
+	if block.Root() != payload.Root() || b.tcount != len(pendingTxs) || len(b.receipts) < b.tcount {
+		t.Fatalf("adopted block mismatch: have root %x and %d txs, want root %x", block.Root(), b.tcount, payload.Root())
+	}
+}
+
+func TestSealedRandomnessJournal(t *testing.T) {
+	key, _ := atrest.NewKey(make([]byte, atrest.KeyLength))
+	w := &worker{config: &Config{RandomnessKey: key}, db: rawdb.NewMemoryDatabase()}
+
+	commitment, parentHash, randomness := common.Hash{1}, common.Hash{2}, common.Hash{3}
+	w.journalRandomness(commitment, parentHash, randomness)
+	if entry := rawdb.ReadRandomCommitmentJournal(w.db, commitment); entry == nil || entry.Randomness != (common.Hash{}) || entry.ParentHash != parentHash {
+		t.Fatalf("journal entry mismatch: have %v, want sealed randomness", entry)
+	}
+	if have, ok := w.journaledRandomness(commitment); !ok || have != randomness {
+		t.Fatalf("journaled randomness mismatch: have %x, %v, want %x", have, ok, randomness)
+	}
+	// Without the key, the randomness has to be generated again
+	w.config = &Config{}
+	if _, ok := w.journaledRandomness(commitment); ok {
+		t.Fatalf("sealed randomness returned without the key")
+	}
+}
+
+func TestStaleRateLimits(t *testing.T) {
+	var (
+		celo       = testutil.NewCeloMock()
+		oracles    = common.HexToAddress("0x06")
+		mock       = testutil.NewSortedOraclesMock()
+		fresh      = common.HexToAddress("0x02")
+		stale      = common.HexToAddress("0x05")
+		header     = &types.Header{Number: big.NewInt(1), Time: 1000}
+		currencies = []common.Address{fresh, stale}
+	)
+	mock.Timestamps[fresh] = 990
+	mock.Timestamps[stale] = 900
+	celo.Registry.AddContract(config.SortedOraclesRegistryId, oracles)
+	celo.Runner.RegisterContract(oracles, mock)
+
+	limits := map[common.Address]float64{fresh: 0.5, stale: 0.5}
+	w := &worker{config: &Config{}, feeCurrencyLimits: limits}
+	if have := w.staleRateLimits(celo.Runner, header, currencies); have[stale] != 0.5 {
+		t.Fatalf("unbounded limit mismatch: have %v, want 0.5", have[stale])
+	}
+	w.config.RateStaleness = time.Minute
+	have := w.staleRateLimits(celo.Runner, header, currencies)
+	if have[fresh] != 0.5 || have[stale] != 0 {
+		t.Fatalf("limits mismatch: have %v, want %v: 0.5, %v: 0", have, fresh, stale)
+	}
+	if limits[stale] != 0.5 {
+		t.Errorf("configured limits modified: have %v, want 0.5", limits[stale])
+	}
+}
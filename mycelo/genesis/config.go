@@ -89,12 +89,15 @@ func (cfg *Config) ChainConfig() *params.ChainConfig {
 		GingerbreadP2Block: cfg.Hardforks.GingerbreadP2Block,
 		HForkBlock:         cfg.Hardforks.HForkBlock,
 
+		MultiFeeMarket: cfg.Hardforks.MultiFeeMarket,
+
 		Istanbul: &params.IstanbulConfig{
-			Epoch:          cfg.Istanbul.Epoch,
-			ProposerPolicy: cfg.Istanbul.ProposerPolicy,
-			LookbackWindow: cfg.Istanbul.LookbackWindow,
-			BlockPeriod:    cfg.Istanbul.BlockPeriod,
-			RequestTimeout: cfg.Istanbul.RequestTimeout,
+			Epoch:           cfg.Istanbul.Epoch,
+			ProposerPolicy:  cfg.Istanbul.ProposerPolicy,
+			LookbackWindow:  cfg.Istanbul.LookbackWindow,
+			BlockPeriod:     cfg.Istanbul.BlockPeriod,
+			RequestTimeout:  cfg.Istanbul.RequestTimeout,
+			ProposerWeights: cfg.Istanbul.ProposerWeights,
 		},
 	}
 }
@@ -107,6 +110,9 @@ type HardforkConfig struct {
 	GingerbreadBlock   *big.Int `json:"gingerbreadBlock"`
 	GingerbreadP2Block *big.Int `json:"gingerbreadP2Block"`
 	HForkBlock         *big.Int `json:"hforkBlock"`
+
+	// Experimental per fee currency base fees, research only
+	MultiFeeMarket *params.MultiFeeMarketConfig `json:"multiFeeMarket,omitempty"`
 }
 
 // MultiSigParameters are the initial configuration parameters for a MultiSig contract
@@ -136,6 +142,7 @@ type ElectionParameters struct {
 	MaxElectableValidators uint64       `json:"maxElectableValidators"`
 	MaxVotesPerAccount     *big.Int     `json:"maxVotesPerAccount"`
 	ElectabilityThreshold  *fixed.Fixed `json:"electabilityThreshold"`
+	GroupVoteWeights       []uint64     `json:"groupVoteWeights,omitempty"` // Votes of every group, in multiples of its locked gold (default 1)
 }
 
 type ElectionParametersMarshaling struct {
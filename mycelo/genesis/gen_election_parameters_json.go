@@ -19,12 +19,14 @@ func (e ElectionParameters) MarshalJSON() ([]byte, error) {
 		MaxElectableValidators uint64               `json:"maxElectableValidators"`
 		MaxVotesPerAccount     *bigintstr.BigIntStr `json:"maxVotesPerAccount"`
 		ElectabilityThreshold  *fixed.Fixed         `json:"electabilityThreshold"`
+		GroupVoteWeights       []uint64             `json:"groupVoteWeights,omitempty"`
 	}
 	var enc ElectionParameters
 	enc.MinElectableValidators = e.MinElectableValidators
 	enc.MaxElectableValidators = e.MaxElectableValidators
 	enc.MaxVotesPerAccount = (*bigintstr.BigIntStr)(e.MaxVotesPerAccount)
 	enc.ElectabilityThreshold = e.ElectabilityThreshold
+	enc.GroupVoteWeights = e.GroupVoteWeights
 	return json.Marshal(&enc)
 }
 
@@ -35,6 +37,7 @@ func (e *ElectionParameters) UnmarshalJSON(input []byte) error {
 		MaxElectableValidators *uint64              `json:"maxElectableValidators"`
 		MaxVotesPerAccount     *bigintstr.BigIntStr `json:"maxVotesPerAccount"`
 		ElectabilityThreshold  *fixed.Fixed         `json:"electabilityThreshold"`
+		GroupVoteWeights       []uint64             `json:"groupVoteWeights,omitempty"`
 	}
 	var dec ElectionParameters
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -52,5 +55,8 @@ func (e *ElectionParameters) UnmarshalJSON(input []byte) error {
 	if dec.ElectabilityThreshold != nil {
 		e.ElectabilityThreshold = dec.ElectabilityThreshold
 	}
+	if dec.GroupVoteWeights != nil {
+		e.GroupVoteWeights = dec.GroupVoteWeights
+	}
 	return nil
 }
@@ -956,8 +956,13 @@ func (ctx *deployContext) addValidatorsToGroups() error {
 
 func (ctx *deployContext) voteForGroups() error {
 	election := ctx.contract("Election")
+	lockedGold := ctx.contract("LockedGold")
 
 	validatorGroups := ctx.accounts.ValidatorGroupAccounts()
+	weights := ctx.genesisConfig.Election.GroupVoteWeights
+	if len(weights) != 0 && len(weights) != len(validatorGroups) {
+		return fmt.Errorf("group vote weights mismatch: have %d, want one per group (%d)", len(weights), len(validatorGroups))
+	}
 
 	// value previously locked on registerValidatorGroups()
 	lockedGoldOnGroup := new(big.Int).Mul(
@@ -968,39 +973,92 @@ func (ctx *deployContext) voteForGroups() error {
 	// current group order (see `addFirstMember` on addValidatorsToGroup) is:
 	// [ groupZero, groupOne, ..., lastgroup]
 
-	// each group votes for themselves.
-	// each group votes the SAME AMOUNT
+	// each group votes for themselves, with its locked gold times its weight
+	// (default 1, so every group votes the SAME AMOUNT)
 	// each group starts with 0 votes
 
-	// so, everytime we vote, that group becomes the one with most votes (most or equal)
-	// hence, we use:
-	//    greater = zero (we become the one with most votes)
-	//    lesser = currentLeader
-
-	// special case: only one group (no lesser or greater)
-	if len(validatorGroups) == 1 {
-		groupAddress := validatorGroups[0].Address
-		ctx.logger.Info("Vote for group", "group", groupAddress, "amount", lockedGoldOnGroup)
-		return election.SimpleCallFrom(groupAddress, "vote", groupAddress, lockedGoldOnGroup, common.ZeroAddress, common.ZeroAddress)
-	}
-
-	// first to vote is group 0, which is already the leader. Hence lesser should go to group 1
-	currentLeader := validatorGroups[1].Address
-	for _, group := range validatorGroups {
+	order := newGroupVoteOrder(validatorGroups)
+	for i, group := range validatorGroups {
 		groupAddress := group.Address
+		amount := new(big.Int).Set(lockedGoldOnGroup)
+		if len(weights) != 0 {
+			if weights[i] == 0 {
+				continue
+			}
+			amount.Mul(amount, new(big.Int).SetUint64(weights[i]))
 
-		ctx.logger.Info("Vote for group", "group", groupAddress, "amount", lockedGoldOnGroup)
-		if err := election.SimpleCallFrom(groupAddress, "vote", groupAddress, lockedGoldOnGroup, currentLeader, common.ZeroAddress); err != nil {
-			return err
+			// Lock the gold voted above what the group locked on registration
+			extra := new(big.Int).Sub(amount, lockedGoldOnGroup)
+			if extra.Sign() > 0 {
+				ctx.statedb.AddBalance(groupAddress, extra)
+				if _, err := lockedGold.Call(contract.CallOpts{Origin: groupAddress, Value: extra}, "lock"); err != nil {
+					return err
+				}
+			}
 		}
 
-		// we now become the currentLeader
-		currentLeader = groupAddress
+		lesser, greater := order.vote(groupAddress, amount)
+		ctx.logger.Info("Vote for group", "group", groupAddress, "amount", amount)
+		if err := election.SimpleCallFrom(groupAddress, "vote", groupAddress, amount, lesser, greater); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// groupVoteOrder tracks the order the election keeps the groups in, sorted by
+// votes: every vote must name the groups right above (greater) and below (lesser)
+// the voted group once the vote is counted. A group that reaches the votes of
+// others goes before them, so with equal weights every vote makes a new leader.
+type groupVoteOrder []groupVotes
+
+type groupVotes struct {
+	address common.Address
+	votes   *big.Int
+}
+
+// newGroupVoteOrder returns the order of the groups before any vote, the one
+// they were registered in.
+func newGroupVoteOrder(groups []env.Account) groupVoteOrder {
+	order := make(groupVoteOrder, len(groups))
+	for i, group := range groups {
+		order[i] = groupVotes{group.Address, new(big.Int)}
+	}
+	return order
+}
+
+// vote counts the amount voted for the group, and returns the groups below and
+// above it afterwards, the zero address if there's none.
+func (o *groupVoteOrder) vote(group common.Address, amount *big.Int) (lesser, greater common.Address) {
+	order := *o
+	votes := new(big.Int).Set(amount)
+	for j := range order {
+		if order[j].address == group {
+			votes.Add(votes, order[j].votes)
+			order = append(order[:j], order[j+1:]...)
+			break
+		}
+	}
+	pos := len(order)
+	for j := range order {
+		if order[j].votes.Cmp(votes) <= 0 {
+			pos = j
+			break
+		}
+	}
+	order = append(order[:pos], append(groupVoteOrder{{group, votes}}, order[pos:]...)...)
+	*o = order
+
+	if pos > 0 {
+		greater = order[pos-1].address
+	}
+	if pos+1 < len(order) {
+		lesser = order[pos+1].address
+	}
+	return lesser, greater
+}
+
 func (ctx *deployContext) electValidators() error {
 	if err := ctx.registerValidators(); err != nil {
 		return err
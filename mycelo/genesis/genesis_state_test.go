@@ -0,0 +1,60 @@
+package genesis
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/celo-org/celo-blockchain/common"
+	"github.com/celo-org/celo-blockchain/mycelo/env"
+)
+
+func TestGroupVoteOrder(t *testing.T) {
+	groups := make([]env.Account, 4)
+	for i := range groups {
+		groups[i] = env.Account{Address: common.BytesToAddress([]byte{byte(i + 1)})}
+	}
+	g0, g1, g2, g3, zero := groups[0].Address, groups[1].Address, groups[2].Address, groups[3].Address, common.ZeroAddress
+
+	tests := []struct {
+		name    string
+		amounts []int64
+		hints   [][2]common.Address // Lesser and greater of every vote
+		want    []common.Address    // Order after the votes
+	}{
+		{
+			// Every vote makes a new leader
+			name:    "uniform",
+			amounts: []int64{1, 1, 1, 1},
+			hints:   [][2]common.Address{{g1, zero}, {g0, zero}, {g1, zero}, {g2, zero}},
+			want:    []common.Address{g3, g2, g1, g0},
+		},
+		{
+			// The first group keeps the lead
+			name:    "linear",
+			amounts: []int64{4, 3, 2, 1},
+			hints:   [][2]common.Address{{g1, zero}, {g2, g0}, {g3, g1}, {zero, g2}},
+			want:    []common.Address{g0, g1, g2, g3},
+		},
+		{
+			// Later votes land between earlier ones
+			name:    "mixed",
+			amounts: []int64{3, 1, 2, 5},
+			hints:   [][2]common.Address{{g1, zero}, {g2, g0}, {g1, g0}, {g0, zero}},
+			want:    []common.Address{g3, g0, g2, g1},
+		},
+	}
+	for _, tt := range tests {
+		order := newGroupVoteOrder(groups)
+		for i, amount := range tt.amounts {
+			lesser, greater := order.vote(groups[i].Address, big.NewInt(amount))
+			if lesser != tt.hints[i][0] || greater != tt.hints[i][1] {
+				t.Errorf("%s: vote %d hints mismatch: have lesser %x and greater %x, want %x and %x", tt.name, i, lesser, greater, tt.hints[i][0], tt.hints[i][1])
+			}
+		}
+		for i, group := range order {
+			if group.address != tt.want[i] {
+				t.Errorf("%s: group %d mismatch: have %x, want %x", tt.name, i, group.address, tt.want[i])
+			}
+		}
+	}
+}
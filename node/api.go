@@ -323,6 +323,16 @@ func (api *publicAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// PeerDiversity reports the distribution of the connected peers across subnets,
+// autonomous systems and countries, along with the dial diversity limits.
+func (api *publicAdminAPI) PeerDiversity() (*p2p.PeerDiversityInfo, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.PeerDiversity(), nil
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
 func (api *publicAdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
@@ -25,6 +25,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/celo-org/celo-blockchain/accounts/keystore"
 	"github.com/celo-org/celo-blockchain/common"
@@ -67,6 +68,10 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DatabaseSlowThreshold is the duration above which the operations on the
+	// databases opened by the node are logged, 0 disables the log.
+	DatabaseSlowThreshold time.Duration `toml:",omitempty"`
+
 	// Specifies if this node is a proxy
 	Proxy bool
 
@@ -86,6 +91,10 @@ type Config struct {
 	// ExternalSigner specifies an external URI for a clef-type signer
 	ExternalSigner string `toml:",omitempty"`
 
+	// KMSKeys are the URLs of the keys held by key management services or HSMs
+	// to sign with, in addition to the keystore (see accounts/kms).
+	KMSKeys []string `toml:",omitempty"`
+
 	// UseLightweightKDF lowers the memory and CPU requirements of the key store
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
@@ -111,6 +120,10 @@ type Config struct {
 	// relative), then that specific path is enforced. An empty path disables IPC.
 	IPCPath string
 
+	// IPCEndpoints are additional IPC endpoints, each exposing a restricted set of
+	// API namespaces with its own file permissions.
+	IPCEndpoints []IPCEndpointConfig `toml:",omitempty"`
+
 	// HTTPHost is the host interface on which to start the HTTP RPC server. If this
 	// field is empty, no HTTP API endpoint will be started.
 	HTTPHost string
@@ -204,25 +217,30 @@ type Config struct {
 // account the set data folders as well as the designated platform we're currently
 // running on.
 func (c *Config) IPCEndpoint() string {
+	return c.resolveIPCPath(c.IPCPath)
+}
+
+// resolveIPCPath resolves a configured IPC path the way IPCEndpoint does.
+func (c *Config) resolveIPCPath(path string) string {
 	// Short circuit if IPC has not been enabled
-	if c.IPCPath == "" {
+	if path == "" {
 		return ""
 	}
 	// On windows we can only use plain top-level pipes
 	if runtime.GOOS == "windows" {
-		if strings.HasPrefix(c.IPCPath, `\\.\pipe\`) {
-			return c.IPCPath
+		if strings.HasPrefix(path, `\\.\pipe\`) {
+			return path
 		}
-		return `\\.\pipe\` + c.IPCPath
+		return `\\.\pipe\` + path
 	}
 	// Resolve names into the data directory full paths otherwise
-	if filepath.Base(c.IPCPath) == c.IPCPath {
+	if filepath.Base(path) == path {
 		if c.DataDir == "" {
-			return filepath.Join(os.TempDir(), c.IPCPath)
+			return filepath.Join(os.TempDir(), path)
 		}
-		return filepath.Join(c.DataDir, c.IPCPath)
+		return filepath.Join(c.DataDir, path)
 	}
-	return c.IPCPath
+	return path
 }
 
 // NodeDB returns the path to the discovery node database.
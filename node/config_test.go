@@ -25,6 +25,8 @@ import (
 	"testing"
 
 	"github.com/celo-org/celo-blockchain/crypto"
+	"github.com/celo-org/celo-blockchain/internal/testlog"
+	"github.com/celo-org/celo-blockchain/log"
 	"github.com/celo-org/celo-blockchain/p2p"
 )
 
@@ -160,3 +162,28 @@ func TestNodeKeyPersistency(t *testing.T) {
 		t.Fatalf("ephemeral node key persisted to disk")
 	}
 }
+
+// Tests that additional IPC endpoints are validated and resolved.
+func TestIPCEndpointsConfig(t *testing.T) {
+	dir := t.TempDir()
+	conf := &Config{DataDir: dir, IPCPath: "geth.ipc"}
+
+	srv, err := newIPCEndpoint(conf, IPCEndpointConfig{Path: "signer.ipc", Modules: []string{"eth"}, Mode: "0660"}, newIPCServer(testlog.Logger(t, log.LvlInfo), ""))
+	if err != nil {
+		t.Fatalf("valid endpoint rejected: %v", err)
+	}
+	if srv.endpoint != conf.resolveIPCPath("signer.ipc") || srv.perms.mode != 0660 || srv.perms.gid != -1 {
+		t.Errorf("endpoint mismatch: path %s, mode %o, gid %d", srv.endpoint, srv.perms.mode, srv.perms.gid)
+	}
+	for _, endpoint := range []IPCEndpointConfig{
+		{Modules: []string{"eth"}},
+		{Path: "signer.ipc"},
+		{Path: "geth.ipc", Modules: []string{"eth"}},
+		{Path: "signer.ipc", Modules: []string{"eth"}, Mode: "rw"},
+		{Path: "signer.ipc", Modules: []string{"eth"}, Mode: "1777"},
+	} {
+		if _, err := newIPCEndpoint(conf, endpoint, newIPCServer(testlog.Logger(t, log.LvlInfo), "")); err == nil {
+			t.Errorf("invalid endpoint %+v accepted", endpoint)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+
+	"github.com/celo-org/celo-blockchain/rpc"
+)
+
+// defaultIPCEndpointMode is the file mode of the IPC endpoints without one configured.
+const defaultIPCEndpointMode = 0600
+
+// IPCEndpointConfig is an additional IPC endpoint, e.g. a signing socket for a
+// sidecar or a monitoring socket, exposing only some API namespaces.
+type IPCEndpointConfig struct {
+	// Path is the location of the endpoint, resolved like Config.IPCPath.
+	Path string
+
+	// Modules is the list of API namespaces exposed on the endpoint.
+	Modules []string
+
+	// Mode is the octal file mode of the socket, 0600 if empty. Ignored on Windows.
+	Mode string `toml:",omitempty"`
+
+	// Group is the name or id of the group owning the socket, so that the mode
+	// can grant it access. Ignored on Windows.
+	Group string `toml:",omitempty"`
+}
+
+// ipcEndpointPermissions are the file permissions applied to an IPC endpoint.
+type ipcEndpointPermissions struct {
+	mode os.FileMode
+	gid  int // Group owning the socket, -1 to keep the default one
+}
+
+// newIPCEndpoint validates the configuration of an additional IPC endpoint and
+// creates its server.
+func newIPCEndpoint(conf *Config, endpoint IPCEndpointConfig, srv *ipcServer) (*ipcServer, error) {
+	if endpoint.Path == "" {
+		return nil, errors.New("IPC endpoint without path")
+	}
+	// Expose nothing rather than everything by mistake
+	if len(endpoint.Modules) == 0 {
+		return nil, fmt.Errorf("IPC endpoint %s without modules", endpoint.Path)
+	}
+	path := conf.resolveIPCPath(endpoint.Path)
+	if path == conf.IPCEndpoint() {
+		return nil, fmt.Errorf("IPC endpoint %s conflicts with the default one", endpoint.Path)
+	}
+	perms := &ipcEndpointPermissions{mode: defaultIPCEndpointMode, gid: -1}
+	if endpoint.Mode != "" {
+		mode, err := strconv.ParseUint(endpoint.Mode, 8, 32)
+		if err != nil || mode&^0777 != 0 {
+			return nil, fmt.Errorf("IPC endpoint %s: invalid mode %q", endpoint.Path, endpoint.Mode)
+		}
+		perms.mode = os.FileMode(mode)
+	}
+	if endpoint.Group != "" {
+		gid, err := lookupGroup(endpoint.Group)
+		if err != nil {
+			return nil, fmt.Errorf("IPC endpoint %s: %v", endpoint.Path, err)
+		}
+		perms.gid = gid
+	}
+	srv.endpoint, srv.modules, srv.perms = path, endpoint.Modules, perms
+	return srv, nil
+}
+
+// lookupGroup resolves a group name or id into its id.
+func lookupGroup(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// restrictAPIs returns the APIs in the given namespaces.
+func restrictAPIs(apis []rpc.API, modules []string) []rpc.API {
+	allowed := make(map[string]bool, len(modules))
+	for _, module := range modules {
+		allowed[module] = true
+	}
+	restricted := make([]rpc.API, 0, len(apis))
+	for _, api := range apis {
+		if allowed[api.Namespace] {
+			restricted = append(restricted, api)
+		}
+	}
+	return restricted
+}
+
+// apply sets the permissions of the endpoint file.
+func (p *ipcEndpointPermissions) apply(endpoint string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if p.gid >= 0 {
+		if err := os.Chown(endpoint, -1, p.gid); err != nil {
+			return err
+		}
+	}
+	return os.Chmod(endpoint, p.mode)
+}
@@ -52,12 +52,13 @@ type Node struct {
 	state         int        // Tracks state of node lifecycle
 
 	lock          sync.Mutex
-	lifecycles    []Lifecycle // All registered backends, services, and auxiliary services that have a lifecycle
-	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	http          *httpServer //
-	ws            *httpServer //
-	ipc           *ipcServer  // Stores information about the ipc http server
-	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	lifecycles    []Lifecycle  // All registered backends, services, and auxiliary services that have a lifecycle
+	rpcAPIs       []rpc.API    // List of APIs currently provided by the node
+	http          *httpServer  //
+	ws            *httpServer  //
+	ipc           *ipcServer   // Stores information about the ipc http server
+	ipcs          []*ipcServer // Additional ipc servers restricted to some modules
+	inprocHandler *rpc.Server  // In-process RPC request handler to process the API requests
 
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
@@ -166,6 +167,13 @@ func New(conf *Config) (*Node, error) {
 	node.http = newHTTPServer(node.log, conf.HTTPTimeouts)
 	node.ws = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
 	node.ipc = newIPCServer(node.log, conf.IPCEndpoint())
+	for _, endpoint := range conf.IPCEndpoints {
+		srv, err := newIPCEndpoint(conf, endpoint, newIPCServer(node.log, ""))
+		if err != nil {
+			return nil, err
+		}
+		node.ipcs = append(node.ipcs, srv)
+	}
 
 	return node, nil
 }
@@ -380,6 +388,11 @@ func (n *Node) startRPC() error {
 			return err
 		}
 	}
+	for _, ipc := range n.ipcs {
+		if err := ipc.start(n.rpcAPIs); err != nil {
+			return err
+		}
+	}
 
 	// Configure HTTP.
 	if n.config.HTTPHost != "" {
@@ -430,6 +443,9 @@ func (n *Node) stopRPC() {
 	n.http.stop()
 	n.ws.stop()
 	n.ipc.stop()
+	for _, ipc := range n.ipcs {
+		ipc.stop()
+	}
 	n.stopInProc()
 }
 
@@ -610,7 +626,7 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string, r
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
 	} else {
-		db, err = rawdb.NewLevelDBDatabase(n.ResolvePath(name), cache, handles, namespace, readonly)
+		db, err = rawdb.NewLevelDBDatabase(n.ResolvePath(name), cache, handles, namespace, readonly, n.config.DatabaseSlowThreshold)
 	}
 
 	if err == nil {
@@ -643,7 +659,7 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer,
 		case !filepath.IsAbs(freezer):
 			freezer = n.ResolvePath(freezer)
 		}
-		db, err = rawdb.NewLevelDBDatabaseWithFreezer(root, cache, handles, freezer, namespace, readonly)
+		db, err = rawdb.NewLevelDBDatabaseWithFreezer(root, cache, handles, freezer, namespace, readonly, n.config.DatabaseSlowThreshold)
 	}
 
 	if err == nil {
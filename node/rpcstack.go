@@ -473,6 +473,8 @@ func newGzipHandler(next http.Handler) http.Handler {
 type ipcServer struct {
 	log      log.Logger
 	endpoint string
+	modules  []string                // API namespaces exposed, all if nil
+	perms    *ipcEndpointPermissions // File permissions of the endpoint, the default ones if nil
 
 	mu       sync.Mutex
 	listener net.Listener
@@ -491,12 +493,30 @@ func (is *ipcServer) start(apis []rpc.API) error {
 	if is.listener != nil {
 		return nil // already running
 	}
+	if is.modules != nil {
+		if bad, available := checkModuleAvailability(is.modules, apis); len(bad) > 0 {
+			is.log.Error("Unavailable modules in IPC API list", "url", is.endpoint, "unavailable", bad, "available", available)
+		}
+		apis = restrictAPIs(apis, is.modules)
+	}
 	listener, srv, err := rpc.StartIPCEndpoint(is.endpoint, apis)
 	if err != nil {
 		is.log.Warn("IPC opening failed", "url", is.endpoint, "error", err)
 		return err
 	}
-	is.log.Info("IPC endpoint opened", "url", is.endpoint)
+	if is.perms != nil {
+		if err := is.perms.apply(is.endpoint); err != nil {
+			listener.Close()
+			srv.Stop()
+			is.log.Warn("IPC endpoint permissions failed", "url", is.endpoint, "error", err)
+			return err
+		}
+	}
+	if is.modules != nil {
+		is.log.Info("IPC endpoint opened", "url", is.endpoint, "modules", is.modules)
+	} else {
+		is.log.Info("IPC endpoint opened", "url", is.endpoint)
+	}
 	is.listener, is.srv = listener, srv
 	return nil
 }
@@ -43,6 +43,10 @@ const (
 	dialStatsLogInterval = 10 * time.Second // printed at most this often
 	dialStatsPeerLimit   = 3                // but not if more than this many dialed peers
 
+	// The number of discovered nodes held back while the dial slots are taken, so that
+	// the most diverse of them is dialed once one is available.
+	maxDiversityCandidates = 32
+
 	// Endpoint resolution is throttled with bounded backoff.
 	initialResolveDelay = 60 * time.Second
 	maxResolveDelay     = time.Hour
@@ -79,6 +83,7 @@ var (
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNetRestrict      = errors.New("not contained in netrestrict list")
 	errNoPort           = errors.New("node does not provide TCP port")
+	errDiversity        = errors.New("too many peers in the same network")
 )
 
 // dialer creates outbound connections and submits them into Server.
@@ -109,6 +114,12 @@ type dialScheduler struct {
 	peers     map[enode.ID]struct{}  // all connected peers
 	dialPeers int                    // current number of dialed peers
 
+	// Dialed peers per diversity bucket, and the buckets of every dialed peer.
+	// Dial candidates are held back in candidates if the diversity policy is set.
+	bucketPeers map[string]int
+	peerBuckets map[enode.ID][]string
+	candidates  []*enode.Node
+
 	// The static map tracks all static dial tasks. The subset of usable static dial tasks
 	// (i.e. those passing checkDial) is kept in staticPool. The scheduler prefers
 	// launching static tasks from the pool over launching dynamic dials from the
@@ -133,6 +144,7 @@ type dialConfig struct {
 	maxDialPeers   int              // maximum number of dialed peers
 	maxActiveDials int              // maximum number of active dials
 	netRestrict    *netutil.Netlist // IP netrestrict list, disabled if nil
+	diversity      *diversityPolicy // peer diversity policy, disabled if nil
 	resolver       nodeResolver
 	dialer         NodeDialer
 	log            log.Logger
@@ -172,6 +184,8 @@ func newDialScheduler(config dialConfig, it enode.Iterator, setupFunc dialSetupF
 		dialing:     make(map[enode.ID]*dialTask),
 		static:      make(map[enode.ID]*dialTask),
 		peers:       make(map[enode.ID]struct{}),
+		bucketPeers: make(map[string]int),
+		peerBuckets: make(map[enode.ID][]string),
 		doneCh:      make(chan *dialTask),
 		nodesIn:     make(chan *enode.Node),
 		addStaticCh: make(chan *enode.Node),
@@ -237,7 +251,8 @@ loop:
 		// Launch new dials if slots are available.
 		slots := d.freeDialSlots()
 		slots -= d.startStaticDials()
-		if slots > 0 {
+		slots -= d.startCandidateDials(slots)
+		if slots > 0 || (d.diversity != nil && len(d.candidates) < maxDiversityCandidates) {
 			nodesCh = d.nodesIn
 		} else {
 			nodesCh = nil
@@ -249,6 +264,10 @@ loop:
 		case node := <-nodesCh:
 			if err := d.checkDial(node); err != nil {
 				d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IP(), "reason", err)
+			} else if err := d.checkDiversity(node); err != nil {
+				d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IP(), "reason", err)
+			} else if d.diversity != nil {
+				d.candidates = append(d.candidates, node)
 			} else {
 				d.startDial(newDialTask(node, dynDialedConn))
 			}
@@ -265,6 +284,7 @@ loop:
 			}
 			id := c.node.ID()
 			d.peers[id] = struct{}{}
+			d.addPeerBuckets(c)
 			// Remove from static pool because the node is now connected.
 			task := d.static[id]
 			if task != nil && task.staticPoolIndex >= 0 {
@@ -277,6 +297,7 @@ loop:
 				d.dialPeers--
 			}
 			delete(d.peers, c.node.ID())
+			d.removePeerBuckets(c)
 			d.updateStaticPool(c.node.ID())
 
 		case node := <-d.addStaticCh:
@@ -417,6 +438,98 @@ func (d *dialScheduler) checkDial(n *enode.Node) error {
 	return nil
 }
 
+// checkDiversity returns an error if dialing n would exceed the peer diversity
+// limits. It only applies to dynamic dials, static nodes are always dialed.
+func (d *dialScheduler) checkDiversity(n *enode.Node) error {
+	if d.diversity == nil {
+		return nil
+	}
+	buckets := d.diversity.buckets(n.IP())
+	if len(buckets) == 0 {
+		return nil
+	}
+	counts := d.bucketCounts()
+	limit := d.diversity.limit(d.maxDialPeers)
+	for _, bucket := range buckets {
+		if counts[bucket] >= limit {
+			return errDiversity
+		}
+	}
+	return nil
+}
+
+// bucketCounts returns the number of dialed peers and running dials in every
+// diversity bucket.
+func (d *dialScheduler) bucketCounts() map[string]int {
+	counts := make(map[string]int, len(d.bucketPeers))
+	for bucket, n := range d.bucketPeers {
+		counts[bucket] = n
+	}
+	for _, task := range d.dialing {
+		for _, bucket := range d.diversity.buckets(task.dest.IP()) {
+			counts[bucket]++
+		}
+	}
+	return counts
+}
+
+// startCandidateDials dials up to slots of the held back dial candidates, the
+// ones in the least represented countries, autonomous systems and subnets first.
+func (d *dialScheduler) startCandidateDials(slots int) (started int) {
+	for started < slots && len(d.candidates) > 0 {
+		i := d.bestCandidate()
+		node := d.candidates[i]
+		d.candidates = append(d.candidates[:i], d.candidates[i+1:]...)
+		// The peers and dials changed since the candidate was discovered
+		if err := d.checkDial(node); err != nil {
+			d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IP(), "reason", err)
+		} else if err := d.checkDiversity(node); err != nil {
+			d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IP(), "reason", err)
+		} else {
+			d.startDial(newDialTask(node, dynDialedConn))
+			started++
+		}
+	}
+	return started
+}
+
+// bestCandidate returns the index of the candidate sharing a country, then an
+// autonomous system, then a subnet with the fewest dialed peers. Ties go to the
+// earliest discovered.
+func (d *dialScheduler) bestCandidate() int {
+	counts := d.bucketCounts()
+	best, bestScore := 0, d.diversity.score(d.candidates[0].IP(), counts)
+	for i, node := range d.candidates[1:] {
+		if score := d.diversity.score(node.IP(), counts); score.less(bestScore) {
+			best, bestScore = i+1, score
+		}
+	}
+	return best
+}
+
+// addPeerBuckets accounts a new dialed peer in its diversity buckets. Inbound
+// peers are not accounted, since the bucket limit is a share of the dialed peers.
+func (d *dialScheduler) addPeerBuckets(c *conn) {
+	if d.diversity == nil || !(c.is(dynDialedConn) || c.is(staticDialedConn)) {
+		return
+	}
+	buckets := d.diversity.buckets(c.node.IP())
+	for _, bucket := range buckets {
+		d.bucketPeers[bucket]++
+	}
+	d.peerBuckets[c.node.ID()] = buckets
+}
+
+// removePeerBuckets removes a peer from its diversity buckets.
+func (d *dialScheduler) removePeerBuckets(c *conn) {
+	for _, bucket := range d.peerBuckets[c.node.ID()] {
+		if d.bucketPeers[bucket]--; d.bucketPeers[bucket] <= 0 {
+			delete(d.bucketPeers, bucket)
+		}
+	}
+	delete(d.peerBuckets, c.node.ID())
+}
+
 // startStaticDials starts static dials nodes in the static pool, subject to the maxActiveDials limit
 func (d *dialScheduler) startStaticDials() (started int) {
 	limit := d.maxActiveDials - len(d.dialing)
@@ -23,6 +23,7 @@ import (
 	"math/rand"
 	"net"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -224,6 +225,101 @@ func TestDialSchedStaticDial(t *testing.T) {
 	})
 }
 
+// This test checks that dynamic dials are limited per /16 subnet.
+func TestDialSchedDiversity(t *testing.T) {
+	t.Parallel()
+
+	nodes := []*enode.Node{
+		newNode(uintID(0x01), "10.1.0.1:30303"),
+		newNode(uintID(0x02), "10.1.0.2:30303"),
+		newNode(uintID(0x03), "10.1.1.3:30303"),
+		newNode(uintID(0x04), "10.1.2.4:30303"),
+		newNode(uintID(0x05), "10.2.0.5:30303"),
+		newNode(uintID(0x06), "10.2.0.6:30303"),
+	}
+	config := dialConfig{
+		diversity:      &diversityPolicy{maxFraction: 0.2},
+		maxActiveDials: 10,
+		maxDialPeers:   10,
+	}
+	runDialTest(t, config, []dialTestRound{
+		{
+			discovered:   nodes,
+			wantNewDials: []*enode.Node{nodes[0], nodes[1], nodes[4], nodes[5]},
+		},
+		{
+			succeeded: []enode.ID{
+				nodes[0].ID(),
+				nodes[1].ID(),
+				nodes[4].ID(),
+				nodes[5].ID(),
+			},
+		},
+		// Inbound peers don't count against the limit of the dialed peers.
+		{
+			peersAdded: []*conn{
+				{flags: inboundConn, node: newNode(uintID(0x08), "10.3.0.8:30303")},
+				{flags: inboundConn, node: newNode(uintID(0x09), "10.3.0.9:30303")},
+			},
+			discovered:   []*enode.Node{newNode(uintID(0x0a), "10.3.0.10:30303")},
+			wantNewDials: []*enode.Node{newNode(uintID(0x0a), "10.3.0.10:30303")},
+		},
+		// Once a peer of the subnet is gone, another one can be dialed.
+		{
+			peersRemoved: []enode.ID{nodes[0].ID()},
+			discovered:   []*enode.Node{newNode(uintID(0x07), "10.1.3.7:30303")},
+			wantNewDials: []*enode.Node{newNode(uintID(0x07), "10.1.3.7:30303")},
+		},
+	})
+}
+
+// This test checks that the held back dial candidates in the least represented
+// countries, then autonomous systems and subnets, are dialed first.
+func TestDialSchedDiversityPreference(t *testing.T) {
+	asnDB, err := netutil.ParseASNDatabase(strings.NewReader(
+		"1.0.0.0\t1.0.255.255\t100\tUS\tUS-1\n" +
+			"1.1.0.0\t1.1.255.255\t101\tUS\tUS-2\n" +
+			"1.2.0.0\t1.2.255.255\t100\tUS\tUS-1\n" +
+			"2.0.0.0\t2.0.255.255\t200\tDE\tDE-1\n",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &dialScheduler{
+		dialConfig:  dialConfig{diversity: &diversityPolicy{maxFraction: 0.5, asnDB: asnDB}, maxDialPeers: 10},
+		dialing:     make(map[enode.ID]*dialTask),
+		bucketPeers: make(map[string]int),
+		peerBuckets: make(map[enode.ID][]string),
+	}
+	d.addPeerBuckets(&conn{flags: dynDialedConn, node: newNode(uintID(0x01), "1.0.0.1:30303")})
+	d.addPeerBuckets(&conn{flags: dynDialedConn, node: newNode(uintID(0x02), "1.0.1.2:30303")})
+	d.addPeerBuckets(&conn{flags: inboundConn, node: newNode(uintID(0x03), "2.0.0.3:30303")})
+	d.dialing[uintID(0x04)] = newDialTask(newNode(uintID(0x04), "2.0.0.4:30303"), dynDialedConn)
+
+	var (
+		sameNet     = newNode(uintID(0x10), "1.0.0.16:30303") // US, AS100 and 1.0/16 are taken
+		sameASN     = newNode(uintID(0x11), "1.2.0.17:30303") // only US and AS100 are taken
+		sameCountry = newNode(uintID(0x12), "1.1.0.18:30303") // only US is taken
+		dialed      = newNode(uintID(0x13), "2.0.1.19:30303") // DE has a running dial
+		unknown     = newNode(uintID(0x14), "3.0.0.20:30303") // not in the database
+	)
+	tests := []struct {
+		candidates []*enode.Node
+		want       *enode.Node
+	}{
+		{[]*enode.Node{sameNet, sameASN}, sameASN},
+		{[]*enode.Node{sameNet, sameCountry}, sameCountry},
+		{[]*enode.Node{sameCountry, dialed}, dialed},
+		{[]*enode.Node{sameNet, dialed, unknown}, unknown},
+	}
+	for i, tt := range tests {
+		d.candidates = tt.candidates
+		if have := d.candidates[d.bestCandidate()]; have != tt.want {
+			t.Errorf("test %d: dialed %v first, want %v", i, have.IP(), tt.want.IP())
+		}
+	}
+}
+
 // This test checks that removing static nodes stops connecting to them.
 func TestDialSchedRemoveStatic(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,140 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/celo-org/celo-blockchain/p2p/netutil"
+)
+
+const (
+	subnetBucketPrefix  = "net:"
+	asnBucketPrefix     = "asn:"
+	countryBucketPrefix = "geo:"
+)
+
+// diversityPolicy limits the share of dialed peers in a single /16 subnet, and if
+// an ASN database is available, in a single autonomous system or country. Among
+// the dial candidates, the ones in the least represented countries are preferred.
+type diversityPolicy struct {
+	maxFraction float64
+	asnDB       *netutil.ASNDatabase // nil if only subnets are considered
+}
+
+// buckets returns the buckets an IP counts against.
+func (p *diversityPolicy) buckets(ip net.IP) []string {
+	if ip == nil || ip.IsLoopback() {
+		return nil
+	}
+	var buckets []string
+	if ip4 := ip.To4(); ip4 != nil {
+		buckets = append(buckets, subnetBucketPrefix+ip4.Mask(net.CIDRMask(16, 32)).String()+"/16")
+	} else {
+		buckets = append(buckets, subnetBucketPrefix+ip.Mask(net.CIDRMask(32, 128)).String()+"/32")
+	}
+	if info, ok := p.asnDB.Lookup(ip); ok {
+		buckets = append(buckets, fmt.Sprintf("%sAS%d", asnBucketPrefix, info.ASN))
+		if info.Country != "" && info.Country != "None" {
+			buckets = append(buckets, countryBucketPrefix+info.Country)
+		}
+	}
+	return buckets
+}
+
+// diversityScore is the number of peers sharing the country, the autonomous
+// system and the subnet of a node, compared in that order.
+type diversityScore [3]int
+
+func (s diversityScore) less(other diversityScore) bool {
+	for i := range s {
+		if s[i] != other[i] {
+			return s[i] < other[i]
+		}
+	}
+	return false
+}
+
+// score returns the diversityScore of ip, given the number of peers in every bucket.
+func (p *diversityPolicy) score(ip net.IP, counts map[string]int) diversityScore {
+	var score diversityScore
+	for _, bucket := range p.buckets(ip) {
+		switch {
+		case strings.HasPrefix(bucket, countryBucketPrefix):
+			score[0] = counts[bucket]
+		case strings.HasPrefix(bucket, asnBucketPrefix):
+			score[1] = counts[bucket]
+		case strings.HasPrefix(bucket, subnetBucketPrefix):
+			score[2] = counts[bucket]
+		}
+	}
+	return score
+}
+
+// limit returns the number of peers allowed in a single bucket out of total.
+func (p *diversityPolicy) limit(total int) int {
+	limit := int(p.maxFraction * float64(total))
+	if limit < 1 {
+		limit = 1
+	}
+	return limit
+}
+
+// PeerDiversityInfo reports the distribution of the connected peers across the
+// buckets of the diversity policy.
+type PeerDiversityInfo struct {
+	Enabled     bool           `json:"enabled"`
+	MaxFraction float64        `json:"maxFraction"`
+	Limit       int            `json:"limit"` // Maximum dialed peers per bucket
+	Subnets     map[string]int `json:"subnets"`
+	ASNs        map[string]int `json:"asns"`
+	Countries   map[string]int `json:"countries"`
+}
+
+// PeerDiversity returns the distribution of the connected peers across subnets,
+// autonomous systems and countries.
+func (srv *Server) PeerDiversity() *PeerDiversityInfo {
+	policy := srv.diversity
+	info := &PeerDiversityInfo{
+		Subnets:   make(map[string]int),
+		ASNs:      make(map[string]int),
+		Countries: make(map[string]int),
+	}
+	if policy != nil {
+		info.Enabled = true
+		info.MaxFraction = policy.maxFraction
+		info.Limit = policy.limit(srv.maxDialedConns())
+	} else {
+		// Still report the subnets of the peers
+		policy = &diversityPolicy{}
+	}
+	for _, peer := range srv.Peers() {
+		for _, bucket := range policy.buckets(peer.Node().IP()) {
+			switch {
+			case strings.HasPrefix(bucket, subnetBucketPrefix):
+				info.Subnets[strings.TrimPrefix(bucket, subnetBucketPrefix)]++
+			case strings.HasPrefix(bucket, asnBucketPrefix):
+				info.ASNs[strings.TrimPrefix(bucket, asnBucketPrefix)]++
+			case strings.HasPrefix(bucket, countryBucketPrefix):
+				info.Countries[strings.TrimPrefix(bucket, countryBucketPrefix)]++
+			}
+		}
+	}
+	return info
+}
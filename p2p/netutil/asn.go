@@ -0,0 +1,112 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package netutil
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ASNInfo is the autonomous system an IP belongs to.
+type ASNInfo struct {
+	ASN     uint32
+	Country string // ISO 3166 country code of the AS registration
+}
+
+type asnRange struct {
+	start, end uint32
+	info       ASNInfo
+}
+
+// ASNDatabase maps IPv4 addresses to their autonomous system.
+type ASNDatabase struct {
+	ranges []asnRange // sorted by start, non overlapping
+}
+
+// LoadASNDatabase reads an IP to ASN database file in the tab separated format
+// of iptoasn.com (range_start, range_end, AS_number, country_code, AS_description).
+func LoadASNDatabase(path string) (*ASNDatabase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseASNDatabase(f)
+}
+
+// ParseASNDatabase reads an IP to ASN database, see LoadASNDatabase. IPv6 ranges
+// and ranges not routed by any AS are skipped.
+func ParseASNDatabase(r io.Reader) (*ASNDatabase, error) {
+	db := new(ASNDatabase)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) == 1 && strings.TrimSpace(fields[0]) == "" {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("line %d: expected at least 4 fields, got %d", line, len(fields))
+		}
+		start, end := net.ParseIP(fields[0]).To4(), net.ParseIP(fields[1]).To4()
+		if start == nil || end == nil {
+			continue
+		}
+		asn, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid AS number %q", line, fields[2])
+		}
+		if asn == 0 {
+			continue
+		}
+		db.ranges = append(db.ranges, asnRange{
+			start: binary.BigEndian.Uint32(start),
+			end:   binary.BigEndian.Uint32(end),
+			info:  ASNInfo{ASN: uint32(asn), Country: fields[3]},
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(db.ranges, func(i, j int) bool { return db.ranges[i].start < db.ranges[j].start })
+	return db, nil
+}
+
+// Lookup returns the autonomous system of ip, if known.
+func (db *ASNDatabase) Lookup(ip net.IP) (ASNInfo, bool) {
+	ip4 := ip.To4()
+	if db == nil || ip4 == nil {
+		return ASNInfo{}, false
+	}
+	addr := binary.BigEndian.Uint32(ip4)
+	i := sort.Search(len(db.ranges), func(i int) bool { return db.ranges[i].start > addr }) - 1
+	if i < 0 || addr > db.ranges[i].end {
+		return ASNInfo{}, false
+	}
+	return db.ranges[i].info, true
+}
+
+// Len returns the number of ranges in the database.
+func (db *ASNDatabase) Len() int {
+	return len(db.ranges)
+}
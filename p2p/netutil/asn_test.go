@@ -0,0 +1,61 @@
+// Copyright 2021 The celo Authors
+// This file is part of the celo library.
+//
+// The celo library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The celo library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the celo library. If not, see <http://www.gnu.org/licenses/>.
+
+package netutil
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestASNDatabase(t *testing.T) {
+	db, err := ParseASNDatabase(strings.NewReader(
+		"1.0.0.0\t1.0.0.255\t13335\tUS\tCLOUDFLARENET\n" +
+			"1.0.1.0\t1.0.3.255\t0\tNone\tNot routed\n" +
+			"1.0.4.0\t1.0.7.255\t38803\tAU\tWPL-AS-AP\n" +
+			"2001:200::\t2001:200:ffff:ffff:ffff:ffff:ffff:ffff\t2500\tJP\tWIDE-BB\n",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() != 2 {
+		t.Fatalf("ranges mismatch: have %d, want %d", db.Len(), 2)
+	}
+	tests := []struct {
+		ip    string
+		asn   uint32
+		found bool
+	}{
+		{"0.255.255.255", 0, false},
+		{"1.0.0.0", 13335, true},
+		{"1.0.0.255", 13335, true},
+		{"1.0.2.1", 0, false},
+		{"1.0.5.1", 38803, true},
+		{"1.0.8.0", 0, false},
+		{"2001:200::1", 0, false},
+	}
+	for _, tt := range tests {
+		info, found := db.Lookup(net.ParseIP(tt.ip))
+		if found != tt.found || info.ASN != tt.asn {
+			t.Errorf("%s: have (%d, %t), want (%d, %t)", tt.ip, info.ASN, found, tt.asn, tt.found)
+		}
+	}
+
+	if _, err := ParseASNDatabase(strings.NewReader("1.0.0.0\t1.0.0.255\n")); err == nil {
+		t.Error("expected error for truncated line")
+	}
+}
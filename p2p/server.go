@@ -123,6 +123,15 @@ type Config struct {
 	// IP networks contained in the list are considered.
 	NetRestrict *netutil.Netlist `toml:",omitempty"`
 
+	// PeerDiversityMaxFraction limits the fraction of the dialed peers in a single
+	// /16 subnet, autonomous system or country. Zero disables the limit.
+	PeerDiversityMaxFraction float64 `toml:",omitempty"`
+
+	// PeerDiversityASNDatabase is the IP to ASN database (iptoasn.com TSV format)
+	// used to bucket the peers by autonomous system and country. If empty, only
+	// subnets are considered.
+	PeerDiversityASNDatabase string `toml:",omitempty"`
+
 	// PingIPFromPacket uses the IP address from p2p discovery ping packet
 	// rather than the UDP header. See https://github.com/celo-org/celo-blockchain/pull/301
 	PingIPFromPacket bool
@@ -207,6 +216,7 @@ type Server struct {
 	DiscV5    *discover.UDPv5
 	discmix   *enode.FairMix
 	dialsched *dialScheduler
+	diversity *diversityPolicy // nil if disabled
 
 	// Channels into the run loop.
 	quit                    chan struct{}
@@ -583,6 +593,9 @@ func (srv *Server) Start() (err error) {
 	if err := srv.setupDiscovery(); err != nil {
 		return err
 	}
+	if err := srv.setupDiversity(); err != nil {
+		return err
+	}
 	srv.setupDialScheduler()
 
 	srv.loopWG.Add(1)
@@ -718,6 +731,25 @@ func (srv *Server) setupDiscovery() error {
 	return nil
 }
 
+func (srv *Server) setupDiversity() error {
+	if srv.PeerDiversityMaxFraction <= 0 {
+		return nil
+	}
+	if srv.PeerDiversityMaxFraction > 1 {
+		return fmt.Errorf("invalid peer diversity fraction %v", srv.PeerDiversityMaxFraction)
+	}
+	srv.diversity = &diversityPolicy{maxFraction: srv.PeerDiversityMaxFraction}
+	if srv.PeerDiversityASNDatabase != "" {
+		db, err := netutil.LoadASNDatabase(srv.PeerDiversityASNDatabase)
+		if err != nil {
+			return fmt.Errorf("failed to load ASN database: %v", err)
+		}
+		srv.log.Info("Loaded ASN database", "ranges", db.Len())
+		srv.diversity.asnDB = db
+	}
+	return nil
+}
+
 func (srv *Server) setupDialScheduler() {
 	config := dialConfig{
 		self:                  srv.localnode.ID(),
@@ -725,6 +757,7 @@ func (srv *Server) setupDialScheduler() {
 		maxActiveDials:        srv.MaxPendingPeers,
 		log:                   srv.Logger,
 		netRestrict:           srv.NetRestrict,
+		diversity:             srv.diversity,
 		dialer:                srv.Dialer,
 		clock:                 srv.clock,
 		dialHistoryExpiration: srv.DialHistoryExpiration,
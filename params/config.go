@@ -318,6 +318,11 @@ type ChainConfig struct {
 	L2MigrationBlock   *big.Int `json:"l2MigrationBlock,omitempty"`   // l2 migration block / first block of Celo as L2 / 1 + last block of Celo as L1 (nil = no migration, 0 = no migration)
 
 	Istanbul *IstanbulConfig `json:"istanbul,omitempty"`
+
+	// MultiFeeMarket enables the experimental per fee currency base fee dynamics. It is a
+	// research mode meant for test networks only and is not scheduled on any public network.
+	MultiFeeMarket *MultiFeeMarketConfig `json:"multiFeeMarket,omitempty"`
+
 	// This does not belong here but passing it to every function is not possible since that breaks
 	// some implemented interfaces and introduces churn across the geth codebase.
 	FullHeaderChainAvailable bool // False for lightest Sync mode, true otherwise
@@ -334,6 +339,10 @@ type IstanbulConfig struct {
 	LookbackWindow uint64 `json:"lookbackwindow"`        // The number of blocks to look back when calculating uptime
 	BlockPeriod    uint64 `json:"blockperiod,omitempty"` // Default minimum difference between two consecutive block's timestamps in second
 
+	// The weights of the validators in the weighted random proposer policy,
+	// 1 for the validators not listed.
+	ProposerWeights map[common.Address]uint64 `json:"proposerWeights,omitempty"`
+
 	// The base timeout for each Istanbul round in milliseconds. The first
 	// round will have a timeout of exactly this and subsequent rounds will
 	// have timeouts of this + additional time that increases with round
@@ -341,6 +350,20 @@ type IstanbulConfig struct {
 	RequestTimeout uint64 `json:"requesttimeout,omitempty"`
 }
 
+// MultiFeeMarketConfig is the configuration of the experimental multidimensional fee market,
+// where the base fee of every fee currency adjusts independently, EIP-1559 style, based on the
+// share of the block gas consumed by transactions paying in that currency.
+type MultiFeeMarketConfig struct {
+	Block *big.Int `json:"block"` // Activation block (nil = disabled, 0 = already activated)
+
+	// TargetShareBps is the share of the block gas limit, in basis points, each fee currency
+	// is expected to consume. Above it the currency base fee rises, below it the base fee falls.
+	TargetShareBps uint64 `json:"targetShareBps"`
+	// ChangeDenominator bounds the change of a currency base fee multiplier between two blocks,
+	// like params.BaseFeeChangeDenominator does for the EIP-1559 base fee.
+	ChangeDenominator uint64 `json:"changeDenominator"`
+}
+
 // String implements the stringer interface, returning the consensus engine details.
 func (c *IstanbulConfig) String() string {
 	return "istanbul"
@@ -476,6 +499,12 @@ func (c *ChainConfig) IsHFork(num *big.Int) bool {
 	return isForked(c.HForkBlock, num)
 }
 
+// IsMultiFeeMarket returns whether num is at or past the activation of the experimental
+// per fee currency base fee dynamics.
+func (c *ChainConfig) IsMultiFeeMarket(num *big.Int) bool {
+	return c.MultiFeeMarket != nil && isForked(c.MultiFeeMarket.Block, num)
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
@@ -601,9 +630,19 @@ func (c *ChainConfig) checkCeloCompatible(newcfg *ChainConfig, head *big.Int) *C
 	if isForkIncompatible(c.HForkBlock, newcfg.HForkBlock, head) {
 		return newCompatError("HFork block", c.HForkBlock, newcfg.HForkBlock)
 	}
+	if isForkIncompatible(c.multiFeeMarketBlock(), newcfg.multiFeeMarketBlock(), head) {
+		return newCompatError("Multi fee market block", c.multiFeeMarketBlock(), newcfg.multiFeeMarketBlock())
+	}
 	return nil
 }
 
+func (c *ChainConfig) multiFeeMarketBlock() *big.Int {
+	if c.MultiFeeMarket == nil {
+		return nil
+	}
+	return c.MultiFeeMarket.Block
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2, head *big.Int) bool {
@@ -745,11 +784,12 @@ func (c *ChainConfig) DeepCopy() *ChainConfig {
 		L2MigrationBlock:    copyBigIntOrNil(c.L2MigrationBlock),
 
 		Istanbul: &IstanbulConfig{
-			Epoch:          c.Istanbul.Epoch,
-			ProposerPolicy: c.Istanbul.ProposerPolicy,
-			LookbackWindow: c.Istanbul.LookbackWindow,
-			BlockPeriod:    c.Istanbul.BlockPeriod,
-			RequestTimeout: c.Istanbul.RequestTimeout,
+			Epoch:           c.Istanbul.Epoch,
+			ProposerPolicy:  c.Istanbul.ProposerPolicy,
+			LookbackWindow:  c.Istanbul.LookbackWindow,
+			BlockPeriod:     c.Istanbul.BlockPeriod,
+			RequestTimeout:  c.Istanbul.RequestTimeout,
+			ProposerWeights: copyProposerWeights(c.Istanbul.ProposerWeights),
 			// V2Block:        copyBigIntOrNil(c.Istanbul.V2Block),
 		},
 
@@ -757,6 +797,13 @@ func (c *ChainConfig) DeepCopy() *ChainConfig {
 		Faker:                    c.Faker,
 		FakeBaseFee:              c.FakeBaseFee,
 	}
+	if c.MultiFeeMarket != nil {
+		cpy.MultiFeeMarket = &MultiFeeMarketConfig{
+			Block:             copyBigIntOrNil(c.MultiFeeMarket.Block),
+			TargetShareBps:    c.MultiFeeMarket.TargetShareBps,
+			ChangeDenominator: c.MultiFeeMarket.ChangeDenominator,
+		}
+	}
 	return cpy
 }
 
@@ -766,3 +813,14 @@ func copyBigIntOrNil(num *big.Int) *big.Int {
 	}
 	return new(big.Int).Set(num)
 }
+
+func copyProposerWeights(weights map[common.Address]uint64) map[common.Address]uint64 {
+	if weights == nil {
+		return nil
+	}
+	cpy := make(map[common.Address]uint64, len(weights))
+	for addr, weight := range weights {
+		cpy[addr] = weight
+	}
+	return cpy
+}